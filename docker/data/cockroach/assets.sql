@@ -1,19 +1,26 @@
 -- Insert mock alarms
-INSERT INTO alarms VALUES ('0000-0000-0000-0000', 'aaaaaaaaaaaaaaaaaaaaaaaa', '1001', 'co');
-INSERT INTO alarms VALUES ('1111-1111-1111-1111', 'aaaaaaaaaaaaaaaaaaaaaaaa', '1002', 'smoke');
-INSERT INTO alarms VALUES ('2222-2222-2222-2222', 'bbbbbbbbbbbbbbbbbbbbbbbb', '1003', 'co');
-INSERT INTO alarms VALUES ('3333-3333-3333-3333', 'bbbbbbbbbbbbbbbbbbbbbbbb', '1004', 'smoke');
-INSERT INTO alarms VALUES ('4444-4444-4444-4444', 'cccccccccccccccccccccccc', '1005', 'co');
-INSERT INTO alarms VALUES ('5555-5555-5555-5555', 'cccccccccccccccccccccccc', '1006', 'smoke');
-INSERT INTO alarms VALUES ('6666-6666-6666-6666', 'dddddddddddddddddddddddd', '1007', 'co');
-INSERT INTO alarms VALUES ('7777-7777-7777-7777', 'dddddddddddddddddddddddd', '1008', 'smoke');
+INSERT INTO alarms VALUES ('0000-0000-0000-0000', 'demo-tenant', 'aaaaaaaaaaaaaaaaaaaaaaaa', '1001', 'co');
+INSERT INTO alarms VALUES ('1111-1111-1111-1111', 'demo-tenant', 'aaaaaaaaaaaaaaaaaaaaaaaa', '1002', 'smoke');
+INSERT INTO alarms VALUES ('2222-2222-2222-2222', 'demo-tenant', 'bbbbbbbbbbbbbbbbbbbbbbbb', '1003', 'co');
+INSERT INTO alarms VALUES ('3333-3333-3333-3333', 'demo-tenant', 'bbbbbbbbbbbbbbbbbbbbbbbb', '1004', 'smoke');
+INSERT INTO alarms VALUES ('4444-4444-4444-4444', 'demo-tenant', 'cccccccccccccccccccccccc', '1005', 'co');
+INSERT INTO alarms VALUES ('5555-5555-5555-5555', 'demo-tenant', 'cccccccccccccccccccccccc', '1006', 'smoke');
+INSERT INTO alarms VALUES ('6666-6666-6666-6666', 'demo-tenant', 'dddddddddddddddddddddddd', '1007', 'co');
+INSERT INTO alarms VALUES ('7777-7777-7777-7777', 'demo-tenant', 'dddddddddddddddddddddddd', '1008', 'smoke');
 
 -- Insert mock cameras
-INSERT INTO cameras VALUES ('8888-8888-8888-8888', 'aaaaaaaaaaaaaaaaaaaaaaaa', '2001', 921600);
-INSERT INTO cameras VALUES ('9999-9999-9999-9999', 'aaaaaaaaaaaaaaaaaaaaaaaa', '2002', 2073600);
-INSERT INTO cameras VALUES ('aaaa-aaaa-aaaa-aaaa', 'bbbbbbbbbbbbbbbbbbbbbbbb', '2003', 921600);
-INSERT INTO cameras VALUES ('bbbb-bbbb-bbbb-bbbb', 'bbbbbbbbbbbbbbbbbbbbbbbb', '2004', 2073600);
-INSERT INTO cameras VALUES ('cccc-cccc-cccc-cccc', 'cccccccccccccccccccccccc', '2005', 921600);
-INSERT INTO cameras VALUES ('dddd-dddd-dddd-dddd', 'cccccccccccccccccccccccc', '2006', 2073600);
-INSERT INTO cameras VALUES ('eeee-eeee-eeee-eeee', 'dddddddddddddddddddddddd', '2007', 921600);
-INSERT INTO cameras VALUES ('ffff-ffff-ffff-ffff', 'dddddddddddddddddddddddd', '2008', 2073600);
+INSERT INTO cameras VALUES ('8888-8888-8888-8888', 'demo-tenant', 'aaaaaaaaaaaaaaaaaaaaaaaa', '2001', 921600);
+INSERT INTO cameras VALUES ('9999-9999-9999-9999', 'demo-tenant', 'aaaaaaaaaaaaaaaaaaaaaaaa', '2002', 2073600);
+INSERT INTO cameras VALUES ('aaaa-aaaa-aaaa-aaaa', 'demo-tenant', 'bbbbbbbbbbbbbbbbbbbbbbbb', '2003', 921600);
+INSERT INTO cameras VALUES ('bbbb-bbbb-bbbb-bbbb', 'demo-tenant', 'bbbbbbbbbbbbbbbbbbbbbbbb', '2004', 2073600);
+INSERT INTO cameras VALUES ('cccc-cccc-cccc-cccc', 'demo-tenant', 'cccccccccccccccccccccccc', '2005', 921600);
+INSERT INTO cameras VALUES ('dddd-dddd-dddd-dddd', 'demo-tenant', 'cccccccccccccccccccccccc', '2006', 2073600);
+INSERT INTO cameras VALUES ('eeee-eeee-eeee-eeee', 'demo-tenant', 'dddddddddddddddddddddddd', '2007', 921600);
+INSERT INTO cameras VALUES ('ffff-ffff-ffff-ffff', 'demo-tenant', 'dddddddddddddddddddddddd', '2008', 2073600);
+
+-- Insert mock relations
+INSERT INTO relations VALUES ('8888-8888-8888-8888', '0000-0000-0000-0000', 'covers', 'demo-tenant');
+INSERT INTO relations VALUES ('8888-8888-8888-8888', '1111-1111-1111-1111', 'covers', 'demo-tenant');
+INSERT INTO relations VALUES ('aaaa-aaaa-aaaa-aaaa', '2222-2222-2222-2222', 'covers', 'demo-tenant');
+INSERT INTO relations VALUES ('cccc-cccc-cccc-cccc', '4444-4444-4444-4444', 'covers', 'demo-tenant');
+INSERT INTO relations VALUES ('eeee-eeee-eeee-eeee', '6666-6666-6666-6666', 'covers', 'demo-tenant');
@@ -5,9 +5,20 @@ CREATE DATABASE sensors;
 -- Create sensor table
 CREATE TABLE sensors (
   id varchar(256) PRIMARY KEY,
+  tenant_id varchar(256) NOT NULL,
   site_id varchar(256) NOT NULL,
   name varchar(256) NOT NULL,
   unit varchar(256) NOT NULL,
   min_safe double precision NOT NULL,
   max_safe double precision NOT NULL
 );
+
+CREATE INDEX sensors_tenant_id_idx ON sensors (tenant_id);
+
+-- Create sensor reading table
+CREATE TABLE sensor_readings (
+  sensor_id varchar(256) PRIMARY KEY REFERENCES sensors (id) ON DELETE CASCADE,
+  tenant_id varchar(256) NOT NULL,
+  value double precision NOT NULL,
+  time timestamp with time zone NOT NULL
+);
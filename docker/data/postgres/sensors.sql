@@ -1,12 +1,12 @@
 -- Insert mock data
-INSERT INTO sensors VALUES ('1111-1111', 'aaaaaaaaaaaaaaaaaaaaaaaa', 'temperature', 'celsius', -30.0, 30.0);
-INSERT INTO sensors VALUES ('2222-2222', 'aaaaaaaaaaaaaaaaaaaaaaaa', 'pressure', 'atmosphere', 0.5, 1.0);
+INSERT INTO sensors VALUES ('1111-1111', 'demo-tenant', 'aaaaaaaaaaaaaaaaaaaaaaaa', 'temperature', 'celsius', -30.0, 30.0);
+INSERT INTO sensors VALUES ('2222-2222', 'demo-tenant', 'aaaaaaaaaaaaaaaaaaaaaaaa', 'pressure', 'atmosphere', 0.5, 1.0);
 
-INSERT INTO sensors VALUES ('3333-3333', 'bbbbbbbbbbbbbbbbbbbbbbbb', 'temperature', 'fahrenheit', -22.0, 86.0);
-INSERT INTO sensors VALUES ('4444-4444', 'bbbbbbbbbbbbbbbbbbbbbbbb', 'pressure', 'pascal', 50000, 100000);
+INSERT INTO sensors VALUES ('3333-3333', 'demo-tenant', 'bbbbbbbbbbbbbbbbbbbbbbbb', 'temperature', 'fahrenheit', -22.0, 86.0);
+INSERT INTO sensors VALUES ('4444-4444', 'demo-tenant', 'bbbbbbbbbbbbbbbbbbbbbbbb', 'pressure', 'pascal', 50000, 100000);
 
-INSERT INTO sensors VALUES ('5555-5555', 'cccccccccccccccccccccccc', 'temperature', 'celsius', -30.0, 30.0);
-INSERT INTO sensors VALUES ('6666-6666', 'cccccccccccccccccccccccc', 'pressure', 'atmosphere', 0.5, 1.0);
+INSERT INTO sensors VALUES ('5555-5555', 'demo-tenant', 'cccccccccccccccccccccccc', 'temperature', 'celsius', -30.0, 30.0);
+INSERT INTO sensors VALUES ('6666-6666', 'demo-tenant', 'cccccccccccccccccccccccc', 'pressure', 'atmosphere', 0.5, 1.0);
 
-INSERT INTO sensors VALUES ('7777-7777', 'dddddddddddddddddddddddd', 'temperature', 'fahrenheit', -22.0, 86.0);
-INSERT INTO sensors VALUES ('8888-8888', 'dddddddddddddddddddddddd', 'pressure', 'pascal', 50000, 100000);
+INSERT INTO sensors VALUES ('7777-7777', 'demo-tenant', 'dddddddddddddddddddddddd', 'temperature', 'fahrenheit', -22.0, 86.0);
+INSERT INTO sensors VALUES ('8888-8888', 'demo-tenant', 'dddddddddddddddddddddddd', 'pressure', 'pascal', 50000, 100000);
@@ -0,0 +1,48 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+// Breaker is a circuit breaker that opens after a number of consecutive failures
+// and lets a call through again once a cooldown has passed.
+type Breaker struct {
+	sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// New creates a new circuit breaker
+func New(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow reports whether a call can go through the circuit
+func (b *Breaker) Allow(now time.Time) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	return !now.Before(b.openUntil)
+}
+
+// Record updates the circuit with the outcome of a call
+func (b *Breaker) Record(now time.Time, ok bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	if ok {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
@@ -0,0 +1,36 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	b := New(5, time.Minute)
+
+	assert.Equal(t, 5, b.threshold)
+	assert.Equal(t, time.Minute, b.cooldown)
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := New(2, time.Minute)
+
+	assert.True(t, b.Allow(now))
+	b.Record(now, false)
+	assert.True(t, b.Allow(now))
+
+	// A success resets the consecutive failures
+	b.Record(now, true)
+	b.Record(now, false)
+	assert.True(t, b.Allow(now))
+
+	b.Record(now, false)
+	assert.False(t, b.Allow(now))
+	assert.False(t, b.Allow(now.Add(30*time.Second)))
+
+	// After the cooldown a call is let through again
+	assert.True(t, b.Allow(now.Add(time.Minute)))
+}
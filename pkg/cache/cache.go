@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// Cache is an in-memory cache whose entries expire after a TTL.
+// It holds at most a maximum number of entries and drops new entries while it is full of unexpired ones.
+type Cache struct {
+	sync.RWMutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]entry
+}
+
+// New creates a new cache
+func New(ttl time.Duration, maxEntries int) *Cache {
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]entry),
+	}
+}
+
+// Get returns the value of a key if it has not expired
+func (c *Cache) Get(key string, now time.Time) (interface{}, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	e, ok := c.entries[key]
+	if !ok || now.After(e.expires) {
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+// Set stores the value of a key until the TTL passes
+func (c *Cache) Set(key string, value interface{}, now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+
+	if len(c.entries) < c.maxEntries {
+		c.entries[key] = entry{
+			value:   value,
+			expires: now.Add(c.ttl),
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	c := New(time.Minute, 10)
+
+	assert.Equal(t, time.Minute, c.ttl)
+	assert.Equal(t, 10, c.maxEntries)
+	assert.NotNil(t, c.entries)
+}
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	c := New(time.Minute, 10)
+
+	_, ok := c.Get("aaaa", now)
+	assert.False(t, ok)
+
+	c.Set("aaaa", true, now)
+	v, ok := c.Get("aaaa", now.Add(30*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, true, v)
+
+	_, ok = c.Get("aaaa", now.Add(2*time.Minute))
+	assert.False(t, ok)
+}
+
+func TestCacheFull(t *testing.T) {
+	now := time.Now()
+	c := New(time.Minute, 2)
+
+	c.Set("aaaa", 1, now)
+	c.Set("bbbb", 2, now)
+	c.Set("cccc", 3, now)
+
+	// The cache is full of unexpired entries, so the new entry is dropped
+	_, ok := c.Get("cccc", now)
+	assert.False(t, ok)
+
+	// Expired entries are evicted to make room
+	later := now.Add(2 * time.Minute)
+	c.Set("cccc", 3, later)
+	v, ok := c.Get("cccc", later)
+	assert.True(t, ok)
+	assert.Equal(t, 3, v)
+}
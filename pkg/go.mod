@@ -0,0 +1,8 @@
+module github.com/moorara/microservices-demo/pkg
+
+go 1.14
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/stretchr/testify v1.6.1
+)
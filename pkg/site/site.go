@@ -0,0 +1,161 @@
+package site
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/moorara/microservices-demo/pkg/breaker"
+	"github.com/moorara/microservices-demo/pkg/cache"
+)
+
+const (
+	defaultTimeout          = 2 * time.Second
+	defaultCacheTTL         = 30 * time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 10 * time.Second
+	maxCacheEntries         = 10000
+)
+
+var (
+	// objectID matches the ids of the site service, which are MongoDB object ids
+	objectID = regexp.MustCompile(`^[0-9a-fA-F]{24}$`)
+
+	// ErrUnknownSite is returned when the site service does not know a site id
+	ErrUnknownSite = errors.New("unknown site id")
+
+	// ErrUnavailable is returned when the site service cannot be reached and the client fails closed
+	ErrUnavailable = errors.New("site service unavailable")
+)
+
+type (
+	// Client validates site ids against the site service
+	Client interface {
+		Validate(ctx context.Context, siteID string) error
+	}
+
+	// Config configures a site client
+	Config struct {
+		// Addr is the base address of the site service (e.g. http://site-service:4010)
+		Addr string
+		// Timeout bounds every call to the site service
+		Timeout time.Duration
+		// CacheTTL is how long a lookup result is remembered
+		CacheTTL time.Duration
+		// FailOpen accepts any site id while the site service is unavailable
+		FailOpen bool
+		// BreakerThreshold is the number of consecutive failures that opens the circuit
+		BreakerThreshold int
+		// BreakerCooldown is how long the circuit stays open before a call is retried
+		BreakerCooldown time.Duration
+	}
+
+	client struct {
+		addr     string
+		failOpen bool
+		http     *http.Client
+		breaker  *breaker.Breaker
+		cache    *cache.Cache
+		now      func() time.Time
+	}
+)
+
+// NewClient creates a new site client
+func NewClient(config Config) Client {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = defaultCacheTTL
+	}
+	if config.BreakerThreshold <= 0 {
+		config.BreakerThreshold = defaultBreakerThreshold
+	}
+	if config.BreakerCooldown <= 0 {
+		config.BreakerCooldown = defaultBreakerCooldown
+	}
+
+	return &client{
+		addr:     strings.TrimSuffix(config.Addr, "/"),
+		failOpen: config.FailOpen,
+		http: &http.Client{
+			Timeout: config.Timeout,
+		},
+		breaker: breaker.New(config.BreakerThreshold, config.BreakerCooldown),
+		cache:   cache.New(config.CacheTTL, maxCacheEntries),
+		now:     time.Now,
+	}
+}
+
+func (c *client) fetch(ctx context.Context, siteID string) (bool, error) {
+	req, err := http.NewRequest("GET", c.addr+"/v1/sites/"+url.PathEscape(siteID), nil)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusOK:
+		return true, nil
+	case res.StatusCode == http.StatusNotFound:
+		return false, nil
+	default:
+		return false, errors.New(res.Status)
+	}
+}
+
+func (c *client) unavailable() error {
+	if c.failOpen {
+		return nil
+	}
+	return ErrUnavailable
+}
+
+// Validate returns ErrUnknownSite if a site does not exist.
+// It returns the error of the context if the caller cancels the call or its deadline passes.
+func (c *client) Validate(ctx context.Context, siteID string) error {
+	// The site service fails with 500 for ids that are not object ids, so they are rejected without calling it
+	if !objectID.MatchString(siteID) {
+		return ErrUnknownSite
+	}
+
+	now := c.now()
+
+	var exists bool
+	if v, ok := c.cache.Get(siteID, now); ok {
+		exists = v.(bool)
+	} else {
+		if !c.breaker.Allow(now) {
+			return c.unavailable()
+		}
+
+		var err error
+		exists, err = c.fetch(ctx, siteID)
+		// A call given up by the caller says nothing about the health of the site service
+		if err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+			return ctx.Err()
+		}
+
+		c.breaker.Record(c.now(), err == nil)
+		if err != nil {
+			return c.unavailable()
+		}
+
+		c.cache.Set(siteID, exists, now)
+	}
+
+	if !exists {
+		return ErrUnknownSite
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package site
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	siteA = "5f1e4c8b9d3a2b0011aaaa01"
+	siteB = "5f1e4c8b9d3a2b0011bbbb02"
+	siteC = "5f1e4c8b9d3a2b0011cccc03"
+	siteD = "5f1e4c8b9d3a2b0011dddd04"
+)
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          Config
+		expectedTimeout time.Duration
+	}{
+		{
+			"Defaults",
+			Config{Addr: "http://localhost:4010/"},
+			defaultTimeout,
+		},
+		{
+			"Custom",
+			Config{Addr: "http://localhost:4010", Timeout: time.Second, CacheTTL: time.Minute},
+			time.Second,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewClient(tc.config).(*client)
+
+			assert.Equal(t, "http://localhost:4010", c.addr)
+			assert.Equal(t, tc.expectedTimeout, c.http.Timeout)
+			assert.NotNil(t, c.breaker)
+			assert.NotNil(t, c.cache)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		failOpen      bool
+		siteID        string
+		expectedError error
+	}{
+		{"EmptyID", 200, false, "", ErrUnknownSite},
+		{"Exists", 200, false, siteA, nil},
+		{"NotFound", 404, false, siteA, ErrUnknownSite},
+		{"UnavailableFailClosed", 503, false, siteA, ErrUnavailable},
+		{"UnavailableFailOpen", 503, true, siteA, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var path string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				w.WriteHeader(tc.statusCode)
+			}))
+			defer ts.Close()
+
+			c := NewClient(Config{Addr: ts.URL, FailOpen: tc.failOpen})
+			err := c.Validate(context.Background(), tc.siteID)
+
+			assert.Equal(t, tc.expectedError, err)
+			if tc.siteID != "" {
+				assert.Equal(t, "/v1/sites/"+tc.siteID, path)
+			}
+		})
+	}
+}
+
+func TestValidateCache(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(200)
+	}))
+	defer ts.Close()
+
+	now := time.Now()
+	c := NewClient(Config{Addr: ts.URL, CacheTTL: time.Minute}).(*client)
+	c.now = func() time.Time { return now }
+
+	assert.NoError(t, c.Validate(context.Background(), siteA))
+	assert.NoError(t, c.Validate(context.Background(), siteA))
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(2 * time.Minute)
+	assert.NoError(t, c.Validate(context.Background(), siteA))
+	assert.Equal(t, 2, calls)
+}
+
+func TestValidateBreaker(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(500)
+	}))
+	defer ts.Close()
+
+	now := time.Now()
+	c := NewClient(Config{Addr: ts.URL, BreakerThreshold: 2, BreakerCooldown: time.Minute}).(*client)
+	c.now = func() time.Time { return now }
+
+	assert.Equal(t, ErrUnavailable, c.Validate(context.Background(), siteA))
+	assert.Equal(t, ErrUnavailable, c.Validate(context.Background(), siteB))
+	assert.Equal(t, 2, calls)
+
+	// The circuit is open, so the site service is not called
+	assert.Equal(t, ErrUnavailable, c.Validate(context.Background(), siteC))
+	assert.Equal(t, 2, calls)
+
+	// After the cooldown a call is let through again
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, ErrUnavailable, c.Validate(context.Background(), siteD))
+	assert.Equal(t, 3, calls)
+}
+
+func TestValidateCanceled(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(200)
+	}))
+	defer ts.Close()
+
+	c := NewClient(Config{Addr: ts.URL, BreakerThreshold: 1, BreakerCooldown: time.Minute}).(*client)
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.Equal(t, context.Canceled, c.Validate(ctx, siteA))
+	})
+
+	t.Run("DeadlineExceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+
+		assert.Equal(t, context.DeadlineExceeded, c.Validate(ctx, siteA))
+	})
+
+	// The caller giving up did not open the circuit
+	assert.NoError(t, c.Validate(context.Background(), siteA))
+	assert.Equal(t, 1, calls)
+}
+
+func TestValidateMalformed(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// The site service fails to cast an id that is not an object id and responds with 500
+		w.WriteHeader(500)
+	}))
+	defer ts.Close()
+
+	c := NewClient(Config{Addr: ts.URL, BreakerThreshold: 1, BreakerCooldown: time.Minute})
+
+	for _, siteID := range []string{"aaaa", "1111-1111", siteA + "0", "5f1e4c8b9d3a2b0011aaaz01"} {
+		assert.Equal(t, ErrUnknownSite, c.Validate(context.Background(), siteID))
+	}
+	assert.Equal(t, 0, calls)
+
+	// The malformed ids did not open the circuit
+	assert.Equal(t, ErrUnavailable, c.Validate(context.Background(), siteA))
+	assert.Equal(t, 1, calls)
+}
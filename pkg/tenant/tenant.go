@@ -0,0 +1,33 @@
+package tenant
+
+// Other is the label of the tenants that are not labeled by their id
+const Other = "other"
+
+// Labels gives the tenants their label in metrics.
+// Tenant ids are sent by clients, so only the configured tenants get their own label and every other tenant is labeled Other,
+// otherwise any client could create an unlimited number of time series.
+type Labels struct {
+	tenants map[string]bool
+}
+
+// NewLabels creates the labels of a list of tenants labeled by their id
+func NewLabels(tenants ...string) *Labels {
+	l := &Labels{
+		tenants: make(map[string]bool, len(tenants)),
+	}
+
+	for _, tenant := range tenants {
+		l.tenants[tenant] = true
+	}
+
+	return l
+}
+
+// Label returns the label of a tenant id
+func (l *Labels) Label(tenantID string) string {
+	if tenantID == "" || l.tenants[tenantID] {
+		return tenantID
+	}
+
+	return Other
+}
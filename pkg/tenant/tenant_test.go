@@ -0,0 +1,28 @@
+package tenant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		name          string
+		tenants       []string
+		tenantID      string
+		expectedLabel string
+	}{
+		{"NoTenant", []string{"tttt-tttt"}, "", ""},
+		{"Labeled", []string{"tttt-tttt"}, "tttt-tttt", "tttt-tttt"},
+		{"Other", []string{"tttt-tttt"}, "uuuu-uuuu", Other},
+		{"NoLabeledTenants", nil, "tttt-tttt", Other},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			labels := NewLabels(tc.tenants...)
+			assert.Equal(t, tc.expectedLabel, labels.Label(tc.tenantID))
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package config
 
-import "github.com/moorara/konfig"
+import (
+	"time"
+
+	"github.com/moorara/konfig"
+)
 
 const (
 	defaultLogLevel          = "info"
@@ -14,6 +18,10 @@ const (
 	defaultCockroachDatabase = "assets"
 	defaultJaegerAgentAddr   = "localhost:6831"
 	defaultJaegerLogSpans    = false
+	defaultTenantQuota       = 0
+	defaultSiteServiceAddr   = ""
+	defaultSiteCacheTTL      = 30 * time.Second
+	defaultSiteFailOpen      = false
 )
 
 var (
@@ -34,6 +42,11 @@ var Global = struct {
 	CockroachDatabase string
 	JaegerAgentAddr   string
 	JaegerLogSpans    bool
+	TenantQuota       int
+	MetricsTenants    []string
+	SiteServiceAddr   string
+	SiteCacheTTL      time.Duration
+	SiteFailOpen      bool
 }{
 	LogLevel:          defaultLogLevel,
 	ServiceName:       defaultServiceName,
@@ -47,6 +60,10 @@ var Global = struct {
 	CockroachDatabase: defaultCockroachDatabase,
 	JaegerAgentAddr:   defaultJaegerAgentAddr,
 	JaegerLogSpans:    defaultJaegerLogSpans,
+	TenantQuota:       defaultTenantQuota,
+	SiteServiceAddr:   defaultSiteServiceAddr,
+	SiteCacheTTL:      defaultSiteCacheTTL,
+	SiteFailOpen:      defaultSiteFailOpen,
 }
 
 func init() {
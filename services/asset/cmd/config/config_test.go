@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -21,6 +22,10 @@ func TestConfig(t *testing.T) {
 		expectedCockroachDatabase string
 		expectedJaegerAgentAddr   string
 		expectedJaegerLogSpans    bool
+		expectedTenantQuota       int
+		expectedSiteServiceAddr   string
+		expectedSiteCacheTTL      time.Duration
+		expectedSiteFailOpen      bool
 	}{
 		{
 			name:                      "Defauts",
@@ -36,6 +41,10 @@ func TestConfig(t *testing.T) {
 			expectedCockroachDatabase: defaultCockroachDatabase,
 			expectedJaegerAgentAddr:   defaultJaegerAgentAddr,
 			expectedJaegerLogSpans:    defaultJaegerLogSpans,
+			expectedTenantQuota:       defaultTenantQuota,
+			expectedSiteServiceAddr:   defaultSiteServiceAddr,
+			expectedSiteCacheTTL:      defaultSiteCacheTTL,
+			expectedSiteFailOpen:      defaultSiteFailOpen,
 		},
 	}
 
@@ -53,6 +62,11 @@ func TestConfig(t *testing.T) {
 			assert.Equal(t, tc.expectedCockroachDatabase, Global.CockroachDatabase)
 			assert.Equal(t, tc.expectedJaegerAgentAddr, Global.JaegerAgentAddr)
 			assert.Equal(t, tc.expectedJaegerLogSpans, Global.JaegerLogSpans)
+			assert.Equal(t, tc.expectedTenantQuota, Global.TenantQuota)
+			assert.Empty(t, Global.MetricsTenants)
+			assert.Equal(t, tc.expectedSiteServiceAddr, Global.SiteServiceAddr)
+			assert.Equal(t, tc.expectedSiteCacheTTL, Global.SiteCacheTTL)
+			assert.Equal(t, tc.expectedSiteFailOpen, Global.SiteFailOpen)
 		})
 	}
 }
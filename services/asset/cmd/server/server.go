@@ -10,9 +10,14 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/moorara/microservices-demo/services/asset/internal/middleware"
+	"github.com/moorara/microservices-demo/services/asset/internal/model"
+	"github.com/moorara/microservices-demo/services/asset/internal/service"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
 	"github.com/moorara/microservices-demo/services/asset/internal/transport"
 	"github.com/moorara/microservices-demo/services/asset/pkg/log"
 	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
+	"github.com/opentracing/opentracing-go"
 )
 
 type (
@@ -27,11 +32,12 @@ type (
 		logger        *log.Logger
 		httpServer    HTTPServer
 		natsTransport transport.NATSTransport
+		labelService  service.LabelService
 	}
 )
 
 // New creates a new Server
-func New(port string, natsTransport transport.NATSTransport, logger *log.Logger, metrics *metrics.Metrics) *Server {
+func New(port string, natsTransport transport.NATSTransport, labelService service.LabelService, logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer) *Server {
 	router := mux.NewRouter()
 	server := &Server{
 		logger: logger,
@@ -40,12 +46,16 @@ func New(port string, natsTransport transport.NATSTransport, logger *log.Logger,
 			Handler: router,
 		},
 		natsTransport: natsTransport,
+		labelService:  labelService,
 	}
 
+	monitor := middleware.NewMonitorMiddleware(logger, metrics, tracer)
+
 	router.NotFoundHandler = http.HandlerFunc(server.notFound)
 	router.Methods("GET").Path("/liveness").HandlerFunc(server.liveness)
 	router.Methods("GET").Path("/readiness").HandlerFunc(server.readiness)
 	router.Methods("GET").Path("/metrics").Handler(metrics.Handler())
+	router.Methods("GET").Path("/labels").HandlerFunc(monitor.Wrap(server.labels))
 
 	return server
 }
@@ -64,6 +74,63 @@ func (s *Server) readiness(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// labels responds with a printable sheet of asset labels.
+// Assets are selected by siteId and/or repeated id query parameters, and format is either png (default) or pdf.
+// Only the assets of the tenant given in the X-Tenant-ID header are labeled.
+func (s *Server) labels(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.Header.Get(tenant.Header)
+	if tenantID == "" {
+		http.Error(w, tenant.ErrNoTenant.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := tenant.NewContext(r.Context(), tenantID)
+	query := r.URL.Query()
+	siteID := query.Get("siteId")
+	ids := query["id"]
+
+	format := query.Get("format")
+	if format == "" {
+		format = model.LabelFormatPNG
+	}
+
+	var contentType string
+	switch format {
+	case model.LabelFormatPNG:
+		contentType = "image/png"
+	case model.LabelFormatPDF:
+		contentType = "application/pdf"
+	default:
+		http.Error(w, service.ErrInvalidLabelFormat.Error(), http.StatusBadRequest)
+		return
+	}
+
+	labels, err := s.labelService.Labels(ctx, siteID, ids)
+	if err == service.ErrNoLabelQuery || err == service.ErrTooManyLabels {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(labels) == 0 {
+		http.Error(w, "no assets found", http.StatusNotFound)
+		return
+	}
+
+	data, err := s.labelService.Render(ctx, format, labels)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=labels.%s", format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
 // Start starts the http server!
 func (s *Server) Start() error {
 	errs := make(chan error)
@@ -9,8 +9,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/moorara/microservices-demo/services/asset/internal/model"
+	"github.com/moorara/microservices-demo/services/asset/internal/service"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
 	"github.com/moorara/microservices-demo/services/asset/pkg/log"
 	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -55,6 +59,38 @@ func (m *mockNATSTransport) Stop(ctx context.Context) error {
 	return m.StopOutError
 }
 
+type mockLabelService struct {
+	LabelsCalled    bool
+	LabelsInContext context.Context
+	LabelsInSiteID  string
+	LabelsInIDs     []string
+	LabelsOutLabels []model.Label
+	LabelsOutError  error
+
+	RenderCalled    bool
+	RenderInContext context.Context
+	RenderInFormat  string
+	RenderInLabels  []model.Label
+	RenderOutData   []byte
+	RenderOutError  error
+}
+
+func (m *mockLabelService) Labels(ctx context.Context, siteID string, ids []string) ([]model.Label, error) {
+	m.LabelsCalled = true
+	m.LabelsInContext = ctx
+	m.LabelsInSiteID = siteID
+	m.LabelsInIDs = ids
+	return m.LabelsOutLabels, m.LabelsOutError
+}
+
+func (m *mockLabelService) Render(ctx context.Context, format string, labels []model.Label) ([]byte, error) {
+	m.RenderCalled = true
+	m.RenderInContext = ctx
+	m.RenderInFormat = format
+	m.RenderInLabels = labels
+	return m.RenderOutData, m.RenderOutError
+}
+
 func TestNotFound(t *testing.T) {
 	tests := []struct {
 		port           string
@@ -74,7 +110,7 @@ func TestNotFound(t *testing.T) {
 		natsTransport := &mockNATSTransport{}
 		logger := log.NewNopLogger()
 		metrics := metrics.New("test-service")
-		server := New(tc.port, natsTransport, logger, metrics)
+		server := New(tc.port, natsTransport, &mockLabelService{}, logger, metrics, mocktracer.New())
 
 		r := httptest.NewRequest(tc.method, tc.url, nil)
 		w := httptest.NewRecorder()
@@ -103,7 +139,7 @@ func TestLiveness(t *testing.T) {
 		natsTransport := &mockNATSTransport{}
 		logger := log.NewNopLogger()
 		metrics := metrics.New("test-service")
-		server := New(tc.port, natsTransport, logger, metrics)
+		server := New(tc.port, natsTransport, &mockLabelService{}, logger, metrics, mocktracer.New())
 
 		r := httptest.NewRequest(tc.method, tc.url, nil)
 		w := httptest.NewRecorder()
@@ -132,7 +168,7 @@ func TestReadiness(t *testing.T) {
 		natsTransport := &mockNATSTransport{}
 		logger := log.NewNopLogger()
 		metrics := metrics.New("test-service")
-		server := New(tc.port, natsTransport, logger, metrics)
+		server := New(tc.port, natsTransport, &mockLabelService{}, logger, metrics, mocktracer.New())
 
 		r := httptest.NewRequest(tc.method, tc.url, nil)
 		w := httptest.NewRecorder()
@@ -142,6 +178,160 @@ func TestReadiness(t *testing.T) {
 	}
 }
 
+func TestLabels(t *testing.T) {
+	labels := []model.Label{
+		{AssetID: "aaaa-aaaa", Type: model.AssetTypeAlarm, SerialNo: "1001"},
+	}
+
+	tests := []struct {
+		name                string
+		tenantID            string
+		url                 string
+		labelService        *mockLabelService
+		expectedStatus      int
+		expectedContentType string
+		expectedSiteID      string
+		expectedIDs         []string
+	}{
+		{
+			"NoTenant",
+			"",
+			"/labels?siteId=1111-1111",
+			&mockLabelService{},
+			http.StatusBadRequest,
+			"",
+			"",
+			nil,
+		},
+		{
+			"InvalidFormat",
+			"tttt-tttt",
+			"/labels?siteId=1111-1111&format=gif",
+			&mockLabelService{},
+			http.StatusBadRequest,
+			"",
+			"",
+			nil,
+		},
+		{
+			"NoQuery",
+			"tttt-tttt",
+			"/labels",
+			&mockLabelService{
+				LabelsOutError: service.ErrNoLabelQuery,
+			},
+			http.StatusBadRequest,
+			"",
+			"",
+			nil,
+		},
+		{
+			"TooManyLabels",
+			"tttt-tttt",
+			"/labels?siteId=1111-1111",
+			&mockLabelService{
+				LabelsOutError: service.ErrTooManyLabels,
+			},
+			http.StatusBadRequest,
+			"",
+			"1111-1111",
+			nil,
+		},
+		{
+			"DatabaseError",
+			"tttt-tttt",
+			"/labels?siteId=1111-1111",
+			&mockLabelService{
+				LabelsOutError: errors.New("find error"),
+			},
+			http.StatusInternalServerError,
+			"",
+			"1111-1111",
+			nil,
+		},
+		{
+			"NoAssets",
+			"tttt-tttt",
+			"/labels?siteId=1111-1111",
+			&mockLabelService{
+				LabelsOutLabels: []model.Label{},
+			},
+			http.StatusNotFound,
+			"",
+			"1111-1111",
+			nil,
+		},
+		{
+			"RenderError",
+			"tttt-tttt",
+			"/labels?id=aaaa-aaaa",
+			&mockLabelService{
+				LabelsOutLabels: labels,
+				RenderOutError:  errors.New("render error"),
+			},
+			http.StatusInternalServerError,
+			"",
+			"",
+			[]string{"aaaa-aaaa"},
+		},
+		{
+			"PNG",
+			"tttt-tttt",
+			"/labels?siteId=1111-1111",
+			&mockLabelService{
+				LabelsOutLabels: labels,
+				RenderOutData:   []byte("png"),
+			},
+			http.StatusOK,
+			"image/png",
+			"1111-1111",
+			nil,
+		},
+		{
+			"PDF",
+			"tttt-tttt",
+			"/labels?id=aaaa-aaaa&id=bbbb-bbbb&format=pdf",
+			&mockLabelService{
+				LabelsOutLabels: labels,
+				RenderOutData:   []byte("pdf"),
+			},
+			http.StatusOK,
+			"application/pdf",
+			"",
+			[]string{"aaaa-aaaa", "bbbb-bbbb"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			natsTransport := &mockNATSTransport{}
+			logger := log.NewNopLogger()
+			metrics := metrics.New("test-service")
+			server := New(":9999", natsTransport, tc.labelService, logger, metrics, mocktracer.New())
+
+			r := httptest.NewRequest("GET", tc.url, nil)
+			r.Header.Set(tenant.Header, tc.tenantID)
+			w := httptest.NewRecorder()
+			server.labels(w, r)
+
+			res := w.Result()
+			assert.Equal(t, tc.expectedStatus, res.StatusCode)
+			assert.Equal(t, tc.expectedSiteID, tc.labelService.LabelsInSiteID)
+			assert.Equal(t, tc.expectedIDs, tc.labelService.LabelsInIDs)
+
+			if tc.labelService.LabelsCalled {
+				tenantID, _ := tenant.FromContext(tc.labelService.LabelsInContext)
+				assert.Equal(t, tc.tenantID, tenantID)
+			}
+
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, tc.expectedContentType, res.Header.Get("Content-Type"))
+				assert.Equal(t, tc.labelService.RenderOutData, w.Body.Bytes())
+			}
+		})
+	}
+}
+
 func TestStart(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -7,13 +7,19 @@ require (
 	github.com/google/uuid v1.1.1
 	github.com/gorilla/mux v1.7.4
 	github.com/jinzhu/gorm v1.9.15
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/moorara/konfig v0.4.1
-	github.com/nats-io/nats-server/v2 v2.1.7 // indirect
+	github.com/moorara/microservices-demo/pkg v0.0.0
+	github.com/nats-io/nats-server/v2 v2.1.7
 	github.com/nats-io/nats.go v1.10.0
 	github.com/opentracing/opentracing-go v1.2.0
 	github.com/prometheus/client_golang v1.7.1
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/stretchr/testify v1.6.1
 	github.com/uber/jaeger-client-go v2.25.0+incompatible
 	github.com/uber/jaeger-lib v2.2.0+incompatible
+	golang.org/x/image v0.0.0-20200618115811-c13761719519
 	google.golang.org/protobuf v1.25.0 // indirect
 )
+
+replace github.com/moorara/microservices-demo/pkg => ../../pkg
@@ -11,18 +11,24 @@ import (
 )
 
 type (
-	// ORM is the interface for gorm.DB
+	// ORM is the interface for storing and querying models.
+	// Every call is a single operation, so implementations do not need to support gorm.DB chaining.
+	// Operations that must succeed or fail together are run on the ORM passed to the function of Transaction.
 	ORM interface {
 		AutoMigrate(values ...interface{}) *gorm.DB
 		Close() error
+		Count(value interface{}, count *int, where ...interface{}) *gorm.DB
 		Create(value interface{}) *gorm.DB
 		Delete(value interface{}, where ...interface{}) *gorm.DB
 		Find(out interface{}, where ...interface{}) *gorm.DB
 		LogMode(enable bool) *gorm.DB
-		Model(value interface{}) *gorm.DB
-		Preload(column string, conditions ...interface{}) *gorm.DB
-		Update(attrs ...interface{}) *gorm.DB
-		Where(query interface{}, args ...interface{}) *gorm.DB
+		Transaction(fn func(tx ORM) error) error
+		Update(value, attrs interface{}, where ...interface{}) *gorm.DB
+	}
+
+	// cockroachORM is the ORM implementation for CockroachDB using gorm.DB
+	cockroachORM struct {
+		db *gorm.DB
 	}
 
 	gormLogger struct {
@@ -53,5 +59,56 @@ func NewCockroachORM(addr, user, password, database string, logger *log.Logger)
 	db.LogMode(false)
 	db.SetLogger(&gormLogger{logger})
 
-	return db, nil
+	return &cockroachORM{db}, nil
+}
+
+func (o *cockroachORM) AutoMigrate(values ...interface{}) *gorm.DB {
+	return o.db.AutoMigrate(values...)
+}
+
+func (o *cockroachORM) Close() error {
+	return o.db.Close()
+}
+
+// Count counts the records of a model matching the where conditions.
+func (o *cockroachORM) Count(value interface{}, count *int, where ...interface{}) *gorm.DB {
+	db := o.db.Model(value)
+	if len(where) > 0 {
+		db = db.Where(where[0], where[1:]...)
+	}
+
+	return db.Count(count)
+}
+
+func (o *cockroachORM) Create(value interface{}) *gorm.DB {
+	return o.db.Create(value)
+}
+
+func (o *cockroachORM) Delete(value interface{}, where ...interface{}) *gorm.DB {
+	return o.db.Delete(value, where...)
+}
+
+func (o *cockroachORM) Find(out interface{}, where ...interface{}) *gorm.DB {
+	return o.db.Find(out, where...)
+}
+
+func (o *cockroachORM) LogMode(enable bool) *gorm.DB {
+	return o.db.LogMode(enable)
+}
+
+// Transaction runs fn in a database transaction, which is committed if fn returns nil and rolled back otherwise.
+func (o *cockroachORM) Transaction(fn func(tx ORM) error) error {
+	return o.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&cockroachORM{tx})
+	})
+}
+
+// Update updates the records of a model matching the where conditions with the given attributes.
+func (o *cockroachORM) Update(value, attrs interface{}, where ...interface{}) *gorm.DB {
+	db := o.db.Model(value)
+	if len(where) > 0 {
+		db = db.Where(where[0], where[1:]...)
+	}
+
+	return db.Update(attrs)
 }
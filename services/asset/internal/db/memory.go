@@ -0,0 +1,661 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MemoryAddr is the CockroachDB address for using the in-memory ORM instead
+const MemoryAddr = "memory://"
+
+var (
+	errInvalidValue    = errors.New("value must be a struct, a pointer to a struct or a pointer to a slice of structs")
+	errDuplicateKey    = errors.New("duplicate key value violates unique constraint \"primary\"")
+	errUnsupportedCond = errors.New("unsupported where condition")
+)
+
+type (
+	// memoryORM is an in-process ORM for local development and tests.
+	// It keeps a copy of every record per model type and supports the where conditions
+	// used by the services: column = ?, column != ?, column IN (?), combined with AND, OR and parentheses.
+	memoryORM struct {
+		sync.RWMutex
+		tables map[reflect.Type][]reflect.Value
+	}
+
+	// column describes how a database column maps to a struct field
+	column struct {
+		index      []int
+		primaryKey bool
+	}
+
+	// fieldUpdate is a new value for a struct field
+	fieldUpdate struct {
+		index []int
+		value reflect.Value
+	}
+
+	// predicate reports whether a record matches a where condition
+	predicate func(record reflect.Value) bool
+)
+
+// NewMemoryORM creates a new in-memory ORM
+func NewMemoryORM() ORM {
+	return &memoryORM{
+		tables: make(map[reflect.Type][]reflect.Value),
+	}
+}
+
+func (o *memoryORM) AutoMigrate(values ...interface{}) *gorm.DB {
+	o.Lock()
+	defer o.Unlock()
+
+	for _, value := range values {
+		t, _, err := structOf(value)
+		if err != nil {
+			return &gorm.DB{Error: err}
+		}
+
+		if _, ok := o.tables[t]; !ok {
+			o.tables[t] = []reflect.Value{}
+		}
+	}
+
+	return &gorm.DB{}
+}
+
+func (o *memoryORM) Close() error {
+	return nil
+}
+
+// Count counts the records matching the where conditions, and the primary key of value if set.
+func (o *memoryORM) Count(value interface{}, count *int, where ...interface{}) *gorm.DB {
+	t, v, err := structOf(value)
+	if err != nil {
+		return &gorm.DB{Error: err}
+	}
+
+	match, err := wherePredicate(t, v, where)
+	if err != nil {
+		return &gorm.DB{Error: err}
+	}
+
+	o.RLock()
+	defer o.RUnlock()
+
+	*count = 0
+	for _, record := range o.tables[t] {
+		if match(record) {
+			*count++
+		}
+	}
+
+	return &gorm.DB{Value: value}
+}
+
+func (o *memoryORM) Create(value interface{}) *gorm.DB {
+	t, v, err := structOf(value)
+	if err != nil {
+		return &gorm.DB{Error: err}
+	}
+
+	o.Lock()
+	defer o.Unlock()
+
+	match := primaryKeyPredicate(t, v)
+	for _, record := range o.tables[t] {
+		if match(record) {
+			return &gorm.DB{Error: errDuplicateKey}
+		}
+	}
+
+	record := reflect.New(t).Elem()
+	record.Set(v)
+	o.tables[t] = append(o.tables[t], record)
+
+	return &gorm.DB{Value: value, RowsAffected: 1}
+}
+
+func (o *memoryORM) Delete(value interface{}, where ...interface{}) *gorm.DB {
+	t, v, err := structOf(value)
+	if err != nil {
+		return &gorm.DB{Error: err}
+	}
+
+	match, err := wherePredicate(t, v, where)
+	if err != nil {
+		return &gorm.DB{Error: err}
+	}
+
+	o.Lock()
+	defer o.Unlock()
+
+	var affected int64
+	kept := []reflect.Value{}
+	for _, record := range o.tables[t] {
+		if match(record) {
+			affected++
+		} else {
+			kept = append(kept, record)
+		}
+	}
+	o.tables[t] = kept
+
+	return &gorm.DB{Value: value, RowsAffected: affected}
+}
+
+func (o *memoryORM) Find(out interface{}, where ...interface{}) *gorm.DB {
+	ptr := reflect.ValueOf(out)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return &gorm.DB{Error: errInvalidValue}
+	}
+
+	dest := ptr.Elem()
+	for dest.Kind() == reflect.Ptr {
+		if dest.IsNil() {
+			dest.Set(reflect.New(dest.Type().Elem()))
+		}
+		dest = dest.Elem()
+	}
+
+	t := dest.Type()
+	isSlice := t.Kind() == reflect.Slice
+	if isSlice {
+		t = t.Elem()
+	}
+
+	isPtrElem := t.Kind() == reflect.Ptr
+	if isPtrElem {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return &gorm.DB{Error: errInvalidValue}
+	}
+
+	match, err := wherePredicate(t, reflect.Zero(t), where)
+	if err != nil {
+		return &gorm.DB{Error: err}
+	}
+
+	o.RLock()
+	defer o.RUnlock()
+
+	records := []reflect.Value{}
+	for _, record := range o.tables[t] {
+		if match(record) {
+			records = append(records, record)
+		}
+	}
+
+	if !isSlice {
+		if len(records) == 0 {
+			return &gorm.DB{Value: out, Error: gorm.ErrRecordNotFound}
+		}
+		dest.Set(records[0])
+		return &gorm.DB{Value: out, RowsAffected: 1}
+	}
+
+	slice := reflect.MakeSlice(dest.Type(), 0, len(records))
+	for _, record := range records {
+		item := reflect.New(t)
+		item.Elem().Set(record)
+		if isPtrElem {
+			slice = reflect.Append(slice, item)
+		} else {
+			slice = reflect.Append(slice, item.Elem())
+		}
+	}
+	dest.Set(slice)
+
+	return &gorm.DB{Value: out, RowsAffected: int64(len(records))}
+}
+
+func (o *memoryORM) LogMode(enable bool) *gorm.DB {
+	return &gorm.DB{}
+}
+
+// Transaction runs fn on a copy of the tables while holding the lock and keeps the copy if fn returns nil,
+// so transactions run one at a time and their changes are applied all together or not at all.
+func (o *memoryORM) Transaction(fn func(tx ORM) error) error {
+	o.Lock()
+	defer o.Unlock()
+
+	tx := &memoryORM{
+		tables: make(map[reflect.Type][]reflect.Value, len(o.tables)),
+	}
+
+	for t, records := range o.tables {
+		copies := make([]reflect.Value, len(records))
+		for i, record := range records {
+			copies[i] = reflect.New(t).Elem()
+			copies[i].Set(record)
+		}
+		tx.tables[t] = copies
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	o.tables = tx.tables
+
+	return nil
+}
+
+// Update updates the records matching the where conditions, and the primary key of value if set.
+// Like gorm, when attrs is a struct only its non-blank fields are updated.
+func (o *memoryORM) Update(value, attrs interface{}, where ...interface{}) *gorm.DB {
+	t, v, err := structOf(value)
+	if err != nil {
+		return &gorm.DB{Error: err}
+	}
+
+	match, err := wherePredicate(t, v, where)
+	if err != nil {
+		return &gorm.DB{Error: err}
+	}
+
+	updates, err := updatesOf(t, attrs)
+	if err != nil {
+		return &gorm.DB{Error: err}
+	}
+
+	o.Lock()
+	defer o.Unlock()
+
+	var affected int64
+	for _, record := range o.tables[t] {
+		if match(record) {
+			for _, u := range updates {
+				record.FieldByIndex(u.index).Set(u.value)
+			}
+			affected++
+		}
+	}
+
+	return &gorm.DB{Value: value, RowsAffected: affected}
+}
+
+// structOf dereferences a value down to its struct type and value.
+func structOf(value interface{}) (reflect.Type, reflect.Value, error) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, reflect.Value{}, errInvalidValue
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil, reflect.Value{}, errInvalidValue
+	}
+
+	return v.Type(), v, nil
+}
+
+// columnsOf returns the columns of a model type, including the columns of embedded structs.
+func columnsOf(t reflect.Type) map[string]column {
+	columns := make(map[string]column)
+
+	var walk func(t reflect.Type, parent []int)
+	walk = func(t reflect.Type, parent []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			index := append(append([]int{}, parent...), i)
+
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				walk(f.Type, index)
+				continue
+			}
+
+			if f.PkgPath != "" {
+				continue // unexported
+			}
+
+			name := gorm.ToColumnName(f.Name)
+			primaryKey := false
+			for _, setting := range strings.Split(f.Tag.Get("gorm"), ";") {
+				s := strings.TrimSpace(setting)
+				switch {
+				case strings.EqualFold(s, "primary_key"):
+					primaryKey = true
+				case strings.HasPrefix(strings.ToLower(s), "column:"):
+					name = s[len("column:"):]
+				}
+			}
+
+			columns[name] = column{index: index, primaryKey: primaryKey}
+		}
+	}
+
+	walk(t, nil)
+
+	// Like gorm, a field named ID is the primary key if none is specified
+	hasPrimaryKey := false
+	for _, c := range columns {
+		hasPrimaryKey = hasPrimaryKey || c.primaryKey
+	}
+	if c, ok := columns["id"]; ok && !hasPrimaryKey {
+		c.primaryKey = true
+		columns["id"] = c
+	}
+
+	return columns
+}
+
+// primaryKeyPredicate matches the records with the same primary key as v.
+func primaryKeyPredicate(t reflect.Type, v reflect.Value) predicate {
+	columns := columnsOf(t)
+	return func(record reflect.Value) bool {
+		for _, c := range columns {
+			if c.primaryKey && !equal(record.FieldByIndex(c.index), v.FieldByIndex(c.index).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// wherePredicate builds a predicate from gorm-style where conditions.
+// If v has a non-blank primary key, records must match it too.
+func wherePredicate(t reflect.Type, v reflect.Value, where []interface{}) (predicate, error) {
+	columns := columnsOf(t)
+
+	var preds []predicate
+	for _, c := range columns {
+		if c.primaryKey {
+			field := v.FieldByIndex(c.index)
+			if !isBlank(field) {
+				c, val := c, field.Interface()
+				preds = append(preds, func(record reflect.Value) bool {
+					return equal(record.FieldByIndex(c.index), val)
+				})
+			}
+		}
+	}
+
+	if len(where) > 0 {
+		query, ok := where[0].(string)
+		if !ok {
+			return nil, errUnsupportedCond
+		}
+
+		p := &parser{
+			columns: columns,
+			tokens:  tokenize(query),
+			args:    where[1:],
+		}
+
+		pred, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+
+	return func(record reflect.Value) bool {
+		for _, pred := range preds {
+			if !pred(record) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// updatesOf returns the field values to update from a struct or a map of columns to values.
+func updatesOf(t reflect.Type, attrs interface{}) ([]fieldUpdate, error) {
+	columns := columnsOf(t)
+	updates := []fieldUpdate{}
+
+	if m, ok := attrs.(map[string]interface{}); ok {
+		for name, val := range m {
+			c, ok := columns[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("column %q does not exist", name)
+			}
+
+			field := reflect.New(t).Elem().FieldByIndex(c.index)
+			rv := reflect.ValueOf(val)
+			if !rv.IsValid() || !rv.Type().ConvertibleTo(field.Type()) {
+				return nil, fmt.Errorf("invalid value for column %q", name)
+			}
+			updates = append(updates, fieldUpdate{c.index, rv.Convert(field.Type())})
+		}
+		return updates, nil
+	}
+
+	at, av, err := structOf(attrs)
+	if err != nil || at != t {
+		return nil, errInvalidValue
+	}
+
+	for _, c := range columns {
+		field := av.FieldByIndex(c.index)
+		if !c.primaryKey && !isBlank(field) {
+			updates = append(updates, fieldUpdate{c.index, field})
+		}
+	}
+
+	return updates, nil
+}
+
+func isBlank(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+func equal(field reflect.Value, arg interface{}) bool {
+	av := reflect.ValueOf(arg)
+	if !av.IsValid() || !av.Type().ConvertibleTo(field.Type()) {
+		return false
+	}
+	return reflect.DeepEqual(field.Interface(), av.Convert(field.Type()).Interface())
+}
+
+func in(field reflect.Value, arg interface{}) bool {
+	av := reflect.ValueOf(arg)
+	if av.Kind() != reflect.Slice && av.Kind() != reflect.Array {
+		return equal(field, arg)
+	}
+
+	for i := 0; i < av.Len(); i++ {
+		if equal(field, av.Index(i).Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenize(query string) []string {
+	var tokens []string
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')' || r == '?' || r == '=':
+			flush()
+			tokens = append(tokens, string(r))
+		case (r == '!' || r == '<') && i+1 < len(runes) && (runes[i+1] == '=' || runes[i+1] == '>'):
+			flush()
+			tokens = append(tokens, "!=")
+			i++
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// parser is a recursive descent parser for where conditions.
+//
+//	expr   = term { "OR" term }
+//	term   = factor { "AND" factor }
+//	factor = "(" expr ")" | column "=" "?" | column "!=" "?" | column "IN" "(" "?" ")"
+type parser struct {
+	columns map[string]column
+	tokens  []string
+	args    []interface{}
+	pos     int
+	argPos  int
+}
+
+func (p *parser) parse() (predicate, error) {
+	pred, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.tokens) || p.argPos != len(p.args) {
+		return nil, errUnsupportedCond
+	}
+
+	return pred, nil
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) expect(token string) error {
+	if !strings.EqualFold(p.peek(), token) {
+		return errUnsupportedCond
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) arg() (interface{}, error) {
+	if err := p.expect("?"); err != nil {
+		return nil, err
+	}
+
+	if p.argPos >= len(p.args) {
+		return nil, errUnsupportedCond
+	}
+
+	arg := p.args[p.argPos]
+	p.argPos++
+	return arg, nil
+}
+
+func (p *parser) expr() (predicate, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for strings.EqualFold(p.peek(), "OR") {
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		l, r := left, right
+		left = func(record reflect.Value) bool {
+			return l(record) || r(record)
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) term() (predicate, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	for strings.EqualFold(p.peek(), "AND") {
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+
+		l, r := left, right
+		left = func(record reflect.Value) bool {
+			return l(record) && r(record)
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) factor() (predicate, error) {
+	if p.peek() == "(" {
+		p.pos++
+		pred, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return pred, nil
+	}
+
+	name := strings.ToLower(p.peek())
+	c, ok := p.columns[name]
+	if !ok {
+		return nil, fmt.Errorf("column %q does not exist", p.peek())
+	}
+	p.pos++
+
+	switch op := strings.ToUpper(p.peek()); op {
+	case "=", "!=":
+		p.pos++
+		arg, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+
+		negate := op == "!="
+		return func(record reflect.Value) bool {
+			return equal(record.FieldByIndex(c.index), arg) != negate
+		}, nil
+
+	case "IN":
+		p.pos++
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		arg, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return func(record reflect.Value) bool {
+			return in(record.FieldByIndex(c.index), arg)
+		}, nil
+
+	default:
+		return nil, errUnsupportedCond
+	}
+}
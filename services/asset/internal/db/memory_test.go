@@ -0,0 +1,282 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	base struct {
+		ID     string `gorm:"primary_key"`
+		SiteID string
+	}
+
+	thing struct {
+		base
+		Name  string
+		Count int
+	}
+
+	edge struct {
+		From string `gorm:"primary_key"`
+		To   string `gorm:"primary_key"`
+		Kind string `gorm:"primary_key;column:edge_kind"`
+	}
+)
+
+func TestNewMemoryORM(t *testing.T) {
+	orm := NewMemoryORM()
+	assert.NotNil(t, orm)
+	assert.NoError(t, orm.AutoMigrate(thing{}, &edge{}).Error)
+	assert.NoError(t, orm.LogMode(true).Error)
+	assert.NoError(t, orm.Close())
+}
+
+func TestMemoryORMCreate(t *testing.T) {
+	orm := NewMemoryORM()
+
+	first := &thing{base: base{ID: "1", SiteID: "a"}, Name: "one"}
+	result := orm.Create(&first)
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(1), result.RowsAffected)
+
+	result = orm.Create(thing{base: base{ID: "1", SiteID: "b"}, Name: "uno"})
+	assert.Equal(t, errDuplicateKey, result.Error)
+
+	result = orm.Create(&edge{"1", "2", "covers"})
+	assert.NoError(t, result.Error)
+
+	result = orm.Create(&edge{"1", "2", "powers"})
+	assert.NoError(t, result.Error)
+
+	result = orm.Create(&edge{"1", "2", "covers"})
+	assert.Equal(t, errDuplicateKey, result.Error)
+
+	result = orm.Create("invalid")
+	assert.Equal(t, errInvalidValue, result.Error)
+
+	// Records are copied on create
+	first.Name = "changed"
+	var got thing
+	assert.NoError(t, orm.Find(&got, "id = ?", "1").Error)
+	assert.Equal(t, "one", got.Name)
+}
+
+func TestMemoryORMFind(t *testing.T) {
+	orm := NewMemoryORM()
+	orm.Create(&thing{base: base{ID: "1", SiteID: "a"}, Name: "one", Count: 1})
+	orm.Create(&thing{base: base{ID: "2", SiteID: "a"}, Name: "two", Count: 2})
+	orm.Create(&thing{base: base{ID: "3", SiteID: "b"}, Name: "three", Count: 3})
+	orm.Create(&edge{"1", "2", "covers"})
+	orm.Create(&edge{"2", "3", "powers"})
+
+	tests := []struct {
+		name          string
+		where         []interface{}
+		expectedError error
+		expectedIDs   []string
+	}{
+		{"All", nil, nil, []string{"1", "2", "3"}},
+		{"Equal", []interface{}{"site_id = ?", "a"}, nil, []string{"1", "2"}},
+		{"NotEqual", []interface{}{"site_id <> ?", "a"}, nil, []string{"3"}},
+		{"UpperCaseColumn", []interface{}{"ID = ?", "2"}, nil, []string{"2"}},
+		{"In", []interface{}{"id IN (?)", []string{"1", "3", "9"}}, nil, []string{"1", "3"}},
+		{"And", []interface{}{"site_id = ? AND count = ?", "a", 2}, nil, []string{"2"}},
+		{"Or", []interface{}{"name = ? OR id = ?", "one", "3"}, nil, []string{"1", "3"}},
+		{"Parentheses", []interface{}{"(id = ? OR id = ?) AND site_id = ?", "1", "3", "b"}, nil, []string{"3"}},
+		{"NoMatch", []interface{}{"site_id = ?", "z"}, nil, []string{}},
+		{"UnknownColumn", []interface{}{"color = ?", "red"}, nil, nil},
+		{"MissingArgument", []interface{}{"id = ? AND name = ?", "1"}, errUnsupportedCond, nil},
+		{"ExtraArgument", []interface{}{"id = ?", "1", "2"}, errUnsupportedCond, nil},
+		{"UnsupportedOperator", []interface{}{"count > ?", 1}, errUnsupportedCond, nil},
+		{"UnsupportedCondition", []interface{}{thing{}}, errUnsupportedCond, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var things []thing
+			result := orm.Find(&things, tc.where...)
+
+			if tc.expectedIDs == nil {
+				assert.Error(t, result.Error)
+				if tc.expectedError != nil {
+					assert.Equal(t, tc.expectedError, result.Error)
+				}
+			} else {
+				assert.NoError(t, result.Error)
+				assert.Equal(t, int64(len(tc.expectedIDs)), result.RowsAffected)
+
+				ids := []string{}
+				for _, th := range things {
+					ids = append(ids, th.ID)
+				}
+				assert.Equal(t, tc.expectedIDs, ids)
+			}
+		})
+	}
+
+	t.Run("Struct", func(t *testing.T) {
+		th := &thing{}
+		result := orm.Find(th, "id = ?", "2")
+		assert.NoError(t, result.Error)
+		assert.Equal(t, "two", th.Name)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		th := &thing{}
+		result := orm.Find(th, "id = ?", "9")
+		assert.Equal(t, gorm.ErrRecordNotFound, result.Error)
+	})
+
+	t.Run("PointerSlice", func(t *testing.T) {
+		var things []*thing
+		result := orm.Find(&things, "site_id = ?", "b")
+		assert.NoError(t, result.Error)
+		assert.Len(t, things, 1)
+		assert.Equal(t, "three", things[0].Name)
+	})
+
+	t.Run("ColumnTag", func(t *testing.T) {
+		var edges []edge
+		result := orm.Find(&edges, "edge_kind IN (?) AND (from = ? OR to = ?)", []string{"covers"}, "2", "2")
+		assert.NoError(t, result.Error)
+		assert.Equal(t, []edge{{"1", "2", "covers"}}, edges)
+	})
+
+	t.Run("InvalidOut", func(t *testing.T) {
+		var n int
+		assert.Equal(t, errInvalidValue, orm.Find(&n).Error)
+		assert.Equal(t, errInvalidValue, orm.Find(thing{}).Error)
+	})
+}
+
+func TestMemoryORMUpdate(t *testing.T) {
+	orm := NewMemoryORM()
+	orm.Create(&thing{base: base{ID: "1", SiteID: "a"}, Name: "one", Count: 1})
+	orm.Create(&thing{base: base{ID: "2", SiteID: "a"}, Name: "two", Count: 2})
+
+	t.Run("Struct", func(t *testing.T) {
+		update := &thing{base: base{ID: "1"}, Name: "uno"}
+		result := orm.Update(update, update, "id = ?", "1")
+		assert.NoError(t, result.Error)
+		assert.Equal(t, int64(1), result.RowsAffected)
+
+		th := &thing{}
+		orm.Find(th, "id = ?", "1")
+		assert.Equal(t, thing{base: base{ID: "1", SiteID: "a"}, Name: "uno", Count: 1}, *th)
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		result := orm.Update(thing{}, map[string]interface{}{"count": 0}, "site_id = ?", "a")
+		assert.NoError(t, result.Error)
+		assert.Equal(t, int64(2), result.RowsAffected)
+
+		var things []thing
+		orm.Find(&things, "count = ?", 0)
+		assert.Len(t, things, 2)
+	})
+
+	t.Run("PrimaryKeyOfValue", func(t *testing.T) {
+		result := orm.Update(&thing{base: base{ID: "2"}}, map[string]interface{}{"name": "dos"})
+		assert.NoError(t, result.Error)
+		assert.Equal(t, int64(1), result.RowsAffected)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		update := &thing{base: base{ID: "9"}, Name: "nine"}
+		result := orm.Update(update, update, "id = ?", "9")
+		assert.NoError(t, result.Error)
+		assert.Equal(t, int64(0), result.RowsAffected)
+	})
+
+	t.Run("InvalidAttrs", func(t *testing.T) {
+		assert.Error(t, orm.Update(thing{}, map[string]interface{}{"color": "red"}).Error)
+		assert.Error(t, orm.Update(thing{}, map[string]interface{}{"count": "many"}).Error)
+		assert.Equal(t, errInvalidValue, orm.Update(thing{}, edge{}).Error)
+	})
+}
+
+func TestMemoryORMDelete(t *testing.T) {
+	orm := NewMemoryORM()
+	orm.Create(&edge{"1", "2", "covers"})
+	orm.Create(&edge{"2", "3", "covers"})
+	orm.Create(&edge{"3", "4", "powers"})
+
+	result := orm.Delete(edge{}, "from = ? OR to = ?", "2", "2")
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(2), result.RowsAffected)
+
+	result = orm.Delete(edge{}, "from = ?", "2")
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+
+	result = orm.Delete(edge{}, "from = ?")
+	assert.Equal(t, errUnsupportedCond, result.Error)
+
+	var edges []edge
+	orm.Find(&edges)
+	assert.Equal(t, []edge{{"3", "4", "powers"}}, edges)
+}
+
+func TestMemoryORMCount(t *testing.T) {
+	orm := NewMemoryORM()
+	orm.Create(&thing{base: base{ID: "1", SiteID: "a"}, Name: "one"})
+	orm.Create(&thing{base: base{ID: "2", SiteID: "a"}, Name: "two"})
+	orm.Create(&thing{base: base{ID: "3", SiteID: "b"}, Name: "three"})
+
+	var count int
+	assert.NoError(t, orm.Count(thing{}, &count).Error)
+	assert.Equal(t, 3, count)
+
+	assert.NoError(t, orm.Count(thing{}, &count, "site_id = ?", "a").Error)
+	assert.Equal(t, 2, count)
+
+	assert.NoError(t, orm.Count(thing{}, &count, "site_id = ?", "c").Error)
+	assert.Equal(t, 0, count)
+
+	assert.Equal(t, errUnsupportedCond, orm.Count(thing{}, &count, "site_id > ?", "a").Error)
+	assert.Equal(t, errInvalidValue, orm.Count("invalid", &count).Error)
+}
+
+func TestMemoryORMTransaction(t *testing.T) {
+	orm := NewMemoryORM()
+	orm.Create(&thing{base: base{ID: "1", SiteID: "a"}, Name: "one"})
+	orm.Create(&edge{"1", "2", "covers"})
+
+	t.Run("RolledBack", func(t *testing.T) {
+		err := orm.Transaction(func(tx ORM) error {
+			assert.NoError(t, tx.Update(thing{}, map[string]interface{}{"name": "uno"}, "id = ?", "1").Error)
+			assert.NoError(t, tx.Delete(edge{}, "from = ?", "1").Error)
+			return errUnsupportedCond
+		})
+		assert.Equal(t, errUnsupportedCond, err)
+
+		var got thing
+		assert.NoError(t, orm.Find(&got, "id = ?", "1").Error)
+		assert.Equal(t, "one", got.Name)
+
+		var count int
+		assert.NoError(t, orm.Count(edge{}, &count).Error)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("Committed", func(t *testing.T) {
+		err := orm.Transaction(func(tx ORM) error {
+			if err := tx.Update(thing{}, map[string]interface{}{"name": "uno"}, "id = ?", "1").Error; err != nil {
+				return err
+			}
+			return tx.Delete(edge{}, "from = ?", "1").Error
+		})
+		assert.NoError(t, err)
+
+		var got thing
+		assert.NoError(t, orm.Find(&got, "id = ?", "1").Error)
+		assert.Equal(t, "uno", got.Name)
+
+		var count int
+		assert.NoError(t, orm.Count(edge{}, &count).Error)
+		assert.Equal(t, 0, count)
+	})
+}
@@ -1,9 +1,36 @@
 package model
 
+// Asset types
+const (
+	AssetTypeAlarm  = "alarm"
+	AssetTypeCamera = "camera"
+)
+
+// Label formats
+const (
+	LabelFormatPNG = "png"
+	LabelFormatPDF = "pdf"
+)
+
+// Relation types between assets
+const (
+	RelationCovers    = "covers"
+	RelationPowers    = "powers"
+	RelationMountedOn = "mounted_on"
+)
+
+// Relation directions for traversing the relations graph
+const (
+	DirectionOutgoing = "outgoing"
+	DirectionIncoming = "incoming"
+	DirectionBoth     = "both"
+)
+
 type (
 	// Asset is the supertype for all assets
 	Asset struct {
 		ID       string `json:"id" gorm:"primary_key"`
+		TenantID string `json:"tenantId" gorm:"not null;index"`
 		SiteID   string `json:"siteId" gorm:"not null"`
 		SerialNo string `json:"serialNo" gorm:"not null"`
 	}
@@ -37,4 +64,26 @@ type (
 		AssetInput
 		Resolution int `json:"resolution"`
 	}
+
+	// Relation is a typed and directed relation from one asset to another
+	Relation struct {
+		SourceID string `json:"sourceId" gorm:"primary_key"`
+		TargetID string `json:"targetId" gorm:"primary_key"`
+		Type     string `json:"type" gorm:"primary_key"`
+		TenantID string `json:"tenantId" gorm:"not null;index"`
+	}
+
+	// RelatedAsset is an asset reached by traversing the relations graph
+	RelatedAsset struct {
+		ID       string   `json:"id"`
+		Depth    int      `json:"depth"`
+		Relation Relation `json:"relation"`
+	}
+
+	// Label is the printable label of an asset
+	Label struct {
+		AssetID  string `json:"assetId"`
+		Type     string `json:"type"`
+		SerialNo string `json:"serialNo"`
+	}
 )
@@ -7,8 +7,10 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
+	"github.com/moorara/microservices-demo/pkg/site"
 	"github.com/moorara/microservices-demo/services/asset/internal/db"
 	"github.com/moorara/microservices-demo/services/asset/internal/model"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
 	"github.com/moorara/microservices-demo/services/asset/pkg/log"
 	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
 	"github.com/opentracing/opentracing-go"
@@ -29,19 +31,25 @@ type (
 
 	alarmService struct {
 		orm     db.ORM
+		quota   int
+		sites   site.Client
 		logger  *log.Logger
 		metrics *metrics.Metrics
 		tracer  opentracing.Tracer
 	}
 )
 
-// NewAlarmService creates a new AlarmService object
-func NewAlarmService(orm db.ORM, logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer) AlarmService {
+// NewAlarmService creates a new AlarmService object.
+// quota is the maximum number of alarms a tenant can have and zero means no limit.
+// sites validates the site of new and updated alarms and nil disables the validation.
+func NewAlarmService(orm db.ORM, quota int, sites site.Client, logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer) AlarmService {
 	// Migrate the database table schema
-	orm.AutoMigrate(model.Alarm{})
+	orm.AutoMigrate(model.Alarm{}, model.Relation{})
 
 	return &alarmService{
 		orm:     orm,
+		quota:   quota,
+		sites:   sites,
 		logger:  logger,
 		metrics: metrics,
 		tracer:  tracer,
@@ -72,24 +80,52 @@ func (s *alarmService) exec(ctx context.Context, op, query string, fn func() err
 		span.LogFields(opentracingLog.String("message", "successful!"))
 	}
 
-	s.metrics.OpLatencyHist.WithLabelValues(op, success).Observe(latency)
-	s.metrics.OpLatencySumm.WithLabelValues(op, success).Observe(latency)
+	tenantID, _ := tenant.FromContext(ctx)
+	tenantLabel := s.metrics.Tenants.Label(tenantID)
+	s.metrics.OpLatencyHist.WithLabelValues(op, success, tenantLabel).Observe(latency)
+	s.metrics.OpLatencySumm.WithLabelValues(op, success, tenantLabel).Observe(latency)
 }
 
 func (s *alarmService) Create(ctx context.Context, input model.AlarmInput) (*model.Alarm, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	if s.sites != nil {
+		if err := s.sites.Validate(ctx, input.SiteID); err != nil {
+			return nil, err
+		}
+	}
+
 	var err error
 
 	alarm := &model.Alarm{
 		Asset: model.Asset{
 			ID:       uuid.New().String(),
+			TenantID: tenantID,
 			SiteID:   input.SiteID,
 			SerialNo: input.SerialNo,
 		},
 		Material: input.Material,
 	}
 
+	// The alarms are counted in the same transaction as the alarm is created, so concurrent creates cannot exceed the quota together
 	s.exec(ctx, "create_alarm", "gorm.Create", func() error {
-		err = s.orm.Create(&alarm).Error
+		err = s.orm.Transaction(func(tx db.ORM) error {
+			if s.quota > 0 {
+				var count int
+				if err := tx.Count(model.Alarm{}, &count, "tenant_id = ?", tenantID).Error; err != nil {
+					return err
+				}
+
+				if count >= s.quota {
+					return tenant.ErrQuotaExceeded
+				}
+			}
+
+			return tx.Create(&alarm).Error
+		})
 		return err
 	})
 
@@ -101,11 +137,16 @@ func (s *alarmService) Create(ctx context.Context, input model.AlarmInput) (*mod
 }
 
 func (s *alarmService) All(ctx context.Context, siteID string) ([]model.Alarm, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
 	var err error
 	var alarms []model.Alarm
 
 	s.exec(ctx, "all_alarms", "gorm.Find", func() error {
-		err = s.orm.Find(&alarms, "site_id = ?", siteID).Error
+		err = s.orm.Find(&alarms, "tenant_id = ? AND site_id = ?", tenantID, siteID).Error
 		return err
 	})
 
@@ -117,11 +158,16 @@ func (s *alarmService) All(ctx context.Context, siteID string) ([]model.Alarm, e
 }
 
 func (s *alarmService) Get(ctx context.Context, id string) (*model.Alarm, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
 	var err error
 	alarm := &model.Alarm{}
 
 	s.exec(ctx, "get_alarm", "gorm.Find", func() error {
-		err = s.orm.Find(alarm, "id = ?", id).Error
+		err = s.orm.Find(alarm, "tenant_id = ? AND id = ?", tenantID, id).Error
 		return err
 	})
 
@@ -133,11 +179,23 @@ func (s *alarmService) Get(ctx context.Context, id string) (*model.Alarm, error)
 }
 
 func (s *alarmService) Update(ctx context.Context, id string, input model.AlarmInput) (bool, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return false, tenant.ErrNoTenant
+	}
+
+	if s.sites != nil {
+		if err := s.sites.Validate(ctx, input.SiteID); err != nil {
+			return false, err
+		}
+	}
+
 	var result *gorm.DB
 
 	alarm := &model.Alarm{
 		Asset: model.Asset{
 			ID:       id,
+			TenantID: tenantID,
 			SiteID:   input.SiteID,
 			SerialNo: input.SerialNo,
 		},
@@ -145,7 +203,7 @@ func (s *alarmService) Update(ctx context.Context, id string, input model.AlarmI
 	}
 
 	s.exec(ctx, "update_alarm", "gorm.Model.Where.Update", func() error {
-		result = s.orm.Model(alarm).Where("id = ?", id).Update(alarm)
+		result = s.orm.Update(alarm, alarm, "tenant_id = ? AND id = ?", tenantID, id)
 		return result.Error
 	})
 
@@ -157,16 +215,34 @@ func (s *alarmService) Update(ctx context.Context, id string, input model.AlarmI
 }
 
 func (s *alarmService) Delete(ctx context.Context, id string) (bool, error) {
-	var result *gorm.DB
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return false, tenant.ErrNoTenant
+	}
+
+	var err error
+	var deleted bool
 
+	// The relations are deleted in the same transaction as the alarm, so no relation is left to a deleted alarm
 	s.exec(ctx, "delete_alarm", "gorm.Delete", func() error {
-		result = s.orm.Delete(model.Alarm{}, "id = ?", id)
-		return result.Error
+		err = s.orm.Transaction(func(tx db.ORM) error {
+			result := tx.Delete(model.Alarm{}, "tenant_id = ? AND id = ?", tenantID, id)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if deleted = result.RowsAffected == 1; !deleted {
+				return nil
+			}
+
+			return deleteRelations(tx, tenantID, id)
+		})
+		return err
 	})
 
-	if err := result.Error; err != nil {
+	if err != nil {
 		return false, err
 	}
 
-	return result.RowsAffected == 1, nil
+	return deleted, nil
 }
@@ -3,12 +3,15 @@ package service
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 
 	"github.com/jinzhu/gorm"
 
+	"github.com/moorara/microservices-demo/pkg/site"
 	"github.com/moorara/microservices-demo/services/asset/internal/db"
 	"github.com/moorara/microservices-demo/services/asset/internal/model"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
 	"github.com/moorara/microservices-demo/services/asset/pkg/log"
 	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
 	"github.com/opentracing/opentracing-go/mocktracer"
@@ -31,7 +34,7 @@ func TestNewAlarmService(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := NewAlarmService(tc.orm, logger, metrics, tracer)
+			service := NewAlarmService(tc.orm, 0, nil, logger, metrics, tracer)
 
 			assert.NotNil(t, service)
 		})
@@ -42,10 +45,70 @@ func TestAlarmServiceCreate(t *testing.T) {
 	tests := []struct {
 		name          string
 		orm           db.ORM
+		quota         int
+		sites         site.Client
 		ctx           context.Context
 		input         model.AlarmInput
 		expectedError error
+		expectedOps   []string
 	}{
+		{
+			"NoTenant",
+			&mockORM{},
+			0,
+			nil,
+			contextWithSpanForTenant(""),
+			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "smoke"},
+			tenant.ErrNoTenant,
+			[]string{},
+		},
+		{
+			"UnknownSite",
+			&mockORM{},
+			0,
+			&mockSiteClient{ValidateOutError: site.ErrUnknownSite},
+			contextWithSpan(),
+			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "smoke"},
+			site.ErrUnknownSite,
+			[]string{},
+		},
+		{
+			"SiteServiceUnavailable",
+			&mockORM{},
+			0,
+			&mockSiteClient{ValidateOutError: site.ErrUnavailable},
+			contextWithSpan(),
+			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "smoke"},
+			site.ErrUnavailable,
+			[]string{},
+		},
+		{
+			"CountError",
+			&mockORM{
+				CountOutDB: &gorm.DB{
+					Error: errors.New("count error"),
+				},
+			},
+			10,
+			nil,
+			contextWithSpan(),
+			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "smoke"},
+			errors.New("count error"),
+			[]string{"create_alarm"},
+		},
+		{
+			"QuotaExceeded",
+			&mockORM{
+				CountOutCount: 10,
+				CountOutDB:    &gorm.DB{},
+			},
+			10,
+			nil,
+			contextWithSpan(),
+			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "smoke"},
+			tenant.ErrQuotaExceeded,
+			[]string{"create_alarm"},
+		},
 		{
 			"DatabaseError",
 			&mockORM{
@@ -53,18 +116,38 @@ func TestAlarmServiceCreate(t *testing.T) {
 					Error: errors.New("create error"),
 				},
 			},
+			0,
+			nil,
 			contextWithSpan(),
 			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "smoke"},
 			errors.New("create error"),
+			[]string{"create_alarm"},
 		},
 		{
 			"Success",
 			&mockORM{
 				CreateOutDB: &gorm.DB{},
 			},
+			0,
+			&mockSiteClient{},
+			contextWithSpan(),
+			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "smoke"},
+			nil,
+			[]string{"create_alarm"},
+		},
+		{
+			"SuccessWithinQuota",
+			&mockORM{
+				CountOutCount: 9,
+				CountOutDB:    &gorm.DB{},
+				CreateOutDB:   &gorm.DB{},
+			},
+			10,
+			nil,
 			contextWithSpan(),
 			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "smoke"},
 			nil,
+			[]string{"create_alarm"},
 		},
 	}
 
@@ -73,18 +156,34 @@ func TestAlarmServiceCreate(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := &alarmService{tc.orm, logger, metrics, tracer}
+			service := &alarmService{tc.orm, tc.quota, tc.sites, logger, metrics, tracer}
 
-			_, err := service.Create(tc.ctx, tc.input)
+			alarm, err := service.Create(tc.ctx, tc.input)
 			assert.Equal(t, tc.expectedError, err)
 
+			if tc.expectedError == nil {
+				assert.Equal(t, testTenantID, alarm.TenantID)
+			}
+
+			ops := []string{}
+			for _, span := range tracer.FinishedSpans() {
+				ops = append(ops, span.OperationName)
+			}
+			assert.Equal(t, tc.expectedOps, ops)
+
 			// Verify trace span
-			span := tracer.FinishedSpans()[0]
-			assert.Equal(t, "create_alarm", span.OperationName)
-			assert.Equal(t, "sql", span.Tag("db.type"))
-			assert.Equal(t, "gorm.Create", span.Tag("db.statement"))
-			assert.Equal(t, "event", span.Logs()[0].Fields[0].Key)
-			assert.Equal(t, "create_alarm", span.Logs()[0].Fields[0].ValueString)
+			for _, span := range tracer.FinishedSpans() {
+				assert.Equal(t, "sql", span.Tag("db.type"))
+				assert.Equal(t, "event", span.Logs()[0].Fields[0].Key)
+				assert.Equal(t, span.OperationName, span.Logs()[0].Fields[0].ValueString)
+				assert.Equal(t, "gorm.Create", span.Tag("db.statement"))
+			}
+
+			// The quota is checked in the transaction creating the alarm
+			if orm, ok := tc.orm.(*mockORM); ok && tc.quota > 0 && len(tc.expectedOps) > 0 {
+				assert.True(t, orm.TransactionCalled)
+				assert.Equal(t, tc.expectedError == nil, orm.CreateCalled)
+			}
 		})
 	}
 }
@@ -97,6 +196,13 @@ func TestAlarmServiceAll(t *testing.T) {
 		siteID        string
 		expectedError error
 	}{
+		{
+			"NoTenant",
+			&mockORM{},
+			contextWithSpanForTenant(""),
+			"1111-1111",
+			tenant.ErrNoTenant,
+		},
 		{
 			"DatabaseError",
 			&mockORM{
@@ -124,11 +230,16 @@ func TestAlarmServiceAll(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := &alarmService{tc.orm, logger, metrics, tracer}
+			service := &alarmService{tc.orm, 0, nil, logger, metrics, tracer}
 
 			_, err := service.All(tc.ctx, tc.siteID)
 			assert.Equal(t, tc.expectedError, err)
 
+			if tc.expectedError == tenant.ErrNoTenant {
+				assert.Empty(t, tracer.FinishedSpans())
+				return
+			}
+
 			// Verify trace span
 			span := tracer.FinishedSpans()[0]
 			assert.Equal(t, "all_alarms", span.OperationName)
@@ -148,6 +259,13 @@ func TestAlarmServiceGet(t *testing.T) {
 		id            string
 		expectedError error
 	}{
+		{
+			"NoTenant",
+			&mockORM{},
+			contextWithSpanForTenant(""),
+			"aaaa-aaaa",
+			tenant.ErrNoTenant,
+		},
 		{
 			"DatabaseError",
 			&mockORM{
@@ -175,11 +293,16 @@ func TestAlarmServiceGet(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := &alarmService{tc.orm, logger, metrics, tracer}
+			service := &alarmService{tc.orm, 0, nil, logger, metrics, tracer}
 
 			_, err := service.Get(tc.ctx, tc.id)
 			assert.Equal(t, tc.expectedError, err)
 
+			if tc.expectedError == tenant.ErrNoTenant {
+				assert.Empty(t, tracer.FinishedSpans())
+				return
+			}
+
 			// Verify trace span
 			span := tracer.FinishedSpans()[0]
 			assert.Equal(t, "get_alarm", span.OperationName)
@@ -195,30 +318,75 @@ func TestAlarmServiceUpdate(t *testing.T) {
 	tests := []struct {
 		name           string
 		orm            db.ORM
+		sites          site.Client
 		ctx            context.Context
 		id             string
 		input          model.AlarmInput
 		expectedError  error
 		expectedResult bool
 	}{
-		/* {
-			"DatabaseError",
+		{
+			"NoTenant",
 			&mockORM{},
-			CreateContextWithSpan(),
+			nil,
+			contextWithSpanForTenant(""),
+			"aaaa-aaaa",
+			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "smoke"},
+			tenant.ErrNoTenant,
+			false,
+		},
+		{
+			"UnknownSite",
+			&mockORM{},
+			&mockSiteClient{ValidateOutError: site.ErrUnknownSite},
+			contextWithSpan(),
+			"aaaa-aaaa",
+			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "smoke"},
+			site.ErrUnknownSite,
+			false,
+		},
+		{
+			"DatabaseError",
+			&mockORM{
+				UpdateOutDB: &gorm.DB{
+					Error: errors.New("update error"),
+				},
+			},
+			nil,
+			contextWithSpan(),
 			"aaaa-aaaa",
 			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1002"}, Material: "co"},
-			errors.New(""),
+			errors.New("update error"),
+			false,
+		},
+		{
+			"NotFound",
+			&mockORM{
+				UpdateOutDB: &gorm.DB{
+					RowsAffected: 0,
+				},
+			},
+			nil,
+			contextWithSpan(),
+			"aaaa-aaaa",
+			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1002"}, Material: "co"},
+			nil,
 			false,
 		},
 		{
 			"Success",
-			&mockORM{},
-			CreateContextWithSpan(),
+			&mockORM{
+				UpdateOutDB: &gorm.DB{
+					RowsAffected: 1,
+				},
+			},
+			nil,
+			contextWithSpan(),
 			"aaaa-aaaa",
 			model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1002"}, Material: "co"},
 			nil,
 			true,
-		}, */
+		},
 	}
 
 	for _, tc := range tests {
@@ -226,12 +394,17 @@ func TestAlarmServiceUpdate(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := &alarmService{tc.orm, logger, metrics, tracer}
+			service := &alarmService{tc.orm, 0, tc.sites, logger, metrics, tracer}
 
 			result, err := service.Update(tc.ctx, tc.id, tc.input)
 			assert.Equal(t, tc.expectedError, err)
 			assert.Equal(t, tc.expectedResult, result)
 
+			if tc.expectedError == tenant.ErrNoTenant || tc.expectedError == site.ErrUnknownSite {
+				assert.Empty(t, tracer.FinishedSpans())
+				return
+			}
+
 			// Verify trace span
 			span := tracer.FinishedSpans()[0]
 			assert.Equal(t, "update_alarm", span.OperationName)
@@ -252,6 +425,14 @@ func TestAlarmServiceDelete(t *testing.T) {
 		expectedError  error
 		expectedResult bool
 	}{
+		{
+			"NoTenant",
+			&mockORM{},
+			contextWithSpanForTenant(""),
+			"aaaa-aaaa",
+			tenant.ErrNoTenant,
+			false,
+		},
 		{
 			"DatabaseError",
 			&mockORM{
@@ -264,6 +445,31 @@ func TestAlarmServiceDelete(t *testing.T) {
 			errors.New("delete error"),
 			false,
 		},
+		{
+			"NotFound",
+			&mockORM{
+				DeleteOutDB: &gorm.DB{
+					RowsAffected: 0,
+				},
+			},
+			contextWithSpan(),
+			"aaaa-aaaa",
+			nil,
+			false,
+		},
+		{
+			"TransactionError",
+			&mockORM{
+				DeleteOutDB: &gorm.DB{
+					RowsAffected: 1,
+				},
+				TransactionOutError: errors.New("commit error"),
+			},
+			contextWithSpan(),
+			"aaaa-aaaa",
+			errors.New("commit error"),
+			false,
+		},
 		{
 			"Success",
 			&mockORM{
@@ -283,12 +489,17 @@ func TestAlarmServiceDelete(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := &alarmService{tc.orm, logger, metrics, tracer}
+			service := &alarmService{tc.orm, 0, nil, logger, metrics, tracer}
 
 			result, err := service.Delete(tc.ctx, tc.id)
 			assert.Equal(t, tc.expectedError, err)
 			assert.Equal(t, tc.expectedResult, result)
 
+			if tc.expectedError == tenant.ErrNoTenant {
+				assert.Empty(t, tracer.FinishedSpans())
+				return
+			}
+
 			// Verify trace span
 			span := tracer.FinishedSpans()[0]
 			assert.Equal(t, "delete_alarm", span.OperationName)
@@ -296,6 +507,102 @@ func TestAlarmServiceDelete(t *testing.T) {
 			assert.Equal(t, "gorm.Delete", span.Tag("db.statement"))
 			assert.Equal(t, "event", span.Logs()[0].Fields[0].Key)
 			assert.Equal(t, "delete_alarm", span.Logs()[0].Fields[0].ValueString)
+
+			// Verify the relations are deleted in the same transaction
+			assert.Len(t, tracer.FinishedSpans(), 1)
+			orm := tc.orm.(*mockORM)
+			assert.True(t, orm.TransactionCalled)
+			if tc.expectedResult || orm.TransactionOutError != nil {
+				assert.Equal(t, model.Relation{}, orm.DeleteInValue)
+			}
 		})
 	}
 }
+
+func TestAlarmServiceMemoryORM(t *testing.T) {
+	logger := log.NewNopLogger()
+	metrics := metrics.New("unit-test")
+	tracer := mocktracer.New()
+	service := NewAlarmService(db.NewMemoryORM(), 2, nil, logger, metrics, tracer)
+
+	alarm, err := service.Create(contextWithSpan(), model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "co"})
+	assert.NoError(t, err)
+
+	_, err = service.Create(contextWithSpan(), model.AlarmInput{AssetInput: model.AssetInput{SiteID: "2222-2222", SerialNo: "1002"}, Material: "smoke"})
+	assert.NoError(t, err)
+
+	alarms, err := service.All(contextWithSpan(), "1111-1111")
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Alarm{*alarm}, alarms)
+
+	result, err := service.Get(contextWithSpan(), alarm.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, alarm, result)
+
+	_, err = service.Get(contextWithSpan(), "ffff-ffff")
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+	updated, err := service.Update(contextWithSpan(), alarm.ID, model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "smoke"})
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	result, err = service.Get(contextWithSpan(), alarm.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "smoke", result.Material)
+
+	updated, err = service.Update(contextWithSpan(), "ffff-ffff", model.AlarmInput{Material: "co"})
+	assert.NoError(t, err)
+	assert.False(t, updated)
+
+	_, err = service.Create(contextWithSpan(), model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1003"}, Material: "co"})
+	assert.Equal(t, tenant.ErrQuotaExceeded, err)
+
+	// Another tenant can neither see nor change the alarms
+	other := contextWithSpanForTenant("uuuu-uuuu")
+
+	alarms, err = service.All(other, "1111-1111")
+	assert.NoError(t, err)
+	assert.Empty(t, alarms)
+
+	_, err = service.Get(other, alarm.ID)
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+	updated, err = service.Update(other, alarm.ID, model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "co"})
+	assert.NoError(t, err)
+	assert.False(t, updated)
+
+	deleted, err := service.Delete(other, alarm.ID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+
+	otherAlarm, err := service.Create(other, model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1003"}, Material: "co"})
+	assert.NoError(t, err)
+	assert.Equal(t, "uuuu-uuuu", otherAlarm.TenantID)
+
+	deleted, err = service.Delete(contextWithSpan(), alarm.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = service.Delete(contextWithSpan(), alarm.ID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestAlarmServiceConcurrentQuota(t *testing.T) {
+	orm := db.NewMemoryORM()
+	service := NewAlarmService(orm, 5, nil, log.NewNopLogger(), metrics.New("unit-test"), mocktracer.New())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = service.Create(contextWithSpan(), model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}, Material: "co"})
+		}()
+	}
+	wg.Wait()
+
+	var count int
+	assert.NoError(t, orm.Count(model.Alarm{}, &count, "tenant_id = ?", testTenantID).Error)
+	assert.Equal(t, 5, count)
+}
@@ -7,8 +7,10 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
+	"github.com/moorara/microservices-demo/pkg/site"
 	"github.com/moorara/microservices-demo/services/asset/internal/db"
 	"github.com/moorara/microservices-demo/services/asset/internal/model"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
 	"github.com/moorara/microservices-demo/services/asset/pkg/log"
 	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
 	"github.com/opentracing/opentracing-go"
@@ -29,19 +31,25 @@ type (
 
 	cameraService struct {
 		orm     db.ORM
+		quota   int
+		sites   site.Client
 		logger  *log.Logger
 		metrics *metrics.Metrics
 		tracer  opentracing.Tracer
 	}
 )
 
-// NewCameraService creates a new CameraService object
-func NewCameraService(orm db.ORM, logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer) CameraService {
+// NewCameraService creates a new CameraService object.
+// quota is the maximum number of cameras a tenant can have and zero means no limit.
+// sites validates the site of new and updated cameras and nil disables the validation.
+func NewCameraService(orm db.ORM, quota int, sites site.Client, logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer) CameraService {
 	// Migrate the database table schema
-	orm.AutoMigrate(model.Camera{})
+	orm.AutoMigrate(model.Camera{}, model.Relation{})
 
 	return &cameraService{
 		orm:     orm,
+		quota:   quota,
+		sites:   sites,
 		logger:  logger,
 		metrics: metrics,
 		tracer:  tracer,
@@ -72,24 +80,52 @@ func (s *cameraService) exec(ctx context.Context, op, query string, fn func() er
 		span.LogFields(opentracingLog.String("message", "successful!"))
 	}
 
-	s.metrics.OpLatencyHist.WithLabelValues(op, success).Observe(latency)
-	s.metrics.OpLatencySumm.WithLabelValues(op, success).Observe(latency)
+	tenantID, _ := tenant.FromContext(ctx)
+	tenantLabel := s.metrics.Tenants.Label(tenantID)
+	s.metrics.OpLatencyHist.WithLabelValues(op, success, tenantLabel).Observe(latency)
+	s.metrics.OpLatencySumm.WithLabelValues(op, success, tenantLabel).Observe(latency)
 }
 
 func (s *cameraService) Create(ctx context.Context, input model.CameraInput) (*model.Camera, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	if s.sites != nil {
+		if err := s.sites.Validate(ctx, input.SiteID); err != nil {
+			return nil, err
+		}
+	}
+
 	var err error
 
 	camera := &model.Camera{
 		Asset: model.Asset{
 			ID:       uuid.New().String(),
+			TenantID: tenantID,
 			SiteID:   input.SiteID,
 			SerialNo: input.SerialNo,
 		},
 		Resolution: input.Resolution,
 	}
 
+	// The cameras are counted in the same transaction as the camera is created, so concurrent creates cannot exceed the quota together
 	s.exec(ctx, "create_camera", "gorm.Create", func() error {
-		err = s.orm.Create(&camera).Error
+		err = s.orm.Transaction(func(tx db.ORM) error {
+			if s.quota > 0 {
+				var count int
+				if err := tx.Count(model.Camera{}, &count, "tenant_id = ?", tenantID).Error; err != nil {
+					return err
+				}
+
+				if count >= s.quota {
+					return tenant.ErrQuotaExceeded
+				}
+			}
+
+			return tx.Create(&camera).Error
+		})
 		return err
 	})
 
@@ -101,11 +137,16 @@ func (s *cameraService) Create(ctx context.Context, input model.CameraInput) (*m
 }
 
 func (s *cameraService) All(ctx context.Context, siteID string) ([]model.Camera, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
 	var err error
 	var cameras []model.Camera
 
 	s.exec(ctx, "all_cameras", "gorm.Find", func() error {
-		err = s.orm.Find(&cameras, "site_id = ?", siteID).Error
+		err = s.orm.Find(&cameras, "tenant_id = ? AND site_id = ?", tenantID, siteID).Error
 		return err
 	})
 
@@ -117,11 +158,16 @@ func (s *cameraService) All(ctx context.Context, siteID string) ([]model.Camera,
 }
 
 func (s *cameraService) Get(ctx context.Context, id string) (*model.Camera, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
 	var err error
 	camera := &model.Camera{}
 
 	s.exec(ctx, "get_camera", "gorm.Find", func() error {
-		err = s.orm.Find(camera, "id = ?", id).Error
+		err = s.orm.Find(camera, "tenant_id = ? AND id = ?", tenantID, id).Error
 		return err
 	})
 
@@ -133,11 +179,23 @@ func (s *cameraService) Get(ctx context.Context, id string) (*model.Camera, erro
 }
 
 func (s *cameraService) Update(ctx context.Context, id string, input model.CameraInput) (bool, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return false, tenant.ErrNoTenant
+	}
+
+	if s.sites != nil {
+		if err := s.sites.Validate(ctx, input.SiteID); err != nil {
+			return false, err
+		}
+	}
+
 	var result *gorm.DB
 
 	camera := &model.Camera{
 		Asset: model.Asset{
 			ID:       id,
+			TenantID: tenantID,
 			SiteID:   input.SiteID,
 			SerialNo: input.SerialNo,
 		},
@@ -145,7 +203,7 @@ func (s *cameraService) Update(ctx context.Context, id string, input model.Camer
 	}
 
 	s.exec(ctx, "update_camera", "gorm.Model.Where.Update", func() error {
-		result = s.orm.Model(camera).Where("id = ?", id).Update(camera)
+		result = s.orm.Update(camera, camera, "tenant_id = ? AND id = ?", tenantID, id)
 		return result.Error
 	})
 
@@ -157,16 +215,34 @@ func (s *cameraService) Update(ctx context.Context, id string, input model.Camer
 }
 
 func (s *cameraService) Delete(ctx context.Context, id string) (bool, error) {
-	var result *gorm.DB
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return false, tenant.ErrNoTenant
+	}
+
+	var err error
+	var deleted bool
 
+	// The relations are deleted in the same transaction as the camera, so no relation is left to a deleted camera
 	s.exec(ctx, "delete_camera", "gorm.Delete", func() error {
-		result = s.orm.Delete(model.Camera{}, "id = ?", id)
-		return result.Error
+		err = s.orm.Transaction(func(tx db.ORM) error {
+			result := tx.Delete(model.Camera{}, "tenant_id = ? AND id = ?", tenantID, id)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if deleted = result.RowsAffected == 1; !deleted {
+				return nil
+			}
+
+			return deleteRelations(tx, tenantID, id)
+		})
+		return err
 	})
 
-	if err := result.Error; err != nil {
+	if err != nil {
 		return false, err
 	}
 
-	return result.RowsAffected == 1, nil
+	return deleted, nil
 }
@@ -6,8 +6,10 @@ import (
 	"testing"
 
 	"github.com/jinzhu/gorm"
+	"github.com/moorara/microservices-demo/pkg/site"
 	"github.com/moorara/microservices-demo/services/asset/internal/db"
 	"github.com/moorara/microservices-demo/services/asset/internal/model"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
 	"github.com/moorara/microservices-demo/services/asset/pkg/log"
 	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
 	"github.com/opentracing/opentracing-go/mocktracer"
@@ -30,7 +32,7 @@ func TestNewCameraService(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := NewCameraService(tc.orm, logger, metrics, tracer)
+			service := NewCameraService(tc.orm, 0, nil, logger, metrics, tracer)
 
 			assert.NotNil(t, service)
 		})
@@ -41,10 +43,70 @@ func TestCameraServiceCreate(t *testing.T) {
 	tests := []struct {
 		name          string
 		orm           db.ORM
+		quota         int
+		sites         site.Client
 		ctx           context.Context
 		input         model.CameraInput
 		expectedError error
+		expectedOps   []string
 	}{
+		{
+			"NoTenant",
+			&mockORM{},
+			0,
+			nil,
+			contextWithSpanForTenant(""),
+			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2001"}, Resolution: 1920000},
+			tenant.ErrNoTenant,
+			[]string{},
+		},
+		{
+			"UnknownSite",
+			&mockORM{},
+			0,
+			&mockSiteClient{ValidateOutError: site.ErrUnknownSite},
+			contextWithSpan(),
+			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2001"}, Resolution: 1920000},
+			site.ErrUnknownSite,
+			[]string{},
+		},
+		{
+			"SiteServiceUnavailable",
+			&mockORM{},
+			0,
+			&mockSiteClient{ValidateOutError: site.ErrUnavailable},
+			contextWithSpan(),
+			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2001"}, Resolution: 1920000},
+			site.ErrUnavailable,
+			[]string{},
+		},
+		{
+			"CountError",
+			&mockORM{
+				CountOutDB: &gorm.DB{
+					Error: errors.New("count error"),
+				},
+			},
+			10,
+			nil,
+			contextWithSpan(),
+			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2001"}, Resolution: 1920000},
+			errors.New("count error"),
+			[]string{"create_camera"},
+		},
+		{
+			"QuotaExceeded",
+			&mockORM{
+				CountOutCount: 10,
+				CountOutDB:    &gorm.DB{},
+			},
+			10,
+			nil,
+			contextWithSpan(),
+			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2001"}, Resolution: 1920000},
+			tenant.ErrQuotaExceeded,
+			[]string{"create_camera"},
+		},
 		{
 			"DatabaseError",
 			&mockORM{
@@ -52,18 +114,38 @@ func TestCameraServiceCreate(t *testing.T) {
 					Error: errors.New("create error"),
 				},
 			},
+			0,
+			nil,
 			contextWithSpan(),
 			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2001"}, Resolution: 1920000},
 			errors.New("create error"),
+			[]string{"create_camera"},
 		},
 		{
 			"Success",
 			&mockORM{
 				CreateOutDB: &gorm.DB{},
 			},
+			0,
+			&mockSiteClient{},
 			contextWithSpan(),
 			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2001"}, Resolution: 1920000},
 			nil,
+			[]string{"create_camera"},
+		},
+		{
+			"SuccessWithinQuota",
+			&mockORM{
+				CountOutCount: 9,
+				CountOutDB:    &gorm.DB{},
+				CreateOutDB:   &gorm.DB{},
+			},
+			10,
+			nil,
+			contextWithSpan(),
+			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2001"}, Resolution: 1920000},
+			nil,
+			[]string{"create_camera"},
 		},
 	}
 
@@ -72,18 +154,34 @@ func TestCameraServiceCreate(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := &cameraService{tc.orm, logger, metrics, tracer}
+			service := &cameraService{tc.orm, tc.quota, tc.sites, logger, metrics, tracer}
 
-			_, err := service.Create(tc.ctx, tc.input)
+			camera, err := service.Create(tc.ctx, tc.input)
 			assert.Equal(t, tc.expectedError, err)
 
+			if tc.expectedError == nil {
+				assert.Equal(t, testTenantID, camera.TenantID)
+			}
+
+			ops := []string{}
+			for _, span := range tracer.FinishedSpans() {
+				ops = append(ops, span.OperationName)
+			}
+			assert.Equal(t, tc.expectedOps, ops)
+
 			// Verify trace span
-			span := tracer.FinishedSpans()[0]
-			assert.Equal(t, "create_camera", span.OperationName)
-			assert.Equal(t, "sql", span.Tag("db.type"))
-			assert.Equal(t, "gorm.Create", span.Tag("db.statement"))
-			assert.Equal(t, "event", span.Logs()[0].Fields[0].Key)
-			assert.Equal(t, "create_camera", span.Logs()[0].Fields[0].ValueString)
+			for _, span := range tracer.FinishedSpans() {
+				assert.Equal(t, "sql", span.Tag("db.type"))
+				assert.Equal(t, "event", span.Logs()[0].Fields[0].Key)
+				assert.Equal(t, span.OperationName, span.Logs()[0].Fields[0].ValueString)
+				assert.Equal(t, "gorm.Create", span.Tag("db.statement"))
+			}
+
+			// The quota is checked in the transaction creating the camera
+			if orm, ok := tc.orm.(*mockORM); ok && tc.quota > 0 && len(tc.expectedOps) > 0 {
+				assert.True(t, orm.TransactionCalled)
+				assert.Equal(t, tc.expectedError == nil, orm.CreateCalled)
+			}
 		})
 	}
 }
@@ -96,6 +194,13 @@ func TestCameraServiceAll(t *testing.T) {
 		siteID        string
 		expectedError error
 	}{
+		{
+			"NoTenant",
+			&mockORM{},
+			contextWithSpanForTenant(""),
+			"1111-1111",
+			tenant.ErrNoTenant,
+		},
 		{
 			"DatabaseError",
 			&mockORM{
@@ -123,11 +228,16 @@ func TestCameraServiceAll(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := &cameraService{tc.orm, logger, metrics, tracer}
+			service := &cameraService{tc.orm, 0, nil, logger, metrics, tracer}
 
 			_, err := service.All(tc.ctx, tc.siteID)
 			assert.Equal(t, tc.expectedError, err)
 
+			if tc.expectedError == tenant.ErrNoTenant {
+				assert.Empty(t, tracer.FinishedSpans())
+				return
+			}
+
 			// Verify trace span
 			span := tracer.FinishedSpans()[0]
 			assert.Equal(t, "all_cameras", span.OperationName)
@@ -147,6 +257,13 @@ func TestCameraServiceGet(t *testing.T) {
 		id            string
 		expectedError error
 	}{
+		{
+			"NoTenant",
+			&mockORM{},
+			contextWithSpanForTenant(""),
+			"aaaa-aaaa",
+			tenant.ErrNoTenant,
+		},
 		{
 			"DatabaseError",
 			&mockORM{
@@ -174,11 +291,16 @@ func TestCameraServiceGet(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := &cameraService{tc.orm, logger, metrics, tracer}
+			service := &cameraService{tc.orm, 0, nil, logger, metrics, tracer}
 
 			_, err := service.Get(tc.ctx, tc.id)
 			assert.Equal(t, tc.expectedError, err)
 
+			if tc.expectedError == tenant.ErrNoTenant {
+				assert.Empty(t, tracer.FinishedSpans())
+				return
+			}
+
 			// Verify trace span
 			span := tracer.FinishedSpans()[0]
 			assert.Equal(t, "get_camera", span.OperationName)
@@ -194,30 +316,75 @@ func TestCameraServiceUpdate(t *testing.T) {
 	tests := []struct {
 		name           string
 		orm            db.ORM
+		sites          site.Client
 		ctx            context.Context
 		id             string
 		input          model.CameraInput
 		expectedError  error
 		expectedResult bool
 	}{
-		/* {
-			"DatabaseError",
+		{
+			"NoTenant",
+			&mockORM{},
+			nil,
+			contextWithSpanForTenant(""),
+			"aaaa-aaaa",
+			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2001"}, Resolution: 1920000},
+			tenant.ErrNoTenant,
+			false,
+		},
+		{
+			"UnknownSite",
 			&mockORM{},
-			CreateContextWithSpan(),
+			&mockSiteClient{ValidateOutError: site.ErrUnknownSite},
+			contextWithSpan(),
+			"aaaa-aaaa",
+			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2001"}, Resolution: 1920000},
+			site.ErrUnknownSite,
+			false,
+		},
+		{
+			"DatabaseError",
+			&mockORM{
+				UpdateOutDB: &gorm.DB{
+					Error: errors.New("update error"),
+				},
+			},
+			nil,
+			contextWithSpan(),
+			"bbbb-bbbb",
+			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2002"}, Resolution: 4915200},
+			errors.New("update error"),
+			false,
+		},
+		{
+			"NotFound",
+			&mockORM{
+				UpdateOutDB: &gorm.DB{
+					RowsAffected: 0,
+				},
+			},
+			nil,
+			contextWithSpan(),
 			"bbbb-bbbb",
 			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2002"}, Resolution: 4915200},
-			errors.New(""),
+			nil,
 			false,
 		},
 		{
 			"Success",
-			&mockORM{},
-			CreateContextWithSpan(),
+			&mockORM{
+				UpdateOutDB: &gorm.DB{
+					RowsAffected: 1,
+				},
+			},
+			nil,
+			contextWithSpan(),
 			"bbbb-bbbb",
 			model.CameraInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "2002"}, Resolution: 4915200},
 			nil,
 			true,
-		}, */
+		},
 	}
 
 	for _, tc := range tests {
@@ -225,12 +392,17 @@ func TestCameraServiceUpdate(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := &cameraService{tc.orm, logger, metrics, tracer}
+			service := &cameraService{tc.orm, 0, tc.sites, logger, metrics, tracer}
 
 			result, err := service.Update(tc.ctx, tc.id, tc.input)
 			assert.Equal(t, tc.expectedError, err)
 			assert.Equal(t, tc.expectedResult, result)
 
+			if tc.expectedError == tenant.ErrNoTenant || tc.expectedError == site.ErrUnknownSite {
+				assert.Empty(t, tracer.FinishedSpans())
+				return
+			}
+
 			// Verify trace span
 			span := tracer.FinishedSpans()[0]
 			assert.Equal(t, "update_camera", span.OperationName)
@@ -251,6 +423,14 @@ func TestCameraServiceDelete(t *testing.T) {
 		expectedError  error
 		expectedResult bool
 	}{
+		{
+			"NoTenant",
+			&mockORM{},
+			contextWithSpanForTenant(""),
+			"aaaa-aaaa",
+			tenant.ErrNoTenant,
+			false,
+		},
 		{
 			"DatabaseError",
 			&mockORM{
@@ -263,6 +443,31 @@ func TestCameraServiceDelete(t *testing.T) {
 			errors.New("delete error"),
 			false,
 		},
+		{
+			"NotFound",
+			&mockORM{
+				DeleteOutDB: &gorm.DB{
+					RowsAffected: 0,
+				},
+			},
+			contextWithSpan(),
+			"aaaa-aaaa",
+			nil,
+			false,
+		},
+		{
+			"TransactionError",
+			&mockORM{
+				DeleteOutDB: &gorm.DB{
+					RowsAffected: 1,
+				},
+				TransactionOutError: errors.New("commit error"),
+			},
+			contextWithSpan(),
+			"bbbb-bbbb",
+			errors.New("commit error"),
+			false,
+		},
 		{
 			"Success",
 			&mockORM{
@@ -282,12 +487,17 @@ func TestCameraServiceDelete(t *testing.T) {
 			logger := log.NewNopLogger()
 			metrics := metrics.New("unit-test")
 			tracer := mocktracer.New()
-			service := &cameraService{tc.orm, logger, metrics, tracer}
+			service := &cameraService{tc.orm, 0, nil, logger, metrics, tracer}
 
 			result, err := service.Delete(tc.ctx, tc.id)
 			assert.Equal(t, tc.expectedError, err)
 			assert.Equal(t, tc.expectedResult, result)
 
+			if tc.expectedError == tenant.ErrNoTenant {
+				assert.Empty(t, tracer.FinishedSpans())
+				return
+			}
+
 			// Verify trace span
 			span := tracer.FinishedSpans()[0]
 			assert.Equal(t, "delete_camera", span.OperationName)
@@ -295,6 +505,14 @@ func TestCameraServiceDelete(t *testing.T) {
 			assert.Equal(t, "gorm.Delete", span.Tag("db.statement"))
 			assert.Equal(t, "event", span.Logs()[0].Fields[0].Key)
 			assert.Equal(t, "delete_camera", span.Logs()[0].Fields[0].ValueString)
+
+			// Verify the relations are deleted in the same transaction
+			assert.Len(t, tracer.FinishedSpans(), 1)
+			orm := tc.orm.(*mockORM)
+			assert.True(t, orm.TransactionCalled)
+			if tc.expectedResult || orm.TransactionOutError != nil {
+				assert.Equal(t, model.Relation{}, orm.DeleteInValue)
+			}
 		})
 	}
 }
@@ -4,17 +4,38 @@ import (
 	"context"
 
 	"github.com/jinzhu/gorm"
+	"github.com/moorara/microservices-demo/services/asset/internal/db"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/mocktracer"
 )
 
+const testTenantID = "tttt-tttt"
+
 func contextWithSpan() context.Context {
+	return contextWithSpanForTenant(testTenantID)
+}
+
+func contextWithSpanForTenant(tenantID string) context.Context {
 	tracer := mocktracer.New()
 	span := tracer.StartSpan("mock-span")
 	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	ctx = tenant.NewContext(ctx, tenantID)
 	return ctx
 }
 
+type mockSiteClient struct {
+	ValidateCalled   bool
+	ValidateInSiteID string
+	ValidateOutError error
+}
+
+func (m *mockSiteClient) Validate(ctx context.Context, siteID string) error {
+	m.ValidateCalled = true
+	m.ValidateInSiteID = siteID
+	return m.ValidateOutError
+}
+
 type mockORM struct {
 	AutoMigrateCalled   bool
 	AutoMigrateInValues []interface{}
@@ -23,6 +44,12 @@ type mockORM struct {
 	CloseCalled   bool
 	CloseOutError error
 
+	CountCalled   bool
+	CountInValue  interface{}
+	CountInWhere  []interface{}
+	CountOutCount int
+	CountOutDB    *gorm.DB
+
 	CreateCalled  bool
 	CreateInValue interface{}
 	CreateOutDB   *gorm.DB
@@ -41,23 +68,14 @@ type mockORM struct {
 	LogModeInEnable bool
 	LogModeOutDB    *gorm.DB
 
-	ModelCalled  bool
-	ModelInValue interface{}
-	ModelOutDB   *gorm.DB
-
-	PreloadCalled       bool
-	PreloadInColumn     string
-	PreloadInConditions []interface{}
-	PreloadOutDB        *gorm.DB
+	TransactionCalled   bool
+	TransactionOutError error
 
 	UpdateCalled  bool
-	UpdateInAttrs []interface{}
+	UpdateInValue interface{}
+	UpdateInAttrs interface{}
+	UpdateInWhere []interface{}
 	UpdateOutDB   *gorm.DB
-
-	WhereCalled  bool
-	WhereInQuery interface{}
-	WhereInArgs  []interface{}
-	WhereOutDB   *gorm.DB
 }
 
 func (m *mockORM) AutoMigrate(values ...interface{}) *gorm.DB {
@@ -71,6 +89,14 @@ func (m *mockORM) Close() error {
 	return m.CloseOutError
 }
 
+func (m *mockORM) Count(value interface{}, count *int, where ...interface{}) *gorm.DB {
+	m.CountCalled = true
+	m.CountInValue = value
+	m.CountInWhere = where
+	*count = m.CountOutCount
+	return m.CountOutDB
+}
+
 func (m *mockORM) Create(value interface{}) *gorm.DB {
 	m.CreateCalled = true
 	m.CreateInValue = value
@@ -97,28 +123,19 @@ func (m *mockORM) LogMode(enable bool) *gorm.DB {
 	return m.LogModeOutDB
 }
 
-func (m *mockORM) Model(value interface{}) *gorm.DB {
-	m.ModelCalled = true
-	m.ModelInValue = value
-	return m.ModelOutDB
+// Transaction runs fn on the mock itself and returns TransactionOutError if fn succeeds
+func (m *mockORM) Transaction(fn func(tx db.ORM) error) error {
+	m.TransactionCalled = true
+	if err := fn(m); err != nil {
+		return err
+	}
+	return m.TransactionOutError
 }
 
-func (m *mockORM) Preload(column string, conditions ...interface{}) *gorm.DB {
-	m.PreloadCalled = true
-	m.PreloadInColumn = column
-	m.PreloadInConditions = conditions
-	return m.PreloadOutDB
-}
-
-func (m *mockORM) Update(attrs ...interface{}) *gorm.DB {
+func (m *mockORM) Update(value, attrs interface{}, where ...interface{}) *gorm.DB {
 	m.UpdateCalled = true
+	m.UpdateInValue = value
 	m.UpdateInAttrs = attrs
+	m.UpdateInWhere = where
 	return m.UpdateOutDB
 }
-
-func (m *mockORM) Where(query interface{}, args ...interface{}) *gorm.DB {
-	m.WhereCalled = true
-	m.WhereInQuery = query
-	m.WhereInArgs = args
-	return m.WhereOutDB
-}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"sort"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/moorara/microservices-demo/services/asset/internal/db"
+	"github.com/moorara/microservices-demo/services/asset/internal/model"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
+	"github.com/moorara/microservices-demo/services/asset/pkg/log"
+	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
+	"github.com/opentracing/opentracing-go"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/opentracing/opentracing-go/ext"
+	opentracingLog "github.com/opentracing/opentracing-go/log"
+)
+
+// PNG sheet layout in pixels
+const (
+	pngColumns     = 2
+	pngLabelWidth  = 360
+	pngLabelHeight = 140
+	pngMargin      = 10
+	pngQRSize      = 120
+)
+
+// PDF sheet layout in millimeters (A4 portrait)
+const (
+	pdfColumns     = 2
+	pdfRows        = 7
+	pdfPageMarginX = 10.0
+	pdfPageMarginY = 15.0
+	pdfLabelWidth  = 95.0
+	pdfLabelHeight = 38.0
+	pdfMargin      = 4.0
+	pdfQRSize      = 30.0
+)
+
+// MaxLabels is the largest number of labels rendered on one sheet
+const MaxLabels = 500
+
+var (
+	// ErrNoLabelQuery is returned when neither a site nor asset ids are given for labels
+	ErrNoLabelQuery = errors.New("site id or asset ids required")
+
+	// ErrInvalidLabelFormat is returned when a label format is not supported
+	ErrInvalidLabelFormat = errors.New("invalid label format")
+
+	// ErrTooManyLabels is returned when more than MaxLabels labels are requested
+	ErrTooManyLabels = fmt.Errorf("at most %d labels per sheet", MaxLabels)
+)
+
+type (
+	// LabelService is the service for printable asset labels
+	LabelService interface {
+		Labels(ctx context.Context, siteID string, ids []string) ([]model.Label, error)
+		Render(ctx context.Context, format string, labels []model.Label) ([]byte, error)
+	}
+
+	labelService struct {
+		orm     db.ORM
+		logger  *log.Logger
+		metrics *metrics.Metrics
+		tracer  opentracing.Tracer
+	}
+)
+
+// NewLabelService creates a new LabelService object
+func NewLabelService(orm db.ORM, logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer) LabelService {
+	return &labelService{
+		orm:     orm,
+		logger:  logger,
+		metrics: metrics,
+		tracer:  tracer,
+	}
+}
+
+func (s *labelService) exec(ctx context.Context, op, query string, fn func() error) {
+	parentSpan := opentracing.SpanFromContext(ctx)
+	span := s.tracer.StartSpan(op, opentracing.ChildOf(parentSpan.Context()))
+	defer span.Finish()
+
+	// https://github.com/opentracing/specification/blob/master/semantic_conventions.md
+	ext.DBType.Set(span, "sql")
+	ext.DBStatement.Set(span, query)
+	span.LogFields(opentracingLog.String("event", op))
+
+	start := time.Now()
+	err := fn()
+	latency := time.Now().Sub(start).Seconds()
+
+	success := "true"
+	if err != nil {
+		success = "false"
+		s.logger.Error("message", fmt.Sprintf("%s failed: %s", op, err))
+		span.LogFields(opentracingLog.String("message", err.Error()))
+	} else {
+		s.logger.Debug("message", fmt.Sprintf("%s succeeded.", op))
+		span.LogFields(opentracingLog.String("message", "successful!"))
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+	tenantLabel := s.metrics.Tenants.Label(tenantID)
+	s.metrics.OpLatencyHist.WithLabelValues(op, success, tenantLabel).Observe(latency)
+	s.metrics.OpLatencySumm.WithLabelValues(op, success, tenantLabel).Observe(latency)
+}
+
+// Labels returns the labels for all assets of a site, a set of assets, or a set of assets of a site.
+// It returns ErrTooManyLabels if more than MaxLabels assets are requested or found.
+func (s *labelService) Labels(ctx context.Context, siteID string, ids []string) ([]model.Label, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	if len(ids) > MaxLabels {
+		return nil, ErrTooManyLabels
+	}
+
+	var err error
+	var alarms []model.Alarm
+	var cameras []model.Camera
+
+	var where []interface{}
+	switch {
+	case siteID != "" && len(ids) > 0:
+		where = []interface{}{"tenant_id = ? AND site_id = ? AND id IN (?)", tenantID, siteID, ids}
+	case siteID != "":
+		where = []interface{}{"tenant_id = ? AND site_id = ?", tenantID, siteID}
+	case len(ids) > 0:
+		where = []interface{}{"tenant_id = ? AND id IN (?)", tenantID, ids}
+	default:
+		return nil, ErrNoLabelQuery
+	}
+
+	s.exec(ctx, "label_alarms", "gorm.Find", func() error {
+		err = s.orm.Find(&alarms, where...).Error
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.exec(ctx, "label_cameras", "gorm.Find", func() error {
+		err = s.orm.Find(&cameras, where...).Error
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(alarms)+len(cameras) > MaxLabels {
+		return nil, ErrTooManyLabels
+	}
+
+	labels := make([]model.Label, 0, len(alarms)+len(cameras))
+	for _, a := range alarms {
+		labels = append(labels, model.Label{AssetID: a.ID, Type: model.AssetTypeAlarm, SerialNo: a.SerialNo})
+	}
+	for _, c := range cameras {
+		labels = append(labels, model.Label{AssetID: c.ID, Type: model.AssetTypeCamera, SerialNo: c.SerialNo})
+	}
+
+	sort.SliceStable(labels, func(i, j int) bool {
+		if labels[i].Type != labels[j].Type {
+			return labels[i].Type < labels[j].Type
+		}
+		return labels[i].SerialNo < labels[j].SerialNo
+	})
+
+	return labels, nil
+}
+
+// Render renders a sheet of labels as a PNG image or a PDF document.
+func (s *labelService) Render(ctx context.Context, format string, labels []model.Label) ([]byte, error) {
+	parentSpan := opentracing.SpanFromContext(ctx)
+	span := s.tracer.StartSpan("render_labels", opentracing.ChildOf(parentSpan.Context()))
+	span.SetTag("format", format)
+	span.SetTag("labels", len(labels))
+	defer span.Finish()
+
+	switch format {
+	case model.LabelFormatPNG:
+		return renderPNG(labels)
+	case model.LabelFormatPDF:
+		return renderPDF(labels)
+	default:
+		return nil, ErrInvalidLabelFormat
+	}
+}
+
+func renderPNG(labels []model.Label) ([]byte, error) {
+	rows := (len(labels) + pngColumns - 1) / pngColumns
+	if rows == 0 {
+		rows = 1
+	}
+
+	sheet := image.NewRGBA(image.Rect(0, 0, pngColumns*pngLabelWidth, rows*pngLabelHeight))
+	draw.Draw(sheet, sheet.Bounds(), image.White, image.Point{}, draw.Src)
+
+	border := image.NewUniform(color.Gray{Y: 0xaa})
+	drawer := &font.Drawer{
+		Dst:  sheet,
+		Src:  image.Black,
+		Face: basicfont.Face7x13,
+	}
+
+	for i, l := range labels {
+		x := (i % pngColumns) * pngLabelWidth
+		y := (i / pngColumns) * pngLabelHeight
+
+		// Cut lines
+		draw.Draw(sheet, image.Rect(x, y+pngLabelHeight-1, x+pngLabelWidth, y+pngLabelHeight), border, image.Point{}, draw.Src)
+		draw.Draw(sheet, image.Rect(x+pngLabelWidth-1, y, x+pngLabelWidth, y+pngLabelHeight), border, image.Point{}, draw.Src)
+
+		qr, err := qrcode.New(l.AssetID, qrcode.Medium)
+		if err != nil {
+			return nil, err
+		}
+
+		qrRect := image.Rect(x+pngMargin, y+pngMargin, x+pngMargin+pngQRSize, y+pngMargin+pngQRSize)
+		draw.Draw(sheet, qrRect, qr.Image(pngQRSize), image.Point{}, draw.Src)
+
+		textX := x + 2*pngMargin + pngQRSize
+		for j, line := range labelLines(l) {
+			drawer.Dot = fixed.P(textX, y+pngMargin+20+j*20)
+			drawer.DrawString(line)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, sheet); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func renderPDF(labels []model.Label) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetDrawColor(0xaa, 0xaa, 0xaa)
+
+	perPage := pdfColumns * pdfRows
+	if len(labels) == 0 {
+		pdf.AddPage()
+	}
+
+	for i, l := range labels {
+		if i%perPage == 0 {
+			pdf.AddPage()
+		}
+
+		x := pdfPageMarginX + float64(i%pdfColumns)*pdfLabelWidth
+		y := pdfPageMarginY + float64((i%perPage)/pdfColumns)*pdfLabelHeight
+		pdf.Rect(x, y, pdfLabelWidth, pdfLabelHeight, "D")
+
+		data, err := qrcode.Encode(l.AssetID, qrcode.Medium, 256)
+		if err != nil {
+			return nil, err
+		}
+
+		name := fmt.Sprintf("qr-%d", i)
+		opts := gofpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(name, opts, bytes.NewReader(data))
+		pdf.ImageOptions(name, x+pdfMargin, y+pdfMargin, pdfQRSize, pdfQRSize, false, opts, 0, "")
+
+		lines := labelLines(l)
+		textX := x + 2*pdfMargin + pdfQRSize
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.Text(textX, y+pdfMargin+8, lines[0])
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.Text(textX, y+pdfMargin+15, lines[1])
+		pdf.SetFont("Courier", "", 7)
+		pdf.Text(textX, y+pdfMargin+22, lines[2])
+	}
+
+	buf := new(bytes.Buffer)
+	if err := pdf.Output(buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func labelLines(l model.Label) []string {
+	return []string{
+		"S/N: " + l.SerialNo,
+		"Type: " + l.Type,
+		l.AssetID,
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/png"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/moorara/microservices-demo/services/asset/internal/db"
+	"github.com/moorara/microservices-demo/services/asset/internal/model"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
+	"github.com/moorara/microservices-demo/services/asset/pkg/log"
+	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLabelService(t *testing.T) {
+	tests := []struct {
+		name string
+		orm  db.ORM
+	}{
+		{
+			"Default",
+			&mockORM{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := log.NewNopLogger()
+			metrics := metrics.New("unit-test")
+			tracer := mocktracer.New()
+			service := NewLabelService(tc.orm, logger, metrics, tracer)
+
+			assert.NotNil(t, service)
+		})
+	}
+}
+
+func TestLabelServiceLabels(t *testing.T) {
+	tests := []struct {
+		name          string
+		orm           *mockORM
+		ctx           context.Context
+		siteID        string
+		ids           []string
+		expectedError error
+		expectedWhere []interface{}
+	}{
+		{
+			"NoTenant",
+			&mockORM{},
+			contextWithSpanForTenant(""),
+			"1111-1111",
+			nil,
+			tenant.ErrNoTenant,
+			nil,
+		},
+		{
+			"NoQuery",
+			&mockORM{},
+			contextWithSpan(),
+			"",
+			nil,
+			ErrNoLabelQuery,
+			nil,
+		},
+		{
+			"TooManyIDs",
+			&mockORM{},
+			contextWithSpan(),
+			"",
+			make([]string, MaxLabels+1),
+			ErrTooManyLabels,
+			nil,
+		},
+		{
+			"DatabaseError",
+			&mockORM{
+				FindOutDB: &gorm.DB{
+					Error: errors.New("find error"),
+				},
+			},
+			contextWithSpan(),
+			"1111-1111",
+			nil,
+			errors.New("find error"),
+			[]interface{}{"tenant_id = ? AND site_id = ?", testTenantID, "1111-1111"},
+		},
+		{
+			"ByIDs",
+			&mockORM{
+				FindOutDB: &gorm.DB{},
+			},
+			contextWithSpan(),
+			"",
+			[]string{"aaaa-aaaa", "bbbb-bbbb"},
+			nil,
+			[]interface{}{"tenant_id = ? AND id IN (?)", testTenantID, []string{"aaaa-aaaa", "bbbb-bbbb"}},
+		},
+		{
+			"BySiteAndIDs",
+			&mockORM{
+				FindOutDB: &gorm.DB{},
+			},
+			contextWithSpan(),
+			"1111-1111",
+			[]string{"aaaa-aaaa"},
+			nil,
+			[]interface{}{"tenant_id = ? AND site_id = ? AND id IN (?)", testTenantID, "1111-1111", []string{"aaaa-aaaa"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := log.NewNopLogger()
+			metrics := metrics.New("unit-test")
+			tracer := mocktracer.New()
+			service := &labelService{tc.orm, logger, metrics, tracer}
+
+			labels, err := service.Labels(tc.ctx, tc.siteID, tc.ids)
+			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedWhere, tc.orm.FindInWhere)
+
+			if tc.expectedError == nil {
+				assert.NotNil(t, labels)
+				assert.Len(t, tracer.FinishedSpans(), 2)
+				assert.Equal(t, "label_alarms", tracer.FinishedSpans()[0].OperationName)
+				assert.Equal(t, "label_cameras", tracer.FinishedSpans()[1].OperationName)
+			}
+		})
+	}
+}
+
+// alarmsORM finds a number of alarms and no cameras
+type alarmsORM struct {
+	*mockORM
+	alarms int
+}
+
+func (o *alarmsORM) Find(out interface{}, where ...interface{}) *gorm.DB {
+	if alarms, ok := out.(*[]model.Alarm); ok {
+		*alarms = make([]model.Alarm, o.alarms)
+	}
+	return o.mockORM.Find(out, where...)
+}
+
+func TestLabelServiceLabelsLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		alarms        int
+		expectedError error
+	}{
+		{"AtLimit", MaxLabels, nil},
+		{"OverLimit", MaxLabels + 1, ErrTooManyLabels},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			orm := &alarmsORM{&mockORM{FindOutDB: &gorm.DB{}}, tc.alarms}
+			service := &labelService{orm, log.NewNopLogger(), metrics.New("unit-test"), mocktracer.New()}
+
+			labels, err := service.Labels(contextWithSpan(), "1111-1111", nil)
+
+			assert.Equal(t, tc.expectedError, err)
+			if tc.expectedError == nil {
+				assert.Len(t, labels, tc.alarms)
+			}
+		})
+	}
+}
+
+func TestLabelServiceRender(t *testing.T) {
+	labels := []model.Label{
+		{AssetID: "0000-0000-0000-0000", Type: model.AssetTypeAlarm, SerialNo: "1001"},
+		{AssetID: "8888-8888-8888-8888", Type: model.AssetTypeCamera, SerialNo: "2001"},
+		{AssetID: "9999-9999-9999-9999", Type: model.AssetTypeCamera, SerialNo: "2002"},
+	}
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		format        string
+		labels        []model.Label
+		expectedError error
+	}{
+		{
+			"InvalidFormat",
+			contextWithSpan(),
+			"gif",
+			labels,
+			ErrInvalidLabelFormat,
+		},
+		{
+			"PNG",
+			contextWithSpan(),
+			model.LabelFormatPNG,
+			labels,
+			nil,
+		},
+		{
+			"PDF",
+			contextWithSpan(),
+			model.LabelFormatPDF,
+			labels,
+			nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := log.NewNopLogger()
+			metrics := metrics.New("unit-test")
+			tracer := mocktracer.New()
+			service := &labelService{&mockORM{}, logger, metrics, tracer}
+
+			data, err := service.Render(tc.ctx, tc.format, tc.labels)
+			assert.Equal(t, tc.expectedError, err)
+
+			switch tc.format {
+			case model.LabelFormatPNG:
+				img, err := png.Decode(bytes.NewReader(data))
+				assert.NoError(t, err)
+				assert.Equal(t, pngColumns*pngLabelWidth, img.Bounds().Dx())
+				assert.Equal(t, 2*pngLabelHeight, img.Bounds().Dy())
+			case model.LabelFormatPDF:
+				assert.True(t, bytes.HasPrefix(data, []byte("%PDF-")))
+			}
+
+			span := tracer.FinishedSpans()[0]
+			assert.Equal(t, "render_labels", span.OperationName)
+			assert.Equal(t, tc.format, span.Tag("format"))
+		})
+	}
+}
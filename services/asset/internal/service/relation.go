@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/moorara/microservices-demo/services/asset/internal/db"
+	"github.com/moorara/microservices-demo/services/asset/internal/model"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
+	"github.com/moorara/microservices-demo/services/asset/pkg/log"
+	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/opentracing/opentracing-go/ext"
+	opentracingLog "github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	defaultRelationDepth = 1
+	maxRelationDepth     = 5
+)
+
+var (
+	// ErrInvalidRelation is returned when a relation is missing an asset or has an unknown type
+	ErrInvalidRelation = errors.New("invalid relation")
+
+	// ErrUnknownAsset is returned when a relation is between assets that do not exist for the tenant
+	ErrUnknownAsset = errors.New("unknown asset")
+
+	// ErrInvalidDirection is returned when a traversal direction is unknown
+	ErrInvalidDirection = errors.New("invalid direction")
+
+	relationTypes = map[string]bool{
+		model.RelationCovers:    true,
+		model.RelationPowers:    true,
+		model.RelationMountedOn: true,
+	}
+)
+
+type (
+	// RelationService is the service for relations between assets
+	RelationService interface {
+		Relate(ctx context.Context, relation model.Relation) (*model.Relation, error)
+		Unrelate(ctx context.Context, relation model.Relation) (bool, error)
+		Related(ctx context.Context, id, direction string, types []string, depth int) ([]model.RelatedAsset, error)
+	}
+
+	relationService struct {
+		orm     db.ORM
+		logger  *log.Logger
+		metrics *metrics.Metrics
+		tracer  opentracing.Tracer
+	}
+)
+
+// NewRelationService creates a new RelationService object
+func NewRelationService(orm db.ORM, logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer) RelationService {
+	// Migrate the database table schema
+	orm.AutoMigrate(model.Alarm{}, model.Camera{}, model.Relation{})
+
+	return &relationService{
+		orm:     orm,
+		logger:  logger,
+		metrics: metrics,
+		tracer:  tracer,
+	}
+}
+
+func (s *relationService) exec(ctx context.Context, op, query string, fn func() error) {
+	parentSpan := opentracing.SpanFromContext(ctx)
+	span := s.tracer.StartSpan(op, opentracing.ChildOf(parentSpan.Context()))
+	defer span.Finish()
+
+	// https://github.com/opentracing/specification/blob/master/semantic_conventions.md
+	ext.DBType.Set(span, "sql")
+	ext.DBStatement.Set(span, query)
+	span.LogFields(opentracingLog.String("event", op))
+
+	start := time.Now()
+	err := fn()
+	latency := time.Now().Sub(start).Seconds()
+
+	success := "true"
+	if err != nil {
+		success = "false"
+		s.logger.Error("message", fmt.Sprintf("%s failed: %s", op, err))
+		span.LogFields(opentracingLog.String("message", err.Error()))
+	} else {
+		s.logger.Debug("message", fmt.Sprintf("%s succeeded.", op))
+		span.LogFields(opentracingLog.String("message", "successful!"))
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+	tenantLabel := s.metrics.Tenants.Label(tenantID)
+	s.metrics.OpLatencyHist.WithLabelValues(op, success, tenantLabel).Observe(latency)
+	s.metrics.OpLatencySumm.WithLabelValues(op, success, tenantLabel).Observe(latency)
+}
+
+func (s *relationService) Relate(ctx context.Context, relation model.Relation) (*model.Relation, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	var err error
+
+	if relation.SourceID == "" || relation.TargetID == "" || relation.SourceID == relation.TargetID || !relationTypes[relation.Type] {
+		return nil, ErrInvalidRelation
+	}
+
+	relation.TenantID = tenantID
+
+	// The assets are checked in the same transaction as the relation is created, so an asset deleted meanwhile is not related
+	s.exec(ctx, "relate_assets", "gorm.Create", func() error {
+		err = s.orm.Transaction(func(tx db.ORM) error {
+			for _, id := range []string{relation.SourceID, relation.TargetID} {
+				exists, err := assetExists(tx, tenantID, id)
+				if err != nil {
+					return err
+				}
+				if !exists {
+					return ErrUnknownAsset
+				}
+			}
+
+			return tx.Create(&relation).Error
+		})
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &relation, nil
+}
+
+func (s *relationService) Unrelate(ctx context.Context, relation model.Relation) (bool, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return false, tenant.ErrNoTenant
+	}
+
+	var result *gorm.DB
+
+	s.exec(ctx, "unrelate_assets", "gorm.Delete", func() error {
+		result = s.orm.Delete(model.Relation{}, "tenant_id = ? AND source_id = ? AND target_id = ? AND type = ?", tenantID, relation.SourceID, relation.TargetID, relation.Type)
+		return result.Error
+	})
+
+	if err := result.Error; err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// Related traverses the relations graph breadth-first starting from an asset.
+// Each asset is reported once at the shallowest depth it is reached.
+func (s *relationService) Related(ctx context.Context, id, direction string, types []string, depth int) ([]model.RelatedAsset, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+
+	if direction == "" {
+		direction = model.DirectionOutgoing
+	} else if direction != model.DirectionOutgoing && direction != model.DirectionIncoming && direction != model.DirectionBoth {
+		return nil, ErrInvalidDirection
+	}
+
+	if depth <= 0 {
+		depth = defaultRelationDepth
+	} else if depth > maxRelationDepth {
+		depth = maxRelationDepth
+	}
+
+	visited := map[string]bool{id: true}
+	frontier := []string{id}
+	related := []model.RelatedAsset{}
+
+	for d := 1; d <= depth && len(frontier) > 0; d++ {
+		relations, err := s.neighbours(ctx, tenantID, frontier, direction, types)
+		if err != nil {
+			return nil, err
+		}
+
+		inFrontier := make(map[string]bool, len(frontier))
+		for _, fid := range frontier {
+			inFrontier[fid] = true
+		}
+
+		frontier = []string{}
+		for _, r := range relations {
+			ids := []string{}
+			if direction != model.DirectionIncoming && inFrontier[r.SourceID] {
+				ids = append(ids, r.TargetID)
+			}
+			if direction != model.DirectionOutgoing && inFrontier[r.TargetID] {
+				ids = append(ids, r.SourceID)
+			}
+
+			for _, nid := range ids {
+				if !visited[nid] {
+					visited[nid] = true
+					frontier = append(frontier, nid)
+					related = append(related, model.RelatedAsset{ID: nid, Depth: d, Relation: r})
+				}
+			}
+		}
+	}
+
+	return related, nil
+}
+
+func (s *relationService) neighbours(ctx context.Context, tenantID string, ids []string, direction string, types []string) ([]model.Relation, error) {
+	var err error
+	var relations []model.Relation
+
+	var query string
+	var args []interface{}
+
+	switch direction {
+	case model.DirectionOutgoing:
+		query, args = "tenant_id = ? AND source_id IN (?)", []interface{}{tenantID, ids}
+	case model.DirectionIncoming:
+		query, args = "tenant_id = ? AND target_id IN (?)", []interface{}{tenantID, ids}
+	default:
+		query, args = "tenant_id = ? AND (source_id IN (?) OR target_id IN (?))", []interface{}{tenantID, ids, ids}
+	}
+
+	if len(types) > 0 {
+		query += " AND type IN (?)"
+		args = append(args, types)
+	}
+
+	s.exec(ctx, "related_assets", "gorm.Find", func() error {
+		err = s.orm.Find(&relations, append([]interface{}{query}, args...)...).Error
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return relations, nil
+}
+
+// assetExists reports whether a tenant has an alarm or a camera with an id.
+func assetExists(orm db.ORM, tenantID, id string) (bool, error) {
+	for _, value := range []interface{}{model.Alarm{}, model.Camera{}} {
+		var count int
+		if err := orm.Count(value, &count, "tenant_id = ? AND id = ?", tenantID, id).Error; err != nil {
+			return false, err
+		}
+
+		if count > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// deleteRelations removes all relations of a tenant from or to an asset.
+func deleteRelations(orm db.ORM, tenantID, id string) error {
+	return orm.Delete(model.Relation{}, "tenant_id = ? AND (source_id = ? OR target_id = ?)", tenantID, id, id).Error
+}
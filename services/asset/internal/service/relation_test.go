@@ -0,0 +1,425 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/moorara/microservices-demo/services/asset/internal/db"
+	"github.com/moorara/microservices-demo/services/asset/internal/model"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
+	"github.com/moorara/microservices-demo/services/asset/pkg/log"
+	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRelationService(t *testing.T) {
+	tests := []struct {
+		name string
+		orm  db.ORM
+	}{
+		{
+			"Default",
+			&mockORM{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := log.NewNopLogger()
+			metrics := metrics.New("unit-test")
+			tracer := mocktracer.New()
+			service := NewRelationService(tc.orm, logger, metrics, tracer)
+
+			assert.NotNil(t, service)
+		})
+	}
+}
+
+func TestRelationServiceRelate(t *testing.T) {
+	tests := []struct {
+		name          string
+		orm           db.ORM
+		ctx           context.Context
+		relation      model.Relation
+		expectedError error
+		expectedSpans int
+	}{
+		{
+			"NoTenant",
+			&mockORM{},
+			contextWithSpanForTenant(""),
+			model.Relation{SourceID: "bbbb-bbbb", TargetID: "aaaa-aaaa", Type: model.RelationCovers},
+			tenant.ErrNoTenant,
+			0,
+		},
+		{
+			"InvalidType",
+			&mockORM{},
+			contextWithSpan(),
+			model.Relation{SourceID: "bbbb-bbbb", TargetID: "aaaa-aaaa", Type: "watches"},
+			ErrInvalidRelation,
+			0,
+		},
+		{
+			"SelfRelation",
+			&mockORM{},
+			contextWithSpan(),
+			model.Relation{SourceID: "aaaa-aaaa", TargetID: "aaaa-aaaa", Type: model.RelationCovers},
+			ErrInvalidRelation,
+			0,
+		},
+		{
+			"CountError",
+			&mockORM{
+				CountOutDB: &gorm.DB{
+					Error: errors.New("count error"),
+				},
+			},
+			contextWithSpan(),
+			model.Relation{SourceID: "bbbb-bbbb", TargetID: "aaaa-aaaa", Type: model.RelationCovers},
+			errors.New("count error"),
+			1,
+		},
+		{
+			"UnknownAsset",
+			&mockORM{
+				CountOutDB: &gorm.DB{},
+			},
+			contextWithSpan(),
+			model.Relation{SourceID: "bbbb-bbbb", TargetID: "aaaa-aaaa", Type: model.RelationCovers},
+			ErrUnknownAsset,
+			1,
+		},
+		{
+			"DatabaseError",
+			&mockORM{
+				CountOutCount: 1,
+				CountOutDB:    &gorm.DB{},
+				CreateOutDB: &gorm.DB{
+					Error: errors.New("create error"),
+				},
+			},
+			contextWithSpan(),
+			model.Relation{SourceID: "bbbb-bbbb", TargetID: "aaaa-aaaa", Type: model.RelationCovers},
+			errors.New("create error"),
+			1,
+		},
+		{
+			"Success",
+			&mockORM{
+				CountOutCount: 1,
+				CountOutDB:    &gorm.DB{},
+				CreateOutDB:   &gorm.DB{},
+			},
+			contextWithSpan(),
+			model.Relation{SourceID: "bbbb-bbbb", TargetID: "aaaa-aaaa", Type: model.RelationCovers},
+			nil,
+			1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := log.NewNopLogger()
+			metrics := metrics.New("unit-test")
+			tracer := mocktracer.New()
+			service := &relationService{tc.orm, logger, metrics, tracer}
+
+			relation, err := service.Relate(tc.ctx, tc.relation)
+			assert.Equal(t, tc.expectedError, err)
+			assert.Len(t, tracer.FinishedSpans(), tc.expectedSpans)
+
+			if tc.expectedError == nil {
+				expected := tc.relation
+				expected.TenantID = testTenantID
+				assert.Equal(t, expected, *relation)
+
+				// Verify trace span
+				span := tracer.FinishedSpans()[0]
+				assert.Equal(t, "relate_assets", span.OperationName)
+				assert.Equal(t, "sql", span.Tag("db.type"))
+				assert.Equal(t, "gorm.Create", span.Tag("db.statement"))
+			}
+		})
+	}
+}
+
+func TestRelationServiceUnrelate(t *testing.T) {
+	tests := []struct {
+		name           string
+		orm            db.ORM
+		ctx            context.Context
+		relation       model.Relation
+		expectedError  error
+		expectedResult bool
+	}{
+		{
+			"DatabaseError",
+			&mockORM{
+				DeleteOutDB: &gorm.DB{
+					Error: errors.New("delete error"),
+				},
+			},
+			contextWithSpan(),
+			model.Relation{SourceID: "bbbb-bbbb", TargetID: "aaaa-aaaa", Type: model.RelationCovers},
+			errors.New("delete error"),
+			false,
+		},
+		{
+			"Success",
+			&mockORM{
+				DeleteOutDB: &gorm.DB{
+					RowsAffected: 1,
+				},
+			},
+			contextWithSpan(),
+			model.Relation{SourceID: "bbbb-bbbb", TargetID: "aaaa-aaaa", Type: model.RelationCovers},
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := log.NewNopLogger()
+			metrics := metrics.New("unit-test")
+			tracer := mocktracer.New()
+			service := &relationService{tc.orm, logger, metrics, tracer}
+
+			result, err := service.Unrelate(tc.ctx, tc.relation)
+			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedResult, result)
+
+			// Verify trace span
+			span := tracer.FinishedSpans()[0]
+			assert.Equal(t, "unrelate_assets", span.OperationName)
+			assert.Equal(t, "sql", span.Tag("db.type"))
+			assert.Equal(t, "gorm.Delete", span.Tag("db.statement"))
+			assert.Equal(t, "event", span.Logs()[0].Fields[0].Key)
+			assert.Equal(t, "unrelate_assets", span.Logs()[0].Fields[0].ValueString)
+		})
+	}
+}
+
+func TestRelationServiceRelated(t *testing.T) {
+	tests := []struct {
+		name           string
+		orm            *mockORM
+		ctx            context.Context
+		id             string
+		direction      string
+		types          []string
+		depth          int
+		expectedError  error
+		expectedWhere  []interface{}
+		expectedResult []model.RelatedAsset
+	}{
+		{
+			"NoTenant",
+			&mockORM{},
+			contextWithSpanForTenant(""),
+			"aaaa-aaaa",
+			"",
+			nil,
+			1,
+			tenant.ErrNoTenant,
+			nil,
+			nil,
+		},
+		{
+			"InvalidDirection",
+			&mockORM{},
+			contextWithSpan(),
+			"aaaa-aaaa",
+			"sideways",
+			nil,
+			1,
+			ErrInvalidDirection,
+			nil,
+			nil,
+		},
+		{
+			"DatabaseError",
+			&mockORM{
+				FindOutDB: &gorm.DB{
+					Error: errors.New("find error"),
+				},
+			},
+			contextWithSpan(),
+			"aaaa-aaaa",
+			"",
+			nil,
+			1,
+			errors.New("find error"),
+			[]interface{}{"tenant_id = ? AND source_id IN (?)", testTenantID, []string{"aaaa-aaaa"}},
+			nil,
+		},
+		{
+			"Incoming",
+			&mockORM{
+				FindOutDB: &gorm.DB{},
+			},
+			contextWithSpan(),
+			"aaaa-aaaa",
+			model.DirectionIncoming,
+			[]string{model.RelationCovers},
+			3,
+			nil,
+			[]interface{}{"tenant_id = ? AND target_id IN (?) AND type IN (?)", testTenantID, []string{"aaaa-aaaa"}, []string{model.RelationCovers}},
+			[]model.RelatedAsset{},
+		},
+		{
+			"Both",
+			&mockORM{
+				FindOutDB: &gorm.DB{},
+			},
+			contextWithSpan(),
+			"aaaa-aaaa",
+			model.DirectionBoth,
+			nil,
+			0,
+			nil,
+			[]interface{}{"tenant_id = ? AND (source_id IN (?) OR target_id IN (?))", testTenantID, []string{"aaaa-aaaa"}, []string{"aaaa-aaaa"}},
+			[]model.RelatedAsset{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := log.NewNopLogger()
+			metrics := metrics.New("unit-test")
+			tracer := mocktracer.New()
+			service := &relationService{tc.orm, logger, metrics, tracer}
+
+			result, err := service.Related(tc.ctx, tc.id, tc.direction, tc.types, tc.depth)
+			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedResult, result)
+			assert.Equal(t, tc.expectedWhere, tc.orm.FindInWhere)
+
+			if tc.expectedWhere != nil {
+				// Verify trace span
+				span := tracer.FinishedSpans()[0]
+				assert.Equal(t, "related_assets", span.OperationName)
+				assert.Equal(t, "sql", span.Tag("db.type"))
+				assert.Equal(t, "gorm.Find", span.Tag("db.statement"))
+			}
+		})
+	}
+}
+
+func TestRelationServiceGraph(t *testing.T) {
+	orm := db.NewMemoryORM()
+	logger := log.NewNopLogger()
+	metrics := metrics.New("unit-test")
+	tracer := mocktracer.New()
+
+	alarmService := NewAlarmService(orm, 0, nil, logger, metrics, tracer)
+	cameraService := NewCameraService(orm, 0, nil, logger, metrics, tracer)
+	relationService := NewRelationService(orm, logger, metrics, tracer)
+
+	// The assets are stored with the names used in the test as their ids
+	for _, id := range []string{"alarm-1", "alarm-2", "pole-1", "panel-1"} {
+		assert.NoError(t, orm.Create(&model.Alarm{Asset: model.Asset{ID: id, TenantID: testTenantID}}).Error)
+	}
+	for _, id := range []string{"camera-1", "camera-2"} {
+		assert.NoError(t, orm.Create(&model.Camera{Asset: model.Asset{ID: id, TenantID: testTenantID}}).Error)
+	}
+
+	// camera-1 covers alarm-1 and alarm-2, camera-2 covers alarm-2 and is mounted on pole-1, panel-1 powers camera-1
+	relations := []model.Relation{
+		{SourceID: "camera-1", TargetID: "alarm-1", Type: model.RelationCovers},
+		{SourceID: "camera-1", TargetID: "alarm-2", Type: model.RelationCovers},
+		{SourceID: "camera-2", TargetID: "alarm-2", Type: model.RelationCovers},
+		{SourceID: "camera-2", TargetID: "pole-1", Type: model.RelationMountedOn},
+		{SourceID: "panel-1", TargetID: "camera-1", Type: model.RelationPowers},
+	}
+
+	for _, r := range relations {
+		_, err := relationService.Relate(contextWithSpan(), r)
+		assert.NoError(t, err)
+	}
+
+	_, err := relationService.Relate(contextWithSpan(), relations[0])
+	assert.Error(t, err)
+
+	related := func(id, direction string, types []string, depth int) map[string]int {
+		assets, err := relationService.Related(contextWithSpan(), id, direction, types, depth)
+		assert.NoError(t, err)
+
+		depths := map[string]int{}
+		for _, a := range assets {
+			depths[a.ID] = a.Depth
+		}
+		return depths
+	}
+
+	t.Run("CamerasCoveringAlarm", func(t *testing.T) {
+		assert.Equal(t, map[string]int{"camera-1": 1, "camera-2": 1}, related("alarm-2", model.DirectionIncoming, []string{model.RelationCovers}, 1))
+	})
+
+	t.Run("Outgoing", func(t *testing.T) {
+		assert.Equal(t, map[string]int{"alarm-2": 1, "pole-1": 1}, related("camera-2", "", nil, 1))
+	})
+
+	t.Run("DepthLimited", func(t *testing.T) {
+		assert.Equal(t, map[string]int{"camera-1": 1}, related("panel-1", model.DirectionOutgoing, nil, 1))
+		assert.Equal(t, map[string]int{"camera-1": 1, "alarm-1": 2, "alarm-2": 2}, related("panel-1", model.DirectionOutgoing, nil, 2))
+	})
+
+	t.Run("Both", func(t *testing.T) {
+		assert.Equal(t, map[string]int{"camera-1": 1, "camera-2": 1, "alarm-1": 2, "panel-1": 2, "pole-1": 2}, related("alarm-2", model.DirectionBoth, nil, 2))
+	})
+
+	t.Run("UnknownAssets", func(t *testing.T) {
+		_, err := relationService.Relate(contextWithSpan(), model.Relation{SourceID: "camera-1", TargetID: "alarm-3", Type: model.RelationCovers})
+		assert.Equal(t, ErrUnknownAsset, err)
+
+		// The assets of another tenant cannot be related
+		_, err = relationService.Relate(contextWithSpanForTenant("uuuu-uuuu"), relations[0])
+		assert.Equal(t, ErrUnknownAsset, err)
+	})
+
+	t.Run("OtherTenant", func(t *testing.T) {
+		assets, err := relationService.Related(contextWithSpanForTenant("uuuu-uuuu"), "alarm-2", model.DirectionBoth, nil, 2)
+		assert.NoError(t, err)
+		assert.Empty(t, assets)
+
+		unrelated, err := relationService.Unrelate(contextWithSpanForTenant("uuuu-uuuu"), relations[4])
+		assert.NoError(t, err)
+		assert.False(t, unrelated)
+	})
+
+	t.Run("Unrelate", func(t *testing.T) {
+		unrelated, err := relationService.Unrelate(contextWithSpan(), relations[3])
+		assert.NoError(t, err)
+		assert.True(t, unrelated)
+
+		unrelated, err = relationService.Unrelate(contextWithSpan(), relations[3])
+		assert.NoError(t, err)
+		assert.False(t, unrelated)
+	})
+
+	t.Run("DeleteAssetCleansUpRelations", func(t *testing.T) {
+		alarm, err := alarmService.Create(contextWithSpan(), model.AlarmInput{AssetInput: model.AssetInput{SiteID: "1111-1111", SerialNo: "1001"}})
+		assert.NoError(t, err)
+
+		_, err = relationService.Relate(contextWithSpan(), model.Relation{SourceID: "camera-1", TargetID: alarm.ID, Type: model.RelationCovers})
+		assert.NoError(t, err)
+		assert.Contains(t, related("camera-1", "", nil, 1), alarm.ID)
+
+		deleted, err := alarmService.Delete(contextWithSpan(), alarm.ID)
+		assert.NoError(t, err)
+		assert.True(t, deleted)
+		assert.NotContains(t, related("camera-1", "", nil, 1), alarm.ID)
+
+		deleted, err = cameraService.Delete(contextWithSpan(), "camera-2")
+		assert.NoError(t, err)
+		assert.True(t, deleted)
+		assert.Empty(t, related("camera-2", model.DirectionBoth, nil, 1))
+		assert.Equal(t, map[string]int{"camera-1": 1}, related("alarm-2", model.DirectionIncoming, nil, 1))
+	})
+}
@@ -0,0 +1,30 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+)
+
+// Header is the HTTP header carrying the tenant identifier
+const Header = "X-Tenant-ID"
+
+var (
+	// ErrNoTenant is returned when a request does not carry a tenant identifier
+	ErrNoTenant = errors.New("tenant id required")
+
+	// ErrQuotaExceeded is returned when a tenant has reached its quota
+	ErrQuotaExceeded = errors.New("tenant quota exceeded")
+)
+
+type contextKey struct{}
+
+// NewContext returns a new context carrying a tenant identifier
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant identifier carried by a context if any
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
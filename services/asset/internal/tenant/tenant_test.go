@@ -0,0 +1,44 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		expectedID string
+		expectedOK bool
+	}{
+		{
+			"NoTenant",
+			context.Background(),
+			"",
+			false,
+		},
+		{
+			"EmptyTenant",
+			NewContext(context.Background(), ""),
+			"",
+			false,
+		},
+		{
+			"WithTenant",
+			NewContext(context.Background(), "tttt-tttt"),
+			"tttt-tttt",
+			true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			id, ok := FromContext(tc.ctx)
+			assert.Equal(t, tc.expectedID, id)
+			assert.Equal(t, tc.expectedOK, ok)
+		})
+	}
+}
@@ -15,12 +15,17 @@ const (
 	getCamera    = "getCamera"
 	updateCamera = "updateCamera"
 	deleteCamera = "deleteCamera"
+
+	relateAssets   = "relateAssets"
+	unrelateAssets = "unrelateAssets"
+	relatedAssets  = "relatedAssets"
 )
 
 type (
 	request struct {
-		Kind string `json:"kind"`
-		Span string `json:"span,omitempty"`
+		Kind     string `json:"kind"`
+		Span     string `json:"span,omitempty"`
+		TenantID string `json:"tenantId,omitempty"`
 	}
 	response struct {
 		Kind  string `json:"kind"`
@@ -118,4 +123,34 @@ type (
 		response
 		Deleted bool `json:"deleted"`
 	}
+
+	relateAssetsRequest struct {
+		request
+		Relation model.Relation `json:"relation"`
+	}
+	relateAssetsResponse struct {
+		response
+		Relation *model.Relation `json:"relation"`
+	}
+
+	unrelateAssetsRequest struct {
+		request
+		Relation model.Relation `json:"relation"`
+	}
+	unrelateAssetsResponse struct {
+		response
+		Unrelated bool `json:"unrelated"`
+	}
+
+	relatedAssetsRequest struct {
+		request
+		ID        string   `json:"id"`
+		Direction string   `json:"direction,omitempty"`
+		Types     []string `json:"types,omitempty"`
+		Depth     int      `json:"depth,omitempty"`
+	}
+	relatedAssetsResponse struct {
+		response
+		Assets []model.RelatedAsset `json:"assets"`
+	}
 )
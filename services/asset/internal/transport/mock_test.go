@@ -302,3 +302,50 @@ func (m *mockCameraService) Delete(ctx context.Context, id string) (bool, error)
 	m.DeleteInID = id
 	return m.DeleteOutDeleted, m.DeleteOutError
 }
+
+type mockRelationService struct {
+	RelateCalled      bool
+	RelateInContext   context.Context
+	RelateInRelation  model.Relation
+	RelateOutRelation *model.Relation
+	RelateOutError    error
+
+	UnrelateCalled       bool
+	UnrelateInContext    context.Context
+	UnrelateInRelation   model.Relation
+	UnrelateOutUnrelated bool
+	UnrelateOutError     error
+
+	RelatedCalled      bool
+	RelatedInContext   context.Context
+	RelatedInID        string
+	RelatedInDirection string
+	RelatedInTypes     []string
+	RelatedInDepth     int
+	RelatedOutAssets   []model.RelatedAsset
+	RelatedOutError    error
+}
+
+func (m *mockRelationService) Relate(ctx context.Context, relation model.Relation) (*model.Relation, error) {
+	m.RelateCalled = true
+	m.RelateInContext = ctx
+	m.RelateInRelation = relation
+	return m.RelateOutRelation, m.RelateOutError
+}
+
+func (m *mockRelationService) Unrelate(ctx context.Context, relation model.Relation) (bool, error) {
+	m.UnrelateCalled = true
+	m.UnrelateInContext = ctx
+	m.UnrelateInRelation = relation
+	return m.UnrelateOutUnrelated, m.UnrelateOutError
+}
+
+func (m *mockRelationService) Related(ctx context.Context, id, direction string, types []string, depth int) ([]model.RelatedAsset, error) {
+	m.RelatedCalled = true
+	m.RelatedInContext = ctx
+	m.RelatedInID = id
+	m.RelatedInDirection = direction
+	m.RelatedInTypes = types
+	m.RelatedInDepth = depth
+	return m.RelatedOutAssets, m.RelatedOutError
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/moorara/microservices-demo/services/asset/internal/queue"
 	"github.com/moorara/microservices-demo/services/asset/internal/service"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
 	"github.com/moorara/microservices-demo/services/asset/pkg/log"
 	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
 	"github.com/nats-io/nats.go"
@@ -27,26 +28,28 @@ type (
 	}
 
 	natsTransport struct {
-		logger        *log.Logger
-		metrics       *metrics.Metrics
-		tracer        opentracing.Tracer
-		conn          queue.NATSConnection
-		alarmService  service.AlarmService
-		cameraService service.CameraService
-		subscription  *nats.Subscription
+		logger          *log.Logger
+		metrics         *metrics.Metrics
+		tracer          opentracing.Tracer
+		conn            queue.NATSConnection
+		alarmService    service.AlarmService
+		cameraService   service.CameraService
+		relationService service.RelationService
+		subscription    *nats.Subscription
 	}
 )
 
 // NewNATSTransport creates a new NATS transport instance
 func NewNATSTransport(logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer,
-	conn queue.NATSConnection, alarmService service.AlarmService, cameraService service.CameraService) NATSTransport {
+	conn queue.NATSConnection, alarmService service.AlarmService, cameraService service.CameraService, relationService service.RelationService) NATSTransport {
 	return &natsTransport{
-		logger:        logger,
-		metrics:       metrics,
-		tracer:        tracer,
-		conn:          conn,
-		alarmService:  alarmService,
-		cameraService: cameraService,
+		logger:          logger,
+		metrics:         metrics,
+		tracer:          tracer,
+		conn:            conn,
+		alarmService:    alarmService,
+		cameraService:   cameraService,
+		relationService: relationService,
 	}
 }
 
@@ -301,6 +304,69 @@ func (t *natsTransport) deleteCameraRequest(ctx context.Context, msg *nats.Msg)
 	t.reply(msg.Reply, res)
 }
 
+func (t *natsTransport) relateAssetsRequest(ctx context.Context, msg *nats.Msg) {
+	var req relateAssetsRequest
+	err := json.Unmarshal(msg.Data, &req)
+	if err != nil {
+		t.logger.Warn("message", "invalid request", "error", err)
+		return
+	}
+
+	res := relateAssetsResponse{
+		response: response{
+			Kind: relateAssets,
+		},
+	}
+
+	relation, err := t.relationService.Relate(ctx, req.Relation)
+	res.response.Error = err
+	res.Relation = relation
+
+	t.reply(msg.Reply, res)
+}
+
+func (t *natsTransport) unrelateAssetsRequest(ctx context.Context, msg *nats.Msg) {
+	var req unrelateAssetsRequest
+	err := json.Unmarshal(msg.Data, &req)
+	if err != nil {
+		t.logger.Warn("message", "invalid request", "error", err)
+		return
+	}
+
+	res := unrelateAssetsResponse{
+		response: response{
+			Kind: unrelateAssets,
+		},
+	}
+
+	unrelated, err := t.relationService.Unrelate(ctx, req.Relation)
+	res.response.Error = err
+	res.Unrelated = unrelated
+
+	t.reply(msg.Reply, res)
+}
+
+func (t *natsTransport) relatedAssetsRequest(ctx context.Context, msg *nats.Msg) {
+	var req relatedAssetsRequest
+	err := json.Unmarshal(msg.Data, &req)
+	if err != nil {
+		t.logger.Warn("message", "invalid request", "error", err)
+		return
+	}
+
+	res := relatedAssetsResponse{
+		response: response{
+			Kind: relatedAssets,
+		},
+	}
+
+	assets, err := t.relationService.Related(ctx, req.ID, req.Direction, req.Types, req.Depth)
+	res.response.Error = err
+	res.Assets = assets
+
+	t.reply(msg.Reply, res)
+}
+
 func (t *natsTransport) Start() (err error) {
 	t.subscription, err = t.conn.QueueSubscribe(subject, queueGroup, func(msg *nats.Msg) {
 		t.logger.Debug("message", "request received", "data", string(msg.Data))
@@ -316,10 +382,12 @@ func (t *natsTransport) Start() (err error) {
 		span.SetTag("broker", "NATS")
 		span.SetTag("subject", msg.Subject)
 		span.SetTag("reply", msg.Reply)
+		span.SetTag("tenant", req.TenantID)
 		span.LogFields(opentracingLog.String("message", string(msg.Data)))
 		defer span.Finish()
 
 		ctx := opentracing.ContextWithSpan(context.Background(), span)
+		ctx = tenant.NewContext(ctx, req.TenantID)
 
 		switch req.Kind {
 		case createAlarm:
@@ -342,6 +410,12 @@ func (t *natsTransport) Start() (err error) {
 			t.updateCameraRequest(ctx, msg)
 		case deleteCamera:
 			t.deleteCameraRequest(ctx, msg)
+		case relateAssets:
+			t.relateAssetsRequest(ctx, msg)
+		case unrelateAssets:
+			t.unrelateAssetsRequest(ctx, msg)
+		case relatedAssets:
+			t.relatedAssetsRequest(ctx, msg)
 		default:
 			t.logger.Warn("message", "unknown request", "kind", req.Kind)
 		}
@@ -5,14 +5,20 @@ import (
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/moorara/microservices-demo/services/asset/internal/db"
 	"github.com/moorara/microservices-demo/services/asset/internal/model"
+	"github.com/moorara/microservices-demo/services/asset/internal/queue"
+	"github.com/moorara/microservices-demo/services/asset/internal/service"
 	"github.com/moorara/microservices-demo/services/asset/pkg/log"
 	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
 	"github.com/nats-io/nats.go"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
+
+	natstest "github.com/nats-io/nats-server/v2/test"
 )
 
 func TestNewNATSTransport(t *testing.T) {
@@ -23,8 +29,9 @@ func TestNewNATSTransport(t *testing.T) {
 	conn := &mockNATSConnection{}
 	alarmService := &mockAlarmService{}
 	cameraService := &mockCameraService{}
+	relationService := &mockRelationService{}
 
-	natsTransport := NewNATSTransport(logger, metrics, tracer, conn, alarmService, cameraService)
+	natsTransport := NewNATSTransport(logger, metrics, tracer, conn, alarmService, cameraService, relationService)
 	assert.NotNil(t, natsTransport)
 }
 
@@ -202,6 +209,7 @@ func TestStart(t *testing.T) {
 		conn             *mockNATSConnection
 		alarmService     *mockAlarmService
 		cameraService    *mockCameraService
+		relationService  *mockRelationService
 		request          map[string]interface{}
 		expectedResponse map[string]interface{}
 	}{
@@ -210,6 +218,7 @@ func TestStart(t *testing.T) {
 			&mockNATSConnection{},
 			&mockAlarmService{},
 			&mockCameraService{},
+			&mockRelationService{},
 			map[string]interface{}{},
 			nil,
 		},
@@ -220,6 +229,7 @@ func TestStart(t *testing.T) {
 				CreateOutAlarm: &model.Alarm{
 					Asset: model.Asset{
 						ID:       "aaaa-aaaa",
+						TenantID: "tttt-tttt",
 						SiteID:   "1111-1111",
 						SerialNo: "1001",
 					},
@@ -227,8 +237,10 @@ func TestStart(t *testing.T) {
 				},
 			},
 			&mockCameraService{},
+			&mockRelationService{},
 			map[string]interface{}{
-				"kind": createAlarm,
+				"kind":     createAlarm,
+				"tenantId": "tttt-tttt",
 				"input": map[string]interface{}{
 					"siteId":   "1111-1111",
 					"serialNo": "1001",
@@ -239,6 +251,7 @@ func TestStart(t *testing.T) {
 				"kind": createAlarm,
 				"alarm": map[string]interface{}{
 					"id":       "aaaa-aaaa",
+					"tenantId": "tttt-tttt",
 					"siteId":   "1111-1111",
 					"serialNo": "1001",
 					"material": "co",
@@ -253,6 +266,7 @@ func TestStart(t *testing.T) {
 					model.Alarm{
 						Asset: model.Asset{
 							ID:       "aaaa-aaaa",
+							TenantID: "tttt-tttt",
 							SiteID:   "1111-1111",
 							SerialNo: "1001",
 						},
@@ -261,15 +275,18 @@ func TestStart(t *testing.T) {
 				},
 			},
 			&mockCameraService{},
+			&mockRelationService{},
 			map[string]interface{}{
-				"kind":   allAlarm,
-				"siteId": "1111-1111",
+				"kind":     allAlarm,
+				"tenantId": "tttt-tttt",
+				"siteId":   "1111-1111",
 			},
 			map[string]interface{}{
 				"kind": allAlarm,
 				"alarms": []interface{}{
 					map[string]interface{}{
 						"id":       "aaaa-aaaa",
+						"tenantId": "tttt-tttt",
 						"siteId":   "1111-1111",
 						"serialNo": "1001",
 						"material": "co",
@@ -284,6 +301,7 @@ func TestStart(t *testing.T) {
 				GetOutAlarm: &model.Alarm{
 					Asset: model.Asset{
 						ID:       "aaaa-aaaa",
+						TenantID: "tttt-tttt",
 						SiteID:   "1111-1111",
 						SerialNo: "1001",
 					},
@@ -291,14 +309,17 @@ func TestStart(t *testing.T) {
 				},
 			},
 			&mockCameraService{},
+			&mockRelationService{},
 			map[string]interface{}{
-				"kind": getAlarm,
-				"id":   "aaaa-aaaa",
+				"kind":     getAlarm,
+				"tenantId": "tttt-tttt",
+				"id":       "aaaa-aaaa",
 			},
 			map[string]interface{}{
 				"kind": getAlarm,
 				"alarm": map[string]interface{}{
 					"id":       "aaaa-aaaa",
+					"tenantId": "tttt-tttt",
 					"siteId":   "1111-1111",
 					"serialNo": "1001",
 					"material": "co",
@@ -312,9 +333,11 @@ func TestStart(t *testing.T) {
 				UpdateOutUpdated: true,
 			},
 			&mockCameraService{},
+			&mockRelationService{},
 			map[string]interface{}{
-				"kind": updateAlarm,
-				"id":   "aaaa-aaaa",
+				"kind":     updateAlarm,
+				"tenantId": "tttt-tttt",
+				"id":       "aaaa-aaaa",
 				"input": map[string]interface{}{
 					"siteId":   "1111-1111",
 					"serialNo": "1002",
@@ -333,9 +356,11 @@ func TestStart(t *testing.T) {
 				DeleteOutDeleted: true,
 			},
 			&mockCameraService{},
+			&mockRelationService{},
 			map[string]interface{}{
-				"kind": deleteAlarm,
-				"id":   "aaaa-aaaa",
+				"kind":     deleteAlarm,
+				"tenantId": "tttt-tttt",
+				"id":       "aaaa-aaaa",
 			},
 			map[string]interface{}{
 				"kind":    deleteAlarm,
@@ -350,14 +375,17 @@ func TestStart(t *testing.T) {
 				CreateOutCamera: &model.Camera{
 					Asset: model.Asset{
 						ID:       "bbbb-bbbb",
+						TenantID: "tttt-tttt",
 						SiteID:   "1111-1111",
 						SerialNo: "2001",
 					},
 					Resolution: 921600,
 				},
 			},
+			&mockRelationService{},
 			map[string]interface{}{
-				"kind": createCamera,
+				"kind":     createCamera,
+				"tenantId": "tttt-tttt",
 				"input": map[string]interface{}{
 					"siteId":     "1111-1111",
 					"serialNo":   "2001",
@@ -368,6 +396,7 @@ func TestStart(t *testing.T) {
 				"kind": createCamera,
 				"camera": map[string]interface{}{
 					"id":         "bbbb-bbbb",
+					"tenantId":   "tttt-tttt",
 					"siteId":     "1111-1111",
 					"serialNo":   "2001",
 					"resolution": float64(921600),
@@ -383,6 +412,7 @@ func TestStart(t *testing.T) {
 					model.Camera{
 						Asset: model.Asset{
 							ID:       "bbbb-bbbb",
+							TenantID: "tttt-tttt",
 							SiteID:   "1111-1111",
 							SerialNo: "2001",
 						},
@@ -390,15 +420,18 @@ func TestStart(t *testing.T) {
 					},
 				},
 			},
+			&mockRelationService{},
 			map[string]interface{}{
-				"kind":   allCamera,
-				"siteId": "1111-1111",
+				"kind":     allCamera,
+				"tenantId": "tttt-tttt",
+				"siteId":   "1111-1111",
 			},
 			map[string]interface{}{
 				"kind": allCamera,
 				"cameras": []interface{}{
 					map[string]interface{}{
 						"id":         "bbbb-bbbb",
+						"tenantId":   "tttt-tttt",
 						"siteId":     "1111-1111",
 						"serialNo":   "2001",
 						"resolution": float64(921600),
@@ -414,20 +447,24 @@ func TestStart(t *testing.T) {
 				GetOutCamera: &model.Camera{
 					Asset: model.Asset{
 						ID:       "bbbb-bbbb",
+						TenantID: "tttt-tttt",
 						SiteID:   "1111-1111",
 						SerialNo: "2001",
 					},
 					Resolution: 921600,
 				},
 			},
+			&mockRelationService{},
 			map[string]interface{}{
-				"kind": getCamera,
-				"id":   "bbbb-bbbb",
+				"kind":     getCamera,
+				"tenantId": "tttt-tttt",
+				"id":       "bbbb-bbbb",
 			},
 			map[string]interface{}{
 				"kind": getCamera,
 				"camera": map[string]interface{}{
 					"id":         "bbbb-bbbb",
+					"tenantId":   "tttt-tttt",
 					"siteId":     "1111-1111",
 					"serialNo":   "2001",
 					"resolution": float64(921600),
@@ -441,9 +478,11 @@ func TestStart(t *testing.T) {
 			&mockCameraService{
 				UpdateOutUpdated: true,
 			},
+			&mockRelationService{},
 			map[string]interface{}{
-				"kind": updateCamera,
-				"id":   "bbbb-bbbb",
+				"kind":     updateCamera,
+				"tenantId": "tttt-tttt",
+				"id":       "bbbb-bbbb",
 				"input": map[string]interface{}{
 					"siteId":     "1111-1111",
 					"serialNo":   "2002",
@@ -462,15 +501,114 @@ func TestStart(t *testing.T) {
 			&mockCameraService{
 				DeleteOutDeleted: true,
 			},
+			&mockRelationService{},
 			map[string]interface{}{
-				"kind": deleteCamera,
-				"id":   "bbbb-bbbb",
+				"kind":     deleteCamera,
+				"tenantId": "tttt-tttt",
+				"id":       "bbbb-bbbb",
 			},
 			map[string]interface{}{
 				"kind":    deleteCamera,
 				"deleted": true,
 			},
 		},
+		{
+			"RelateAssets",
+			&mockNATSConnection{},
+			&mockAlarmService{},
+			&mockCameraService{},
+			&mockRelationService{
+				RelateOutRelation: &model.Relation{
+					SourceID: "bbbb-bbbb",
+					TargetID: "aaaa-aaaa",
+					Type:     model.RelationCovers,
+					TenantID: "tttt-tttt",
+				},
+			},
+			map[string]interface{}{
+				"kind":     relateAssets,
+				"tenantId": "tttt-tttt",
+				"relation": map[string]interface{}{
+					"sourceId": "bbbb-bbbb",
+					"targetId": "aaaa-aaaa",
+					"type":     "covers",
+				},
+			},
+			map[string]interface{}{
+				"kind": relateAssets,
+				"relation": map[string]interface{}{
+					"sourceId": "bbbb-bbbb",
+					"targetId": "aaaa-aaaa",
+					"type":     "covers",
+					"tenantId": "tttt-tttt",
+				},
+			},
+		},
+		{
+			"UnrelateAssets",
+			&mockNATSConnection{},
+			&mockAlarmService{},
+			&mockCameraService{},
+			&mockRelationService{
+				UnrelateOutUnrelated: true,
+			},
+			map[string]interface{}{
+				"kind":     unrelateAssets,
+				"tenantId": "tttt-tttt",
+				"relation": map[string]interface{}{
+					"sourceId": "bbbb-bbbb",
+					"targetId": "aaaa-aaaa",
+					"type":     "covers",
+				},
+			},
+			map[string]interface{}{
+				"kind":      unrelateAssets,
+				"unrelated": true,
+			},
+		},
+		{
+			"RelatedAssets",
+			&mockNATSConnection{},
+			&mockAlarmService{},
+			&mockCameraService{},
+			&mockRelationService{
+				RelatedOutAssets: []model.RelatedAsset{
+					model.RelatedAsset{
+						ID:    "bbbb-bbbb",
+						Depth: 1,
+						Relation: model.Relation{
+							SourceID: "bbbb-bbbb",
+							TargetID: "aaaa-aaaa",
+							Type:     model.RelationCovers,
+							TenantID: "tttt-tttt",
+						},
+					},
+				},
+			},
+			map[string]interface{}{
+				"kind":      relatedAssets,
+				"tenantId":  "tttt-tttt",
+				"id":        "aaaa-aaaa",
+				"direction": "incoming",
+				"types":     []string{"covers"},
+				"depth":     1,
+			},
+			map[string]interface{}{
+				"kind": relatedAssets,
+				"assets": []interface{}{
+					map[string]interface{}{
+						"id":    "bbbb-bbbb",
+						"depth": float64(1),
+						"relation": map[string]interface{}{
+							"sourceId": "bbbb-bbbb",
+							"targetId": "aaaa-aaaa",
+							"type":     "covers",
+							"tenantId": "tttt-tttt",
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -480,12 +618,13 @@ func TestStart(t *testing.T) {
 			tracer := mocktracer.New()
 
 			nt := &natsTransport{
-				logger:        logger,
-				metrics:       metrics,
-				tracer:        tracer,
-				conn:          tc.conn,
-				alarmService:  tc.alarmService,
-				cameraService: tc.cameraService,
+				logger:          logger,
+				metrics:         metrics,
+				tracer:          tracer,
+				conn:            tc.conn,
+				alarmService:    tc.alarmService,
+				cameraService:   tc.cameraService,
+				relationService: tc.relationService,
 			}
 
 			err := nt.Start()
@@ -518,6 +657,7 @@ func TestStart(t *testing.T) {
 				assert.Equal(t, "NATS", span.Tag("broker"))
 				assert.Equal(t, msg.Subject, span.Tag("subject"))
 				assert.Equal(t, msg.Reply, span.Tag("reply"))
+				assert.Equal(t, tc.request["tenantId"], span.Tag("tenant"))
 			}
 		})
 	}
@@ -557,3 +697,95 @@ func TestStop(t *testing.T) {
 		})
 	}
 }
+
+func TestNATSTransportInProcess(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	natsServer := natstest.RunServer(&opts)
+	defer natsServer.Shutdown()
+
+	logger := log.NewNopLogger()
+	metrics := metrics.New("unit-test")
+	tracer := mocktracer.New()
+
+	conn, err := queue.NewNATSConnection([]string{natsServer.ClientURL()}, "unit-test", "", "")
+	assert.NoError(t, err)
+
+	orm := db.NewMemoryORM()
+	alarmService := service.NewAlarmService(orm, 0, nil, logger, metrics, tracer)
+	cameraService := service.NewCameraService(orm, 0, nil, logger, metrics, tracer)
+	relationService := service.NewRelationService(orm, logger, metrics, tracer)
+
+	nt := NewNATSTransport(logger, metrics, tracer, conn, alarmService, cameraService, relationService)
+	assert.NoError(t, nt.Start())
+	defer nt.Stop(context.Background())
+
+	request := func(req map[string]interface{}) map[string]interface{} {
+		data, err := json.Marshal(req)
+		assert.NoError(t, err)
+
+		msg, err := conn.Request(subject, data, 2*time.Second)
+		assert.NoError(t, err)
+
+		var res map[string]interface{}
+		assert.NoError(t, json.Unmarshal(msg.Data, &res))
+		return res
+	}
+
+	res := request(map[string]interface{}{
+		"kind":     createCamera,
+		"tenantId": "tttt-tttt",
+		"input": map[string]interface{}{
+			"siteId":     "1111-1111",
+			"serialNo":   "2001",
+			"resolution": 921600,
+		},
+	})
+	camera := res["camera"].(map[string]interface{})
+	assert.Equal(t, "2001", camera["serialNo"])
+
+	res = request(map[string]interface{}{
+		"kind":     allCamera,
+		"tenantId": "tttt-tttt",
+		"siteId":   "1111-1111",
+	})
+	assert.Equal(t, []interface{}{camera}, res["cameras"])
+
+	res = request(map[string]interface{}{
+		"kind":     allCamera,
+		"tenantId": "uuuu-uuuu",
+		"siteId":   "1111-1111",
+	})
+	assert.Equal(t, []interface{}{}, res["cameras"])
+
+	res = request(map[string]interface{}{
+		"kind":     deleteCamera,
+		"tenantId": "uuuu-uuuu",
+		"id":       camera["id"],
+	})
+	assert.Equal(t, false, res["deleted"])
+
+	res = request(map[string]interface{}{
+		"kind":     updateCamera,
+		"tenantId": "tttt-tttt",
+		"id":       camera["id"],
+		"input": map[string]interface{}{
+			"resolution": 2073600,
+		},
+	})
+	assert.Equal(t, true, res["updated"])
+
+	res = request(map[string]interface{}{
+		"kind":     deleteCamera,
+		"tenantId": "tttt-tttt",
+		"id":       camera["id"],
+	})
+	assert.Equal(t, true, res["deleted"])
+
+	res = request(map[string]interface{}{
+		"kind":     deleteCamera,
+		"tenantId": "tttt-tttt",
+		"id":       camera["id"],
+	})
+	assert.Equal(t, false, res["deleted"])
+}
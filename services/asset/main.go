@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 
+	"github.com/moorara/microservices-demo/pkg/site"
 	"github.com/moorara/microservices-demo/services/asset/cmd/config"
 	"github.com/moorara/microservices-demo/services/asset/cmd/server"
 	"github.com/moorara/microservices-demo/services/asset/cmd/version"
@@ -18,7 +19,7 @@ import (
 
 func main() {
 	logger := log.NewLogger(config.Global.ServiceName, "singleton", config.Global.LogLevel)
-	metrics := metrics.New(config.Global.ServiceName)
+	metrics := metrics.New(config.Global.ServiceName, config.Global.MetricsTenants...)
 
 	// Tracer
 	sampler := trace.NewConstSampler()
@@ -33,17 +34,34 @@ func main() {
 		panic(err)
 	}
 
-	// CockroachDB ORM
-	orm, err := db.NewCockroachORM(config.Global.CockroachAddr, config.Global.CockroachUser, config.Global.CockroachPassword, config.Global.CockroachDatabase, logger)
-	if err != nil {
-		panic(err)
+	// CockroachDB ORM (or in-memory ORM for local development)
+	var orm db.ORM
+	if config.Global.CockroachAddr == db.MemoryAddr {
+		orm = db.NewMemoryORM()
+	} else {
+		orm, err = db.NewCockroachORM(config.Global.CockroachAddr, config.Global.CockroachUser, config.Global.CockroachPassword, config.Global.CockroachDatabase, logger)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Site service client (site validation is disabled without an address)
+	var sites site.Client
+	if config.Global.SiteServiceAddr != "" {
+		sites = site.NewClient(site.Config{
+			Addr:     config.Global.SiteServiceAddr,
+			CacheTTL: config.Global.SiteCacheTTL,
+			FailOpen: config.Global.SiteFailOpen,
+		})
 	}
 
-	alarmService := service.NewAlarmService(orm, logger, metrics, tracer)
-	cameraService := service.NewCameraService(orm, logger, metrics, tracer)
+	alarmService := service.NewAlarmService(orm, config.Global.TenantQuota, sites, logger, metrics, tracer)
+	cameraService := service.NewCameraService(orm, config.Global.TenantQuota, sites, logger, metrics, tracer)
+	relationService := service.NewRelationService(orm, logger, metrics, tracer)
+	labelService := service.NewLabelService(orm, logger, metrics, tracer)
 
-	natsTransport := transport.NewNATSTransport(logger, metrics, tracer, conn, alarmService, cameraService)
-	server := server.New(config.Global.ServicePort, natsTransport, logger, metrics)
+	natsTransport := transport.NewNATSTransport(logger, metrics, tracer, conn, alarmService, cameraService, relationService)
+	server := server.New(config.Global.ServicePort, natsTransport, labelService, logger, metrics, tracer)
 
 	logger.Info(
 		"version", version.Version,
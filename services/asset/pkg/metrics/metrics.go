@@ -4,12 +4,14 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/moorara/microservices-demo/pkg/tenant"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Metrics defines all the metrics
 type Metrics struct {
+	Tenants          *tenant.Labels
 	Registry         *prometheus.Registry
 	ReqCounter       *prometheus.CounterVec
 	OpLatencyHist    *prometheus.HistogramVec
@@ -19,7 +21,8 @@ type Metrics struct {
 }
 
 // New creates a new metrics
-func New(service string) *Metrics {
+// tenants are the tenants labeled by their id in the operation metrics.
+func New(service string, tenants ...string) *Metrics {
 	service = strings.Replace(service, "-", "_", -1)
 
 	registry := prometheus.NewRegistry()
@@ -44,7 +47,7 @@ func New(service string) *Metrics {
 			Help:      "latency of internal operations",
 			Buckets:   []float64{0.01, 0.10, 0.50, 1.00, 2.00},
 		},
-		[]string{"op", "success"},
+		[]string{"op", "success", "tenant"},
 	)
 
 	OpLatencySumm := prometheus.NewSummaryVec(
@@ -59,7 +62,7 @@ func New(service string) *Metrics {
 				0.99: 0.001,
 			},
 		},
-		[]string{"op", "success"},
+		[]string{"op", "success", "tenant"},
 	)
 
 	HTTPDurationHist := prometheus.NewHistogramVec(
@@ -91,7 +94,8 @@ func New(service string) *Metrics {
 	registry.MustRegister(HTTPDurationHist)
 	registry.MustRegister(HTTPDurationSumm)
 
-	return &Metrics{
+	m := &Metrics{
+		Tenants:          tenant.NewLabels(tenants...),
 		Registry:         registry,
 		ReqCounter:       ReqCounter,
 		OpLatencyHist:    OpLatencyHist,
@@ -99,6 +103,8 @@ func New(service string) *Metrics {
 		HTTPDurationHist: HTTPDurationHist,
 		HTTPDurationSumm: HTTPDurationSumm,
 	}
+
+	return m
 }
 
 // Handler returns http handler for metrics endpoint
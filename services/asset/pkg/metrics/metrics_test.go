@@ -19,6 +19,7 @@ func TestNew(t *testing.T) {
 		handler := metrics.Handler()
 
 		assert.NotNil(t, handler)
+		assert.NotNil(t, metrics.Tenants)
 		assert.NotNil(t, metrics.Registry)
 		assert.NotNil(t, metrics.ReqCounter)
 		assert.NotNil(t, metrics.OpLatencyHist)
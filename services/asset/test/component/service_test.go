@@ -43,7 +43,8 @@ func TestAPI(t *testing.T) {
 			"CreateAlarm",
 			"asset_service",
 			map[string]interface{}{
-				"kind": "createAlarm",
+				"kind":     "createAlarm",
+				"tenantId": "demo-tenant",
 				"input": map[string]interface{}{
 					"siteId":   "1111-1111",
 					"serialNo": "1001",
@@ -63,8 +64,9 @@ func TestAPI(t *testing.T) {
 			"AllAlarm",
 			"asset_service",
 			map[string]interface{}{
-				"kind":   "allAlarm",
-				"siteId": "0000-0000",
+				"kind":     "allAlarm",
+				"tenantId": "demo-tenant",
+				"siteId":   "0000-0000",
 			},
 			map[string]interface{}{
 				"kind":   "allAlarm",
@@ -75,8 +77,9 @@ func TestAPI(t *testing.T) {
 			"GetAlarm",
 			"asset_service",
 			map[string]interface{}{
-				"kind": "getAlarm",
-				"id":   "aaaa-aaaa",
+				"kind":     "getAlarm",
+				"tenantId": "demo-tenant",
+				"id":       "aaaa-aaaa",
 			},
 			map[string]interface{}{
 				"kind":  "getAlarm",
@@ -87,8 +90,9 @@ func TestAPI(t *testing.T) {
 			"UpdateAlarm",
 			"asset_service",
 			map[string]interface{}{
-				"kind": "updateAlarm",
-				"id":   "aaaa-aaaa",
+				"kind":     "updateAlarm",
+				"tenantId": "demo-tenant",
+				"id":       "aaaa-aaaa",
 				"input": map[string]interface{}{
 					"siteId":   "1111-1111",
 					"serialNo": "1001",
@@ -104,8 +108,9 @@ func TestAPI(t *testing.T) {
 			"DeleteAlarm",
 			"asset_service",
 			map[string]interface{}{
-				"kind": "deleteAlarm",
-				"id":   "aaaa-aaaa",
+				"kind":     "deleteAlarm",
+				"tenantId": "demo-tenant",
+				"id":       "aaaa-aaaa",
 			},
 			map[string]interface{}{
 				"kind":    "deleteAlarm",
@@ -116,7 +121,8 @@ func TestAPI(t *testing.T) {
 			"CreateCamera",
 			"asset_service",
 			map[string]interface{}{
-				"kind": "createCamera",
+				"kind":     "createCamera",
+				"tenantId": "demo-tenant",
 				"input": map[string]interface{}{
 					"siteId":     "1111-1111",
 					"serialNo":   "2001",
@@ -136,8 +142,9 @@ func TestAPI(t *testing.T) {
 			"AllCamera",
 			"asset_service",
 			map[string]interface{}{
-				"kind":   "allCamera",
-				"siteId": "0000-0000",
+				"kind":     "allCamera",
+				"tenantId": "demo-tenant",
+				"siteId":   "0000-0000",
 			},
 			map[string]interface{}{
 				"kind":    "allCamera",
@@ -148,8 +155,9 @@ func TestAPI(t *testing.T) {
 			"GetCamera",
 			"asset_service",
 			map[string]interface{}{
-				"kind": "getCamera",
-				"id":   "bbbb-bbbb",
+				"kind":     "getCamera",
+				"tenantId": "demo-tenant",
+				"id":       "bbbb-bbbb",
 			},
 			map[string]interface{}{
 				"kind":   "getCamera",
@@ -160,8 +168,9 @@ func TestAPI(t *testing.T) {
 			"UpdateCamera",
 			"asset_service",
 			map[string]interface{}{
-				"kind": "updateCamera",
-				"id":   "bbbb-bbbb",
+				"kind":     "updateCamera",
+				"tenantId": "demo-tenant",
+				"id":       "bbbb-bbbb",
 				"input": map[string]interface{}{
 					"siteId":     "1111-1111",
 					"serialNo":   "2001",
@@ -177,14 +186,47 @@ func TestAPI(t *testing.T) {
 			"DeleteCamera",
 			"asset_service",
 			map[string]interface{}{
-				"kind": "deleteCamera",
-				"id":   "bbbb-bbbb",
+				"kind":     "deleteCamera",
+				"tenantId": "demo-tenant",
+				"id":       "bbbb-bbbb",
 			},
 			map[string]interface{}{
 				"kind":    "deleteCamera",
 				"deleted": false,
 			},
 		},
+		{
+			"UnrelateAssets",
+			"asset_service",
+			map[string]interface{}{
+				"kind":     "unrelateAssets",
+				"tenantId": "demo-tenant",
+				"relation": map[string]interface{}{
+					"sourceId": "bbbb-bbbb",
+					"targetId": "aaaa-aaaa",
+					"type":     "covers",
+				},
+			},
+			map[string]interface{}{
+				"kind":      "unrelateAssets",
+				"unrelated": false,
+			},
+		},
+		{
+			"RelatedAssets",
+			"asset_service",
+			map[string]interface{}{
+				"kind":      "relatedAssets",
+				"tenantId":  "demo-tenant",
+				"id":        "aaaa-aaaa",
+				"direction": "incoming",
+				"depth":     2,
+			},
+			map[string]interface{}{
+				"kind":   "relatedAssets",
+				"assets": []interface{}{},
+			},
+		},
 	}
 
 	nats, err := queue.NewNATSConnection(Config.NatsServers, natsClientName, Config.NatsUser, Config.NatsPassword)
@@ -8,6 +8,7 @@ import (
 	"github.com/moorara/microservices-demo/services/asset/internal/db"
 	"github.com/moorara/microservices-demo/services/asset/internal/model"
 	"github.com/moorara/microservices-demo/services/asset/internal/service"
+	"github.com/moorara/microservices-demo/services/asset/internal/tenant"
 	"github.com/moorara/microservices-demo/services/asset/pkg/log"
 	"github.com/moorara/microservices-demo/services/asset/pkg/metrics"
 	opentracing "github.com/opentracing/opentracing-go"
@@ -24,6 +25,7 @@ func contextWithSpan() context.Context {
 	tracer := mocktracer.New()
 	span := tracer.StartSpan("test-span")
 	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	ctx = tenant.NewContext(ctx, "integration-test")
 	return ctx
 }
 
@@ -72,7 +74,7 @@ func TestCockroachORM(t *testing.T) {
 
 			// UPDATE
 			t.Run("Update", func(t *testing.T) {
-				err := orm.Model(&tc.update).Where("ID = ?", id).Update(tc.update).Error
+				err := orm.Update(&tc.update, tc.update, "ID = ?", id).Error
 				assert.NoError(t, err)
 			})
 
@@ -135,7 +137,7 @@ func TestAlarmService(t *testing.T) {
 	assert.NotNil(t, orm)
 	defer orm.Close()
 
-	alarmService := service.NewAlarmService(orm, logger, metrics, tracer)
+	alarmService := service.NewAlarmService(orm, 0, nil, logger, metrics, tracer)
 	assert.NotNil(t, alarmService)
 
 	for _, tc := range tests {
@@ -235,7 +237,7 @@ func TestCameraService(t *testing.T) {
 	assert.NotNil(t, orm)
 	defer orm.Close()
 
-	cameraService := service.NewCameraService(orm, logger, metrics, tracer)
+	cameraService := service.NewCameraService(orm, 0, nil, logger, metrics, tracer)
 	assert.NotNil(t, cameraService)
 
 	for _, tc := range tests {
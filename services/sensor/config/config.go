@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 const (
 	defaultLogLevel         = "info"
 	defaultServiceName      = "sensor-service"
@@ -11,6 +13,10 @@ const (
 	defaultPostgresPassword = ""
 	defaultJaegerAgentAddr  = "localhost:6831"
 	defaultJaegerLogSpans   = false
+	defaultTenantQuota      = 0
+	defaultSiteServiceAddr  = ""
+	defaultSiteCacheTTL     = 30 * time.Second
+	defaultSiteFailOpen     = false
 )
 
 // Config defines the schema for configurations
@@ -25,6 +31,11 @@ type Config struct {
 	PostgresPassword string
 	JaegerAgentAddr  string
 	JaegerLogSpans   bool
+	TenantQuota      int
+	MetricsTenants   []string
+	SiteServiceAddr  string
+	SiteCacheTTL     time.Duration
+	SiteFailOpen     bool
 }
 
 // New creates a new configuration object
@@ -40,5 +51,9 @@ func New() Config {
 		PostgresPassword: defaultPostgresPassword,
 		JaegerAgentAddr:  defaultJaegerAgentAddr,
 		JaegerLogSpans:   defaultJaegerLogSpans,
+		TenantQuota:      defaultTenantQuota,
+		SiteServiceAddr:  defaultSiteServiceAddr,
+		SiteCacheTTL:     defaultSiteCacheTTL,
+		SiteFailOpen:     defaultSiteFailOpen,
 	}
 }
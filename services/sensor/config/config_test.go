@@ -19,4 +19,9 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, defaultPostgresPassword, config.PostgresPassword)
 	assert.Equal(t, defaultJaegerAgentAddr, config.JaegerAgentAddr)
 	assert.Equal(t, defaultJaegerLogSpans, config.JaegerLogSpans)
+	assert.Equal(t, defaultTenantQuota, config.TenantQuota)
+	assert.Empty(t, config.MetricsTenants)
+	assert.Equal(t, defaultSiteServiceAddr, config.SiteServiceAddr)
+	assert.Equal(t, defaultSiteCacheTTL, config.SiteCacheTTL)
+	assert.Equal(t, defaultSiteFailOpen, config.SiteFailOpen)
 }
@@ -9,9 +9,12 @@ require (
 	github.com/gorilla/mux v1.7.4
 	github.com/lib/pq v1.8.0
 	github.com/moorara/konfig v0.4.1
+	github.com/moorara/microservices-demo/pkg v0.0.0
 	github.com/opentracing/opentracing-go v1.2.0
 	github.com/prometheus/client_golang v1.7.1
 	github.com/stretchr/testify v1.6.1
 	github.com/uber/jaeger-client-go v2.25.0+incompatible
 	github.com/uber/jaeger-lib v2.2.0+incompatible
 )
+
+replace github.com/moorara/microservices-demo/pkg => ../../pkg
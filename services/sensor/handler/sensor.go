@@ -6,6 +6,7 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/gorilla/mux"
+	"github.com/moorara/microservices-demo/pkg/site"
 	"github.com/moorara/microservices-demo/services/sensor/service"
 	"github.com/opentracing/opentracing-go"
 )
@@ -18,6 +19,8 @@ type (
 		GetSensor(w http.ResponseWriter, r *http.Request)
 		PutSensor(w http.ResponseWriter, r *http.Request)
 		DeleteSensor(w http.ResponseWriter, r *http.Request)
+		PutReading(w http.ResponseWriter, r *http.Request)
+		GetReading(w http.ResponseWriter, r *http.Request)
 	}
 
 	postgresSensorHandler struct {
@@ -27,9 +30,9 @@ type (
 )
 
 // NewSensorHandler creates a new sensor handler
-func NewSensorHandler(db service.DB, logger log.Logger, tracer opentracing.Tracer) SensorHandler {
+func NewSensorHandler(db service.DB, tenantQuota int, sites site.Client, logger log.Logger, tracer opentracing.Tracer) SensorHandler {
 	return &postgresSensorHandler{
-		manager: service.NewSensorManager(db, logger, tracer),
+		manager: service.NewSensorManager(db, tenantQuota, sites, logger, tracer),
 		logger:  logger,
 	}
 }
@@ -53,8 +56,11 @@ func (h *postgresSensorHandler) PostSensor(w http.ResponseWriter, r *http.Reques
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
 	sensor, err := h.manager.Create(r.Context(), s.SiteID, s.Name, s.Unit, s.MinSafe, s.MaxSafe)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if err == service.ErrQuotaExceeded {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	} else if err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -78,7 +84,7 @@ func (h *postgresSensorHandler) GetSensors(w http.ResponseWriter, r *http.Reques
 
 	sensors, err := h.manager.All(r.Context(), siteID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(statusCode(err))
 		return
 	}
 
@@ -102,7 +108,7 @@ func (h *postgresSensorHandler) GetSensor(w http.ResponseWriter, r *http.Request
 
 	sensor, err := h.manager.Get(r.Context(), sensorID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(statusCode(err))
 		return
 	}
 
@@ -140,7 +146,7 @@ func (h *postgresSensorHandler) PutSensor(w http.ResponseWriter, r *http.Request
 
 	n, err := h.manager.Update(r.Context(), s)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -163,9 +169,94 @@ func (h *postgresSensorHandler) DeleteSensor(w http.ResponseWriter, r *http.Requ
 
 	err := h.manager.Delete(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(statusCode(err))
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *postgresSensorHandler) PutReading(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s := struct {
+		Value *float64 `json:"value"`
+	}{}
+
+	defer r.Body.Close()
+	err := json.NewDecoder(r.Body).Decode(&s)
+	if err != nil || s.Value == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	n, err := h.manager.SetReading(r.Context(), id, *s.Value)
+	if err != nil {
+		w.WriteHeader(statusCode(err))
+		return
+	}
+
+	if n == 0 {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (h *postgresSensorHandler) GetReading(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	reading, err := h.manager.GetReading(r.Context(), id)
+	if err != nil {
+		w.WriteHeader(statusCode(err))
+		return
+	}
+
+	if reading == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(reading)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// statusCode maps an error from sensor manager to a http status code
+func statusCode(err error) int {
+	switch err {
+	case service.ErrNoTenant, site.ErrUnknownSite:
+		return http.StatusBadRequest
+	case site.ErrUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError writes the status code for an error and explains validation errors in the body
+func writeError(w http.ResponseWriter, err error) {
+	w.WriteHeader(statusCode(err))
+	if err == site.ErrUnknownSite {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"error": err.Error(),
+		})
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/gorilla/mux"
+	"github.com/moorara/microservices-demo/pkg/site"
 	"github.com/moorara/microservices-demo/services/sensor/service"
 	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
@@ -35,6 +37,14 @@ type mockSensorManager struct {
 
 	DeleteCalled bool
 	DeleteError  error
+
+	SetReadingCalled bool
+	SetReadingResult int
+	SetReadingError  error
+
+	GetReadingCalled bool
+	GetReadingResult *service.Reading
+	GetReadingError  error
 }
 
 func (m *mockSensorManager) Create(ctx context.Context, siteID string, name, unit string, minSafe, maxSafe float64) (*service.Sensor, error) {
@@ -62,6 +72,16 @@ func (m *mockSensorManager) Delete(ctx context.Context, id string) error {
 	return m.DeleteError
 }
 
+func (m *mockSensorManager) SetReading(ctx context.Context, id string, value float64) (int, error) {
+	m.SetReadingCalled = true
+	return m.SetReadingResult, m.SetReadingError
+}
+
+func (m *mockSensorManager) GetReading(ctx context.Context, id string) (*service.Reading, error) {
+	m.GetReadingCalled = true
+	return m.GetReadingResult, m.GetReadingError
+}
+
 func TestNewSensorHandler(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -99,7 +119,7 @@ func TestNewSensorHandler(t *testing.T) {
 			logger := log.NewNopLogger()
 			tracer := mocktracer.New()
 			db := service.NewPostgresDB(logger, tc.host, tc.port, tc.database, tc.username, tc.password)
-			h := NewSensorHandler(db, logger, tracer)
+			h := NewSensorHandler(db, 0, nil, logger, tracer)
 
 			assert.NotNil(t, h)
 		})
@@ -143,7 +163,34 @@ func TestPostSensor(t *testing.T) {
 			400,
 			``,
 		},
-		// TODO
+		{
+			"NoTenant",
+			nil, service.ErrNoTenant,
+			`{"siteId": "1111-aaaa", "name": "temperature", "unit": "celsius", "minSafe": -30, "maxSafe": 30}`,
+			400,
+			``,
+		},
+		{
+			"QuotaExceeded",
+			nil, service.ErrQuotaExceeded,
+			`{"siteId": "1111-aaaa", "name": "temperature", "unit": "celsius", "minSafe": -30, "maxSafe": 30}`,
+			429,
+			``,
+		},
+		{
+			"UnknownSite",
+			nil, site.ErrUnknownSite,
+			`{"siteId": "1111-aaaa", "name": "temperature", "unit": "celsius", "minSafe": -30, "maxSafe": 30}`,
+			400,
+			`{"error":"unknown site id"}`,
+		},
+		{
+			"SiteServiceUnavailable",
+			nil, site.ErrUnavailable,
+			`{"siteId": "1111-aaaa", "name": "temperature", "unit": "celsius", "minSafe": -30, "maxSafe": 30}`,
+			503,
+			``,
+		},
 		{
 			"SensorManagerError",
 			nil, errors.New("error"),
@@ -154,17 +201,18 @@ func TestPostSensor(t *testing.T) {
 		{
 			"Successful",
 			&service.Sensor{
-				ID:      "2222-bbbb",
-				SiteID:  "1111-aaaa",
-				Name:    "temperature",
-				Unit:    "celsius",
-				MinSafe: -30.0,
-				MaxSafe: 30.0,
+				ID:       "2222-bbbb",
+				TenantID: "tttt-tttt",
+				SiteID:   "1111-aaaa",
+				Name:     "temperature",
+				Unit:     "celsius",
+				MinSafe:  -30.0,
+				MaxSafe:  30.0,
 			},
 			nil,
 			`{"siteId": "1111-aaaa", "name": "temperature", "unit": "celsius", "minSafe": -30, "maxSafe": 30}`,
 			201,
-			`{"id":"2222-bbbb","siteId":"1111-aaaa","name":"temperature","unit":"celsius","minSafe":-30,"maxSafe":30}`,
+			`{"id":"2222-bbbb","tenantId":"tttt-tttt","siteId":"1111-aaaa","name":"temperature","unit":"celsius","minSafe":-30,"maxSafe":30}`,
 		},
 	}
 
@@ -190,7 +238,7 @@ func TestPostSensor(t *testing.T) {
 			assert.NoError(t, err)
 
 			assert.Equal(t, tc.expectedStatus, res.StatusCode)
-			if tc.expectedStatus == http.StatusCreated {
+			if tc.expectedResBody != "" {
 				assert.Contains(t, string(body), tc.expectedResBody)
 			}
 		})
@@ -223,13 +271,13 @@ func TestGetSensors(t *testing.T) {
 		{
 			"Successful",
 			[]service.Sensor{
-				{ID: "2222-bbbb", SiteID: "1111-aaaa", Name: "temperature", Unit: "celsius", MinSafe: -30.0, MaxSafe: 30.0},
-				{ID: "4444-dddd", SiteID: "1111-aaaa", Name: "temperature", Unit: "fahrenheit", MinSafe: -22.0, MaxSafe: 86.0},
+				{ID: "2222-bbbb", TenantID: "tttt-tttt", SiteID: "1111-aaaa", Name: "temperature", Unit: "celsius", MinSafe: -30.0, MaxSafe: 30.0},
+				{ID: "4444-dddd", TenantID: "tttt-tttt", SiteID: "1111-aaaa", Name: "temperature", Unit: "fahrenheit", MinSafe: -22.0, MaxSafe: 86.0},
 			},
 			nil,
 			"1111-aaaa",
 			200,
-			`[{"id":"2222-bbbb","siteId":"1111-aaaa","name":"temperature","unit":"celsius","minSafe":-30,"maxSafe":30},{"id":"4444-dddd","siteId":"1111-aaaa","name":"temperature","unit":"fahrenheit","minSafe":-22,"maxSafe":86}]`,
+			`[{"id":"2222-bbbb","tenantId":"tttt-tttt","siteId":"1111-aaaa","name":"temperature","unit":"celsius","minSafe":-30,"maxSafe":30},{"id":"4444-dddd","tenantId":"tttt-tttt","siteId":"1111-aaaa","name":"temperature","unit":"fahrenheit","minSafe":-22,"maxSafe":86}]`,
 		},
 	}
 
@@ -296,17 +344,18 @@ func TestGetSensor(t *testing.T) {
 		{
 			"Successful",
 			&service.Sensor{
-				ID:      "2222-bbbb",
-				SiteID:  "1111-aaaa",
-				Name:    "temperature",
-				Unit:    "celsius",
-				MinSafe: -30.0,
-				MaxSafe: 30.0,
+				ID:       "2222-bbbb",
+				TenantID: "tttt-tttt",
+				SiteID:   "1111-aaaa",
+				Name:     "temperature",
+				Unit:     "celsius",
+				MinSafe:  -30.0,
+				MaxSafe:  30.0,
 			},
 			nil,
 			"1111-aaaa",
 			200,
-			`{"id":"2222-bbbb","siteId":"1111-aaaa","name":"temperature","unit":"celsius","minSafe":-30,"maxSafe":30}`,
+			`{"id":"2222-bbbb","tenantId":"tttt-tttt","siteId":"1111-aaaa","name":"temperature","unit":"celsius","minSafe":-30,"maxSafe":30}`,
 		},
 	}
 
@@ -398,6 +447,14 @@ func TestPutSensor(t *testing.T) {
 			400,
 			``,
 		},
+		{
+			"UnknownSite",
+			0,
+			site.ErrUnknownSite,
+			"2222-bbbb", `{"siteId": "0000-0000", "name": "temperature", "unit": "fahrenheit", "minSafe": -22, "maxSafe": 86}`,
+			400,
+			``,
+		},
 		{
 			"SensorManagerError",
 			0,
@@ -520,3 +577,159 @@ func TestDeleteSensor(t *testing.T) {
 		})
 	}
 }
+
+func TestPutReading(t *testing.T) {
+	tests := []struct {
+		name             string
+		setReadingResult int
+		setReadingError  error
+		sensorID         string
+		reqBody          string
+		expectedStatus   int
+	}{
+		{
+			"NoSensorID",
+			0,
+			nil,
+			"", ``,
+			404,
+		},
+		{
+			"InvalidJSON",
+			0,
+			nil,
+			"2222-bbbb", `{`,
+			400,
+		},
+		{
+			"NoValue",
+			0,
+			nil,
+			"2222-bbbb", `{}`,
+			400,
+		},
+		{
+			"NoTenant",
+			0,
+			service.ErrNoTenant,
+			"2222-bbbb", `{"value": 21.5}`,
+			400,
+		},
+		{
+			"SensorManagerError",
+			0,
+			errors.New("error"),
+			"2222-bbbb", `{"value": 21.5}`,
+			500,
+		},
+		{
+			"SensorNotFound",
+			0,
+			nil,
+			"0000-0000", `{"value": 21.5}`,
+			404,
+		},
+		{
+			"Successful",
+			1,
+			nil,
+			"2222-bbbb", `{"value": 0}`,
+			204,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &mockSensorManager{
+				SetReadingResult: tc.setReadingResult,
+				SetReadingError:  tc.setReadingError,
+			}
+
+			h := &postgresSensorHandler{
+				manager: m,
+				logger:  log.NewNopLogger(),
+			}
+
+			mr := mux.NewRouter()
+			mr.Methods("PUT").Path("/sensors/{id}/reading").HandlerFunc(h.PutReading)
+			ts := httptest.NewServer(mr)
+			defer ts.Close()
+
+			reqBody := strings.NewReader(tc.reqBody)
+			req, err := http.NewRequest("PUT", ts.URL+"/sensors/"+tc.sensorID+"/reading", reqBody)
+			assert.NoError(t, err)
+			client := &http.Client{}
+			res, err := client.Do(req)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestGetReading(t *testing.T) {
+	tests := []struct {
+		name             string
+		getReadingResult *service.Reading
+		getReadingError  error
+		sensorID         string
+		expectedStatus   int
+		expectedResBody  string
+	}{
+		{
+			"SensorManagerError",
+			nil, errors.New("error"),
+			"2222-bbbb",
+			500,
+			``,
+		},
+		{
+			"NoReadingFound",
+			nil, nil,
+			"2222-bbbb",
+			404,
+			``,
+		},
+		{
+			"Successful",
+			&service.Reading{
+				SensorID: "2222-bbbb",
+				Value:    21.5,
+				Time:     time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC),
+			},
+			nil,
+			"2222-bbbb",
+			200,
+			`{"sensorId":"2222-bbbb","value":21.5,"time":"2020-06-01T12:00:00Z"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &mockSensorManager{
+				GetReadingResult: tc.getReadingResult,
+				GetReadingError:  tc.getReadingError,
+			}
+
+			h := &postgresSensorHandler{
+				manager: m,
+				logger:  log.NewNopLogger(),
+			}
+
+			mr := mux.NewRouter()
+			mr.Methods("GET").Path("/sensors/{id}/reading").HandlerFunc(h.GetReading)
+			ts := httptest.NewServer(mr)
+			defer ts.Close()
+
+			res, err := http.Get(ts.URL + "/sensors/" + tc.sensorID + "/reading")
+			assert.NoError(t, err)
+			body, err := ioutil.ReadAll(res.Body)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatus, res.StatusCode)
+			if tc.expectedStatus == http.StatusOK {
+				assert.Contains(t, string(body), tc.expectedResBody)
+			}
+		})
+	}
+}
@@ -14,12 +14,13 @@ import (
 var (
 	histogramName    = "http_requests_duration_seconds"
 	summaryName      = "http_requests_duration_quantiles_seconds"
-	defaultLabels    = []string{"method", "endpoint", "statusCode", "statusClass"}
+	defaultLabels    = []string{"method", "endpoint", "statusCode", "statusClass", "tenant"}
 	defaultBuckets   = []float64{0.01, 0.1, 0.5, 1.}
 	defaultQuantiles = map[float64]float64{0.1: 0.1, 0.5: 0.05, 0.95: 0.01, 0.99: 0.001}
 )
 
 type metricsMiddleware struct {
+	metrics   *util.Metrics
 	histogram *prometheus.HistogramVec
 	summary   *prometheus.SummaryVec
 }
@@ -27,6 +28,7 @@ type metricsMiddleware struct {
 // NewMetricsMiddleware creates a new middleware for metrics
 func NewMetricsMiddleware(metrics *util.Metrics) Middleware {
 	return &metricsMiddleware{
+		metrics: metrics,
 		histogram: metrics.NewHistogram(
 			false,
 			histogramName,
@@ -49,6 +51,7 @@ func (m *metricsMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc {
 		start := time.Now()
 		method := r.Method
 		endpoint := r.URL.Path
+		tenant := m.metrics.Tenants.Label(r.Header.Get(util.TenantHeader))
 
 		// This only works with mux router
 		for p, v := range mux.Vars(r) {
@@ -62,7 +65,7 @@ func (m *metricsMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc {
 		statusCode := strconv.Itoa(rw.StatusCode())
 		statusClass := rw.StatusClass()
 
-		m.histogram.WithLabelValues(method, endpoint, statusCode, statusClass).Observe(duration)
-		m.summary.WithLabelValues(method, endpoint, statusCode, statusClass).Observe(duration)
+		m.histogram.WithLabelValues(method, endpoint, statusCode, statusClass, tenant).Observe(duration)
+		m.summary.WithLabelValues(method, endpoint, statusCode, statusClass, tenant).Observe(duration)
 	}
 }
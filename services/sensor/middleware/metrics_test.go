@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/moorara/microservices-demo/pkg/tenant"
 	"github.com/moorara/microservices-demo/services/sensor/util"
 	"github.com/stretchr/testify/assert"
 )
@@ -39,3 +40,37 @@ func TestGetMetricsWrapper(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricsTenantLabel(t *testing.T) {
+	metrics := util.NewMetrics("go_service", "tttt-tttt")
+	metricsMiddleware := NewMetricsMiddleware(metrics)
+
+	handler := metricsMiddleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tenant := range []string{"tttt-tttt", "uuuu-uuuu", "vvvv-vvvv"} {
+		r := httptest.NewRequest("GET", "http://service/resource", nil)
+		r.Header.Set(util.TenantHeader, tenant)
+		handler(httptest.NewRecorder(), r)
+	}
+
+	families, err := metrics.Registry.Gather()
+	assert.NoError(t, err)
+
+	tenants := map[string]bool{}
+	for _, mf := range families {
+		if mf.GetName() != histogramName {
+			continue
+		}
+		for _, m := range mf.Metric {
+			for _, l := range m.Label {
+				if l.GetName() == "tenant" {
+					tenants[l.GetValue()] = true
+				}
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]bool{"tttt-tttt": true, tenant.Other: true}, tenants)
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/moorara/microservices-demo/services/sensor/util"
+)
+
+type tenantMiddleware struct{}
+
+// NewTenantMiddleware creates a new middleware for requiring a tenant on every request
+func NewTenantMiddleware() Middleware {
+	return &tenantMiddleware{}
+}
+
+func (m *tenantMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID := r.Header.Get(util.TenantHeader)
+		if tenantID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		ctx := util.ContextWithTenant(r.Context(), tenantID)
+		next(w, r.WithContext(ctx))
+	}
+}
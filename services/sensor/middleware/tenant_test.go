@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moorara/microservices-demo/services/sensor/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	tests := []struct {
+		name               string
+		tenantID           string
+		expectedCalled     bool
+		expectedStatusCode int
+	}{
+		{"NoTenant", "", false, 400},
+		{"WithTenant", "tttt-tttt", true, 200},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tenantMiddleware := NewTenantMiddleware()
+
+			r := httptest.NewRequest("GET", "http://service/resource", nil)
+			r.Header.Set(util.TenantHeader, tc.tenantID)
+			w := httptest.NewRecorder()
+
+			called := false
+			handler := tenantMiddleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				tenantID, ok := util.TenantFromContext(r.Context())
+				assert.True(t, ok)
+				assert.Equal(t, tc.tenantID, tenantID)
+				w.WriteHeader(http.StatusOK)
+			})
+			handler(w, r)
+			res := w.Result()
+
+			assert.Equal(t, tc.expectedCalled, called)
+			assert.Equal(t, tc.expectedStatusCode, res.StatusCode)
+		})
+	}
+}
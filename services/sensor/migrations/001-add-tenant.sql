@@ -0,0 +1,13 @@
+-- Scope the sensors of a database created before tenants to a tenant
+-- Run with the tenant the existing sensors belong to:
+--   psql -d sensors -v tenant=demo-tenant -f migrations/001-add-tenant.sql
+
+BEGIN;
+
+ALTER TABLE sensors ADD COLUMN IF NOT EXISTS tenant_id varchar(256);
+UPDATE sensors SET tenant_id = :'tenant' WHERE tenant_id IS NULL;
+ALTER TABLE sensors ALTER COLUMN tenant_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS sensors_tenant_id_idx ON sensors (tenant_id);
+
+COMMIT;
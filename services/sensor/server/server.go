@@ -8,6 +8,7 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/gorilla/mux"
+	"github.com/moorara/microservices-demo/pkg/site"
 	"github.com/moorara/microservices-demo/services/sensor/config"
 	"github.com/moorara/microservices-demo/services/sensor/handler"
 	"github.com/moorara/microservices-demo/services/sensor/middleware"
@@ -33,21 +34,34 @@ type (
 
 // New creates a new http server
 func New(config config.Config) *HTTPServer {
-	metrics := util.NewMetrics("sensor_service")
+	metrics := util.NewMetrics("sensor_service", config.MetricsTenants...)
 	logger := util.NewLogger(config.LogLevel, config.ServiceName, "singleton")
 	tracer, tracerCloser := util.NewTracer(config, logger, metrics.Registry)
 
 	metricsMiddleware := middleware.NewMetricsMiddleware(metrics)
 	loggerMiddleware := middleware.NewLoggerMiddleware(logger)
 	tracerMiddleware := middleware.NewTracerMiddleware(tracer)
+	tenantMiddleware := middleware.NewTenantMiddleware()
 
 	postgresDB := service.NewPostgresDB(logger, config.PostgresHost, config.PostgresPort, config.PostgresDatabase, config.PostgresUsername, config.PostgresPassword)
-	sensorHandler := handler.NewSensorHandler(postgresDB, logger, tracer)
-	postSensorHandler := middleware.WrapAll(sensorHandler.PostSensor, metricsMiddleware, loggerMiddleware, tracerMiddleware)
-	getSensorsHandler := middleware.WrapAll(sensorHandler.GetSensors, metricsMiddleware, loggerMiddleware, tracerMiddleware)
-	getSensorHandler := middleware.WrapAll(sensorHandler.GetSensor, metricsMiddleware, loggerMiddleware, tracerMiddleware)
-	putSensorHandler := middleware.WrapAll(sensorHandler.PutSensor, metricsMiddleware, loggerMiddleware, tracerMiddleware)
-	deleteSensorHandler := middleware.WrapAll(sensorHandler.DeleteSensor, metricsMiddleware, loggerMiddleware, tracerMiddleware)
+	// Site validation is disabled without a site service address
+	var sites site.Client
+	if config.SiteServiceAddr != "" {
+		sites = site.NewClient(site.Config{
+			Addr:     config.SiteServiceAddr,
+			CacheTTL: config.SiteCacheTTL,
+			FailOpen: config.SiteFailOpen,
+		})
+	}
+
+	sensorHandler := handler.NewSensorHandler(postgresDB, config.TenantQuota, sites, logger, tracer)
+	postSensorHandler := middleware.WrapAll(sensorHandler.PostSensor, metricsMiddleware, loggerMiddleware, tracerMiddleware, tenantMiddleware)
+	getSensorsHandler := middleware.WrapAll(sensorHandler.GetSensors, metricsMiddleware, loggerMiddleware, tracerMiddleware, tenantMiddleware)
+	getSensorHandler := middleware.WrapAll(sensorHandler.GetSensor, metricsMiddleware, loggerMiddleware, tracerMiddleware, tenantMiddleware)
+	putSensorHandler := middleware.WrapAll(sensorHandler.PutSensor, metricsMiddleware, loggerMiddleware, tracerMiddleware, tenantMiddleware)
+	deleteSensorHandler := middleware.WrapAll(sensorHandler.DeleteSensor, metricsMiddleware, loggerMiddleware, tracerMiddleware, tenantMiddleware)
+	putReadingHandler := middleware.WrapAll(sensorHandler.PutReading, metricsMiddleware, loggerMiddleware, tracerMiddleware, tenantMiddleware)
+	getReadingHandler := middleware.WrapAll(sensorHandler.GetReading, metricsMiddleware, loggerMiddleware, tracerMiddleware, tenantMiddleware)
 
 	router := mux.NewRouter()
 	router.NotFoundHandler = middleware.WrapAll(handler.GetNotFoundHandler(logger), loggerMiddleware, tracerMiddleware)
@@ -58,6 +72,8 @@ func New(config config.Config) *HTTPServer {
 	router.Methods("GET").Path("/v1/sensors/{id}").HandlerFunc(getSensorHandler)
 	router.Methods("PUT").Path("/v1/sensors/{id}").HandlerFunc(putSensorHandler)
 	router.Methods("DELETE").Path("/v1/sensors/{id}").HandlerFunc(deleteSensorHandler)
+	router.Methods("PUT").Path("/v1/sensors/{id}/reading").HandlerFunc(putReadingHandler)
+	router.Methods("GET").Path("/v1/sensors/{id}/reading").HandlerFunc(getReadingHandler)
 
 	return &HTTPServer{
 		config:  config,
@@ -12,11 +12,17 @@ import (
 type (
 	// DB is the interface for a sql database
 	DB interface {
+		BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
 		Close() error
 		ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
 		QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
 		QueryRowContext(context.Context, string, ...interface{}) *sql.Row
 	}
+
+	// execer runs statements on a DB or in a transaction
+	execer interface {
+		ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	}
 )
 
 // NewPostgresDB creates a new DB for PostgreSQL
@@ -3,10 +3,14 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/google/uuid"
+	"github.com/moorara/microservices-demo/pkg/site"
+	"github.com/moorara/microservices-demo/services/sensor/util"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 
@@ -14,22 +18,46 @@ import (
 )
 
 const (
-	queryCreate = `INSERT INTO sensors (id, site_id, name, unit, min_safe, max_safe) VALUES ($1, $2, $3, $4, $5, $6)`
-	queryAll    = `SELECT id, site_id, name, unit, min_safe, max_safe FROM sensors WHERE site_id = $1`
-	queryGet    = `SELECT id, site_id, name, unit, min_safe, max_safe FROM sensors WHERE id = $1`
-	queryUpdate = `UPDATE sensors SET site_id = $2, name = $3, unit = $4, min_safe = $5, max_safe = $6 WHERE id = $1`
-	queryDelete = `DELETE FROM sensors WHERE id = $1`
+	// Creates of the same tenant wait for each other's transaction from the lock on, so they cannot count the same sensors and exceed the quota together
+	queryLockTenant = `SELECT pg_advisory_xact_lock(hashtext($1))`
+
+	queryCount  = `SELECT COUNT(*) FROM sensors WHERE tenant_id = $1`
+	queryCreate = `INSERT INTO sensors (id, tenant_id, site_id, name, unit, min_safe, max_safe) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	queryAll    = `SELECT id, tenant_id, site_id, name, unit, min_safe, max_safe FROM sensors WHERE tenant_id = $1 AND site_id = $2`
+	queryGet    = `SELECT id, tenant_id, site_id, name, unit, min_safe, max_safe FROM sensors WHERE tenant_id = $1 AND id = $2`
+	queryUpdate = `UPDATE sensors SET site_id = $3, name = $4, unit = $5, min_safe = $6, max_safe = $7 WHERE tenant_id = $1 AND id = $2`
+	queryDelete = `DELETE FROM sensors WHERE tenant_id = $1 AND id = $2`
+
+	// A reading is only stored for a sensor of the tenant and replaces the previous one
+	querySetReading = `INSERT INTO sensor_readings (sensor_id, tenant_id, value, time) SELECT id, tenant_id, $3, $4 FROM sensors WHERE tenant_id = $1 AND id = $2 ON CONFLICT (sensor_id) DO UPDATE SET value = EXCLUDED.value, time = EXCLUDED.time`
+	queryGetReading = `SELECT sensor_id, value, time FROM sensor_readings WHERE tenant_id = $1 AND sensor_id = $2`
+)
+
+var (
+	// ErrNoTenant is returned when a request does not carry a tenant identifier
+	ErrNoTenant = errors.New("tenant id required")
+
+	// ErrQuotaExceeded is returned when a tenant has reached its quota of sensors
+	ErrQuotaExceeded = errors.New("tenant quota exceeded")
 )
 
 type (
 	// Sensor represents a Sensor in a site
 	Sensor struct {
-		ID      string  `json:"id"`
-		SiteID  string  `json:"siteId"`
-		Name    string  `json:"name"`
-		Unit    string  `json:"unit"`
-		MinSafe float64 `json:"minSafe"`
-		MaxSafe float64 `json:"maxSafe"`
+		ID       string  `json:"id"`
+		TenantID string  `json:"tenantId"`
+		SiteID   string  `json:"siteId"`
+		Name     string  `json:"name"`
+		Unit     string  `json:"unit"`
+		MinSafe  float64 `json:"minSafe"`
+		MaxSafe  float64 `json:"maxSafe"`
+	}
+
+	// Reading is the latest value measured by a Sensor
+	Reading struct {
+		SensorID string    `json:"sensorId"`
+		Value    float64   `json:"value"`
+		Time     time.Time `json:"time"`
 	}
 
 	// SensorManager abstracts CRUD operations for Sensor
@@ -39,19 +67,27 @@ type (
 		Get(ctx context.Context, id string) (*Sensor, error)
 		Update(ctx context.Context, s Sensor) (int, error)
 		Delete(ctx context.Context, id string) error
+		SetReading(ctx context.Context, id string, value float64) (int, error)
+		GetReading(ctx context.Context, id string) (*Reading, error)
 	}
 
 	postgresSensorManager struct {
 		db     DB
+		quota  int
+		sites  site.Client
 		logger log.Logger
 		tracer opentracing.Tracer
 	}
 )
 
-// NewSensorManager creates a new sensor manager
-func NewSensorManager(db DB, logger log.Logger, tracer opentracing.Tracer) SensorManager {
+// NewSensorManager creates a new sensor manager.
+// quota is the maximum number of sensors a tenant can have and zero means no limit.
+// sites validates the site of new and updated sensors and nil disables the validation.
+func NewSensorManager(db DB, quota int, sites site.Client, logger log.Logger, tracer opentracing.Tracer) SensorManager {
 	return &postgresSensorManager{
 		db:     db,
+		quota:  quota,
+		sites:  sites,
 		logger: logger,
 		tracer: tracer,
 	}
@@ -82,20 +118,66 @@ func (m *postgresSensorManager) exec(ctx context.Context, name, query string, fn
 }
 
 func (m *postgresSensorManager) Create(ctx context.Context, siteID, name, unit string, minSafe, maxSafe float64) (*Sensor, error) {
+	tenantID, ok := util.TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	if m.sites != nil {
+		if err := m.sites.Validate(ctx, siteID); err != nil {
+			return nil, err
+		}
+	}
+
 	sensor := &Sensor{
-		ID:      uuid.New().String(),
-		SiteID:  siteID,
-		Name:    name,
-		Unit:    unit,
-		MinSafe: minSafe,
-		MaxSafe: maxSafe,
+		ID:       uuid.New().String(),
+		TenantID: tenantID,
+		SiteID:   siteID,
+		Name:     name,
+		Unit:     unit,
+		MinSafe:  minSafe,
+		MaxSafe:  maxSafe,
+	}
+
+	var err error
+	var tx *sql.Tx
+	var db execer = m.db
+
+	// The sensors are counted and the new one is inserted in one transaction holding the lock of the tenant
+	if m.quota > 0 {
+		if tx, err = m.db.BeginTx(ctx, nil); err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+
+		var count int
+		err = m.exec(ctx, "count-records", queryCount, func() error {
+			if _, err := tx.ExecContext(ctx, queryLockTenant, tenantID); err != nil {
+				return err
+			}
+			return tx.QueryRowContext(ctx, queryCount, tenantID).Scan(&count)
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if count >= m.quota {
+			return nil, ErrQuotaExceeded
+		}
+
+		db = tx
 	}
 
-	err := m.exec(ctx, "insert-record", queryCreate, func() error {
-		_, err := m.db.ExecContext(ctx, queryCreate, sensor.ID, sensor.SiteID, sensor.Name, sensor.Unit, sensor.MinSafe, sensor.MaxSafe)
+	err = m.exec(ctx, "insert-record", queryCreate, func() error {
+		_, err := db.ExecContext(ctx, queryCreate, sensor.ID, sensor.TenantID, sensor.SiteID, sensor.Name, sensor.Unit, sensor.MinSafe, sensor.MaxSafe)
 		return err
 	})
 
+	if err == nil && tx != nil {
+		err = tx.Commit()
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -103,17 +185,22 @@ func (m *postgresSensorManager) Create(ctx context.Context, siteID, name, unit s
 }
 
 func (m *postgresSensorManager) All(ctx context.Context, siteID string) ([]Sensor, error) {
+	tenantID, ok := util.TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
 	sensors := make([]Sensor, 0)
 
 	err := m.exec(ctx, "select-records", queryAll, func() error {
-		rows, err := m.db.QueryContext(ctx, queryAll, siteID)
+		rows, err := m.db.QueryContext(ctx, queryAll, tenantID, siteID)
 		if err != nil {
 			return err
 		}
 
 		for rows.Next() {
 			sensor := Sensor{}
-			err := rows.Scan(&sensor.ID, &sensor.SiteID, &sensor.Name, &sensor.Unit, &sensor.MinSafe, &sensor.MaxSafe)
+			err := rows.Scan(&sensor.ID, &sensor.TenantID, &sensor.SiteID, &sensor.Name, &sensor.Unit, &sensor.MinSafe, &sensor.MaxSafe)
 			if err == nil {
 				sensors = append(sensors, sensor)
 			}
@@ -129,11 +216,16 @@ func (m *postgresSensorManager) All(ctx context.Context, siteID string) ([]Senso
 }
 
 func (m *postgresSensorManager) Get(ctx context.Context, id string) (*Sensor, error) {
+	tenantID, ok := util.TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
 	sensor := new(Sensor)
 
 	err := m.exec(ctx, "select-record", queryGet, func() error {
-		row := m.db.QueryRowContext(ctx, queryGet, id)
-		err := row.Scan(&sensor.ID, &sensor.SiteID, &sensor.Name, &sensor.Unit, &sensor.MinSafe, &sensor.MaxSafe)
+		row := m.db.QueryRowContext(ctx, queryGet, tenantID, id)
+		err := row.Scan(&sensor.ID, &sensor.TenantID, &sensor.SiteID, &sensor.Name, &sensor.Unit, &sensor.MinSafe, &sensor.MaxSafe)
 		if err == sql.ErrNoRows { // record does not exist
 			sensor = nil
 			return nil
@@ -148,10 +240,21 @@ func (m *postgresSensorManager) Get(ctx context.Context, id string) (*Sensor, er
 }
 
 func (m *postgresSensorManager) Update(ctx context.Context, s Sensor) (int, error) {
+	tenantID, ok := util.TenantFromContext(ctx)
+	if !ok {
+		return 0, ErrNoTenant
+	}
+
+	if m.sites != nil {
+		if err := m.sites.Validate(ctx, s.SiteID); err != nil {
+			return 0, err
+		}
+	}
+
 	var n int64
 
 	err := m.exec(ctx, "update-record", queryUpdate, func() error {
-		res, err := m.db.ExecContext(ctx, queryUpdate, s.ID, s.SiteID, s.Name, s.Unit, s.MinSafe, s.MaxSafe)
+		res, err := m.db.ExecContext(ctx, queryUpdate, tenantID, s.ID, s.SiteID, s.Name, s.Unit, s.MinSafe, s.MaxSafe)
 		if err != nil {
 			return err
 		}
@@ -167,10 +270,63 @@ func (m *postgresSensorManager) Update(ctx context.Context, s Sensor) (int, erro
 }
 
 func (m *postgresSensorManager) Delete(ctx context.Context, id string) error {
+	tenantID, ok := util.TenantFromContext(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+
 	err := m.exec(ctx, "delete-record", queryDelete, func() error {
-		_, err := m.db.ExecContext(ctx, queryDelete, id)
+		_, err := m.db.ExecContext(ctx, queryDelete, tenantID, id)
 		return err
 	})
 
 	return err
 }
+
+func (m *postgresSensorManager) SetReading(ctx context.Context, id string, value float64) (int, error) {
+	tenantID, ok := util.TenantFromContext(ctx)
+	if !ok {
+		return 0, ErrNoTenant
+	}
+
+	var n int64
+
+	err := m.exec(ctx, "upsert-reading", querySetReading, func() error {
+		res, err := m.db.ExecContext(ctx, querySetReading, tenantID, id, value, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+
+		n, err = res.RowsAffected()
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+func (m *postgresSensorManager) GetReading(ctx context.Context, id string) (*Reading, error) {
+	tenantID, ok := util.TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	reading := new(Reading)
+
+	err := m.exec(ctx, "select-reading", queryGetReading, func() error {
+		row := m.db.QueryRowContext(ctx, queryGetReading, tenantID, id)
+		err := row.Scan(&reading.SensorID, &reading.Value, &reading.Time)
+		if err == sql.ErrNoRows { // no reading or no sensor
+			reading = nil
+			return nil
+		}
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return reading, nil
+}
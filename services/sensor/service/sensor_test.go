@@ -5,9 +5,12 @@ import (
 	"database/sql/driver"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-kit/kit/log"
+	"github.com/moorara/microservices-demo/pkg/site"
+	"github.com/moorara/microservices-demo/services/sensor/util"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
@@ -17,6 +20,7 @@ func CreateContextWithSpan() context.Context {
 	tracer := mocktracer.New()
 	span := tracer.StartSpan("mock-span")
 	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	ctx = util.ContextWithTenant(ctx, "tttt-tttt")
 	return ctx
 }
 
@@ -57,7 +61,7 @@ func TestNewSensorManager(t *testing.T) {
 			logger := log.NewNopLogger()
 			tracer := mocktracer.New()
 			db := NewPostgresDB(logger, tc.host, tc.port, tc.database, tc.username, tc.password)
-			m := NewSensorManager(db, logger, tracer)
+			m := NewSensorManager(db, 0, nil, logger, tracer)
 
 			assert.NotNil(t, m)
 		})
@@ -122,6 +126,7 @@ func TestSensorManagerCreate(t *testing.T) {
 			} else {
 				assert.NoError(t, err)
 				assert.NotEmpty(t, sensor.ID)
+				assert.Equal(t, "tttt-tttt", sensor.TenantID)
 				assert.Equal(t, tc.sensorSiteID, sensor.SiteID)
 				assert.Equal(t, tc.sensorName, sensor.Name)
 				assert.Equal(t, tc.sensorUnit, sensor.Unit)
@@ -155,15 +160,15 @@ func TestSensorManagerAll(t *testing.T) {
 			"AllSensors",
 			nil,
 			[][]driver.Value{
-				[]driver.Value{"2222-bbbb", "1111-aaaa", "temperature", "fahrenheit", -22.0, 86.0},
-				[]driver.Value{"3333-cccc", "1111-aaaa", "pressure", "pascal", 50000.0, 100000.0},
+				[]driver.Value{"2222-bbbb", "tttt-tttt", "1111-aaaa", "temperature", "fahrenheit", -22.0, 86.0},
+				[]driver.Value{"3333-cccc", "tttt-tttt", "1111-aaaa", "pressure", "pascal", 50000.0, 100000.0},
 			},
 			CreateContextWithSpan(),
 			"1111-aaaa",
 			false,
 			[]Sensor{
-				Sensor{"2222-bbbb", "1111-aaaa", "temperature", "fahrenheit", -22.0, 86.0},
-				Sensor{"3333-cccc", "1111-aaaa", "pressure", "pascal", 50000.0, 100000.0},
+				Sensor{"2222-bbbb", "tttt-tttt", "1111-aaaa", "temperature", "fahrenheit", -22.0, 86.0},
+				Sensor{"3333-cccc", "tttt-tttt", "1111-aaaa", "pressure", "pascal", 50000.0, 100000.0},
 			},
 		},
 	}
@@ -180,11 +185,11 @@ func TestSensorManagerAll(t *testing.T) {
 			}
 
 			// Mock SQL query
-			expect := mock.ExpectQuery(`SELECT id, site_id, name, unit, min_safe, max_safe FROM sensors`)
+			expect := mock.ExpectQuery(`SELECT id, tenant_id, site_id, name, unit, min_safe, max_safe FROM sensors`)
 			if tc.dbError != nil {
 				expect.WillReturnError(tc.dbError)
 			} else {
-				rows := sqlmock.NewRows([]string{"id", "site_id", "name", "unit", "min_safe", "max_safe"})
+				rows := sqlmock.NewRows([]string{"id", "tenant_id", "site_id", "name", "unit", "min_safe", "max_safe"})
 				for _, row := range tc.dbRows {
 					rows.AddRow(row...)
 				}
@@ -235,11 +240,11 @@ func TestSensorManagerGet(t *testing.T) {
 		{
 			"GetSensor",
 			nil,
-			[]driver.Value{"2222-bbbb", "1111-aaaa", "temperature", "fahrenheit", -22.0, 86.0},
+			[]driver.Value{"2222-bbbb", "tttt-tttt", "1111-aaaa", "temperature", "fahrenheit", -22.0, 86.0},
 			CreateContextWithSpan(),
 			"2222-bbbb",
 			false,
-			&Sensor{"2222-bbbb", "1111-aaaa", "temperature", "fahrenheit", -22.0, 86.0},
+			&Sensor{"2222-bbbb", "tttt-tttt", "1111-aaaa", "temperature", "fahrenheit", -22.0, 86.0},
 		},
 	}
 
@@ -255,11 +260,11 @@ func TestSensorManagerGet(t *testing.T) {
 			}
 
 			// Mock SQL query
-			expect := mock.ExpectQuery(`SELECT id, site_id, name, unit, min_safe, max_safe FROM sensors`)
+			expect := mock.ExpectQuery(`SELECT id, tenant_id, site_id, name, unit, min_safe, max_safe FROM sensors`).WithArgs("tttt-tttt", tc.id)
 			if tc.dbError != nil {
 				expect.WillReturnError(tc.dbError)
 			} else {
-				rows := sqlmock.NewRows([]string{"id", "site_id", "name", "unit", "min_safe", "max_safe"})
+				rows := sqlmock.NewRows([]string{"id", "tenant_id", "site_id", "name", "unit", "min_safe", "max_safe"})
 				rows.AddRow(tc.dbRow...)
 				expect.WillReturnRows(rows)
 			}
@@ -297,7 +302,7 @@ func TestSensorManagerUpdate(t *testing.T) {
 			errors.New("db error"),
 			nil,
 			CreateContextWithSpan(),
-			Sensor{"", "", "", "", 0.0, 0.0},
+			Sensor{"", "", "", "", "", 0.0, 0.0},
 			true,
 			0,
 		},
@@ -306,7 +311,7 @@ func TestSensorManagerUpdate(t *testing.T) {
 			nil,
 			sqlmock.NewResult(0, 1),
 			CreateContextWithSpan(),
-			Sensor{"2222-bbbb", "1111-aaaa", "temperature", "fahrenheit", -22.0, 86.0},
+			Sensor{"2222-bbbb", "tttt-tttt", "1111-aaaa", "temperature", "fahrenheit", -22.0, 86.0},
 			false,
 			1,
 		},
@@ -383,7 +388,7 @@ func TestSensorManagerDelete(t *testing.T) {
 			}
 
 			// Mock SQL query
-			expect := mock.ExpectExec(`DELETE FROM sensors`)
+			expect := mock.ExpectExec(`DELETE FROM sensors`).WithArgs("tttt-tttt", tc.sensorID)
 			if tc.dbError != nil {
 				expect.WillReturnError(tc.dbError)
 			} else {
@@ -400,3 +405,293 @@ func TestSensorManagerDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestSensorManagerSetReading(t *testing.T) {
+	tests := []struct {
+		name               string
+		dbError            error
+		dbResult           driver.Result
+		sensorID           string
+		value              float64
+		expectError        bool
+		expectedAffectedNo int
+	}{
+		{
+			"DatabaseError",
+			errors.New("db error"),
+			nil,
+			"2222-bbbb",
+			21.5,
+			true,
+			0,
+		},
+		{
+			"SensorNotFound",
+			nil,
+			sqlmock.NewResult(0, 0),
+			"0000-0000",
+			21.5,
+			false,
+			0,
+		},
+		{
+			"SetReading",
+			nil,
+			sqlmock.NewResult(0, 1),
+			"2222-bbbb",
+			21.5,
+			false,
+			1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+
+			m := &postgresSensorManager{
+				db:     db,
+				logger: log.NewNopLogger(),
+				tracer: mocktracer.New(),
+			}
+
+			// Mock SQL query
+			expect := mock.ExpectExec(`INSERT INTO sensor_readings`).WithArgs("tttt-tttt", tc.sensorID, tc.value, sqlmock.AnyArg())
+			if tc.dbError != nil {
+				expect.WillReturnError(tc.dbError)
+			} else {
+				expect.WillReturnResult(tc.dbResult)
+			}
+
+			n, err := m.SetReading(CreateContextWithSpan(), tc.sensorID, tc.value)
+
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Zero(t, n)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedAffectedNo, n)
+			}
+		})
+	}
+}
+
+func TestSensorManagerGetReading(t *testing.T) {
+	readAt := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		dbError         error
+		dbRow           []driver.Value
+		sensorID        string
+		expectError     bool
+		expectedReading *Reading
+	}{
+		{
+			"DatabaseError",
+			errors.New("db error"),
+			nil,
+			"2222-bbbb",
+			true,
+			nil,
+		},
+		{
+			"NoReading",
+			nil,
+			nil,
+			"2222-bbbb",
+			false,
+			nil,
+		},
+		{
+			"GetReading",
+			nil,
+			[]driver.Value{"2222-bbbb", 21.5, readAt},
+			"2222-bbbb",
+			false,
+			&Reading{"2222-bbbb", 21.5, readAt},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+
+			m := &postgresSensorManager{
+				db:     db,
+				logger: log.NewNopLogger(),
+				tracer: mocktracer.New(),
+			}
+
+			// Mock SQL query
+			expect := mock.ExpectQuery(`SELECT sensor_id, value, time FROM sensor_readings`).WithArgs("tttt-tttt", tc.sensorID)
+			if tc.dbError != nil {
+				expect.WillReturnError(tc.dbError)
+			} else {
+				rows := sqlmock.NewRows([]string{"sensor_id", "value", "time"})
+				if tc.dbRow != nil {
+					rows.AddRow(tc.dbRow...)
+				}
+				expect.WillReturnRows(rows)
+			}
+
+			reading, err := m.GetReading(CreateContextWithSpan(), tc.sensorID)
+
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedReading, reading)
+		})
+	}
+}
+
+func TestSensorManagerQuota(t *testing.T) {
+	tests := []struct {
+		name        string
+		quota       int
+		dbError     error
+		count       int
+		expectError error
+	}{
+		{"DatabaseError", 10, errors.New("db error"), 0, errors.New("db error")},
+		{"QuotaExceeded", 10, nil, 10, ErrQuotaExceeded},
+		{"WithinQuota", 10, nil, 9, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+
+			m := &postgresSensorManager{
+				db:     db,
+				quota:  tc.quota,
+				logger: log.NewNopLogger(),
+				tracer: mocktracer.New(),
+			}
+
+			// Mock SQL queries
+			mock.ExpectBegin()
+			mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WithArgs("tttt-tttt").WillReturnResult(sqlmock.NewResult(0, 0))
+			expect := mock.ExpectQuery(`SELECT COUNT\(\*\) FROM sensors`).WithArgs("tttt-tttt")
+			if tc.dbError != nil {
+				expect.WillReturnError(tc.dbError)
+				mock.ExpectRollback()
+			} else {
+				expect.WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tc.count))
+			}
+			if tc.expectError == nil {
+				mock.ExpectExec(`INSERT INTO sensors`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else if tc.dbError == nil {
+				mock.ExpectRollback()
+			}
+
+			sensor, err := m.Create(CreateContextWithSpan(), "1111-aaaa", "temperature", "celsius", -30.0, 30.0)
+			assert.Equal(t, tc.expectError, err)
+
+			if tc.expectError == nil {
+				assert.NotNil(t, sensor)
+			} else {
+				assert.Nil(t, sensor)
+			}
+
+			// The sensor is only inserted in the transaction holding the lock of the tenant
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSensorManagerNoTenant(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+
+	m := NewSensorManager(db, 0, nil, log.NewNopLogger(), mocktracer.New())
+	ctx := opentracing.ContextWithSpan(context.Background(), mocktracer.New().StartSpan("mock-span"))
+
+	_, err = m.Create(ctx, "1111-aaaa", "temperature", "celsius", -30.0, 30.0)
+	assert.Equal(t, ErrNoTenant, err)
+
+	_, err = m.All(ctx, "1111-aaaa")
+	assert.Equal(t, ErrNoTenant, err)
+
+	_, err = m.Get(ctx, "2222-bbbb")
+	assert.Equal(t, ErrNoTenant, err)
+
+	_, err = m.Update(ctx, Sensor{ID: "2222-bbbb"})
+	assert.Equal(t, ErrNoTenant, err)
+
+	err = m.Delete(ctx, "2222-bbbb")
+	assert.Equal(t, ErrNoTenant, err)
+
+	_, err = m.SetReading(ctx, "2222-bbbb", 21.5)
+	assert.Equal(t, ErrNoTenant, err)
+
+	_, err = m.GetReading(ctx, "2222-bbbb")
+	assert.Equal(t, ErrNoTenant, err)
+
+	// No query should reach the database without a tenant
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type mockSiteClient struct {
+	ValidateInSiteID string
+	ValidateOutError error
+}
+
+func (m *mockSiteClient) Validate(ctx context.Context, siteID string) error {
+	m.ValidateInSiteID = siteID
+	return m.ValidateOutError
+}
+
+func TestSensorManagerSiteValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		siteError   error
+		expectError error
+	}{
+		{"UnknownSite", site.ErrUnknownSite, site.ErrUnknownSite},
+		{"SiteServiceUnavailable", site.ErrUnavailable, site.ErrUnavailable},
+		{"KnownSite", nil, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+
+			sites := &mockSiteClient{ValidateOutError: tc.siteError}
+			m := &postgresSensorManager{
+				db:     db,
+				sites:  sites,
+				logger: log.NewNopLogger(),
+				tracer: mocktracer.New(),
+			}
+
+			// Mock SQL queries
+			mock.ExpectExec(`INSERT INTO sensors`).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(`UPDATE sensors`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+			sensor, err := m.Create(CreateContextWithSpan(), "1111-aaaa", "temperature", "celsius", -30.0, 30.0)
+			assert.Equal(t, tc.expectError, err)
+			assert.Equal(t, "1111-aaaa", sites.ValidateInSiteID)
+
+			n, err := m.Update(CreateContextWithSpan(), Sensor{ID: "2222-bbbb", SiteID: "3333-cccc"})
+			assert.Equal(t, tc.expectError, err)
+			assert.Equal(t, "3333-cccc", sites.ValidateInSiteID)
+
+			if tc.expectError == nil {
+				assert.NotNil(t, sensor)
+				assert.Equal(t, 1, n)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			} else {
+				assert.Nil(t, sensor)
+				assert.Equal(t, 0, n)
+			}
+		})
+	}
+}
@@ -11,12 +11,14 @@ import (
 
 const (
 	timeoutMS = 10000
+	tenantID  = "demo-tenant"
 )
 
 type (
 	// Component represents the component under test
 	Component struct {
 		ServiceURL string
+		TenantID   string
 		transport  *http.Transport
 	}
 )
@@ -30,6 +32,7 @@ func NewComponent() *Component {
 
 	return &Component{
 		ServiceURL: serviceURL,
+		TenantID:   tenantID,
 		transport:  &http.Transport{},
 	}
 }
@@ -51,6 +54,9 @@ func (c *Component) Call(ctx context.Context, method, endpoint string, body []by
 
 	req = req.WithContext(ctx)
 	req.Header.Set("Content-Type", "application/json")
+	if c.TenantID != "" {
+		req.Header.Set("X-Tenant-ID", c.TenantID)
+	}
 
 	res, err := client.Do(req)
 	if err != nil {
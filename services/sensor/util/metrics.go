@@ -3,6 +3,7 @@ package util
 import (
 	"net/http"
 
+	"github.com/moorara/microservices-demo/pkg/tenant"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -10,21 +11,26 @@ import (
 // Metrics represents metrics utility
 type Metrics struct {
 	service  string
+	Tenants  *tenant.Labels
 	Registry *prometheus.Registry
 }
 
 // NewMetrics creates a Metrics instance
-func NewMetrics(service string) *Metrics {
+// tenants are the tenants labeled by their id in the metrics.
+func NewMetrics(service string, tenants ...string) *Metrics {
 	registry := prometheus.NewRegistry()
 	registry.MustRegister(prometheus.NewGoCollector())
 	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{
 		Namespace: service,
 	}))
 
-	return &Metrics{
+	m := &Metrics{
 		service:  service,
+		Tenants:  tenant.NewLabels(tenants...),
 		Registry: registry,
 	}
+
+	return m
 }
 
 // NewCounter creates and registers a new counter metric
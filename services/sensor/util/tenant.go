@@ -0,0 +1,19 @@
+package util
+
+import "context"
+
+// TenantHeader is the http header carrying the tenant identifier
+const TenantHeader = "X-Tenant-ID"
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a new context carrying a tenant identifier
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant identifier carried by a context if any
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
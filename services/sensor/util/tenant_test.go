@@ -0,0 +1,29 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantContext(t *testing.T) {
+	tests := []struct {
+		name             string
+		ctx              context.Context
+		expectedTenantID string
+		expectedOK       bool
+	}{
+		{"NoTenant", context.Background(), "", false},
+		{"EmptyTenant", ContextWithTenant(context.Background(), ""), "", false},
+		{"WithTenant", ContextWithTenant(context.Background(), "tttt-tttt"), "tttt-tttt", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tenantID, ok := TenantFromContext(tc.ctx)
+			assert.Equal(t, tc.expectedTenantID, tenantID)
+			assert.Equal(t, tc.expectedOK, ok)
+		})
+	}
+}
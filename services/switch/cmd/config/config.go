@@ -5,16 +5,42 @@ import (
 )
 
 const (
-	defaultLogLevel         = "info"
-	defaultServiceName      = "switch-service"
-	defaultServiceGRPCPort  = ":4030"
-	defaultServiceHTTPPort  = ":4031"
-	defaultServerTimeout    = int64(30 * time.Second)
-	defaultArangoUser       = "root"
-	defaultArangoDatabase   = "switches"
-	defaultArangoCollection = "switches"
-	defaultJaegerAgentAddr  = "localhost:6831"
-	defaultJaegerLogSpans   = false
+	defaultLogLevel          = "info"
+	defaultServiceName       = "switch-service"
+	defaultServiceGRPCPort   = ":4030"
+	defaultServiceHTTPPort   = ":4031"
+	defaultServerTimeout     = int64(30 * time.Second)
+	defaultArangoUser        = "root"
+	defaultArangoDatabase    = "switches"
+	defaultArangoCollection  = "switches"
+	defaultArangoHistory     = "switch_history"
+	defaultJaegerAgentAddr   = "localhost:6831"
+	defaultJaegerLogSpans    = false
+	defaultTenantQuota       = 0
+	defaultSiteServiceAddr   = ""
+	defaultSiteCacheTTL      = 30 * time.Second
+	defaultSiteFailOpen      = false
+	defaultSensorServiceAddr = ""
+	defaultSensorCacheTTL    = 30 * time.Second
+	defaultSensorMaxAge      = time.Minute
+	defaultWatchHistorySize  = 1000
+	defaultWatchBufferSize   = 100
+	defaultSchedulerEnabled  = true
+	defaultSchedulerPeriod   = 10 * time.Second
+	defaultSchedulerCatchUp  = "once"
+	defaultSchedulerGrace    = time.Minute
+	defaultHealthPeriod      = 5 * time.Second
+	defaultGRPCReflection    = false
+	defaultHTTPGateway       = false
+	defaultAuthPolicyFile    = ""
+	defaultAuthPolicyReload  = 10 * time.Second
+	defaultCertReload        = 10 * time.Second
+	defaultCertExpiryWarn    = 24 * time.Hour
+	defaultSyncTimeout       = 30 * time.Second
+	defaultSyncPeriod        = 5 * time.Second
+	defaultNatsUser          = "client"
+	defaultNatsPassword      = "pass"
+	defaultOutboxPeriod      = time.Second
 )
 
 var (
@@ -23,36 +49,90 @@ var (
 
 // Config defines the schema for configurations
 type Config struct {
-	LogLevel         string
-	ServiceName      string
-	ServiceGRPCPort  string
-	ServiceHTTPPort  string
-	ServerTimeout    int64
-	ArangoEndpoints  []string
-	ArangoUser       string
-	ArangoPassword   string
-	ArangoDatabase   string
-	ArangoCollection string
-	JaegerAgentAddr  string
-	JaegerLogSpans   bool
-	CAChainFile      string
-	ServerCertFile   string
-	ServerKeyFile    string
+	LogLevel          string
+	ServiceName       string
+	ServiceGRPCPort   string
+	ServiceHTTPPort   string
+	ServerTimeout     int64
+	ArangoEndpoints   []string
+	ArangoUser        string
+	ArangoPassword    string
+	ArangoDatabase    string
+	ArangoCollection  string
+	ArangoHistory     string
+	JaegerAgentAddr   string
+	JaegerLogSpans    bool
+	CAChainFile       string
+	ServerCertFile    string
+	ServerKeyFile     string
+	TenantQuota       int
+	MetricsTenants    []string
+	SiteServiceAddr   string
+	SiteCacheTTL      time.Duration
+	SiteFailOpen      bool
+	SensorServiceAddr string
+	SensorCacheTTL    time.Duration
+	SensorMaxAge      time.Duration
+	WatchHistorySize  int
+	WatchBufferSize   int
+	SchedulerEnabled  bool
+	SchedulerPeriod   time.Duration
+	SchedulerCatchUp  string
+	SchedulerGrace    time.Duration
+	HealthPeriod      time.Duration
+	GRPCReflection    bool
+	HTTPGateway       bool
+	AuthPolicyFile    string
+	AuthPolicyReload  time.Duration
+	CertReload        time.Duration
+	CertExpiryWarn    time.Duration
+	SyncTimeout       time.Duration
+	SyncPeriod        time.Duration
+	NatsServers       []string
+	NatsUser          string
+	NatsPassword      string
+	OutboxPeriod      time.Duration
 }
 
 // New creates a new configuration object
 func New() Config {
 	return Config{
-		LogLevel:         defaultLogLevel,
-		ServiceName:      defaultServiceName,
-		ServiceGRPCPort:  defaultServiceGRPCPort,
-		ServiceHTTPPort:  defaultServiceHTTPPort,
-		ServerTimeout:    defaultServerTimeout,
-		ArangoEndpoints:  defaultArangoEndpoints,
-		ArangoUser:       defaultArangoUser,
-		ArangoDatabase:   defaultArangoDatabase,
-		ArangoCollection: defaultArangoCollection,
-		JaegerAgentAddr:  defaultJaegerAgentAddr,
-		JaegerLogSpans:   defaultJaegerLogSpans,
+		LogLevel:          defaultLogLevel,
+		ServiceName:       defaultServiceName,
+		ServiceGRPCPort:   defaultServiceGRPCPort,
+		ServiceHTTPPort:   defaultServiceHTTPPort,
+		ServerTimeout:     defaultServerTimeout,
+		ArangoEndpoints:   defaultArangoEndpoints,
+		ArangoUser:        defaultArangoUser,
+		ArangoDatabase:    defaultArangoDatabase,
+		ArangoCollection:  defaultArangoCollection,
+		ArangoHistory:     defaultArangoHistory,
+		JaegerAgentAddr:   defaultJaegerAgentAddr,
+		JaegerLogSpans:    defaultJaegerLogSpans,
+		TenantQuota:       defaultTenantQuota,
+		SiteServiceAddr:   defaultSiteServiceAddr,
+		SiteCacheTTL:      defaultSiteCacheTTL,
+		SiteFailOpen:      defaultSiteFailOpen,
+		SensorServiceAddr: defaultSensorServiceAddr,
+		SensorCacheTTL:    defaultSensorCacheTTL,
+		SensorMaxAge:      defaultSensorMaxAge,
+		WatchHistorySize:  defaultWatchHistorySize,
+		WatchBufferSize:   defaultWatchBufferSize,
+		SchedulerEnabled:  defaultSchedulerEnabled,
+		SchedulerPeriod:   defaultSchedulerPeriod,
+		SchedulerCatchUp:  defaultSchedulerCatchUp,
+		SchedulerGrace:    defaultSchedulerGrace,
+		HealthPeriod:      defaultHealthPeriod,
+		GRPCReflection:    defaultGRPCReflection,
+		HTTPGateway:       defaultHTTPGateway,
+		AuthPolicyFile:    defaultAuthPolicyFile,
+		AuthPolicyReload:  defaultAuthPolicyReload,
+		CertReload:        defaultCertReload,
+		CertExpiryWarn:    defaultCertExpiryWarn,
+		SyncTimeout:       defaultSyncTimeout,
+		SyncPeriod:        defaultSyncPeriod,
+		NatsUser:          defaultNatsUser,
+		NatsPassword:      defaultNatsPassword,
+		OutboxPeriod:      defaultOutboxPeriod,
 	}
 }
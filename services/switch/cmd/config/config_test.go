@@ -19,9 +19,37 @@ func TestNew(t *testing.T) {
 	assert.Empty(t, config.ArangoPassword)
 	assert.Equal(t, defaultArangoDatabase, config.ArangoDatabase)
 	assert.Equal(t, defaultArangoCollection, config.ArangoCollection)
+	assert.Equal(t, defaultArangoHistory, config.ArangoHistory)
 	assert.Equal(t, defaultJaegerAgentAddr, config.JaegerAgentAddr)
 	assert.Equal(t, defaultJaegerLogSpans, config.JaegerLogSpans)
 	assert.Empty(t, config.CAChainFile)
 	assert.Empty(t, config.ServerCertFile)
 	assert.Empty(t, config.ServerKeyFile)
+	assert.Equal(t, defaultTenantQuota, config.TenantQuota)
+	assert.Empty(t, config.MetricsTenants)
+	assert.Equal(t, defaultSiteServiceAddr, config.SiteServiceAddr)
+	assert.Equal(t, defaultSiteCacheTTL, config.SiteCacheTTL)
+	assert.Equal(t, defaultSiteFailOpen, config.SiteFailOpen)
+	assert.Equal(t, defaultSensorServiceAddr, config.SensorServiceAddr)
+	assert.Equal(t, defaultSensorCacheTTL, config.SensorCacheTTL)
+	assert.Equal(t, defaultSensorMaxAge, config.SensorMaxAge)
+	assert.Equal(t, defaultWatchHistorySize, config.WatchHistorySize)
+	assert.Equal(t, defaultWatchBufferSize, config.WatchBufferSize)
+	assert.Equal(t, defaultSchedulerEnabled, config.SchedulerEnabled)
+	assert.Equal(t, defaultSchedulerPeriod, config.SchedulerPeriod)
+	assert.Equal(t, defaultSchedulerCatchUp, config.SchedulerCatchUp)
+	assert.Equal(t, defaultSchedulerGrace, config.SchedulerGrace)
+	assert.Equal(t, defaultHealthPeriod, config.HealthPeriod)
+	assert.Equal(t, defaultGRPCReflection, config.GRPCReflection)
+	assert.Equal(t, defaultHTTPGateway, config.HTTPGateway)
+	assert.Equal(t, defaultAuthPolicyFile, config.AuthPolicyFile)
+	assert.Equal(t, defaultAuthPolicyReload, config.AuthPolicyReload)
+	assert.Equal(t, defaultCertReload, config.CertReload)
+	assert.Equal(t, defaultCertExpiryWarn, config.CertExpiryWarn)
+	assert.Equal(t, defaultSyncTimeout, config.SyncTimeout)
+	assert.Equal(t, defaultSyncPeriod, config.SyncPeriod)
+	assert.Empty(t, config.NatsServers)
+	assert.Equal(t, defaultNatsUser, config.NatsUser)
+	assert.Equal(t, defaultNatsPassword, config.NatsPassword)
+	assert.Equal(t, defaultOutboxPeriod, config.OutboxPeriod)
 }
@@ -11,12 +11,32 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/moorara/microservices-demo/pkg/site"
 	"github.com/moorara/microservices-demo/services/switch/cmd/config"
+	"github.com/moorara/microservices-demo/services/switch/internal/auth"
+	"github.com/moorara/microservices-demo/services/switch/internal/broker"
 	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
+	"github.com/moorara/microservices-demo/services/switch/internal/queue"
+	"github.com/moorara/microservices-demo/services/switch/internal/scheduler"
 	"github.com/moorara/microservices-demo/services/switch/internal/service"
 	"github.com/moorara/microservices-demo/services/switch/internal/transport"
+	"github.com/moorara/microservices-demo/services/switch/pkg/certs"
 	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/sensor"
 	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// switchServiceName is the name the switch service is checked by in health checks
+	switchServiceName = "proto.SwitchService"
+)
+
+var (
+	// ErrInsecureGateway is returned when the REST endpoints are enabled with MTLS or an authorization policy.
+	// The HTTP port does not require client certificates, so its calls would bypass both.
+	ErrInsecureGateway = errors.New("http gateway cannot be enabled with mtls or an authorization policy")
 )
 
 type (
@@ -27,36 +47,120 @@ type (
 	}
 
 	server struct {
-		ready         bool
 		config        config.Config
 		logger        *log.Logger
 		arangoService service.ArangoService
 		httpServer    transport.HTTPServer
 		grpcServer    transport.GRPCServer
+		health        *health.Server
+		scheduler     *scheduler.Scheduler
+		authorizer    *auth.Authorizer
+		certs         *certs.Store
+		syncMonitor   *service.SyncMonitor
+		outboxRelay   *service.OutboxRelay
 	}
 )
 
 // New creates a new server
 func New(config config.Config, logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer) (Server, error) {
+	mtls := config.CAChainFile != "" && config.ServerCertFile != "" && config.ServerKeyFile != ""
+	if config.HTTPGateway && (mtls || config.AuthPolicyFile != "") {
+		return nil, ErrInsecureGateway
+	}
+
 	var err error
 	s := &server{
 		config: config,
 		logger: logger,
+		health: health.NewServer(),
 	}
 
+	// Nothing is served until the database is connected
+	s.setServing(false)
+
 	s.arangoService, err = service.NewArangoService(config.ArangoEndpoints, config.ArangoUser, config.ArangoPassword)
 	if err != nil {
 		return nil, err
 	}
 
-	s.httpServer = transport.NewHTTPServer(config.ServiceHTTPPort, s.liveHandler, s.readyHandler, metrics.Handler().ServeHTTP)
+	// Site validation is disabled without a site service address
+	var sites site.Client
+	if config.SiteServiceAddr != "" {
+		sites = site.NewClient(site.Config{
+			Addr:     config.SiteServiceAddr,
+			CacheTTL: config.SiteCacheTTL,
+			FailOpen: config.SiteFailOpen,
+		})
+	}
+
+	// Without a sensor service address every change blocked by an interlock is rejected
+	var sensors sensor.Client
+	if config.SensorServiceAddr != "" {
+		sensors = sensor.NewClient(sensor.Config{
+			Addr:     config.SensorServiceAddr,
+			CacheTTL: config.SensorCacheTTL,
+			MaxAge:   config.SensorMaxAge,
+		})
+	}
+
+	// Switch events are only written to the outbox and published to NATS with NATS servers
+	outbox := len(config.NatsServers) > 0
+	if outbox {
+		s.outboxRelay = service.NewOutboxRelay(s.arangoService, func() (queue.NATSConnection, error) {
+			return queue.NewNATSConnection(config.NatsServers, config.ServiceName, config.NatsUser, config.NatsPassword)
+		}, logger)
+	}
+
+	events := broker.New(config.WatchHistorySize, config.WatchBufferSize)
+	switchService := service.NewSwitchService(s.arangoService, config.TenantQuota, sites, sensors, events, outbox, logger, metrics, tracer)
+	s.syncMonitor = service.NewSyncMonitor(s.arangoService, events, logger, config.SyncTimeout)
+
+	// Every call is allowed without an authorization policy
+	var authorizer transport.Authorizer
+	if config.AuthPolicyFile != "" {
+		s.authorizer, err = auth.NewAuthorizer(config.AuthPolicyFile, logger)
+		if err != nil {
+			return nil, err
+		}
+		authorizer = s.authorizer
+	}
+
+	interceptor := transport.NewInterceptor(logger, metrics, tracer, authorizer)
+
+	// The REST endpoints call the same switch service as gRPC
+	var gateway *transport.Gateway
+	if config.HTTPGateway {
+		gateway = transport.NewGateway(interceptor, switchService)
+	}
+
+	s.httpServer = transport.NewHTTPServer(config.ServiceHTTPPort, s.liveHandler, s.readyHandler, metrics.Handler().ServeHTTP, gateway)
 
-	switchService := service.NewSwitchService(s.arangoService, logger, metrics, tracer)
-	s.grpcServer, err = transport.NewGRPCServer(config.CAChainFile, config.ServerCertFile, config.ServerKeyFile, switchService)
+	// MTLS is disabled without a certificate authority and a key pair
+	if mtls {
+		s.certs, err = certs.NewStore(config.CAChainFile, config.ServerCertFile, config.ServerKeyFile, logger, metrics.CertExpiry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.grpcServer, err = transport.NewGRPCServer(s.certs, config.GRPCReflection, interceptor, s.health, switchService)
 	if err != nil {
 		return nil, err
 	}
 
+	if config.SchedulerEnabled {
+		catchUp, err := scheduler.ParseCatchUp(config.SchedulerCatchUp)
+		if err != nil {
+			return nil, err
+		}
+
+		s.scheduler = scheduler.New(s.arangoService, switchService, logger, scheduler.Config{
+			Interval:    config.SchedulerPeriod,
+			MissedAfter: config.SchedulerGrace,
+			CatchUp:     catchUp,
+		})
+	}
+
 	return s, nil
 }
 
@@ -65,9 +169,53 @@ func (s *server) liveHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// setServing sets the health status of the server and the switch service
+func (s *server) setServing(serving bool) {
+	status := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = grpc_health_v1.HealthCheckResponse_SERVING
+	}
+
+	s.health.SetServingStatus("", status)
+	s.health.SetServingStatus(switchServiceName, status)
+}
+
+// monitorDatabase pings the database periodically and updates the health status whenever it becomes unreachable or reachable again
+func (s *server) monitorDatabase(ctx context.Context) {
+	ticker := time.NewTicker(s.config.HealthPeriod)
+	defer ticker.Stop()
+
+	serving := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, s.config.HealthPeriod)
+		err := s.arangoService.Ping(pingCtx)
+		cancel()
+
+		if (err == nil) == serving {
+			continue
+		}
+
+		serving = err == nil
+		if serving {
+			s.logger.Info("message", "Database is reachable again.")
+		} else {
+			s.logger.Error("message", fmt.Sprintf("Database is unreachable: %s", err))
+		}
+
+		s.setServing(serving)
+	}
+}
+
 // readyHandler implements readiness prob
 func (s *server) readyHandler(w http.ResponseWriter, r *http.Request) {
-	if s.ready {
+	resp, err := s.health.Check(r.Context(), &grpc_health_v1.HealthCheckRequest{Service: switchServiceName})
+	if err == nil && resp.Status == grpc_health_v1.HealthCheckResponse_SERVING {
 		w.WriteHeader(http.StatusOK)
 	} else {
 		w.WriteHeader(http.StatusAccepted)
@@ -83,6 +231,20 @@ func (s *server) Start() error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	// The scheduler and the database monitor run until the server stops
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// The authorization policy is reloaded whenever it changes
+	if s.authorizer != nil {
+		go s.authorizer.Watch(backgroundCtx, s.config.AuthPolicyReload)
+	}
+
+	// The certificates are reloaded whenever they are rotated
+	if s.certs != nil {
+		go s.certs.Watch(backgroundCtx, s.config.CertReload, s.config.CertExpiryWarn)
+	}
+
 	// Handle OS signals
 	go func() {
 		sigs := make(chan os.Signal, 1)
@@ -98,10 +260,28 @@ func (s *server) Start() error {
 	// Connect to database
 	go func() {
 		for {
-			err := s.arangoService.Connect(ctx, s.config.ArangoDatabase, s.config.ArangoCollection)
+			err := s.arangoService.Connect(ctx, s.config.ArangoDatabase, s.config.ArangoCollection, s.config.ArangoHistory)
+			if err == nil {
+				err = s.arangoService.EnsureCollections(ctx, scheduler.SchedulesCollection, scheduler.LeasesCollection, scheduler.ExecutionsCollection, service.GroupsCollection, service.ScenesCollection, service.OutboxCollection, service.QuotaCollection)
+			}
+
 			if err == nil {
 				s.logger.Info("message", "Connected to database.")
-				s.ready = true
+				s.setServing(true)
+
+				if s.config.HealthPeriod > 0 {
+					go s.monitorDatabase(backgroundCtx)
+				}
+
+				if s.scheduler != nil {
+					go s.scheduler.Run(backgroundCtx)
+				}
+
+				go s.syncMonitor.Run(backgroundCtx, s.config.SyncPeriod)
+
+				if s.outboxRelay != nil {
+					go s.outboxRelay.Run(backgroundCtx, s.config.OutboxPeriod)
+				}
 				return
 			}
 
@@ -159,6 +339,9 @@ func (s *server) Stop() {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	// Health checks report NOT_SERVING while the calls in progress complete
+	s.health.Shutdown()
+
 	_ = s.httpServer.Shutdown(ctx)
 	s.grpcServer.GracefulStop()
 
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -13,8 +14,19 @@ import (
 	"github.com/moorara/microservices-demo/services/switch/pkg/log"
 	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
+// healthStatus returns the health status of the switch service
+func healthStatus(s *server) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	resp, err := s.health.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: switchServiceName})
+	if err != nil {
+		return grpc_health_v1.HealthCheckResponse_UNKNOWN
+	}
+	return resp.Status
+}
+
 func TestNew(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -46,6 +58,70 @@ func TestNew(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"MTLS",
+			config.Config{
+				ServiceHTTPPort: ":12345",
+				ServiceGRPCPort: ":12346",
+				ArangoEndpoints: []string{"localhost:12347"},
+				ArangoUser:      "root",
+				ArangoPassword:  "pass",
+				CAChainFile:     "../../pkg/certs/ca.chain.cert",
+				ServerCertFile:  "../../pkg/certs/server.cert",
+				ServerKeyFile:   "../../pkg/certs/server.key",
+			},
+			false,
+		},
+		{
+			"InvalidCatchUp",
+			config.Config{
+				ServiceHTTPPort:  ":12345",
+				ServiceGRPCPort:  ":12346",
+				ArangoEndpoints:  []string{"localhost:12347"},
+				ArangoUser:       "root",
+				ArangoPassword:   "pass",
+				SchedulerEnabled: true,
+				SchedulerCatchUp: "always",
+			},
+			true,
+		},
+		{
+			"NoAuthPolicy",
+			config.Config{
+				ServiceHTTPPort: ":12345",
+				ServiceGRPCPort: ":12346",
+				ArangoEndpoints: []string{"localhost:12347"},
+				ArangoUser:      "root",
+				ArangoPassword:  "pass",
+				AuthPolicyFile:  "policy.yaml",
+			},
+			true,
+		},
+		{
+			"Gateway",
+			config.Config{
+				ServiceHTTPPort: ":12345",
+				ServiceGRPCPort: ":12346",
+				ArangoEndpoints: []string{"localhost:12347"},
+				ArangoUser:      "root",
+				ArangoPassword:  "pass",
+				HTTPGateway:     true,
+			},
+			false,
+		},
+		{
+			"WithScheduler",
+			config.Config{
+				ServiceHTTPPort:  ":12345",
+				ServiceGRPCPort:  ":12346",
+				ArangoEndpoints:  []string{"localhost:12347"},
+				ArangoUser:       "root",
+				ArangoPassword:   "pass",
+				SchedulerEnabled: true,
+				SchedulerCatchUp: "skip",
+			},
+			false,
+		},
 	}
 
 	for _, tc := range tests {
@@ -53,19 +129,53 @@ func TestNew(t *testing.T) {
 			logger := log.NewVoidLogger()
 			metrics := metrics.Mock()
 			tracer := mocktracer.New()
-			server, err := New(tc.config, logger, metrics, tracer)
+			s, err := New(tc.config, logger, metrics, tracer)
 
 			if tc.expectError {
 				assert.Error(t, err)
-				assert.Nil(t, server)
+				assert.Nil(t, s)
 			} else {
 				assert.NoError(t, err)
-				assert.NotNil(t, server)
+				assert.NotNil(t, s)
+				assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, healthStatus(s.(*server)))
 			}
 		})
 	}
 }
 
+func TestNewInsecureGateway(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.Config
+	}{
+		{
+			"MTLS",
+			config.Config{
+				HTTPGateway:    true,
+				CAChainFile:    "../../pkg/certs/ca.chain.cert",
+				ServerCertFile: "../../pkg/certs/server.cert",
+				ServerKeyFile:  "../../pkg/certs/server.key",
+			},
+		},
+		{
+			"AuthPolicy",
+			config.Config{
+				HTTPGateway:    true,
+				AuthPolicyFile: "policy.yaml",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New(tc.config, log.NewVoidLogger(), metrics.Mock(), mocktracer.New())
+
+			assert.Equal(t, ErrInsecureGateway, err)
+			assert.Nil(t, s)
+		})
+	}
+}
+
 func TestLiveHandler(t *testing.T) {
 	r := httptest.NewRequest("GET", "/live", nil)
 	w := httptest.NewRecorder()
@@ -80,10 +190,12 @@ func TestReadyHandler(t *testing.T) {
 	tests := []struct {
 		name               string
 		ready              bool
+		shutdown           bool
 		expectedStatusCode int
 	}{
-		{"Ready", true, http.StatusOK},
-		{"NotReady", false, http.StatusAccepted},
+		{"Ready", true, false, http.StatusOK},
+		{"NotReady", false, false, http.StatusAccepted},
+		{"ShuttingDown", true, true, http.StatusAccepted},
 	}
 
 	for _, tc := range tests {
@@ -92,7 +204,12 @@ func TestReadyHandler(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			server := &server{
-				ready: tc.ready,
+				health: health.NewServer(),
+			}
+
+			server.setServing(tc.ready)
+			if tc.shutdown {
+				server.health.Shutdown()
 			}
 
 			server.readyHandler(w, r)
@@ -101,6 +218,50 @@ func TestReadyHandler(t *testing.T) {
 	}
 }
 
+func TestMonitorDatabase(t *testing.T) {
+	tests := []struct {
+		name           string
+		arangoService  *mockArangoService
+		expectedStatus grpc_health_v1.HealthCheckResponse_ServingStatus
+	}{
+		{
+			"Reachable",
+			&mockArangoService{},
+			grpc_health_v1.HealthCheckResponse_SERVING,
+		},
+		{
+			"Unreachable",
+			&mockArangoService{
+				PingOutError: errors.New("database error"),
+			},
+			grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := &server{
+				config: config.Config{
+					HealthPeriod: 10 * time.Millisecond,
+				},
+				logger:        log.NewVoidLogger(),
+				arangoService: tc.arangoService,
+				health:        health.NewServer(),
+			}
+
+			server.setServing(true)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			server.monitorDatabase(ctx)
+
+			assert.True(t, tc.arangoService.PingCalled)
+			assert.Equal(t, tc.expectedStatus, healthStatus(server))
+		})
+	}
+}
+
 func TestStart(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -214,6 +375,7 @@ func TestStart(t *testing.T) {
 				arangoService: tc.arangoService,
 				httpServer:    tc.httpServer,
 				grpcServer:    tc.grpcServer,
+				health:        health.NewServer(),
 			}
 
 			if tc.signal > 0 {
@@ -258,12 +420,15 @@ func TestStop(t *testing.T) {
 				logger:     logger,
 				httpServer: tc.httpServer,
 				grpcServer: tc.grpcServer,
+				health:     health.NewServer(),
 			}
 
+			server.setServing(true)
 			server.Stop()
 
 			assert.True(t, tc.httpServer.ShutdownCalled)
 			assert.True(t, tc.grpcServer.GracefulStopCalled)
+			assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, healthStatus(server))
 		})
 	}
 }
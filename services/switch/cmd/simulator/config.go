@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultLogLevel          = "info"
+	defaultSwitchServiceAddr = "localhost:4030"
+	defaultTenantID          = "simulator"
+	defaultSwitches          = 10
+	defaultMode              = modeWatch
+	defaultPollPeriod        = time.Second
+	defaultLatency           = 100 * time.Millisecond
+	defaultLatencyJitter     = 50 * time.Millisecond
+	defaultFailureRate       = 0
+	defaultLoadProfile       = profileNone
+	defaultLoadRate          = 10
+	defaultLoadDuration      = time.Minute
+	defaultLoadConcurrency   = 50
+	defaultCleanup           = true
+
+	modeWatch = "watch"
+	modePoll  = "poll"
+)
+
+var (
+	defaultSiteIDs = []string{"simulator-1"}
+)
+
+// Config defines the schema for the simulator configurations
+type Config struct {
+	LogLevel          string
+	SwitchServiceAddr string
+	CAChainFile       string
+	ClientCertFile    string
+	ClientKeyFile     string
+	ServerName        string
+	TenantID          string
+	SiteIDs           []string
+	Switches          int
+	Mode              string
+	PollPeriod        time.Duration
+	Latency           time.Duration
+	LatencyJitter     time.Duration
+	FailureRate       float64
+	LoadProfile       string
+	LoadRate          float64
+	LoadDuration      time.Duration
+	LoadConcurrency   int
+	Cleanup           bool
+	Seed              int64
+}
+
+// newConfig creates a new configuration object
+func newConfig() Config {
+	return Config{
+		LogLevel:          defaultLogLevel,
+		SwitchServiceAddr: defaultSwitchServiceAddr,
+		TenantID:          defaultTenantID,
+		SiteIDs:           defaultSiteIDs,
+		Switches:          defaultSwitches,
+		Mode:              defaultMode,
+		PollPeriod:        defaultPollPeriod,
+		Latency:           defaultLatency,
+		LatencyJitter:     defaultLatencyJitter,
+		FailureRate:       defaultFailureRate,
+		LoadProfile:       defaultLoadProfile,
+		LoadRate:          defaultLoadRate,
+		LoadDuration:      defaultLoadDuration,
+		LoadConcurrency:   defaultLoadConcurrency,
+		Cleanup:           defaultCleanup,
+	}
+}
+
+// validate checks the configurations that cannot be defaulted
+func (c Config) validate() error {
+	if len(c.SiteIDs) == 0 {
+		return fmt.Errorf("at least one site is required")
+	}
+
+	if c.Switches <= 0 {
+		return fmt.Errorf("invalid number of switches: %d", c.Switches)
+	}
+
+	if c.Mode != modeWatch && c.Mode != modePoll {
+		return fmt.Errorf("invalid mode %q: must be %s or %s", c.Mode, modeWatch, modePoll)
+	}
+
+	if c.FailureRate < 0 || c.FailureRate > 1 {
+		return fmt.Errorf("invalid failure rate %g: must be between 0 and 1", c.FailureRate)
+	}
+
+	if _, ok := profiles[c.LoadProfile]; !ok {
+		return fmt.Errorf("invalid load profile %q", c.LoadProfile)
+	}
+
+	if c.LoadProfile != profileNone && (c.LoadRate <= 0 || c.LoadDuration <= 0) {
+		return fmt.Errorf("load profile %s requires a positive rate and duration", c.LoadProfile)
+	}
+
+	return nil
+}
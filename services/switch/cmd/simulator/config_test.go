@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConfig(t *testing.T) {
+	config := newConfig()
+
+	assert.Equal(t, defaultLogLevel, config.LogLevel)
+	assert.Equal(t, defaultSwitchServiceAddr, config.SwitchServiceAddr)
+	assert.Empty(t, config.CAChainFile)
+	assert.Empty(t, config.ClientCertFile)
+	assert.Empty(t, config.ClientKeyFile)
+	assert.Empty(t, config.ServerName)
+	assert.Equal(t, defaultTenantID, config.TenantID)
+	assert.Equal(t, defaultSiteIDs, config.SiteIDs)
+	assert.Equal(t, defaultSwitches, config.Switches)
+	assert.Equal(t, defaultMode, config.Mode)
+	assert.Equal(t, defaultPollPeriod, config.PollPeriod)
+	assert.Equal(t, defaultLatency, config.Latency)
+	assert.Equal(t, defaultLatencyJitter, config.LatencyJitter)
+	assert.Equal(t, float64(defaultFailureRate), config.FailureRate)
+	assert.Equal(t, defaultLoadProfile, config.LoadProfile)
+	assert.Equal(t, float64(defaultLoadRate), config.LoadRate)
+	assert.Equal(t, defaultLoadDuration, config.LoadDuration)
+	assert.Equal(t, defaultLoadConcurrency, config.LoadConcurrency)
+	assert.Equal(t, defaultCleanup, config.Cleanup)
+	assert.Zero(t, config.Seed)
+
+	assert.NoError(t, config.validate())
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		update        func(*Config)
+		expectedError string
+	}{
+		{"NoSite", func(c *Config) { c.SiteIDs = nil }, "at least one site is required"},
+		{"NoSwitch", func(c *Config) { c.Switches = 0 }, "invalid number of switches: 0"},
+		{"InvalidMode", func(c *Config) { c.Mode = "push" }, `invalid mode "push": must be watch or poll`},
+		{"InvalidFailureRate", func(c *Config) { c.FailureRate = 1.5 }, "invalid failure rate 1.5: must be between 0 and 1"},
+		{"InvalidProfile", func(c *Config) { c.LoadProfile = "wave" }, `invalid load profile "wave"`},
+		{"NoRate", func(c *Config) { c.LoadProfile = profileRamp; c.LoadRate = 0 }, "load profile ramp requires a positive rate and duration"},
+		{"NoDuration", func(c *Config) { c.LoadProfile = profileSpike; c.LoadDuration = -time.Second }, "load profile spike requires a positive rate and duration"},
+		{"Poll", func(c *Config) { c.Mode = modePoll; c.FailureRate = 1 }, ""},
+		{"Load", func(c *Config) { c.LoadProfile = profileConstant }, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config := newConfig()
+			tc.update(&config)
+
+			err := config.validate()
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/client"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	retryDelay    = time.Second
+	removeTimeout = 30 * time.Second
+)
+
+var (
+	deviceStates = []string{"OFF", "ON"}
+)
+
+type (
+	// device is a virtual switch which changes to its desired state after a latency and reports the state it is in
+	device struct {
+		id     string
+		siteID string
+
+		mutex    sync.Mutex
+		desired  string
+		reported string
+		wake     chan struct{}
+	}
+
+	// fleet is the set of virtual switches of the simulator and the agent running them
+	fleet struct {
+		client      proto.SwitchServiceClient
+		logger      *log.Logger
+		stats       *stats
+		tenantID    string
+		latency     time.Duration
+		jitter      time.Duration
+		failureRate float64
+
+		randMutex sync.Mutex
+		rand      *rand.Rand
+
+		devices map[string]*device
+		order   []*device
+	}
+)
+
+func newDevice(id, siteID, state string) *device {
+	return &device{
+		id:       id,
+		siteID:   siteID,
+		desired:  state,
+		reported: state,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// command sets the desired state of the device and wakes it up if the state is new
+func (d *device) command(state string) {
+	d.mutex.Lock()
+	changed := d.desired != state || d.reported != state
+	d.desired = state
+	d.mutex.Unlock()
+
+	if changed {
+		d.notify()
+	}
+}
+
+func (d *device) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *device) state() (desired, reported string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.desired, d.reported
+}
+
+func (d *device) setReported(state string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.reported = state
+}
+
+func newFleet(client proto.SwitchServiceClient, logger *log.Logger, stats *stats, config Config, rand *rand.Rand) *fleet {
+	return &fleet{
+		client:      client,
+		logger:      logger,
+		stats:       stats,
+		tenantID:    config.TenantID,
+		latency:     config.Latency,
+		jitter:      config.LatencyJitter,
+		failureRate: config.FailureRate,
+		rand:        rand,
+		devices:     map[string]*device{},
+	}
+}
+
+func (f *fleet) context(ctx context.Context) context.Context {
+	return client.WithTenant(ctx, f.tenantID)
+}
+
+// register installs n switches spread evenly across the sites and reports their initial state
+func (f *fleet) register(ctx context.Context, siteIDs []string, n int) error {
+	for i := 0; i < n; i++ {
+		siteID := siteIDs[i%len(siteIDs)]
+
+		sw, err := f.client.InstallSwitch(f.context(ctx), &proto.InstallSwitchRequest{
+			SiteId: siteID,
+			Name:   fmt.Sprintf("simulator-%d", i+1),
+			State:  deviceStates[0],
+			States: deviceStates,
+		})
+
+		if err != nil {
+			return fmt.Errorf("cannot install switch %d: %s", i+1, err)
+		}
+
+		d := newDevice(sw.Id, siteID, sw.State)
+		f.devices[d.id] = d
+		f.order = append(f.order, d)
+
+		// The service only tracks the convergence of switches whose state has been reported
+		if err := f.report(ctx, d, d.reported); err != nil {
+			return fmt.Errorf("cannot report switch %s: %s", d.id, err)
+		}
+	}
+
+	f.logger.Info("message", fmt.Sprintf("%d switches installed on %d sites.", n, len(siteIDs)))
+
+	return nil
+}
+
+// remove removes every registered switch.
+// It does not use the context of the simulator which is already done when the simulator stops.
+func (f *fleet) remove() {
+	ctx, cancel := context.WithTimeout(context.Background(), removeTimeout)
+	defer cancel()
+
+	removed := 0
+	for _, d := range f.order {
+		if _, err := f.client.RemoveSwitch(f.context(ctx), &proto.RemoveSwitchRequest{Id: d.id}); err != nil {
+			f.logger.Error("switchId", d.id, "message", fmt.Sprintf("Failed to remove switch: %s", err))
+			continue
+		}
+		removed++
+	}
+
+	f.logger.Info("message", fmt.Sprintf("%d switches removed.", removed))
+}
+
+// command passes the desired state of a switch to its device and ignores the switches not simulated
+func (f *fleet) command(sw *proto.Switch) {
+	if d, ok := f.devices[sw.Id]; ok {
+		d.command(sw.State)
+	}
+}
+
+// pick returns a random device and a state it is not meant to be in
+func (f *fleet) pick() (*device, string) {
+	f.randMutex.Lock()
+	d := f.order[f.rand.Intn(len(f.order))]
+	offset := 1 + f.rand.Intn(len(deviceStates)-1)
+	f.randMutex.Unlock()
+
+	desired, _ := d.state()
+	for i, state := range deviceStates {
+		if state == desired {
+			return d, deviceStates[(i+offset)%len(deviceStates)]
+		}
+	}
+
+	return d, deviceStates[0]
+}
+
+// delay returns how long a device takes to change its state
+func (f *fleet) delay() time.Duration {
+	if f.jitter <= 0 {
+		return f.latency
+	}
+
+	f.randMutex.Lock()
+	defer f.randMutex.Unlock()
+
+	delay := f.latency - f.jitter + time.Duration(f.rand.Int63n(int64(2*f.jitter)+1))
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// fail decides whether a device fails to change its state
+func (f *fleet) fail() bool {
+	if f.failureRate <= 0 {
+		return false
+	}
+
+	f.randMutex.Lock()
+	defer f.randMutex.Unlock()
+
+	return f.rand.Float64() < f.failureRate
+}
+
+func (f *fleet) report(ctx context.Context, d *device, state string) error {
+	_, err := f.client.ReportSwitchState(f.context(ctx), &proto.ReportSwitchStateRequest{
+		Id:    d.id,
+		State: state,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	d.setReported(state)
+	f.stats.reported(d.id, state, time.Now())
+
+	return nil
+}
+
+// start runs the agent of every device until the context is done
+func (f *fleet) start(ctx context.Context, wg *sync.WaitGroup) {
+	for _, d := range f.order {
+		wg.Add(1)
+		go func(d *device) {
+			defer wg.Done()
+			f.run(ctx, d)
+		}(d)
+	}
+}
+
+// run changes a device to its desired state whenever it is commanded.
+// A device that fails keeps reporting its previous state, so the service sees it out of sync until it is commanded again.
+func (f *fleet) run(ctx context.Context, d *device) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		}
+
+		desired, reported := d.state()
+		if desired == reported {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(f.delay()):
+		}
+
+		// The latest desired state is applied if it changed during the latency
+		state, _ := d.state()
+		if f.fail() {
+			f.stats.failed()
+			f.logger.Debug("switchId", d.id, "message", fmt.Sprintf("Switch failed to change to state %q.", state))
+			state = reported
+		}
+
+		if err := f.report(ctx, d, state); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			f.logger.Error("switchId", d.id, "message", fmt.Sprintf("Failed to report switch state: %s", err))
+			time.AfterFunc(retryDelay, d.notify)
+		}
+	}
+}
+
+// watch commands the devices of a site from a watch stream and resumes the stream when it breaks
+func (f *fleet) watch(ctx context.Context, siteID string) {
+	var revision uint64
+
+	for {
+		err := f.watchOnce(ctx, siteID, &revision)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// The changes missed are no longer kept, so the stream starts over with the current state of the switches
+		if status.Code(err) == codes.OutOfRange {
+			revision = 0
+		}
+
+		f.logger.Warn("siteId", siteID, "message", fmt.Sprintf("Watch stream broke: %s", err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (f *fleet) watchOnce(ctx context.Context, siteID string, revision *uint64) error {
+	stream, err := f.client.WatchSwitches(f.context(ctx), &proto.WatchSwitchesRequest{
+		SiteId:       siteID,
+		FromRevision: *revision,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return fmt.Errorf("stream closed by the service")
+		}
+		if err != nil {
+			return err
+		}
+
+		if event.Revision > *revision {
+			*revision = event.Revision
+		}
+
+		switch event.Type {
+		case proto.SwitchEvent_CURRENT, proto.SwitchEvent_INSTALLED, proto.SwitchEvent_STATE_CHANGED, proto.SwitchEvent_UPDATED:
+			f.command(event.Switch)
+		}
+	}
+}
+
+// poll commands the devices of a site from their state fetched every period
+func (f *fleet) poll(ctx context.Context, siteID string, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		if err := f.pollOnce(ctx, siteID); err != nil && ctx.Err() == nil {
+			f.logger.Warn("siteId", siteID, "message", fmt.Sprintf("Failed to poll switches: %s", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *fleet) pollOnce(ctx context.Context, siteID string) error {
+	stream, err := f.client.GetSwitches(f.context(ctx), &proto.GetSwitchesRequest{
+		SiteId: siteID,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		sw, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		f.command(sw)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testFleet(client *mockSwitchClient, update func(*Config)) *fleet {
+	config := newConfig()
+	config.Latency = 0
+	config.LatencyJitter = 0
+	if update != nil {
+		update(&config)
+	}
+
+	return newFleet(client, log.NewVoidLogger(), newStats(), config, rand.New(rand.NewSource(1)))
+}
+
+func TestDeviceCommand(t *testing.T) {
+	d := newDevice("switch-1", "site-1", "OFF")
+
+	d.command("OFF")
+	assert.Len(t, d.wake, 0)
+
+	d.command("ON")
+	d.command("ON")
+	assert.Len(t, d.wake, 1)
+
+	desired, reported := d.state()
+	assert.Equal(t, "ON", desired)
+	assert.Equal(t, "OFF", reported)
+}
+
+func TestFleetRegister(t *testing.T) {
+	tests := []struct {
+		name            string
+		client          *mockSwitchClient
+		siteIDs         []string
+		n               int
+		expectedError   string
+		expectedSiteIDs []string
+	}{
+		{
+			"InstallFail",
+			&mockSwitchClient{InstallOutError: status.Error(codes.ResourceExhausted, "quota exceeded")},
+			[]string{"site-1"},
+			1,
+			"cannot install switch 1: rpc error: code = ResourceExhausted desc = quota exceeded",
+			nil,
+		},
+		{
+			"ReportFail",
+			&mockSwitchClient{switches: map[string]*proto.Switch{}, ReportOutError: status.Error(codes.Unavailable, "unavailable")},
+			[]string{"site-1"},
+			1,
+			"cannot report switch switch-1: rpc error: code = Unavailable desc = unavailable",
+			nil,
+		},
+		{
+			"Success",
+			newMockSwitchClient(),
+			[]string{"site-1", "site-2"},
+			3,
+			"",
+			[]string{"site-1", "site-2", "site-1"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := testFleet(tc.client, nil)
+
+			err := f.register(context.Background(), tc.siteIDs, tc.n)
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, f.order, tc.n)
+			for i, d := range f.order {
+				assert.Equal(t, tc.expectedSiteIDs[i], d.siteID)
+				assert.Equal(t, "OFF", tc.client.get(d.id).ReportedState)
+				assert.Equal(t, defaultTenantID, tc.client.InstallInTenants[i])
+			}
+			assert.Len(t, tc.client.reports(), tc.n)
+		})
+	}
+}
+
+func TestFleetRemove(t *testing.T) {
+	client := newMockSwitchClient()
+	f := testFleet(client, nil)
+
+	assert.NoError(t, f.register(context.Background(), []string{"site-1"}, 2))
+	f.remove()
+
+	assert.Equal(t, []string{"switch-1", "switch-2"}, client.RemoveInIDs)
+}
+
+func TestFleetPick(t *testing.T) {
+	client := newMockSwitchClient()
+	f := testFleet(client, nil)
+	assert.NoError(t, f.register(context.Background(), []string{"site-1"}, 5))
+
+	for i := 0; i < 20; i++ {
+		d, state := f.pick()
+		desired, _ := d.state()
+		assert.NotEqual(t, desired, state)
+		assert.Contains(t, deviceStates, state)
+	}
+}
+
+func TestFleetDelay(t *testing.T) {
+	tests := []struct {
+		name        string
+		latency     time.Duration
+		jitter      time.Duration
+		expectedMin time.Duration
+		expectedMax time.Duration
+	}{
+		{"NoJitter", 100 * time.Millisecond, 0, 100 * time.Millisecond, 100 * time.Millisecond},
+		{"Jitter", 100 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond, 150 * time.Millisecond},
+		{"JitterAboveLatency", 10 * time.Millisecond, 50 * time.Millisecond, 0, 60 * time.Millisecond},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := testFleet(newMockSwitchClient(), func(c *Config) {
+				c.Latency = tc.latency
+				c.LatencyJitter = tc.jitter
+			})
+
+			for i := 0; i < 100; i++ {
+				delay := f.delay()
+				assert.True(t, delay >= tc.expectedMin && delay <= tc.expectedMax, "delay %s", delay)
+			}
+		})
+	}
+}
+
+func TestFleetRun(t *testing.T) {
+	tests := []struct {
+		name             string
+		failureRate      float64
+		expectedReported string
+		expectedFailures int
+	}{
+		{"Success", 0, "ON", 0},
+		{"Failure", 1, "OFF", 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newMockSwitchClient()
+			f := testFleet(client, func(c *Config) {
+				c.FailureRate = tc.failureRate
+			})
+			assert.NoError(t, f.register(context.Background(), []string{"site-1"}, 1))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			f.start(ctx, &wg)
+
+			d := f.order[0]
+			d.command("ON")
+
+			assert.Eventually(t, func() bool {
+				return len(client.reports()) == 2
+			}, time.Second, time.Millisecond)
+
+			cancel()
+			wg.Wait()
+
+			assert.Equal(t, tc.expectedReported, client.get(d.id).ReportedState)
+			assert.Equal(t, tc.expectedFailures, f.stats.failures)
+		})
+	}
+}
+
+func TestFleetWatch(t *testing.T) {
+	client := newMockSwitchClient()
+	client.WatchOutErrors = []error{errors.New("connection refused")}
+
+	f := testFleet(client, nil)
+	assert.NoError(t, f.register(context.Background(), []string{"site-1", "site-2"}, 2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	f.start(ctx, &wg)
+
+	for _, siteID := range []string{"site-1", "site-2"} {
+		wg.Add(1)
+		go func(siteID string) {
+			defer wg.Done()
+			f.watch(ctx, siteID)
+		}(siteID)
+	}
+
+	// State changes are made until both watchers are connected and their switches reported
+	assert.Eventually(t, func() bool {
+		for _, d := range f.order {
+			if _, err := client.SetSwitch(ctx, &proto.SetSwitchRequest{Id: d.id, State: "ON"}); err != nil {
+				return false
+			}
+		}
+		return client.get("switch-1").ReportedState == "ON" && client.get("switch-2").ReportedState == "ON"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	wg.Wait()
+
+	assert.True(t, len(client.WatchInReqs) >= 3)
+}
+
+func TestFleetPoll(t *testing.T) {
+	client := newMockSwitchClient()
+	f := testFleet(client, nil)
+	assert.NoError(t, f.register(context.Background(), []string{"site-1"}, 2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	f.start(ctx, &wg)
+
+	_, err := client.SetSwitch(ctx, &proto.SetSwitchRequest{Id: "switch-2", State: "ON"})
+	assert.NoError(t, err)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		f.poll(ctx, "site-1", 10*time.Millisecond)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return client.get("switch-2").ReportedState == "ON"
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	wg.Wait()
+
+	assert.Equal(t, "OFF", client.get("switch-1").ReportedState)
+}
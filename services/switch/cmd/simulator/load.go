@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	loadTick = 100 * time.Millisecond
+
+	profileNone     = "none"
+	profileConstant = "constant"
+	profileRamp     = "ramp"
+	profileSpike    = "spike"
+)
+
+// profiles return the rate of state changes at a time of a load run given the peak rate
+var profiles = map[string]func(peak float64, elapsed, duration time.Duration) float64{
+	// No state changes are made and the simulator only reacts to the ones made by others
+	profileNone: func(peak float64, elapsed, duration time.Duration) float64 {
+		return 0
+	},
+	// The peak rate for the whole run
+	profileConstant: func(peak float64, elapsed, duration time.Duration) float64 {
+		return peak
+	},
+	// A rate rising linearly from zero to the peak rate
+	profileRamp: func(peak float64, elapsed, duration time.Duration) float64 {
+		return peak * float64(elapsed) / float64(duration)
+	},
+	// A tenth of the peak rate with the peak rate in the middle fifth of the run
+	profileSpike: func(peak float64, elapsed, duration time.Duration) float64 {
+		if elapsed >= 2*duration/5 && elapsed < 3*duration/5 {
+			return peak
+		}
+		return peak / 10
+	},
+}
+
+type (
+	// command is a state change waiting for a device to report it
+	command struct {
+		state string
+		time  time.Time
+	}
+
+	// stats collects the outcome of a load run
+	stats struct {
+		mutex       sync.Mutex
+		calls       int
+		skipped     int
+		errors      map[string]int
+		latencies   []time.Duration
+		commands    map[string]command
+		convergence []time.Duration
+		reports     int
+		failures    int
+	}
+
+	// loadGenerator changes the state of random switches at the rate of a load profile
+	loadGenerator struct {
+		client      proto.SwitchServiceClient
+		fleet       *fleet
+		stats       *stats
+		profile     func(peak float64, elapsed, duration time.Duration) float64
+		rate        float64
+		duration    time.Duration
+		concurrency int
+		tick        time.Duration
+	}
+)
+
+func newStats() *stats {
+	return &stats{
+		errors:   map[string]int{},
+		commands: map[string]command{},
+	}
+}
+
+// commanded records a state change sent to a switch
+func (s *stats) commanded(id, state string, t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.commands[id] = command{state, t}
+}
+
+// called records the outcome of a state change
+func (s *stats) called(id string, latency time.Duration, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls++
+	s.latencies = append(s.latencies, latency)
+
+	if err != nil {
+		s.errors[status.Code(err).String()]++
+		delete(s.commands, id)
+	}
+}
+
+// reported records a state reported by a device and how long it took to converge if it was commanded
+func (s *stats) reported(id, state string, t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.reports++
+
+	if c, ok := s.commands[id]; ok && c.state == state {
+		s.convergence = append(s.convergence, t.Sub(c.time))
+		delete(s.commands, id)
+	}
+}
+
+// failed records a device failing to change its state
+func (s *stats) failed() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures++
+}
+
+// skip records a state change not made because too many were in flight
+func (s *stats) skip() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.skipped++
+}
+
+// summary returns the stats as key-value pairs for logging
+func (s *stats) summary() []interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return []interface{}{
+		"calls", s.calls,
+		"skipped", s.skipped,
+		"errors", s.errors,
+		"latencyP50", percentile(s.latencies, 0.50).String(),
+		"latencyP95", percentile(s.latencies, 0.95).String(),
+		"latencyP99", percentile(s.latencies, 0.99).String(),
+		"latencyMax", percentile(s.latencies, 1).String(),
+		"converged", len(s.convergence),
+		"notConverged", len(s.commands),
+		"convergenceP50", percentile(s.convergence, 0.50).String(),
+		"convergenceP95", percentile(s.convergence, 0.95).String(),
+		"convergenceP99", percentile(s.convergence, 0.99).String(),
+		"reports", s.reports,
+		"failures", s.failures,
+	}
+}
+
+// percentile returns the smallest duration greater than or equal to a fraction p of the durations
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+
+	return sorted[i]
+}
+
+func newLoadGenerator(client proto.SwitchServiceClient, fleet *fleet, stats *stats, config Config) *loadGenerator {
+	return &loadGenerator{
+		client:      client,
+		fleet:       fleet,
+		stats:       stats,
+		profile:     profiles[config.LoadProfile],
+		rate:        config.LoadRate,
+		duration:    config.LoadDuration,
+		concurrency: config.LoadConcurrency,
+		tick:        loadTick,
+	}
+}
+
+// run makes state changes at the rate of the profile until the duration is over or the context is done.
+// A state change due while all calls allowed by the concurrency are in flight is skipped, so a slow service does not pile up calls.
+func (g *loadGenerator) run(ctx context.Context) {
+	concurrency := g.concurrency
+	if concurrency <= 0 {
+		concurrency = defaultLoadConcurrency
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	inFlight := make(chan struct{}, concurrency)
+	ticker := time.NewTicker(g.tick)
+	defer ticker.Stop()
+
+	start := time.Now()
+	last := start
+	due := 0.0
+
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		elapsed := now.Sub(start)
+		if elapsed >= g.duration {
+			return
+		}
+
+		due += g.profile(g.rate, elapsed, g.duration) * now.Sub(last).Seconds()
+		last = now
+
+		for ; due >= 1; due-- {
+			select {
+			case inFlight <- struct{}{}:
+			default:
+				g.stats.skip()
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				g.setSwitch(ctx)
+				<-inFlight
+			}()
+		}
+	}
+}
+
+func (g *loadGenerator) setSwitch(ctx context.Context) {
+	d, state := g.fleet.pick()
+
+	start := time.Now()
+	g.stats.commanded(d.id, state, start)
+
+	_, err := g.client.SetSwitch(g.fleet.context(ctx), &proto.SetSwitchRequest{
+		Id:     d.id,
+		State:  state,
+		Reason: "simulator load",
+	})
+
+	g.stats.called(d.id, time.Since(start), err)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestProfiles(t *testing.T) {
+	tests := []struct {
+		profile      string
+		elapsed      time.Duration
+		expectedRate float64
+	}{
+		{profileNone, 30 * time.Second, 0},
+		{profileConstant, 0, 100},
+		{profileConstant, 90 * time.Second, 100},
+		{profileRamp, 0, 0},
+		{profileRamp, 25 * time.Second, 25},
+		{profileRamp, 75 * time.Second, 75},
+		{profileSpike, 10 * time.Second, 10},
+		{profileSpike, 40 * time.Second, 100},
+		{profileSpike, 59 * time.Second, 100},
+		{profileSpike, 60 * time.Second, 10},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.profile, func(t *testing.T) {
+			rate := profiles[tc.profile](100, tc.elapsed, 100*time.Second)
+			assert.Equal(t, tc.expectedRate, rate)
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	durations := []time.Duration{5, 1, 4, 2, 3, 10, 9, 8, 7, 6}
+
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		p         float64
+		expected  time.Duration
+	}{
+		{"Empty", nil, 0.5, 0},
+		{"Min", durations, 0, 1},
+		{"P50", durations, 0.5, 5},
+		{"P95", durations, 0.95, 10},
+		{"Max", durations, 1, 10},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, percentile(tc.durations, tc.p))
+		})
+	}
+
+	// The durations are not reordered
+	assert.Equal(t, time.Duration(5), durations[0])
+}
+
+func TestStats(t *testing.T) {
+	s := newStats()
+	start := time.Now()
+
+	s.commanded("switch-1", "ON", start)
+	s.commanded("switch-2", "ON", start)
+	s.commanded("switch-3", "OFF", start)
+	s.called("switch-1", 10*time.Millisecond, nil)
+	s.called("switch-2", 20*time.Millisecond, nil)
+	s.called("switch-3", 30*time.Millisecond, status.Error(codes.NotFound, "switch not found"))
+	s.skip()
+
+	// A report of another state does not converge
+	s.reported("switch-1", "ON", start.Add(time.Second))
+	s.reported("switch-2", "OFF", start.Add(time.Second))
+	s.failed()
+
+	assert.Equal(t, 3, s.calls)
+	assert.Equal(t, 1, s.skipped)
+	assert.Equal(t, map[string]int{"NotFound": 1}, s.errors)
+	assert.Equal(t, []time.Duration{time.Second}, s.convergence)
+	assert.Len(t, s.commands, 1)
+	assert.Equal(t, 2, s.reports)
+	assert.Equal(t, 1, s.failures)
+
+	summary := s.summary()
+	assert.Len(t, summary, 28)
+	assert.Contains(t, summary, "30ms")
+}
+
+func TestLoadGenerator(t *testing.T) {
+	tests := []struct {
+		name          string
+		setError      error
+		concurrency   int
+		expectedError bool
+	}{
+		{"Success", nil, 10, false},
+		{"Error", status.Error(codes.Unavailable, "unavailable"), 10, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newMockSwitchClient()
+			client.SetOutError = tc.setError
+
+			f := testFleet(client, nil)
+			assert.NoError(t, f.register(context.Background(), []string{"site-1"}, 4))
+
+			g := newLoadGenerator(client, f, f.stats, Config{
+				LoadProfile:     profileConstant,
+				LoadRate:        100,
+				LoadDuration:    500 * time.Millisecond,
+				LoadConcurrency: tc.concurrency,
+			})
+			g.tick = 10 * time.Millisecond
+
+			g.run(context.Background())
+
+			// About 50 state changes are due within the duration
+			assert.InDelta(t, 50, f.stats.calls+f.stats.skipped, 15)
+			assert.Len(t, client.SetInReqs, f.stats.calls)
+			for _, req := range client.SetInReqs {
+				assert.Equal(t, "simulator load", req.Reason)
+			}
+
+			if tc.expectedError {
+				assert.Equal(t, f.stats.calls, f.stats.errors["Unavailable"])
+				assert.Empty(t, f.stats.commands)
+			} else {
+				assert.Empty(t, f.stats.errors)
+			}
+		})
+	}
+}
+
+func TestLoadGeneratorCancel(t *testing.T) {
+	client := newMockSwitchClient()
+	f := testFleet(client, nil)
+	assert.NoError(t, f.register(context.Background(), []string{"site-1"}, 1))
+
+	g := newLoadGenerator(client, f, f.stats, Config{
+		LoadProfile:  profileConstant,
+		LoadRate:     100,
+		LoadDuration: time.Hour,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	g.run(ctx)
+
+	assert.True(t, time.Since(start) < time.Second)
+	assert.NotZero(t, f.stats.calls)
+}
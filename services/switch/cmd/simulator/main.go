@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/moorara/konfig"
+	"github.com/moorara/microservices-demo/services/switch/pkg/client"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+)
+
+const serviceName = "switch-simulator"
+
+func main() {
+	config := newConfig()
+	if err := konfig.Pick(&config); err != nil {
+		panic(err)
+	}
+
+	if err := config.validate(); err != nil {
+		panic(err)
+	}
+
+	logger := log.NewLogger(serviceName, "singleton", config.LogLevel)
+
+	switchClient, conn, err := client.New(client.Config{
+		Addr:           config.SwitchServiceAddr,
+		CAChainFile:    config.CAChainFile,
+		ClientCertFile: config.ClientCertFile,
+		ClientKeyFile:  config.ClientKeyFile,
+		ServerName:     config.ServerName,
+		Logger:         logger,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Handle OS signals
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-sigs
+		logger.Info("signal", sig.String(), "message", "Simulator stopping ...")
+		cancel()
+	}()
+
+	logger.Info("seed", seed, "message", fmt.Sprintf("%s started.", serviceName))
+
+	sim := newSimulator(switchClient, logger, config, rand.New(rand.NewSource(seed)))
+	if err := sim.run(ctx); err != nil {
+		logger.Error("message", err.Error())
+		os.Exit(1)
+	}
+}
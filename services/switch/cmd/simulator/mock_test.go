@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// mockSwitchClient is an in-memory switch service for the calls made by the simulator
+type mockSwitchClient struct {
+	proto.SwitchServiceClient
+
+	mutex    sync.Mutex
+	switches map[string]*proto.Switch
+	order    []string
+	revision uint64
+	watchers []chan *proto.SwitchEvent
+
+	InstallInTenants []string
+	InstallOutError  error
+
+	RemoveInIDs []string
+
+	ReportInReqs   []*proto.ReportSwitchStateRequest
+	ReportOutError error
+
+	SetInReqs   []*proto.SetSwitchRequest
+	SetOutError error
+
+	GetCallCount int
+
+	WatchInReqs []*proto.WatchSwitchesRequest
+	// WatchOutErrors fail the watch calls in turn before the others succeed
+	WatchOutErrors []error
+}
+
+func newMockSwitchClient() *mockSwitchClient {
+	return &mockSwitchClient{
+		switches: map[string]*proto.Switch{},
+	}
+}
+
+func (m *mockSwitchClient) tenant(ctx context.Context) string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if values := md.Get("tenant-id"); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (m *mockSwitchClient) get(id string) *proto.Switch {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	sw := *m.switches[id]
+	return &sw
+}
+
+func (m *mockSwitchClient) reports() []*proto.ReportSwitchStateRequest {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]*proto.ReportSwitchStateRequest{}, m.ReportInReqs...)
+}
+
+// publish sends a state change to the watchers as the switch service does
+func (m *mockSwitchClient) publish(sw *proto.Switch) {
+	m.revision++
+	for _, events := range m.watchers {
+		copy := *sw
+		select {
+		case events <- &proto.SwitchEvent{Type: proto.SwitchEvent_STATE_CHANGED, Revision: m.revision, Switch: &copy}:
+		default:
+		}
+	}
+}
+
+func (m *mockSwitchClient) InstallSwitch(ctx context.Context, in *proto.InstallSwitchRequest, opts ...grpc.CallOption) (*proto.Switch, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.InstallInTenants = append(m.InstallInTenants, m.tenant(ctx))
+	if m.InstallOutError != nil {
+		return nil, m.InstallOutError
+	}
+
+	sw := &proto.Switch{
+		Id:     fmt.Sprintf("switch-%d", len(m.order)+1),
+		SiteId: in.SiteId,
+		Name:   in.Name,
+		State:  in.State,
+		States: in.States,
+	}
+
+	m.switches[sw.Id] = sw
+	m.order = append(m.order, sw.Id)
+
+	copy := *sw
+	return &copy, nil
+}
+
+func (m *mockSwitchClient) RemoveSwitch(ctx context.Context, in *proto.RemoveSwitchRequest, opts ...grpc.CallOption) (*proto.RemoveSwitchResponse, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.RemoveInIDs = append(m.RemoveInIDs, in.Id)
+	delete(m.switches, in.Id)
+
+	return &proto.RemoveSwitchResponse{}, nil
+}
+
+func (m *mockSwitchClient) ReportSwitchState(ctx context.Context, in *proto.ReportSwitchStateRequest, opts ...grpc.CallOption) (*proto.Switch, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.ReportInReqs = append(m.ReportInReqs, in)
+	if m.ReportOutError != nil {
+		return nil, m.ReportOutError
+	}
+
+	sw, ok := m.switches[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "switch not found")
+	}
+
+	sw.ReportedState = in.State
+	sw.Pending = sw.State != in.State
+
+	copy := *sw
+	return &copy, nil
+}
+
+func (m *mockSwitchClient) SetSwitch(ctx context.Context, in *proto.SetSwitchRequest, opts ...grpc.CallOption) (*proto.SetSwitchResponse, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.SetInReqs = append(m.SetInReqs, in)
+	if m.SetOutError != nil {
+		return nil, m.SetOutError
+	}
+
+	sw, ok := m.switches[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "switch not found")
+	}
+
+	previous := sw.State
+	sw.State = in.State
+	sw.Pending = sw.State != sw.ReportedState
+	m.publish(sw)
+
+	return &proto.SetSwitchResponse{PreviousState: previous}, nil
+}
+
+func (m *mockSwitchClient) GetSwitches(ctx context.Context, in *proto.GetSwitchesRequest, opts ...grpc.CallOption) (proto.SwitchService_GetSwitchesClient, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.GetCallCount++
+
+	stream := &mockGetSwitchesClient{}
+	for _, id := range m.order {
+		if sw, ok := m.switches[id]; ok && sw.SiteId == in.SiteId {
+			copy := *sw
+			stream.switches = append(stream.switches, &copy)
+		}
+	}
+
+	return stream, nil
+}
+
+func (m *mockSwitchClient) WatchSwitches(ctx context.Context, in *proto.WatchSwitchesRequest, opts ...grpc.CallOption) (proto.SwitchService_WatchSwitchesClient, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.WatchInReqs = append(m.WatchInReqs, in)
+	if len(m.WatchOutErrors) > 0 {
+		err := m.WatchOutErrors[0]
+		m.WatchOutErrors = m.WatchOutErrors[1:]
+		return nil, err
+	}
+
+	stream := &mockWatchSwitchesClient{
+		ctx:    ctx,
+		siteID: in.SiteId,
+		events: make(chan *proto.SwitchEvent, 1000),
+	}
+	m.watchers = append(m.watchers, stream.events)
+
+	if in.FromRevision == 0 {
+		for _, id := range m.order {
+			if sw, ok := m.switches[id]; ok && sw.SiteId == in.SiteId {
+				copy := *sw
+				stream.current = append(stream.current, &proto.SwitchEvent{Type: proto.SwitchEvent_CURRENT, Revision: m.revision, Switch: &copy})
+			}
+		}
+	}
+
+	return stream, nil
+}
+
+type mockGetSwitchesClient struct {
+	grpc.ClientStream
+	switches []*proto.Switch
+}
+
+func (m *mockGetSwitchesClient) Recv() (*proto.Switch, error) {
+	if len(m.switches) == 0 {
+		return nil, io.EOF
+	}
+
+	sw := m.switches[0]
+	m.switches = m.switches[1:]
+	return sw, nil
+}
+
+type mockWatchSwitchesClient struct {
+	grpc.ClientStream
+	ctx     context.Context
+	siteID  string
+	current []*proto.SwitchEvent
+	events  chan *proto.SwitchEvent
+}
+
+func (m *mockWatchSwitchesClient) Recv() (*proto.SwitchEvent, error) {
+	if len(m.current) > 0 {
+		event := m.current[0]
+		m.current = m.current[1:]
+		return event, nil
+	}
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return nil, status.Error(codes.Canceled, m.ctx.Err().Error())
+		case event := <-m.events:
+			if event.Switch.SiteId == m.siteID {
+				return event, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+)
+
+type (
+	// simulator runs virtual switches against the switch service and optionally puts them under load
+	simulator struct {
+		config Config
+		logger *log.Logger
+		stats  *stats
+		fleet  *fleet
+		load   *loadGenerator
+	}
+)
+
+func newSimulator(client proto.SwitchServiceClient, logger *log.Logger, config Config, rand *rand.Rand) *simulator {
+	stats := newStats()
+	fleet := newFleet(client, logger, stats, config, rand)
+
+	return &simulator{
+		config: config,
+		logger: logger,
+		stats:  stats,
+		fleet:  fleet,
+		load:   newLoadGenerator(client, fleet, stats, config),
+	}
+}
+
+// settleTime is how long the devices are given to converge after a load run
+func (s *simulator) settleTime() time.Duration {
+	settle := 2 * (s.config.Latency + s.config.LatencyJitter)
+	if s.config.Mode == modePoll {
+		settle += s.config.PollPeriod
+	}
+	return settle
+}
+
+// run registers the switches and runs their agents until the context is done or, with a load profile, until the load run is over.
+// With cleanup, the switches installed before registering fails are removed too.
+func (s *simulator) run(ctx context.Context) error {
+	if s.config.Cleanup {
+		defer s.fleet.remove()
+	}
+
+	if err := s.fleet.register(ctx, s.config.SiteIDs, s.config.Switches); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	s.fleet.start(ctx, &wg)
+
+	for _, siteID := range s.config.SiteIDs {
+		wg.Add(1)
+		go func(siteID string) {
+			defer wg.Done()
+			if s.config.Mode == modePoll {
+				s.fleet.poll(ctx, siteID, s.config.PollPeriod)
+			} else {
+				s.fleet.watch(ctx, siteID)
+			}
+		}(siteID)
+	}
+
+	if s.config.LoadProfile == profileNone {
+		<-ctx.Done()
+		s.logger.Info(append(s.stats.summary(), "message", "Simulator stopped.")...)
+		return nil
+	}
+
+	s.logger.Info("message", fmt.Sprintf("Running %s load at %g state changes per second for %s.", s.config.LoadProfile, s.config.LoadRate, s.config.LoadDuration))
+	s.load.run(ctx)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(s.settleTime()):
+	}
+
+	s.logger.Info(append(s.stats.summary(), "message", "Load run finished.")...)
+
+	return nil
+}
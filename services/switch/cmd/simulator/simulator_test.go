@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSimulatorRun(t *testing.T) {
+	tests := []struct {
+		name            string
+		client          *mockSwitchClient
+		mode            string
+		profile         string
+		cleanup         bool
+		expectedError   string
+		expectedRemoved int
+	}{
+		{
+			name:          "RegisterFail",
+			client:        &mockSwitchClient{InstallOutError: status.Error(codes.PermissionDenied, "denied")},
+			mode:          modeWatch,
+			profile:       profileConstant,
+			expectedError: "cannot install switch 1: rpc error: code = PermissionDenied desc = denied",
+		},
+		{
+			name:            "PartialRegisterFail",
+			client:          &mockSwitchClient{switches: map[string]*proto.Switch{}, ReportOutError: status.Error(codes.Unavailable, "unavailable")},
+			mode:            modeWatch,
+			profile:         profileConstant,
+			cleanup:         true,
+			expectedError:   "cannot report switch switch-1: rpc error: code = Unavailable desc = unavailable",
+			expectedRemoved: 1,
+		},
+		{
+			name:            "NoLoad",
+			client:          newMockSwitchClient(),
+			mode:            modeWatch,
+			profile:         profileNone,
+			cleanup:         true,
+			expectedRemoved: 6,
+		},
+		{
+			name:            "WatchLoad",
+			client:          newMockSwitchClient(),
+			mode:            modeWatch,
+			profile:         profileConstant,
+			cleanup:         true,
+			expectedRemoved: 6,
+		},
+		{
+			name:    "PollLoad",
+			client:  newMockSwitchClient(),
+			mode:    modePoll,
+			profile: profileRamp,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config := newConfig()
+			config.SiteIDs = []string{"site-1", "site-2"}
+			config.Switches = 6
+			config.Mode = tc.mode
+			config.PollPeriod = 10 * time.Millisecond
+			config.Latency = 5 * time.Millisecond
+			config.LatencyJitter = 5 * time.Millisecond
+			config.LoadProfile = tc.profile
+			config.LoadRate = 50
+			config.LoadDuration = 500 * time.Millisecond
+			config.Cleanup = tc.cleanup
+
+			sim := newSimulator(tc.client, log.NewVoidLogger(), config, rand.New(rand.NewSource(1)))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			err := sim.run(ctx)
+			assert.Len(t, tc.client.RemoveInIDs, tc.expectedRemoved)
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			if tc.profile != profileNone {
+				assert.NotZero(t, sim.stats.calls)
+				assert.Empty(t, sim.stats.errors)
+				assert.NotEmpty(t, sim.stats.convergence)
+			}
+		})
+	}
+}
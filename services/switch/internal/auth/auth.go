@@ -0,0 +1,403 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Any matches every method, site or identity in a policy
+	Any = "*"
+
+	// OverrideInterlocks is the permission to change the state of a switch despite its interlocks.
+	// Roles give it like a method.
+	OverrideInterlocks = "OverrideInterlocks"
+
+	// servicePrefix is the prefix of the full names of the methods of the switch service
+	servicePrefix = "/proto.SwitchService/"
+
+	defaultReloadPeriod = 10 * time.Second
+)
+
+var (
+	// ErrPermissionDenied is returned when a call is not allowed by the policy
+	ErrPermissionDenied = status.Error(codes.PermissionDenied, "permission denied")
+)
+
+type (
+	// Role allows a set of methods at a set of sites
+	Role struct {
+		Name    string   `yaml:"name"`
+		Methods []string `yaml:"methods"`
+		Sites   []string `yaml:"sites"`
+	}
+
+	// Binding gives a set of roles to a set of identities.
+	// An identity ending with * matches every identity starting with what comes before it.
+	Binding struct {
+		Identities []string `yaml:"identities"`
+		Roles      []string `yaml:"roles"`
+	}
+
+	// Policy decides which identities can call which methods at which sites
+	Policy struct {
+		Roles    []Role    `yaml:"roles"`
+		Bindings []Binding `yaml:"bindings"`
+	}
+
+	// Grant is what a call is allowed to do and is added to the context of the call
+	Grant struct {
+		Identities []string
+		Method     string
+		// Sites is nil if the call is allowed at every site
+		Sites []string
+
+		policy *Policy
+		logger *log.Logger
+	}
+
+	// Authorizer authorizes calls against a policy file and reloads the file when it changes
+	Authorizer struct {
+		path   string
+		logger *log.Logger
+
+		mu      sync.RWMutex
+		policy  *Policy
+		modTime time.Time
+	}
+
+	contextKey struct{}
+
+	// internalCallerKey is the context key of the name of a call made by the service itself
+	internalCallerKey struct{}
+)
+
+// ParsePolicy parses a policy in YAML or JSON and makes sure every binding refers to known roles
+func ParsePolicy(data []byte) (*Policy, error) {
+	policy := new(Policy)
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]bool)
+	for _, role := range policy.Roles {
+		if role.Name == "" {
+			return nil, errors.New("role name is required")
+		}
+		if roles[role.Name] {
+			return nil, fmt.Errorf("role %s is defined more than once", role.Name)
+		}
+		roles[role.Name] = true
+	}
+
+	for _, binding := range policy.Bindings {
+		for _, name := range binding.Roles {
+			if !roles[name] {
+				return nil, fmt.Errorf("unknown role %s", name)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// LoadPolicy reads and parses a policy file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePolicy(data)
+}
+
+func contains(list []string, val string) bool {
+	for _, v := range list {
+		if v == Any || v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// matchIdentity determines whether one of the identities of a caller matches an identity of a binding
+func matchIdentity(pattern string, identities []string) bool {
+	if strings.HasSuffix(pattern, Any) {
+		prefix := strings.TrimSuffix(pattern, Any)
+		if prefix == "" {
+			return true
+		}
+
+		for _, identity := range identities {
+			if strings.HasPrefix(identity, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, identity := range identities {
+		if identity == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Grant returns what the identities of a caller are allowed to do with a method and nil if the method is not allowed
+func (p *Policy) Grant(identities []string, method string) *Grant {
+	roles := make(map[string]bool)
+	for _, binding := range p.Bindings {
+		for _, pattern := range binding.Identities {
+			if matchIdentity(pattern, identities) {
+				for _, name := range binding.Roles {
+					roles[name] = true
+				}
+				break
+			}
+		}
+	}
+
+	var grant *Grant
+	for _, role := range p.Roles {
+		if !roles[role.Name] || !contains(role.Methods, method) {
+			continue
+		}
+
+		if grant == nil {
+			grant = &Grant{
+				Identities: identities,
+				Method:     method,
+				Sites:      []string{},
+			}
+		}
+
+		if grant.Sites == nil || contains(role.Sites, Any) {
+			grant.Sites = nil
+		} else {
+			grant.Sites = append(grant.Sites, role.Sites...)
+		}
+	}
+
+	return grant
+}
+
+// Identities returns the identities of the client certificate of a call: its URI SANs, including SPIFFE IDs, and its common name
+func Identities(ctx context.Context) []string {
+	identities := []string{}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return identities
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return identities
+	}
+
+	cert := info.State.PeerCertificates[0]
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+
+	return identities
+}
+
+// NewContext returns a context carrying the grant of a call
+func NewContext(ctx context.Context, grant *Grant) context.Context {
+	return context.WithValue(ctx, contextKey{}, grant)
+}
+
+// FromContext returns the grant of a call if the call was authorized
+func FromContext(ctx context.Context) (*Grant, bool) {
+	grant, ok := ctx.Value(contextKey{}).(*Grant)
+	return grant, ok && grant != nil
+}
+
+// WithInternalCaller returns a context for a call made by the service itself, such as by the scheduler, with the name of the caller.
+// Unlike metadata, the name cannot be set by a remote caller.
+func WithInternalCaller(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, internalCallerKey{}, name)
+}
+
+// InternalCaller returns the name of the caller of a call made by the service itself and an empty string for other calls
+func InternalCaller(ctx context.Context) string {
+	name, _ := ctx.Value(internalCallerKey{}).(string)
+	return name
+}
+
+// AllowsSite determines whether a call is allowed at a site.
+// Calls that were not authorized, such as the ones made by the scheduler, are allowed everywhere.
+func AllowsSite(ctx context.Context, siteID string) bool {
+	grant, ok := FromContext(ctx)
+	return !ok || grant.Sites == nil || contains(grant.Sites, siteID)
+}
+
+// Sites returns the sites a call is restricted to and nil if it is allowed at every site
+func Sites(ctx context.Context) []string {
+	if grant, ok := FromContext(ctx); ok {
+		return grant.Sites
+	}
+	return nil
+}
+
+// CheckSite fails with PermissionDenied and writes an audit log entry if a call is not allowed at a site
+func CheckSite(ctx context.Context, siteID string) error {
+	if AllowsSite(ctx, siteID) {
+		return nil
+	}
+
+	grant, _ := FromContext(ctx)
+	audit(grant.logger, grant.Identities, grant.Method, siteID, "site not allowed")
+
+	return ErrPermissionDenied
+}
+
+// CheckPermission fails with PermissionDenied and writes an audit log entry if a call does not have a permission at a site.
+// A permission is given by the roles of the caller like a method, and calls that were not authorized have every permission.
+func CheckPermission(ctx context.Context, permission, siteID string) error {
+	grant, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	if grant.policy != nil {
+		if g := grant.policy.Grant(grant.Identities, permission); g != nil && (g.Sites == nil || contains(g.Sites, siteID)) {
+			return nil
+		}
+	}
+
+	audit(grant.logger, grant.Identities, permission, siteID, "permission not given")
+
+	return ErrPermissionDenied
+}
+
+// audit writes an audit log entry for a denied call
+func audit(logger *log.Logger, identities []string, method, siteID, reason string) {
+	if logger == nil {
+		return
+	}
+
+	logger.Warn(
+		"audit", "permission_denied",
+		"identities", strings.Join(identities, ","),
+		"method", method,
+		"siteId", siteID,
+		"reason", reason,
+		"message", fmt.Sprintf("Permission denied for %s: %s.", method, reason),
+	)
+}
+
+// NewAuthorizer creates a new authorizer and loads its policy file
+func NewAuthorizer(path string, logger *log.Logger) (*Authorizer, error) {
+	a := &Authorizer{
+		path:   path,
+		logger: logger,
+	}
+
+	if _, err := a.Reload(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Reload loads the policy file if it changed since it was last loaded.
+// An invalid file fails and leaves the current policy in place.
+func (a *Authorizer) Reload() (bool, error) {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return false, err
+	}
+
+	a.mu.RLock()
+	unchanged := a.policy != nil && info.ModTime().Equal(a.modTime)
+	a.mu.RUnlock()
+
+	if unchanged {
+		return false, nil
+	}
+
+	policy, err := LoadPolicy(a.path)
+	if err != nil {
+		return false, err
+	}
+
+	a.mu.Lock()
+	a.policy = policy
+	a.modTime = info.ModTime()
+	a.mu.Unlock()
+
+	return true, nil
+}
+
+// Watch reloads the policy file every period until the context is done
+func (a *Authorizer) Watch(ctx context.Context, period time.Duration) {
+	if period <= 0 {
+		period = defaultReloadPeriod
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := a.Reload()
+		if err != nil {
+			a.logger.Error("message", fmt.Sprintf("Failed to reload authorization policy: %s", err))
+		} else if reloaded {
+			a.logger.Info("message", "Authorization policy reloaded.")
+		}
+	}
+}
+
+// Authorize allows or denies a call to a method of the switch service by the identities of its client certificate.
+// Allowed calls get a context carrying their grant and denied calls fail with PermissionDenied.
+// Calls to other services, such as health checks, are always allowed.
+func (a *Authorizer) Authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	if !strings.HasPrefix(fullMethod, servicePrefix) {
+		return ctx, nil
+	}
+
+	method := strings.TrimPrefix(fullMethod, servicePrefix)
+	identities := Identities(ctx)
+
+	a.mu.RLock()
+	policy := a.policy
+	a.mu.RUnlock()
+
+	grant := policy.Grant(identities, method)
+
+	if grant == nil {
+		audit(a.logger, identities, method, "", "method not allowed")
+		return ctx, ErrPermissionDenied
+	}
+
+	grant.policy = policy
+	grant.logger = a.logger
+
+	return NewContext(ctx, grant), nil
+}
@@ -0,0 +1,345 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const testPolicy = `
+roles:
+  - name: viewer
+    methods: [GetSwitch, GetSwitches]
+    sites: ["*"]
+  - name: operator
+    methods: [GetSwitch, SetSwitch]
+    sites: [site-a]
+  - name: night-shift
+    methods: [SetSwitch]
+    sites: [site-b]
+  - name: supervisor
+    methods: [SetSwitch, OverrideInterlocks]
+    sites: [site-a]
+bindings:
+  - identities: [spiffe://example.org/ns/ops/sa/console]
+    roles: [operator, night-shift]
+  - identities: ["spiffe://example.org/ns/monitoring/*", graphql-service]
+    roles: [viewer]
+  - identities: [spiffe://example.org/ns/ops/sa/supervisor]
+    roles: [supervisor]
+`
+
+// contextWithCert creates the context of a call made with a client certificate
+func contextWithCert(commonName string, uris ...string) context.Context {
+	cert := &x509.Certificate{
+		Subject: pkix.Name{CommonName: commonName},
+	}
+
+	for _, uri := range uris {
+		u, _ := url.Parse(uri)
+		cert.URIs = append(cert.URIs, u)
+	}
+
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{cert},
+			},
+		},
+	})
+}
+
+// writePolicy writes a policy file with a modification time
+func writePolicy(t *testing.T, path, policy string, modTime time.Time) {
+	assert.NoError(t, ioutil.WriteFile(path, []byte(policy), 0644))
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		expectedError bool
+	}{
+		{"Valid", testPolicy, false},
+		{"JSON", `{"roles": [{"name": "admin", "methods": ["*"], "sites": ["*"]}], "bindings": [{"identities": ["admin"], "roles": ["admin"]}]}`, false},
+		{"InvalidYAML", "roles: [", true},
+		{"NoRoleName", "roles:\n  - methods: [GetSwitch]", true},
+		{"DuplicateRole", "roles:\n  - name: admin\n  - name: admin", true},
+		{"UnknownRole", "bindings:\n  - identities: [admin]\n    roles: [admin]", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := ParsePolicy([]byte(tc.policy))
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				assert.Nil(t, policy)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, policy)
+			}
+		})
+	}
+}
+
+func TestPolicyGrant(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		identities    []string
+		method        string
+		expectedGrant bool
+		expectedSites []string
+	}{
+		{"NoIdentity", []string{}, "GetSwitch", false, nil},
+		{"UnknownIdentity", []string{"spiffe://example.org/ns/dev/sa/cli"}, "GetSwitch", false, nil},
+		{"MethodNotAllowed", []string{"graphql-service"}, "SetSwitch", false, nil},
+		{"CommonName", []string{"graphql-service"}, "GetSwitches", true, nil},
+		{"Prefix", []string{"spiffe://example.org/ns/monitoring/sa/prometheus"}, "GetSwitch", true, nil},
+		{"OneRole", []string{"spiffe://example.org/ns/ops/sa/console"}, "GetSwitch", true, []string{"site-a"}},
+		{"SitesOfRoles", []string{"spiffe://example.org/ns/ops/sa/console"}, "SetSwitch", true, []string{"site-a", "site-b"}},
+		{"AnySite", []string{"spiffe://example.org/ns/ops/sa/console", "graphql-service"}, "GetSwitch", true, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			grant := policy.Grant(tc.identities, tc.method)
+
+			if !tc.expectedGrant {
+				assert.Nil(t, grant)
+			} else {
+				assert.NotNil(t, grant)
+				assert.Equal(t, tc.method, grant.Method)
+				assert.Equal(t, tc.expectedSites, grant.Sites)
+			}
+		})
+	}
+}
+
+func TestIdentities(t *testing.T) {
+	tests := []struct {
+		name               string
+		ctx                context.Context
+		expectedIdentities []string
+	}{
+		{"NoPeer", context.Background(), []string{}},
+		{"NoTLS", peer.NewContext(context.Background(), &peer.Peer{}), []string{}},
+		{"CommonName", contextWithCert("graphql-service"), []string{"graphql-service"}},
+		{"URIs", contextWithCert("console", "spiffe://example.org/ns/ops/sa/console"), []string{"spiffe://example.org/ns/ops/sa/console", "console"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedIdentities, Identities(tc.ctx))
+		})
+	}
+}
+
+func TestInternalCaller(t *testing.T) {
+	assert.Equal(t, "", InternalCaller(context.Background()))
+	assert.Equal(t, "scheduler", InternalCaller(WithInternalCaller(context.Background(), "scheduler")))
+}
+
+func TestCheckSite(t *testing.T) {
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		siteID        string
+		expectedSites []string
+		expectedCode  codes.Code
+	}{
+		{"NotAuthorized", context.Background(), "site-a", nil, codes.OK},
+		{"AnySite", NewContext(context.Background(), &Grant{}), "site-a", nil, codes.OK},
+		{"Allowed", NewContext(context.Background(), &Grant{Sites: []string{"site-a"}}), "site-a", []string{"site-a"}, codes.OK},
+		{"Denied", NewContext(context.Background(), &Grant{Sites: []string{"site-a"}, logger: log.NewVoidLogger()}), "site-b", []string{"site-a"}, codes.PermissionDenied},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckSite(tc.ctx, tc.siteID)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedCode == codes.OK, AllowsSite(tc.ctx, tc.siteID))
+			assert.Equal(t, tc.expectedSites, Sites(tc.ctx))
+		})
+	}
+}
+
+func TestCheckPermission(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.yaml")
+	writePolicy(t, path, testPolicy, time.Now())
+
+	authorizer, err := NewAuthorizer(path, log.NewVoidLogger())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		siteID       string
+		expectedCode codes.Code
+	}{
+		{"NotAuthorized", context.Background(), "site-a", codes.OK},
+		{"NoPolicy", NewContext(context.Background(), &Grant{logger: log.NewVoidLogger()}), "site-a", codes.PermissionDenied},
+		{"NotGiven", contextWithCert("", "spiffe://example.org/ns/ops/sa/console"), "site-a", codes.PermissionDenied},
+		{"OtherSite", contextWithCert("", "spiffe://example.org/ns/ops/sa/supervisor"), "site-b", codes.PermissionDenied},
+		{"Given", contextWithCert("", "spiffe://example.org/ns/ops/sa/supervisor"), "site-a", codes.OK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := tc.ctx
+			if len(Identities(ctx)) > 0 {
+				ctx, err = authorizer.Authorize(ctx, "/proto.SwitchService/SetSwitch")
+				assert.NoError(t, err)
+			}
+
+			err := CheckPermission(ctx, OverrideInterlocks, tc.siteID)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestNewAuthorizer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	valid := filepath.Join(dir, "valid.yaml")
+	writePolicy(t, valid, testPolicy, time.Now())
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	writePolicy(t, invalid, "roles: [", time.Now())
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedError bool
+	}{
+		{"NoFile", filepath.Join(dir, "missing.yaml"), true},
+		{"InvalidPolicy", invalid, true},
+		{"Success", valid, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			authorizer, err := NewAuthorizer(tc.path, log.NewVoidLogger())
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				assert.Nil(t, authorizer)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, authorizer)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.yaml")
+	writePolicy(t, path, testPolicy, time.Now())
+
+	authorizer, err := NewAuthorizer(path, log.NewVoidLogger())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		method        string
+		expectedCode  codes.Code
+		expectedGrant bool
+	}{
+		{"OtherService", context.Background(), "/grpc.health.v1.Health/Check", codes.OK, false},
+		{"NoCertificate", context.Background(), "/proto.SwitchService/GetSwitch", codes.PermissionDenied, false},
+		{"Denied", contextWithCert("graphql-service"), "/proto.SwitchService/SetSwitch", codes.PermissionDenied, false},
+		{"Allowed", contextWithCert("graphql-service"), "/proto.SwitchService/GetSwitch", codes.OK, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, err := authorizer.Authorize(tc.ctx, tc.method)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			grant, ok := FromContext(ctx)
+			assert.Equal(t, tc.expectedGrant, ok)
+			if ok {
+				assert.Equal(t, []string{"graphql-service"}, grant.Identities)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.yaml")
+	modTime := time.Now().Add(-time.Hour)
+	writePolicy(t, path, testPolicy, modTime)
+
+	authorizer, err := NewAuthorizer(path, log.NewVoidLogger())
+	assert.NoError(t, err)
+
+	ctx := contextWithCert("graphql-service")
+	_, err = authorizer.Authorize(ctx, "/proto.SwitchService/SetSwitch")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Unchanged
+	reloaded, err := authorizer.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// Invalid policies are not loaded
+	modTime = modTime.Add(time.Minute)
+	writePolicy(t, path, "roles: [", modTime)
+	reloaded, err = authorizer.Reload()
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+
+	// Changed
+	modTime = modTime.Add(time.Minute)
+	writePolicy(t, path, `
+roles:
+  - name: admin
+    methods: ["*"]
+    sites: ["*"]
+bindings:
+  - identities: [graphql-service]
+    roles: [admin]
+`, modTime)
+
+	watchCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	authorizer.Watch(watchCtx, 10*time.Millisecond)
+
+	_, err = authorizer.Authorize(ctx, "/proto.SwitchService/SetSwitch")
+	assert.NoError(t, err)
+}
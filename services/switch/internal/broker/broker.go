@@ -0,0 +1,209 @@
+package broker
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+)
+
+const (
+	defaultHistorySize = 1000
+	defaultBufferSize  = 100
+
+	// epochShift is the position of the epoch of a broker in its revisions
+	epochShift = 32
+)
+
+var (
+	// ErrUnknownRevision is returned when the events after a revision are not kept (too old, or from a previous broker or another replica)
+	ErrUnknownRevision = errors.New("cannot resume from revision")
+
+	// ErrSlowSubscriber is returned when a subscription is dropped for not keeping up with events
+	ErrSlowSubscriber = errors.New("subscriber is too slow")
+)
+
+type (
+	// Event is a change to a switch
+	Event struct {
+		TenantID string
+		*proto.SwitchEvent
+	}
+
+	// Filter selects the events delivered to a subscription
+	Filter func(Event) bool
+
+	// Subscription receives the events published after it was created
+	Subscription struct {
+		filter Filter
+		events chan Event
+		done   chan struct{}
+		once   sync.Once
+		err    error
+	}
+
+	// Broker fans out switch events to subscriptions in-process.
+	// Every event gets a revision which increases monotonically for the lifetime of the broker.
+	// The upper bits of the revisions are a random epoch, so the revisions of a previous broker or of another replica are told apart.
+	Broker struct {
+		mutex       sync.Mutex
+		epoch       uint64
+		revision    uint64
+		history     []Event
+		historySize int
+		bufferSize  int
+		subs        map[*Subscription]struct{}
+	}
+)
+
+// New creates a new broker.
+// historySize is the number of past events kept for resuming and bufferSize is the number of events a subscription can fall behind.
+func New(historySize, bufferSize int) *Broker {
+	if historySize <= 0 {
+		historySize = defaultHistorySize
+	}
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	epoch := newEpoch()
+
+	return &Broker{
+		epoch:       epoch,
+		revision:    epoch,
+		historySize: historySize,
+		bufferSize:  bufferSize,
+		subs:        make(map[*Subscription]struct{}),
+	}
+}
+
+// newEpoch returns a random non-zero epoch in the upper bits of a revision
+func newEpoch() uint64 {
+	var buf [4]byte
+	_, _ = rand.Read(buf[:])
+
+	return uint64(binary.BigEndian.Uint32(buf[:])|1) << epochShift
+}
+
+// Events returns the channel of events for the subscription
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Done is closed when the subscription is dropped by the broker
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the reason the subscription was dropped
+func (s *Subscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+func (s *Subscription) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+// Revision returns the revision of the last published event
+func (b *Broker) Revision() uint64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.revision
+}
+
+// Publish assigns the next revision to an event and delivers it to the matching subscriptions
+func (b *Broker) Publish(tenantID string, typ proto.SwitchEvent_Type, sw *proto.Switch) Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.revision++
+	event := Event{
+		TenantID: tenantID,
+		SwitchEvent: &proto.SwitchEvent{
+			Type:     typ,
+			Revision: b.revision,
+			Switch:   sw,
+		},
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for sub := range b.subs {
+		if !sub.filter(event) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			// The subscriber cannot keep up, so it is dropped instead of blocking the publisher
+			delete(b.subs, sub)
+			sub.close(ErrSlowSubscriber)
+		}
+	}
+
+	return event
+}
+
+// Subscribe creates a subscription for the events matching a filter.
+// It returns the current revision and, if fromRevision is not zero, the matching events published after fromRevision.
+func (b *Broker) Subscribe(filter Filter, fromRevision uint64) (*Subscription, uint64, []Event, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var missed []Event
+	if fromRevision > b.revision {
+		return nil, 0, nil, ErrUnknownRevision
+	}
+
+	// A revision of another epoch is from a previous broker or another replica, so the events after it are unknown
+	if fromRevision > 0 && fromRevision>>epochShift != b.epoch>>epochShift {
+		return nil, 0, nil, ErrUnknownRevision
+	}
+
+	if fromRevision > 0 && fromRevision < b.revision {
+		oldest := b.revision - uint64(len(b.history)) + 1
+		if fromRevision+1 < oldest {
+			return nil, 0, nil, ErrUnknownRevision
+		}
+
+		for _, event := range b.history {
+			if event.Revision > fromRevision && filter(event) {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	sub := &Subscription{
+		filter: filter,
+		events: make(chan Event, b.bufferSize),
+		done:   make(chan struct{}),
+	}
+
+	b.subs[sub] = struct{}{}
+
+	return sub, b.revision, missed, nil
+}
+
+// Unsubscribe removes a subscription from the broker
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.subs, sub)
+	sub.close(nil)
+}
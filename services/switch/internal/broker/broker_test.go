@@ -0,0 +1,139 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func all(Event) bool {
+	return true
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name                string
+		historySize         int
+		bufferSize          int
+		expectedHistorySize int
+		expectedBufferSize  int
+	}{
+		{"Defaults", 0, 0, defaultHistorySize, defaultBufferSize},
+		{"Custom", 10, 5, 10, 5},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := New(tc.historySize, tc.bufferSize)
+
+			assert.Equal(t, tc.expectedHistorySize, b.historySize)
+			assert.Equal(t, tc.expectedBufferSize, b.bufferSize)
+			assert.NotZero(t, b.epoch)
+			assert.Equal(t, b.epoch, b.Revision())
+		})
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	b := New(10, 10)
+
+	siteFilter := func(e Event) bool {
+		return e.TenantID == "tttt-tttt" && e.Switch.SiteId == "1111-1111"
+	}
+
+	sub, rev, missed, err := b.Subscribe(siteFilter, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, b.epoch, rev)
+	assert.Empty(t, missed)
+
+	b.Publish("tttt-tttt", proto.SwitchEvent_INSTALLED, &proto.Switch{Id: "aaaa", SiteId: "1111-1111"})
+	b.Publish("uuuu-uuuu", proto.SwitchEvent_INSTALLED, &proto.Switch{Id: "bbbb", SiteId: "1111-1111"})
+	b.Publish("tttt-tttt", proto.SwitchEvent_INSTALLED, &proto.Switch{Id: "cccc", SiteId: "2222-2222"})
+	b.Publish("tttt-tttt", proto.SwitchEvent_STATE_CHANGED, &proto.Switch{Id: "aaaa", SiteId: "1111-1111", State: "ON"})
+
+	assert.Equal(t, b.epoch+4, b.Revision())
+
+	e := <-sub.Events()
+	assert.Equal(t, b.epoch+1, e.Revision)
+	assert.Equal(t, proto.SwitchEvent_INSTALLED, e.Type)
+	assert.Equal(t, "aaaa", e.Switch.Id)
+
+	e = <-sub.Events()
+	assert.Equal(t, b.epoch+4, e.Revision)
+	assert.Equal(t, proto.SwitchEvent_STATE_CHANGED, e.Type)
+	assert.Equal(t, "ON", e.Switch.State)
+
+	assert.Empty(t, sub.Events())
+
+	b.Unsubscribe(sub)
+	assert.NoError(t, sub.Err())
+	b.Publish("tttt-tttt", proto.SwitchEvent_REMOVED, &proto.Switch{Id: "aaaa", SiteId: "1111-1111"})
+	assert.Empty(t, sub.Events())
+}
+
+func TestSubscribeResume(t *testing.T) {
+	b := New(3, 10)
+	for i := 0; i < 5; i++ {
+		b.Publish("tttt-tttt", proto.SwitchEvent_STATE_CHANGED, &proto.Switch{Id: "aaaa"})
+	}
+
+	// A fresh broker, as after a restart or on another replica, has published as many events
+	fresh := New(3, 10)
+	for i := 0; i < 5; i++ {
+		fresh.Publish("tttt-tttt", proto.SwitchEvent_STATE_CHANGED, &proto.Switch{Id: "bbbb"})
+	}
+
+	tests := []struct {
+		name              string
+		fromRevision      uint64
+		expectedError     error
+		expectedRevisions []uint64
+	}{
+		{"NoResume", 0, nil, nil},
+		{"UpToDate", b.epoch + 5, nil, nil},
+		{"Resume", b.epoch + 3, nil, []uint64{4, 5}},
+		{"OldestKept", b.epoch + 2, nil, []uint64{3, 4, 5}},
+		{"TooOld", b.epoch + 1, ErrUnknownRevision, nil},
+		{"FromFuture", b.epoch + 9, ErrUnknownRevision, nil},
+		{"WithoutEpoch", 3, ErrUnknownRevision, nil},
+		{"OtherBroker", fresh.epoch + 3, ErrUnknownRevision, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sub, rev, missed, err := b.Subscribe(all, tc.fromRevision)
+			assert.Equal(t, tc.expectedError, err)
+
+			if tc.expectedError == nil {
+				defer b.Unsubscribe(sub)
+				assert.Equal(t, b.epoch+5, rev)
+
+				var revisions []uint64
+				for _, e := range missed {
+					revisions = append(revisions, e.Revision-b.epoch)
+				}
+				assert.Equal(t, tc.expectedRevisions, revisions)
+			}
+		})
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	b := New(10, 2)
+
+	slow, _, _, err := b.Subscribe(all, 0)
+	assert.NoError(t, err)
+
+	b.Publish("tttt-tttt", proto.SwitchEvent_INSTALLED, &proto.Switch{Id: "aaaa"})
+	b.Publish("tttt-tttt", proto.SwitchEvent_INSTALLED, &proto.Switch{Id: "bbbb"})
+	assert.NoError(t, slow.Err())
+
+	// The third event does not fit in the buffer
+	b.Publish("tttt-tttt", proto.SwitchEvent_INSTALLED, &proto.Switch{Id: "cccc"})
+
+	<-slow.Done()
+	assert.Equal(t, ErrSlowSubscriber, slow.Err())
+	assert.Len(t, slow.Events(), 2)
+	assert.Empty(t, b.subs)
+}
@@ -4,16 +4,20 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/moorara/microservices-demo/pkg/tenant"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Metrics includes all metrics
 type Metrics struct {
-	Registry      *prometheus.Registry
-	ReqCounter    *prometheus.CounterVec
-	OpLatencyHist *prometheus.HistogramVec
-	OpLatencySumm *prometheus.SummaryVec
+	Tenants        *tenant.Labels
+	Registry       *prometheus.Registry
+	ReqCounter     *prometheus.CounterVec
+	ReqLatencyHist *prometheus.HistogramVec
+	OpLatencyHist  *prometheus.HistogramVec
+	OpLatencySumm  *prometheus.SummaryVec
+	CertExpiry     *prometheus.GaugeVec
 }
 
 // Mock creates a new metrics for testing purposes
@@ -24,33 +28,51 @@ func Mock() *Metrics {
 		prometheus.CounterOpts{
 			Name: "grpc_requests_total",
 		},
-		[]string{"call", "success"},
+		[]string{"method", "code"},
+	)
+
+	ReqLatencyHist := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "grpc_request_duration_seconds",
+		},
+		[]string{"method", "code"},
 	)
 
 	OpLatencyHist := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name: "operations_latency_seconds",
 		},
-		[]string{"op", "success"},
+		[]string{"op", "success", "tenant"},
 	)
 
 	OpLatencySumm := prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
 			Name: "operations_latency_quantiles_seconds",
 		},
-		[]string{"op", "success"},
+		[]string{"op", "success", "tenant"},
+	)
+
+	CertExpiry := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "certificate_expiry_timestamp_seconds",
+		},
+		[]string{"file"},
 	)
 
 	return &Metrics{
-		Registry:      registry,
-		ReqCounter:    ReqCounter,
-		OpLatencyHist: OpLatencyHist,
-		OpLatencySumm: OpLatencySumm,
+		Tenants:        tenant.NewLabels(),
+		Registry:       registry,
+		ReqCounter:     ReqCounter,
+		ReqLatencyHist: ReqLatencyHist,
+		OpLatencyHist:  OpLatencyHist,
+		OpLatencySumm:  OpLatencySumm,
+		CertExpiry:     CertExpiry,
 	}
 }
 
 // New creates a new metrics
-func New(service string) *Metrics {
+// tenants are the tenants labeled by their id in the operation metrics.
+func New(service string, tenants ...string) *Metrics {
 	service = strings.Replace(service, "-", "_", -1)
 
 	registry := prometheus.NewRegistry()
@@ -66,7 +88,18 @@ func New(service string) *Metrics {
 			Name:      "grpc_requests_total",
 			Help:      "total number of grpc requests",
 		},
-		[]string{"call", "success"},
+		[]string{"method", "code"},
+	)
+
+	// ReqLatencyHist is a histogram tracking the response times of grpc requests
+	ReqLatencyHist := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: service,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "latency of grpc requests",
+			Buckets:   []float64{0.01, 0.10, 0.50, 1.00},
+		},
+		[]string{"method", "code"},
 	)
 
 	// OpLatencyHist is a histogram tracking the response times of internal operations
@@ -77,7 +110,7 @@ func New(service string) *Metrics {
 			Help:      "latency of internal operations",
 			Buckets:   []float64{0.01, 0.10, 0.50, 1.00},
 		},
-		[]string{"op", "success"},
+		[]string{"op", "success", "tenant"},
 	)
 
 	// OpLatencySumm is a summary tracking the response times of internal operations
@@ -93,19 +126,36 @@ func New(service string) *Metrics {
 				0.99: 0.001,
 			},
 		},
-		[]string{"op", "success"},
+		[]string{"op", "success", "tenant"},
+	)
+
+	// CertExpiry is a gauge tracking when the certificates in use expire
+	CertExpiry := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: service,
+			Name:      "certificate_expiry_timestamp_seconds",
+			Help:      "expiry time of certificates in seconds since epoch",
+		},
+		[]string{"file"},
 	)
 
 	registry.MustRegister(ReqCounter)
+	registry.MustRegister(ReqLatencyHist)
 	registry.MustRegister(OpLatencySumm)
 	registry.MustRegister(OpLatencyHist)
-
-	return &Metrics{
-		Registry:      registry,
-		ReqCounter:    ReqCounter,
-		OpLatencyHist: OpLatencyHist,
-		OpLatencySumm: OpLatencySumm,
+	registry.MustRegister(CertExpiry)
+
+	m := &Metrics{
+		Tenants:        tenant.NewLabels(tenants...),
+		Registry:       registry,
+		ReqCounter:     ReqCounter,
+		ReqLatencyHist: ReqLatencyHist,
+		OpLatencyHist:  OpLatencyHist,
+		OpLatencySumm:  OpLatencySumm,
+		CertExpiry:     CertExpiry,
 	}
+
+	return m
 }
 
 // Handler returns http handler for metrics endpoint
@@ -9,10 +9,13 @@ import (
 func TestMock(t *testing.T) {
 	metrics := Mock()
 
+	assert.NotNil(t, metrics.Tenants)
 	assert.NotNil(t, metrics.Registry)
 	assert.NotNil(t, metrics.ReqCounter)
+	assert.NotNil(t, metrics.ReqLatencyHist)
 	assert.NotNil(t, metrics.OpLatencyHist)
 	assert.NotNil(t, metrics.OpLatencySumm)
+	assert.NotNil(t, metrics.CertExpiry)
 }
 
 func TestNew(t *testing.T) {
@@ -27,10 +30,14 @@ func TestNew(t *testing.T) {
 		metrics := New(tc.service)
 		handler := metrics.Handler()
 
+		assert.NotNil(t, metrics.Tenants)
 		assert.NotNil(t, metrics.Registry)
 		assert.NotNil(t, metrics.ReqCounter)
+		assert.NotNil(t, metrics.ReqLatencyHist)
+		assert.NotNil(t, metrics.ReqLatencyHist)
 		assert.NotNil(t, metrics.OpLatencyHist)
 		assert.NotNil(t, metrics.OpLatencySumm)
+		assert.NotNil(t, metrics.CertExpiry)
 		assert.NotNil(t, handler)
 	}
 }
@@ -2,13 +2,134 @@ package model
 
 type (
 	// Switch is the Arango model for proto.Switch
+	// State is the desired state and ReportedState the state the device is actually in.
 	Switch struct {
-		ID     string   `json:"_id"`
-		Key    string   `json:"_key"`
-		Rev    string   `json:"_rev"`
-		SiteID string   `json:"siteId,omitempty"`
-		Name   string   `json:"name,omitempty"`
-		State  string   `json:"state,omitempty"`
-		States []string `json:"states,omitempty"`
+		ID       string   `json:"_id"`
+		Key      string   `json:"_key"`
+		Rev      string   `json:"_rev"`
+		TenantID string   `json:"tenantId,omitempty"`
+		SiteID   string   `json:"siteId,omitempty"`
+		Name     string   `json:"name,omitempty"`
+		State    string   `json:"state,omitempty"`
+		States   []string `json:"states,omitempty"`
+		// Transitions are the allowed state changes and any change is allowed if there is none
+		Transitions []Transition `json:"transitions,omitempty"`
+		// ReportedState is the state last reported by the device agent
+		ReportedState string `json:"reportedState,omitempty"`
+		ReportedAt    int64  `json:"reportedAt,omitempty"`
+		// Pending is set while the reported state differs from the desired state, which has been the case since PendingSince
+		Pending      bool  `json:"pending,omitempty"`
+		PendingSince int64 `json:"pendingSince,omitempty"`
+		OutOfSync    bool  `json:"outOfSync,omitempty"`
+		// Interlocks block changes of state on sensor readings
+		Interlocks []Interlock `json:"interlocks,omitempty"`
+	}
+
+	// Transition is the Arango model for proto.Transition
+	Transition struct {
+		From string   `json:"from"`
+		To   []string `json:"to"`
+	}
+
+	// Interlock is the Arango model for proto.Interlock
+	// Operator and Limit are the names of the values of their proto enums.
+	Interlock struct {
+		Name     string   `json:"name"`
+		States   []string `json:"states,omitempty"`
+		SensorID string   `json:"sensorId"`
+		Operator string   `json:"operator"`
+		Limit    string   `json:"limit"`
+		Value    float64  `json:"value,omitempty"`
+	}
+
+	// SwitchStateChange is the Arango model for proto.SwitchStateChange
+	SwitchStateChange struct {
+		ID            string `json:"_id,omitempty"`
+		Key           string `json:"_key,omitempty"`
+		Rev           string `json:"_rev,omitempty"`
+		TenantID      string `json:"tenantId,omitempty"`
+		SwitchID      string `json:"switchId,omitempty"`
+		PreviousState string `json:"previousState,omitempty"`
+		State         string `json:"state,omitempty"`
+		Time          int64  `json:"time"`
+		Caller        string `json:"caller,omitempty"`
+		// ClaimedCaller is the caller-id metadata of the change, which is not authenticated
+		ClaimedCaller string `json:"claimedCaller,omitempty"`
+		Reason        string `json:"reason,omitempty"`
+		// OverriddenInterlocks are the names of the interlocks that blocked the change and were overridden
+		OverriddenInterlocks []string `json:"overriddenInterlocks,omitempty"`
+	}
+
+	// OutboxEvent is a switch event waiting in the outbox to be published to NATS
+	// Data is the JSON event.Message and ClaimedUntil is when the replica publishing the event gives it up.
+	OutboxEvent struct {
+		ID           string `json:"_id,omitempty"`
+		Key          string `json:"_key,omitempty"`
+		Rev          string `json:"_rev,omitempty"`
+		Subject      string `json:"subject"`
+		Data         string `json:"data"`
+		Time         int64  `json:"time"`
+		ClaimedUntil int64  `json:"claimedUntil"`
+	}
+
+	// Schedule is the Arango model for proto.Schedule
+	Schedule struct {
+		ID       string       `json:"_id,omitempty"`
+		Key      string       `json:"_key,omitempty"`
+		Rev      string       `json:"_rev,omitempty"`
+		TenantID string       `json:"tenantId,omitempty"`
+		SwitchID string       `json:"switchId,omitempty"`
+		SiteID   string       `json:"siteId,omitempty"`
+		State    string       `json:"state,omitempty"`
+		Cron     string       `json:"cron,omitempty"`
+		RunAt    int64        `json:"runAt,omitempty"`
+		TimeZone string       `json:"timeZone,omitempty"`
+		Enabled  bool         `json:"enabled"`
+		NextRun  int64        `json:"nextRun"`
+		LastRun  *ScheduleRun `json:"lastRun,omitempty"`
+	}
+
+	// ScheduleRun is the Arango model for proto.ScheduleRun
+	ScheduleRun struct {
+		DueTime  int64  `json:"dueTime"`
+		ExecTime int64  `json:"execTime,omitempty"`
+		Success  bool   `json:"success"`
+		Error    string `json:"error,omitempty"`
+		Missed   int    `json:"missed,omitempty"`
+	}
+
+	// ScheduleExecution is an execution of a schedule in the execution history of schedules.
+	// Time is when the execution was recorded and Outcome is one of succeeded, failed and missed.
+	ScheduleExecution struct {
+		Key        string `json:"_key,omitempty"`
+		TenantID   string `json:"tenantId"`
+		ScheduleID string `json:"scheduleId"`
+		SwitchID   string `json:"switchId"`
+		State      string `json:"state"`
+		Time       int64  `json:"time"`
+		Outcome    string `json:"outcome"`
+		ScheduleRun
+	}
+
+	// Group is the Arango model for proto.Group
+	Group struct {
+		ID        string   `json:"_id,omitempty"`
+		Key       string   `json:"_key,omitempty"`
+		Rev       string   `json:"_rev,omitempty"`
+		TenantID  string   `json:"tenantId,omitempty"`
+		SiteID    string   `json:"siteId,omitempty"`
+		Name      string   `json:"name,omitempty"`
+		SwitchIDs []string `json:"switchIds"`
+	}
+
+	// Scene is the Arango model for proto.Scene
+	Scene struct {
+		ID       string            `json:"_id,omitempty"`
+		Key      string            `json:"_key,omitempty"`
+		Rev      string            `json:"_rev,omitempty"`
+		TenantID string            `json:"tenantId,omitempty"`
+		SiteID   string            `json:"siteId,omitempty"`
+		Name     string            `json:"name,omitempty"`
+		States   map[string]string `json:"states"`
 	}
 )
@@ -0,0 +1,42 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	natsMaxReconnect  = 10
+	natsReconnectWait = 2 * time.Second
+)
+
+type (
+	// NATSConnection is the connection to NATS cluster
+	NATSConnection interface {
+		Close()
+		Flush() error
+		IsClosed() bool
+		Publish(subject string, data []byte) error
+	}
+)
+
+// NewNATSConnection creates a new connection to a NATS cluster
+func NewNATSConnection(servers []string, name, user, password string) (NATSConnection, error) {
+	opts := nats.Options{
+		Servers:        servers,
+		Name:           name,
+		User:           user,
+		Password:       password,
+		AllowReconnect: true,
+		MaxReconnect:   natsMaxReconnect,
+		ReconnectWait:  natsReconnectWait,
+	}
+
+	conn, err := opts.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,44 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNATSConnection(t *testing.T) {
+	tests := []struct {
+		name           string
+		servers        []string
+		clientName     string
+		user, password string
+	}{
+		{
+			"NoServer",
+			[]string{},
+			"",
+			"", "",
+		},
+		{
+			"WithName",
+			[]string{"localhost:14222"},
+			"switch-service",
+			"", "",
+		},
+		{
+			"WithAuth",
+			[]string{"localhost:14222"},
+			"switch-service",
+			"client", "pass",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			nc, err := NewNATSConnection(tc.servers, tc.clientName, tc.user, tc.password)
+
+			assert.Error(t, err)
+			assert.Nil(t, nc)
+		})
+	}
+}
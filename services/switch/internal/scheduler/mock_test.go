@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+// mockCursor is a mock implementation of arango.Cursor returning a list of documents
+type mockCursor struct {
+	arango.Cursor
+
+	Docs        []interface{}
+	CloseCalled bool
+}
+
+func (m *mockCursor) HasMore() bool {
+	return len(m.Docs) > 0
+}
+
+func (m *mockCursor) ReadDocument(ctx context.Context, doc interface{}) (arango.DocumentMeta, error) {
+	data, _ := json.Marshal(m.Docs[0])
+	m.Docs = m.Docs[1:]
+	return arango.DocumentMeta{}, json.Unmarshal(data, doc)
+}
+
+func (m *mockCursor) Close() error {
+	m.CloseCalled = true
+	return nil
+}
+
+// mockResult is the result of a query
+type mockResult struct {
+	Cursor arango.Cursor
+	Error  error
+}
+
+// mockArango is a mock implementation of Arango answering each query with a fixed result
+type mockArango struct {
+	QueryInVars  map[string]map[string]interface{}
+	QueryResults map[string]mockResult
+}
+
+func (m *mockArango) Query(ctx context.Context, query string, vars map[string]interface{}) (arango.Cursor, error) {
+	if m.QueryInVars == nil {
+		m.QueryInVars = map[string]map[string]interface{}{}
+	}
+	m.QueryInVars[query] = vars
+
+	result, ok := m.QueryResults[query]
+	if !ok {
+		return &mockCursor{}, nil
+	}
+	return result.Cursor, result.Error
+}
+
+// mockSwitchService is a mock implementation of proto.SwitchServiceServer
+type mockSwitchService struct {
+	proto.SwitchServiceServer
+
+	SetSwitchCalled    bool
+	SetSwitchInContext context.Context
+	SetSwitchInReq     *proto.SetSwitchRequest
+	SetSwitchOutError  error
+}
+
+func (m *mockSwitchService) SetSwitch(ctx context.Context, req *proto.SetSwitchRequest) (*proto.SetSwitchResponse, error) {
+	m.SetSwitchCalled = true
+	m.SetSwitchInContext = ctx
+	m.SetSwitchInReq = req
+	return &proto.SetSwitchResponse{}, m.SetSwitchOutError
+}
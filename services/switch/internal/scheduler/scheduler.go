@@ -0,0 +1,396 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/moorara/microservices-demo/services/switch/internal/auth"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/robfig/cron/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+const (
+	// SchedulesCollection is the Arango collection schedules are stored in
+	SchedulesCollection = "schedules"
+
+	// LeasesCollection is the Arango collection the scheduler leader lease is stored in
+	LeasesCollection = "scheduler_leases"
+
+	// ExecutionsCollection is the Arango collection the execution history of schedules is stored in
+	ExecutionsCollection = "schedule_executions"
+
+	// OutcomeSucceeded, OutcomeFailed and OutcomeMissed are the outcomes of the executions of schedules
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	OutcomeMissed    = "missed"
+
+	// Caller identifies the scheduler in the switch history
+	Caller = "scheduler"
+
+	defaultInterval    = 10 * time.Second
+	defaultMissedAfter = time.Minute
+	maxMissedRuns      = 10000
+	dueBatchSize       = 100
+	leaseKey           = "leader"
+
+	queryAcquireLease = `UPSERT { _key: @key }
+		INSERT { _key: @key, owner: @owner, expiresAt: @expiresAt }
+		UPDATE OLD.owner == @owner || OLD.expiresAt < @now ? { owner: @owner, expiresAt: @expiresAt } : {}
+		IN scheduler_leases RETURN NEW.owner`
+	queryDueSchedules = `FOR sc IN schedules FILTER sc.enabled == true AND sc.nextRun > 0 AND sc.nextRun <= @now SORT sc.nextRun LIMIT @count RETURN sc`
+	queryClaimRun     = `UPDATE { _key: @key, _rev: @rev } WITH { enabled: @enabled, nextRun: @nextRun } IN schedules OPTIONS { ignoreRevs: false }`
+	queryInsertExec   = `INSERT @execution INTO schedule_executions`
+	queryUpdateRun    = `FOR sc IN schedules FILTER sc._key == @key UPDATE sc WITH { enabled: sc.enabled && @enabled, lastRun: @lastRun } IN schedules`
+)
+
+// CatchUp decides what happens to runs missed while no scheduler was running
+type CatchUp string
+
+const (
+	// CatchUpOnce executes the latest missed run once
+	CatchUpOnce CatchUp = "once"
+
+	// CatchUpSkip skips missed runs and waits for the next one
+	CatchUpSkip CatchUp = "skip"
+)
+
+var (
+	// ErrNoSchedule is returned when a schedule has neither a cron expression nor a run time
+	ErrNoSchedule = errors.New("either a cron expression or a run time is required")
+
+	parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+)
+
+type (
+	// Arango selects the functions used from arango by the scheduler
+	Arango interface {
+		Query(ctx context.Context, query string, vars map[string]interface{}) (arango.Cursor, error)
+	}
+
+	// Config configures a scheduler
+	Config struct {
+		// Interval is how often due schedules are looked up
+		Interval time.Duration
+		// MissedAfter is how late a run can be before it is considered missed
+		MissedAfter time.Duration
+		// CatchUp is the policy for missed runs
+		CatchUp CatchUp
+	}
+
+	// Scheduler executes due schedules through the switch service.
+	// Only the replica holding the leader lease executes schedules, so a schedule runs once across replicas.
+	Scheduler struct {
+		arango      Arango
+		switches    proto.SwitchServiceServer
+		logger      *log.Logger
+		owner       string
+		interval    time.Duration
+		missedAfter time.Duration
+		catchUp     CatchUp
+		now         func() time.Time
+	}
+)
+
+// ParseCatchUp parses a catch-up policy
+func ParseCatchUp(policy string) (CatchUp, error) {
+	switch CatchUp(policy) {
+	case CatchUpOnce, CatchUpSkip:
+		return CatchUp(policy), nil
+	default:
+		return "", fmt.Errorf("invalid catch-up policy %q", policy)
+	}
+}
+
+// NextRun returns the first run of a schedule after a time or the zero time if there is none
+func NextRun(sc *model.Schedule, after time.Time) (time.Time, error) {
+	if sc.Cron == "" {
+		if sc.RunAt == 0 {
+			return time.Time{}, ErrNoSchedule
+		}
+
+		runAt := time.Unix(0, sc.RunAt)
+		if runAt.After(after) {
+			return runAt, nil
+		}
+		return time.Time{}, nil
+	}
+
+	loc := time.UTC
+	if sc.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(sc.TimeZone); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	schedule, err := parser.Parse(sc.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return schedule.Next(after.In(loc)), nil
+}
+
+// New creates a new scheduler
+func New(arango Arango, switches proto.SwitchServiceServer, logger *log.Logger, config Config) *Scheduler {
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+	if config.MissedAfter <= 0 {
+		config.MissedAfter = defaultMissedAfter
+	}
+	if config.CatchUp == "" {
+		config.CatchUp = CatchUpOnce
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &Scheduler{
+		arango:      arango,
+		switches:    switches,
+		logger:      logger,
+		owner:       fmt.Sprintf("%s-%s", hostname, uuid.New().String()),
+		interval:    config.Interval,
+		missedAfter: config.MissedAfter,
+		catchUp:     config.CatchUp,
+		now:         time.Now,
+	}
+}
+
+// Run executes due schedules every interval until the context is done
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.tick(ctx); err != nil {
+			s.logger.Error("message", fmt.Sprintf("Scheduler failed: %s", err))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// tick executes the due schedules if this replica is the leader
+func (s *Scheduler) tick(ctx context.Context) error {
+	leader, err := s.acquireLease(ctx)
+	if err != nil || !leader {
+		return err
+	}
+
+	schedules, err := s.dueSchedules(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, sc := range schedules {
+		if err := s.execute(ctx, sc); err != nil {
+			s.logger.Error("message", fmt.Sprintf("Failed to run schedule %s: %s", sc.Key, err))
+		}
+	}
+
+	return nil
+}
+
+// acquireLease takes or renews the leader lease, which expires if it is not renewed for a few intervals
+func (s *Scheduler) acquireLease(ctx context.Context) (bool, error) {
+	now := s.now()
+	vars := map[string]interface{}{
+		"key":       leaseKey,
+		"owner":     s.owner,
+		"now":       now.UnixNano(),
+		"expiresAt": now.Add(3 * s.interval).UnixNano(),
+	}
+
+	cursor, err := s.arango.Query(ctx, queryAcquireLease, vars)
+	if err != nil {
+		// Another replica took the lease at the same time
+		if arango.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer cursor.Close()
+
+	if !cursor.HasMore() {
+		return false, nil
+	}
+
+	var owner string
+	if _, err = cursor.ReadDocument(ctx, &owner); err != nil {
+		return false, err
+	}
+
+	return owner == s.owner, nil
+}
+
+func (s *Scheduler) dueSchedules(ctx context.Context) ([]*model.Schedule, error) {
+	vars := map[string]interface{}{
+		"now":   s.now().UnixNano(),
+		"count": dueBatchSize,
+	}
+
+	cursor, err := s.arango.Query(ctx, queryDueSchedules, vars)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	schedules := []*model.Schedule{}
+	for cursor.HasMore() {
+		sc := &model.Schedule{}
+		if _, err = cursor.ReadDocument(ctx, sc); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, sc)
+	}
+
+	return schedules, nil
+}
+
+// execute runs a due schedule according to the catch-up policy and records the outcome.
+// The next run is claimed before the switch is changed, so a run is executed at most once
+// even if its outcome cannot be recorded or the schedule changed since it was read.
+func (s *Scheduler) execute(ctx context.Context, sc *model.Schedule) error {
+	now := s.now()
+
+	// Find the latest due run, counting the runs missed before it
+	due := time.Unix(0, sc.NextRun)
+	missed := 0
+	for missed < maxMissedRuns {
+		next, err := NextRun(sc, due)
+		if err != nil || next.IsZero() || next.After(now) {
+			break
+		}
+		due = next
+		missed++
+	}
+
+	enabled := sc.Enabled
+	var nextRun int64
+	if next, err := NextRun(sc, now); err == nil && !next.IsZero() {
+		nextRun = next.UnixNano()
+	} else {
+		// One-off schedules are done after their run
+		enabled = false
+	}
+
+	claimed, err := s.claimRun(ctx, sc, enabled, nextRun)
+	if err != nil || !claimed {
+		return err
+	}
+
+	run := &model.ScheduleRun{
+		DueTime: due.UnixNano(),
+		Missed:  missed,
+	}
+
+	outcome := OutcomeSucceeded
+	if now.Sub(due) > s.missedAfter && s.catchUp == CatchUpSkip {
+		run.Missed++
+		run.Error = "missed"
+		outcome = OutcomeMissed
+	} else {
+		run.ExecTime = now.UnixNano()
+		err := s.setSwitch(ctx, sc)
+		run.Success = err == nil
+		if err != nil {
+			run.Error = err.Error()
+			outcome = OutcomeFailed
+			// The switch is gone, so the schedule can never succeed
+			if status.Code(err) == codes.NotFound {
+				enabled = false
+			}
+		}
+	}
+
+	return s.recordRun(ctx, sc, run, outcome, enabled)
+}
+
+// claimRun advances a schedule to its next run if the schedule has not changed since it was read.
+// It reports false if the schedule changed or was deleted, in which case the due run is not executed.
+func (s *Scheduler) claimRun(ctx context.Context, sc *model.Schedule, enabled bool, nextRun int64) (bool, error) {
+	vars := map[string]interface{}{
+		"key":     sc.Key,
+		"rev":     sc.Rev,
+		"enabled": enabled,
+		"nextRun": nextRun,
+	}
+
+	cursor, err := s.arango.Query(ctx, queryClaimRun, vars)
+	if err != nil {
+		if arango.IsConflict(err) || arango.IsPreconditionFailed(err) || arango.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, cursor.Close()
+}
+
+// recordRun adds a run to the execution history and keeps it as the last run of its schedule.
+// The schedule is disabled if enabled is false.
+func (s *Scheduler) recordRun(ctx context.Context, sc *model.Schedule, run *model.ScheduleRun, outcome string, enabled bool) error {
+	vars := map[string]interface{}{
+		"execution": &model.ScheduleExecution{
+			TenantID:    sc.TenantID,
+			ScheduleID:  sc.Key,
+			SwitchID:    sc.SwitchID,
+			State:       sc.State,
+			Time:        s.now().UnixNano(),
+			Outcome:     outcome,
+			ScheduleRun: *run,
+		},
+	}
+
+	cursor, err := s.arango.Query(ctx, queryInsertExec, vars)
+	if err != nil {
+		return err
+	}
+
+	if err = cursor.Close(); err != nil {
+		return err
+	}
+
+	vars = map[string]interface{}{
+		"key":     sc.Key,
+		"enabled": enabled,
+		"lastRun": run,
+	}
+
+	cursor, err = s.arango.Query(ctx, queryUpdateRun, vars)
+	if err != nil {
+		return err
+	}
+
+	return cursor.Close()
+}
+
+// setSwitch changes the state of the switch through the same path as clients
+func (s *Scheduler) setSwitch(ctx context.Context, sc *model.Schedule) error {
+	md := metadata.Pairs("tenant-id", sc.TenantID)
+	ctx = metadata.NewIncomingContext(auth.WithInternalCaller(ctx, Caller), md)
+
+	_, err := s.switches.SetSwitch(ctx, &proto.SetSwitchRequest{
+		Id:     sc.SwitchID,
+		State:  sc.State,
+		Reason: fmt.Sprintf("schedule %s", sc.Key),
+	})
+
+	return err
+}
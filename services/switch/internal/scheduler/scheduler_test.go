@@ -0,0 +1,363 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/auth"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+func date(day, hour, min int) time.Time {
+	return time.Date(2020, time.January, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestParseCatchUp(t *testing.T) {
+	tests := []struct {
+		policy          string
+		expectedCatchUp CatchUp
+		expectError     bool
+	}{
+		{"once", CatchUpOnce, false},
+		{"skip", CatchUpSkip, false},
+		{"always", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.policy, func(t *testing.T) {
+			catchUp, err := ParseCatchUp(tc.policy)
+
+			assert.Equal(t, tc.expectedCatchUp, catchUp)
+			assert.Equal(t, tc.expectError, err != nil)
+		})
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		schedule     *model.Schedule
+		after        time.Time
+		expectedNext time.Time
+		expectError  bool
+	}{
+		{"NoSchedule", &model.Schedule{}, date(10, 12, 0), time.Time{}, true},
+		{"InvalidCron", &model.Schedule{Cron: "0 25 * * *"}, date(10, 12, 0), time.Time{}, true},
+		{"InvalidTimeZone", &model.Schedule{Cron: "0 18 * * *", TimeZone: "Mars/Olympus"}, date(10, 12, 0), time.Time{}, true},
+		{"Cron", &model.Schedule{Cron: "0 18 * * *"}, date(10, 12, 0), date(10, 18, 0), false},
+		{"CronNextDay", &model.Schedule{Cron: "0 18 * * *"}, date(10, 18, 0), date(11, 18, 0), false},
+		{"CronTimeZone", &model.Schedule{Cron: "0 18 * * *", TimeZone: "America/Toronto"}, date(10, 12, 0), date(10, 23, 0), false},
+		{"Descriptor", &model.Schedule{Cron: "@daily"}, date(10, 12, 0), date(11, 0, 0), false},
+		{"RunAt", &model.Schedule{RunAt: date(10, 18, 0).UnixNano()}, date(10, 12, 0), date(10, 18, 0), false},
+		{"RunAtPast", &model.Schedule{RunAt: date(10, 6, 0).UnixNano()}, date(10, 12, 0), time.Time{}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			next, err := NextRun(tc.schedule, tc.after)
+
+			assert.Equal(t, tc.expectError, err != nil)
+			assert.True(t, tc.expectedNext.Equal(next), "expected %s, got %s", tc.expectedNext, next)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name                string
+		config              Config
+		expectedInterval    time.Duration
+		expectedMissedAfter time.Duration
+		expectedCatchUp     CatchUp
+	}{
+		{"Defaults", Config{}, defaultInterval, defaultMissedAfter, CatchUpOnce},
+		{"Custom", Config{Interval: time.Second, MissedAfter: time.Hour, CatchUp: CatchUpSkip}, time.Second, time.Hour, CatchUpSkip},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := New(&mockArango{}, &mockSwitchService{}, log.NewVoidLogger(), tc.config)
+
+			assert.NotEmpty(t, s.owner)
+			assert.Equal(t, tc.expectedInterval, s.interval)
+			assert.Equal(t, tc.expectedMissedAfter, s.missedAfter)
+			assert.Equal(t, tc.expectedCatchUp, s.catchUp)
+		})
+	}
+}
+
+func TestTick(t *testing.T) {
+	tests := []struct {
+		name              string
+		results           map[string]mockResult
+		expectError       bool
+		expectedDueLookup bool
+		expectedExecution bool
+	}{
+		{
+			"LeaseError",
+			map[string]mockResult{
+				queryAcquireLease: {nil, errors.New("database error")},
+			},
+			true, false, false,
+		},
+		{
+			"LeaseConflict",
+			map[string]mockResult{
+				queryAcquireLease: {nil, arango.ArangoError{HasError: true, Code: 409, ErrorNum: 1210}},
+			},
+			false, false, false,
+		},
+		{
+			"NotLeader",
+			map[string]mockResult{
+				queryAcquireLease: {&mockCursor{Docs: []interface{}{"another-replica"}}, nil},
+			},
+			false, false, false,
+		},
+		{
+			"DueSchedulesError",
+			map[string]mockResult{
+				queryAcquireLease: {&mockCursor{Docs: []interface{}{"owner"}}, nil},
+				queryDueSchedules: {nil, errors.New("database error")},
+			},
+			true, true, false,
+		},
+		{
+			"Leader",
+			map[string]mockResult{
+				queryAcquireLease: {&mockCursor{Docs: []interface{}{"owner"}}, nil},
+				queryDueSchedules: {&mockCursor{Docs: []interface{}{
+					&model.Schedule{Key: "1234", TenantID: "tttt-tttt", SwitchID: "aaaa-aaaa", State: "ON", Cron: "0 18 * * *", Enabled: true, NextRun: date(10, 18, 0).UnixNano()},
+				}}, nil},
+			},
+			false, true, true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			arango := &mockArango{QueryResults: tc.results}
+			switches := &mockSwitchService{}
+			s := New(arango, switches, log.NewVoidLogger(), Config{})
+			s.owner = "owner"
+			s.now = func() time.Time { return date(10, 18, 0) }
+
+			err := s.tick(context.Background())
+
+			assert.Equal(t, tc.expectError, err != nil)
+			assert.Equal(t, "owner", arango.QueryInVars[queryAcquireLease]["owner"])
+			assert.Equal(t, tc.expectedDueLookup, arango.QueryInVars[queryDueSchedules] != nil)
+			assert.Equal(t, tc.expectedExecution, switches.SetSwitchCalled)
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	conflict := arango.ArangoError{HasError: true, Code: 409, ErrorNum: 1200}
+	exec := date(10, 18, 0).Add(30 * time.Second).UnixNano()
+
+	tests := []struct {
+		name                 string
+		catchUp              CatchUp
+		schedule             *model.Schedule
+		claimError           error
+		setSwitchError       error
+		updateError          error
+		expectError          bool
+		expectedExec         bool
+		expectedClaimEnabled bool
+		expectedNextRun      time.Time
+		expectedRun          *model.ScheduleRun
+		expectedOutcome      string
+		expectedEnabled      bool
+	}{
+		{
+			"OnTime",
+			CatchUpOnce,
+			&model.Schedule{Key: "1234", Rev: "_aaaa", Cron: "0 18 * * *", Enabled: true, NextRun: date(10, 18, 0).UnixNano()},
+			nil, nil, nil, false,
+			true,
+			true, date(11, 18, 0),
+			&model.ScheduleRun{DueTime: date(10, 18, 0).UnixNano(), ExecTime: exec, Success: true},
+			OutcomeSucceeded, true,
+		},
+		{
+			"MissedRunOnce",
+			CatchUpOnce,
+			&model.Schedule{Key: "1234", Rev: "_aaaa", Cron: "0 12 * * *", Enabled: true, NextRun: date(7, 12, 0).UnixNano()},
+			nil, nil, nil, false,
+			true,
+			true, date(11, 12, 0),
+			&model.ScheduleRun{DueTime: date(10, 12, 0).UnixNano(), ExecTime: exec, Success: true, Missed: 3},
+			OutcomeSucceeded, true,
+		},
+		{
+			"MissedRunSkipped",
+			CatchUpSkip,
+			&model.Schedule{Key: "1234", Rev: "_aaaa", Cron: "0 12 * * *", Enabled: true, NextRun: date(7, 12, 0).UnixNano()},
+			nil, nil, nil, false,
+			false,
+			true, date(11, 12, 0),
+			&model.ScheduleRun{DueTime: date(10, 12, 0).UnixNano(), Error: "missed", Missed: 4},
+			OutcomeMissed, true,
+		},
+		{
+			"LateRunWithinGrace",
+			CatchUpSkip,
+			&model.Schedule{Key: "1234", Rev: "_aaaa", Cron: "0 18 * * *", Enabled: true, NextRun: date(10, 18, 0).UnixNano()},
+			nil, nil, nil, false,
+			true,
+			true, date(11, 18, 0),
+			&model.ScheduleRun{DueTime: date(10, 18, 0).UnixNano(), ExecTime: exec, Success: true},
+			OutcomeSucceeded, true,
+		},
+		{
+			"OneOff",
+			CatchUpOnce,
+			&model.Schedule{Key: "1234", Rev: "_aaaa", RunAt: date(10, 18, 0).UnixNano(), Enabled: true, NextRun: date(10, 18, 0).UnixNano()},
+			nil, nil, nil, false,
+			true,
+			false, time.Time{},
+			&model.ScheduleRun{DueTime: date(10, 18, 0).UnixNano(), ExecTime: exec, Success: true},
+			OutcomeSucceeded, false,
+		},
+		{
+			"ClaimConflict",
+			CatchUpOnce,
+			&model.Schedule{Key: "1234", Rev: "_aaaa", Cron: "0 18 * * *", Enabled: true, NextRun: date(10, 18, 0).UnixNano()},
+			conflict, nil, nil, false,
+			false,
+			true, date(11, 18, 0),
+			nil, "", false,
+		},
+		{
+			"ClaimError",
+			CatchUpOnce,
+			&model.Schedule{Key: "1234", Rev: "_aaaa", Cron: "0 18 * * *", Enabled: true, NextRun: date(10, 18, 0).UnixNano()},
+			errors.New("database error"), nil, nil, true,
+			false,
+			true, date(11, 18, 0),
+			nil, "", false,
+		},
+		{
+			"SetSwitchError",
+			CatchUpOnce,
+			&model.Schedule{Key: "1234", Rev: "_aaaa", Cron: "0 18 * * *", Enabled: true, NextRun: date(10, 18, 0).UnixNano()},
+			nil, status.Error(codes.Aborted, "conflict"), nil, false,
+			true,
+			true, date(11, 18, 0),
+			&model.ScheduleRun{DueTime: date(10, 18, 0).UnixNano(), ExecTime: exec, Error: "rpc error: code = Aborted desc = conflict"},
+			OutcomeFailed, true,
+		},
+		{
+			"SwitchNotFound",
+			CatchUpOnce,
+			&model.Schedule{Key: "1234", Rev: "_aaaa", Cron: "0 18 * * *", Enabled: true, NextRun: date(10, 18, 0).UnixNano()},
+			nil, status.Error(codes.NotFound, "switch not found"), nil, false,
+			true,
+			true, date(11, 18, 0),
+			&model.ScheduleRun{DueTime: date(10, 18, 0).UnixNano(), ExecTime: exec, Error: "rpc error: code = NotFound desc = switch not found"},
+			OutcomeFailed, false,
+		},
+		{
+			"UpdateError",
+			CatchUpOnce,
+			&model.Schedule{Key: "1234", Rev: "_aaaa", Cron: "0 18 * * *", Enabled: true, NextRun: date(10, 18, 0).UnixNano()},
+			nil, nil, errors.New("database error"), true,
+			true,
+			true, date(11, 18, 0),
+			&model.ScheduleRun{DueTime: date(10, 18, 0).UnixNano(), ExecTime: exec, Success: true},
+			OutcomeSucceeded, true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.schedule.TenantID = "tttt-tttt"
+			tc.schedule.SwitchID = "aaaa-aaaa"
+			tc.schedule.State = "ON"
+
+			arango := &mockArango{
+				QueryResults: map[string]mockResult{
+					queryClaimRun:  {&mockCursor{}, tc.claimError},
+					queryUpdateRun: {&mockCursor{}, tc.updateError},
+				},
+			}
+			switches := &mockSwitchService{
+				SetSwitchOutError: tc.setSwitchError,
+			}
+
+			s := New(arango, switches, log.NewVoidLogger(), Config{CatchUp: tc.catchUp})
+			s.now = func() time.Time { return date(10, 18, 0).Add(30 * time.Second) }
+
+			err := s.execute(context.Background(), tc.schedule)
+
+			assert.Equal(t, tc.expectError, err != nil)
+			assert.Equal(t, tc.expectedExec, switches.SetSwitchCalled)
+
+			if switches.SetSwitchCalled {
+				md, _ := metadata.FromIncomingContext(switches.SetSwitchInContext)
+				assert.Equal(t, []string{"tttt-tttt"}, md.Get("tenant-id"))
+				assert.Empty(t, md.Get("caller-id"))
+				assert.Equal(t, Caller, auth.InternalCaller(switches.SetSwitchInContext))
+				assert.Equal(t, "aaaa-aaaa", switches.SetSwitchInReq.Id)
+				assert.Equal(t, "ON", switches.SetSwitchInReq.State)
+				assert.Equal(t, "schedule 1234", switches.SetSwitchInReq.Reason)
+			}
+
+			// The next run is always claimed before the switch is changed
+			claim := arango.QueryInVars[queryClaimRun]
+			assert.Equal(t, "1234", claim["key"])
+			assert.Equal(t, "_aaaa", claim["rev"])
+			assert.Equal(t, tc.expectedClaimEnabled, claim["enabled"])
+
+			var expectedNextRun int64
+			if !tc.expectedNextRun.IsZero() {
+				expectedNextRun = tc.expectedNextRun.UnixNano()
+			}
+			assert.Equal(t, expectedNextRun, claim["nextRun"])
+
+			if tc.expectedRun == nil {
+				assert.Nil(t, arango.QueryInVars[queryInsertExec])
+				assert.Nil(t, arango.QueryInVars[queryUpdateRun])
+				return
+			}
+
+			execution := arango.QueryInVars[queryInsertExec]["execution"].(*model.ScheduleExecution)
+			assert.Equal(t, "tttt-tttt", execution.TenantID)
+			assert.Equal(t, "1234", execution.ScheduleID)
+			assert.Equal(t, "aaaa-aaaa", execution.SwitchID)
+			assert.Equal(t, "ON", execution.State)
+			assert.Equal(t, exec, execution.Time)
+			assert.Equal(t, tc.expectedOutcome, execution.Outcome)
+			assert.Equal(t, *tc.expectedRun, execution.ScheduleRun)
+
+			vars := arango.QueryInVars[queryUpdateRun]
+			assert.Equal(t, "1234", vars["key"])
+			assert.Equal(t, tc.expectedRun, vars["lastRun"])
+			assert.Equal(t, tc.expectedEnabled, vars["enabled"])
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	arango := &mockArango{}
+	s := New(arango, &mockSwitchService{}, log.NewVoidLogger(), Config{Interval: time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// The lease is never granted because the mock returns no owner, so Run only returns when the context is done
+	s.Run(ctx)
+	assert.NotNil(t, arango.QueryInVars[queryAcquireLease])
+}
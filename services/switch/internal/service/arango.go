@@ -7,15 +7,30 @@ import (
 	arangoHttp "github.com/arangodb/go-driver/http"
 )
 
+// switchIndexes are the persistent indexes of the switch collection
+var switchIndexes = [][]string{
+	{"tenantId", "siteId", "name"},
+	{"tenantId", "siteId", "state"},
+	{"tenantId", "name"},
+	{"pending", "pendingSince"},
+}
+
 type (
+	// transactionKey is the context key of the stream transaction a context runs in
+	transactionKey struct{}
+
 	// ArangoService selects the functions used from arango driver
 	ArangoService interface {
-		Connect(ctx context.Context, database, collection string) error
+		Connect(ctx context.Context, database, collection, historyCollection string) error
 		Query(ctx context.Context, query string, vars map[string]interface{}) (arango.Cursor, error)
 		CreateDocument(ctx context.Context, doc interface{}) (arango.DocumentMeta, error)
 		ReadDocument(ctx context.Context, key string, doc interface{}) (arango.DocumentMeta, error)
 		UpdateDocument(ctx context.Context, key string, doc interface{}) (arango.DocumentMeta, error)
 		RemoveDocument(ctx context.Context, key string) (arango.DocumentMeta, error)
+		CreateHistoryDocument(ctx context.Context, doc interface{}) (arango.DocumentMeta, error)
+		EnsureCollections(ctx context.Context, names ...string) error
+		Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+		Ping(ctx context.Context) error
 	}
 
 	arangoService struct {
@@ -23,6 +38,7 @@ type (
 		arango.Client
 		arango.Database
 		arango.Collection
+		history arango.Collection
 	}
 )
 
@@ -53,7 +69,7 @@ func NewArangoService(endpoints []string, user, password string) (ArangoService,
 	}, nil
 }
 
-func (s *arangoService) Connect(ctx context.Context, databaseName, collectionName string) error {
+func (s *arangoService) Connect(ctx context.Context, databaseName, collectionName, historyCollectionName string) error {
 	database, err := s.Client.Database(ctx, databaseName)
 	if err != nil {
 		if !arango.IsNotFound(err) {
@@ -68,22 +84,98 @@ func (s *arangoService) Connect(ctx context.Context, databaseName, collectionNam
 		}
 	}
 
-	collection, err := database.Collection(ctx, collectionName)
+	collection, err := ensureCollection(ctx, database, collectionName)
 	if err != nil {
-		if !arango.IsNotFound(err) {
+		return err
+	}
+
+	if err = ensureSwitchIndexes(ctx, collection); err != nil {
+		return err
+	}
+
+	history, err := ensureCollection(ctx, database, historyCollectionName)
+	if err != nil {
+		return err
+	}
+
+	s.Database = database
+	s.Collection = collection
+	s.history = history
+
+	return nil
+}
+
+func (s *arangoService) CreateHistoryDocument(ctx context.Context, doc interface{}) (arango.DocumentMeta, error) {
+	return s.history.CreateDocument(ctx, doc)
+}
+
+// EnsureCollections creates the collections queried without a document API, such as schedules
+func (s *arangoService) EnsureCollections(ctx context.Context, names ...string) error {
+	for _, name := range names {
+		if _, err := ensureCollection(ctx, s.Database, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Transaction runs a function in a stream transaction writing to the switch, history, schedule, outbox and quota collections.
+// The transaction is committed if the function succeeds and aborted otherwise.
+// A transaction started in the function of another one runs as part of the other one.
+func (s *arangoService) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(transactionKey{}) != nil {
+		return fn(ctx)
+	}
+
+	cols := arango.TransactionCollections{
+		Write: []string{s.Collection.Name(), s.history.Name(), schedulesCollection, OutboxCollection, QuotaCollection},
+	}
+
+	tid, err := s.Database.BeginTransaction(ctx, cols, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(context.WithValue(arango.WithTransactionID(ctx, tid), transactionKey{}, tid)); err != nil {
+		s.Database.AbortTransaction(ctx, tid, nil)
+		return err
+	}
+
+	return s.Database.CommitTransaction(ctx, tid, nil)
+}
+
+// ensureSwitchIndexes creates the indexes used to filter and sort switches if they do not exist
+func ensureSwitchIndexes(ctx context.Context, collection arango.Collection) error {
+	for _, fields := range switchIndexes {
+		if _, _, err := collection.EnsurePersistentIndex(ctx, fields, nil); err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
+
+// Ping checks that the database is reachable
+func (s *arangoService) Ping(ctx context.Context) error {
+	_, err := s.Database.Info(ctx)
+	return err
+}
+
+func ensureCollection(ctx context.Context, database arango.Database, name string) (arango.Collection, error) {
+	collection, err := database.Collection(ctx, name)
+	if err != nil {
+		if !arango.IsNotFound(err) {
+			return nil, err
+		}
 
 		// Create collection if not exist
 		opts := &arango.CreateCollectionOptions{}
-		collection, err = database.CreateCollection(ctx, collectionName, opts)
+		collection, err = database.CreateCollection(ctx, name, opts)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	s.Database = database
-	s.Collection = collection
-
-	return nil
+	return collection, nil
 }
@@ -20,6 +20,7 @@ func TestArangoService(t *testing.T) {
 		name                 string
 		user, password       string
 		database, collection string
+		historyCollection    string
 		contextTimeout       time.Duration
 		mocks                map[string]mockResp
 		expectError          bool
@@ -28,6 +29,7 @@ func TestArangoService(t *testing.T) {
 			"Unauthorized",
 			"user", "pass",
 			"animals", "mammals",
+			"primates",
 			50 * time.Millisecond,
 			map[string]mockResp{
 				"/_db/animals/_api/database/current":   mockResp{http.StatusUnauthorized, `{"code":401, "error":true, "errorMessage":"not authorized"}`},
@@ -39,6 +41,7 @@ func TestArangoService(t *testing.T) {
 			"Unauthorized",
 			"user", "pass",
 			"animals", "mammals",
+			"primates",
 			50 * time.Millisecond,
 			map[string]mockResp{
 				"/_db/animals/_api/database/current":   mockResp{http.StatusOK, `{"code":200, "error":false}`},
@@ -50,10 +53,13 @@ func TestArangoService(t *testing.T) {
 			"DatabaseAndCollectionExist",
 			"user", "pass",
 			"animals", "mammals",
+			"primates",
 			50 * time.Millisecond,
 			map[string]mockResp{
-				"/_db/animals/_api/database/current":   mockResp{http.StatusOK, `{"code":200, "error":false}`},
-				"/_db/animals/_api/collection/mammals": mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/database/current":    mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/collection/mammals":  mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/collection/primates": mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/index":               mockResp{http.StatusOK, `{"code":200, "error":false, "id":"mammals/1", "type":"persistent"}`},
 			},
 			false,
 		},
@@ -61,6 +67,7 @@ func TestArangoService(t *testing.T) {
 			"CreateDatabaseError",
 			"user", "pass",
 			"animals", "mammals",
+			"primates",
 			50 * time.Millisecond,
 			map[string]mockResp{
 				"/_db/animals/_api/database/current": mockResp{http.StatusNotFound, `{"code":404, "error":true, "errorMessage":"database not found"}`},
@@ -72,6 +79,7 @@ func TestArangoService(t *testing.T) {
 			"CreateCollectionError",
 			"user", "pass",
 			"animals", "mammals",
+			"primates",
 			50 * time.Millisecond,
 			map[string]mockResp{
 				"/_db/animals/_api/database/current":   mockResp{http.StatusNotFound, `{"code":404, "error":true, "errorMessage":"database not found"}`},
@@ -81,16 +89,46 @@ func TestArangoService(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"IndexError",
+			"user", "pass",
+			"animals", "mammals",
+			"primates",
+			50 * time.Millisecond,
+			map[string]mockResp{
+				"/_db/animals/_api/database/current":   mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/collection/mammals": mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/index":              mockResp{http.StatusUnauthorized, `{"code":401, "error":true, "errorMessage":"not authorized"}`},
+			},
+			true,
+		},
+		{
+			"HistoryCollectionError",
+			"user", "pass",
+			"animals", "mammals",
+			"primates",
+			50 * time.Millisecond,
+			map[string]mockResp{
+				"/_db/animals/_api/database/current":    mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/collection/mammals":  mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/index":               mockResp{http.StatusOK, `{"code":200, "error":false, "id":"mammals/1", "type":"persistent"}`},
+				"/_db/animals/_api/collection/primates": mockResp{http.StatusUnauthorized, `{"code":401, "error":true, "errorMessage":"not authorized"}`},
+			},
+			true,
+		},
 		{
 			"CreateDatabaseAndCollection",
 			"user", "pass",
 			"animals", "mammals",
+			"primates",
 			50 * time.Millisecond,
 			map[string]mockResp{
-				"/_db/animals/_api/database/current":   mockResp{http.StatusNotFound, `{"code":404, "error":true, "errorMessage":"database not found"}`},
-				"/_db/_system/_api/database":           mockResp{http.StatusCreated, `{"code":201, "error":false}`},
-				"/_db/animals/_api/collection/mammals": mockResp{http.StatusNotFound, `{"code":404, "error":true, "errorMessage":"collection not found"}`},
-				"/_db/animals/_api/collection":         mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/database/current":    mockResp{http.StatusNotFound, `{"code":404, "error":true, "errorMessage":"database not found"}`},
+				"/_db/_system/_api/database":            mockResp{http.StatusCreated, `{"code":201, "error":false}`},
+				"/_db/animals/_api/collection/mammals":  mockResp{http.StatusNotFound, `{"code":404, "error":true, "errorMessage":"collection not found"}`},
+				"/_db/animals/_api/collection/primates": mockResp{http.StatusNotFound, `{"code":404, "error":true, "errorMessage":"collection not found"}`},
+				"/_db/animals/_api/collection":          mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/index":               mockResp{http.StatusCreated, `{"code":201, "error":false, "id":"mammals/1", "type":"persistent"}`},
 			},
 			false,
 		},
@@ -113,7 +151,68 @@ func TestArangoService(t *testing.T) {
 			service, err := NewArangoService([]string{ts.URL}, tc.user, tc.password)
 			assert.NoError(t, err)
 
-			err = service.Connect(ctx, tc.database, tc.collection)
+			err = service.Connect(ctx, tc.database, tc.collection, tc.historyCollection)
+
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestArangoServicePing(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     mockResp
+		expectError bool
+	}{
+		{
+			"Reachable",
+			mockResp{http.StatusOK, `{"code":200, "error":false, "result":{"name":"animals"}}`},
+			false,
+		},
+		{
+			"Unavailable",
+			mockResp{http.StatusServiceUnavailable, `{"code":503, "error":true, "errorMessage":"service unavailable"}`},
+			true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mocks := map[string]mockResp{
+				"/_db/animals/_api/database/current":    mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/collection/mammals":  mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/collection/primates": mockResp{http.StatusOK, `{"code":200, "error":false}`},
+				"/_db/animals/_api/index":               mockResp{http.StatusOK, `{"code":200, "error":false, "id":"mammals/1", "type":"persistent"}`},
+			}
+
+			connected := false
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				resp := mocks[r.URL.Path]
+				if connected && r.URL.Path == "/_db/animals/_api/database/current" {
+					resp = tc.current
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(resp.StatusCode)
+				w.Write([]byte(resp.Body))
+			}))
+			defer ts.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			service, err := NewArangoService([]string{ts.URL}, "user", "pass")
+			assert.NoError(t, err)
+
+			err = service.Connect(ctx, "animals", "mammals", "primates")
+			assert.NoError(t, err)
+
+			connected = true
+			err = service.Ping(ctx)
 
 			if tc.expectError {
 				assert.Error(t, err)
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/auth"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"google.golang.org/grpc/status"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+const (
+	maxBulkSize = 1000
+
+	querySelectSwitches = `FOR sw IN switches FILTER sw.tenantId == @tenantId AND (sw._key IN @keys OR (sw.siteId == @siteId AND (@name == "" OR LIKE(sw.name, @name, true)) AND (@state == "" OR sw.state == @state))) LIMIT @count RETURN sw`
+)
+
+// ErrBulkAborted is returned for the switches of an all-or-nothing request left unchanged because another switch failed
+var ErrBulkAborted = errors.New("not changed because another switch failed")
+
+// switchResult creates the result of a change to a switch
+func switchResult(id string, sw *proto.Switch, err error) *proto.SwitchResult {
+	st := status.Convert(toStatus(err))
+	return &proto.SwitchResult{
+		Id:      id,
+		Code:    int32(st.Code()),
+		Message: st.Message(),
+		Switch:  sw,
+	}
+}
+
+// bulk keeps track of the switches of a bulk request and their outcome
+type bulk struct {
+	mode   proto.BulkMode
+	errs   []error
+	failed bool
+}
+
+func newBulk(mode proto.BulkMode, size int) *bulk {
+	return &bulk{
+		mode: mode,
+		errs: make([]error, size),
+	}
+}
+
+func (b *bulk) fail(i int, err error) {
+	b.errs[i] = err
+	b.failed = true
+}
+
+// pending determines whether a switch is still to be changed
+func (b *bulk) pending(i int) bool {
+	return b.errs[i] == nil && !(b.failed && b.mode == proto.BulkMode_ALL_OR_NOTHING)
+}
+
+// abort marks the switches not failed as aborted in all-or-nothing mode once a switch has failed
+// and reports whether the request was aborted, in which case no switch has changed.
+func (b *bulk) abort() bool {
+	if !b.failed || b.mode != proto.BulkMode_ALL_OR_NOTHING {
+		return false
+	}
+
+	for i, err := range b.errs {
+		if err == nil {
+			b.errs[i] = ErrBulkAborted
+		}
+	}
+
+	return true
+}
+
+// run changes the pending switches one by one, in a transaction in all-or-nothing mode.
+// The error is only returned if the transaction itself fails.
+func (b *bulk) run(ctx context.Context, db ArangoService, fn func(ctx context.Context, i int) error) error {
+	each := func(ctx context.Context) error {
+		for i := range b.errs {
+			if !b.pending(i) {
+				continue
+			}
+			if err := fn(ctx, i); err != nil {
+				b.fail(i, err)
+				if b.mode == proto.BulkMode_ALL_OR_NOTHING {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if b.mode != proto.BulkMode_ALL_OR_NOTHING || b.failed {
+		return each(ctx)
+	}
+
+	err := db.Transaction(ctx, each)
+	if err != nil && !b.failed {
+		return err
+	}
+
+	return nil
+}
+
+// querySwitches reads the switches of a tenant selected by keys or by a site and filters
+func (s *SwitchService) querySwitches(ctx context.Context, req interface{}, op, tenantID string, keys []string, siteID, name, state string) ([]*model.Switch, error) {
+	var err error
+	var cursor arango.Cursor
+
+	if keys == nil {
+		keys = []string{}
+	}
+
+	vars := map[string]interface{}{
+		"tenantId": tenantID,
+		"keys":     keys,
+		"siteId":   siteID,
+		"name":     name,
+		"state":    state,
+		"count":    maxBulkSize + 1,
+	}
+
+	s.exec(ctx, req, op+"_Query", querySelectSwitches, func() error {
+		cursor, err = s.arango.Query(ctx, querySelectSwitches, vars)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close()
+
+	docs := []*model.Switch{}
+	s.exec(ctx, req, op+"_ReadDocument", "ReadDocument", func() error {
+		for cursor.HasMore() {
+			doc := &model.Switch{}
+			if _, err = cursor.ReadDocument(ctx, doc); err != nil {
+				return err
+			}
+			docs = append(docs, doc)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// readSwitches reads the switches of a tenant by key.
+// Every key is in the result and a switch without tenant marks a switch not found.
+func (s *SwitchService) readSwitches(ctx context.Context, req interface{}, op, tenantID string, keys []string) ([]*model.Switch, error) {
+	docs, err := s.querySwitches(ctx, req, op, tenantID, keys, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := map[string]*model.Switch{}
+	for _, doc := range docs {
+		byKey[doc.Key] = doc
+	}
+
+	docs = make([]*model.Switch, len(keys))
+	for i, key := range keys {
+		if doc, ok := byKey[key]; ok {
+			docs[i] = doc
+		} else {
+			docs[i] = &model.Switch{Key: key}
+		}
+	}
+
+	return docs, nil
+}
+
+// SetSwitches changes the state of a list of switches or of the switches of a site
+// Interlocks cannot be overridden in bulk, so a switch blocked by one fails.
+func (s *SwitchService) SetSwitches(ctx context.Context, req *proto.SetSwitchesRequest) (*proto.SetSwitchesResponse, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	ids := req.GetIds()
+	selectors := 0
+	for _, set := range []bool{len(ids) > 0, req.GetGroupId() != "", req.GetSiteId() != ""} {
+		if set {
+			selectors++
+		}
+	}
+
+	var violations fieldViolations
+	if selectors == 0 {
+		violations.add("ids", "either switch ids, a group id or a site id is required")
+	} else if selectors > 1 {
+		violations.add("ids", "only one of switch ids, a group id and a site id can be used")
+	} else if len(ids) > maxBulkSize {
+		violations.add("ids", fmt.Sprintf("at most %d switches can be changed at once", maxBulkSize))
+	}
+	if req.GetSiteId() == "" && (req.GetNameFilter() != "" || req.GetStateFilter() != "") {
+		violations.add("name_filter", "filters only apply to the switches of a site")
+	}
+	if req.GetState() == "" {
+		violations.add("state", "state is required")
+	}
+	if err := violations.err(); err != nil {
+		return nil, err
+	}
+
+	if siteID := req.GetSiteId(); siteID != "" {
+		if err := auth.CheckSite(ctx, siteID); err != nil {
+			return nil, err
+		}
+	}
+
+	if groupID := req.GetGroupId(); groupID != "" {
+		group, err := s.queryGroup(ctx, req, "SetSwitches_GetGroup", queryGetGroup, map[string]interface{}{
+			"key":      groupID,
+			"tenantId": tenantID,
+		})
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		ids = group.SwitchIDs
+	}
+
+	var docs []*model.Switch
+	var err error
+
+	// Switches requested by id are reported in the requested order, including the ones not found
+	if len(ids) > 0 {
+		docs, err = s.readSwitches(ctx, req, "SetSwitches", tenantID, ids)
+	} else {
+		docs, err = s.querySwitches(ctx, req, "SetSwitches", tenantID, nil, req.GetSiteId(), req.GetNameFilter(), req.GetStateFilter())
+	}
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if len(docs) > maxBulkSize {
+		violations.add("site_id", fmt.Sprintf("more than %d switches are selected", maxBulkSize))
+		return nil, violations.err()
+	}
+
+	b := newBulk(req.GetMode(), len(docs))
+	for i, doc := range docs {
+		if doc.TenantID == "" {
+			b.fail(i, ErrSwitchNotFound)
+		} else if err := auth.CheckSite(ctx, doc.SiteID); err != nil {
+			b.fail(i, err)
+		} else if !hasState(doc.States, req.GetState()) {
+			var violations fieldViolations
+			violations.add("state", fmt.Sprintf("state %q is not one of the switch states", req.GetState()))
+			b.fail(i, violations.err())
+		} else if err := checkTransition(doc, req.GetState()); err != nil {
+			b.fail(i, err)
+		} else if _, err := s.checkInterlocks(ctx, tenantID, doc, req.GetState()); err != nil {
+			b.fail(i, err)
+		}
+	}
+
+	switches := make([]*proto.Switch, len(docs))
+	err = b.run(ctx, s.arango, func(ctx context.Context, i int) error {
+		sw, err := s.setState(ctx, req, "SetSwitches", tenantID, docs[i], req.GetState(), req.GetReason(), nil)
+		switches[i] = sw
+		return err
+	})
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	// The switches changed in an aborted transaction are unchanged
+	if b.abort() {
+		switches = make([]*proto.Switch, len(docs))
+	}
+
+	resp := &proto.SetSwitchesResponse{
+		Results: make([]*proto.SwitchResult, len(docs)),
+	}
+
+	for i, doc := range docs {
+		sw := switches[i]
+		if sw != nil {
+			s.publish(tenantID, proto.SwitchEvent_STATE_CHANGED, sw)
+		}
+
+		resp.Results[i] = switchResult(doc.Key, sw, b.errs[i])
+	}
+
+	return resp, nil
+}
+
+// InstallSwitches creates the switches sent on a stream
+func (s *SwitchService) InstallSwitches(stream proto.SwitchService_InstallSwitchesServer) error {
+	ctx := stream.Context()
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return toStatus(ErrNoTenant)
+	}
+
+	var mode proto.BulkMode
+	reqs := []*proto.InstallSwitchRequest{}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return toStatus(err)
+		}
+
+		if len(reqs) == 0 {
+			mode = req.GetMode()
+		}
+
+		if len(reqs) == maxBulkSize {
+			var violations fieldViolations
+			violations.add("switch", fmt.Sprintf("at most %d switches can be installed at once", maxBulkSize))
+			return violations.err()
+		}
+
+		reqs = append(reqs, req.GetSwitch())
+	}
+
+	b := newBulk(mode, len(reqs))
+	sites := map[string]error{}
+
+	for i, req := range reqs {
+		if err := validateInstall(req); err != nil {
+			b.fail(i, err)
+			continue
+		}
+
+		if err := auth.CheckSite(ctx, req.GetSiteId()); err != nil {
+			b.fail(i, err)
+			continue
+		}
+
+		if s.sites != nil {
+			siteID := req.GetSiteId()
+			if _, ok := sites[siteID]; !ok {
+				sites[siteID] = s.sites.Validate(ctx, siteID)
+			}
+			if err := sites[siteID]; err != nil {
+				b.fail(i, err)
+				continue
+			}
+		}
+	}
+
+	// Every switch is checked against the quota of the tenant in its own transaction, or in the transaction of the request in all-or-nothing mode,
+	// so the switches beyond the quota are not installed
+	switches := make([]*proto.Switch, len(reqs))
+	err := b.run(ctx, s.arango, func(ctx context.Context, i int) error {
+		sw, err := s.installSwitch(ctx, reqs[i], "InstallSwitches", tenantID, reqs[i])
+		switches[i] = sw
+		return err
+	})
+
+	if err != nil {
+		return toStatus(err)
+	}
+
+	// The switches created in an aborted transaction do not exist
+	if b.abort() {
+		switches = make([]*proto.Switch, len(reqs))
+	}
+
+	resp := &proto.InstallSwitchesResponse{
+		Results: make([]*proto.SwitchResult, len(reqs)),
+	}
+
+	for i, sw := range switches {
+		var id string
+		if sw != nil {
+			id = sw.Id
+			s.publish(tenantID, proto.SwitchEvent_INSTALLED, sw)
+		}
+
+		resp.Results[i] = switchResult(id, sw, b.errs[i])
+	}
+
+	return stream.SendAndClose(resp)
+}
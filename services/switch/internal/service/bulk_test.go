@@ -0,0 +1,551 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/moorara/microservices-demo/pkg/site"
+	"github.com/moorara/microservices-demo/services/switch/internal/auth"
+	"github.com/moorara/microservices-demo/services/switch/internal/broker"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+func resultCodes(results []*proto.SwitchResult) []codes.Code {
+	result := []codes.Code{}
+	for _, r := range results {
+		result = append(result, codes.Code(r.GetCode()))
+	}
+	return result
+}
+
+func TestSetSwitches(t *testing.T) {
+	doc := &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", SiteID: "1111-1111", State: "ON", States: []string{"OFF", "ON"}}
+
+	tests := []struct {
+		name                string
+		arango              *mockArangoService
+		ctx                 context.Context
+		req                 *proto.SetSwitchesRequest
+		expectedCode        codes.Code
+		expectedFields      []string
+		expectedResults     []codes.Code
+		expectedTransaction bool
+		expectedEvents      uint64
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			&proto.SetSwitchesRequest{SiteId: "1111-1111", State: "OFF"},
+			codes.Unauthenticated,
+			nil, nil, false, 0,
+		},
+		{
+			"NoSelection",
+			&mockArangoService{},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{},
+			codes.InvalidArgument,
+			[]string{"ids", "state"},
+			nil, false, 0,
+		},
+		{
+			"IdsAndSite",
+			&mockArangoService{},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{Ids: []string{"aaaa-aaaa"}, SiteId: "1111-1111", State: "OFF"},
+			codes.InvalidArgument,
+			[]string{"ids"},
+			nil, false, 0,
+		},
+		{
+			"IdsWithFilter",
+			&mockArangoService{},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{Ids: []string{"aaaa-aaaa"}, NameFilter: "light%", State: "OFF"},
+			codes.InvalidArgument,
+			[]string{"name_filter"},
+			nil, false, 0,
+		},
+		{
+			"GroupNotFound",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:            &mockCloser{},
+					HasMoreOutResults: []bool{false},
+				},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{GroupId: "gggg-gggg", State: "OFF"},
+			codes.NotFound,
+			nil, nil, false, 0,
+		},
+		{
+			"Group",
+			&mockArangoService{
+				QueryOutResults: map[string]mockQueryResult{
+					queryGetGroup: {
+						Cursor: &mockArangoCursor{
+							Closer:              &mockCloser{},
+							ReadDocumentOutDocs: []interface{}{&model.Group{Key: "gggg-gggg", SwitchIDs: []string{"aaaa-aaaa"}}},
+						},
+					},
+					querySelectSwitches: {
+						Cursor: &mockArangoCursor{
+							Closer:              &mockCloser{},
+							ReadDocumentOutDocs: []interface{}{doc},
+						},
+					},
+				},
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{GroupId: "gggg-gggg", State: "OFF"},
+			codes.OK,
+			nil,
+			[]codes.Code{codes.OK},
+			true, 1,
+		},
+		{
+			"QueryFail",
+			&mockArangoService{
+				QueryOutError: errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{SiteId: "1111-1111", State: "OFF"},
+			codes.Internal,
+			nil, nil, false, 0,
+		},
+		{
+			"BestEffortNotFound",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true, false},
+					ReadDocumentOutDoc: doc,
+				},
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{Ids: []string{"aaaa-aaaa", "bbbb-bbbb"}, State: "OFF"},
+			codes.OK,
+			nil,
+			[]codes.Code{codes.OK, codes.NotFound},
+			true, 1,
+		},
+		{
+			"AllOrNothingNotFound",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true, false},
+					ReadDocumentOutDoc: doc,
+				},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{Ids: []string{"aaaa-aaaa", "bbbb-bbbb"}, State: "OFF", Mode: proto.BulkMode_ALL_OR_NOTHING},
+			codes.OK,
+			nil,
+			[]codes.Code{codes.Aborted, codes.NotFound},
+			false, 0,
+		},
+		{
+			"InvalidState",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true, false},
+					ReadDocumentOutDoc: doc,
+				},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{SiteId: "1111-1111", State: "DIM"},
+			codes.OK,
+			nil,
+			[]codes.Code{codes.InvalidArgument},
+			false, 0,
+		},
+		{
+			"IllegalTransition",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(&model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", SiteID: "1111-1111", State: "ON", States: []string{"OFF", "ON"}, Transitions: []model.Transition{
+					{From: "OFF", To: []string{"ON"}},
+				}}),
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{SiteId: "1111-1111", State: "OFF"},
+			codes.OK,
+			nil,
+			[]codes.Code{codes.FailedPrecondition},
+			false, 0,
+		},
+		{
+			"Interlocked",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(&model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", SiteID: "1111-1111", State: "ON", States: []string{"OFF", "ON"}, Interlocks: []model.Interlock{
+					{Name: "overheat", SensorID: "temperature", Operator: "GREATER_THAN", Limit: "MAX_SAFE"},
+				}}),
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{SiteId: "1111-1111", State: "OFF"},
+			codes.OK,
+			nil,
+			[]codes.Code{codes.FailedPrecondition},
+			false, 0,
+		},
+		{
+			"SiteNotAllowed",
+			&mockArangoService{},
+			auth.NewContext(contextWithTenant(testTenantID), &auth.Grant{Sites: []string{"2222-2222"}}),
+			&proto.SetSwitchesRequest{SiteId: "1111-1111", State: "OFF"},
+			codes.PermissionDenied,
+			nil, nil, false, 0,
+		},
+		{
+			"SwitchSiteNotAllowed",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(&model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", SiteID: "1111-1111", State: "ON", States: []string{"OFF", "ON"}}),
+			},
+			auth.NewContext(contextWithTenant(testTenantID), &auth.Grant{Sites: []string{"2222-2222"}}),
+			&proto.SetSwitchesRequest{Ids: []string{"aaaa-aaaa"}, State: "OFF"},
+			codes.OK,
+			nil,
+			[]codes.Code{codes.PermissionDenied},
+			false, 0,
+		},
+		{
+			"TransactionFail",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true, false},
+					ReadDocumentOutDoc: doc,
+				},
+				TransactionOutError: errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{SiteId: "1111-1111", State: "OFF", Mode: proto.BulkMode_ALL_OR_NOTHING},
+			codes.Internal,
+			nil, nil, true, 0,
+		},
+		{
+			"AllOrNothingUpdateFail",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true, true, false},
+					ReadDocumentOutDoc: doc,
+				},
+				UpdateDocumentOutError: errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{SiteId: "1111-1111", State: "OFF", Mode: proto.BulkMode_ALL_OR_NOTHING},
+			codes.OK,
+			nil,
+			[]codes.Code{codes.Internal, codes.Aborted},
+			true, 0,
+		},
+		{
+			"AllOrNothingSuccess",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true, true, false},
+					ReadDocumentOutDoc: doc,
+				},
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchesRequest{SiteId: "1111-1111", NameFilter: "light%", StateFilter: "ON", State: "OFF", Mode: proto.BulkMode_ALL_OR_NOTHING},
+			codes.OK,
+			nil,
+			[]codes.Code{codes.OK, codes.OK},
+			true, 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+			service.broker = broker.New(0, 0)
+			base := service.broker.Revision()
+
+			resp, err := service.SetSwitches(tc.ctx, tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedTransaction, tc.arango.TransactionCalled)
+			assert.Equal(t, tc.expectedEvents, service.broker.Revision()-base)
+			if tc.expectedFields != nil {
+				assert.Equal(t, tc.expectedFields, violatedFields(err))
+			}
+
+			if tc.expectedCode == codes.OK {
+				assert.Equal(t, tc.expectedResults, resultCodes(resp.Results))
+				assert.Equal(t, querySelectSwitches, tc.arango.QueryInQuery)
+				assert.Equal(t, tc.req.SiteId, tc.arango.QueryInVars["siteId"])
+				assert.Equal(t, tc.req.NameFilter, tc.arango.QueryInVars["name"])
+				assert.Equal(t, tc.req.StateFilter, tc.arango.QueryInVars["state"])
+
+				for _, r := range resp.Results {
+					if codes.Code(r.Code) == codes.OK {
+						assert.Equal(t, "OFF", r.Switch.State)
+						assert.Equal(t, "_bbbb", r.Switch.Revision)
+					} else {
+						assert.Nil(t, r.Switch)
+						assert.NotEmpty(t, r.Message)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestInstallSwitches(t *testing.T) {
+	valid := &proto.InstallSwitchRequest{SiteId: "1111-1111", Name: "Light", State: "OFF", States: []string{"OFF", "ON"}}
+	invalid := &proto.InstallSwitchRequest{SiteId: "1111-1111", State: "OFF", States: []string{"OFF", "ON"}}
+
+	tests := []struct {
+		name            string
+		arango          *mockArangoService
+		sites           site.Client
+		quota           int
+		ctx             context.Context
+		stream          *mockInstallSwitchesServer
+		expectedCode    codes.Code
+		expectedResults []codes.Code
+		expectedEvents  uint64
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			nil, 0,
+			context.Background(),
+			&mockInstallSwitchesServer{},
+			codes.Unauthenticated,
+			nil, 0,
+		},
+		{
+			"RecvFail",
+			&mockArangoService{},
+			nil, 0,
+			contextWithTenant(testTenantID),
+			&mockInstallSwitchesServer{
+				RecvOutError: errors.New("stream error"),
+			},
+			codes.Internal,
+			nil, 0,
+		},
+		{
+			"TooManySwitches",
+			&mockArangoService{},
+			nil, 0,
+			contextWithTenant(testTenantID),
+			&mockInstallSwitchesServer{
+				RecvOutReqs: make([]*proto.InstallSwitchesRequest, maxBulkSize+1),
+			},
+			codes.InvalidArgument,
+			nil, 0,
+		},
+		{
+			"BestEffortInvalid",
+			&mockArangoService{
+				CreateDocumentOutMeta: arango.DocumentMeta{Key: "aaaa-aaaa", Rev: "_aaaa"},
+			},
+			nil, 0,
+			contextWithTenant(testTenantID),
+			&mockInstallSwitchesServer{
+				RecvOutReqs: []*proto.InstallSwitchesRequest{
+					{Switch: valid},
+					{Switch: invalid},
+				},
+			},
+			codes.OK,
+			[]codes.Code{codes.OK, codes.InvalidArgument},
+			1,
+		},
+		{
+			"AllOrNothingInvalid",
+			&mockArangoService{},
+			nil, 0,
+			contextWithTenant(testTenantID),
+			&mockInstallSwitchesServer{
+				RecvOutReqs: []*proto.InstallSwitchesRequest{
+					{Mode: proto.BulkMode_ALL_OR_NOTHING, Switch: valid},
+					{Switch: invalid},
+				},
+			},
+			codes.OK,
+			[]codes.Code{codes.Aborted, codes.InvalidArgument},
+			0,
+		},
+		{
+			"UnknownSite",
+			&mockArangoService{},
+			&mockSiteClient{
+				ValidateOutError: site.ErrUnknownSite,
+			},
+			0,
+			contextWithTenant(testTenantID),
+			&mockInstallSwitchesServer{
+				RecvOutReqs: []*proto.InstallSwitchesRequest{
+					{Switch: valid},
+					{Switch: valid},
+				},
+			},
+			codes.OK,
+			[]codes.Code{codes.InvalidArgument, codes.InvalidArgument},
+			0,
+		},
+		{
+			"CountFail",
+			&mockArangoService{
+				QueryOutError: errors.New("database error"),
+			},
+			nil, 10,
+			contextWithTenant(testTenantID),
+			&mockInstallSwitchesServer{
+				RecvOutReqs: []*proto.InstallSwitchesRequest{
+					{Switch: valid},
+				},
+			},
+			codes.OK,
+			[]codes.Code{codes.Internal},
+			0,
+		},
+		{
+			"WithinQuota",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:         &mockCloser{},
+					CountOutResult: 9,
+				},
+				CreateDocumentOutMeta: arango.DocumentMeta{Key: "aaaa-aaaa", Rev: "_aaaa"},
+			},
+			nil, 10,
+			contextWithTenant(testTenantID),
+			&mockInstallSwitchesServer{
+				RecvOutReqs: []*proto.InstallSwitchesRequest{
+					{Switch: valid},
+					{Switch: invalid},
+				},
+			},
+			codes.OK,
+			[]codes.Code{codes.OK, codes.InvalidArgument},
+			1,
+		},
+		{
+			"QuotaExceeded",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:         &mockCloser{},
+					CountOutResult: 10,
+				},
+				CreateDocumentOutMeta: arango.DocumentMeta{Key: "aaaa-aaaa", Rev: "_aaaa"},
+			},
+			nil, 10,
+			contextWithTenant(testTenantID),
+			&mockInstallSwitchesServer{
+				RecvOutReqs: []*proto.InstallSwitchesRequest{
+					{Switch: valid},
+					{Switch: invalid},
+					{Switch: valid},
+				},
+			},
+			codes.OK,
+			[]codes.Code{codes.ResourceExhausted, codes.InvalidArgument, codes.ResourceExhausted},
+			0,
+		},
+		{
+			"AllOrNothingCreateFail",
+			&mockArangoService{
+				CreateDocumentOutError: errors.New("database error"),
+			},
+			nil, 0,
+			contextWithTenant(testTenantID),
+			&mockInstallSwitchesServer{
+				RecvOutReqs: []*proto.InstallSwitchesRequest{
+					{Mode: proto.BulkMode_ALL_OR_NOTHING, Switch: valid},
+					{Switch: valid},
+				},
+			},
+			codes.OK,
+			[]codes.Code{codes.Internal, codes.Aborted},
+			0,
+		},
+		{
+			"TransactionFail",
+			&mockArangoService{
+				TransactionOutError: errors.New("database error"),
+			},
+			nil, 0,
+			contextWithTenant(testTenantID),
+			&mockInstallSwitchesServer{
+				RecvOutReqs: []*proto.InstallSwitchesRequest{
+					{Mode: proto.BulkMode_ALL_OR_NOTHING, Switch: valid},
+				},
+			},
+			codes.Internal,
+			nil, 0,
+		},
+		{
+			"AllOrNothingSuccess",
+			&mockArangoService{
+				CreateDocumentOutMeta: arango.DocumentMeta{Key: "aaaa-aaaa", Rev: "_aaaa"},
+			},
+			&mockSiteClient{},
+			0,
+			contextWithTenant(testTenantID),
+			&mockInstallSwitchesServer{
+				RecvOutReqs: []*proto.InstallSwitchesRequest{
+					{Mode: proto.BulkMode_ALL_OR_NOTHING, Switch: valid},
+					{Switch: valid},
+				},
+			},
+			codes.OK,
+			[]codes.Code{codes.OK, codes.OK},
+			2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+			service.sites = tc.sites
+			service.quota = tc.quota
+			service.broker = broker.New(0, 0)
+			base := service.broker.Revision()
+
+			tc.stream.ServerStream = &mockServerStream{
+				ContextOutContext: tc.ctx,
+			}
+
+			err := service.InstallSwitches(tc.stream)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedCode == codes.OK, tc.stream.SendAndCloseCalled)
+			assert.Equal(t, tc.expectedEvents, service.broker.Revision()-base)
+
+			if tc.expectedCode == codes.OK {
+				results := tc.stream.SendAndCloseInResp.Results
+				assert.Equal(t, tc.expectedResults, resultCodes(results))
+
+				for _, r := range results {
+					if codes.Code(r.Code) == codes.OK {
+						assert.Equal(t, "aaaa-aaaa", r.Id)
+						assert.Equal(t, "_aaaa", r.Switch.Revision)
+					} else {
+						assert.Empty(t, r.Id)
+						assert.Nil(t, r.Switch)
+					}
+				}
+			}
+		})
+	}
+}
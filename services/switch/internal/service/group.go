@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/auth"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+const (
+	// GroupsCollection is the Arango collection groups are stored in
+	GroupsCollection = "groups"
+
+	queryCreateGroup = `INSERT @doc INTO groups RETURN NEW`
+	queryGetGroup    = `FOR g IN groups FILTER g._key == @key AND g.tenantId == @tenantId RETURN g`
+	queryGetGroups   = `FOR g IN groups FILTER g.tenantId == @tenantId AND g.siteId == @siteId RETURN g`
+	queryUpdateGroup = `UPDATE { _key: @key, _rev: @rev } WITH @patch IN groups OPTIONS { ignoreRevs: false } RETURN NEW`
+	queryRemoveGroup = `REMOVE @key IN groups`
+)
+
+// ErrGroupNotFound is returned when a group does not exist for a tenant
+var ErrGroupNotFound = errors.New("group not found")
+
+func groupToProto(doc *model.Group) *proto.Group {
+	return &proto.Group{
+		Id:        doc.Key,
+		SiteId:    doc.SiteID,
+		Name:      doc.Name,
+		SwitchIds: doc.SwitchIDs,
+	}
+}
+
+// queryGroup runs a query returning at most one group
+func (s *SwitchService) queryGroup(ctx context.Context, req interface{}, op, query string, vars map[string]interface{}) (*model.Group, error) {
+	doc := &model.Group{}
+	if err := s.queryDocument(ctx, req, op, query, vars, doc, ErrGroupNotFound); err != nil {
+		return nil, err
+	}
+
+	if err := auth.CheckSite(ctx, doc.SiteID); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// validateGroup checks the name of a group and that its switches are switches of its site
+func (s *SwitchService) validateGroup(ctx context.Context, req interface{}, op, tenantID string, doc *model.Group) error {
+	var violations fieldViolations
+	if doc.SiteID == "" {
+		violations.add("site_id", "site id is required")
+	}
+	if doc.Name == "" {
+		violations.add("name", "name is required")
+	}
+	if len(doc.SwitchIDs) == 0 {
+		violations.add("switch_ids", "at least one switch is required")
+	} else if len(doc.SwitchIDs) > maxBulkSize {
+		violations.add("switch_ids", fmt.Sprintf("a group can have at most %d switches", maxBulkSize))
+	}
+
+	if err := violations.err(); err != nil {
+		return err
+	}
+
+	switches, err := s.readSwitches(ctx, req, op, tenantID, doc.SwitchIDs)
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, sw := range switches {
+		if seen[sw.Key] {
+			violations.add("switch_ids", fmt.Sprintf("switch %q is listed more than once", sw.Key))
+		} else if sw.TenantID == "" || sw.SiteID != doc.SiteID {
+			violations.add("switch_ids", fmt.Sprintf("switch %q is not a switch of site %q", sw.Key, doc.SiteID))
+		}
+		seen[sw.Key] = true
+	}
+
+	return violations.err()
+}
+
+// CreateGroup creates a new group of switches
+func (s *SwitchService) CreateGroup(ctx context.Context, req *proto.CreateGroupRequest) (*proto.Group, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	doc := &model.Group{
+		TenantID:  tenantID,
+		SiteID:    req.GetSiteId(),
+		Name:      req.GetName(),
+		SwitchIDs: req.GetSwitchIds(),
+	}
+
+	if err := auth.CheckSite(ctx, doc.SiteID); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateGroup(ctx, req, "CreateGroup", tenantID, doc); err != nil {
+		return nil, toStatus(err)
+	}
+
+	vars := map[string]interface{}{
+		"doc": doc,
+	}
+
+	doc, err := s.queryGroup(ctx, req, "CreateGroup_Insert", queryCreateGroup, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return groupToProto(doc), nil
+}
+
+// GetGroup retrieves a group
+func (s *SwitchService) GetGroup(ctx context.Context, req *proto.GetGroupRequest) (*proto.Group, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	vars := map[string]interface{}{
+		"key":      req.GetId(),
+		"tenantId": tenantID,
+	}
+
+	doc, err := s.queryGroup(ctx, req, "GetGroup_Query", queryGetGroup, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return groupToProto(doc), nil
+}
+
+// GetGroups retrieves the groups of a site
+func (s *SwitchService) GetGroups(req *proto.GetGroupsRequest, stream proto.SwitchService_GetGroupsServer) error {
+	var err error
+	var cursor arango.Cursor
+
+	ctx := stream.Context()
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return toStatus(ErrNoTenant)
+	}
+
+	if req.GetSiteId() == "" {
+		var violations fieldViolations
+		violations.add("site_id", "site id is required")
+		return violations.err()
+	}
+
+	if err := auth.CheckSite(ctx, req.GetSiteId()); err != nil {
+		return err
+	}
+
+	vars := map[string]interface{}{
+		"tenantId": tenantID,
+		"siteId":   req.GetSiteId(),
+	}
+
+	s.exec(ctx, req, "GetGroups_Query", queryGetGroups, func() error {
+		cursor, err = s.arango.Query(ctx, queryGetGroups, vars)
+		return err
+	})
+
+	if err != nil {
+		return toStatus(err)
+	}
+
+	defer cursor.Close()
+
+	s.exec(ctx, req, "GetGroups_ReadDocument_Send", "ReadDocument", func() error {
+		for cursor.HasMore() {
+			doc := &model.Group{}
+			if _, err = cursor.ReadDocument(ctx, doc); err != nil {
+				return err
+			}
+
+			if err = stream.Send(groupToProto(doc)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return toStatus(err)
+}
+
+// UpdateGroup replaces the name and switches of a group
+func (s *SwitchService) UpdateGroup(ctx context.Context, req *proto.UpdateGroupRequest) (*proto.Group, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	vars := map[string]interface{}{
+		"key":      req.GetId(),
+		"tenantId": tenantID,
+	}
+
+	current, err := s.queryGroup(ctx, req, "UpdateGroup_Query", queryGetGroup, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	doc := &model.Group{
+		SiteID:    current.SiteID,
+		Name:      req.GetName(),
+		SwitchIDs: req.GetSwitchIds(),
+	}
+
+	if err = s.validateGroup(ctx, req, "UpdateGroup", tenantID, doc); err != nil {
+		return nil, toStatus(err)
+	}
+
+	// The update only succeeds if the group has not changed since it was read
+	vars = map[string]interface{}{
+		"key": current.Key,
+		"rev": current.Rev,
+		"patch": map[string]interface{}{
+			"name":      doc.Name,
+			"switchIds": doc.SwitchIDs,
+		},
+	}
+
+	doc, err = s.queryGroup(ctx, req, "UpdateGroup_Update", queryUpdateGroup, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return groupToProto(doc), nil
+}
+
+// DeleteGroup deletes a group
+func (s *SwitchService) DeleteGroup(ctx context.Context, req *proto.DeleteGroupRequest) (*proto.DeleteGroupResponse, error) {
+	var err error
+	var cursor arango.Cursor
+
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	vars := map[string]interface{}{
+		"key":      req.GetId(),
+		"tenantId": tenantID,
+	}
+
+	if _, err = s.queryGroup(ctx, req, "DeleteGroup_Query", queryGetGroup, vars); err != nil {
+		return nil, toStatus(err)
+	}
+
+	vars = map[string]interface{}{
+		"key": req.GetId(),
+	}
+
+	s.exec(ctx, req, "DeleteGroup_Remove", queryRemoveGroup, func() error {
+		cursor, err = s.arango.Query(ctx, queryRemoveGroup, vars)
+		if err != nil {
+			return err
+		}
+		return cursor.Close()
+	})
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &proto.DeleteGroupResponse{}, nil
+}
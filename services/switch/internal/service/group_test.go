@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	testSwitchA = &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", SiteID: "1111-1111", State: "ON", States: []string{"OFF", "ON"}}
+	testSwitchB = &model.Switch{TenantID: testTenantID, Key: "bbbb-bbbb", Rev: "_bbbb", SiteID: "1111-1111", State: "OFF", States: []string{"OFF", "ON"}}
+	testSwitchC = &model.Switch{TenantID: testTenantID, Key: "cccc-cccc", Rev: "_cccc", SiteID: "2222-2222", State: "OFF", States: []string{"OFF", "ON"}}
+)
+
+func TestCreateGroup(t *testing.T) {
+	tests := []struct {
+		name             string
+		arango           *mockArangoService
+		ctx              context.Context
+		req              *proto.CreateGroupRequest
+		expectedCode     codes.Code
+		expectedFields   []string
+		expectedResponse *proto.Group
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			&proto.CreateGroupRequest{SiteId: "1111-1111", Name: "Lights", SwitchIds: []string{"aaaa-aaaa"}},
+			codes.Unauthenticated,
+			nil, nil,
+		},
+		{
+			"Empty",
+			&mockArangoService{},
+			contextWithTenant(testTenantID),
+			&proto.CreateGroupRequest{},
+			codes.InvalidArgument,
+			[]string{"site_id", "name", "switch_ids"},
+			nil,
+		},
+		{
+			"QueryError",
+			&mockArangoService{
+				QueryOutError: errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			&proto.CreateGroupRequest{SiteId: "1111-1111", Name: "Lights", SwitchIds: []string{"aaaa-aaaa"}},
+			codes.Internal,
+			nil, nil,
+		},
+		{
+			"InvalidSwitches",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(testSwitchA, testSwitchC),
+			},
+			contextWithTenant(testTenantID),
+			&proto.CreateGroupRequest{SiteId: "1111-1111", Name: "Lights", SwitchIds: []string{"aaaa-aaaa", "cccc-cccc", "dddd-dddd", "aaaa-aaaa"}},
+			codes.InvalidArgument,
+			[]string{"switch_ids", "switch_ids", "switch_ids"},
+			nil,
+		},
+		{
+			"Success",
+			&mockArangoService{
+				QueryOutResults: map[string]mockQueryResult{
+					querySelectSwitches: {Cursor: newMockCursor(testSwitchA, testSwitchB)},
+					queryCreateGroup: {Cursor: newMockCursor(&model.Group{
+						Key: "gggg-gggg", SiteID: "1111-1111", Name: "Lights", SwitchIDs: []string{"aaaa-aaaa", "bbbb-bbbb"},
+					})},
+				},
+			},
+			contextWithTenant(testTenantID),
+			&proto.CreateGroupRequest{SiteId: "1111-1111", Name: "Lights", SwitchIds: []string{"aaaa-aaaa", "bbbb-bbbb"}},
+			codes.OK,
+			nil,
+			&proto.Group{Id: "gggg-gggg", SiteId: "1111-1111", Name: "Lights", SwitchIds: []string{"aaaa-aaaa", "bbbb-bbbb"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+
+			group, err := service.CreateGroup(tc.ctx, tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedResponse, group)
+			if tc.expectedFields != nil {
+				assert.Equal(t, tc.expectedFields, violatedFields(err))
+			}
+
+			if tc.expectedCode == codes.OK {
+				doc := tc.arango.QueryInVars["doc"].(*model.Group)
+				assert.Equal(t, testTenantID, doc.TenantID)
+				assert.Equal(t, tc.req.SwitchIds, doc.SwitchIDs)
+			}
+		})
+	}
+}
+
+func TestGetGroups(t *testing.T) {
+	tests := []struct {
+		name          string
+		arango        *mockArangoService
+		req           *proto.GetGroupsRequest
+		expectedCode  codes.Code
+		expectedCount int
+	}{
+		{
+			"NoSiteID",
+			&mockArangoService{},
+			&proto.GetGroupsRequest{},
+			codes.InvalidArgument,
+			0,
+		},
+		{
+			"QueryError",
+			&mockArangoService{
+				QueryOutError: errors.New("database error"),
+			},
+			&proto.GetGroupsRequest{SiteId: "1111-1111"},
+			codes.Internal,
+			0,
+		},
+		{
+			"Success",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(&model.Group{Key: "gggg-gggg"}, &model.Group{Key: "hhhh-hhhh"}),
+			},
+			&proto.GetGroupsRequest{SiteId: "1111-1111"},
+			codes.OK,
+			2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+			stream := &mockGetGroupsServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: contextWithTenant(testTenantID),
+				},
+			}
+
+			err := service.GetGroups(tc.req, stream)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Len(t, stream.SendInGroups, tc.expectedCount)
+		})
+	}
+}
+
+func TestUpdateGroup(t *testing.T) {
+	current := &model.Group{Key: "gggg-gggg", Rev: "_gggg", SiteID: "1111-1111", Name: "Lights", SwitchIDs: []string{"aaaa-aaaa"}}
+
+	tests := []struct {
+		name           string
+		arango         *mockArangoService
+		req            *proto.UpdateGroupRequest
+		expectedCode   codes.Code
+		expectedFields []string
+	}{
+		{
+			"NotFound",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(),
+			},
+			&proto.UpdateGroupRequest{Id: "gggg-gggg", Name: "Lights", SwitchIds: []string{"aaaa-aaaa"}},
+			codes.NotFound,
+			nil,
+		},
+		{
+			"OtherSite",
+			&mockArangoService{
+				QueryOutResults: map[string]mockQueryResult{
+					queryGetGroup:       {Cursor: newMockCursor(current)},
+					querySelectSwitches: {Cursor: newMockCursor(testSwitchC)},
+				},
+			},
+			&proto.UpdateGroupRequest{Id: "gggg-gggg", Name: "Lights", SwitchIds: []string{"cccc-cccc"}},
+			codes.InvalidArgument,
+			[]string{"switch_ids"},
+		},
+		{
+			"Success",
+			&mockArangoService{
+				QueryOutResults: map[string]mockQueryResult{
+					queryGetGroup:       {Cursor: newMockCursor(current)},
+					querySelectSwitches: {Cursor: newMockCursor(testSwitchA, testSwitchB)},
+					queryUpdateGroup:    {Cursor: newMockCursor(current)},
+				},
+			},
+			&proto.UpdateGroupRequest{Id: "gggg-gggg", Name: "All lights", SwitchIds: []string{"aaaa-aaaa", "bbbb-bbbb"}},
+			codes.OK,
+			nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+
+			group, err := service.UpdateGroup(contextWithTenant(testTenantID), tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			if tc.expectedFields != nil {
+				assert.Equal(t, tc.expectedFields, violatedFields(err))
+			}
+
+			if tc.expectedCode == codes.OK {
+				assert.NotNil(t, group)
+				assert.Equal(t, queryUpdateGroup, tc.arango.QueryInQuery)
+				assert.Equal(t, "_gggg", tc.arango.QueryInVars["rev"])
+
+				patch := tc.arango.QueryInVars["patch"].(map[string]interface{})
+				assert.Equal(t, tc.req.Name, patch["name"])
+				assert.Equal(t, tc.req.SwitchIds, patch["switchIds"])
+			}
+		})
+	}
+}
+
+func TestDeleteGroup(t *testing.T) {
+	tests := []struct {
+		name         string
+		arango       *mockArangoService
+		expectedCode codes.Code
+	}{
+		{
+			"NotFound",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(),
+			},
+			codes.NotFound,
+		},
+		{
+			"Success",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true},
+					ReadDocumentOutDoc: &model.Group{Key: "gggg-gggg"},
+				},
+			},
+			codes.OK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+
+			_, err := service.DeleteGroup(contextWithTenant(testTenantID), &proto.DeleteGroupRequest{Id: "gggg-gggg"})
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			if tc.expectedCode == codes.OK {
+				assert.Equal(t, queryRemoveGroup, tc.arango.QueryInQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/moorara/microservices-demo/services/switch/pkg/sensor"
+)
+
+var operatorDescriptions = map[proto.Interlock_Operator]string{
+	proto.Interlock_GREATER_THAN:     "greater than",
+	proto.Interlock_GREATER_OR_EQUAL: "greater than or equal to",
+	proto.Interlock_LESS_THAN:        "less than",
+	proto.Interlock_LESS_OR_EQUAL:    "less than or equal to",
+	proto.Interlock_EQUAL:            "equal to",
+	proto.Interlock_NOT_EQUAL:        "not equal to",
+}
+
+func interlocksToProto(interlocks []model.Interlock) []*proto.Interlock {
+	if len(interlocks) == 0 {
+		return nil
+	}
+
+	out := make([]*proto.Interlock, len(interlocks))
+	for i, in := range interlocks {
+		out[i] = &proto.Interlock{
+			Name:     in.Name,
+			States:   in.States,
+			SensorId: in.SensorID,
+			Operator: proto.Interlock_Operator(proto.Interlock_Operator_value[in.Operator]),
+			Limit:    proto.Interlock_Limit(proto.Interlock_Limit_value[in.Limit]),
+			Value:    in.Value,
+		}
+	}
+
+	return out
+}
+
+func interlocksFromProto(interlocks []*proto.Interlock) []model.Interlock {
+	if len(interlocks) == 0 {
+		return nil
+	}
+
+	out := make([]model.Interlock, len(interlocks))
+	for i, in := range interlocks {
+		out[i] = model.Interlock{
+			Name:     in.GetName(),
+			States:   in.GetStates(),
+			SensorID: in.GetSensorId(),
+			Operator: in.GetOperator().String(),
+			Limit:    in.GetLimit().String(),
+			Value:    in.GetValue(),
+		}
+	}
+
+	return out
+}
+
+// validateInterlocks checks that the interlocks of a switch have unique names, a sensor and known operators and limits, and block states of the switch
+func validateInterlocks(violations *fieldViolations, field string, states []string, interlocks []*proto.Interlock) {
+	names := map[string]bool{}
+	for _, in := range interlocks {
+		if in.GetName() == "" {
+			violations.add(field, "interlock name is required")
+		} else if names[in.GetName()] {
+			violations.add(field, fmt.Sprintf("interlock %q is listed more than once", in.GetName()))
+		}
+		names[in.GetName()] = true
+
+		if in.GetSensorId() == "" {
+			violations.add(field, fmt.Sprintf("interlock %q requires a sensor id", in.GetName()))
+		}
+		if _, ok := proto.Interlock_Operator_name[int32(in.GetOperator())]; !ok {
+			violations.add(field, fmt.Sprintf("interlock %q has an unknown operator %d", in.GetName(), in.GetOperator()))
+		}
+		if _, ok := proto.Interlock_Limit_name[int32(in.GetLimit())]; !ok {
+			violations.add(field, fmt.Sprintf("interlock %q has an unknown limit %d", in.GetName(), in.GetLimit()))
+		}
+
+		for _, state := range in.GetStates() {
+			if !hasState(states, state) {
+				violations.add(field, fmt.Sprintf("state %q is not one of the switch states", state))
+			}
+		}
+	}
+}
+
+// compare applies the operator of an interlock to a reading and a limit
+func compare(op proto.Interlock_Operator, reading, limit float64) bool {
+	switch op {
+	case proto.Interlock_GREATER_THAN:
+		return reading > limit
+	case proto.Interlock_GREATER_OR_EQUAL:
+		return reading >= limit
+	case proto.Interlock_LESS_THAN:
+		return reading < limit
+	case proto.Interlock_LESS_OR_EQUAL:
+		return reading <= limit
+	case proto.Interlock_EQUAL:
+		return reading == limit
+	case proto.Interlock_NOT_EQUAL:
+		return reading != limit
+	default:
+		return false
+	}
+}
+
+// describeLimit returns the limit of an interlock for a reading and how it is described
+func describeLimit(in model.Interlock, r *sensor.Reading) (float64, string) {
+	switch in.Limit {
+	case proto.Interlock_MIN_SAFE.String():
+		return r.MinSafe, fmt.Sprintf("the minimum safe value %g", r.MinSafe)
+	case proto.Interlock_MAX_SAFE.String():
+		return r.MaxSafe, fmt.Sprintf("the maximum safe value %g", r.MaxSafe)
+	default:
+		return in.Value, fmt.Sprintf("%g", in.Value)
+	}
+}
+
+// checkInterlocks returns a FailedPrecondition status and the names of the interlocks of a switch that block changing it to a state.
+// A switch can always be set to its current state.
+// An interlock whose sensor cannot be read blocks the change, so the changes are only made while they are known to be safe.
+func (s *SwitchService) checkInterlocks(ctx context.Context, tenantID string, sw *model.Switch, state string) ([]string, error) {
+	if state == sw.State {
+		return nil, nil
+	}
+
+	var names []string
+	var violations preconditionViolations
+	readings := map[string]*sensor.Reading{}
+
+	for _, in := range sw.Interlocks {
+		if len(in.States) > 0 && !hasState(in.States, state) {
+			continue
+		}
+
+		r, ok := readings[in.SensorID]
+		if !ok {
+			var err error
+			if s.sensors == nil {
+				err = sensor.ErrUnavailable
+			} else {
+				r, err = s.sensors.Read(ctx, tenantID, in.SensorID)
+			}
+
+			if err != nil {
+				names = append(names, in.Name)
+				violations.add("INTERLOCK", fmt.Sprintf("interlock %q blocks state %q: cannot read sensor %q: %s", in.Name, state, in.SensorID, err))
+				continue
+			}
+
+			readings[in.SensorID] = r
+		}
+
+		op := proto.Interlock_Operator(proto.Interlock_Operator_value[in.Operator])
+		limit, description := describeLimit(in, r)
+		if compare(op, r.Value, limit) {
+			names = append(names, in.Name)
+			violations.add("INTERLOCK", fmt.Sprintf("interlock %q blocks state %q: sensor %q reads %g which is %s %s", in.Name, state, in.SensorID, r.Value, operatorDescriptions[op], description))
+		}
+	}
+
+	return names, violations.err(switchToProto(sw))
+}
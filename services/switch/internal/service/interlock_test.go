@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/auth"
+	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/moorara/microservices-demo/services/switch/pkg/sensor"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+// testValve is a valve that must not open while the pressure is above its maximum safe value or the level is below 10
+func testValve() *model.Switch {
+	return &model.Switch{
+		TenantID: testTenantID,
+		Key:      "aaaa-aaaa",
+		Rev:      "_aaaa",
+		SiteID:   "site-a",
+		State:    "CLOSED",
+		States:   []string{"CLOSED", "OPEN"},
+		Interlocks: []model.Interlock{
+			{Name: "overpressure", States: []string{"OPEN"}, SensorID: "pressure", Operator: "GREATER_THAN", Limit: "MAX_SAFE"},
+			{Name: "low-level", SensorID: "level", Operator: "LESS_THAN", Limit: "VALUE", Value: 10},
+		},
+	}
+}
+
+func TestInterlocksProto(t *testing.T) {
+	interlocks := []*proto.Interlock{
+		{Name: "overpressure", States: []string{"OPEN"}, SensorId: "pressure", Operator: proto.Interlock_GREATER_THAN, Limit: proto.Interlock_MAX_SAFE},
+		{Name: "low-level", SensorId: "level", Operator: proto.Interlock_LESS_THAN, Value: 10},
+	}
+
+	docs := interlocksFromProto(interlocks)
+	assert.Equal(t, testValve().Interlocks, docs)
+	assert.Equal(t, interlocks, interlocksToProto(docs))
+
+	assert.Nil(t, interlocksFromProto(nil))
+	assert.Nil(t, interlocksToProto(nil))
+}
+
+func TestValidateInterlocks(t *testing.T) {
+	tests := []struct {
+		name               string
+		interlocks         []*proto.Interlock
+		expectedViolations int
+	}{
+		{"None", nil, 0},
+		{"Valid", []*proto.Interlock{
+			{Name: "overpressure", States: []string{"OPEN"}, SensorId: "pressure", Limit: proto.Interlock_MAX_SAFE},
+			{Name: "low-level", SensorId: "level", Operator: proto.Interlock_LESS_THAN, Value: 10},
+		}, 0},
+		{"NoName", []*proto.Interlock{{SensorId: "pressure"}}, 1},
+		{"DuplicateName", []*proto.Interlock{{Name: "a", SensorId: "pressure"}, {Name: "a", SensorId: "level"}}, 1},
+		{"NoSensor", []*proto.Interlock{{Name: "a"}}, 1},
+		{"UnknownOperator", []*proto.Interlock{{Name: "a", SensorId: "pressure", Operator: 42}}, 1},
+		{"UnknownLimit", []*proto.Interlock{{Name: "a", SensorId: "pressure", Limit: 42}}, 1},
+		{"UnknownState", []*proto.Interlock{{Name: "a", SensorId: "pressure", States: []string{"OPEN", "HALF"}}}, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var violations fieldViolations
+			validateInterlocks(&violations, "interlocks", []string{"CLOSED", "OPEN"}, tc.interlocks)
+
+			assert.Len(t, violations, tc.expectedViolations)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		op       proto.Interlock_Operator
+		reading  float64
+		expected []bool
+	}{
+		{proto.Interlock_GREATER_THAN, 1, []bool{false, false, true}},
+		{proto.Interlock_GREATER_OR_EQUAL, 1, []bool{false, true, true}},
+		{proto.Interlock_LESS_THAN, 1, []bool{true, false, false}},
+		{proto.Interlock_LESS_OR_EQUAL, 1, []bool{true, true, false}},
+		{proto.Interlock_EQUAL, 1, []bool{false, true, false}},
+		{proto.Interlock_NOT_EQUAL, 1, []bool{true, false, true}},
+		{proto.Interlock_Operator(42), 1, []bool{false, false, false}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.op.String(), func(t *testing.T) {
+			for i, limit := range []float64{2, 1, 0} {
+				assert.Equal(t, tc.expected[i], compare(tc.op, tc.reading, limit), "limit %g", limit)
+			}
+		})
+	}
+}
+
+func TestCheckInterlocks(t *testing.T) {
+	safe := map[string]*sensor.Reading{
+		"pressure": {SensorID: "pressure", Value: 3, MaxSafe: 5},
+		"level":    {SensorID: "level", Value: 50},
+	}
+
+	unsafe := map[string]*sensor.Reading{
+		"pressure": {SensorID: "pressure", Value: 6, MaxSafe: 5},
+		"level":    {SensorID: "level", Value: 5},
+	}
+
+	tests := []struct {
+		name                 string
+		sensors              *mockSensorClient
+		state                string
+		expectedNames        []string
+		expectedDescriptions []string
+		expectedSensorIDs    []string
+	}{
+		{
+			"CurrentState",
+			&mockSensorClient{ReadOutReadings: unsafe},
+			"CLOSED",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"Safe",
+			&mockSensorClient{ReadOutReadings: safe},
+			"OPEN",
+			nil,
+			nil,
+			[]string{"pressure", "level"},
+		},
+		{
+			"Unsafe",
+			&mockSensorClient{ReadOutReadings: unsafe},
+			"OPEN",
+			[]string{"overpressure", "low-level"},
+			[]string{
+				`interlock "overpressure" blocks state "OPEN": sensor "pressure" reads 6 which is greater than the maximum safe value 5`,
+				`interlock "low-level" blocks state "OPEN": sensor "level" reads 5 which is less than 10`,
+			},
+			[]string{"pressure", "level"},
+		},
+		{
+			"Unavailable",
+			&mockSensorClient{ReadOutError: sensor.ErrUnavailable},
+			"OPEN",
+			[]string{"overpressure", "low-level"},
+			[]string{
+				`interlock "overpressure" blocks state "OPEN": cannot read sensor "pressure": sensor service unavailable`,
+				`interlock "low-level" blocks state "OPEN": cannot read sensor "level": sensor service unavailable`,
+			},
+			[]string{"pressure", "level"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &SwitchService{sensors: tc.sensors}
+			sw := testValve()
+			sw.State = "CLOSED"
+
+			names, err := service.checkInterlocks(context.Background(), testTenantID, sw, tc.state)
+
+			assert.Equal(t, tc.expectedNames, names)
+			assert.Equal(t, tc.expectedSensorIDs, tc.sensors.ReadInSensorIDs)
+
+			if tc.expectedNames == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+			pf := status.Convert(err).Details()[0].(*errdetails.PreconditionFailure)
+			for i, v := range pf.Violations {
+				assert.Equal(t, "INTERLOCK", v.Type)
+				assert.Equal(t, tc.expectedDescriptions[i], v.Description)
+			}
+		})
+	}
+}
+
+func TestCheckInterlocksNoSensorService(t *testing.T) {
+	service := &SwitchService{}
+
+	names, err := service.checkInterlocks(context.Background(), testTenantID, testValve(), "OPEN")
+
+	assert.Equal(t, []string{"overpressure", "low-level"}, names)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestSetSwitchInterlocks(t *testing.T) {
+	unsafe := map[string]*sensor.Reading{
+		"pressure": {SensorID: "pressure", Value: 6, MaxSafe: 5},
+		"level":    {SensorID: "level", Value: 50},
+	}
+
+	operator := metadata.NewIncomingContext(context.Background(), metadata.Pairs(tenantMetadataKey, testTenantID, callerMetadataKey, "operator"))
+
+	tests := []struct {
+		name               string
+		ctx                context.Context
+		req                *proto.SetSwitchRequest
+		expectedCode       codes.Code
+		expectedOverridden []string
+	}{
+		{
+			"Blocked",
+			operator,
+			&proto.SetSwitchRequest{Id: "aaaa-aaaa", State: "OPEN", Reason: "drain"},
+			codes.FailedPrecondition,
+			nil,
+		},
+		{
+			"NotBlocked",
+			operator,
+			&proto.SetSwitchRequest{Id: "aaaa-aaaa", State: "CLOSED", Reason: "drain", OverrideInterlocks: true},
+			codes.OK,
+			nil,
+		},
+		{
+			"OverrideWithoutReason",
+			operator,
+			&proto.SetSwitchRequest{Id: "aaaa-aaaa", State: "OPEN", OverrideInterlocks: true},
+			codes.InvalidArgument,
+			nil,
+		},
+		{
+			"OverrideNotPermitted",
+			auth.NewContext(operator, &auth.Grant{}),
+			&proto.SetSwitchRequest{Id: "aaaa-aaaa", State: "OPEN", Reason: "drain", OverrideInterlocks: true},
+			codes.PermissionDenied,
+			nil,
+		},
+		{
+			"Override",
+			operator,
+			&proto.SetSwitchRequest{Id: "aaaa-aaaa", State: "OPEN", Reason: "drain", OverrideInterlocks: true},
+			codes.OK,
+			[]string{"overpressure"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			arango := &mockArangoService{
+				ReadDocumentOutDoc:    testValve(),
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+			}
+
+			service := &SwitchService{
+				arango:  arango,
+				sensors: &mockSensorClient{ReadOutReadings: unsafe},
+				logger:  log.NewVoidLogger(),
+				metrics: metrics.Mock(),
+				tracer:  mocktracer.New(),
+			}
+
+			resp, err := service.SetSwitch(tc.ctx, tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+
+			if tc.expectedCode != codes.OK {
+				assert.Nil(t, resp)
+				assert.Nil(t, arango.UpdateDocumentInDoc)
+				return
+			}
+
+			assert.Equal(t, &proto.SetSwitchResponse{Revision: "_bbbb", PreviousState: "CLOSED"}, resp)
+
+			change := arango.CreateHistoryDocumentInDoc.(*model.SwitchStateChange)
+			assert.Empty(t, change.Caller)
+			assert.Equal(t, "operator", change.ClaimedCaller)
+			assert.Equal(t, "drain", change.Reason)
+			assert.Equal(t, tc.expectedOverridden, change.OverriddenInterlocks)
+		})
+	}
+}
@@ -2,13 +2,15 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
 	arango "github.com/arangodb/go-driver"
-	"github.com/moorara/microservices-demo/services/switch/internal/proto"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/moorara/microservices-demo/services/switch/pkg/sensor"
 )
 
 // mockCloser is a mock implementation of io.Closer
@@ -85,17 +87,109 @@ func (m *mockServerStream) RecvMsg(msg interface{}) error {
 type mockGetSwitchesServer struct {
 	grpc.ServerStream
 
-	SendCalled   bool
-	SendInSwitch *proto.Switch
-	SendOutError error
+	SendCalled    bool
+	SendCallCount int
+	SendInSwitch  *proto.Switch
+	SendOutError  error
 }
 
 func (m *mockGetSwitchesServer) Send(sw *proto.Switch) error {
 	m.SendCalled = true
+	m.SendCallCount++
 	m.SendInSwitch = sw
 	return m.SendOutError
 }
 
+// mockWatchSwitchesServer mocks proto.SwitchService_WatchSwitchesServer
+type mockWatchSwitchesServer struct {
+	grpc.ServerStream
+
+	SendInEvents []*proto.SwitchEvent
+	SendOutError error
+
+	// Cancel is called once CancelAfter events are sent
+	Cancel      context.CancelFunc
+	CancelAfter int
+}
+
+func (m *mockWatchSwitchesServer) Send(e *proto.SwitchEvent) error {
+	m.SendInEvents = append(m.SendInEvents, e)
+	if m.Cancel != nil && len(m.SendInEvents) == m.CancelAfter {
+		m.Cancel()
+	}
+	return m.SendOutError
+}
+
+// mockGetSchedulesServer mocks proto.SwitchService_GetSchedulesServer
+type mockGetSchedulesServer struct {
+	grpc.ServerStream
+
+	SendInSchedules []*proto.Schedule
+	SendOutError    error
+}
+
+func (m *mockGetSchedulesServer) Send(sc *proto.Schedule) error {
+	m.SendInSchedules = append(m.SendInSchedules, sc)
+	return m.SendOutError
+}
+
+// mockGetGroupsServer mocks proto.SwitchService_GetGroupsServer
+type mockGetGroupsServer struct {
+	grpc.ServerStream
+
+	SendInGroups []*proto.Group
+	SendOutError error
+}
+
+func (m *mockGetGroupsServer) Send(g *proto.Group) error {
+	m.SendInGroups = append(m.SendInGroups, g)
+	return m.SendOutError
+}
+
+// mockGetScenesServer mocks proto.SwitchService_GetScenesServer
+type mockGetScenesServer struct {
+	grpc.ServerStream
+
+	SendInScenes []*proto.Scene
+	SendOutError error
+}
+
+func (m *mockGetScenesServer) Send(sc *proto.Scene) error {
+	m.SendInScenes = append(m.SendInScenes, sc)
+	return m.SendOutError
+}
+
+// mockInstallSwitchesServer mocks proto.SwitchService_InstallSwitchesServer
+type mockInstallSwitchesServer struct {
+	grpc.ServerStream
+
+	RecvOutReqs  []*proto.InstallSwitchesRequest
+	RecvOutError error
+
+	SendAndCloseCalled bool
+	SendAndCloseInResp *proto.InstallSwitchesResponse
+	SendAndCloseOutErr error
+}
+
+func (m *mockInstallSwitchesServer) Recv() (*proto.InstallSwitchesRequest, error) {
+	if m.RecvOutError != nil {
+		return nil, m.RecvOutError
+	}
+	if len(m.RecvOutReqs) == 0 {
+		return nil, io.EOF
+	}
+
+	req := m.RecvOutReqs[0]
+	m.RecvOutReqs = m.RecvOutReqs[1:]
+	return req, nil
+}
+
+func (m *mockInstallSwitchesServer) SendAndClose(resp *proto.InstallSwitchesResponse) error {
+	m.SendAndCloseCalled = true
+	m.SendAndCloseInResp = resp
+	return m.SendAndCloseOutErr
+}
+
 // mockArangoCursor is a mock implementation of arango.Cursor
 type mockArangoCursor struct {
 	io.Closer
@@ -106,9 +200,14 @@ type mockArangoCursor struct {
 	ReadDocumentCalled    bool
 	ReadDocumentInContext context.Context
 	ReadDocumentInDoc     interface{}
+	ReadDocumentOutDoc    interface{}
 	ReadDocumentOutMeta   arango.DocumentMeta
 	ReadDocumentOutError  error
 
+	// ReadDocumentOutDocs are read in turn instead of ReadDocumentOutDoc when HasMoreOutResults is not set
+	ReadDocumentCallCount int
+	ReadDocumentOutDocs   []interface{}
+
 	CountCalled    bool
 	CountOutResult int64
 
@@ -116,7 +215,19 @@ type mockArangoCursor struct {
 	StatisticsOutResult arango.QueryStatistics
 }
 
+// newMockCursor creates a cursor reading documents in turn
+func newMockCursor(docs ...interface{}) *mockArangoCursor {
+	return &mockArangoCursor{
+		Closer:              &mockCloser{},
+		ReadDocumentOutDocs: docs,
+	}
+}
+
 func (m *mockArangoCursor) HasMore() bool {
+	if m.HasMoreOutResults == nil {
+		return m.ReadDocumentCallCount < len(m.ReadDocumentOutDocs)
+	}
+
 	i := m.HasMoreCallCount % len(m.HasMoreOutResults)
 	m.HasMoreCallCount++
 	return m.HasMoreOutResults[i]
@@ -126,6 +237,17 @@ func (m *mockArangoCursor) ReadDocument(ctx context.Context, doc interface{}) (a
 	m.ReadDocumentCalled = true
 	m.ReadDocumentInContext = ctx
 	m.ReadDocumentInDoc = doc
+
+	out := m.ReadDocumentOutDoc
+	if m.ReadDocumentCallCount < len(m.ReadDocumentOutDocs) {
+		out = m.ReadDocumentOutDocs[m.ReadDocumentCallCount]
+	}
+	m.ReadDocumentCallCount++
+
+	if out != nil {
+		data, _ := json.Marshal(out)
+		_ = json.Unmarshal(data, doc)
+	}
 	return m.ReadDocumentOutMeta, m.ReadDocumentOutError
 }
 
@@ -139,13 +261,20 @@ func (m *mockArangoCursor) Statistics() arango.QueryStatistics {
 	return m.StatisticsOutResult
 }
 
+// mockQueryResult is the result of a query
+type mockQueryResult struct {
+	Cursor arango.Cursor
+	Error  error
+}
+
 // mockArangoService is a mock implementation of service.ArangoService
 type mockArangoService struct {
-	ConnectCalled       bool
-	ConnectInContext    context.Context
-	ConnectInDatabase   string
-	ConnectInCollection string
-	ConnectOutError     error
+	ConnectCalled              bool
+	ConnectInContext           context.Context
+	ConnectInDatabase          string
+	ConnectInCollection        string
+	ConnectInHistoryCollection string
+	ConnectOutError            error
 
 	QueryCalled    bool
 	QueryInContext context.Context
@@ -154,6 +283,10 @@ type mockArangoService struct {
 	QueryOutCursor arango.Cursor
 	QueryOutError  error
 
+	// QueryOutResults answer the queries found in them instead of QueryOutCursor and QueryOutError
+	QueryInQueries  []string
+	QueryOutResults map[string]mockQueryResult
+
 	CreateDocumentCalled    bool
 	CreateDocumentInContext context.Context
 	CreateDocumentInDoc     interface{}
@@ -164,6 +297,7 @@ type mockArangoService struct {
 	ReadDocumentInContext context.Context
 	ReadDocumentInKey     string
 	ReadDocumentInDoc     interface{}
+	ReadDocumentOutDoc    interface{}
 	ReadDocumentOutMeta   arango.DocumentMeta
 	ReadDocumentOutError  error
 
@@ -179,13 +313,33 @@ type mockArangoService struct {
 	RemoveDocumentInKey     string
 	RemoveDocumentOutMeta   arango.DocumentMeta
 	RemoveDocumentOutError  error
+
+	CreateHistoryDocumentCalled    bool
+	CreateHistoryDocumentInContext context.Context
+	CreateHistoryDocumentInDoc     interface{}
+	CreateHistoryDocumentOutMeta   arango.DocumentMeta
+	CreateHistoryDocumentOutError  error
+
+	EnsureCollectionsCalled    bool
+	EnsureCollectionsInContext context.Context
+	EnsureCollectionsInNames   []string
+	EnsureCollectionsOutError  error
+
+	TransactionCalled    bool
+	TransactionInContext context.Context
+	TransactionOutError  error
+
+	PingCalled    bool
+	PingInContext context.Context
+	PingOutError  error
 }
 
-func (m *mockArangoService) Connect(ctx context.Context, database, collection string) error {
+func (m *mockArangoService) Connect(ctx context.Context, database, collection, historyCollection string) error {
 	m.ConnectCalled = true
 	m.ConnectInContext = ctx
 	m.ConnectInDatabase = database
 	m.ConnectInCollection = collection
+	m.ConnectInHistoryCollection = historyCollection
 	return m.ConnectOutError
 }
 
@@ -194,6 +348,10 @@ func (m *mockArangoService) Query(ctx context.Context, query string, vars map[st
 	m.QueryInContext = ctx
 	m.QueryInQuery = query
 	m.QueryInVars = vars
+	m.QueryInQueries = append(m.QueryInQueries, query)
+	if result, ok := m.QueryOutResults[query]; ok {
+		return result.Cursor, result.Error
+	}
 	return m.QueryOutCursor, m.QueryOutError
 }
 
@@ -209,6 +367,10 @@ func (m *mockArangoService) ReadDocument(ctx context.Context, key string, doc in
 	m.ReadDocumentInContext = ctx
 	m.ReadDocumentInKey = key
 	m.ReadDocumentInDoc = doc
+	if m.ReadDocumentOutDoc != nil {
+		data, _ := json.Marshal(m.ReadDocumentOutDoc)
+		_ = json.Unmarshal(data, doc)
+	}
 	return m.ReadDocumentOutMeta, m.ReadDocumentOutError
 }
 
@@ -226,3 +388,103 @@ func (m *mockArangoService) RemoveDocument(ctx context.Context, key string) (ara
 	m.RemoveDocumentInKey = key
 	return m.RemoveDocumentOutMeta, m.RemoveDocumentOutError
 }
+
+func (m *mockArangoService) CreateHistoryDocument(ctx context.Context, doc interface{}) (arango.DocumentMeta, error) {
+	m.CreateHistoryDocumentCalled = true
+	m.CreateHistoryDocumentInContext = ctx
+	m.CreateHistoryDocumentInDoc = doc
+	return m.CreateHistoryDocumentOutMeta, m.CreateHistoryDocumentOutError
+}
+
+func (m *mockArangoService) EnsureCollections(ctx context.Context, names ...string) error {
+	m.EnsureCollectionsCalled = true
+	m.EnsureCollectionsInContext = ctx
+	m.EnsureCollectionsInNames = names
+	return m.EnsureCollectionsOutError
+}
+
+func (m *mockArangoService) Ping(ctx context.Context) error {
+	m.PingCalled = true
+	m.PingInContext = ctx
+	return m.PingOutError
+}
+
+func (m *mockArangoService) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.TransactionCalled = true
+	m.TransactionInContext = ctx
+	if m.TransactionOutError != nil {
+		return m.TransactionOutError
+	}
+	return fn(ctx)
+}
+
+// mockSiteClient is a mock implementation of site.Client
+type mockSiteClient struct {
+	ValidateCalled    bool
+	ValidateInContext context.Context
+	ValidateInSiteID  string
+	ValidateOutError  error
+}
+
+func (m *mockSiteClient) Validate(ctx context.Context, siteID string) error {
+	m.ValidateCalled = true
+	m.ValidateInContext = ctx
+	m.ValidateInSiteID = siteID
+	return m.ValidateOutError
+}
+
+// mockSensorClient is a mock implementation of sensor.Client
+type mockSensorClient struct {
+	ReadInSensorIDs []string
+	ReadOutReadings map[string]*sensor.Reading
+	ReadOutError    error
+}
+
+func (m *mockSensorClient) Read(ctx context.Context, tenantID, sensorID string) (*sensor.Reading, error) {
+	m.ReadInSensorIDs = append(m.ReadInSensorIDs, sensorID)
+	if m.ReadOutError != nil {
+		return nil, m.ReadOutError
+	}
+	if r, ok := m.ReadOutReadings[sensorID]; ok {
+		return r, nil
+	}
+	return nil, sensor.ErrUnknownSensor
+}
+
+// mockNATSConnection is a mock implementation of queue.NATSConnection
+type mockNATSConnection struct {
+	CloseCalled bool
+
+	FlushCalled   bool
+	FlushOutError error
+
+	IsClosedOutResult bool
+
+	PublishInSubjects []string
+	PublishInData     []string
+	// PublishOutErrors are returned by the publications in turn and the publications after them succeed
+	PublishOutErrors []error
+}
+
+func (m *mockNATSConnection) Close() {
+	m.CloseCalled = true
+}
+
+func (m *mockNATSConnection) Flush() error {
+	m.FlushCalled = true
+	return m.FlushOutError
+}
+
+func (m *mockNATSConnection) IsClosed() bool {
+	return m.IsClosedOutResult
+}
+
+func (m *mockNATSConnection) Publish(subject string, data []byte) error {
+	i := len(m.PublishInSubjects)
+	m.PublishInSubjects = append(m.PublishInSubjects, subject)
+	m.PublishInData = append(m.PublishInData, string(data))
+	if i < len(m.PublishOutErrors) {
+		return m.PublishOutErrors[i]
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/internal/queue"
+	"github.com/moorara/microservices-demo/services/switch/pkg/event"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/opentracing/opentracing-go"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+const (
+	// OutboxCollection is the Arango collection the switch events waiting to be published to NATS are stored in
+	OutboxCollection = "switch_outbox"
+
+	defaultOutboxPeriod    = time.Second
+	defaultOutboxBatchSize = 100
+	outboxClaimTimeout     = 30 * time.Second
+
+	queryInsertOutbox = `INSERT @event INTO switch_outbox`
+	queryClaimOutbox  = `FOR e IN switch_outbox FILTER e.claimedUntil < @now SORT e.time, e._key LIMIT @count UPDATE e WITH { claimedUntil: @until } IN switch_outbox RETURN NEW`
+	queryRemoveOutbox = `FOR key IN @keys REMOVE key IN switch_outbox OPTIONS { ignoreErrors: true }`
+)
+
+type (
+	// OutboxRelay publishes the switch events of the outbox to NATS and removes them once NATS has received them
+	OutboxRelay struct {
+		arango    ArangoService
+		connect   func() (queue.NATSConnection, error)
+		conn      queue.NATSConnection
+		logger    *log.Logger
+		batchSize int
+		now       func() time.Time
+	}
+)
+
+// spanContext returns the trace context of a call as a JSON OpenTracing text map and an empty string if the call is not traced
+func (s *SwitchService) spanContext(ctx context.Context) string {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil || s.tracer == nil {
+		return ""
+	}
+
+	carrier := opentracing.TextMapCarrier{}
+	if err := s.tracer.Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+		return ""
+	}
+
+	data, err := json.Marshal(carrier)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+// enqueue writes a switch event with the trace context of the call to the outbox, from which the outbox relay publishes it to NATS.
+// Nothing is written if the outbox is disabled, and in a transaction the event is only kept if the change is.
+func (s *SwitchService) enqueue(ctx context.Context, req interface{}, op, tenantID, subject string, sw *proto.Switch, previousState string) error {
+	if !s.outbox {
+		return nil
+	}
+
+	msg := &event.Message{
+		ID:            uuid.New().String(),
+		Kind:          subject,
+		Span:          s.spanContext(ctx),
+		TenantID:      tenantID,
+		Time:          time.Now().UnixNano(),
+		PreviousState: previousState,
+	}
+
+	if err := msg.EncodeSwitch(sw); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	vars := map[string]interface{}{
+		"event": &model.OutboxEvent{
+			Key:     msg.ID,
+			Subject: subject,
+			Data:    string(data),
+			Time:    msg.Time,
+		},
+	}
+
+	s.exec(ctx, req, op+"_InsertOutbox", queryInsertOutbox, func() error {
+		var cursor arango.Cursor
+		if cursor, err = s.arango.Query(ctx, queryInsertOutbox, vars); err != nil {
+			return err
+		}
+		return cursor.Close()
+	})
+
+	return err
+}
+
+// NewOutboxRelay creates a new outbox relay
+// connect connects to NATS and is called again whenever the connection is closed, so NATS can be down when the relay starts.
+func NewOutboxRelay(arango ArangoService, connect func() (queue.NATSConnection, error), logger *log.Logger) *OutboxRelay {
+	return &OutboxRelay{
+		arango:    arango,
+		connect:   connect,
+		logger:    logger,
+		batchSize: defaultOutboxBatchSize,
+		now:       time.Now,
+	}
+}
+
+// Run publishes the events of the outbox every period until the context is done and then closes the connection to NATS
+func (r *OutboxRelay) Run(ctx context.Context, period time.Duration) {
+	if period <= 0 {
+		period = defaultOutboxPeriod
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	defer func() {
+		if r.conn != nil {
+			r.conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Full batches are followed by the next one right away until the outbox is drained
+		for {
+			n, err := r.relay(ctx)
+			if err != nil {
+				r.logger.Error("message", fmt.Sprintf("Failed to publish switch events: %s", err))
+			}
+			if err != nil || n < r.batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+}
+
+// relay publishes a batch of the oldest events of the outbox and returns how many it published.
+// The events are claimed for a while, so other replicas leave them until then, and only removed once NATS has received them.
+// An event whose removal fails is published again, so events are delivered at least once.
+func (r *OutboxRelay) relay(ctx context.Context) (int, error) {
+	if r.conn == nil || r.conn.IsClosed() {
+		conn, err := r.connect()
+		if err != nil {
+			return 0, fmt.Errorf("cannot connect to NATS: %s", err)
+		}
+		r.conn = conn
+	}
+
+	now := r.now()
+	vars := map[string]interface{}{
+		"now":   now.UnixNano(),
+		"until": now.Add(outboxClaimTimeout).UnixNano(),
+		"count": r.batchSize,
+	}
+
+	cursor, err := r.arango.Query(ctx, queryClaimOutbox, vars)
+	if err != nil {
+		// Another replica claimed the events at the same time
+		if arango.IsConflict(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer cursor.Close()
+
+	events := []*model.OutboxEvent{}
+	for cursor.HasMore() {
+		doc := &model.OutboxEvent{}
+		if _, err := cursor.ReadDocument(ctx, doc); err != nil {
+			return 0, err
+		}
+		events = append(events, doc)
+	}
+
+	// The events after one that fails are left for the next run, so they are published in order
+	keys := []string{}
+	var perr error
+	for _, e := range events {
+		if perr = r.conn.Publish(e.Subject, []byte(e.Data)); perr != nil {
+			break
+		}
+		keys = append(keys, e.Key)
+	}
+
+	if len(keys) == 0 {
+		return 0, perr
+	}
+
+	if err := r.conn.Flush(); err != nil {
+		return 0, err
+	}
+
+	vars = map[string]interface{}{
+		"keys": keys,
+	}
+
+	cursor, err = r.arango.Query(ctx, queryRemoveOutbox, vars)
+	if err != nil {
+		return len(keys), err
+	}
+	cursor.Close()
+
+	return len(keys), perr
+}
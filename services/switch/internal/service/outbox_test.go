@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/broker"
+	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/internal/queue"
+	"github.com/moorara/microservices-demo/services/switch/pkg/event"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+// outboxMessage returns the message of the event written to the outbox by a query
+func outboxMessage(t *testing.T, vars map[string]interface{}) *event.Message {
+	doc := vars["event"].(*model.OutboxEvent)
+
+	msg := new(event.Message)
+	assert.NoError(t, json.Unmarshal([]byte(doc.Data), msg))
+	assert.Equal(t, doc.Key, msg.ID)
+	assert.Equal(t, doc.Subject, msg.Kind)
+	assert.Equal(t, doc.Time, msg.Time)
+
+	return msg
+}
+
+func TestEnqueue(t *testing.T) {
+	tests := []struct {
+		name          string
+		outbox        bool
+		arango        *mockArangoService
+		traced        bool
+		expectedError string
+		expectedQuery bool
+	}{
+		{
+			"Disabled",
+			false,
+			&mockArangoService{},
+			true,
+			"",
+			false,
+		},
+		{
+			"QueryFail",
+			true,
+			&mockArangoService{QueryOutError: errors.New("database error")},
+			true,
+			"database error",
+			true,
+		},
+		{
+			"NotTraced",
+			true,
+			&mockArangoService{QueryOutCursor: &mockArangoCursor{Closer: &mockCloser{}}},
+			false,
+			"",
+			true,
+		},
+		{
+			"Success",
+			true,
+			&mockArangoService{QueryOutCursor: &mockArangoCursor{Closer: &mockCloser{}}},
+			true,
+			"",
+			true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			service := &SwitchService{
+				arango:  tc.arango,
+				outbox:  tc.outbox,
+				logger:  log.NewVoidLogger(),
+				metrics: metrics.Mock(),
+				tracer:  tracer,
+			}
+
+			ctx := context.Background()
+			if tc.traced {
+				ctx = opentracing.ContextWithSpan(ctx, tracer.StartSpan("/proto.SwitchService/SetSwitch"))
+			}
+
+			sw := &proto.Switch{Id: "aaaa-aaaa", SiteId: "1111-1111", State: "ON", States: []string{"OFF", "ON"}}
+			err := service.enqueue(ctx, nil, "SetSwitch", testTenantID, event.SubjectStateChanged, sw, "OFF")
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedQuery, tc.arango.QueryCalled)
+			if !tc.expectedQuery || tc.expectedError != "" {
+				return
+			}
+
+			assert.Equal(t, queryInsertOutbox, tc.arango.QueryInQuery)
+			msg := outboxMessage(t, tc.arango.QueryInVars)
+			assert.Equal(t, event.SubjectStateChanged, msg.Kind)
+			assert.Equal(t, testTenantID, msg.TenantID)
+			assert.Equal(t, "OFF", msg.PreviousState)
+
+			decoded, err := msg.DecodeSwitch()
+			assert.NoError(t, err)
+			assert.Equal(t, "aaaa-aaaa", decoded.Id)
+
+			if !tc.traced {
+				assert.Empty(t, msg.Span)
+				return
+			}
+
+			// The trace context can be extracted by the consumers of the event
+			carrier := opentracing.TextMapCarrier{}
+			assert.NoError(t, json.Unmarshal([]byte(msg.Span), &carrier))
+			spanContext, err := tracer.Extract(opentracing.TextMap, carrier)
+			assert.NoError(t, err)
+			assert.NotNil(t, spanContext)
+		})
+	}
+}
+
+func TestSwitchOutbox(t *testing.T) {
+	t.Run("SetSwitch", func(t *testing.T) {
+		arango := &mockArangoService{
+			ReadDocumentOutDoc:    &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "OFF", States: []string{"OFF", "ON"}},
+			UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+			QueryOutCursor:        &mockArangoCursor{Closer: &mockCloser{}},
+		}
+
+		service := &SwitchService{arango: arango, outbox: true, logger: log.NewVoidLogger(), metrics: metrics.Mock(), tracer: mocktracer.New()}
+		_, err := service.SetSwitch(contextWithTenant(testTenantID), &proto.SetSwitchRequest{Id: "aaaa-aaaa", State: "ON"})
+
+		assert.NoError(t, err)
+		assert.True(t, arango.TransactionCalled)
+		msg := outboxMessage(t, arango.QueryInVars)
+		assert.Equal(t, event.SubjectStateChanged, msg.Kind)
+		assert.Equal(t, "OFF", msg.PreviousState)
+	})
+
+	t.Run("SetSwitchFail", func(t *testing.T) {
+		arango := &mockArangoService{
+			ReadDocumentOutDoc:    &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "OFF", States: []string{"OFF", "ON"}},
+			UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+			QueryOutError:         errors.New("database error"),
+		}
+
+		service := &SwitchService{arango: arango, broker: broker.New(0, 0), outbox: true, logger: log.NewVoidLogger(), metrics: metrics.Mock(), tracer: mocktracer.New()}
+		events := recordEvents(service.broker)
+		resp, err := service.SetSwitch(contextWithTenant(testTenantID), &proto.SetSwitchRequest{Id: "aaaa-aaaa", State: "ON"})
+
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.True(t, arango.TransactionCalled)
+		assert.True(t, arango.UpdateDocumentCalled)
+		assert.Equal(t, queryInsertOutbox, arango.QueryInQuery)
+		assert.Empty(t, events())
+	})
+
+	t.Run("InstallSwitch", func(t *testing.T) {
+		arango := &mockArangoService{
+			CreateDocumentOutMeta: arango.DocumentMeta{Key: "aaaa-aaaa", Rev: "_aaaa"},
+			QueryOutCursor:        &mockArangoCursor{Closer: &mockCloser{}},
+		}
+
+		service := &SwitchService{arango: arango, outbox: true, logger: log.NewVoidLogger(), metrics: metrics.Mock(), tracer: mocktracer.New()}
+		_, err := service.InstallSwitch(contextWithTenant(testTenantID), &proto.InstallSwitchRequest{SiteId: "1111-1111", Name: "light", State: "OFF", States: []string{"OFF", "ON"}})
+
+		assert.NoError(t, err)
+		assert.True(t, arango.TransactionCalled)
+		msg := outboxMessage(t, arango.QueryInVars)
+		assert.Equal(t, event.SubjectInstalled, msg.Kind)
+	})
+
+	t.Run("InstallSwitchFail", func(t *testing.T) {
+		arango := &mockArangoService{
+			CreateDocumentOutMeta: arango.DocumentMeta{Key: "aaaa-aaaa", Rev: "_aaaa"},
+			QueryOutError:         errors.New("database error"),
+		}
+
+		service := &SwitchService{arango: arango, broker: broker.New(0, 0), outbox: true, logger: log.NewVoidLogger(), metrics: metrics.Mock(), tracer: mocktracer.New()}
+		events := recordEvents(service.broker)
+		sw, err := service.InstallSwitch(contextWithTenant(testTenantID), &proto.InstallSwitchRequest{SiteId: "1111-1111", Name: "light", State: "OFF", States: []string{"OFF", "ON"}})
+
+		assert.Nil(t, sw)
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.True(t, arango.TransactionCalled)
+		assert.True(t, arango.CreateDocumentCalled)
+		assert.Equal(t, queryInsertOutbox, arango.QueryInQuery)
+		assert.Empty(t, events())
+	})
+
+	t.Run("RemoveSwitchFail", func(t *testing.T) {
+		arango := &mockArangoService{
+			ReadDocumentOutDoc: &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "OFF", States: []string{"OFF", "ON"}},
+			QueryOutError:      errors.New("database error"),
+		}
+
+		service := &SwitchService{arango: arango, broker: broker.New(0, 0), outbox: true, logger: log.NewVoidLogger(), metrics: metrics.Mock(), tracer: mocktracer.New()}
+		events := recordEvents(service.broker)
+		resp, err := service.RemoveSwitch(contextWithTenant(testTenantID), &proto.RemoveSwitchRequest{Id: "aaaa-aaaa"})
+
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.True(t, arango.TransactionCalled)
+		assert.True(t, arango.RemoveDocumentCalled)
+		assert.Equal(t, queryInsertOutbox, arango.QueryInQuery)
+		assert.Empty(t, events())
+	})
+}
+
+func TestNewOutboxRelay(t *testing.T) {
+	r := NewOutboxRelay(&mockArangoService{}, nil, log.NewVoidLogger())
+
+	assert.NotNil(t, r)
+	assert.Equal(t, defaultOutboxBatchSize, r.batchSize)
+	assert.NotNil(t, r.now)
+}
+
+// outboxCursor returns a cursor reading outbox events with the given keys
+func outboxCursor(keys ...string) *mockArangoCursor {
+	docs := make([]interface{}, len(keys))
+	for i, key := range keys {
+		docs[i] = &model.OutboxEvent{Key: key, Subject: event.SubjectStateChanged, Data: `{"id":"` + key + `"}`}
+	}
+	return newMockCursor(docs...)
+}
+
+func TestOutboxRelay(t *testing.T) {
+	tests := []struct {
+		name             string
+		connectError     error
+		conn             *mockNATSConnection
+		claim            mockQueryResult
+		remove           mockQueryResult
+		expectedError    string
+		expectedCount    int
+		expectedData     []string
+		expectedFlush    bool
+		expectedRemoved  []string
+		expectedConnects int
+	}{
+		{
+			name:             "ConnectFail",
+			connectError:     errors.New("no servers available for connection"),
+			expectedError:    "cannot connect to NATS: no servers available for connection",
+			expectedConnects: 1,
+		},
+		{
+			name:             "ClaimConflict",
+			conn:             &mockNATSConnection{},
+			claim:            mockQueryResult{Error: arango.ArangoError{HasError: true, Code: 409, ErrorNum: 1200}},
+			expectedConnects: 1,
+		},
+		{
+			name:             "ClaimFail",
+			conn:             &mockNATSConnection{},
+			claim:            mockQueryResult{Error: errors.New("database error")},
+			expectedError:    "database error",
+			expectedConnects: 1,
+		},
+		{
+			name:             "Empty",
+			conn:             &mockNATSConnection{},
+			claim:            mockQueryResult{Cursor: outboxCursor()},
+			expectedConnects: 1,
+		},
+		{
+			name:             "PublishFail",
+			conn:             &mockNATSConnection{PublishOutErrors: []error{errors.New("nats: connection closed")}},
+			claim:            mockQueryResult{Cursor: outboxCursor("1", "2")},
+			expectedError:    "nats: connection closed",
+			expectedData:     []string{`{"id":"1"}`},
+			expectedConnects: 1,
+		},
+		{
+			name:             "FlushFail",
+			conn:             &mockNATSConnection{FlushOutError: errors.New("nats: timeout")},
+			claim:            mockQueryResult{Cursor: outboxCursor("1", "2")},
+			expectedError:    "nats: timeout",
+			expectedData:     []string{`{"id":"1"}`, `{"id":"2"}`},
+			expectedFlush:    true,
+			expectedConnects: 1,
+		},
+		{
+			name:             "PartlyPublished",
+			conn:             &mockNATSConnection{PublishOutErrors: []error{nil, errors.New("nats: connection closed")}},
+			claim:            mockQueryResult{Cursor: outboxCursor("1", "2", "3")},
+			remove:           mockQueryResult{Cursor: &mockArangoCursor{Closer: &mockCloser{}}},
+			expectedError:    "nats: connection closed",
+			expectedCount:    1,
+			expectedData:     []string{`{"id":"1"}`, `{"id":"2"}`},
+			expectedFlush:    true,
+			expectedRemoved:  []string{"1"},
+			expectedConnects: 1,
+		},
+		{
+			name:             "RemoveFail",
+			conn:             &mockNATSConnection{},
+			claim:            mockQueryResult{Cursor: outboxCursor("1")},
+			remove:           mockQueryResult{Error: errors.New("database error")},
+			expectedError:    "database error",
+			expectedCount:    1,
+			expectedData:     []string{`{"id":"1"}`},
+			expectedFlush:    true,
+			expectedRemoved:  []string{"1"},
+			expectedConnects: 1,
+		},
+		{
+			name:             "Success",
+			conn:             &mockNATSConnection{},
+			claim:            mockQueryResult{Cursor: outboxCursor("1", "2")},
+			remove:           mockQueryResult{Cursor: &mockArangoCursor{Closer: &mockCloser{}}},
+			expectedCount:    2,
+			expectedData:     []string{`{"id":"1"}`, `{"id":"2"}`},
+			expectedFlush:    true,
+			expectedRemoved:  []string{"1", "2"},
+			expectedConnects: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			arango := &mockArangoService{
+				QueryOutResults: map[string]mockQueryResult{
+					queryClaimOutbox:  tc.claim,
+					queryRemoveOutbox: tc.remove,
+				},
+			}
+
+			connects := 0
+			r := NewOutboxRelay(arango, func() (queue.NATSConnection, error) {
+				connects++
+				if tc.connectError != nil {
+					return nil, tc.connectError
+				}
+				return tc.conn, nil
+			}, log.NewVoidLogger())
+
+			n, err := r.relay(context.Background())
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedCount, n)
+			assert.Equal(t, tc.expectedConnects, connects)
+
+			if tc.conn == nil {
+				return
+			}
+
+			assert.Equal(t, tc.expectedData, tc.conn.PublishInData)
+			assert.Equal(t, tc.expectedFlush, tc.conn.FlushCalled)
+			if tc.expectedRemoved != nil {
+				assert.Equal(t, queryRemoveOutbox, arango.QueryInQuery)
+				assert.Equal(t, tc.expectedRemoved, arango.QueryInVars["keys"])
+			} else {
+				assert.NotContains(t, arango.QueryInQueries, queryRemoveOutbox)
+			}
+		})
+	}
+}
+
+func TestOutboxRelayReconnect(t *testing.T) {
+	arango := &mockArangoService{QueryOutCursor: outboxCursor()}
+	conns := []*mockNATSConnection{{}, {}}
+
+	connects := 0
+	r := NewOutboxRelay(arango, func() (queue.NATSConnection, error) {
+		conn := conns[connects]
+		connects++
+		return conn, nil
+	}, log.NewVoidLogger())
+
+	_, err := r.relay(context.Background())
+	assert.NoError(t, err)
+	_, err = r.relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, connects)
+
+	// A connection closed after running out of reconnects is replaced
+	conns[0].IsClosedOutResult = true
+	_, err = r.relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, connects)
+	assert.Equal(t, conns[1], r.conn)
+}
+
+func TestOutboxRelayRun(t *testing.T) {
+	conn := &mockNATSConnection{}
+	arango := &mockArangoService{
+		QueryOutResults: map[string]mockQueryResult{
+			queryClaimOutbox:  {Cursor: outboxCursor("1", "2", "3")},
+			queryRemoveOutbox: {Cursor: &mockArangoCursor{Closer: &mockCloser{}}},
+		},
+	}
+
+	r := NewOutboxRelay(arango, func() (queue.NATSConnection, error) {
+		return conn, nil
+	}, log.NewVoidLogger())
+
+	// A full batch is followed by the next one right away
+	r.batchSize = 3
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	r.Run(ctx, 10*time.Millisecond)
+
+	assert.Equal(t, []string{`{"id":"1"}`, `{"id":"2"}`, `{"id":"3"}`}, conn.PublishInData)
+	assert.Equal(t, []string{queryClaimOutbox, queryRemoveOutbox, queryClaimOutbox}, arango.QueryInQueries[:3])
+	assert.True(t, conn.CloseCalled)
+}
@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/auth"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+const (
+	// ScenesCollection is the Arango collection scenes are stored in
+	ScenesCollection = "scenes"
+
+	queryCreateScene = `INSERT @doc INTO scenes RETURN NEW`
+	queryGetScene    = `FOR sc IN scenes FILTER sc._key == @key AND sc.tenantId == @tenantId RETURN sc`
+	queryGetScenes   = `FOR sc IN scenes FILTER sc.tenantId == @tenantId AND sc.siteId == @siteId RETURN sc`
+	queryUpdateScene = `UPDATE { _key: @key, _rev: @rev } WITH @patch IN scenes OPTIONS { ignoreRevs: false, mergeObjects: false } RETURN NEW`
+	queryRemoveScene = `REMOVE @key IN scenes`
+)
+
+// ErrSceneNotFound is returned when a scene does not exist for a tenant
+var ErrSceneNotFound = errors.New("scene not found")
+
+func sceneToProto(doc *model.Scene) *proto.Scene {
+	return &proto.Scene{
+		Id:     doc.Key,
+		SiteId: doc.SiteID,
+		Name:   doc.Name,
+		States: doc.States,
+	}
+}
+
+// sceneSwitches returns the switch ids of a scene in order
+func sceneSwitches(doc *model.Scene) []string {
+	keys := make([]string, 0, len(doc.States))
+	for key := range doc.States {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// queryScene runs a query returning at most one scene
+func (s *SwitchService) queryScene(ctx context.Context, req interface{}, op, query string, vars map[string]interface{}) (*model.Scene, error) {
+	doc := &model.Scene{}
+	if err := s.queryDocument(ctx, req, op, query, vars, doc, ErrSceneNotFound); err != nil {
+		return nil, err
+	}
+
+	if err := auth.CheckSite(ctx, doc.SiteID); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// validateScene checks the name of a scene and that its states are states of switches of its site
+func (s *SwitchService) validateScene(ctx context.Context, req interface{}, op, tenantID string, doc *model.Scene) error {
+	var violations fieldViolations
+	if doc.SiteID == "" {
+		violations.add("site_id", "site id is required")
+	}
+	if doc.Name == "" {
+		violations.add("name", "name is required")
+	}
+	if len(doc.States) == 0 {
+		violations.add("states", "at least one switch state is required")
+	} else if len(doc.States) > maxBulkSize {
+		violations.add("states", fmt.Sprintf("a scene can have at most %d switches", maxBulkSize))
+	}
+
+	if err := violations.err(); err != nil {
+		return err
+	}
+
+	switches, err := s.readSwitches(ctx, req, op, tenantID, sceneSwitches(doc))
+	if err != nil {
+		return err
+	}
+
+	for _, sw := range switches {
+		field := fmt.Sprintf("states[%s]", sw.Key)
+		if sw.TenantID == "" || sw.SiteID != doc.SiteID {
+			violations.add(field, fmt.Sprintf("switch %q is not a switch of site %q", sw.Key, doc.SiteID))
+		} else if state := doc.States[sw.Key]; !hasState(sw.States, state) {
+			violations.add(field, fmt.Sprintf("state %q is not one of the switch states", state))
+		}
+	}
+
+	return violations.err()
+}
+
+// CreateScene creates a new scene
+func (s *SwitchService) CreateScene(ctx context.Context, req *proto.CreateSceneRequest) (*proto.Scene, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	doc := &model.Scene{
+		TenantID: tenantID,
+		SiteID:   req.GetSiteId(),
+		Name:     req.GetName(),
+		States:   req.GetStates(),
+	}
+
+	if err := auth.CheckSite(ctx, doc.SiteID); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateScene(ctx, req, "CreateScene", tenantID, doc); err != nil {
+		return nil, toStatus(err)
+	}
+
+	vars := map[string]interface{}{
+		"doc": doc,
+	}
+
+	doc, err := s.queryScene(ctx, req, "CreateScene_Insert", queryCreateScene, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return sceneToProto(doc), nil
+}
+
+// GetScene retrieves a scene
+func (s *SwitchService) GetScene(ctx context.Context, req *proto.GetSceneRequest) (*proto.Scene, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	vars := map[string]interface{}{
+		"key":      req.GetId(),
+		"tenantId": tenantID,
+	}
+
+	doc, err := s.queryScene(ctx, req, "GetScene_Query", queryGetScene, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return sceneToProto(doc), nil
+}
+
+// GetScenes retrieves the scenes of a site
+func (s *SwitchService) GetScenes(req *proto.GetScenesRequest, stream proto.SwitchService_GetScenesServer) error {
+	var err error
+	var cursor arango.Cursor
+
+	ctx := stream.Context()
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return toStatus(ErrNoTenant)
+	}
+
+	if req.GetSiteId() == "" {
+		var violations fieldViolations
+		violations.add("site_id", "site id is required")
+		return violations.err()
+	}
+
+	if err := auth.CheckSite(ctx, req.GetSiteId()); err != nil {
+		return err
+	}
+
+	vars := map[string]interface{}{
+		"tenantId": tenantID,
+		"siteId":   req.GetSiteId(),
+	}
+
+	s.exec(ctx, req, "GetScenes_Query", queryGetScenes, func() error {
+		cursor, err = s.arango.Query(ctx, queryGetScenes, vars)
+		return err
+	})
+
+	if err != nil {
+		return toStatus(err)
+	}
+
+	defer cursor.Close()
+
+	s.exec(ctx, req, "GetScenes_ReadDocument_Send", "ReadDocument", func() error {
+		for cursor.HasMore() {
+			doc := &model.Scene{}
+			if _, err = cursor.ReadDocument(ctx, doc); err != nil {
+				return err
+			}
+
+			if err = stream.Send(sceneToProto(doc)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return toStatus(err)
+}
+
+// UpdateScene replaces the name and states of a scene
+func (s *SwitchService) UpdateScene(ctx context.Context, req *proto.UpdateSceneRequest) (*proto.Scene, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	vars := map[string]interface{}{
+		"key":      req.GetId(),
+		"tenantId": tenantID,
+	}
+
+	current, err := s.queryScene(ctx, req, "UpdateScene_Query", queryGetScene, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	doc := &model.Scene{
+		SiteID: current.SiteID,
+		Name:   req.GetName(),
+		States: req.GetStates(),
+	}
+
+	if err = s.validateScene(ctx, req, "UpdateScene", tenantID, doc); err != nil {
+		return nil, toStatus(err)
+	}
+
+	// The states replace the previous ones rather than being merged into them,
+	// and the update only succeeds if the scene has not changed since it was read
+	vars = map[string]interface{}{
+		"key": current.Key,
+		"rev": current.Rev,
+		"patch": map[string]interface{}{
+			"name":   doc.Name,
+			"states": doc.States,
+		},
+	}
+
+	doc, err = s.queryScene(ctx, req, "UpdateScene_Update", queryUpdateScene, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return sceneToProto(doc), nil
+}
+
+// DeleteScene deletes a scene
+func (s *SwitchService) DeleteScene(ctx context.Context, req *proto.DeleteSceneRequest) (*proto.DeleteSceneResponse, error) {
+	var err error
+	var cursor arango.Cursor
+
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	vars := map[string]interface{}{
+		"key":      req.GetId(),
+		"tenantId": tenantID,
+	}
+
+	if _, err = s.queryScene(ctx, req, "DeleteScene_Query", queryGetScene, vars); err != nil {
+		return nil, toStatus(err)
+	}
+
+	vars = map[string]interface{}{
+		"key": req.GetId(),
+	}
+
+	s.exec(ctx, req, "DeleteScene_Remove", queryRemoveScene, func() error {
+		cursor, err = s.arango.Query(ctx, queryRemoveScene, vars)
+		if err != nil {
+			return err
+		}
+		return cursor.Close()
+	})
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &proto.DeleteSceneResponse{}, nil
+}
+
+// ApplyScene sets every switch of a scene to its target state in a transaction, so either all switches change or none does.
+// Switches already in their target state are left unchanged, and a switch blocked by one of its interlocks fails the scene.
+func (s *SwitchService) ApplyScene(ctx context.Context, req *proto.ApplySceneRequest) (*proto.ApplySceneResponse, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	vars := map[string]interface{}{
+		"key":      req.GetId(),
+		"tenantId": tenantID,
+	}
+
+	scene, err := s.queryScene(ctx, req, "ApplyScene_Query", queryGetScene, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	reason := req.GetReason()
+	if reason == "" {
+		reason = fmt.Sprintf("scene %s", scene.Name)
+	}
+
+	docs, err := s.readSwitches(ctx, req, "ApplyScene", tenantID, sceneSwitches(scene))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	// The switches may have changed since the scene was saved
+	b := newBulk(proto.BulkMode_ALL_OR_NOTHING, len(docs))
+	unchanged := make([]bool, len(docs))
+	for i, doc := range docs {
+		state := scene.States[doc.Key]
+		if doc.TenantID == "" {
+			b.fail(i, ErrSwitchNotFound)
+		} else if !hasState(doc.States, state) {
+			var violations fieldViolations
+			violations.add("state", fmt.Sprintf("state %q is not one of the switch states", state))
+			b.fail(i, violations.err())
+		} else if err := checkTransition(doc, state); err != nil {
+			b.fail(i, err)
+		} else if _, err := s.checkInterlocks(ctx, tenantID, doc, state); err != nil {
+			b.fail(i, err)
+		} else if doc.State == state {
+			unchanged[i] = true
+		}
+	}
+
+	switches := make([]*proto.Switch, len(docs))
+	err = b.run(ctx, s.arango, func(ctx context.Context, i int) error {
+		if unchanged[i] {
+			switches[i] = switchToProto(docs[i])
+			return nil
+		}
+
+		sw, err := s.setState(ctx, req, "ApplyScene", tenantID, docs[i], scene.States[docs[i].Key], reason, nil)
+		switches[i] = sw
+		return err
+	})
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	// The switches changed in an aborted transaction are unchanged
+	if b.abort() {
+		switches = make([]*proto.Switch, len(docs))
+	}
+
+	resp := &proto.ApplySceneResponse{
+		Results: make([]*proto.SwitchResult, len(docs)),
+	}
+
+	for i, doc := range docs {
+		sw := switches[i]
+		if sw != nil {
+			if unchanged[i] {
+				resp.UnchangedIds = append(resp.UnchangedIds, doc.Key)
+			} else {
+				s.publish(tenantID, proto.SwitchEvent_STATE_CHANGED, sw)
+			}
+		}
+
+		resp.Results[i] = switchResult(doc.Key, sw, b.errs[i])
+	}
+
+	return resp, nil
+}
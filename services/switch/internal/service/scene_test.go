@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/broker"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+func TestCreateScene(t *testing.T) {
+	tests := []struct {
+		name           string
+		arango         *mockArangoService
+		ctx            context.Context
+		req            *proto.CreateSceneRequest
+		expectedCode   codes.Code
+		expectedFields []string
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			&proto.CreateSceneRequest{SiteId: "1111-1111", Name: "Night", States: map[string]string{"aaaa-aaaa": "OFF"}},
+			codes.Unauthenticated,
+			nil,
+		},
+		{
+			"Empty",
+			&mockArangoService{},
+			contextWithTenant(testTenantID),
+			&proto.CreateSceneRequest{},
+			codes.InvalidArgument,
+			[]string{"site_id", "name", "states"},
+		},
+		{
+			"InvalidStates",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(testSwitchA, testSwitchC),
+			},
+			contextWithTenant(testTenantID),
+			&proto.CreateSceneRequest{SiteId: "1111-1111", Name: "Night", States: map[string]string{"aaaa-aaaa": "DIM", "cccc-cccc": "OFF"}},
+			codes.InvalidArgument,
+			[]string{"states[aaaa-aaaa]", "states[cccc-cccc]"},
+		},
+		{
+			"Success",
+			&mockArangoService{
+				QueryOutResults: map[string]mockQueryResult{
+					querySelectSwitches: {Cursor: newMockCursor(testSwitchA, testSwitchB)},
+					queryCreateScene:    {Cursor: newMockCursor(&model.Scene{Key: "ssss-ssss"})},
+				},
+			},
+			contextWithTenant(testTenantID),
+			&proto.CreateSceneRequest{SiteId: "1111-1111", Name: "Night", States: map[string]string{"aaaa-aaaa": "OFF", "bbbb-bbbb": "OFF"}},
+			codes.OK,
+			nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+
+			scene, err := service.CreateScene(tc.ctx, tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			if tc.expectedFields != nil {
+				assert.Equal(t, tc.expectedFields, violatedFields(err))
+			}
+
+			if tc.expectedCode == codes.OK {
+				assert.Equal(t, "ssss-ssss", scene.Id)
+				doc := tc.arango.QueryInVars["doc"].(*model.Scene)
+				assert.Equal(t, testTenantID, doc.TenantID)
+				assert.Equal(t, tc.req.States, doc.States)
+			}
+		})
+	}
+}
+
+func TestGetScenes(t *testing.T) {
+	service := newTestService(&mockArangoService{
+		QueryOutCursor: newMockCursor(&model.Scene{Key: "ssss-ssss"}),
+	})
+
+	stream := &mockGetScenesServer{
+		ServerStream: &mockServerStream{
+			ContextOutContext: contextWithTenant(testTenantID),
+		},
+	}
+
+	err := service.GetScenes(&proto.GetScenesRequest{}, stream)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	err = service.GetScenes(&proto.GetScenesRequest{SiteId: "1111-1111"}, stream)
+	assert.NoError(t, err)
+	assert.Len(t, stream.SendInScenes, 1)
+}
+
+func TestApplyScene(t *testing.T) {
+	scene := func(states map[string]string) *mockArangoCursor {
+		return newMockCursor(&model.Scene{Key: "ssss-ssss", SiteID: "1111-1111", Name: "Night", States: states})
+	}
+
+	tests := []struct {
+		name              string
+		arango            *mockArangoService
+		ctx               context.Context
+		expectedCode      codes.Code
+		expectedResults   []codes.Code
+		expectedUnchanged []string
+		expectedEvents    uint64
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			codes.Unauthenticated,
+			nil, nil, 0,
+		},
+		{
+			"NotFound",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(),
+			},
+			contextWithTenant(testTenantID),
+			codes.NotFound,
+			nil, nil, 0,
+		},
+		{
+			"SwitchRemoved",
+			&mockArangoService{
+				QueryOutResults: map[string]mockQueryResult{
+					queryGetScene:       {Cursor: scene(map[string]string{"aaaa-aaaa": "OFF", "dddd-dddd": "OFF"})},
+					querySelectSwitches: {Cursor: newMockCursor(testSwitchA)},
+				},
+			},
+			contextWithTenant(testTenantID),
+			codes.OK,
+			[]codes.Code{codes.Aborted, codes.NotFound},
+			nil, 0,
+		},
+		{
+			"StateRemoved",
+			&mockArangoService{
+				QueryOutResults: map[string]mockQueryResult{
+					queryGetScene:       {Cursor: scene(map[string]string{"aaaa-aaaa": "DIM"})},
+					querySelectSwitches: {Cursor: newMockCursor(testSwitchA)},
+				},
+			},
+			contextWithTenant(testTenantID),
+			codes.OK,
+			[]codes.Code{codes.InvalidArgument},
+			nil, 0,
+		},
+		{
+			"TransactionFail",
+			&mockArangoService{
+				QueryOutResults: map[string]mockQueryResult{
+					queryGetScene:       {Cursor: scene(map[string]string{"aaaa-aaaa": "OFF"})},
+					querySelectSwitches: {Cursor: newMockCursor(testSwitchA)},
+				},
+				TransactionOutError: errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			codes.Internal,
+			nil, nil, 0,
+		},
+		{
+			"UpdateFail",
+			&mockArangoService{
+				QueryOutResults: map[string]mockQueryResult{
+					queryGetScene:       {Cursor: scene(map[string]string{"aaaa-aaaa": "OFF", "bbbb-bbbb": "ON"})},
+					querySelectSwitches: {Cursor: newMockCursor(testSwitchA, testSwitchB)},
+				},
+				UpdateDocumentOutError: arango.ArangoError{HasError: true, Code: 412, ErrorNum: 1200},
+			},
+			contextWithTenant(testTenantID),
+			codes.OK,
+			[]codes.Code{codes.Aborted, codes.Aborted},
+			nil, 0,
+		},
+		{
+			"Success",
+			&mockArangoService{
+				QueryOutResults: map[string]mockQueryResult{
+					queryGetScene:       {Cursor: scene(map[string]string{"aaaa-aaaa": "ON", "bbbb-bbbb": "ON"})},
+					querySelectSwitches: {Cursor: newMockCursor(testSwitchA, testSwitchB)},
+				},
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbc"},
+			},
+			contextWithTenant(testTenantID),
+			codes.OK,
+			[]codes.Code{codes.OK, codes.OK},
+			[]string{"aaaa-aaaa"},
+			1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+			service.broker = broker.New(0, 0)
+			base := service.broker.Revision()
+
+			resp, err := service.ApplyScene(tc.ctx, &proto.ApplySceneRequest{Id: "ssss-ssss"})
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedEvents, service.broker.Revision()-base)
+
+			if tc.expectedCode == codes.OK {
+				assert.Equal(t, tc.expectedResults, resultCodes(resp.Results))
+				assert.Equal(t, tc.expectedUnchanged, resp.UnchangedIds)
+				assert.True(t, tc.arango.TransactionCalled || !tc.arango.UpdateDocumentCalled)
+			}
+
+			if tc.expectedEvents > 0 {
+				assert.Equal(t, "bbbb-bbbb", tc.arango.UpdateDocumentInKey)
+				change := tc.arango.CreateHistoryDocumentInDoc.(*model.SwitchStateChange)
+				assert.Equal(t, "scene Night", change.Reason)
+				assert.Equal(t, "_bbbc", resp.Results[1].Switch.Revision)
+			}
+		})
+	}
+}
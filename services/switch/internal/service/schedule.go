@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/auth"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/internal/scheduler"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+const (
+	// schedulesCollection is the collection the queries of schedules run against
+	schedulesCollection = "schedules"
+
+	queryCreateSchedule = `INSERT @doc INTO schedules RETURN NEW`
+	queryGetSchedule    = `FOR sc IN schedules FILTER sc._key == @key AND sc.tenantId == @tenantId RETURN sc`
+	queryGetSchedules   = `FOR sc IN schedules FILTER sc.tenantId == @tenantId AND (sc.switchId == @switchId OR sc.siteId == @siteId) RETURN sc`
+	queryUpdateSchedule = `UPDATE { _key: @key, _rev: @rev } WITH @patch IN schedules OPTIONS { ignoreRevs: false } RETURN NEW`
+	queryRemoveSchedule = `REMOVE @key IN schedules`
+)
+
+// ErrScheduleNotFound is returned when a schedule does not exist for a tenant
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+func scheduleToProto(doc *model.Schedule) *proto.Schedule {
+	sc := &proto.Schedule{
+		Id:       doc.Key,
+		SwitchId: doc.SwitchID,
+		SiteId:   doc.SiteID,
+		State:    doc.State,
+		Cron:     doc.Cron,
+		RunAt:    doc.RunAt,
+		TimeZone: doc.TimeZone,
+		Enabled:  doc.Enabled,
+		NextRun:  doc.NextRun,
+	}
+
+	if doc.LastRun != nil {
+		sc.LastRun = &proto.ScheduleRun{
+			DueTime:  doc.LastRun.DueTime,
+			ExecTime: doc.LastRun.ExecTime,
+			Success:  doc.LastRun.Success,
+			Error:    doc.LastRun.Error,
+			Missed:   int32(doc.LastRun.Missed),
+		}
+	}
+
+	return sc
+}
+
+// validateSchedule checks the timing of a schedule and the state against the switch states, then sets its next run
+func validateSchedule(doc *model.Schedule, sw *model.Switch) error {
+	var violations fieldViolations
+	if !hasState(sw.States, doc.State) {
+		violations.add("state", fmt.Sprintf("state %q is not one of the switch states", doc.State))
+	}
+
+	var next time.Time
+	if _, err := time.LoadLocation(doc.TimeZone); err != nil {
+		violations.add("time_zone", fmt.Sprintf("unknown time zone %q", doc.TimeZone))
+	} else if next, err = scheduler.NextRun(doc, time.Now()); err == scheduler.ErrNoSchedule {
+		violations.add("cron", err.Error())
+	} else if err != nil {
+		violations.add("cron", fmt.Sprintf("invalid cron expression: %s", err))
+	} else if doc.Cron == "" && next.IsZero() {
+		violations.add("run_at", "run time is in the past")
+	}
+
+	if err := violations.err(); err != nil {
+		return err
+	}
+
+	if !next.IsZero() {
+		doc.NextRun = next.UnixNano()
+	}
+
+	return nil
+}
+
+// querySchedule runs a query returning at most one schedule
+func (s *SwitchService) querySchedule(ctx context.Context, req interface{}, op, query string, vars map[string]interface{}) (*model.Schedule, error) {
+	doc := &model.Schedule{}
+	if err := s.queryDocument(ctx, req, op, query, vars, doc, ErrScheduleNotFound); err != nil {
+		return nil, err
+	}
+
+	if err := auth.CheckSite(ctx, doc.SiteID); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// CreateSchedule creates a new schedule for a switch
+func (s *SwitchService) CreateSchedule(ctx context.Context, req *proto.CreateScheduleRequest) (*proto.Schedule, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	if req.GetSwitchId() == "" {
+		var violations fieldViolations
+		violations.add("switch_id", "switch id is required")
+		return nil, violations.err()
+	}
+
+	sw, err := s.readSwitch(ctx, req, "CreateSchedule_ReadDocument", tenantID, req.GetSwitchId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	doc := &model.Schedule{
+		TenantID: tenantID,
+		SwitchID: req.GetSwitchId(),
+		SiteID:   sw.SiteID,
+		State:    req.GetState(),
+		Cron:     req.GetCron(),
+		RunAt:    req.GetRunAt(),
+		TimeZone: req.GetTimeZone(),
+		Enabled:  true,
+	}
+
+	if err = validateSchedule(doc, sw); err != nil {
+		return nil, err
+	}
+
+	vars := map[string]interface{}{
+		"doc": doc,
+	}
+
+	doc, err = s.querySchedule(ctx, req, "CreateSchedule_Query", queryCreateSchedule, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return scheduleToProto(doc), nil
+}
+
+// GetSchedule retrieves a schedule
+func (s *SwitchService) GetSchedule(ctx context.Context, req *proto.GetScheduleRequest) (*proto.Schedule, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	vars := map[string]interface{}{
+		"key":      req.GetId(),
+		"tenantId": tenantID,
+	}
+
+	doc, err := s.querySchedule(ctx, req, "GetSchedule_Query", queryGetSchedule, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return scheduleToProto(doc), nil
+}
+
+// GetSchedules retrieves the schedules of a switch or a site
+func (s *SwitchService) GetSchedules(req *proto.GetSchedulesRequest, stream proto.SwitchService_GetSchedulesServer) error {
+	var err error
+	var cursor arango.Cursor
+
+	ctx := stream.Context()
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return toStatus(ErrNoTenant)
+	}
+
+	if req.GetSwitchId() == "" && req.GetSiteId() == "" {
+		var violations fieldViolations
+		violations.add("switch_id", "either a switch id or a site id is required")
+		return violations.err()
+	}
+
+	if siteID := req.GetSiteId(); siteID != "" {
+		if err := auth.CheckSite(ctx, siteID); err != nil {
+			return err
+		}
+	}
+
+	vars := map[string]interface{}{
+		"tenantId": tenantID,
+		"switchId": req.GetSwitchId(),
+		"siteId":   req.GetSiteId(),
+	}
+
+	s.exec(ctx, req, "GetSchedules_Query", queryGetSchedules, func() error {
+		cursor, err = s.arango.Query(ctx, queryGetSchedules, vars)
+		return err
+	})
+
+	if err != nil {
+		return toStatus(err)
+	}
+
+	defer cursor.Close()
+
+	s.exec(ctx, req, "GetSchedules_ReadDocument_Send", "ReadDocument", func() error {
+		for cursor.HasMore() {
+			doc := &model.Schedule{}
+			if _, err = cursor.ReadDocument(ctx, doc); err != nil {
+				return err
+			}
+
+			// The schedules of a switch are left out if the caller is not allowed at its site
+			if !auth.AllowsSite(ctx, doc.SiteID) {
+				continue
+			}
+
+			if err = stream.Send(scheduleToProto(doc)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return toStatus(err)
+}
+
+// UpdateSchedule replaces the state, timing and enabled flag of a schedule
+func (s *SwitchService) UpdateSchedule(ctx context.Context, req *proto.UpdateScheduleRequest) (*proto.Schedule, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	vars := map[string]interface{}{
+		"key":      req.GetId(),
+		"tenantId": tenantID,
+	}
+
+	current, err := s.querySchedule(ctx, req, "UpdateSchedule_Query", queryGetSchedule, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	sw, err := s.readSwitch(ctx, req, "UpdateSchedule_ReadDocument", tenantID, current.SwitchID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	doc := &model.Schedule{
+		State:    req.GetState(),
+		Cron:     req.GetCron(),
+		RunAt:    req.GetRunAt(),
+		TimeZone: req.GetTimeZone(),
+		Enabled:  req.GetEnabled(),
+	}
+
+	if err = validateSchedule(doc, sw); err != nil {
+		return nil, err
+	}
+
+	// The update only succeeds if the schedule has not changed since it was read
+	vars = map[string]interface{}{
+		"key": current.Key,
+		"rev": current.Rev,
+		"patch": map[string]interface{}{
+			"state":    doc.State,
+			"cron":     doc.Cron,
+			"runAt":    doc.RunAt,
+			"timeZone": doc.TimeZone,
+			"enabled":  doc.Enabled,
+			"nextRun":  doc.NextRun,
+		},
+	}
+
+	doc, err = s.querySchedule(ctx, req, "UpdateSchedule_Update", queryUpdateSchedule, vars)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return scheduleToProto(doc), nil
+}
+
+// DeleteSchedule deletes a schedule
+func (s *SwitchService) DeleteSchedule(ctx context.Context, req *proto.DeleteScheduleRequest) (*proto.DeleteScheduleResponse, error) {
+	var err error
+	var cursor arango.Cursor
+
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	vars := map[string]interface{}{
+		"key":      req.GetId(),
+		"tenantId": tenantID,
+	}
+
+	if _, err = s.querySchedule(ctx, req, "DeleteSchedule_Query", queryGetSchedule, vars); err != nil {
+		return nil, toStatus(err)
+	}
+
+	vars = map[string]interface{}{
+		"key": req.GetId(),
+	}
+
+	s.exec(ctx, req, "DeleteSchedule_Remove", queryRemoveSchedule, func() error {
+		cursor, err = s.arango.Query(ctx, queryRemoveSchedule, vars)
+		if err != nil {
+			return err
+		}
+		return cursor.Close()
+	})
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &proto.DeleteScheduleResponse{}, nil
+}
@@ -0,0 +1,469 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+var testSwitch = &model.Switch{TenantID: testTenantID, SiteID: "1111-1111", States: []string{"OFF", "ON"}}
+
+func newTestService(arango *mockArangoService) *SwitchService {
+	return &SwitchService{
+		arango:  arango,
+		logger:  log.NewVoidLogger(),
+		metrics: metrics.Mock(),
+		tracer:  mocktracer.New(),
+	}
+}
+
+func violatedFields(err error) []string {
+	fields := []string{}
+	for _, detail := range status.Convert(err).Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+		}
+	}
+	return fields
+}
+
+func TestCreateSchedule(t *testing.T) {
+	future := time.Now().Add(time.Hour).UnixNano()
+	past := time.Now().Add(-time.Hour).UnixNano()
+
+	tests := []struct {
+		name           string
+		arango         *mockArangoService
+		ctx            context.Context
+		req            *proto.CreateScheduleRequest
+		expectedCode   codes.Code
+		expectedFields []string
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			&proto.CreateScheduleRequest{SwitchId: "aaaa-aaaa", State: "ON", Cron: "0 18 * * *"},
+			codes.Unauthenticated,
+			nil,
+		},
+		{
+			"NoSwitchID",
+			&mockArangoService{},
+			contextWithTenant(testTenantID),
+			&proto.CreateScheduleRequest{State: "ON", Cron: "0 18 * * *"},
+			codes.InvalidArgument,
+			[]string{"switch_id"},
+		},
+		{
+			"SwitchNotFound",
+			&mockArangoService{
+				ReadDocumentOutError: arango.ArangoError{HasError: true, Code: 404, ErrorNum: 1202},
+			},
+			contextWithTenant(testTenantID),
+			&proto.CreateScheduleRequest{SwitchId: "aaaa-aaaa", State: "ON", Cron: "0 18 * * *"},
+			codes.NotFound,
+			nil,
+		},
+		{
+			"InvalidState",
+			&mockArangoService{ReadDocumentOutDoc: testSwitch},
+			contextWithTenant(testTenantID),
+			&proto.CreateScheduleRequest{SwitchId: "aaaa-aaaa", State: "DIM", Cron: "0 18 * * *"},
+			codes.InvalidArgument,
+			[]string{"state"},
+		},
+		{
+			"NoTiming",
+			&mockArangoService{ReadDocumentOutDoc: testSwitch},
+			contextWithTenant(testTenantID),
+			&proto.CreateScheduleRequest{SwitchId: "aaaa-aaaa", State: "ON"},
+			codes.InvalidArgument,
+			[]string{"cron"},
+		},
+		{
+			"InvalidCron",
+			&mockArangoService{ReadDocumentOutDoc: testSwitch},
+			contextWithTenant(testTenantID),
+			&proto.CreateScheduleRequest{SwitchId: "aaaa-aaaa", State: "ON", Cron: "every evening"},
+			codes.InvalidArgument,
+			[]string{"cron"},
+		},
+		{
+			"InvalidTimeZone",
+			&mockArangoService{ReadDocumentOutDoc: testSwitch},
+			contextWithTenant(testTenantID),
+			&proto.CreateScheduleRequest{SwitchId: "aaaa-aaaa", State: "ON", Cron: "0 18 * * *", TimeZone: "Mars/Olympus"},
+			codes.InvalidArgument,
+			[]string{"time_zone"},
+		},
+		{
+			"RunAtPast",
+			&mockArangoService{ReadDocumentOutDoc: testSwitch},
+			contextWithTenant(testTenantID),
+			&proto.CreateScheduleRequest{SwitchId: "aaaa-aaaa", State: "ON", RunAt: past},
+			codes.InvalidArgument,
+			[]string{"run_at"},
+		},
+		{
+			"QueryError",
+			&mockArangoService{
+				ReadDocumentOutDoc: testSwitch,
+				QueryOutError:      errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			&proto.CreateScheduleRequest{SwitchId: "aaaa-aaaa", State: "ON", Cron: "0 18 * * *"},
+			codes.Internal,
+			nil,
+		},
+		{
+			"SuccessCron",
+			&mockArangoService{
+				ReadDocumentOutDoc: testSwitch,
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true},
+					ReadDocumentOutDoc: &model.Schedule{Key: "1234", SwitchID: "aaaa-aaaa", State: "ON", Cron: "0 18 * * *", TimeZone: "America/Toronto", Enabled: true},
+				},
+			},
+			contextWithTenant(testTenantID),
+			&proto.CreateScheduleRequest{SwitchId: "aaaa-aaaa", State: "ON", Cron: "0 18 * * *", TimeZone: "America/Toronto"},
+			codes.OK,
+			nil,
+		},
+		{
+			"SuccessOneOff",
+			&mockArangoService{
+				ReadDocumentOutDoc: testSwitch,
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true},
+					ReadDocumentOutDoc: &model.Schedule{Key: "1234", SwitchID: "aaaa-aaaa", State: "ON", RunAt: future, Enabled: true},
+				},
+			},
+			contextWithTenant(testTenantID),
+			&proto.CreateScheduleRequest{SwitchId: "aaaa-aaaa", State: "ON", RunAt: future},
+			codes.OK,
+			nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+
+			sc, err := service.CreateSchedule(tc.ctx, tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			if tc.expectedFields != nil {
+				assert.Equal(t, tc.expectedFields, violatedFields(err))
+			}
+
+			if tc.expectedCode == codes.OK {
+				assert.Equal(t, "1234", sc.Id)
+				assert.Equal(t, queryCreateSchedule, tc.arango.QueryInQuery)
+
+				doc := tc.arango.QueryInVars["doc"].(*model.Schedule)
+				assert.Equal(t, testTenantID, doc.TenantID)
+				assert.Equal(t, "1111-1111", doc.SiteID)
+				assert.True(t, doc.Enabled)
+				assert.True(t, doc.NextRun > time.Now().UnixNano())
+			} else {
+				assert.Nil(t, sc)
+			}
+		})
+	}
+}
+
+func TestGetSchedule(t *testing.T) {
+	tests := []struct {
+		name         string
+		arango       *mockArangoService
+		ctx          context.Context
+		expectedCode codes.Code
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			codes.Unauthenticated,
+		},
+		{
+			"NotFound",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:            &mockCloser{},
+					HasMoreOutResults: []bool{false},
+				},
+			},
+			contextWithTenant(testTenantID),
+			codes.NotFound,
+		},
+		{
+			"Success",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true},
+					ReadDocumentOutDoc: &model.Schedule{Key: "1234", State: "ON", LastRun: &model.ScheduleRun{Success: true, Missed: 2}},
+				},
+			},
+			contextWithTenant(testTenantID),
+			codes.OK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+
+			sc, err := service.GetSchedule(tc.ctx, &proto.GetScheduleRequest{Id: "1234"})
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+
+			if tc.arango.QueryCalled {
+				assert.Equal(t, queryGetSchedule, tc.arango.QueryInQuery)
+				assert.Equal(t, testTenantID, tc.arango.QueryInVars["tenantId"])
+			}
+
+			if tc.expectedCode == codes.OK {
+				assert.Equal(t, "1234", sc.Id)
+				assert.True(t, sc.LastRun.Success)
+				assert.Equal(t, int32(2), sc.LastRun.Missed)
+			}
+		})
+	}
+}
+
+func TestGetSchedules(t *testing.T) {
+	tests := []struct {
+		name          string
+		arango        *mockArangoService
+		ctx           context.Context
+		req           *proto.GetSchedulesRequest
+		expectedCode  codes.Code
+		expectedCount int
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			&proto.GetSchedulesRequest{SiteId: "1111-1111"},
+			codes.Unauthenticated,
+			0,
+		},
+		{
+			"NoSwitchOrSite",
+			&mockArangoService{},
+			contextWithTenant(testTenantID),
+			&proto.GetSchedulesRequest{},
+			codes.InvalidArgument,
+			0,
+		},
+		{
+			"QueryError",
+			&mockArangoService{
+				QueryOutError: errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			&proto.GetSchedulesRequest{SiteId: "1111-1111"},
+			codes.Internal,
+			0,
+		},
+		{
+			"Success",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true, true, false},
+					ReadDocumentOutDoc: &model.Schedule{Key: "1234", State: "ON"},
+				},
+			},
+			contextWithTenant(testTenantID),
+			&proto.GetSchedulesRequest{SwitchId: "aaaa-aaaa"},
+			codes.OK,
+			2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+			stream := &mockGetSchedulesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: tc.ctx,
+				},
+			}
+
+			err := service.GetSchedules(tc.req, stream)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Len(t, stream.SendInSchedules, tc.expectedCount)
+
+			if tc.arango.QueryCalled {
+				assert.Equal(t, queryGetSchedules, tc.arango.QueryInQuery)
+				assert.Equal(t, tc.req.SwitchId, tc.arango.QueryInVars["switchId"])
+				assert.Equal(t, tc.req.SiteId, tc.arango.QueryInVars["siteId"])
+			}
+		})
+	}
+}
+
+func TestUpdateSchedule(t *testing.T) {
+	tests := []struct {
+		name           string
+		arango         *mockArangoService
+		req            *proto.UpdateScheduleRequest
+		expectedCode   codes.Code
+		expectedFields []string
+	}{
+		{
+			"NotFound",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:            &mockCloser{},
+					HasMoreOutResults: []bool{false},
+				},
+			},
+			&proto.UpdateScheduleRequest{Id: "1234", State: "ON", Cron: "0 18 * * *"},
+			codes.NotFound,
+			nil,
+		},
+		{
+			"SwitchNotFound",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true},
+					ReadDocumentOutDoc: &model.Schedule{Key: "1234", Rev: "_aaaa", SwitchID: "aaaa-aaaa"},
+				},
+				ReadDocumentOutError: arango.ArangoError{HasError: true, Code: 404, ErrorNum: 1202},
+			},
+			&proto.UpdateScheduleRequest{Id: "1234", State: "ON", Cron: "0 18 * * *"},
+			codes.NotFound,
+			nil,
+		},
+		{
+			"Invalid",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true},
+					ReadDocumentOutDoc: &model.Schedule{Key: "1234", Rev: "_aaaa", SwitchID: "aaaa-aaaa"},
+				},
+				ReadDocumentOutDoc: testSwitch,
+			},
+			&proto.UpdateScheduleRequest{Id: "1234", State: "DIM", Cron: "0 25 * * *"},
+			codes.InvalidArgument,
+			[]string{"state", "cron"},
+		},
+		{
+			"Success",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true},
+					ReadDocumentOutDoc: &model.Schedule{Key: "1234", Rev: "_aaaa", SwitchID: "aaaa-aaaa"},
+				},
+				ReadDocumentOutDoc: testSwitch,
+			},
+			&proto.UpdateScheduleRequest{Id: "1234", State: "ON", Cron: "0 18 * * *", Enabled: true},
+			codes.OK,
+			nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+
+			sc, err := service.UpdateSchedule(contextWithTenant(testTenantID), tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			if tc.expectedFields != nil {
+				assert.Equal(t, tc.expectedFields, violatedFields(err))
+			}
+
+			if tc.expectedCode == codes.OK {
+				assert.NotNil(t, sc)
+				assert.Equal(t, queryUpdateSchedule, tc.arango.QueryInQuery)
+				assert.Equal(t, "_aaaa", tc.arango.QueryInVars["rev"])
+
+				patch := tc.arango.QueryInVars["patch"].(map[string]interface{})
+				assert.Equal(t, "ON", patch["state"])
+				assert.Equal(t, true, patch["enabled"])
+				assert.True(t, patch["nextRun"].(int64) > time.Now().UnixNano())
+			}
+		})
+	}
+}
+
+func TestDeleteSchedule(t *testing.T) {
+	tests := []struct {
+		name         string
+		arango       *mockArangoService
+		ctx          context.Context
+		expectedCode codes.Code
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			codes.Unauthenticated,
+		},
+		{
+			"NotFound",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:            &mockCloser{},
+					HasMoreOutResults: []bool{false},
+				},
+			},
+			contextWithTenant(testTenantID),
+			codes.NotFound,
+		},
+		{
+			"Success",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:             &mockCloser{},
+					HasMoreOutResults:  []bool{true},
+					ReadDocumentOutDoc: &model.Schedule{Key: "1234"},
+				},
+			},
+			contextWithTenant(testTenantID),
+			codes.OK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestService(tc.arango)
+
+			resp, err := service.DeleteSchedule(tc.ctx, &proto.DeleteScheduleRequest{Id: "1234"})
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+
+			if tc.expectedCode == codes.OK {
+				assert.Equal(t, &proto.DeleteScheduleResponse{}, resp)
+				assert.Equal(t, queryRemoveSchedule, tc.arango.QueryInQuery)
+				assert.Equal(t, "1234", tc.arango.QueryInVars["key"])
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/moorara/microservices-demo/pkg/site"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+// fieldViolations collects invalid fields of a request
+type fieldViolations []*errdetails.BadRequest_FieldViolation
+
+func (v *fieldViolations) add(field, description string) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: description,
+	})
+}
+
+// err returns an InvalidArgument status with a BadRequest detail or nil if there is no violation
+func (v fieldViolations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	return invalidArgument("invalid request", v...)
+}
+
+// invalidArgument creates an InvalidArgument status error with a BadRequest detail
+func invalidArgument(msg string, violations ...*errdetails.BadRequest_FieldViolation) error {
+	st := status.New(codes.InvalidArgument, msg)
+	if ds, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = ds
+	}
+
+	return st.Err()
+}
+
+type preconditionViolations []*errdetails.PreconditionFailure_Violation
+
+func (v *preconditionViolations) add(typ, description string) {
+	*v = append(*v, &errdetails.PreconditionFailure_Violation{
+		Type:        typ,
+		Subject:     "switch",
+		Description: description,
+	})
+}
+
+// err returns a FailedPrecondition status with a PreconditionFailure detail and the current switch or nil if there is no violation
+func (v preconditionViolations) err(current *proto.Switch) error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("switch is in state %q at revision %q", current.State, current.Revision)
+	st := status.New(codes.FailedPrecondition, msg)
+	if ds, err := st.WithDetails(&errdetails.PreconditionFailure{Violations: v}, current); err == nil {
+		st = ds
+	}
+
+	return st.Err()
+}
+
+// isUnavailable determines whether an error means Arango could not be reached
+func isUnavailable(err error) bool {
+	if arango.IsNoLeaderOrOngoing(err) || arango.IsArangoErrorWithCode(err, 503) || arango.IsResponse(err) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(arango.Cause(err), &netErr)
+}
+
+// toStatus maps an error to a gRPC status error
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case err == ErrNoTenant:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case err == ErrQuotaExceeded:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case err == ErrBulkAborted:
+		return status.Error(codes.Aborted, err.Error())
+	case err == ErrScheduleNotFound, err == ErrGroupNotFound, err == ErrSceneNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case err == ErrSwitchNotFound, arango.IsNotFound(err):
+		return status.Error(codes.NotFound, ErrSwitchNotFound.Error())
+	case err == site.ErrUnknownSite:
+		return invalidArgument(err.Error(), &errdetails.BadRequest_FieldViolation{
+			Field:       "site_id",
+			Description: err.Error(),
+		})
+	case err == site.ErrUnavailable:
+		return status.Error(codes.Unavailable, err.Error())
+	case arango.IsConflict(err), arango.IsPreconditionFailed(err):
+		return status.Error(codes.Aborted, err.Error())
+	case err == context.Canceled, arango.IsCanceled(err):
+		return status.Error(codes.Canceled, err.Error())
+	case err == context.DeadlineExceeded, arango.IsTimeout(err):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case isUnavailable(err):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
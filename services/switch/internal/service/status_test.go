@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/moorara/microservices-demo/pkg/site"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode codes.Code
+	}{
+		{"Nil", nil, codes.OK},
+		{"Status", status.Error(codes.InvalidArgument, "bad"), codes.InvalidArgument},
+		{"NoTenant", ErrNoTenant, codes.Unauthenticated},
+		{"QuotaExceeded", ErrQuotaExceeded, codes.ResourceExhausted},
+		{"SwitchNotFound", ErrSwitchNotFound, codes.NotFound},
+		{"ScheduleNotFound", ErrScheduleNotFound, codes.NotFound},
+		{"ArangoNotFound", arango.ArangoError{HasError: true, Code: 404, ErrorNum: 1202}, codes.NotFound},
+		{"ArangoConflict", arango.ArangoError{HasError: true, Code: 409, ErrorNum: 1210}, codes.Aborted},
+		{"ArangoPreconditionFailed", arango.ArangoError{HasError: true, Code: 412, ErrorNum: 1200}, codes.Aborted},
+		{"ArangoUnavailable", arango.ArangoError{HasError: true, Code: 503, ErrorNum: 1496}, codes.Unavailable},
+		{"ConnectionRefused", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, codes.Unavailable},
+		{"Canceled", context.Canceled, codes.Canceled},
+		{"DeadlineExceeded", context.DeadlineExceeded, codes.DeadlineExceeded},
+		{"UnknownSite", site.ErrUnknownSite, codes.InvalidArgument},
+		{"SiteServiceUnavailable", site.ErrUnavailable, codes.Unavailable},
+		{"Other", errors.New("database error"), codes.Internal},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := toStatus(tc.err)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestFieldViolations(t *testing.T) {
+	tests := []struct {
+		name           string
+		fields         []string
+		expectedFields []string
+	}{
+		{"None", nil, nil},
+		{"One", []string{"state"}, []string{"state"}},
+		{"Many", []string{"site_id", "name"}, []string{"site_id", "name"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var violations fieldViolations
+			for _, field := range tc.fields {
+				violations.add(field, field+" is invalid")
+			}
+
+			err := violations.err()
+			if tc.expectedFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			st := status.Convert(err)
+			assert.Equal(t, codes.InvalidArgument, st.Code())
+			assert.Len(t, st.Details(), 1)
+
+			br, ok := st.Details()[0].(*errdetails.BadRequest)
+			assert.True(t, ok)
+
+			fields := []string{}
+			for _, v := range br.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+			assert.Equal(t, tc.expectedFields, fields)
+		})
+	}
+}
@@ -2,24 +2,68 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/moorara/microservices-demo/pkg/site"
+	"github.com/moorara/microservices-demo/services/switch/internal/auth"
+	"github.com/moorara/microservices-demo/services/switch/internal/broker"
 	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
 	"github.com/moorara/microservices-demo/services/switch/internal/model"
-	"github.com/moorara/microservices-demo/services/switch/internal/proto"
+	"github.com/moorara/microservices-demo/services/switch/pkg/event"
 	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/moorara/microservices-demo/services/switch/pkg/sensor"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 
 	arango "github.com/arangodb/go-driver"
 	opentracingLog "github.com/opentracing/opentracing-go/log"
 )
 
-const queryGetSwitches = `FOR sw IN switches FILTER sw.siteId == @siteId RETURN sw`
+const (
+	// QuotaCollection is the Arango collection with a document per tenant written by every install checking the quota of the tenant
+	QuotaCollection = "switch_quotas"
+
+	tenantMetadataKey        = "tenant-id"
+	callerMetadataKey        = "caller-id"
+	nextPageTokenMetadataKey = "next-page-token"
+
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 1000
+	maxSwitchesPageSize    = 1000
+
+	queryLockQuota     = `UPSERT { _key: @key } INSERT { _key: @key, tenantId: @tenantId, lockedAt: @now } UPDATE { lockedAt: @now } IN switch_quotas`
+	queryCountSwitches = `FOR sw IN switches FILTER sw.tenantId == @tenantId RETURN sw._key`
+	queryWatchSwitches = `FOR sw IN switches FILTER sw.tenantId == @tenantId AND (sw.siteId == @siteId OR sw._key IN @keys) RETURN sw`
+	querySyncSchedules = `FOR sc IN schedules FILTER sc.tenantId == @tenantId AND sc.switchId == @switchId UPDATE sc WITH { siteId: @siteId, enabled: sc.enabled AND sc.state IN @states } IN schedules`
+
+	// Changes are paged after the time and key of the last change of the previous page
+	queryGetSwitchHistory = `FOR h IN switch_history FILTER h.tenantId == @tenantId AND h.switchId == @switchId AND h.time >= @fromTime AND h.time < @toTime AND (h.time < @afterTime OR (h.time == @afterTime AND h._key < @afterKey)) SORT h.time DESC, h._key DESC LIMIT @count RETURN h`
+)
+
+var (
+	// ErrNoTenant is returned when a call does not carry a tenant identifier
+	ErrNoTenant = errors.New("tenant id required")
+
+	// ErrQuotaExceeded is returned when a tenant has reached its quota of switches
+	ErrQuotaExceeded = errors.New("tenant quota exceeded")
+
+	// ErrSwitchNotFound is returned when a switch does not exist for a tenant
+	ErrSwitchNotFound = errors.New("switch not found")
+)
 
 type (
 	callback func() error
@@ -27,6 +71,11 @@ type (
 	// SwitchService implements proto.SwitchServiceServer
 	SwitchService struct {
 		arango  ArangoService
+		quota   int
+		sites   site.Client
+		sensors sensor.Client
+		broker  *broker.Broker
+		outbox  bool
 		logger  *log.Logger
 		metrics *metrics.Metrics
 		tracer  opentracing.Tracer
@@ -34,41 +83,120 @@ type (
 )
 
 // NewSwitchService creates a new switch service
-func NewSwitchService(arango ArangoService, logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer) proto.SwitchServiceServer {
+// quota is the maximum number of switches a tenant can have and zero means no limit.
+// sites validates the site of new switches and nil disables the validation.
+// sensors reads the sensors of interlocks and nil makes every interlock block the changes it applies to.
+// broker receives an event for every change to a switch and feeds WatchSwitches.
+// outbox enables writing the installs, removals and state changes to the outbox collection for the outbox relay.
+func NewSwitchService(arango ArangoService, quota int, sites site.Client, sensors sensor.Client, broker *broker.Broker, outbox bool, logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer) proto.SwitchServiceServer {
 	return &SwitchService{
 		arango:  arango,
+		quota:   quota,
+		sites:   sites,
+		sensors: sensors,
+		broker:  broker,
+		outbox:  outbox,
 		logger:  logger,
 		metrics: metrics,
 		tracer:  tracer,
 	}
 }
 
-func (s *SwitchService) extractParentSpanContext(ctx context.Context) (opentracing.SpanContext, error) {
+func switchToProto(doc *model.Switch) *proto.Switch {
+	return &proto.Switch{
+		Id:            doc.Key,
+		SiteId:        doc.SiteID,
+		Name:          doc.Name,
+		State:         doc.State,
+		States:        doc.States,
+		Revision:      doc.Rev,
+		Transitions:   transitionsToProto(doc.Transitions),
+		ReportedState: doc.ReportedState,
+		ReportedAt:    doc.ReportedAt,
+		Pending:       doc.Pending,
+		OutOfSync:     doc.OutOfSync,
+		Interlocks:    interlocksToProto(doc.Interlocks),
+	}
+}
+
+func transitionsToProto(transitions []model.Transition) []*proto.Transition {
+	if len(transitions) == 0 {
+		return nil
+	}
+
+	out := make([]*proto.Transition, len(transitions))
+	for i, t := range transitions {
+		out[i] = &proto.Transition{
+			From: t.From,
+			To:   t.To,
+		}
+	}
+
+	return out
+}
+
+func transitionsFromProto(transitions []*proto.Transition) []model.Transition {
+	if len(transitions) == 0 {
+		return nil
+	}
+
+	out := make([]model.Transition, len(transitions))
+	for i, t := range transitions {
+		out[i] = model.Transition{
+			From: t.GetFrom(),
+			To:   t.GetTo(),
+		}
+	}
+
+	return out
+}
+
+func (s *SwitchService) extractTenant(ctx context.Context) (string, bool) {
 	meta, ok := metadata.FromIncomingContext(ctx)
 	if ok {
-		vals := meta.Get("span.context")
-		if len(vals) == 1 {
-			data := make(map[string]string)
-			err := json.Unmarshal([]byte(vals[0]), &data)
-			if err != nil {
-				return nil, err
+		vals := meta.Get(tenantMetadataKey)
+		if len(vals) == 1 && vals[0] != "" {
+			return vals[0], true
+		}
+	}
+
+	return "", false
+}
+
+// extractCaller identifies the caller by its client certificate or, for a call made by the service itself, by its internal name.
+// The caller-id metadata is set by the caller and never used as its identity.
+func (s *SwitchService) extractCaller(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			if certs := info.State.PeerCertificates; len(certs) > 0 && certs[0].Subject.CommonName != "" {
+				return certs[0].Subject.CommonName
 			}
+		}
+	}
+
+	return auth.InternalCaller(ctx)
+}
 
-			carrier := opentracing.TextMapCarrier(data)
-			return s.tracer.Extract(opentracing.TextMap, carrier)
+// extractClaimedCaller returns the caller-id metadata, which identifies a caller only as the caller claims
+func (s *SwitchService) extractClaimedCaller(ctx context.Context) string {
+	meta, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		vals := meta.Get(callerMetadataKey)
+		if len(vals) == 1 {
+			return vals[0]
 		}
 	}
 
-	return nil, nil
+	return ""
 }
 
 func (s *SwitchService) exec(ctx context.Context, req interface{}, op, query string, fn callback) {
+	// The span of the call is started by the gRPC interceptor
 	var span opentracing.Span
-	parentSpanContext, _ := s.extractParentSpanContext(ctx)
-	if parentSpanContext == nil {
+	if parent := opentracing.SpanFromContext(ctx); parent == nil {
 		span = s.tracer.StartSpan(op)
 	} else {
-		span = s.tracer.StartSpan(op, opentracing.ChildOf(parentSpanContext))
+		span = s.tracer.StartSpan(op, opentracing.ChildOf(parent.Context()))
 	}
 
 	// https://github.com/opentracing/specification/blob/master/semantic_conventions.md
@@ -91,151 +219,1196 @@ func (s *SwitchService) exec(ctx context.Context, req interface{}, op, query str
 		span.LogFields(opentracingLog.String("message", "successful!"))
 	}
 
-	s.metrics.OpLatencyHist.WithLabelValues(op, success).Observe(latency)
-	s.metrics.OpLatencySumm.WithLabelValues(op, success).Observe(latency)
+	tenantID, _ := s.extractTenant(ctx)
+	tenantLabel := s.metrics.Tenants.Label(tenantID)
+	s.metrics.OpLatencyHist.WithLabelValues(op, success, tenantLabel).Observe(latency)
+	s.metrics.OpLatencySumm.WithLabelValues(op, success, tenantLabel).Observe(latency)
 }
 
-// InstallSwitch creates a new switch
-func (s *SwitchService) InstallSwitch(ctx context.Context, req *proto.InstallSwitchRequest) (*proto.Switch, error) {
-	var err error
-	var meta arango.DocumentMeta
+// publish notifies the watchers of a change to a switch
+func (s *SwitchService) publish(tenantID string, typ proto.SwitchEvent_Type, sw *proto.Switch) {
+	if s.broker != nil {
+		s.broker.Publish(tenantID, typ, sw)
+	}
+}
 
-	doc := &model.Switch{
-		Key:    uuid.New().String(),
-		SiteID: req.GetSiteId(),
-		Name:   req.GetName(),
-		State:  req.GetState(),
-		States: req.GetStates(),
+// encodePageToken creates an opaque page token from the offset of the next page
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodePageToken returns the offset of the page a token refers to
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+
+	offset, err := strconv.Atoi(string(b))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid page token")
+	}
+
+	return offset, nil
+}
+
+// encodeHistoryToken creates an opaque page token from the time and key of the last change of a page
+func encodeHistoryToken(time int64, key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(time, 10) + "/" + key))
+}
+
+// decodeHistoryToken returns the time and key of the last change before the page a token refers to
+func decodeHistoryToken(token string) (int64, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, "", err
+	}
+
+	parts := strings.SplitN(string(b), "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return 0, "", errors.New("invalid page token")
+	}
+
+	time, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", errors.New("invalid page token")
+	}
+
+	return time, parts[1], nil
+}
+
+// hasState determines whether a state is one of the given states
+func hasState(states []string, state string) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTransitions checks that the transitions of a switch are between its states and start from each state at most once
+func validateTransitions(violations *fieldViolations, field string, states []string, transitions []model.Transition) {
+	from := map[string]bool{}
+	for _, t := range transitions {
+		if !hasState(states, t.From) {
+			violations.add(field, fmt.Sprintf("state %q is not one of the switch states", t.From))
+		} else if from[t.From] {
+			violations.add(field, fmt.Sprintf("transitions from state %q are listed more than once", t.From))
+		}
+		from[t.From] = true
+
+		for _, to := range t.To {
+			if !hasState(states, to) {
+				violations.add(field, fmt.Sprintf("state %q is not one of the switch states", to))
+			}
+		}
+	}
+}
+
+// allowedStates returns the states a switch can change to from its current state
+func allowedStates(sw *model.Switch) []string {
+	if len(sw.Transitions) == 0 {
+		states := []string{}
+		for _, state := range sw.States {
+			if state != sw.State {
+				states = append(states, state)
+			}
+		}
+		return states
+	}
+
+	for _, t := range sw.Transitions {
+		if t.From == sw.State {
+			return t.To
+		}
+	}
+
+	return []string{}
+}
+
+// checkTransition returns a FailedPrecondition status if a switch cannot change from its current state to a state.
+// A switch can always be set to its current state.
+func checkTransition(sw *model.Switch, state string) error {
+	var violations preconditionViolations
+	if state != sw.State && !hasState(allowedStates(sw), state) {
+		violations.add("TRANSITION", fmt.Sprintf("switch cannot change from state %q to state %q", sw.State, state))
 	}
 
-	s.exec(ctx, req, "InstallSwitch_CreateDocument", "CreateDocument", func() error {
-		meta, err = s.arango.CreateDocument(ctx, doc)
+	return violations.err(switchToProto(sw))
+}
+
+// readSwitch reads a switch document and makes sure it belongs to the given tenant
+func (s *SwitchService) readSwitch(ctx context.Context, req interface{}, op, tenantID, key string) (*model.Switch, error) {
+	var err error
+	doc := &model.Switch{}
+
+	s.exec(ctx, req, op, "ReadDocument", func() error {
+		_, err = s.arango.ReadDocument(ctx, key, doc)
 		return err
 	})
 
 	if err != nil {
+		if arango.IsNotFound(err) {
+			return nil, ErrSwitchNotFound
+		}
 		return nil, err
 	}
 
-	doc.ID = meta.ID.String()
-	doc.Key = meta.Key
-	doc.Rev = meta.Rev
+	if doc.TenantID != tenantID {
+		return nil, ErrSwitchNotFound
+	}
 
-	return &proto.Switch{
-		Id:     meta.Key,
-		SiteId: doc.SiteID,
-		Name:   doc.Name,
-		State:  doc.State,
-		States: doc.States,
-	}, nil
+	if err := auth.CheckSite(ctx, doc.SiteID); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
 }
 
-// RemoveSwitch deletes a switch
-func (s *SwitchService) RemoveSwitch(ctx context.Context, req *proto.RemoveSwitchRequest) (*proto.RemoveSwitchResponse, error) {
+// queryDocument runs a query returning at most one document and fails with notFound if there is none
+func (s *SwitchService) queryDocument(ctx context.Context, req interface{}, op, query string, vars map[string]interface{}, doc interface{}, notFound error) error {
 	var err error
-	key := req.GetId()
+	var cursor arango.Cursor
+
+	s.exec(ctx, req, op, query, func() error {
+		cursor, err = s.arango.Query(ctx, query, vars)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+
+		if !cursor.HasMore() {
+			err = notFound
+			return err
+		}
 
-	s.exec(ctx, req, "RemoveSwitch_RemoveDocument", "RemoveDocument", func() error {
-		_, err = s.arango.RemoveDocument(ctx, key)
+		_, err = cursor.ReadDocument(ctx, doc)
 		return err
 	})
 
-	if err != nil {
-		return nil, err
+	return err
+}
+
+// validateInstall checks the fields of a new switch
+func validateInstall(req *proto.InstallSwitchRequest) error {
+	var violations fieldViolations
+	if req.GetSiteId() == "" {
+		violations.add("site_id", "site id is required")
 	}
+	if req.GetName() == "" {
+		violations.add("name", "name is required")
+	}
+	if len(req.GetStates()) == 0 {
+		violations.add("states", "at least one state is required")
+	} else if !hasState(req.GetStates(), req.GetState()) {
+		violations.add("state", fmt.Sprintf("state %q is not one of the switch states", req.GetState()))
+	}
+	validateTransitions(&violations, "transitions", req.GetStates(), transitionsFromProto(req.GetTransitions()))
+	validateInterlocks(&violations, "interlocks", req.GetStates(), req.GetInterlocks())
 
-	return &proto.RemoveSwitchResponse{}, nil
+	return violations.err()
 }
 
-// GetSwitch retrieves a switch
-func (s *SwitchService) GetSwitch(ctx context.Context, req *proto.GetSwitchRequest) (*proto.Switch, error) {
+// countSwitches counts the switches of a tenant
+func (s *SwitchService) countSwitches(ctx context.Context, req interface{}, op, tenantID string) (int64, error) {
 	var err error
-	key := req.GetId()
-	doc := &model.Switch{}
+	var cursor arango.Cursor
 
-	s.exec(ctx, req, "GetSwitch_ReadDocument", "ReadDocument", func() error {
-		_, err = s.arango.ReadDocument(ctx, key, doc)
+	vars := map[string]interface{}{
+		"tenantId": tenantID,
+	}
+
+	s.exec(ctx, req, op, queryCountSwitches, func() error {
+		cursor, err = s.arango.Query(arango.WithQueryCount(ctx), queryCountSwitches, vars)
 		return err
 	})
 
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	return &proto.Switch{
-		Id:     doc.Key,
-		SiteId: doc.SiteID,
-		Name:   doc.Name,
-		State:  doc.State,
-		States: doc.States,
-	}, nil
+	defer cursor.Close()
+
+	return cursor.Count(), nil
 }
 
-// GetSwitches retrieves a group of switches
-func (s *SwitchService) GetSwitches(req *proto.GetSwitchesRequest, stream proto.SwitchService_GetSwitchesServer) error {
+// checkQuota fails with ErrQuotaExceeded if a tenant has already reached its quota of switches.
+// It runs in the transaction installing the switch and first writes the quota document of the tenant,
+// so concurrent installs of the tenant conflict instead of counting the same switches and exceeding the quota together.
+func (s *SwitchService) checkQuota(ctx context.Context, req interface{}, op, tenantID string) error {
+	if s.quota <= 0 {
+		return nil
+	}
+
 	var err error
 	var cursor arango.Cursor
 
-	ctx := stream.Context()
+	// Tenant ids can have characters that are not allowed in document keys
 	vars := map[string]interface{}{
-		"siteId": req.GetSiteId(),
+		"key":      fmt.Sprintf("%x", sha256.Sum256([]byte(tenantID))),
+		"tenantId": tenantID,
+		"now":      time.Now().UnixNano(),
 	}
 
-	s.exec(ctx, req, "GetSwitches_Query", queryGetSwitches, func() error {
-		cursor, err = s.arango.Query(ctx, queryGetSwitches, vars)
-		return err
+	s.exec(ctx, req, op+"_LockQuota", queryLockQuota, func() error {
+		if cursor, err = s.arango.Query(ctx, queryLockQuota, vars); err != nil {
+			return err
+		}
+		return cursor.Close()
 	})
 
 	if err != nil {
 		return err
 	}
 
-	defer cursor.Close()
+	count, err := s.countSwitches(ctx, req, op+"_Count", tenantID)
+	if err != nil {
+		return err
+	}
 
-	s.exec(ctx, req, "GetSwitches_ReadDocument_Send", "ReadDocument", func() error {
-		for cursor.HasMore() {
-			doc := &model.Switch{}
-			_, err = cursor.ReadDocument(ctx, doc)
-			if err != nil {
-				return err
-			}
+	if count >= int64(s.quota) {
+		return ErrQuotaExceeded
+	}
 
-			sw := &proto.Switch{
-				Id:     doc.Key,
-				SiteId: doc.SiteID,
-				Name:   doc.Name,
-				State:  doc.State,
-				States: doc.States,
-			}
+	return nil
+}
 
-			err = stream.Send(sw)
-			if err != nil {
-				return err
-			}
+// installSwitch checks the quota of a tenant and creates a new switch in a transaction
+func (s *SwitchService) installSwitch(ctx context.Context, req interface{}, op, tenantID string, in *proto.InstallSwitchRequest) (*proto.Switch, error) {
+	var sw *proto.Switch
+
+	err := s.arango.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.checkQuota(ctx, req, op, tenantID); err != nil {
+			return err
 		}
 
-		return nil
+		var err error
+		sw, err = s.createSwitch(ctx, req, op, tenantID, in)
+		return err
 	})
 
-	return err
+	if err != nil {
+		return nil, err
+	}
+
+	return sw, nil
 }
 
-// SetSwitch changes the state of a switch
-func (s *SwitchService) SetSwitch(ctx context.Context, req *proto.SetSwitchRequest) (*proto.SetSwitchResponse, error) {
-	var err error
-	key := req.GetId()
+// createSwitch creates the document of a new switch and writes its event to the outbox in a transaction
+func (s *SwitchService) createSwitch(ctx context.Context, req interface{}, op, tenantID string, in *proto.InstallSwitchRequest) (*proto.Switch, error) {
 	doc := &model.Switch{
-		State: req.GetState(),
+		Key:         uuid.New().String(),
+		TenantID:    tenantID,
+		SiteID:      in.GetSiteId(),
+		Name:        in.GetName(),
+		State:       in.GetState(),
+		States:      in.GetStates(),
+		Transitions: transitionsFromProto(in.GetTransitions()),
+		Interlocks:  interlocksFromProto(in.GetInterlocks()),
 	}
 
-	s.exec(ctx, req, "SetSwitch_UpdateDocument", "UpdateDocument", func() error {
-		_, err = s.arango.UpdateDocument(ctx, key, doc)
-		return err
+	var sw *proto.Switch
+
+	err := s.arango.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		var meta arango.DocumentMeta
+
+		s.exec(ctx, req, op+"_CreateDocument", "CreateDocument", func() error {
+			meta, err = s.arango.CreateDocument(ctx, doc)
+			return err
+		})
+
+		if err != nil {
+			return err
+		}
+
+		doc.ID = meta.ID.String()
+		doc.Key = meta.Key
+		doc.Rev = meta.Rev
+		sw = switchToProto(doc)
+
+		return s.enqueue(ctx, req, op, tenantID, event.SubjectInstalled, sw, "")
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &proto.SetSwitchResponse{}, nil
+	return sw, nil
+}
+
+// InstallSwitch creates a new switch
+func (s *SwitchService) InstallSwitch(ctx context.Context, req *proto.InstallSwitchRequest) (*proto.Switch, error) {
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	if err := validateInstall(req); err != nil {
+		return nil, err
+	}
+
+	if err := auth.CheckSite(ctx, req.GetSiteId()); err != nil {
+		return nil, err
+	}
+
+	if s.sites != nil {
+		if err := s.sites.Validate(ctx, req.GetSiteId()); err != nil {
+			return nil, toStatus(err)
+		}
+	}
+
+	sw, err := s.installSwitch(ctx, req, "InstallSwitch", tenantID, req)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	s.publish(tenantID, proto.SwitchEvent_INSTALLED, sw)
+
+	return sw, nil
+}
+
+// RemoveSwitch deletes a switch
+func (s *SwitchService) RemoveSwitch(ctx context.Context, req *proto.RemoveSwitchRequest) (*proto.RemoveSwitchResponse, error) {
+	key := req.GetId()
+
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	doc, err := s.readSwitch(ctx, req, "RemoveSwitch_ReadDocument", tenantID, key)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	sw := switchToProto(doc)
+
+	// The switch is removed and its event is written to the outbox in one transaction, so a switch is never removed without its event
+	err = s.arango.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		s.exec(ctx, req, "RemoveSwitch_RemoveDocument", "RemoveDocument", func() error {
+			_, err = s.arango.RemoveDocument(ctx, key)
+			return err
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return s.enqueue(ctx, req, "RemoveSwitch", tenantID, event.SubjectRemoved, sw, "")
+	})
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	s.publish(tenantID, proto.SwitchEvent_REMOVED, sw)
+
+	return &proto.RemoveSwitchResponse{}, nil
+}
+
+// GetSwitch retrieves a switch
+func (s *SwitchService) GetSwitch(ctx context.Context, req *proto.GetSwitchRequest) (*proto.Switch, error) {
+	key := req.GetId()
+
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	doc, err := s.readSwitch(ctx, req, "GetSwitch_ReadDocument", tenantID, key)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return switchToProto(doc), nil
+}
+
+// GetAllowedTransitions returns the states a switch can change to from its current state
+func (s *SwitchService) GetAllowedTransitions(ctx context.Context, req *proto.GetAllowedTransitionsRequest) (*proto.GetAllowedTransitionsResponse, error) {
+	key := req.GetId()
+
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	doc, err := s.readSwitch(ctx, req, "GetAllowedTransitions_ReadDocument", tenantID, key)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &proto.GetAllowedTransitionsResponse{
+		CurrentState:  doc.State,
+		AllowedStates: allowedStates(doc),
+		Revision:      doc.Rev,
+	}, nil
+}
+
+// switchSortAttributes are the attributes switches can be sorted by
+var switchSortAttributes = map[proto.GetSwitchesRequest_SortBy]string{
+	proto.GetSwitchesRequest_NAME:    "name",
+	proto.GetSwitchesRequest_STATE:   "state",
+	proto.GetSwitchesRequest_SITE_ID: "siteId",
+}
+
+// escapeLike escapes the wildcards of a string so LIKE matches it literally
+func escapeLike(str string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(str)
+}
+
+// getSwitchesQuery builds the query selecting the switches of a GetSwitches request.
+// The query only consists of fixed clauses and every value of the request is a bind variable.
+// A zero count reads every switch.
+func getSwitchesQuery(tenantID string, req *proto.GetSwitchesRequest, offset, count int) (string, map[string]interface{}) {
+	var query strings.Builder
+	vars := map[string]interface{}{
+		"tenantId": tenantID,
+	}
+
+	query.WriteString("FOR sw IN switches FILTER sw.tenantId == @tenantId")
+
+	siteIDs := req.GetSiteIds()
+	if siteID := req.GetSiteId(); siteID != "" {
+		siteIDs = append([]string{siteID}, siteIDs...)
+	}
+	if len(siteIDs) > 0 {
+		query.WriteString(" AND sw.siteId IN @siteIds")
+		vars["siteIds"] = siteIDs
+	}
+
+	if prefix := req.GetNamePrefix(); prefix != "" {
+		query.WriteString(" AND LIKE(sw.name, @namePrefix, true)")
+		vars["namePrefix"] = escapeLike(prefix) + "%"
+	}
+
+	if contains := req.GetNameContains(); contains != "" {
+		query.WriteString(" AND LIKE(sw.name, @nameContains, true)")
+		vars["nameContains"] = "%" + escapeLike(contains) + "%"
+	}
+
+	if state := req.GetState(); state != "" {
+		query.WriteString(" AND sw.state == @state")
+		vars["state"] = state
+	}
+
+	// Switches with the same value are sorted by key so pages do not overlap
+	direction := "ASC"
+	if req.GetDescending() {
+		direction = "DESC"
+	}
+	query.WriteString(" SORT sw.@sortBy " + direction + ", sw._key " + direction)
+	vars["sortBy"] = switchSortAttributes[req.GetSortBy()]
+
+	if count > 0 {
+		query.WriteString(" LIMIT @offset, @count")
+		vars["offset"] = offset
+		vars["count"] = count
+	}
+
+	query.WriteString(" RETURN sw")
+
+	return query.String(), vars
+}
+
+// GetSwitches retrieves the switches of some sites, optionally filtered, sorted and paginated.
+// The token of the next page is sent in the trailer and is empty on the last page.
+func (s *SwitchService) GetSwitches(req *proto.GetSwitchesRequest, stream proto.SwitchService_GetSwitchesServer) error {
+	var err error
+	var cursor arango.Cursor
+
+	ctx := stream.Context()
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return toStatus(ErrNoTenant)
+	}
+
+	pageSize := int(req.GetPageSize())
+
+	var violations fieldViolations
+	if _, ok := switchSortAttributes[req.GetSortBy()]; !ok {
+		violations.add("sort_by", fmt.Sprintf("unknown sort field %d", req.GetSortBy()))
+	}
+	if pageSize < 0 {
+		violations.add("page_size", "page size cannot be negative")
+	} else if pageSize > maxSwitchesPageSize {
+		violations.add("page_size", fmt.Sprintf("page size cannot be more than %d", maxSwitchesPageSize))
+	}
+	offset, err := decodePageToken(req.GetPageToken())
+	if err != nil {
+		violations.add("page_token", "invalid page token")
+	}
+	if err := violations.err(); err != nil {
+		return err
+	}
+
+	// A caller restricted to some sites only sees the switches of those sites
+	if sites := auth.Sites(ctx); sites != nil {
+		siteIDs := req.GetSiteIds()
+		if req.GetSiteId() != "" {
+			siteIDs = append([]string{req.GetSiteId()}, siteIDs...)
+		}
+
+		for _, siteID := range siteIDs {
+			if err := auth.CheckSite(ctx, siteID); err != nil {
+				return err
+			}
+		}
+
+		// A caller allowed at no site sees no switch rather than the switches of every site
+		if len(siteIDs) == 0 && len(sites) == 0 {
+			return nil
+		}
+
+		if len(siteIDs) == 0 {
+			restricted := *req
+			restricted.SiteIds = sites
+			req = &restricted
+		}
+	}
+
+	// One more switch than the page size is read to find out whether there is a next page
+	count := 0
+	if pageSize > 0 {
+		count = pageSize + 1
+	}
+
+	query, vars := getSwitchesQuery(tenantID, req, offset, count)
+
+	s.exec(ctx, req, "GetSwitches_Query", query, func() error {
+		cursor, err = s.arango.Query(ctx, query, vars)
+		return err
+	})
+
+	if err != nil {
+		return toStatus(err)
+	}
+
+	defer cursor.Close()
+
+	var nextPageToken string
+	s.exec(ctx, req, "GetSwitches_ReadDocument_Send", "ReadDocument", func() error {
+		for sent := 0; cursor.HasMore(); sent++ {
+			doc := &model.Switch{}
+			_, err = cursor.ReadDocument(ctx, doc)
+			if err != nil {
+				return err
+			}
+
+			if pageSize > 0 && sent == pageSize {
+				nextPageToken = encodePageToken(offset + pageSize)
+				break
+			}
+
+			err = stream.Send(switchToProto(doc))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return toStatus(err)
+	}
+
+	if pageSize > 0 {
+		stream.SetTrailer(metadata.Pairs(nextPageTokenMetadataKey, nextPageToken))
+	}
+
+	return nil
+}
+
+// UpdateSwitch changes the site, name, states, transitions or interlocks of a switch selected by the update mask
+func (s *SwitchService) UpdateSwitch(ctx context.Context, req *proto.UpdateSwitchRequest) (*proto.Switch, error) {
+	sw := req.GetSwitch()
+	key := sw.GetId()
+
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	var violations fieldViolations
+	if key == "" {
+		violations.add("switch.id", "switch id is required")
+	}
+
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		violations.add("update_mask", "at least one field to update is required")
+	}
+
+	for _, path := range paths {
+		switch path {
+		case "site_id":
+			if sw.GetSiteId() == "" {
+				violations.add("switch.site_id", "site id cannot be empty")
+			}
+		case "name":
+			if sw.GetName() == "" {
+				violations.add("switch.name", "name cannot be empty")
+			}
+		case "states":
+			if len(sw.GetStates()) == 0 {
+				violations.add("switch.states", "at least one state is required")
+			}
+		case "transitions":
+			// No transitions allow any change between states
+		case "interlocks":
+			// No interlocks never block a change
+		default:
+			violations.add("update_mask", fmt.Sprintf("field %q cannot be updated", path))
+		}
+	}
+
+	if err := violations.err(); err != nil {
+		return nil, err
+	}
+
+	current, err := s.readSwitch(ctx, req, "UpdateSwitch_ReadDocument", tenantID, key)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	// The patch is a map rather than a switch document so the transitions can be cleared
+	doc := &model.Switch{}
+	patch := map[string]interface{}{}
+	for _, path := range paths {
+		switch path {
+		case "site_id":
+			if err := auth.CheckSite(ctx, sw.GetSiteId()); err != nil {
+				return nil, err
+			}
+			doc.SiteID = sw.GetSiteId()
+			patch["siteId"] = doc.SiteID
+		case "name":
+			doc.Name = sw.GetName()
+			patch["name"] = doc.Name
+		case "states":
+			doc.States = sw.GetStates()
+			patch["states"] = doc.States
+		case "transitions":
+			doc.Transitions = transitionsFromProto(sw.GetTransitions())
+			patch["transitions"] = doc.Transitions
+		case "interlocks":
+			doc.Interlocks = interlocksFromProto(sw.GetInterlocks())
+			patch["interlocks"] = doc.Interlocks
+		}
+	}
+
+	states, transitions := current.States, current.Transitions
+	if _, ok := patch["states"]; ok {
+		states = doc.States
+	}
+	if _, ok := patch["transitions"]; ok {
+		transitions = doc.Transitions
+	}
+	interlocks := current.Interlocks
+	if _, ok := patch["interlocks"]; ok {
+		interlocks = doc.Interlocks
+	}
+
+	// The transitions and interlocks kept from the switch must still be between its states
+	if !hasState(states, current.State) {
+		violations.add("switch.states", fmt.Sprintf("states must include the current state %q", current.State))
+	}
+	validateTransitions(&violations, "switch.transitions", states, transitions)
+	if _, ok := patch["interlocks"]; ok {
+		validateInterlocks(&violations, "switch.interlocks", states, sw.GetInterlocks())
+	} else {
+		validateInterlocks(&violations, "switch.interlocks", states, interlocksToProto(interlocks))
+	}
+	if err = violations.err(); err != nil {
+		return nil, err
+	}
+
+	moved := doc.SiteID != "" && doc.SiteID != current.SiteID
+	if moved && s.sites != nil {
+		if err = s.sites.Validate(ctx, doc.SiteID); err != nil {
+			return nil, toStatus(err)
+		}
+	}
+
+	siteID := current.SiteID
+	if doc.SiteID != "" {
+		siteID = doc.SiteID
+	}
+	_, redefined := patch["states"]
+
+	// The switch and its schedules are written in one transaction, so schedules never keep a site or state the switch no longer has
+	var meta arango.DocumentMeta
+	err = s.arango.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		// The update only succeeds if the switch has not changed since it was read
+		s.exec(ctx, req, "UpdateSwitch_UpdateDocument", "UpdateDocument", func() error {
+			meta, err = s.arango.UpdateDocument(arango.WithRevision(ctx, current.Rev), key, patch)
+			return err
+		})
+
+		if err != nil || (!moved && !redefined) {
+			return err
+		}
+
+		// Schedules keep the site of their switch so they can be listed by site, and are disabled once their state is removed
+		var cursor arango.Cursor
+		vars := map[string]interface{}{
+			"tenantId": tenantID,
+			"switchId": key,
+			"siteId":   siteID,
+			"states":   states,
+		}
+
+		s.exec(ctx, req, "UpdateSwitch_SyncSchedules", querySyncSchedules, func() error {
+			if cursor, err = s.arango.Query(ctx, querySyncSchedules, vars); err != nil {
+				return err
+			}
+			return cursor.Close()
+		})
+
+		return err
+	})
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	updated := switchToProto(current)
+	updated.Id = key
+	updated.Revision = meta.Rev
+	updated.SiteId = siteID
+
+	if doc.Name != "" {
+		updated.Name = doc.Name
+	}
+	updated.States = states
+	updated.Transitions = transitionsToProto(transitions)
+	updated.Interlocks = interlocksToProto(interlocks)
+
+	s.publish(tenantID, proto.SwitchEvent_UPDATED, updated)
+
+	return updated, nil
+}
+
+// SetSwitch changes the state of a switch
+// When an expected revision or state is given, the state is only changed if the switch still matches them.
+// Interlocks blocking the change are only overridden on request by callers with the OverrideInterlocks permission.
+func (s *SwitchService) SetSwitch(ctx context.Context, req *proto.SetSwitchRequest) (*proto.SetSwitchResponse, error) {
+	key := req.GetId()
+
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	if req.GetOverrideInterlocks() && req.GetReason() == "" {
+		var violations fieldViolations
+		violations.add("reason", "reason is required to override interlocks")
+		return nil, violations.err()
+	}
+
+	current, err := s.readSwitch(ctx, req, "SetSwitch_ReadDocument", tenantID, key)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if req.GetOverrideInterlocks() {
+		if err := auth.CheckPermission(ctx, auth.OverrideInterlocks, current.SiteID); err != nil {
+			return nil, err
+		}
+	}
+
+	if !hasState(current.States, req.GetState()) {
+		var violations fieldViolations
+		violations.add("state", fmt.Sprintf("state %q is not one of the switch states", req.GetState()))
+		return nil, violations.err()
+	}
+
+	cas := req.GetExpectedRevision() != "" || req.GetExpectedState() != ""
+	if err := checkExpected(req, current); err != nil {
+		return nil, err
+	}
+
+	if err := checkTransition(current, req.GetState()); err != nil {
+		return nil, err
+	}
+
+	overridden, err := s.checkInterlocks(ctx, tenantID, current, req.GetState())
+	if err != nil && !req.GetOverrideInterlocks() {
+		return nil, err
+	}
+
+	sw, err := s.setState(ctx, req, "SetSwitch", tenantID, current, req.GetState(), req.GetReason(), overridden)
+
+	// Every override is audited, including the ones whose change then fails
+	if len(overridden) > 0 {
+		s.logger.Warn(
+			"audit", "interlock_override",
+			"tenantId", tenantID,
+			"switchId", key,
+			"state", req.GetState(),
+			"interlocks", strings.Join(overridden, ","),
+			"caller", s.extractCaller(ctx),
+			"claimedCaller", s.extractClaimedCaller(ctx),
+			"reason", req.GetReason(),
+			"message", fmt.Sprintf("Interlocks of switch %s overridden to change it to state %s.", key, req.GetState()),
+		)
+	}
+
+	// The switch changed after it was read, so the expectations are checked again against the latest switch
+	if sw == nil && cas && arango.IsPreconditionFailed(err) {
+		latest, rerr := s.readSwitch(ctx, req, "SetSwitch_ReadDocument", tenantID, key)
+		if rerr != nil {
+			return nil, toStatus(rerr)
+		}
+		if rerr = checkExpected(req, latest); rerr != nil {
+			return nil, rerr
+		}
+	}
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	s.publish(tenantID, proto.SwitchEvent_STATE_CHANGED, sw)
+
+	return &proto.SetSwitchResponse{
+		Revision:      sw.Revision,
+		PreviousState: current.State,
+	}, nil
+}
+
+// setState changes the state of a switch, if it has not changed since it was read, and records the change in the history and the outbox.
+// The state change, its history and its event are written in one transaction, so a state is never changed without being recorded.
+// overridden are the names of the interlocks that blocked the change and were overridden.
+func (s *SwitchService) setState(ctx context.Context, req interface{}, op, tenantID string, current *model.Switch, state, reason string, overridden []string) (*proto.Switch, error) {
+	updated := *current
+	updated.State = state
+
+	// The patch is a map rather than a switch document so the pending and out of sync flags can be cleared
+	patch := map[string]interface{}{
+		"state": state,
+	}
+
+	// The device has to converge to the state in time only if its agent reports its state.
+	// Every change restarts the time the device has, including a change to the same state.
+	if current.ReportedAt != 0 {
+		updated.Pending = state != current.ReportedState
+		updated.PendingSince = 0
+		updated.OutOfSync = false
+		if updated.Pending {
+			updated.PendingSince = time.Now().UnixNano()
+		}
+
+		patch["pending"] = updated.Pending
+		patch["pendingSince"] = updated.PendingSince
+		patch["outOfSync"] = updated.OutOfSync
+	}
+
+	change := &model.SwitchStateChange{
+		TenantID:             tenantID,
+		SwitchID:             current.Key,
+		PreviousState:        current.State,
+		State:                state,
+		Caller:               s.extractCaller(ctx),
+		ClaimedCaller:        s.extractClaimedCaller(ctx),
+		Reason:               reason,
+		OverriddenInterlocks: overridden,
+	}
+
+	var sw *proto.Switch
+
+	err := s.arango.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		var meta arango.DocumentMeta
+
+		s.exec(ctx, req, op+"_UpdateDocument", "UpdateDocument", func() error {
+			meta, err = s.arango.UpdateDocument(arango.WithRevision(ctx, current.Rev), current.Key, patch)
+			return err
+		})
+
+		if err != nil {
+			return err
+		}
+
+		updated.Rev = meta.Rev
+		change.Time = time.Now().UnixNano()
+
+		s.exec(ctx, req, op+"_CreateHistoryDocument", "CreateDocument", func() error {
+			_, err = s.arango.CreateHistoryDocument(ctx, change)
+			return err
+		})
+
+		if err != nil {
+			return err
+		}
+
+		sw = switchToProto(&updated)
+
+		return s.enqueue(ctx, req, op, tenantID, event.SubjectStateChanged, sw, current.State)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sw, nil
+}
+
+// checkExpected returns a FailedPrecondition status if a switch does not match the expected revision or state of a request
+func checkExpected(req *proto.SetSwitchRequest, sw *model.Switch) error {
+	var violations preconditionViolations
+	if rev := req.GetExpectedRevision(); rev != "" && rev != sw.Rev {
+		violations.add("REVISION", fmt.Sprintf("expected revision %q but switch is at revision %q", rev, sw.Rev))
+	}
+	if state := req.GetExpectedState(); state != "" && state != sw.State {
+		violations.add("STATE", fmt.Sprintf("expected state %q but switch is in state %q", state, sw.State))
+	}
+
+	return violations.err(switchToProto(sw))
+}
+
+// GetSwitchHistory returns the state changes of a switch, newest first
+func (s *SwitchService) GetSwitchHistory(ctx context.Context, req *proto.GetSwitchHistoryRequest) (*proto.GetSwitchHistoryResponse, error) {
+	var err error
+	var cursor arango.Cursor
+
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	fromTime, toTime := req.GetFromTime(), req.GetToTime()
+	if toTime == 0 {
+		toTime = math.MaxInt64
+	}
+
+	pageSize := int(req.GetPageSize())
+	if pageSize == 0 {
+		pageSize = defaultHistoryPageSize
+	} else if pageSize > maxHistoryPageSize {
+		pageSize = maxHistoryPageSize
+	}
+
+	var violations fieldViolations
+	if req.GetSwitchId() == "" {
+		violations.add("switch_id", "switch id is required")
+	}
+	if fromTime < 0 {
+		violations.add("from_time", "from time cannot be negative")
+	}
+	if toTime < fromTime {
+		violations.add("to_time", "to time cannot be before from time")
+	}
+	if pageSize < 0 {
+		violations.add("page_size", "page size cannot be negative")
+	}
+	// The first page starts right before the to time, which is excluded anyway
+	afterTime, afterKey := toTime, ""
+	if token := req.GetPageToken(); token != "" {
+		if afterTime, afterKey, err = decodeHistoryToken(token); err != nil {
+			violations.add("page_token", "invalid page token")
+		}
+	}
+	if err := violations.err(); err != nil {
+		return nil, err
+	}
+
+	// Changes carry no site, so the site of a caller restricted to some sites is checked on the switch
+	if auth.Sites(ctx) != nil {
+		if _, err := s.readSwitch(ctx, req, "GetSwitchHistory_ReadDocument", tenantID, req.GetSwitchId()); err != nil {
+			return nil, toStatus(err)
+		}
+	}
+
+	// One more change than the page size is read to find out whether there is a next page
+	vars := map[string]interface{}{
+		"tenantId":  tenantID,
+		"switchId":  req.GetSwitchId(),
+		"fromTime":  fromTime,
+		"toTime":    toTime,
+		"afterTime": afterTime,
+		"afterKey":  afterKey,
+		"count":     pageSize + 1,
+	}
+
+	s.exec(ctx, req, "GetSwitchHistory_Query", queryGetSwitchHistory, func() error {
+		cursor, err = s.arango.Query(ctx, queryGetSwitchHistory, vars)
+		return err
+	})
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	defer cursor.Close()
+
+	resp := &proto.GetSwitchHistoryResponse{}
+
+	s.exec(ctx, req, "GetSwitchHistory_ReadDocument", "ReadDocument", func() error {
+		var last model.SwitchStateChange
+		for cursor.HasMore() {
+			doc := &model.SwitchStateChange{}
+			if _, err = cursor.ReadDocument(ctx, doc); err != nil {
+				return err
+			}
+
+			if len(resp.Changes) == pageSize {
+				resp.NextPageToken = encodeHistoryToken(last.Time, last.Key)
+				break
+			}
+
+			last = *doc
+
+			resp.Changes = append(resp.Changes, &proto.SwitchStateChange{
+				SwitchId:             doc.SwitchID,
+				PreviousState:        doc.PreviousState,
+				State:                doc.State,
+				Time:                 doc.Time,
+				Caller:               doc.Caller,
+				ClaimedCaller:        doc.ClaimedCaller,
+				Reason:               doc.Reason,
+				OverriddenInterlocks: doc.OverriddenInterlocks,
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return resp, nil
+}
+
+// WatchSwitches sends the current state of a group of switches and then every change to them.
+// Events are delivered at least once, so a change racing with the current state may be sent twice.
+func (s *SwitchService) WatchSwitches(req *proto.WatchSwitchesRequest, stream proto.SwitchService_WatchSwitchesServer) error {
+	ctx := stream.Context()
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return toStatus(ErrNoTenant)
+	}
+
+	siteID := req.GetSiteId()
+	keys := make(map[string]bool)
+	for _, id := range req.GetIds() {
+		keys[id] = true
+	}
+
+	if siteID == "" && len(keys) == 0 {
+		var violations fieldViolations
+		violations.add("site_id", "either a site id or switch ids are required")
+		return violations.err()
+	}
+
+	if siteID != "" {
+		if err := auth.CheckSite(ctx, siteID); err != nil {
+			return err
+		}
+	}
+
+	// Switches watched by id are left out if the caller is not allowed at their site
+	filter := func(e broker.Event) bool {
+		return e.TenantID == tenantID && ((siteID != "" && e.Switch.SiteId == siteID) || keys[e.Switch.Id]) && auth.AllowsSite(ctx, e.Switch.SiteId)
+	}
+
+	// Subscribe before reading the current state, so no change is missed in between
+	sub, revision, missed, err := s.broker.Subscribe(filter, req.GetFromRevision())
+	if err == broker.ErrUnknownRevision {
+		return status.Error(codes.OutOfRange, err.Error())
+	} else if err != nil {
+		return toStatus(err)
+	}
+
+	defer s.broker.Unsubscribe(sub)
+
+	if req.GetFromRevision() == 0 {
+		if err = s.sendCurrent(ctx, req, stream, tenantID, siteID, req.GetIds(), revision); err != nil {
+			return toStatus(err)
+		}
+	}
+
+	for _, e := range missed {
+		if err = stream.Send(e.SwitchEvent); err != nil {
+			return toStatus(err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.Done():
+			return status.Error(codes.ResourceExhausted, broker.ErrSlowSubscriber.Error())
+		case e := <-sub.Events():
+			if err = stream.Send(e.SwitchEvent); err != nil {
+				return toStatus(err)
+			}
+		}
+	}
+}
+
+// sendCurrent sends the current state of a group of switches as of a revision
+func (s *SwitchService) sendCurrent(ctx context.Context, req interface{}, stream proto.SwitchService_WatchSwitchesServer, tenantID, siteID string, ids []string, revision uint64) error {
+	var err error
+	var cursor arango.Cursor
+
+	if ids == nil {
+		ids = []string{}
+	}
+
+	vars := map[string]interface{}{
+		"tenantId": tenantID,
+		"siteId":   siteID,
+		"keys":     ids,
+	}
+
+	s.exec(ctx, req, "WatchSwitches_Query", queryWatchSwitches, func() error {
+		cursor, err = s.arango.Query(ctx, queryWatchSwitches, vars)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	defer cursor.Close()
+
+	s.exec(ctx, req, "WatchSwitches_ReadDocument_Send", "ReadDocument", func() error {
+		for cursor.HasMore() {
+			doc := &model.Switch{}
+			if _, err = cursor.ReadDocument(ctx, doc); err != nil {
+				return err
+			}
+
+			if !auth.AllowsSite(ctx, doc.SiteID) {
+				continue
+			}
+
+			err = stream.Send(&proto.SwitchEvent{
+				Type:     proto.SwitchEvent_CURRENT,
+				Revision: revision,
+				Switch:   switchToProto(doc),
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return err
 }
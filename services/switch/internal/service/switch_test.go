@@ -2,26 +2,56 @@ package service
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/moorara/microservices-demo/pkg/site"
+	"github.com/moorara/microservices-demo/services/switch/internal/auth"
+	"github.com/moorara/microservices-demo/services/switch/internal/broker"
 	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
-	"github.com/moorara/microservices-demo/services/switch/internal/proto"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
 	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 
 	arango "github.com/arangodb/go-driver"
 )
 
+const testTenantID = "tttt-tttt"
+
+func contextWithTenant(tenantID string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(tenantMetadataKey, tenantID))
+}
+
 func TestNewSwitchService(t *testing.T) {
 	tests := []struct {
 		name   string
 		arango ArangoService
+		quota  int
 	}{
 		{
 			"Default",
 			&mockArangoService{},
+			0,
+		},
+		{
+			"WithQuota",
+			&mockArangoService{},
+			10,
 		},
 	}
 
@@ -30,7 +60,7 @@ func TestNewSwitchService(t *testing.T) {
 			logger := log.NewVoidLogger()
 			metrics := metrics.Mock()
 			tracer := mocktracer.New()
-			service := NewSwitchService(tc.arango, logger, metrics, tracer)
+			service := NewSwitchService(tc.arango, tc.quota, nil, nil, broker.New(0, 0), false, logger, metrics, tracer)
 
 			assert.NotNil(t, service)
 		})
@@ -40,25 +70,155 @@ func TestNewSwitchService(t *testing.T) {
 func TestInstallSwitch(t *testing.T) {
 	tests := []struct {
 		name           string
-		arango         ArangoService
+		arango         *mockArangoService
+		quota          int
+		sites          site.Client
 		ctx            context.Context
 		req            *proto.InstallSwitchRequest
-		expectedError  error
+		expectedCode   codes.Code
 		expectedSwitch *proto.Switch
 	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			0,
+			nil,
+			context.Background(),
+			&proto.InstallSwitchRequest{
+				SiteId: "1111-1111",
+				Name:   "Light",
+				State:  "OFF",
+				States: []string{"ON", "OFF"},
+			},
+			codes.Unauthenticated,
+			nil,
+		},
+		{
+			"MissingFields",
+			&mockArangoService{},
+			0,
+			nil,
+			contextWithTenant(testTenantID),
+			&proto.InstallSwitchRequest{},
+			codes.InvalidArgument,
+			nil,
+		},
+		{
+			"InvalidState",
+			&mockArangoService{},
+			0,
+			nil,
+			contextWithTenant(testTenantID),
+			&proto.InstallSwitchRequest{
+				SiteId: "1111-1111",
+				Name:   "Light",
+				State:  "DIM",
+				States: []string{"ON", "OFF"},
+			},
+			codes.InvalidArgument,
+			nil,
+		},
+		{
+			"InvalidTransitions",
+			&mockArangoService{},
+			0,
+			nil,
+			contextWithTenant(testTenantID),
+			&proto.InstallSwitchRequest{
+				SiteId: "1111-1111",
+				Name:   "Light",
+				State:  "OFF",
+				States: []string{"ON", "OFF"},
+				Transitions: []*proto.Transition{
+					{From: "OFF", To: []string{"DIM"}},
+					{From: "OFF", To: []string{"ON"}},
+				},
+			},
+			codes.InvalidArgument,
+			nil,
+		},
+		{
+			"UnknownSite",
+			&mockArangoService{},
+			0,
+			&mockSiteClient{ValidateOutError: site.ErrUnknownSite},
+			contextWithTenant(testTenantID),
+			&proto.InstallSwitchRequest{
+				SiteId: "0000-0000",
+				Name:   "Light",
+				State:  "OFF",
+				States: []string{"ON", "OFF"},
+			},
+			codes.InvalidArgument,
+			nil,
+		},
+		{
+			"SiteServiceUnavailable",
+			&mockArangoService{},
+			0,
+			&mockSiteClient{ValidateOutError: site.ErrUnavailable},
+			contextWithTenant(testTenantID),
+			&proto.InstallSwitchRequest{
+				SiteId: "1111-1111",
+				Name:   "Light",
+				State:  "OFF",
+				States: []string{"ON", "OFF"},
+			},
+			codes.Unavailable,
+			nil,
+		},
+		{
+			"CountFail",
+			&mockArangoService{
+				QueryOutError: errors.New("database error"),
+			},
+			2,
+			nil,
+			contextWithTenant(testTenantID),
+			&proto.InstallSwitchRequest{
+				SiteId: "1111-1111",
+				Name:   "Light",
+				State:  "OFF",
+				States: []string{"ON", "OFF"},
+			},
+			codes.Internal,
+			nil,
+		},
+		{
+			"QuotaExceeded",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:         &mockCloser{},
+					CountOutResult: 2,
+				},
+			},
+			2,
+			nil,
+			contextWithTenant(testTenantID),
+			&proto.InstallSwitchRequest{
+				SiteId: "1111-1111",
+				Name:   "Light",
+				State:  "OFF",
+				States: []string{"ON", "OFF"},
+			},
+			codes.ResourceExhausted,
+			nil,
+		},
 		{
 			"Fail",
 			&mockArangoService{
 				CreateDocumentOutError: errors.New("database error"),
 			},
-			context.Background(),
+			0,
+			nil,
+			contextWithTenant(testTenantID),
 			&proto.InstallSwitchRequest{
 				SiteId: "1111-1111",
 				Name:   "Light",
 				State:  "OFF",
 				States: []string{"ON", "OFF"},
 			},
-			errors.New("database error"),
+			codes.Internal,
 			nil,
 		},
 		{
@@ -68,14 +228,45 @@ func TestInstallSwitch(t *testing.T) {
 					Key: "aaaa-aaaa",
 				},
 			},
-			context.Background(),
+			0,
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
 			&proto.InstallSwitchRequest{
 				SiteId: "1111-1111",
 				Name:   "Light",
 				State:  "OFF",
 				States: []string{"ON", "OFF"},
 			},
+			codes.OK,
+			&proto.Switch{
+				Id:     "aaaa-aaaa",
+				SiteId: "1111-1111",
+				Name:   "Light",
+				State:  "OFF",
+				States: []string{"ON", "OFF"},
+			},
+		},
+		{
+			"SuccessWithinQuota",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:         &mockCloser{},
+					CountOutResult: 1,
+				},
+				CreateDocumentOutMeta: arango.DocumentMeta{
+					Key: "aaaa-aaaa",
+				},
+			},
+			2,
 			nil,
+			contextWithTenant(testTenantID),
+			&proto.InstallSwitchRequest{
+				SiteId: "1111-1111",
+				Name:   "Light",
+				State:  "OFF",
+				States: []string{"ON", "OFF"},
+			},
+			codes.OK,
 			&proto.Switch{
 				Id:     "aaaa-aaaa",
 				SiteId: "1111-1111",
@@ -93,6 +284,8 @@ func TestInstallSwitch(t *testing.T) {
 			tracer := mocktracer.New()
 			service := &SwitchService{
 				arango:  tc.arango,
+				quota:   tc.quota,
+				sites:   tc.sites,
 				logger:  logger,
 				metrics: metrics,
 				tracer:  tracer,
@@ -100,8 +293,27 @@ func TestInstallSwitch(t *testing.T) {
 
 			sw, err := service.InstallSwitch(tc.ctx, tc.req)
 
-			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedCode, status.Code(err))
 			assert.Equal(t, tc.expectedSwitch, sw)
+
+			// The quota is checked in the transaction creating the switch after writing the quota document of the tenant
+			if tc.quota > 0 && tc.arango.QueryCalled {
+				assert.True(t, tc.arango.TransactionCalled)
+				assert.Equal(t, queryLockQuota, tc.arango.QueryInQueries[0])
+				assert.Equal(t, testTenantID, tc.arango.QueryInVars["tenantId"])
+				if tc.arango.QueryOutError == nil {
+					assert.Equal(t, queryCountSwitches, tc.arango.QueryInQuery)
+				}
+			}
+
+			if tc.expectedCode == codes.InvalidArgument || tc.expectedCode == codes.Unavailable {
+				assert.False(t, tc.arango.CreateDocumentCalled)
+			}
+
+			if tc.expectedSwitch != nil {
+				doc := tc.arango.CreateDocumentInDoc.(*model.Switch)
+				assert.Equal(t, testTenantID, doc.TenantID)
+			}
 		})
 	}
 }
@@ -109,34 +321,70 @@ func TestInstallSwitch(t *testing.T) {
 func TestRemoveSwitch(t *testing.T) {
 	tests := []struct {
 		name             string
-		arango           ArangoService
+		arango           *mockArangoService
 		ctx              context.Context
 		req              *proto.RemoveSwitchRequest
-		expectedError    error
+		expectedCode     codes.Code
 		expectedResponse *proto.RemoveSwitchResponse
 	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			&proto.RemoveSwitchRequest{
+				Id: "aaaa-aaaa",
+			},
+			codes.Unauthenticated,
+			nil,
+		},
+		{
+			"ReadFail",
+			&mockArangoService{
+				ReadDocumentOutError: errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			&proto.RemoveSwitchRequest{
+				Id: "aaaa-aaaa",
+			},
+			codes.Internal,
+			nil,
+		},
+		{
+			"OtherTenant",
+			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{TenantID: "uuuu-uuuu"},
+			},
+			contextWithTenant(testTenantID),
+			&proto.RemoveSwitchRequest{
+				Id: "aaaa-aaaa",
+			},
+			codes.NotFound,
+			nil,
+		},
 		{
 			"Fail",
 			&mockArangoService{
+				ReadDocumentOutDoc:     &model.Switch{TenantID: testTenantID},
 				RemoveDocumentOutError: errors.New("database error"),
 			},
-			context.Background(),
+			contextWithTenant(testTenantID),
 			&proto.RemoveSwitchRequest{
 				Id: "aaaa-aaaa",
 			},
-			errors.New("database error"),
+			codes.Internal,
 			nil,
 		},
 		{
 			"Success",
 			&mockArangoService{
+				ReadDocumentOutDoc:    &model.Switch{TenantID: testTenantID},
 				RemoveDocumentOutMeta: arango.DocumentMeta{},
 			},
-			context.Background(),
+			contextWithTenant(testTenantID),
 			&proto.RemoveSwitchRequest{
 				Id: "aaaa-aaaa",
 			},
-			nil,
+			codes.OK,
 			&proto.RemoveSwitchResponse{},
 		},
 	}
@@ -155,8 +403,12 @@ func TestRemoveSwitch(t *testing.T) {
 
 			resp, err := service.RemoveSwitch(tc.ctx, tc.req)
 
-			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedCode, status.Code(err))
 			assert.Equal(t, tc.expectedResponse, resp)
+
+			if tc.expectedCode == codes.NotFound {
+				assert.False(t, tc.arango.RemoveDocumentCalled)
+			}
 		})
 	}
 }
@@ -167,34 +419,102 @@ func TestGetSwitch(t *testing.T) {
 		arango         ArangoService
 		ctx            context.Context
 		req            *proto.GetSwitchRequest
-		expectedError  error
+		expectedCode   codes.Code
 		expectedSwitch *proto.Switch
 	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			&proto.GetSwitchRequest{
+				Id: "aaaa-aaaa",
+			},
+			codes.Unauthenticated,
+			nil,
+		},
 		{
 			"Fail",
 			&mockArangoService{
 				ReadDocumentOutError: errors.New("database error"),
 			},
-			context.Background(),
+			contextWithTenant(testTenantID),
+			&proto.GetSwitchRequest{
+				Id: "aaaa-aaaa",
+			},
+			codes.Internal,
+			nil,
+		},
+		{
+			"NotFound",
+			&mockArangoService{
+				ReadDocumentOutError: arango.ArangoError{HasError: true, Code: 404, ErrorNum: 1202},
+			},
+			contextWithTenant(testTenantID),
+			&proto.GetSwitchRequest{
+				Id: "aaaa-aaaa",
+			},
+			codes.NotFound,
+			nil,
+		},
+		{
+			"OtherTenant",
+			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{
+					Key:      "aaaa-aaaa",
+					TenantID: "uuuu-uuuu",
+					SiteID:   "1111-1111",
+				},
+			},
+			contextWithTenant(testTenantID),
+			&proto.GetSwitchRequest{
+				Id: "aaaa-aaaa",
+			},
+			codes.NotFound,
+			nil,
+		},
+		{
+			"SiteNotAllowed",
+			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{
+					Key:      "aaaa-aaaa",
+					TenantID: testTenantID,
+					SiteID:   "1111-1111",
+				},
+			},
+			auth.NewContext(contextWithTenant(testTenantID), &auth.Grant{Sites: []string{"2222-2222"}}),
 			&proto.GetSwitchRequest{
 				Id: "aaaa-aaaa",
 			},
-			errors.New("database error"),
+			codes.PermissionDenied,
 			nil,
 		},
 		{
 			"Success",
 			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{
+					Key:      "aaaa-aaaa",
+					TenantID: testTenantID,
+					SiteID:   "1111-1111",
+					Name:     "Light",
+					State:    "OFF",
+					States:   []string{"ON", "OFF"},
+				},
 				ReadDocumentOutMeta: arango.DocumentMeta{
 					Key: "aaaa-aaaa",
 				},
 			},
-			context.Background(),
+			contextWithTenant(testTenantID),
 			&proto.GetSwitchRequest{
 				Id: "aaaa-aaaa",
 			},
-			nil,
-			&proto.Switch{},
+			codes.OK,
+			&proto.Switch{
+				Id:     "aaaa-aaaa",
+				SiteId: "1111-1111",
+				Name:   "Light",
+				State:  "OFF",
+				States: []string{"ON", "OFF"},
+			},
 		},
 	}
 
@@ -212,97 +532,1003 @@ func TestGetSwitch(t *testing.T) {
 
 			sw, err := service.GetSwitch(tc.ctx, tc.req)
 
-			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedCode, status.Code(err))
 			assert.Equal(t, tc.expectedSwitch, sw)
 		})
 	}
 }
 
-func TestGetSwitches(t *testing.T) {
+func TestGetAllowedTransitions(t *testing.T) {
+	breaker := []model.Transition{
+		{From: "CLOSED", To: []string{"OPEN", "TRIPPED"}},
+		{From: "OPEN", To: []string{"CLOSED"}},
+		{From: "TRIPPED", To: []string{"RESET"}},
+		{From: "RESET", To: []string{"CLOSED", "OPEN"}},
+	}
+
 	tests := []struct {
-		name          string
-		arango        ArangoService
-		req           *proto.GetSwitchesRequest
-		stream        *mockGetSwitchesServer
-		expectedError error
+		name             string
+		arango           ArangoService
+		ctx              context.Context
+		req              *proto.GetAllowedTransitionsRequest
+		expectedCode     codes.Code
+		expectedResponse *proto.GetAllowedTransitionsResponse
 	}{
 		{
-			"QueryError",
-			&mockArangoService{
-				QueryOutError: errors.New("database error"),
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			&proto.GetAllowedTransitionsRequest{
+				Id: "aaaa-aaaa",
 			},
-			&proto.GetSwitchesRequest{
-				SiteId: "1111-1111",
+			codes.Unauthenticated,
+			nil,
+		},
+		{
+			"NotFound",
+			&mockArangoService{
+				ReadDocumentOutError: arango.ArangoError{HasError: true, Code: 404, ErrorNum: 1202},
 			},
-			&mockGetSwitchesServer{
-				ServerStream: &mockServerStream{
-					ContextOutContext: context.Background(),
-				},
+			contextWithTenant(testTenantID),
+			&proto.GetAllowedTransitionsRequest{
+				Id: "aaaa-aaaa",
 			},
-			errors.New("database error"),
+			codes.NotFound,
+			nil,
 		},
 		{
-			"ReadDocumentError",
+			"NoTransitions",
 			&mockArangoService{
-				QueryOutCursor: &mockArangoCursor{
-					Closer:               &mockCloser{},
-					HasMoreOutResults:    []bool{true},
-					ReadDocumentOutError: errors.New("cursor error"),
-				},
-			},
-			&proto.GetSwitchesRequest{
-				SiteId: "1111-1111",
+				ReadDocumentOutDoc: &model.Switch{TenantID: testTenantID, Rev: "_aaaa", State: "OFF", States: []string{"OFF", "DIM", "ON"}},
 			},
-			&mockGetSwitchesServer{
-				ServerStream: &mockServerStream{
-					ContextOutContext: context.Background(),
-				},
+			contextWithTenant(testTenantID),
+			&proto.GetAllowedTransitionsRequest{
+				Id: "aaaa-aaaa",
 			},
-			errors.New("cursor error"),
+			codes.OK,
+			&proto.GetAllowedTransitionsResponse{CurrentState: "OFF", AllowedStates: []string{"DIM", "ON"}, Revision: "_aaaa"},
 		},
 		{
-			"SendError",
+			"Transitions",
 			&mockArangoService{
-				QueryOutCursor: &mockArangoCursor{
-					Closer:            &mockCloser{},
-					HasMoreOutResults: []bool{true},
-					ReadDocumentOutMeta: arango.DocumentMeta{
-						Key: "aaaa-aaaa",
-					},
-				},
-			},
-			&proto.GetSwitchesRequest{
-				SiteId: "1111-1111",
+				ReadDocumentOutDoc: &model.Switch{TenantID: testTenantID, Rev: "_aaaa", State: "TRIPPED", States: []string{"CLOSED", "OPEN", "TRIPPED", "RESET"}, Transitions: breaker},
 			},
-			&mockGetSwitchesServer{
-				ServerStream: &mockServerStream{
-					ContextOutContext: context.Background(),
-				},
-				SendOutError: errors.New("stream error"),
+			contextWithTenant(testTenantID),
+			&proto.GetAllowedTransitionsRequest{
+				Id: "aaaa-aaaa",
 			},
-			errors.New("stream error"),
+			codes.OK,
+			&proto.GetAllowedTransitionsResponse{CurrentState: "TRIPPED", AllowedStates: []string{"RESET"}, Revision: "_aaaa"},
+		},
+		{
+			"FinalState",
+			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{TenantID: testTenantID, Rev: "_aaaa", State: "RETIRED", States: []string{"ACTIVE", "RETIRED"}, Transitions: []model.Transition{
+					{From: "ACTIVE", To: []string{"RETIRED"}},
+				}},
+			},
+			contextWithTenant(testTenantID),
+			&proto.GetAllowedTransitionsRequest{
+				Id: "aaaa-aaaa",
+			},
+			codes.OK,
+			&proto.GetAllowedTransitionsResponse{CurrentState: "RETIRED", AllowedStates: []string{}, Revision: "_aaaa"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := log.NewVoidLogger()
+			metrics := metrics.Mock()
+			tracer := mocktracer.New()
+			service := &SwitchService{
+				arango:  tc.arango,
+				logger:  logger,
+				metrics: metrics,
+				tracer:  tracer,
+			}
+
+			resp, err := service.GetAllowedTransitions(tc.ctx, tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedResponse, resp)
+		})
+	}
+}
+
+func TestGetSwitchesQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           *proto.GetSwitchesRequest
+		offset, count int
+		expectedQuery string
+		expectedVars  map[string]interface{}
+	}{
+		{
+			"Site",
+			&proto.GetSwitchesRequest{SiteId: "1111-1111"},
+			0, 0,
+			`FOR sw IN switches FILTER sw.tenantId == @tenantId AND sw.siteId IN @siteIds SORT sw.@sortBy ASC, sw._key ASC RETURN sw`,
+			map[string]interface{}{"tenantId": testTenantID, "siteIds": []string{"1111-1111"}, "sortBy": "name"},
+		},
+		{
+			"AllSites",
+			&proto.GetSwitchesRequest{SortBy: proto.GetSwitchesRequest_STATE, Descending: true},
+			0, 0,
+			`FOR sw IN switches FILTER sw.tenantId == @tenantId SORT sw.@sortBy DESC, sw._key DESC RETURN sw`,
+			map[string]interface{}{"tenantId": testTenantID, "sortBy": "state"},
+		},
+		{
+			"FiltersAndPage",
+			&proto.GetSwitchesRequest{
+				SiteId:       "1111-1111",
+				SiteIds:      []string{"2222-2222"},
+				NamePrefix:   "50%_",
+				NameContains: `a\b`,
+				State:        "ON",
+				SortBy:       proto.GetSwitchesRequest_SITE_ID,
+			},
+			20, 11,
+			`FOR sw IN switches FILTER sw.tenantId == @tenantId AND sw.siteId IN @siteIds AND LIKE(sw.name, @namePrefix, true) AND LIKE(sw.name, @nameContains, true) AND sw.state == @state SORT sw.@sortBy ASC, sw._key ASC LIMIT @offset, @count RETURN sw`,
+			map[string]interface{}{
+				"tenantId":     testTenantID,
+				"siteIds":      []string{"1111-1111", "2222-2222"},
+				"namePrefix":   `50\%\_%`,
+				"nameContains": `%a\\b%`,
+				"state":        "ON",
+				"sortBy":       "siteId",
+				"offset":       20,
+				"count":        11,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query, vars := getSwitchesQuery(testTenantID, tc.req, tc.offset, tc.count)
+
+			assert.Equal(t, tc.expectedQuery, query)
+			assert.Equal(t, tc.expectedVars, vars)
+		})
+	}
+}
+
+func TestGetSwitches(t *testing.T) {
+	docs := []interface{}{
+		&model.Switch{Key: "aaaa-aaaa", TenantID: testTenantID, SiteID: "1111-1111", Name: "Fan"},
+		&model.Switch{Key: "bbbb-bbbb", TenantID: testTenantID, SiteID: "1111-1111", Name: "Heater"},
+		&model.Switch{Key: "cccc-cccc", TenantID: testTenantID, SiteID: "1111-1111", Name: "Light"},
+	}
+
+	tests := []struct {
+		name            string
+		arango          *mockArangoService
+		req             *proto.GetSwitchesRequest
+		stream          *mockGetSwitchesServer
+		expectedCode    codes.Code
+		expectedFields  []string
+		expectedSent    int
+		expectedTrailer metadata.MD
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			&proto.GetSwitchesRequest{
+				SiteId: "1111-1111",
+			},
+			&mockGetSwitchesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: context.Background(),
+				},
+			},
+			codes.Unauthenticated,
+			nil, 0, nil,
+		},
+		{
+			"InvalidRequest",
+			&mockArangoService{},
+			&proto.GetSwitchesRequest{
+				SortBy:    proto.GetSwitchesRequest_SortBy(9),
+				PageSize:  -1,
+				PageToken: "invalid",
+			},
+			&mockGetSwitchesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: contextWithTenant(testTenantID),
+				},
+			},
+			codes.InvalidArgument,
+			[]string{"sort_by", "page_size", "page_token"},
+			0, nil,
+		},
+		{
+			"PageTooLarge",
+			&mockArangoService{},
+			&proto.GetSwitchesRequest{
+				PageSize: maxSwitchesPageSize + 1,
+			},
+			&mockGetSwitchesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: contextWithTenant(testTenantID),
+				},
+			},
+			codes.InvalidArgument,
+			[]string{"page_size"},
+			0, nil,
+		},
+		{
+			"QueryError",
+			&mockArangoService{
+				QueryOutError: errors.New("database error"),
+			},
+			&proto.GetSwitchesRequest{
+				SiteId: "1111-1111",
+			},
+			&mockGetSwitchesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: contextWithTenant(testTenantID),
+				},
+			},
+			codes.Internal,
+			nil, 0, nil,
+		},
+		{
+			"ReadDocumentError",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:               &mockCloser{},
+					HasMoreOutResults:    []bool{true},
+					ReadDocumentOutError: errors.New("cursor error"),
+				},
+			},
+			&proto.GetSwitchesRequest{
+				SiteId: "1111-1111",
+			},
+			&mockGetSwitchesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: contextWithTenant(testTenantID),
+				},
+			},
+			codes.Internal,
+			nil, 0, nil,
+		},
+		{
+			"SendError",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:            &mockCloser{},
+					HasMoreOutResults: []bool{true},
+					ReadDocumentOutMeta: arango.DocumentMeta{
+						Key: "aaaa-aaaa",
+					},
+				},
+			},
+			&proto.GetSwitchesRequest{
+				SiteId: "1111-1111",
+			},
+			&mockGetSwitchesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: contextWithTenant(testTenantID),
+				},
+				SendOutError: errors.New("stream error"),
+			},
+			codes.Internal,
+			nil, 1, nil,
+		},
+		{
+			"Success",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:            &mockCloser{},
+					HasMoreOutResults: []bool{true, false},
+					ReadDocumentOutMeta: arango.DocumentMeta{
+						Key: "aaaa-aaaa",
+					},
+				},
+			},
+			&proto.GetSwitchesRequest{
+				SiteId: "1111-1111",
+			},
+			&mockGetSwitchesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: contextWithTenant(testTenantID),
+				},
+				SendOutError: nil,
+			},
+			codes.OK,
+			nil, 1, nil,
+		},
+		{
+			"FirstPage",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(docs...),
+			},
+			&proto.GetSwitchesRequest{
+				SiteId:   "1111-1111",
+				PageSize: 2,
+			},
+			&mockGetSwitchesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: contextWithTenant(testTenantID),
+				},
+			},
+			codes.OK,
+			nil, 2,
+			metadata.Pairs(nextPageTokenMetadataKey, encodePageToken(2)),
+		},
+		{
+			"LastPage",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(docs[2:]...),
+			},
+			&proto.GetSwitchesRequest{
+				SiteId:    "1111-1111",
+				PageSize:  2,
+				PageToken: encodePageToken(2),
+			},
+			&mockGetSwitchesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: contextWithTenant(testTenantID),
+				},
+			},
+			codes.OK,
+			nil, 1,
+			metadata.Pairs(nextPageTokenMetadataKey, ""),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := log.NewVoidLogger()
+			metrics := metrics.Mock()
+			tracer := mocktracer.New()
+			service := &SwitchService{
+				arango:  tc.arango,
+				logger:  logger,
+				metrics: metrics,
+				tracer:  tracer,
+			}
+
+			err := service.GetSwitches(tc.req, tc.stream)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedSent, tc.stream.SendCallCount)
+			assert.Equal(t, tc.expectedTrailer, tc.stream.ServerStream.(*mockServerStream).SetTrailerInMeta)
+			if tc.expectedFields != nil {
+				assert.Equal(t, tc.expectedFields, violatedFields(err))
+			}
+
+			if tc.arango.QueryCalled {
+				assert.Equal(t, testTenantID, tc.arango.QueryInVars["tenantId"])
+				if tc.req.PageSize > 0 {
+					assert.Equal(t, int(tc.req.PageSize)+1, tc.arango.QueryInVars["count"])
+				}
+			}
+		})
+	}
+}
+
+func TestGetSwitchesRestricted(t *testing.T) {
+	ctx := auth.NewContext(contextWithTenant(testTenantID), &auth.Grant{Sites: []string{"1111-1111", "2222-2222"}})
+
+	tests := []struct {
+		name            string
+		req             *proto.GetSwitchesRequest
+		expectedCode    codes.Code
+		expectedSiteIDs interface{}
+	}{
+		{"SiteNotAllowed", &proto.GetSwitchesRequest{SiteId: "3333-3333"}, codes.PermissionDenied, nil},
+		{"OneSiteNotAllowed", &proto.GetSwitchesRequest{SiteIds: []string{"1111-1111", "3333-3333"}}, codes.PermissionDenied, nil},
+		{"AllowedSite", &proto.GetSwitchesRequest{SiteId: "1111-1111"}, codes.OK, []string{"1111-1111"}},
+		{"AllowedSites", &proto.GetSwitchesRequest{}, codes.OK, []string{"1111-1111", "2222-2222"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			arango := &mockArangoService{
+				QueryOutCursor: newMockCursor(),
+			}
+			service := &SwitchService{
+				arango:  arango,
+				logger:  log.NewVoidLogger(),
+				metrics: metrics.Mock(),
+				tracer:  mocktracer.New(),
+			}
+
+			stream := &mockGetSwitchesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: ctx,
+				},
+			}
+
+			err := service.GetSwitches(tc.req, stream)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedCode == codes.OK, arango.QueryCalled)
+			if arango.QueryCalled {
+				assert.Equal(t, tc.expectedSiteIDs, arango.QueryInVars["siteIds"])
+			}
+		})
+	}
+}
+
+func TestGetSwitchesNoSites(t *testing.T) {
+	policy := &auth.Policy{
+		Roles:    []auth.Role{{Name: "reader", Methods: []string{"GetSwitches"}}},
+		Bindings: []auth.Binding{{Identities: []string{"graphql-service"}, Roles: []string{"reader"}}},
+	}
+
+	grant := policy.Grant([]string{"graphql-service"}, "GetSwitches")
+	assert.NotNil(t, grant)
+
+	arango := &mockArangoService{
+		QueryOutCursor: newMockCursor(&model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", SiteID: "1111-1111"}),
+	}
+	service := &SwitchService{
+		arango:  arango,
+		logger:  log.NewVoidLogger(),
+		metrics: metrics.Mock(),
+		tracer:  mocktracer.New(),
+	}
+
+	stream := &mockGetSwitchesServer{
+		ServerStream: &mockServerStream{
+			ContextOutContext: auth.NewContext(contextWithTenant(testTenantID), grant),
+		},
+	}
+
+	err := service.GetSwitches(&proto.GetSwitchesRequest{}, stream)
+
+	assert.NoError(t, err)
+	assert.False(t, arango.QueryCalled)
+	assert.False(t, stream.SendCalled)
+}
+
+func TestUpdateSwitch(t *testing.T) {
+	current := &model.Switch{TenantID: testTenantID, Rev: "_aaaa", SiteID: "1111-1111", Name: "Light", State: "ON", States: []string{"OFF", "ON"}}
+	dimmer := &model.Switch{TenantID: testTenantID, Rev: "_aaaa", SiteID: "1111-1111", Name: "Light", State: "ON", States: []string{"OFF", "DIM", "ON"}, Transitions: []model.Transition{
+		{From: "OFF", To: []string{"DIM"}},
+		{From: "DIM", To: []string{"OFF", "ON"}},
+		{From: "ON", To: []string{"DIM"}},
+	}}
+
+	tests := []struct {
+		name             string
+		arango           *mockArangoService
+		sites            *mockSiteClient
+		ctx              context.Context
+		req              *proto.UpdateSwitchRequest
+		expectedCode     codes.Code
+		expectedFields   []string
+		expectedResponse *proto.Switch
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			&mockSiteClient{},
+			context.Background(),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa", Name: "Lamp"},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"name"}},
+			},
+			codes.Unauthenticated,
+			nil,
+			nil,
+		},
+		{
+			"NoIDOrMask",
+			&mockArangoService{},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{},
+			codes.InvalidArgument,
+			[]string{"switch.id", "update_mask"},
+			nil,
+		},
+		{
+			"InvalidFields",
+			&mockArangoService{},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa", State: "OFF"},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"site_id", "name", "states", "state"}},
+			},
+			codes.InvalidArgument,
+			[]string{"switch.site_id", "switch.name", "switch.states", "update_mask"},
+			nil,
+		},
+		{
+			"NotFound",
+			&mockArangoService{
+				ReadDocumentOutError: arango.ArangoError{HasError: true, Code: 404, ErrorNum: 1202},
+			},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa", Name: "Lamp"},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"name"}},
+			},
+			codes.NotFound,
+			nil,
+			nil,
+		},
+		{
+			"DropsCurrentState",
+			&mockArangoService{
+				ReadDocumentOutDoc: current,
+			},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa", States: []string{"OFF", "DIM"}},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"states"}},
+			},
+			codes.InvalidArgument,
+			[]string{"switch.states"},
+			nil,
+		},
+		{
+			"InvalidTransitions",
+			&mockArangoService{
+				ReadDocumentOutDoc: current,
+			},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch: &proto.Switch{Id: "aaaa-aaaa", Transitions: []*proto.Transition{
+					{From: "OFF", To: []string{"DIM"}},
+				}},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"transitions"}},
+			},
+			codes.InvalidArgument,
+			[]string{"switch.transitions"},
+			nil,
+		},
+		{
+			"StatesDropTransitionState",
+			&mockArangoService{
+				ReadDocumentOutDoc: dimmer,
+			},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa", States: []string{"OFF", "ON"}},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"states"}},
+			},
+			codes.InvalidArgument,
+			[]string{"switch.transitions", "switch.transitions", "switch.transitions"},
+			nil,
+		},
+		{
+			"UnknownSite",
+			&mockArangoService{
+				ReadDocumentOutDoc: current,
+			},
+			&mockSiteClient{
+				ValidateOutError: site.ErrUnknownSite,
+			},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa", SiteId: "2222-2222"},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"site_id"}},
+			},
+			codes.InvalidArgument,
+			[]string{"site_id"},
+			nil,
+		},
+		{
+			"Conflict",
+			&mockArangoService{
+				ReadDocumentOutDoc:     current,
+				UpdateDocumentOutError: arango.ArangoError{HasError: true, Code: 412, ErrorNum: 1200},
+			},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa", Name: "Lamp"},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"name"}},
+			},
+			codes.Aborted,
+			nil,
+			nil,
+		},
+		{
+			"SyncSchedulesError",
+			&mockArangoService{
+				ReadDocumentOutDoc: current,
+				QueryOutError:      errors.New("database error"),
+			},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa", SiteId: "2222-2222"},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"site_id"}},
+			},
+			codes.Internal,
+			nil,
+			nil,
+		},
+		{
+			"Rename",
+			&mockArangoService{
+				ReadDocumentOutDoc:    current,
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+			},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa", SiteId: "ignored", Name: "Lamp"},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"name"}},
+			},
+			codes.OK,
+			nil,
+			&proto.Switch{Id: "aaaa-aaaa", SiteId: "1111-1111", Name: "Lamp", State: "ON", States: []string{"OFF", "ON"}, Revision: "_bbbb"},
+		},
+		{
+			"MoveAndRedefineStates",
+			&mockArangoService{
+				ReadDocumentOutDoc:    current,
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+				QueryOutCursor: &mockArangoCursor{
+					Closer: &mockCloser{},
+				},
+			},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa", SiteId: "2222-2222", States: []string{"OFF", "DIM", "ON"}},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"site_id", "states"}},
+			},
+			codes.OK,
+			nil,
+			&proto.Switch{Id: "aaaa-aaaa", SiteId: "2222-2222", Name: "Light", State: "ON", States: []string{"OFF", "DIM", "ON"}, Revision: "_bbbb"},
+		},
+		{
+			"DropState",
+			&mockArangoService{
+				ReadDocumentOutDoc:    current,
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+				QueryOutCursor: &mockArangoCursor{
+					Closer: &mockCloser{},
+				},
+			},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa", States: []string{"ON"}},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"states"}},
+			},
+			codes.OK,
+			nil,
+			&proto.Switch{Id: "aaaa-aaaa", SiteId: "1111-1111", Name: "Light", State: "ON", States: []string{"ON"}, Revision: "_bbbb"},
+		},
+		{
+			"StatesAndTransitions",
+			&mockArangoService{
+				ReadDocumentOutDoc:    current,
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+				QueryOutCursor: &mockArangoCursor{
+					Closer: &mockCloser{},
+				},
+			},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch: &proto.Switch{Id: "aaaa-aaaa", States: []string{"OFF", "DIM", "ON"}, Transitions: []*proto.Transition{
+					{From: "OFF", To: []string{"DIM"}},
+					{From: "DIM", To: []string{"OFF", "ON"}},
+					{From: "ON", To: []string{"DIM"}},
+				}},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"states", "transitions"}},
+			},
+			codes.OK,
+			nil,
+			&proto.Switch{Id: "aaaa-aaaa", SiteId: "1111-1111", Name: "Light", State: "ON", States: []string{"OFF", "DIM", "ON"}, Revision: "_bbbb", Transitions: []*proto.Transition{
+				{From: "OFF", To: []string{"DIM"}},
+				{From: "DIM", To: []string{"OFF", "ON"}},
+				{From: "ON", To: []string{"DIM"}},
+			}},
+		},
+		{
+			"ClearTransitions",
+			&mockArangoService{
+				ReadDocumentOutDoc:    dimmer,
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+			},
+			&mockSiteClient{},
+			contextWithTenant(testTenantID),
+			&proto.UpdateSwitchRequest{
+				Switch:     &proto.Switch{Id: "aaaa-aaaa"},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"transitions"}},
+			},
+			codes.OK,
+			nil,
+			&proto.Switch{Id: "aaaa-aaaa", SiteId: "1111-1111", Name: "Light", State: "ON", States: []string{"OFF", "DIM", "ON"}, Revision: "_bbbb"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := log.NewVoidLogger()
+			metrics := metrics.Mock()
+			tracer := mocktracer.New()
+			service := &SwitchService{
+				arango:  tc.arango,
+				sites:   tc.sites,
+				broker:  broker.New(0, 0),
+				logger:  logger,
+				metrics: metrics,
+				tracer:  tracer,
+			}
+			base := service.broker.Revision()
+
+			sw, err := service.UpdateSwitch(tc.ctx, tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedResponse, sw)
+			if tc.expectedFields != nil {
+				assert.Equal(t, tc.expectedFields, violatedFields(err))
+			}
+
+			// The switch and its schedules are only written in a transaction
+			assert.Equal(t, tc.arango.UpdateDocumentCalled, tc.arango.TransactionCalled)
+			if tc.arango.QueryCalled {
+				assert.True(t, tc.arango.TransactionCalled)
+			}
+
+			if tc.expectedCode != codes.OK {
+				assert.Equal(t, base, service.broker.Revision())
+			}
+
+			if tc.expectedCode == codes.OK {
+				patch := tc.arango.UpdateDocumentInDoc.(map[string]interface{})
+				assert.Len(t, patch, len(tc.req.UpdateMask.Paths))
+				assert.NotContains(t, patch, "state")
+				assert.Equal(t, uint64(1), service.broker.Revision()-base)
+
+				moved := tc.expectedResponse.SiteId != current.SiteID
+				_, redefined := patch["states"]
+				assert.Equal(t, moved, tc.sites.ValidateCalled)
+				assert.Equal(t, moved || redefined, tc.arango.QueryCalled)
+				if moved || redefined {
+					assert.Equal(t, querySyncSchedules, tc.arango.QueryInQuery)
+					assert.Equal(t, tc.expectedResponse.SiteId, tc.arango.QueryInVars["siteId"])
+					assert.Equal(t, tc.expectedResponse.States, tc.arango.QueryInVars["states"])
+				}
+			}
+		})
+	}
+}
+
+func TestSetSwitch(t *testing.T) {
+	tests := []struct {
+		name             string
+		arango           *mockArangoService
+		ctx              context.Context
+		req              *proto.SetSwitchRequest
+		expectedCode     codes.Code
+		expectedCurrent  *proto.Switch
+		expectedResponse *proto.SetSwitchResponse
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			&proto.SetSwitchRequest{
+				Id:    "aaaa-aaaa",
+				State: "ON",
+			},
+			codes.Unauthenticated,
+			nil,
+			nil,
+		},
+		{
+			"ReadFail",
+			&mockArangoService{
+				ReadDocumentOutError: errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchRequest{
+				Id:    "aaaa-aaaa",
+				State: "ON",
+			},
+			codes.Internal,
+			nil,
+			nil,
+		},
+		{
+			"OtherTenant",
+			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{TenantID: "uuuu-uuuu"},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchRequest{
+				Id:    "aaaa-aaaa",
+				State: "ON",
+			},
+			codes.NotFound,
+			nil,
+			nil,
+		},
+		{
+			"InvalidState",
+			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{TenantID: testTenantID, Rev: "_aaaa", States: []string{"OFF", "ON"}},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchRequest{
+				Id:    "aaaa-aaaa",
+				State: "DIM",
+			},
+			codes.InvalidArgument,
+			nil,
+			nil,
+		},
+		{
+			"Conflict",
+			&mockArangoService{
+				ReadDocumentOutDoc:     &model.Switch{TenantID: testTenantID, Rev: "_aaaa", States: []string{"OFF", "ON"}},
+				UpdateDocumentOutError: arango.ArangoError{HasError: true, Code: 412, ErrorNum: 1200},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchRequest{
+				Id:    "aaaa-aaaa",
+				State: "ON",
+			},
+			codes.Aborted,
+			nil,
+			nil,
+		},
+		{
+			"Fail",
+			&mockArangoService{
+				ReadDocumentOutDoc:     &model.Switch{TenantID: testTenantID, Rev: "_aaaa", States: []string{"OFF", "ON"}},
+				UpdateDocumentOutError: errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchRequest{
+				Id:    "aaaa-aaaa",
+				State: "ON",
+			},
+			codes.Internal,
+			nil,
+			nil,
+		},
+		{
+			"HistoryFail",
+			&mockArangoService{
+				ReadDocumentOutDoc:            &model.Switch{TenantID: testTenantID, Rev: "_aaaa", States: []string{"OFF", "ON"}},
+				UpdateDocumentOutMeta:         arango.DocumentMeta{},
+				CreateHistoryDocumentOutError: errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchRequest{
+				Id:    "aaaa-aaaa",
+				State: "ON",
+			},
+			codes.Internal,
+			nil,
+			nil,
 		},
 		{
 			"Success",
 			&mockArangoService{
-				QueryOutCursor: &mockArangoCursor{
-					Closer:            &mockCloser{},
-					HasMoreOutResults: []bool{true, false},
-					ReadDocumentOutMeta: arango.DocumentMeta{
-						Key: "aaaa-aaaa",
-					},
-				},
+				ReadDocumentOutDoc:    &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "OFF", States: []string{"OFF", "ON"}},
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
 			},
-			&proto.GetSwitchesRequest{
-				SiteId: "1111-1111",
+			metadata.NewIncomingContext(context.Background(), metadata.Pairs(tenantMetadataKey, testTenantID, callerMetadataKey, "operator")),
+			&proto.SetSwitchRequest{
+				Id:     "aaaa-aaaa",
+				State:  "ON",
+				Reason: "maintenance",
 			},
-			&mockGetSwitchesServer{
-				ServerStream: &mockServerStream{
-					ContextOutContext: context.Background(),
-				},
-				SendOutError: nil,
+			codes.OK,
+			nil,
+			&proto.SetSwitchResponse{Revision: "_bbbb", PreviousState: "OFF"},
+		},
+		{
+			"RevisionMismatch",
+			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_bbbb", State: "ON", States: []string{"OFF", "ON"}},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchRequest{
+				Id:               "aaaa-aaaa",
+				State:            "OFF",
+				ExpectedRevision: "_aaaa",
+			},
+			codes.FailedPrecondition,
+			&proto.Switch{Id: "aaaa-aaaa", State: "ON", States: []string{"OFF", "ON"}, Revision: "_bbbb"},
+			nil,
+		},
+		{
+			"StateMismatch",
+			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "ON", States: []string{"OFF", "ON"}},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchRequest{
+				Id:               "aaaa-aaaa",
+				State:            "ON",
+				ExpectedRevision: "_aaaa",
+				ExpectedState:    "OFF",
+			},
+			codes.FailedPrecondition,
+			&proto.Switch{Id: "aaaa-aaaa", State: "ON", States: []string{"OFF", "ON"}, Revision: "_aaaa"},
+			nil,
+		},
+		{
+			"ConflictWithExpectations",
+			&mockArangoService{
+				ReadDocumentOutDoc:     &model.Switch{TenantID: testTenantID, Rev: "_aaaa", State: "OFF", States: []string{"OFF", "ON"}},
+				UpdateDocumentOutError: arango.ArangoError{HasError: true, Code: 412, ErrorNum: 1200},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchRequest{
+				Id:            "aaaa-aaaa",
+				State:         "ON",
+				ExpectedState: "OFF",
+			},
+			codes.Aborted,
+			nil,
+			nil,
+		},
+		{
+			"SuccessWithExpectations",
+			&mockArangoService{
+				ReadDocumentOutDoc:    &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "OFF", States: []string{"OFF", "ON"}},
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+			},
+			metadata.NewIncomingContext(context.Background(), metadata.Pairs(tenantMetadataKey, testTenantID, callerMetadataKey, "operator")),
+			&proto.SetSwitchRequest{
+				Id:               "aaaa-aaaa",
+				State:            "ON",
+				ExpectedRevision: "_aaaa",
+				ExpectedState:    "OFF",
+			},
+			codes.OK,
+			nil,
+			&proto.SetSwitchResponse{Revision: "_bbbb", PreviousState: "OFF"},
+		},
+		{
+			"IllegalTransition",
+			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "TRIPPED", States: []string{"CLOSED", "TRIPPED", "RESET"}, Transitions: []model.Transition{
+					{From: "CLOSED", To: []string{"TRIPPED"}},
+					{From: "TRIPPED", To: []string{"RESET"}},
+					{From: "RESET", To: []string{"CLOSED"}},
+				}},
+			},
+			contextWithTenant(testTenantID),
+			&proto.SetSwitchRequest{
+				Id:    "aaaa-aaaa",
+				State: "CLOSED",
+			},
+			codes.FailedPrecondition,
+			&proto.Switch{Id: "aaaa-aaaa", State: "TRIPPED", States: []string{"CLOSED", "TRIPPED", "RESET"}, Revision: "_aaaa", Transitions: []*proto.Transition{
+				{From: "CLOSED", To: []string{"TRIPPED"}},
+				{From: "TRIPPED", To: []string{"RESET"}},
+				{From: "RESET", To: []string{"CLOSED"}},
+			}},
+			nil,
+		},
+		{
+			"AllowedTransition",
+			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "OFF", States: []string{"OFF", "ON"}, Transitions: []model.Transition{
+					{From: "OFF", To: []string{"ON"}},
+				}},
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+			},
+			metadata.NewIncomingContext(context.Background(), metadata.Pairs(tenantMetadataKey, testTenantID, callerMetadataKey, "operator")),
+			&proto.SetSwitchRequest{
+				Id:    "aaaa-aaaa",
+				State: "ON",
 			},
+			codes.OK,
 			nil,
+			&proto.SetSwitchResponse{Revision: "_bbbb", PreviousState: "OFF"},
 		},
 	}
 
@@ -318,47 +1544,386 @@ func TestGetSwitches(t *testing.T) {
 				tracer:  tracer,
 			}
 
-			err := service.GetSwitches(tc.req, tc.stream)
+			resp, err := service.SetSwitch(tc.ctx, tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedResponse, resp)
+
+			if tc.expectedCode == codes.NotFound || tc.expectedCode == codes.InvalidArgument || tc.expectedCode == codes.FailedPrecondition {
+				assert.False(t, tc.arango.UpdateDocumentCalled)
+			}
+
+			// The state and its history are written in one transaction
+			if tc.arango.UpdateDocumentCalled {
+				assert.True(t, tc.arango.TransactionCalled)
+			}
+
+			if tc.expectedCurrent != nil {
+				details := status.Convert(err).Details()
+				assert.Len(t, details, 2)
+				assert.IsType(t, &errdetails.PreconditionFailure{}, details[0])
+				assert.Equal(t, tc.expectedCurrent, details[1])
+			}
 
-			assert.Equal(t, tc.expectedError, err)
+			if tc.expectedCode == codes.OK {
+				change := tc.arango.CreateHistoryDocumentInDoc.(*model.SwitchStateChange)
+				assert.Equal(t, testTenantID, change.TenantID)
+				assert.Equal(t, tc.req.Id, change.SwitchID)
+				assert.Equal(t, "OFF", change.PreviousState)
+				assert.Equal(t, tc.req.State, change.State)
+				assert.Empty(t, change.Caller)
+				assert.Equal(t, "operator", change.ClaimedCaller)
+				assert.Equal(t, tc.req.Reason, change.Reason)
+				assert.NotZero(t, change.Time)
+			}
 		})
 	}
 }
 
-func TestSetSwitch(t *testing.T) {
+func TestGetSwitchHistory(t *testing.T) {
 	tests := []struct {
-		name             string
-		arango           ArangoService
-		ctx              context.Context
-		req              *proto.SetSwitchRequest
-		expectedError    error
-		expectedResponse *proto.SetSwitchResponse
+		name              string
+		arango            *mockArangoService
+		ctx               context.Context
+		req               *proto.GetSwitchHistoryRequest
+		expectedCode      codes.Code
+		expectedFields    []string
+		expectedChanges   int
+		expectedNextToken string
 	}{
 		{
-			"Fail",
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			&proto.GetSwitchHistoryRequest{SwitchId: "aaaa-aaaa"},
+			codes.Unauthenticated,
+			nil, 0, "",
+		},
+		{
+			"InvalidRequest",
+			&mockArangoService{},
+			contextWithTenant(testTenantID),
+			&proto.GetSwitchHistoryRequest{FromTime: 2000, ToTime: 1000, PageSize: -1, PageToken: "!!"},
+			codes.InvalidArgument,
+			[]string{"switch_id", "to_time", "page_size", "page_token"},
+			0, "",
+		},
+		{
+			"QueryError",
 			&mockArangoService{
-				UpdateDocumentOutError: errors.New("database error"),
+				QueryOutError: errors.New("database error"),
 			},
-			context.Background(),
-			&proto.SetSwitchRequest{
-				Id:    "aaaa-aaaa",
-				State: "ON",
+			contextWithTenant(testTenantID),
+			&proto.GetSwitchHistoryRequest{SwitchId: "aaaa-aaaa"},
+			codes.Internal,
+			nil, 0, "",
+		},
+		{
+			"ReadDocumentError",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:               &mockCloser{},
+					HasMoreOutResults:    []bool{true},
+					ReadDocumentOutError: errors.New("cursor error"),
+				},
 			},
-			errors.New("database error"),
-			nil,
+			contextWithTenant(testTenantID),
+			&proto.GetSwitchHistoryRequest{SwitchId: "aaaa-aaaa"},
+			codes.Internal,
+			nil, 0, "",
 		},
 		{
-			"Success",
+			"LastPage",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:            &mockCloser{},
+					HasMoreOutResults: []bool{true, true, false},
+				},
+			},
+			contextWithTenant(testTenantID),
+			&proto.GetSwitchHistoryRequest{SwitchId: "aaaa-aaaa", PageSize: 2},
+			codes.OK,
+			nil, 2, "",
+		},
+		{
+			"NextPage",
 			&mockArangoService{
-				UpdateDocumentOutMeta: arango.DocumentMeta{},
+				QueryOutCursor: newMockCursor(
+					model.SwitchStateChange{Key: "3003", Time: 1500},
+					model.SwitchStateChange{Key: "2002", Time: 1500},
+					model.SwitchStateChange{Key: "1001", Time: 1200},
+				),
+			},
+			contextWithTenant(testTenantID),
+			&proto.GetSwitchHistoryRequest{SwitchId: "aaaa-aaaa", FromTime: 1000, ToTime: 2000, PageSize: 2, PageToken: encodeHistoryToken(1800, "4004")},
+			codes.OK,
+			nil, 2, encodeHistoryToken(1500, "2002"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := log.NewVoidLogger()
+			metrics := metrics.Mock()
+			tracer := mocktracer.New()
+			service := &SwitchService{
+				arango:  tc.arango,
+				logger:  logger,
+				metrics: metrics,
+				tracer:  tracer,
+			}
+
+			resp, err := service.GetSwitchHistory(tc.ctx, tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+
+			if tc.expectedFields != nil {
+				br := status.Convert(err).Details()[0].(*errdetails.BadRequest)
+				fields := []string{}
+				for _, v := range br.GetFieldViolations() {
+					fields = append(fields, v.GetField())
+				}
+				assert.Equal(t, tc.expectedFields, fields)
+			}
+
+			if tc.expectedCode == codes.OK {
+				assert.Len(t, resp.Changes, tc.expectedChanges)
+				assert.Equal(t, tc.expectedNextToken, resp.NextPageToken)
+			}
+
+			if tc.arango.QueryCalled {
+				assert.Equal(t, queryGetSwitchHistory, tc.arango.QueryInQuery)
+				assert.Equal(t, testTenantID, tc.arango.QueryInVars["tenantId"])
+				assert.Equal(t, tc.req.SwitchId, tc.arango.QueryInVars["switchId"])
+				assert.Equal(t, tc.req.FromTime, tc.arango.QueryInVars["fromTime"])
+
+				afterTime, afterKey := tc.arango.QueryInVars["toTime"], interface{}("")
+				if tc.req.PageToken != "" {
+					afterTime, afterKey, _ = decodeHistoryToken(tc.req.PageToken)
+				}
+				assert.Equal(t, afterTime, tc.arango.QueryInVars["afterTime"])
+				assert.Equal(t, afterKey, tc.arango.QueryInVars["afterKey"])
+			}
+		})
+	}
+}
+
+func TestHistoryToken(t *testing.T) {
+	for _, key := range []string{"1001", "a/b"} {
+		time, decoded, err := decodeHistoryToken(encodeHistoryToken(1500, key))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1500), time)
+		assert.Equal(t, key, decoded)
+	}
+
+	_, _, err := decodeHistoryToken("!!")
+	assert.Error(t, err)
+
+	_, _, err = decodeHistoryToken(base64.RawURLEncoding.EncodeToString([]byte("1500")))
+	assert.Error(t, err)
+
+	_, _, err = decodeHistoryToken(base64.RawURLEncoding.EncodeToString([]byte("1500/")))
+	assert.Error(t, err)
+
+	_, _, err = decodeHistoryToken(base64.RawURLEncoding.EncodeToString([]byte("time/1001")))
+	assert.Error(t, err)
+}
+
+func TestPageToken(t *testing.T) {
+	for _, offset := range []int{0, 50, 1000} {
+		decoded, err := decodePageToken(encodePageToken(offset))
+		assert.NoError(t, err)
+		assert.Equal(t, offset, decoded)
+	}
+
+	offset, err := decodePageToken("")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, offset)
+
+	_, err = decodePageToken("!!")
+	assert.Error(t, err)
+
+	_, err = decodePageToken(base64.RawURLEncoding.EncodeToString([]byte("-1")))
+	assert.Error(t, err)
+}
+
+func TestExtractCaller(t *testing.T) {
+	tlsInfo := credentials.TLSInfo{
+		State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{
+				{Subject: pkix.Name{CommonName: "dashboard"}},
 			},
+		},
+	}
+
+	tests := []struct {
+		name                  string
+		ctx                   context.Context
+		expectedCaller        string
+		expectedClaimedCaller string
+	}{
+		{
+			"None",
 			context.Background(),
-			&proto.SetSwitchRequest{
-				Id:    "aaaa-aaaa",
-				State: "ON",
+			"",
+			"",
+		},
+		{
+			"Metadata",
+			metadata.NewIncomingContext(context.Background(), metadata.Pairs(callerMetadataKey, "operator")),
+			"",
+			"operator",
+		},
+		{
+			"Certificate",
+			peer.NewContext(
+				metadata.NewIncomingContext(context.Background(), metadata.Pairs(callerMetadataKey, "operator")),
+				&peer.Peer{AuthInfo: tlsInfo},
+			),
+			"dashboard",
+			"operator",
+		},
+		{
+			"Internal",
+			auth.WithInternalCaller(context.Background(), "scheduler"),
+			"scheduler",
+			"",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &SwitchService{}
+			assert.Equal(t, tc.expectedCaller, service.extractCaller(tc.ctx))
+			assert.Equal(t, tc.expectedClaimedCaller, service.extractClaimedCaller(tc.ctx))
+		})
+	}
+}
+
+func TestExecSpan(t *testing.T) {
+	tracer := mocktracer.New()
+	service := &SwitchService{
+		logger:  log.NewVoidLogger(),
+		metrics: metrics.Mock(),
+		tracer:  tracer,
+	}
+
+	call := tracer.StartSpan("/proto.SwitchService/GetSwitch")
+	ctx := opentracing.ContextWithSpan(contextWithTenant(testTenantID), call)
+
+	service.exec(ctx, nil, "GetSwitch_ReadDocument", "ReadDocument", func() error { return nil })
+	service.exec(contextWithTenant(testTenantID), nil, "Scheduler_ReadDocument", "ReadDocument", func() error { return nil })
+
+	spans := tracer.FinishedSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, call.Context().(mocktracer.MockSpanContext).SpanID, spans[0].ParentID)
+	assert.Zero(t, spans[1].ParentID)
+}
+
+func TestWatchSwitches(t *testing.T) {
+	tests := []struct {
+		name              string
+		arango            *mockArangoService
+		published         []*proto.Switch
+		ctx               context.Context
+		req               *proto.WatchSwitchesRequest
+		sendError         error
+		cancelAfter       int
+		expectedCode      codes.Code
+		expectedTypes     []proto.SwitchEvent_Type
+		expectedRevisions []uint64
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			nil,
+			context.Background(),
+			&proto.WatchSwitchesRequest{SiteId: "1111-1111"},
+			nil, 0,
+			codes.Unauthenticated,
+			nil, nil,
+		},
+		{
+			"NoSiteOrIDs",
+			&mockArangoService{},
+			nil,
+			contextWithTenant(testTenantID),
+			&proto.WatchSwitchesRequest{},
+			nil, 0,
+			codes.InvalidArgument,
+			nil, nil,
+		},
+		{
+			"UnknownRevision",
+			&mockArangoService{},
+			nil,
+			contextWithTenant(testTenantID),
+			&proto.WatchSwitchesRequest{SiteId: "1111-1111", FromRevision: 5},
+			nil, 0,
+			codes.OutOfRange,
+			nil, nil,
+		},
+		{
+			"QueryError",
+			&mockArangoService{
+				QueryOutError: errors.New("database error"),
+			},
+			nil,
+			contextWithTenant(testTenantID),
+			&proto.WatchSwitchesRequest{SiteId: "1111-1111"},
+			nil, 0,
+			codes.Internal,
+			nil, nil,
+		},
+		{
+			"SendError",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:            &mockCloser{},
+					HasMoreOutResults: []bool{true},
+				},
 			},
 			nil,
-			&proto.SetSwitchResponse{},
+			contextWithTenant(testTenantID),
+			&proto.WatchSwitchesRequest{SiteId: "1111-1111"},
+			errors.New("stream error"), 0,
+			codes.Internal,
+			[]proto.SwitchEvent_Type{proto.SwitchEvent_CURRENT},
+			[]uint64{0},
+		},
+		{
+			"CurrentState",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:            &mockCloser{},
+					HasMoreOutResults: []bool{true, true, false},
+				},
+			},
+			[]*proto.Switch{
+				{Id: "aaaa-aaaa", SiteId: "1111-1111"},
+			},
+			contextWithTenant(testTenantID),
+			&proto.WatchSwitchesRequest{SiteId: "1111-1111"},
+			nil, 2,
+			codes.OK,
+			[]proto.SwitchEvent_Type{proto.SwitchEvent_CURRENT, proto.SwitchEvent_CURRENT},
+			[]uint64{1, 1},
+		},
+		{
+			"Resume",
+			&mockArangoService{},
+			[]*proto.Switch{
+				{Id: "aaaa-aaaa", SiteId: "1111-1111"},
+				{Id: "bbbb-bbbb", SiteId: "2222-2222"},
+				{Id: "cccc-cccc", SiteId: "2222-2222"},
+				{Id: "aaaa-aaaa", SiteId: "1111-1111"},
+			},
+			contextWithTenant(testTenantID),
+			&proto.WatchSwitchesRequest{Ids: []string{"aaaa-aaaa", "cccc-cccc"}, FromRevision: 1},
+			nil, 2,
+			codes.OK,
+			[]proto.SwitchEvent_Type{proto.SwitchEvent_INSTALLED, proto.SwitchEvent_INSTALLED},
+			[]uint64{3, 4},
 		},
 	}
 
@@ -369,15 +1934,101 @@ func TestSetSwitch(t *testing.T) {
 			tracer := mocktracer.New()
 			service := &SwitchService{
 				arango:  tc.arango,
+				broker:  broker.New(0, 0),
 				logger:  logger,
 				metrics: metrics,
 				tracer:  tracer,
 			}
 
-			resp, err := service.SetSwitch(tc.ctx, tc.req)
+			// The revisions of the test cases are relative to the first revision of the broker
+			base := service.broker.Revision()
+			req := &proto.WatchSwitchesRequest{SiteId: tc.req.SiteId, Ids: tc.req.Ids}
+			if tc.req.FromRevision != 0 {
+				req.FromRevision = base + tc.req.FromRevision
+			}
 
-			assert.Equal(t, tc.expectedError, err)
-			assert.Equal(t, tc.expectedResponse, resp)
+			for _, sw := range tc.published {
+				service.publish(testTenantID, proto.SwitchEvent_INSTALLED, sw)
+			}
+
+			ctx, cancel := context.WithCancel(tc.ctx)
+			defer cancel()
+
+			stream := &mockWatchSwitchesServer{
+				ServerStream: &mockServerStream{
+					ContextOutContext: ctx,
+				},
+				SendOutError: tc.sendError,
+				Cancel:       cancel,
+				CancelAfter:  tc.cancelAfter,
+			}
+
+			err := service.WatchSwitches(req, stream)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+
+			var types []proto.SwitchEvent_Type
+			var revisions []uint64
+			for _, e := range stream.SendInEvents {
+				types = append(types, e.Type)
+				revisions = append(revisions, e.Revision-base)
+			}
+			assert.Equal(t, tc.expectedTypes, types)
+			assert.Equal(t, tc.expectedRevisions, revisions)
+
+			if tc.arango.QueryCalled {
+				assert.Equal(t, queryWatchSwitches, tc.arango.QueryInQuery)
+				assert.Equal(t, testTenantID, tc.arango.QueryInVars["tenantId"])
+				assert.Equal(t, tc.req.SiteId, tc.arango.QueryInVars["siteId"])
+			}
 		})
 	}
 }
+
+func TestWatchSwitchesLive(t *testing.T) {
+	logger := log.NewVoidLogger()
+	metrics := metrics.Mock()
+	tracer := mocktracer.New()
+	service := &SwitchService{
+		arango:  &mockArangoService{},
+		broker:  broker.New(0, 0),
+		logger:  logger,
+		metrics: metrics,
+		tracer:  tracer,
+	}
+
+	// Resuming from the current revision only sends live events
+	base := service.broker.Revision()
+	service.publish(testTenantID, proto.SwitchEvent_INSTALLED, &proto.Switch{Id: "aaaa-aaaa", SiteId: "1111-1111"})
+
+	ctx, cancel := context.WithCancel(contextWithTenant(testTenantID))
+	defer cancel()
+
+	stream := &mockWatchSwitchesServer{
+		ServerStream: &mockServerStream{
+			ContextOutContext: ctx,
+		},
+		Cancel:      cancel,
+		CancelAfter: 1,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- service.WatchSwitches(&proto.WatchSwitchesRequest{SiteId: "1111-1111", FromRevision: base + 1}, stream)
+	}()
+
+	// Events are published until the watcher is subscribed and receives one
+	for {
+		service.publish(testTenantID, proto.SwitchEvent_STATE_CHANGED, &proto.Switch{Id: "aaaa-aaaa", SiteId: "1111-1111", State: "ON"})
+
+		select {
+		case err := <-errs:
+			assert.NoError(t, err)
+			assert.Len(t, stream.SendInEvents, 1)
+			assert.Equal(t, proto.SwitchEvent_STATE_CHANGED, stream.SendInEvents[0].Type)
+			assert.Equal(t, "ON", stream.SendInEvents[0].Switch.State)
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/broker"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+const (
+	defaultSyncTimeout = 30 * time.Second
+	defaultSyncPeriod  = 5 * time.Second
+
+	// A switch becomes pending when its reported state starts differing from its desired state and stays pending until they match again
+	queryReportSwitchState = `FOR sw IN switches FILTER sw._key == @key AND sw.tenantId == @tenantId LET pending = sw.state != @state UPDATE sw WITH { reportedState: @state, reportedAt: @time, pending: pending, pendingSince: pending ? (sw.pending ? sw.pendingSince : @time) : 0, outOfSync: pending AND sw.outOfSync == true } IN switches RETURN NEW`
+	queryFlagOutOfSync     = `FOR sw IN switches FILTER sw.pending == true AND sw.outOfSync != true AND sw.pendingSince < @before UPDATE sw WITH { outOfSync: true } IN switches RETURN NEW`
+)
+
+type (
+	// SyncMonitor flags the switches whose devices do not converge to their desired state within a timeout
+	SyncMonitor struct {
+		arango  ArangoService
+		broker  *broker.Broker
+		logger  *log.Logger
+		timeout time.Duration
+		now     func() time.Time
+	}
+)
+
+// ReportSwitchState records the state a device is actually in.
+// The switch is pending while the reported state differs from its desired state.
+func (s *SwitchService) ReportSwitchState(ctx context.Context, req *proto.ReportSwitchStateRequest) (*proto.Switch, error) {
+	key := req.GetId()
+
+	tenantID, ok := s.extractTenant(ctx)
+	if !ok {
+		return nil, toStatus(ErrNoTenant)
+	}
+
+	current, err := s.readSwitch(ctx, req, "ReportSwitchState_ReadDocument", tenantID, key)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if !hasState(current.States, req.GetState()) {
+		var violations fieldViolations
+		violations.add("state", fmt.Sprintf("state %q is not one of the switch states", req.GetState()))
+		return nil, violations.err()
+	}
+
+	vars := map[string]interface{}{
+		"key":      key,
+		"tenantId": tenantID,
+		"state":    req.GetState(),
+		"time":     time.Now().UnixNano(),
+	}
+
+	// The switch is updated by a query so the desired state is compared with the reported state atomically
+	doc := &model.Switch{}
+	if err := s.queryDocument(ctx, req, "ReportSwitchState_Query", queryReportSwitchState, vars, doc, ErrSwitchNotFound); err != nil {
+		return nil, toStatus(err)
+	}
+
+	sw := switchToProto(doc)
+
+	// Repeated reports of the same state are not sent to watchers
+	if doc.ReportedState != current.ReportedState || doc.Pending != current.Pending || doc.OutOfSync != current.OutOfSync {
+		s.publish(tenantID, proto.SwitchEvent_REPORTED, sw)
+	}
+
+	return sw, nil
+}
+
+// NewSyncMonitor creates a new sync monitor
+// timeout is how long the reported state of a switch can differ from its desired state before the switch is out of sync.
+// broker receives an event for every switch out of sync and nil disables the events.
+func NewSyncMonitor(arango ArangoService, broker *broker.Broker, logger *log.Logger, timeout time.Duration) *SyncMonitor {
+	if timeout <= 0 {
+		timeout = defaultSyncTimeout
+	}
+
+	return &SyncMonitor{
+		arango:  arango,
+		broker:  broker,
+		logger:  logger,
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// Run flags the switches out of sync every period until the context is done
+func (m *SyncMonitor) Run(ctx context.Context, period time.Duration) {
+	if period <= 0 {
+		period = defaultSyncPeriod
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.check(ctx); err != nil {
+			m.logger.Error("message", fmt.Sprintf("Failed to check switches out of sync: %s", err))
+		}
+	}
+}
+
+// check flags the switches that have been pending for longer than the timeout and sends an event for each of them.
+// Replicas can check at the same time, so a switch flagged by another replica is left to it.
+func (m *SyncMonitor) check(ctx context.Context) error {
+	vars := map[string]interface{}{
+		"before": m.now().Add(-m.timeout).UnixNano(),
+	}
+
+	cursor, err := m.arango.Query(ctx, queryFlagOutOfSync, vars)
+	if err != nil {
+		if arango.IsConflict(err) {
+			return nil
+		}
+		return err
+	}
+	defer cursor.Close()
+
+	for cursor.HasMore() {
+		doc := &model.Switch{}
+		if _, err := cursor.ReadDocument(ctx, doc); err != nil {
+			return err
+		}
+
+		m.logger.Warn(
+			"tenantId", doc.TenantID,
+			"switchId", doc.Key,
+			"state", doc.State,
+			"reportedState", doc.ReportedState,
+			"message", fmt.Sprintf("Switch %s did not change to state %q within %s.", doc.Key, doc.State, m.timeout),
+		)
+
+		if m.broker != nil {
+			m.broker.Publish(doc.TenantID, proto.SwitchEvent_OUT_OF_SYNC, switchToProto(doc))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/broker"
+	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
+	"github.com/moorara/microservices-demo/services/switch/internal/model"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	arango "github.com/arangodb/go-driver"
+)
+
+// recordEvents subscribes to every event of a broker and returns a function collecting the events published so far
+func recordEvents(b *broker.Broker) func() []broker.Event {
+	sub, base, _, _ := b.Subscribe(func(broker.Event) bool { return true }, 0)
+
+	return func() []broker.Event {
+		var events []broker.Event
+		for {
+			select {
+			case e := <-sub.Events():
+				// The revisions are made relative to the revision the recording started at
+				se := *e.SwitchEvent
+				se.Revision -= base
+				e.SwitchEvent = &se
+				events = append(events, e)
+			default:
+				return events
+			}
+		}
+	}
+}
+
+func TestSetSwitchPending(t *testing.T) {
+	tests := []struct {
+		name             string
+		current          *model.Switch
+		state            string
+		expectedPatch    map[string]interface{}
+		expectedPending  bool
+		expectedRestart  bool
+		expectedResponse *proto.Switch
+	}{
+		{
+			"NeverReported",
+			&model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "OFF", States: []string{"OFF", "ON"}},
+			"ON",
+			map[string]interface{}{"state": "ON"},
+			false,
+			false,
+			&proto.Switch{Id: "aaaa-aaaa", State: "ON", States: []string{"OFF", "ON"}, Revision: "_bbbb"},
+		},
+		{
+			"Pending",
+			&model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "OFF", States: []string{"OFF", "ON"}, ReportedState: "OFF", ReportedAt: 1000},
+			"ON",
+			map[string]interface{}{"state": "ON", "pending": true, "outOfSync": false},
+			true,
+			true,
+			&proto.Switch{Id: "aaaa-aaaa", State: "ON", States: []string{"OFF", "ON"}, Revision: "_bbbb", ReportedState: "OFF", ReportedAt: 1000, Pending: true},
+		},
+		{
+			"Converged",
+			&model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "OFF", States: []string{"OFF", "ON"}, ReportedState: "ON", ReportedAt: 1000, Pending: true, PendingSince: 1000, OutOfSync: true},
+			"ON",
+			map[string]interface{}{"state": "ON", "pending": false, "pendingSince": int64(0), "outOfSync": false},
+			false,
+			false,
+			&proto.Switch{Id: "aaaa-aaaa", State: "ON", States: []string{"OFF", "ON"}, Revision: "_bbbb", ReportedState: "ON", ReportedAt: 1000},
+		},
+		{
+			"OutOfSyncRetried",
+			&model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "ON", States: []string{"OFF", "ON"}, ReportedState: "OFF", ReportedAt: 1000, Pending: true, PendingSince: 1000, OutOfSync: true},
+			"ON",
+			map[string]interface{}{"state": "ON", "pending": true, "outOfSync": false},
+			true,
+			true,
+			&proto.Switch{Id: "aaaa-aaaa", State: "ON", States: []string{"OFF", "ON"}, Revision: "_bbbb", ReportedState: "OFF", ReportedAt: 1000, Pending: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			arangoService := &mockArangoService{
+				ReadDocumentOutDoc:    tc.current,
+				UpdateDocumentOutMeta: arango.DocumentMeta{Rev: "_bbbb"},
+			}
+
+			service := &SwitchService{
+				arango:  arangoService,
+				broker:  broker.New(0, 0),
+				logger:  log.NewVoidLogger(),
+				metrics: metrics.Mock(),
+				tracer:  mocktracer.New(),
+			}
+			events := recordEvents(service.broker)
+
+			resp, err := service.SetSwitch(contextWithTenant(testTenantID), &proto.SetSwitchRequest{Id: "aaaa-aaaa", State: tc.state})
+			assert.NoError(t, err)
+			assert.Equal(t, "_bbbb", resp.Revision)
+
+			patch := arangoService.UpdateDocumentInDoc.(map[string]interface{})
+			pendingSince := patch["pendingSince"]
+			if tc.expectedRestart {
+				assert.NotZero(t, pendingSince)
+				delete(patch, "pendingSince")
+			}
+			assert.Equal(t, tc.expectedPatch, patch)
+
+			published := events()
+			assert.Len(t, published, 1)
+			assert.Equal(t, tc.expectedPending, published[0].Switch.Pending)
+			assert.Equal(t, tc.expectedResponse, published[0].Switch)
+		})
+	}
+}
+
+func TestReportSwitchState(t *testing.T) {
+	current := &model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_aaaa", State: "ON", States: []string{"OFF", "ON"}, ReportedState: "OFF", ReportedAt: 1000, Pending: true, PendingSince: 1000}
+
+	tests := []struct {
+		name             string
+		arango           *mockArangoService
+		ctx              context.Context
+		req              *proto.ReportSwitchStateRequest
+		expectedCode     codes.Code
+		expectedEvent    bool
+		expectedResponse *proto.Switch
+	}{
+		{
+			"NoTenant",
+			&mockArangoService{},
+			context.Background(),
+			&proto.ReportSwitchStateRequest{Id: "aaaa-aaaa", State: "ON"},
+			codes.Unauthenticated,
+			false,
+			nil,
+		},
+		{
+			"OtherTenant",
+			&mockArangoService{
+				ReadDocumentOutDoc: &model.Switch{TenantID: "uuuu-uuuu"},
+			},
+			contextWithTenant(testTenantID),
+			&proto.ReportSwitchStateRequest{Id: "aaaa-aaaa", State: "ON"},
+			codes.NotFound,
+			false,
+			nil,
+		},
+		{
+			"InvalidState",
+			&mockArangoService{
+				ReadDocumentOutDoc: current,
+			},
+			contextWithTenant(testTenantID),
+			&proto.ReportSwitchStateRequest{Id: "aaaa-aaaa", State: "DIM"},
+			codes.InvalidArgument,
+			false,
+			nil,
+		},
+		{
+			"QueryFail",
+			&mockArangoService{
+				ReadDocumentOutDoc: current,
+				QueryOutError:      errors.New("database error"),
+			},
+			contextWithTenant(testTenantID),
+			&proto.ReportSwitchStateRequest{Id: "aaaa-aaaa", State: "ON"},
+			codes.Internal,
+			false,
+			nil,
+		},
+		{
+			"Removed",
+			&mockArangoService{
+				ReadDocumentOutDoc: current,
+				QueryOutCursor:     newMockCursor(),
+			},
+			contextWithTenant(testTenantID),
+			&proto.ReportSwitchStateRequest{Id: "aaaa-aaaa", State: "ON"},
+			codes.NotFound,
+			false,
+			nil,
+		},
+		{
+			"Unchanged",
+			&mockArangoService{
+				ReadDocumentOutDoc: current,
+				QueryOutCursor:     newMockCursor(&model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_bbbb", State: "ON", States: []string{"OFF", "ON"}, ReportedState: "OFF", ReportedAt: 2000, Pending: true, PendingSince: 1000}),
+			},
+			contextWithTenant(testTenantID),
+			&proto.ReportSwitchStateRequest{Id: "aaaa-aaaa", State: "OFF"},
+			codes.OK,
+			false,
+			&proto.Switch{Id: "aaaa-aaaa", State: "ON", States: []string{"OFF", "ON"}, Revision: "_bbbb", ReportedState: "OFF", ReportedAt: 2000, Pending: true},
+		},
+		{
+			"Converged",
+			&mockArangoService{
+				ReadDocumentOutDoc: current,
+				QueryOutCursor:     newMockCursor(&model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_bbbb", State: "ON", States: []string{"OFF", "ON"}, ReportedState: "ON", ReportedAt: 2000}),
+			},
+			contextWithTenant(testTenantID),
+			&proto.ReportSwitchStateRequest{Id: "aaaa-aaaa", State: "ON"},
+			codes.OK,
+			true,
+			&proto.Switch{Id: "aaaa-aaaa", State: "ON", States: []string{"OFF", "ON"}, Revision: "_bbbb", ReportedState: "ON", ReportedAt: 2000},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &SwitchService{
+				arango:  tc.arango,
+				broker:  broker.New(0, 0),
+				logger:  log.NewVoidLogger(),
+				metrics: metrics.Mock(),
+				tracer:  mocktracer.New(),
+			}
+			events := recordEvents(service.broker)
+
+			sw, err := service.ReportSwitchState(tc.ctx, tc.req)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedResponse, sw)
+
+			if tc.arango.QueryCalled {
+				assert.Equal(t, queryReportSwitchState, tc.arango.QueryInQuery)
+				assert.Equal(t, tc.req.State, tc.arango.QueryInVars["state"])
+				assert.Equal(t, testTenantID, tc.arango.QueryInVars["tenantId"])
+			}
+
+			published := events()
+			if !tc.expectedEvent {
+				assert.Empty(t, published)
+			} else {
+				assert.Len(t, published, 1)
+				assert.Equal(t, proto.SwitchEvent_REPORTED, published[0].Type)
+				assert.Equal(t, tc.expectedResponse, published[0].Switch)
+			}
+		})
+	}
+}
+
+func TestNewSyncMonitor(t *testing.T) {
+	monitor := NewSyncMonitor(&mockArangoService{}, broker.New(0, 0), log.NewVoidLogger(), 0)
+
+	assert.NotNil(t, monitor)
+	assert.Equal(t, defaultSyncTimeout, monitor.timeout)
+}
+
+func TestSyncMonitorCheck(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		arango         *mockArangoService
+		expectedError  bool
+		expectedEvents []broker.Event
+	}{
+		{
+			"QueryFail",
+			&mockArangoService{
+				QueryOutError: errors.New("database error"),
+			},
+			true,
+			nil,
+		},
+		{
+			"Conflict",
+			&mockArangoService{
+				QueryOutError: arango.ArangoError{HasError: true, Code: 409, ErrorNum: 1200},
+			},
+			false,
+			nil,
+		},
+		{
+			"ReadFail",
+			&mockArangoService{
+				QueryOutCursor: &mockArangoCursor{
+					Closer:               &mockCloser{},
+					HasMoreOutResults:    []bool{true},
+					ReadDocumentOutError: errors.New("database error"),
+				},
+			},
+			true,
+			nil,
+		},
+		{
+			"OutOfSync",
+			&mockArangoService{
+				QueryOutCursor: newMockCursor(
+					&model.Switch{TenantID: testTenantID, Key: "aaaa-aaaa", Rev: "_bbbb", State: "ON", ReportedState: "OFF", Pending: true, OutOfSync: true},
+					&model.Switch{TenantID: "uuuu-uuuu", Key: "bbbb-bbbb", Rev: "_cccc", State: "OFF", ReportedState: "ON", Pending: true, OutOfSync: true},
+				),
+			},
+			false,
+			[]broker.Event{
+				{TenantID: testTenantID, SwitchEvent: &proto.SwitchEvent{Type: proto.SwitchEvent_OUT_OF_SYNC, Revision: 1, Switch: &proto.Switch{Id: "aaaa-aaaa", State: "ON", Revision: "_bbbb", ReportedState: "OFF", Pending: true, OutOfSync: true}}},
+				{TenantID: "uuuu-uuuu", SwitchEvent: &proto.SwitchEvent{Type: proto.SwitchEvent_OUT_OF_SYNC, Revision: 2, Switch: &proto.Switch{Id: "bbbb-bbbb", State: "OFF", Revision: "_cccc", ReportedState: "ON", Pending: true, OutOfSync: true}}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			monitor := NewSyncMonitor(tc.arango, broker.New(0, 0), log.NewVoidLogger(), time.Minute)
+			monitor.now = func() time.Time { return now }
+			events := recordEvents(monitor.broker)
+
+			err := monitor.check(context.Background())
+
+			if tc.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, queryFlagOutOfSync, tc.arango.QueryInQuery)
+			assert.Equal(t, now.Add(-time.Minute).UnixNano(), tc.arango.QueryInVars["before"])
+
+			assert.Equal(t, tc.expectedEvents, events())
+		})
+	}
+}
@@ -0,0 +1,753 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/gorilla/mux"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	protov1 "github.com/golang/protobuf/proto"
+)
+
+const (
+	jsonContentType   = "application/json"
+	ndjsonContentType = "application/x-ndjson"
+
+	// nextPageTokenKey is the trailer the token of the next page of a stream is sent in
+	nextPageTokenKey = "next-page-token"
+
+	switchServiceMethodPrefix = "/proto.SwitchService/"
+)
+
+type (
+	// route maps an HTTP endpoint to a method of the switch service.
+	// Path variables and query parameters are named after the JSON names of the request fields.
+	route struct {
+		method string
+		path   string
+		rpc    string
+		// body is true if the request is read from the HTTP body
+		body bool
+		// newReq is nil for client streams, which read their requests from the HTTP body
+		newReq func() protov1.Message
+		unary  grpc.UnaryHandler
+		stream func(req interface{}, stream grpc.ServerStream) error
+	}
+
+	// Gateway serves the switch service as REST endpoints with JSON bodies.
+	// Server streams are written as newline-delimited JSON and client streams are read as newline-delimited JSON.
+	Gateway struct {
+		interceptor   *Interceptor
+		switchService proto.SwitchServiceServer
+		marshaler     *jsonpb.Marshaler
+		unmarshaler   *jsonpb.Unmarshaler
+	}
+
+	// httpStream implements grpc.ServerStream on top of an HTTP request
+	httpStream struct {
+		ctx         context.Context
+		w           http.ResponseWriter
+		decoder     *json.Decoder
+		marshaler   *jsonpb.Marshaler
+		unmarshaler *jsonpb.Unmarshaler
+		contentType string
+		header      metadata.MD
+		trailer     metadata.MD
+		sent        bool
+	}
+
+	getSwitchesServer     struct{ grpc.ServerStream }
+	installSwitchesServer struct{ grpc.ServerStream }
+	watchSwitchesServer   struct{ grpc.ServerStream }
+	getSchedulesServer    struct{ grpc.ServerStream }
+	getGroupsServer       struct{ grpc.ServerStream }
+	getScenesServer       struct{ grpc.ServerStream }
+)
+
+func (s *getSwitchesServer) Send(m *proto.Switch) error {
+	return s.SendMsg(m)
+}
+
+func (s *installSwitchesServer) SendAndClose(m *proto.InstallSwitchesResponse) error {
+	return s.SendMsg(m)
+}
+
+func (s *installSwitchesServer) Recv() (*proto.InstallSwitchesRequest, error) {
+	m := new(proto.InstallSwitchesRequest)
+	if err := s.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *watchSwitchesServer) Send(m *proto.SwitchEvent) error {
+	return s.SendMsg(m)
+}
+
+func (s *getSchedulesServer) Send(m *proto.Schedule) error {
+	return s.SendMsg(m)
+}
+
+func (s *getGroupsServer) Send(m *proto.Group) error {
+	return s.SendMsg(m)
+}
+
+func (s *getScenesServer) Send(m *proto.Scene) error {
+	return s.SendMsg(m)
+}
+
+// httpStatus returns the HTTP status code for a gRPC status code
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		// Client Closed Request
+		return 499
+	case codes.Unknown:
+		return http.StatusInternalServerError
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Aborted:
+		return http.StatusConflict
+	case codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Internal:
+		return http.StatusInternalServerError
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DataLoss:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// findField returns the field of a message with a JSON name or a proto name
+func findField(msg protoreflect.Message, name string) protoreflect.FieldDescriptor {
+	fields := msg.Descriptor().Fields()
+	if fd := fields.ByJSONName(name); fd != nil {
+		return fd
+	}
+	return fields.ByName(protoreflect.Name(name))
+}
+
+// parseValue parses the value of a scalar or enum field
+func parseValue(fd protoreflect.FieldDescriptor, val string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(val)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(val, 10, 32)
+		return protoreflect.ValueOfInt32(int32(i)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(val, 10, 64)
+		return protoreflect.ValueOfInt64(i), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		u, err := strconv.ParseUint(val, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(u)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		u, err := strconv.ParseUint(val, 10, 64)
+		return protoreflect.ValueOfUint64(u), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(val, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(val, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(val), nil
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(val)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		i, err := strconv.ParseInt(val, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(i)), err
+	default:
+		return protoreflect.Value{}, fmt.Errorf("unsupported type %s", fd.Kind())
+	}
+}
+
+// setField sets a scalar, enum or repeated field of a request from strings.
+// Fields of nested messages are named with dots (switch.id).
+func setField(req protov1.Message, name string, vals []string) error {
+	msg := protov1.MessageReflect(req)
+	parts := strings.Split(name, ".")
+
+	for _, part := range parts[:len(parts)-1] {
+		fd := findField(msg, part)
+		if fd == nil || fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("unknown field %s", name)
+		}
+		msg = msg.Mutable(fd).Message()
+	}
+
+	fd := findField(msg, parts[len(parts)-1])
+	if fd == nil || fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind || fd.IsMap() {
+		return fmt.Errorf("unknown field %s", name)
+	}
+
+	if fd.IsList() {
+		list := msg.Mutable(fd).List()
+		for _, val := range vals {
+			v, err := parseValue(fd, val)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %s", name, err)
+			}
+			list.Append(v)
+		}
+		return nil
+	}
+
+	if len(vals) != 1 {
+		return fmt.Errorf("too many values for %s", name)
+	}
+
+	v, err := parseValue(fd, vals[0])
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %s", name, err)
+	}
+	msg.Set(fd, v)
+
+	return nil
+}
+
+// NewGateway creates a new gateway for a switch service
+// interceptor monitors every call and nil disables it.
+func NewGateway(interceptor *Interceptor, switchService proto.SwitchServiceServer) *Gateway {
+	return &Gateway{
+		interceptor:   interceptor,
+		switchService: switchService,
+		marshaler: &jsonpb.Marshaler{
+			EmitDefaults: true,
+		},
+		unmarshaler: &jsonpb.Unmarshaler{},
+	}
+}
+
+// routes returns the endpoints of the switch service
+func (g *Gateway) routes() []route {
+	s := g.switchService
+
+	getSwitches := func(req interface{}, stream grpc.ServerStream) error {
+		return s.GetSwitches(req.(*proto.GetSwitchesRequest), &getSwitchesServer{stream})
+	}
+	watchSwitches := func(req interface{}, stream grpc.ServerStream) error {
+		return s.WatchSwitches(req.(*proto.WatchSwitchesRequest), &watchSwitchesServer{stream})
+	}
+	getSchedules := func(req interface{}, stream grpc.ServerStream) error {
+		return s.GetSchedules(req.(*proto.GetSchedulesRequest), &getSchedulesServer{stream})
+	}
+	getGroups := func(req interface{}, stream grpc.ServerStream) error {
+		return s.GetGroups(req.(*proto.GetGroupsRequest), &getGroupsServer{stream})
+	}
+	getScenes := func(req interface{}, stream grpc.ServerStream) error {
+		return s.GetScenes(req.(*proto.GetScenesRequest), &getScenesServer{stream})
+	}
+
+	newGetSwitchesRequest := func() protov1.Message { return new(proto.GetSwitchesRequest) }
+	newWatchSwitchesRequest := func() protov1.Message { return new(proto.WatchSwitchesRequest) }
+	newGetSchedulesRequest := func() protov1.Message { return new(proto.GetSchedulesRequest) }
+	newGetGroupsRequest := func() protov1.Message { return new(proto.GetGroupsRequest) }
+	newGetScenesRequest := func() protov1.Message { return new(proto.GetScenesRequest) }
+
+	return []route{
+		// Switches
+		{
+			method: "POST", path: "/v1/switches", rpc: "InstallSwitch", body: true,
+			newReq: func() protov1.Message { return new(proto.InstallSwitchRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.InstallSwitch(ctx, req.(*proto.InstallSwitchRequest))
+			},
+		},
+		{
+			method: "POST", path: "/v1/switches/batch", rpc: "InstallSwitches", body: true,
+			stream: func(req interface{}, stream grpc.ServerStream) error {
+				return s.InstallSwitches(&installSwitchesServer{stream})
+			},
+		},
+		{
+			method: "GET", path: "/v1/switches", rpc: "GetSwitches",
+			newReq: newGetSwitchesRequest, stream: getSwitches,
+		},
+		{
+			method: "GET", path: "/v1/sites/{siteId}/switches", rpc: "GetSwitches",
+			newReq: newGetSwitchesRequest, stream: getSwitches,
+		},
+		{
+			method: "PUT", path: "/v1/switches/state", rpc: "SetSwitches", body: true,
+			newReq: func() protov1.Message { return new(proto.SetSwitchesRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.SetSwitches(ctx, req.(*proto.SetSwitchesRequest))
+			},
+		},
+		{
+			method: "GET", path: "/v1/switches/{id}", rpc: "GetSwitch",
+			newReq: func() protov1.Message { return new(proto.GetSwitchRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetSwitch(ctx, req.(*proto.GetSwitchRequest))
+			},
+		},
+		{
+			method: "PATCH", path: "/v1/switches/{switch.id}", rpc: "UpdateSwitch", body: true,
+			newReq: func() protov1.Message { return new(proto.UpdateSwitchRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.UpdateSwitch(ctx, req.(*proto.UpdateSwitchRequest))
+			},
+		},
+		{
+			method: "DELETE", path: "/v1/switches/{id}", rpc: "RemoveSwitch",
+			newReq: func() protov1.Message { return new(proto.RemoveSwitchRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.RemoveSwitch(ctx, req.(*proto.RemoveSwitchRequest))
+			},
+		},
+		{
+			method: "PUT", path: "/v1/switches/{id}/state", rpc: "SetSwitch", body: true,
+			newReq: func() protov1.Message { return new(proto.SetSwitchRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.SetSwitch(ctx, req.(*proto.SetSwitchRequest))
+			},
+		},
+		{
+			method: "PUT", path: "/v1/switches/{id}/reported-state", rpc: "ReportSwitchState", body: true,
+			newReq: func() protov1.Message { return new(proto.ReportSwitchStateRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ReportSwitchState(ctx, req.(*proto.ReportSwitchStateRequest))
+			},
+		},
+		{
+			method: "GET", path: "/v1/switches/{id}/transitions", rpc: "GetAllowedTransitions",
+			newReq: func() protov1.Message { return new(proto.GetAllowedTransitionsRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetAllowedTransitions(ctx, req.(*proto.GetAllowedTransitionsRequest))
+			},
+		},
+		{
+			method: "GET", path: "/v1/switches/{switchId}/history", rpc: "GetSwitchHistory",
+			newReq: func() protov1.Message { return new(proto.GetSwitchHistoryRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetSwitchHistory(ctx, req.(*proto.GetSwitchHistoryRequest))
+			},
+		},
+		{
+			method: "GET", path: "/v1/switches/{switchId}/schedules", rpc: "GetSchedules",
+			newReq: newGetSchedulesRequest, stream: getSchedules,
+		},
+		{
+			method: "GET", path: "/v1/events", rpc: "WatchSwitches",
+			newReq: newWatchSwitchesRequest, stream: watchSwitches,
+		},
+		{
+			method: "GET", path: "/v1/sites/{siteId}/events", rpc: "WatchSwitches",
+			newReq: newWatchSwitchesRequest, stream: watchSwitches,
+		},
+
+		// Schedules
+		{
+			method: "POST", path: "/v1/schedules", rpc: "CreateSchedule", body: true,
+			newReq: func() protov1.Message { return new(proto.CreateScheduleRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.CreateSchedule(ctx, req.(*proto.CreateScheduleRequest))
+			},
+		},
+		{
+			method: "GET", path: "/v1/schedules", rpc: "GetSchedules",
+			newReq: newGetSchedulesRequest, stream: getSchedules,
+		},
+		{
+			method: "GET", path: "/v1/sites/{siteId}/schedules", rpc: "GetSchedules",
+			newReq: newGetSchedulesRequest, stream: getSchedules,
+		},
+		{
+			method: "GET", path: "/v1/schedules/{id}", rpc: "GetSchedule",
+			newReq: func() protov1.Message { return new(proto.GetScheduleRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetSchedule(ctx, req.(*proto.GetScheduleRequest))
+			},
+		},
+		{
+			method: "PUT", path: "/v1/schedules/{id}", rpc: "UpdateSchedule", body: true,
+			newReq: func() protov1.Message { return new(proto.UpdateScheduleRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.UpdateSchedule(ctx, req.(*proto.UpdateScheduleRequest))
+			},
+		},
+		{
+			method: "DELETE", path: "/v1/schedules/{id}", rpc: "DeleteSchedule",
+			newReq: func() protov1.Message { return new(proto.DeleteScheduleRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.DeleteSchedule(ctx, req.(*proto.DeleteScheduleRequest))
+			},
+		},
+
+		// Groups
+		{
+			method: "POST", path: "/v1/groups", rpc: "CreateGroup", body: true,
+			newReq: func() protov1.Message { return new(proto.CreateGroupRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.CreateGroup(ctx, req.(*proto.CreateGroupRequest))
+			},
+		},
+		{
+			method: "GET", path: "/v1/groups", rpc: "GetGroups",
+			newReq: newGetGroupsRequest, stream: getGroups,
+		},
+		{
+			method: "GET", path: "/v1/sites/{siteId}/groups", rpc: "GetGroups",
+			newReq: newGetGroupsRequest, stream: getGroups,
+		},
+		{
+			method: "GET", path: "/v1/groups/{id}", rpc: "GetGroup",
+			newReq: func() protov1.Message { return new(proto.GetGroupRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetGroup(ctx, req.(*proto.GetGroupRequest))
+			},
+		},
+		{
+			method: "PUT", path: "/v1/groups/{id}", rpc: "UpdateGroup", body: true,
+			newReq: func() protov1.Message { return new(proto.UpdateGroupRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.UpdateGroup(ctx, req.(*proto.UpdateGroupRequest))
+			},
+		},
+		{
+			method: "DELETE", path: "/v1/groups/{id}", rpc: "DeleteGroup",
+			newReq: func() protov1.Message { return new(proto.DeleteGroupRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.DeleteGroup(ctx, req.(*proto.DeleteGroupRequest))
+			},
+		},
+
+		// Scenes
+		{
+			method: "POST", path: "/v1/scenes", rpc: "CreateScene", body: true,
+			newReq: func() protov1.Message { return new(proto.CreateSceneRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.CreateScene(ctx, req.(*proto.CreateSceneRequest))
+			},
+		},
+		{
+			method: "GET", path: "/v1/scenes", rpc: "GetScenes",
+			newReq: newGetScenesRequest, stream: getScenes,
+		},
+		{
+			method: "GET", path: "/v1/sites/{siteId}/scenes", rpc: "GetScenes",
+			newReq: newGetScenesRequest, stream: getScenes,
+		},
+		{
+			method: "GET", path: "/v1/scenes/{id}", rpc: "GetScene",
+			newReq: func() protov1.Message { return new(proto.GetSceneRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetScene(ctx, req.(*proto.GetSceneRequest))
+			},
+		},
+		{
+			method: "PUT", path: "/v1/scenes/{id}", rpc: "UpdateScene", body: true,
+			newReq: func() protov1.Message { return new(proto.UpdateSceneRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.UpdateScene(ctx, req.(*proto.UpdateSceneRequest))
+			},
+		},
+		{
+			method: "DELETE", path: "/v1/scenes/{id}", rpc: "DeleteScene",
+			newReq: func() protov1.Message { return new(proto.DeleteSceneRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.DeleteScene(ctx, req.(*proto.DeleteSceneRequest))
+			},
+		},
+		{
+			method: "POST", path: "/v1/scenes/{id}/apply", rpc: "ApplyScene", body: true,
+			newReq: func() protov1.Message { return new(proto.ApplySceneRequest) },
+			unary: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ApplyScene(ctx, req.(*proto.ApplySceneRequest))
+			},
+		},
+	}
+}
+
+// Register adds the endpoints of the switch service to a router
+func (g *Gateway) Register(router *mux.Router) {
+	for _, rt := range g.routes() {
+		router.Methods(rt.method).Path(rt.path).HandlerFunc(g.handler(rt))
+	}
+}
+
+// context creates the context of a call with the HTTP headers as the incoming metadata
+func (g *Gateway) context(r *http.Request) context.Context {
+	md := metadata.MD{}
+	for key, vals := range r.Header {
+		md.Append(key, vals...)
+	}
+
+	return metadata.NewIncomingContext(r.Context(), md)
+}
+
+// request creates the request of a call from the body, the query parameters and the path variables of an HTTP request
+func (g *Gateway) request(r *http.Request, rt route) (protov1.Message, error) {
+	req := rt.newReq()
+
+	if rt.body {
+		if err := g.unmarshaler.Unmarshal(r.Body, req); err != nil && err != io.EOF {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid body: %s", err)
+		}
+	} else {
+		for name, vals := range r.URL.Query() {
+			if err := setField(req, name, vals); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid query: %s", err)
+			}
+		}
+	}
+
+	// Path variables take precedence over the body
+	for name, val := range mux.Vars(r) {
+		if err := setField(req, name, []string{val}); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid path: %s", err)
+		}
+	}
+
+	return req, nil
+}
+
+// handler returns the HTTP handler of an endpoint
+func (g *Gateway) handler(rt route) http.HandlerFunc {
+	method := switchServiceMethodPrefix + rt.rpc
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := g.context(r)
+
+		var req protov1.Message
+		if rt.newReq != nil {
+			var err error
+			if req, err = g.request(r, rt); err != nil {
+				g.writeError(w, err)
+				return
+			}
+		}
+
+		if rt.unary != nil {
+			g.serveUnary(ctx, w, method, req, rt.unary)
+		} else {
+			g.serveStream(ctx, w, r, method, req, rt.stream)
+		}
+	}
+}
+
+// serveUnary calls a unary method and writes its response
+func (g *Gateway) serveUnary(ctx context.Context, w http.ResponseWriter, method string, req protov1.Message, handler grpc.UnaryHandler) {
+	var resp interface{}
+	var err error
+
+	if g.interceptor != nil {
+		resp, err = g.interceptor.Unary(ctx, req, &grpc.UnaryServerInfo{Server: g.switchService, FullMethod: method}, handler)
+	} else {
+		resp, err = handler(ctx, req)
+	}
+
+	if err != nil {
+		g.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", jsonContentType)
+	_ = g.marshaler.Marshal(w, resp.(protov1.Message))
+}
+
+// serveStream calls a streaming method and writes the messages it sends as they are sent
+func (g *Gateway) serveStream(ctx context.Context, w http.ResponseWriter, r *http.Request, method string, req protov1.Message, handler func(interface{}, grpc.ServerStream) error) {
+	// Client streams read their requests from the body and send a single response
+	clientStream := req == nil
+	contentType := ndjsonContentType
+	if clientStream {
+		contentType = jsonContentType
+	}
+
+	stream := &httpStream{
+		ctx:         ctx,
+		w:           w,
+		decoder:     json.NewDecoder(r.Body),
+		marshaler:   g.marshaler,
+		unmarshaler: g.unmarshaler,
+		contentType: contentType,
+		header:      metadata.MD{},
+		trailer:     metadata.MD{},
+	}
+
+	streamHandler := func(srv interface{}, ss grpc.ServerStream) error {
+		return handler(req, ss)
+	}
+
+	var err error
+	if g.interceptor != nil {
+		info := &grpc.StreamServerInfo{FullMethod: method, IsClientStream: clientStream, IsServerStream: !clientStream}
+		err = g.interceptor.Stream(g.switchService, stream, info, streamHandler)
+	} else {
+		err = streamHandler(g.switchService, stream)
+	}
+
+	if err != nil {
+		// Once the response has started the error can only be added to the stream
+		if stream.sent {
+			stream.writeError(err)
+		} else {
+			g.writeError(w, err)
+		}
+		return
+	}
+
+	if !stream.sent {
+		stream.writeHeader()
+	}
+
+	// Many HTTP clients and proxies drop trailers, so the token of the next page is also written as the last line
+	if vals := stream.trailer.Get(nextPageTokenKey); !clientStream && len(vals) > 0 {
+		stream.writeNextPageToken(vals[0])
+	}
+
+	for key, vals := range stream.trailer {
+		for _, val := range vals {
+			w.Header().Add(http.TrailerPrefix+key, val)
+		}
+	}
+}
+
+// statusJSON returns the JSON encoding of the status of an error
+func statusJSON(marshaler *jsonpb.Marshaler, err error) []byte {
+	st := status.Convert(err)
+
+	buf := new(bytes.Buffer)
+	if err := marshaler.Marshal(buf, st.Proto()); err != nil {
+		// The details cannot be encoded
+		buf.Reset()
+		_ = marshaler.Marshal(buf, status.New(st.Code(), st.Message()).Proto())
+	}
+
+	return buf.Bytes()
+}
+
+// writeError writes an error response with the HTTP status code for its gRPC status code
+func (g *Gateway) writeError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(httpStatus(status.Code(err)))
+	_, _ = w.Write(statusJSON(g.marshaler, err))
+}
+
+func (s *httpStream) SetHeader(md metadata.MD) error {
+	if s.sent {
+		return status.Error(codes.Internal, "header already sent")
+	}
+
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *httpStream) SendHeader(md metadata.MD) error {
+	if err := s.SetHeader(md); err != nil {
+		return err
+	}
+
+	s.writeHeader()
+	return nil
+}
+
+func (s *httpStream) SetTrailer(md metadata.MD) {
+	s.trailer = metadata.Join(s.trailer, md)
+}
+
+func (s *httpStream) Context() context.Context {
+	return s.ctx
+}
+
+// writeHeader starts the response
+func (s *httpStream) writeHeader() {
+	for key, vals := range s.header {
+		for _, val := range vals {
+			s.w.Header().Add(key, val)
+		}
+	}
+
+	s.w.Header().Set("Content-Type", s.contentType)
+	s.w.WriteHeader(http.StatusOK)
+	s.sent = true
+}
+
+// writeLine writes a line to the response and flushes it to the client
+func (s *httpStream) writeLine(line []byte) error {
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	return nil
+}
+
+// writeError writes the status of an error as the last line of the response
+func (s *httpStream) writeError(err error) {
+	line := fmt.Sprintf(`{"error":%s}`, statusJSON(s.marshaler, err))
+	_ = s.writeLine([]byte(line))
+}
+
+// writeNextPageToken writes the token of the next page as the last line of the response
+func (s *httpStream) writeNextPageToken(token string) {
+	data, _ := json.Marshal(token)
+	line := fmt.Sprintf(`{"nextPageToken":%s}`, data)
+	_ = s.writeLine([]byte(line))
+}
+
+func (s *httpStream) SendMsg(m interface{}) error {
+	if !s.sent {
+		s.writeHeader()
+	}
+
+	buf := new(bytes.Buffer)
+	if err := s.marshaler.Marshal(buf, m.(protov1.Message)); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return s.writeLine(buf.Bytes())
+}
+
+func (s *httpStream) RecvMsg(m interface{}) error {
+	if !s.decoder.More() {
+		return io.EOF
+	}
+
+	if err := s.unmarshaler.UnmarshalNext(s.decoder, m.(protov1.Message)); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid body: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,415 @@
+package transport
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newGatewayServer creates a test server serving the endpoints of a gateway
+func newGatewayServer(gateway *Gateway) *httptest.Server {
+	router := mux.NewRouter()
+	gateway.Register(router)
+
+	return httptest.NewServer(router)
+}
+
+// doRequest sends a request with a tenant to a test server
+func doRequest(t *testing.T, ts *httptest.Server, method, path, body string) (*http.Response, string) {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set("Tenant-ID", "tenant-a")
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	return resp, string(data)
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code               codes.Code
+		expectedStatusCode int
+	}{
+		{codes.OK, http.StatusOK},
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.AlreadyExists, http.StatusConflict},
+		{codes.FailedPrecondition, http.StatusBadRequest},
+		{codes.Aborted, http.StatusConflict},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.Unknown, http.StatusInternalServerError},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.code.String(), func(t *testing.T) {
+			assert.Equal(t, tc.expectedStatusCode, httpStatus(tc.code))
+		})
+	}
+}
+
+func TestSetField(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           *proto.GetSwitchesRequest
+		field         string
+		vals          []string
+		expectedError bool
+		expectedReq   *proto.GetSwitchesRequest
+	}{
+		{"JSONName", &proto.GetSwitchesRequest{}, "siteId", []string{"1111"}, false, &proto.GetSwitchesRequest{SiteId: "1111"}},
+		{"ProtoName", &proto.GetSwitchesRequest{}, "name_prefix", []string{"light"}, false, &proto.GetSwitchesRequest{NamePrefix: "light"}},
+		{"Repeated", &proto.GetSwitchesRequest{}, "siteIds", []string{"1111", "2222"}, false, &proto.GetSwitchesRequest{SiteIds: []string{"1111", "2222"}}},
+		{"Bool", &proto.GetSwitchesRequest{}, "descending", []string{"true"}, false, &proto.GetSwitchesRequest{Descending: true}},
+		{"Int32", &proto.GetSwitchesRequest{}, "pageSize", []string{"10"}, false, &proto.GetSwitchesRequest{PageSize: 10}},
+		{"EnumName", &proto.GetSwitchesRequest{}, "sortBy", []string{"STATE"}, false, &proto.GetSwitchesRequest{SortBy: proto.GetSwitchesRequest_STATE}},
+		{"EnumNumber", &proto.GetSwitchesRequest{}, "sortBy", []string{"2"}, false, &proto.GetSwitchesRequest{SortBy: proto.GetSwitchesRequest_SITE_ID}},
+		{"UnknownField", &proto.GetSwitchesRequest{}, "color", []string{"red"}, true, nil},
+		{"InvalidValue", &proto.GetSwitchesRequest{}, "pageSize", []string{"ten"}, true, nil},
+		{"TooManyValues", &proto.GetSwitchesRequest{}, "state", []string{"on", "off"}, true, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := setField(tc.req, tc.field, tc.vals)
+
+			if tc.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedReq.String(), tc.req.String())
+			}
+		})
+	}
+
+	t.Run("Nested", func(t *testing.T) {
+		req := &proto.UpdateSwitchRequest{}
+		err := setField(req, "switch.id", []string{"1111"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "1111", req.Switch.Id)
+	})
+}
+
+func TestGatewayUnary(t *testing.T) {
+	st, _ := status.New(codes.FailedPrecondition, "switch changed").WithDetails(&errdetails.PreconditionFailure{
+		Violations: []*errdetails.PreconditionFailure_Violation{
+			{Type: "REVISION", Description: "switch is at another revision"},
+		},
+	})
+
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		switchService      *mockSwitchService
+		expectedStatusCode int
+		expectedBody       string
+		check              func(*testing.T, *mockSwitchService)
+	}{
+		{
+			name:   "GetSwitch",
+			method: "GET",
+			path:   "/v1/switches/1111",
+			switchService: &mockSwitchService{
+				GetSwitchOutResp: &proto.Switch{Id: "1111", SiteId: "2222", Name: "light", State: "on", States: []string{"on", "off"}},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `"siteId":"2222"`,
+			check: func(t *testing.T, m *mockSwitchService) {
+				assert.Equal(t, "1111", m.GetSwitchInReq.Id)
+				md, _ := metadata.FromIncomingContext(m.GetSwitchInContext)
+				assert.Equal(t, []string{"tenant-a"}, md.Get("tenant-id"))
+			},
+		},
+		{
+			name:   "SwitchNotFound",
+			method: "GET",
+			path:   "/v1/switches/1111",
+			switchService: &mockSwitchService{
+				GetSwitchOutError: status.Error(codes.NotFound, "switch not found"),
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `"message":"switch not found"`,
+		},
+		{
+			name:   "SetSwitch",
+			method: "PUT",
+			path:   "/v1/switches/1111/state",
+			body:   `{"id": "2222", "state": "off", "expectedRevision": "1"}`,
+			switchService: &mockSwitchService{
+				SetSwitchOutResp: &proto.SetSwitchResponse{Revision: "2", PreviousState: "on"},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `"previousState":"on"`,
+			check: func(t *testing.T, m *mockSwitchService) {
+				assert.Equal(t, "1111", m.SetSwitchInReq.Id)
+				assert.Equal(t, "off", m.SetSwitchInReq.State)
+				assert.Equal(t, "1", m.SetSwitchInReq.ExpectedRevision)
+			},
+		},
+		{
+			name:   "SetSwitchPreconditionFailed",
+			method: "PUT",
+			path:   "/v1/switches/1111/state",
+			body:   `{"state": "off", "expectedRevision": "1"}`,
+			switchService: &mockSwitchService{
+				SetSwitchOutError: st.Err(),
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `"type":"REVISION"`,
+		},
+		{
+			name:   "UpdateSwitch",
+			method: "PATCH",
+			path:   "/v1/switches/1111",
+			body:   `{"switch": {"name": "lamp"}, "updateMask": {"paths": ["name"]}}`,
+			switchService: &mockSwitchService{
+				UpdateSwitchOutResp: &proto.Switch{Id: "1111", Name: "lamp"},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `"name":"lamp"`,
+			check: func(t *testing.T, m *mockSwitchService) {
+				assert.Equal(t, "1111", m.UpdateSwitchInReq.Switch.Id)
+				assert.Equal(t, "lamp", m.UpdateSwitchInReq.Switch.Name)
+				assert.Equal(t, []string{"name"}, m.UpdateSwitchInReq.UpdateMask.Paths)
+			},
+		},
+		{
+			name:               "InvalidBody",
+			method:             "POST",
+			path:               "/v1/switches",
+			body:               `{"siteId": 1111}`,
+			switchService:      &mockSwitchService{},
+			expectedStatusCode: http.StatusBadRequest,
+			check: func(t *testing.T, m *mockSwitchService) {
+				assert.False(t, m.InstallSwitchCalled)
+			},
+		},
+		{
+			name:   "GetSwitchHistory",
+			method: "GET",
+			path:   "/v1/switches/1111/history?fromTime=100&pageSize=10",
+			switchService: &mockSwitchService{
+				GetSwitchHistoryOutResp: &proto.GetSwitchHistoryResponse{},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `"changes":[]`,
+			check: func(t *testing.T, m *mockSwitchService) {
+				assert.Equal(t, "1111", m.GetSwitchHistoryInReq.SwitchId)
+				assert.Equal(t, int64(100), m.GetSwitchHistoryInReq.FromTime)
+				assert.Equal(t, int32(10), m.GetSwitchHistoryInReq.PageSize)
+			},
+		},
+		{
+			name:               "InvalidQuery",
+			method:             "GET",
+			path:               "/v1/switches/1111/history?pageSize=ten",
+			switchService:      &mockSwitchService{},
+			expectedStatusCode: http.StatusBadRequest,
+			check: func(t *testing.T, m *mockSwitchService) {
+				assert.False(t, m.GetSwitchHistoryCalled)
+			},
+		},
+		{
+			name:   "ApplyScene",
+			method: "POST",
+			path:   "/v1/scenes/3333/apply",
+			switchService: &mockSwitchService{
+				ApplySceneOutResp: &proto.ApplySceneResponse{UnchangedIds: []string{"1111"}},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `"unchangedIds":["1111"]`,
+			check: func(t *testing.T, m *mockSwitchService) {
+				assert.Equal(t, "3333", m.ApplySceneInReq.Id)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newGatewayServer(NewGateway(nil, tc.switchService))
+			defer ts.Close()
+
+			resp, body := doRequest(t, ts, tc.method, tc.path, tc.body)
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			assert.Equal(t, jsonContentType, resp.Header.Get("Content-Type"))
+			assert.Contains(t, body, tc.expectedBody)
+			if tc.check != nil {
+				tc.check(t, tc.switchService)
+			}
+		})
+	}
+}
+
+func TestGatewayStream(t *testing.T) {
+	tests := []struct {
+		name                string
+		path                string
+		switchService       *mockSwitchService
+		expectedStatusCode  int
+		expectedContentType string
+		expectedLines       []string
+		expectedTrailer     string
+	}{
+		{
+			name: "Page",
+			path: "/v1/sites/2222/switches?state=on&sortBy=NAME&pageSize=2",
+			switchService: &mockSwitchService{
+				GetSwitchesOutSwitches: []*proto.Switch{{Id: "1111"}, {Id: "3333"}},
+				GetSwitchesOutTrailer:  metadata.Pairs("next-page-token", "Mg=="),
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: ndjsonContentType,
+			expectedLines:       []string{`"id":"1111"`, `"id":"3333"`, `{"nextPageToken":"Mg=="}`},
+			expectedTrailer:     "Mg==",
+		},
+		{
+			name: "LastPage",
+			path: "/v1/switches?pageSize=2",
+			switchService: &mockSwitchService{
+				GetSwitchesOutSwitches: []*proto.Switch{{Id: "1111"}},
+				GetSwitchesOutTrailer:  metadata.Pairs("next-page-token", ""),
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: ndjsonContentType,
+			expectedLines:       []string{`"id":"1111"`, `{"nextPageToken":""}`},
+		},
+		{
+			name:                "Empty",
+			path:                "/v1/switches",
+			switchService:       &mockSwitchService{},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: ndjsonContentType,
+		},
+		{
+			name: "ErrorBeforeSend",
+			path: "/v1/switches?pageToken=invalid",
+			switchService: &mockSwitchService{
+				GetSwitchesOutError: status.Error(codes.InvalidArgument, "invalid page token"),
+			},
+			expectedStatusCode:  http.StatusBadRequest,
+			expectedContentType: jsonContentType,
+			expectedLines:       []string{`"message":"invalid page token"`},
+		},
+		{
+			name: "ErrorAfterSend",
+			path: "/v1/switches",
+			switchService: &mockSwitchService{
+				GetSwitchesOutSwitches: []*proto.Switch{{Id: "1111"}},
+				GetSwitchesOutError:    errors.New("database error"),
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: ndjsonContentType,
+			expectedLines:       []string{`"id":"1111"`, `{"error":{"code":2,"message":"database error"`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newGatewayServer(NewGateway(nil, tc.switchService))
+			defer ts.Close()
+
+			resp, body := doRequest(t, ts, "GET", tc.path, "")
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			assert.Equal(t, tc.expectedContentType, resp.Header.Get("Content-Type"))
+			assert.Equal(t, tc.expectedTrailer, resp.Trailer.Get("next-page-token"))
+
+			var lines []string
+			scanner := bufio.NewScanner(strings.NewReader(body))
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+
+			assert.Len(t, lines, len(tc.expectedLines))
+			for i := range lines {
+				if i < len(tc.expectedLines) {
+					assert.Contains(t, lines[i], tc.expectedLines[i])
+				}
+			}
+		})
+	}
+
+	t.Run("Request", func(t *testing.T) {
+		switchService := &mockSwitchService{}
+		ts := newGatewayServer(NewGateway(nil, switchService))
+		defer ts.Close()
+
+		doRequest(t, ts, "GET", "/v1/sites/2222/switches?siteIds=3333&state=on&sortBy=NAME&descending=true&pageSize=2", "")
+
+		assert.Equal(t, "2222", switchService.GetSwitchesInReq.SiteId)
+		assert.Equal(t, []string{"3333"}, switchService.GetSwitchesInReq.SiteIds)
+		assert.Equal(t, "on", switchService.GetSwitchesInReq.State)
+		assert.Equal(t, proto.GetSwitchesRequest_NAME, switchService.GetSwitchesInReq.SortBy)
+		assert.True(t, switchService.GetSwitchesInReq.Descending)
+		assert.Equal(t, int32(2), switchService.GetSwitchesInReq.PageSize)
+	})
+}
+
+func TestGatewayInstallSwitches(t *testing.T) {
+	switchService := &mockSwitchService{
+		InstallSwitchesOutResp: &proto.InstallSwitchesResponse{
+			Results: []*proto.SwitchResult{{Id: "1111"}, {Id: "3333"}},
+		},
+	}
+
+	ts := newGatewayServer(NewGateway(nil, switchService))
+	defer ts.Close()
+
+	body := `{"mode": "ALL_OR_NOTHING", "switch": {"siteId": "2222", "name": "light", "state": "on", "states": ["on", "off"]}}
+{"switch": {"siteId": "2222", "name": "fan", "state": "off", "states": ["on", "off"]}}
+`
+	resp, data := doRequest(t, ts, "POST", "/v1/switches/batch", body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, jsonContentType, resp.Header.Get("Content-Type"))
+	assert.Contains(t, data, `"id":"3333"`)
+	assert.Len(t, switchService.InstallSwitchesInReqs, 2)
+	assert.Equal(t, proto.BulkMode_ALL_OR_NOTHING, switchService.InstallSwitchesInReqs[0].Mode)
+	assert.Equal(t, "fan", switchService.InstallSwitchesInReqs[1].Switch.Name)
+}
+
+func TestGatewayInterceptor(t *testing.T) {
+	metrics := metrics.Mock()
+	tracer := mocktracer.New()
+	interceptor := NewInterceptor(log.NewVoidLogger(), metrics, tracer, nil)
+
+	switchService := &mockSwitchService{
+		GetSwitchOutError:      status.Error(codes.NotFound, "switch not found"),
+		GetSwitchesOutSwitches: []*proto.Switch{{Id: "1111"}},
+	}
+
+	ts := newGatewayServer(NewGateway(interceptor, switchService))
+	defer ts.Close()
+
+	doRequest(t, ts, "GET", "/v1/switches/1111", "")
+	doRequest(t, ts, "GET", "/v1/switches", "")
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ReqCounter.WithLabelValues("/proto.SwitchService/GetSwitch", "NotFound")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ReqCounter.WithLabelValues("/proto.SwitchService/GetSwitches", "OK")))
+	assert.Len(t, tracer.FinishedSpans(), 2)
+}
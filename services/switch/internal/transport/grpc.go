@@ -1,16 +1,21 @@
 package transport
 
 import (
-	"crypto/tls"
-	"crypto/x509"
-	"errors"
-	"io/ioutil"
 	"net"
 	"net/http"
+	"time"
 
-	"github.com/moorara/microservices-demo/services/switch/internal/proto"
+	"github.com/moorara/microservices-demo/services/switch/pkg/certs"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+)
+
+const (
+	keepaliveMinTime = 10 * time.Second
 )
 
 type (
@@ -24,38 +29,39 @@ type (
 )
 
 // NewGRPCServer creates a new grpc server
-func NewGRPCServer(caFile, certFile, keyFile string, switchService proto.SwitchServiceServer) (GRPCServer, error) {
-	opts := []grpc.ServerOption{}
+// store holds the certificates for MTLS and nil disables it.
+// interceptor monitors every call and nil disables it.
+// health serves the standard health checks and nil disables them.
+// reflect enables server reflection for tools such as grpcurl.
+func NewGRPCServer(store *certs.Store, reflect bool, interceptor *Interceptor, health grpc_health_v1.HealthServer, switchService proto.SwitchServiceServer) (GRPCServer, error) {
+	opts := []grpc.ServerOption{
+		// Clients check idle connections with pings as often as every keepaliveMinTime
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             keepaliveMinTime,
+			PermitWithoutStream: true,
+		}),
+	}
+
+	if interceptor != nil {
+		opts = append(opts, grpc.UnaryInterceptor(interceptor.Unary), grpc.StreamInterceptor(interceptor.Stream))
+	}
 
 	// Configure MTLS
-	if caFile != "" && certFile != "" && keyFile != "" {
-		ca, err := ioutil.ReadFile(caFile)
-		if err != nil {
-			return nil, err
-		}
-
-		pool := x509.NewCertPool()
-		if ok := pool.AppendCertsFromPEM(ca); !ok {
-			return nil, errors.New("Failed to append certificate authority")
-		}
-
-		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
-		if err != nil {
-			return nil, err
-		}
-
-		tlsConfig := &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			ClientAuth:   tls.RequireAndVerifyClientCert,
-			ClientCAs:    pool,
-		}
-
-		creds := credentials.NewTLS(tlsConfig)
+	if store != nil {
+		creds := credentials.NewTLS(store.ServerConfig())
 		opts = append(opts, grpc.Creds(creds))
 	}
 
 	grpcServer := grpc.NewServer(opts...)
 	proto.RegisterSwitchServiceServer(grpcServer, switchService)
 
+	if health != nil {
+		grpc_health_v1.RegisterHealthServer(grpcServer, health)
+	}
+
+	if reflect {
+		reflection.Register(grpcServer)
+	}
+
 	return grpcServer, nil
 }
@@ -2,10 +2,18 @@ package transport
 
 import (
 	"context"
+	"io"
 	"testing"
 
-	"github.com/moorara/microservices-demo/services/switch/internal/proto"
+	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
+	"github.com/moorara/microservices-demo/services/switch/pkg/certs"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 )
 
 // mockSwitchService is a mock implementation of proto.SwitchServiceServer
@@ -28,16 +36,152 @@ type mockSwitchService struct {
 	GetSwitchOutResp   *proto.Switch
 	GetSwitchOutError  error
 
-	GetSwitchesCalled   bool
-	GetSwitchesInReq    *proto.GetSwitchesRequest
-	GetSwitchesInStream proto.SwitchService_GetSwitchesServer
-	GetSwitchesOutError error
+	GetSwitchesCalled      bool
+	GetSwitchesInReq       *proto.GetSwitchesRequest
+	GetSwitchesInStream    proto.SwitchService_GetSwitchesServer
+	GetSwitchesOutSwitches []*proto.Switch
+	GetSwitchesOutTrailer  metadata.MD
+	GetSwitchesOutError    error
 
 	SetSwitchCalled    bool
 	SetSwitchInContext context.Context
 	SetSwitchInReq     *proto.SetSwitchRequest
 	SetSwitchOutResp   *proto.SetSwitchResponse
 	SetSwitchOutError  error
+
+	ReportSwitchStateCalled    bool
+	ReportSwitchStateInContext context.Context
+	ReportSwitchStateInReq     *proto.ReportSwitchStateRequest
+	ReportSwitchStateOutResp   *proto.Switch
+	ReportSwitchStateOutError  error
+
+	UpdateSwitchCalled    bool
+	UpdateSwitchInContext context.Context
+	UpdateSwitchInReq     *proto.UpdateSwitchRequest
+	UpdateSwitchOutResp   *proto.Switch
+	UpdateSwitchOutError  error
+
+	GetAllowedTransitionsCalled    bool
+	GetAllowedTransitionsInContext context.Context
+	GetAllowedTransitionsInReq     *proto.GetAllowedTransitionsRequest
+	GetAllowedTransitionsOutResp   *proto.GetAllowedTransitionsResponse
+	GetAllowedTransitionsOutError  error
+
+	SetSwitchesCalled    bool
+	SetSwitchesInContext context.Context
+	SetSwitchesInReq     *proto.SetSwitchesRequest
+	SetSwitchesOutResp   *proto.SetSwitchesResponse
+	SetSwitchesOutError  error
+
+	InstallSwitchesCalled   bool
+	InstallSwitchesInStream proto.SwitchService_InstallSwitchesServer
+	InstallSwitchesInReqs   []*proto.InstallSwitchesRequest
+	InstallSwitchesOutResp  *proto.InstallSwitchesResponse
+	InstallSwitchesOutError error
+
+	WatchSwitchesCalled   bool
+	WatchSwitchesInReq    *proto.WatchSwitchesRequest
+	WatchSwitchesInStream proto.SwitchService_WatchSwitchesServer
+	WatchSwitchesOutError error
+
+	GetSwitchHistoryCalled    bool
+	GetSwitchHistoryInContext context.Context
+	GetSwitchHistoryInReq     *proto.GetSwitchHistoryRequest
+	GetSwitchHistoryOutResp   *proto.GetSwitchHistoryResponse
+	GetSwitchHistoryOutError  error
+
+	CreateScheduleCalled    bool
+	CreateScheduleInContext context.Context
+	CreateScheduleInReq     *proto.CreateScheduleRequest
+	CreateScheduleOutResp   *proto.Schedule
+	CreateScheduleOutError  error
+
+	GetScheduleCalled    bool
+	GetScheduleInContext context.Context
+	GetScheduleInReq     *proto.GetScheduleRequest
+	GetScheduleOutResp   *proto.Schedule
+	GetScheduleOutError  error
+
+	GetSchedulesCalled   bool
+	GetSchedulesInReq    *proto.GetSchedulesRequest
+	GetSchedulesInStream proto.SwitchService_GetSchedulesServer
+	GetSchedulesOutError error
+
+	UpdateScheduleCalled    bool
+	UpdateScheduleInContext context.Context
+	UpdateScheduleInReq     *proto.UpdateScheduleRequest
+	UpdateScheduleOutResp   *proto.Schedule
+	UpdateScheduleOutError  error
+
+	DeleteScheduleCalled    bool
+	DeleteScheduleInContext context.Context
+	DeleteScheduleInReq     *proto.DeleteScheduleRequest
+	DeleteScheduleOutResp   *proto.DeleteScheduleResponse
+	DeleteScheduleOutError  error
+
+	CreateGroupCalled    bool
+	CreateGroupInContext context.Context
+	CreateGroupInReq     *proto.CreateGroupRequest
+	CreateGroupOutResp   *proto.Group
+	CreateGroupOutError  error
+
+	GetGroupCalled    bool
+	GetGroupInContext context.Context
+	GetGroupInReq     *proto.GetGroupRequest
+	GetGroupOutResp   *proto.Group
+	GetGroupOutError  error
+
+	GetGroupsCalled   bool
+	GetGroupsInReq    *proto.GetGroupsRequest
+	GetGroupsInStream proto.SwitchService_GetGroupsServer
+	GetGroupsOutError error
+
+	UpdateGroupCalled    bool
+	UpdateGroupInContext context.Context
+	UpdateGroupInReq     *proto.UpdateGroupRequest
+	UpdateGroupOutResp   *proto.Group
+	UpdateGroupOutError  error
+
+	DeleteGroupCalled    bool
+	DeleteGroupInContext context.Context
+	DeleteGroupInReq     *proto.DeleteGroupRequest
+	DeleteGroupOutResp   *proto.DeleteGroupResponse
+	DeleteGroupOutError  error
+
+	CreateSceneCalled    bool
+	CreateSceneInContext context.Context
+	CreateSceneInReq     *proto.CreateSceneRequest
+	CreateSceneOutResp   *proto.Scene
+	CreateSceneOutError  error
+
+	GetSceneCalled    bool
+	GetSceneInContext context.Context
+	GetSceneInReq     *proto.GetSceneRequest
+	GetSceneOutResp   *proto.Scene
+	GetSceneOutError  error
+
+	GetScenesCalled   bool
+	GetScenesInReq    *proto.GetScenesRequest
+	GetScenesInStream proto.SwitchService_GetScenesServer
+	GetScenesOutError error
+
+	UpdateSceneCalled    bool
+	UpdateSceneInContext context.Context
+	UpdateSceneInReq     *proto.UpdateSceneRequest
+	UpdateSceneOutResp   *proto.Scene
+	UpdateSceneOutError  error
+
+	DeleteSceneCalled    bool
+	DeleteSceneInContext context.Context
+	DeleteSceneInReq     *proto.DeleteSceneRequest
+	DeleteSceneOutResp   *proto.DeleteSceneResponse
+	DeleteSceneOutError  error
+
+	ApplySceneCalled    bool
+	ApplySceneInContext context.Context
+	ApplySceneInReq     *proto.ApplySceneRequest
+	ApplySceneOutResp   *proto.ApplySceneResponse
+	ApplySceneOutError  error
 }
 
 func (m *mockSwitchService) InstallSwitch(ctx context.Context, req *proto.InstallSwitchRequest) (*proto.Switch, error) {
@@ -65,6 +209,12 @@ func (m *mockSwitchService) GetSwitches(req *proto.GetSwitchesRequest, stream pr
 	m.GetSwitchesCalled = true
 	m.GetSwitchesInReq = req
 	m.GetSwitchesInStream = stream
+	for _, sw := range m.GetSwitchesOutSwitches {
+		if err := stream.Send(sw); err != nil {
+			return err
+		}
+	}
+	stream.SetTrailer(m.GetSwitchesOutTrailer)
 	return m.GetSwitchesOutError
 }
 
@@ -75,12 +225,188 @@ func (m *mockSwitchService) SetSwitch(ctx context.Context, req *proto.SetSwitchR
 	return m.SetSwitchOutResp, m.SetSwitchOutError
 }
 
+func (m *mockSwitchService) ReportSwitchState(ctx context.Context, req *proto.ReportSwitchStateRequest) (*proto.Switch, error) {
+	m.ReportSwitchStateCalled = true
+	m.ReportSwitchStateInContext = ctx
+	m.ReportSwitchStateInReq = req
+	return m.ReportSwitchStateOutResp, m.ReportSwitchStateOutError
+}
+
+func (m *mockSwitchService) UpdateSwitch(ctx context.Context, req *proto.UpdateSwitchRequest) (*proto.Switch, error) {
+	m.UpdateSwitchCalled = true
+	m.UpdateSwitchInContext = ctx
+	m.UpdateSwitchInReq = req
+	return m.UpdateSwitchOutResp, m.UpdateSwitchOutError
+}
+
+func (m *mockSwitchService) GetAllowedTransitions(ctx context.Context, req *proto.GetAllowedTransitionsRequest) (*proto.GetAllowedTransitionsResponse, error) {
+	m.GetAllowedTransitionsCalled = true
+	m.GetAllowedTransitionsInContext = ctx
+	m.GetAllowedTransitionsInReq = req
+	return m.GetAllowedTransitionsOutResp, m.GetAllowedTransitionsOutError
+}
+
+func (m *mockSwitchService) SetSwitches(ctx context.Context, req *proto.SetSwitchesRequest) (*proto.SetSwitchesResponse, error) {
+	m.SetSwitchesCalled = true
+	m.SetSwitchesInContext = ctx
+	m.SetSwitchesInReq = req
+	return m.SetSwitchesOutResp, m.SetSwitchesOutError
+}
+
+func (m *mockSwitchService) InstallSwitches(stream proto.SwitchService_InstallSwitchesServer) error {
+	m.InstallSwitchesCalled = true
+	m.InstallSwitchesInStream = stream
+	if m.InstallSwitchesOutResp == nil {
+		return m.InstallSwitchesOutError
+	}
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(m.InstallSwitchesOutResp)
+		}
+		if err != nil {
+			return err
+		}
+		m.InstallSwitchesInReqs = append(m.InstallSwitchesInReqs, req)
+	}
+}
+
+func (m *mockSwitchService) WatchSwitches(req *proto.WatchSwitchesRequest, stream proto.SwitchService_WatchSwitchesServer) error {
+	m.WatchSwitchesCalled = true
+	m.WatchSwitchesInReq = req
+	m.WatchSwitchesInStream = stream
+	return m.WatchSwitchesOutError
+}
+
+func (m *mockSwitchService) GetSwitchHistory(ctx context.Context, req *proto.GetSwitchHistoryRequest) (*proto.GetSwitchHistoryResponse, error) {
+	m.GetSwitchHistoryCalled = true
+	m.GetSwitchHistoryInContext = ctx
+	m.GetSwitchHistoryInReq = req
+	return m.GetSwitchHistoryOutResp, m.GetSwitchHistoryOutError
+}
+
+func (m *mockSwitchService) CreateSchedule(ctx context.Context, req *proto.CreateScheduleRequest) (*proto.Schedule, error) {
+	m.CreateScheduleCalled = true
+	m.CreateScheduleInContext = ctx
+	m.CreateScheduleInReq = req
+	return m.CreateScheduleOutResp, m.CreateScheduleOutError
+}
+
+func (m *mockSwitchService) GetSchedule(ctx context.Context, req *proto.GetScheduleRequest) (*proto.Schedule, error) {
+	m.GetScheduleCalled = true
+	m.GetScheduleInContext = ctx
+	m.GetScheduleInReq = req
+	return m.GetScheduleOutResp, m.GetScheduleOutError
+}
+
+func (m *mockSwitchService) GetSchedules(req *proto.GetSchedulesRequest, stream proto.SwitchService_GetSchedulesServer) error {
+	m.GetSchedulesCalled = true
+	m.GetSchedulesInReq = req
+	m.GetSchedulesInStream = stream
+	return m.GetSchedulesOutError
+}
+
+func (m *mockSwitchService) UpdateSchedule(ctx context.Context, req *proto.UpdateScheduleRequest) (*proto.Schedule, error) {
+	m.UpdateScheduleCalled = true
+	m.UpdateScheduleInContext = ctx
+	m.UpdateScheduleInReq = req
+	return m.UpdateScheduleOutResp, m.UpdateScheduleOutError
+}
+
+func (m *mockSwitchService) DeleteSchedule(ctx context.Context, req *proto.DeleteScheduleRequest) (*proto.DeleteScheduleResponse, error) {
+	m.DeleteScheduleCalled = true
+	m.DeleteScheduleInContext = ctx
+	m.DeleteScheduleInReq = req
+	return m.DeleteScheduleOutResp, m.DeleteScheduleOutError
+}
+
+func (m *mockSwitchService) CreateGroup(ctx context.Context, req *proto.CreateGroupRequest) (*proto.Group, error) {
+	m.CreateGroupCalled = true
+	m.CreateGroupInContext = ctx
+	m.CreateGroupInReq = req
+	return m.CreateGroupOutResp, m.CreateGroupOutError
+}
+
+func (m *mockSwitchService) GetGroup(ctx context.Context, req *proto.GetGroupRequest) (*proto.Group, error) {
+	m.GetGroupCalled = true
+	m.GetGroupInContext = ctx
+	m.GetGroupInReq = req
+	return m.GetGroupOutResp, m.GetGroupOutError
+}
+
+func (m *mockSwitchService) GetGroups(req *proto.GetGroupsRequest, stream proto.SwitchService_GetGroupsServer) error {
+	m.GetGroupsCalled = true
+	m.GetGroupsInReq = req
+	m.GetGroupsInStream = stream
+	return m.GetGroupsOutError
+}
+
+func (m *mockSwitchService) UpdateGroup(ctx context.Context, req *proto.UpdateGroupRequest) (*proto.Group, error) {
+	m.UpdateGroupCalled = true
+	m.UpdateGroupInContext = ctx
+	m.UpdateGroupInReq = req
+	return m.UpdateGroupOutResp, m.UpdateGroupOutError
+}
+
+func (m *mockSwitchService) DeleteGroup(ctx context.Context, req *proto.DeleteGroupRequest) (*proto.DeleteGroupResponse, error) {
+	m.DeleteGroupCalled = true
+	m.DeleteGroupInContext = ctx
+	m.DeleteGroupInReq = req
+	return m.DeleteGroupOutResp, m.DeleteGroupOutError
+}
+
+func (m *mockSwitchService) CreateScene(ctx context.Context, req *proto.CreateSceneRequest) (*proto.Scene, error) {
+	m.CreateSceneCalled = true
+	m.CreateSceneInContext = ctx
+	m.CreateSceneInReq = req
+	return m.CreateSceneOutResp, m.CreateSceneOutError
+}
+
+func (m *mockSwitchService) GetScene(ctx context.Context, req *proto.GetSceneRequest) (*proto.Scene, error) {
+	m.GetSceneCalled = true
+	m.GetSceneInContext = ctx
+	m.GetSceneInReq = req
+	return m.GetSceneOutResp, m.GetSceneOutError
+}
+
+func (m *mockSwitchService) GetScenes(req *proto.GetScenesRequest, stream proto.SwitchService_GetScenesServer) error {
+	m.GetScenesCalled = true
+	m.GetScenesInReq = req
+	m.GetScenesInStream = stream
+	return m.GetScenesOutError
+}
+
+func (m *mockSwitchService) UpdateScene(ctx context.Context, req *proto.UpdateSceneRequest) (*proto.Scene, error) {
+	m.UpdateSceneCalled = true
+	m.UpdateSceneInContext = ctx
+	m.UpdateSceneInReq = req
+	return m.UpdateSceneOutResp, m.UpdateSceneOutError
+}
+
+func (m *mockSwitchService) DeleteScene(ctx context.Context, req *proto.DeleteSceneRequest) (*proto.DeleteSceneResponse, error) {
+	m.DeleteSceneCalled = true
+	m.DeleteSceneInContext = ctx
+	m.DeleteSceneInReq = req
+	return m.DeleteSceneOutResp, m.DeleteSceneOutError
+}
+
+func (m *mockSwitchService) ApplyScene(ctx context.Context, req *proto.ApplySceneRequest) (*proto.ApplySceneResponse, error) {
+	m.ApplySceneCalled = true
+	m.ApplySceneInContext = ctx
+	m.ApplySceneInReq = req
+	return m.ApplySceneOutResp, m.ApplySceneOutError
+}
+
 func TestGRPCServer(t *testing.T) {
+	store, err := certs.NewStore("../../pkg/certs/ca.chain.cert", "../../pkg/certs/server.cert", "../../pkg/certs/server.key", log.NewVoidLogger(), nil)
+	assert.NoError(t, err)
+
 	tests := []struct {
 		name          string
-		caFile        string
-		certFile      string
-		keyFile       string
+		store         *certs.Store
+		reflect       bool
+		interceptor   *Interceptor
+		health        grpc_health_v1.HealthServer
 		switchService proto.SwitchServiceServer
 		expectError   bool
 	}{
@@ -90,42 +416,29 @@ func TestGRPCServer(t *testing.T) {
 			expectError:   false,
 		},
 		{
-			name:          "MTLS",
-			caFile:        "../certs/ca.chain.cert",
-			certFile:      "../certs/server.cert",
-			keyFile:       "../certs/server.key",
+			name:          "HealthAndReflection",
+			reflect:       true,
+			health:        health.NewServer(),
 			switchService: &mockSwitchService{},
 			expectError:   false,
 		},
 		{
-			name:          "NoCACert",
-			caFile:        "../certs/ca.chain",
-			certFile:      "../certs/server.cert",
-			keyFile:       "../certs/server.key",
-			switchService: &mockSwitchService{},
-			expectError:   true,
-		},
-		{
-			name:          "NoCert",
-			caFile:        "../certs/ca.chain.cert",
-			certFile:      "../certs/server",
-			keyFile:       "../certs/server.key",
+			name:          "Interceptor",
+			interceptor:   NewInterceptor(log.NewVoidLogger(), metrics.Mock(), mocktracer.New(), nil),
 			switchService: &mockSwitchService{},
-			expectError:   true,
+			expectError:   false,
 		},
 		{
-			name:          "NoKey",
-			caFile:        "../certs/ca.chain.cert",
-			certFile:      "../certs/server.cert",
-			keyFile:       "../certs/server",
+			name:          "MTLS",
+			store:         store,
 			switchService: &mockSwitchService{},
-			expectError:   true,
+			expectError:   false,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			grpcServer, err := NewGRPCServer(tc.caFile, tc.certFile, tc.keyFile, tc.switchService)
+			grpcServer, err := NewGRPCServer(tc.store, tc.reflect, tc.interceptor, tc.health, tc.switchService)
 
 			if tc.expectError {
 				assert.Error(t, err)
@@ -19,13 +19,18 @@ type (
 )
 
 // NewHTTPServer creates a new http server
-func NewHTTPServer(addr string, liveHandler, readyHandler, metricsHandler http.HandlerFunc) HTTPServer {
+// gateway serves the switch service as REST endpoints and nil disables them.
+func NewHTTPServer(addr string, liveHandler, readyHandler, metricsHandler http.HandlerFunc, gateway *Gateway) HTTPServer {
 	httpRouter := mux.NewRouter()
 	httpRouter.NotFoundHandler = http.NotFoundHandler()
 	httpRouter.Methods("GET").Path("/live").HandlerFunc(liveHandler)
 	httpRouter.Methods("GET").Path("/ready").HandlerFunc(readyHandler)
 	httpRouter.Methods("GET").Path("/metrics").HandlerFunc(metricsHandler)
 
+	if gateway != nil {
+		gateway.Register(httpRouter)
+	}
+
 	httpServer := &http.Server{
 		Addr:    addr,
 		Handler: httpRouter,
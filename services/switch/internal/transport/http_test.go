@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,9 +16,11 @@ func TestHTTPServer(t *testing.T) {
 		liveHandler               http.HandlerFunc
 		readyHandler              http.HandlerFunc
 		metricsHandler            http.HandlerFunc
+		gateway                   *Gateway
 		expectedLiveStatusCode    int
 		expectedReadyStatusCode   int
 		expectedMetricsStatusCode int
+		expectedGatewayStatusCode int
 	}{
 		{
 			"LiveNotReady",
@@ -31,9 +34,11 @@ func TestHTTPServer(t *testing.T) {
 			func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusNotFound)
 			},
+			nil,
 			http.StatusOK,
 			http.StatusAccepted,
 			http.StatusNotFound,
+			http.StatusNotFound,
 		},
 		{
 			"LiveAndReady",
@@ -47,6 +52,10 @@ func TestHTTPServer(t *testing.T) {
 			func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusOK)
 			},
+			NewGateway(nil, &mockSwitchService{
+				GetSwitchOutResp: &proto.Switch{Id: "1111"},
+			}),
+			http.StatusOK,
 			http.StatusOK,
 			http.StatusOK,
 			http.StatusOK,
@@ -55,7 +64,7 @@ func TestHTTPServer(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			httpServer := NewHTTPServer(tc.addr, tc.liveHandler, tc.readyHandler, tc.metricsHandler)
+			httpServer := NewHTTPServer(tc.addr, tc.liveHandler, tc.readyHandler, tc.metricsHandler, tc.gateway)
 			assert.NotNil(t, httpServer)
 
 			server, ok := httpServer.(*http.Server)
@@ -81,6 +90,12 @@ func TestHTTPServer(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, tc.expectedMetricsStatusCode, resp.StatusCode)
 			})
+
+			t.Run("Gateway", func(t *testing.T) {
+				resp, err := http.Get(ts.URL + "/v1/switches/1111")
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedGatewayStatusCode, resp.StatusCode)
+			})
 		})
 	}
 }
@@ -0,0 +1,201 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// legacySpanContextKey is the metadata key some clients send the trace context in, encoded as JSON
+	legacySpanContextKey = "span.context"
+)
+
+type (
+	// metadataCarrier reads and writes a trace context in gRPC metadata
+	metadataCarrier metadata.MD
+
+	// serverStream replaces the context of a stream
+	serverStream struct {
+		grpc.ServerStream
+		ctx context.Context
+	}
+
+	// Authorizer allows or denies calls and returns the context the allowed calls run with
+	Authorizer interface {
+		Authorize(ctx context.Context, method string) (context.Context, error)
+	}
+
+	// Interceptor authorizes every gRPC call and records metrics, an access log and a trace span for it
+	Interceptor struct {
+		logger     *log.Logger
+		metrics    *metrics.Metrics
+		tracer     opentracing.Tracer
+		authorizer Authorizer
+	}
+)
+
+func (c metadataCarrier) Set(key, val string) {
+	key = strings.ToLower(key)
+	c[key] = append(c[key], val)
+}
+
+func (c metadataCarrier) ForeachKey(handler func(key, val string) error) error {
+	for key, vals := range c {
+		for _, val := range vals {
+			if err := handler(key, val); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// NewInterceptor creates a new interceptor
+// authorizer decides which calls are allowed and nil allows every call.
+func NewInterceptor(logger *log.Logger, metrics *metrics.Metrics, tracer opentracing.Tracer, authorizer Authorizer) *Interceptor {
+	return &Interceptor{
+		logger:     logger,
+		metrics:    metrics,
+		tracer:     tracer,
+		authorizer: authorizer,
+	}
+}
+
+// extract returns the trace context of a call if there is one
+func (i *Interceptor) extract(md metadata.MD) opentracing.SpanContext {
+	if spanContext, err := i.tracer.Extract(opentracing.TextMap, metadataCarrier(md)); err == nil {
+		return spanContext
+	}
+
+	if vals := md.Get(legacySpanContextKey); len(vals) == 1 {
+		data := map[string]string{}
+		if err := json.Unmarshal([]byte(vals[0]), &data); err == nil {
+			if spanContext, err := i.tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier(data)); err == nil {
+				return spanContext
+			}
+		}
+	}
+
+	return nil
+}
+
+// start starts the span of a call as a child of the trace context of the call.
+// The span is added to the context and its trace context to the metadata of the calls made with the context.
+func (i *Interceptor) start(ctx context.Context, method string) (context.Context, opentracing.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	opts := []opentracing.StartSpanOption{ext.SpanKindRPCServer}
+	if parent := i.extract(md); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent))
+	}
+
+	span := i.tracer.StartSpan(method, opts...)
+	ext.Component.Set(span, "grpc")
+
+	out, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		out = out.Copy()
+	} else {
+		out = metadata.MD{}
+	}
+
+	i.tracer.Inject(span.Context(), opentracing.TextMap, metadataCarrier(out))
+	ctx = metadata.NewOutgoingContext(ctx, out)
+
+	return opentracing.ContextWithSpan(ctx, span), span
+}
+
+// authorize authorizes a call if there is an authorizer
+func (i *Interceptor) authorize(ctx context.Context, method string) (context.Context, error) {
+	if i.authorizer == nil {
+		return ctx, nil
+	}
+
+	return i.authorizer.Authorize(ctx, method)
+}
+
+// finish records the outcome of a call and finishes its span
+func (i *Interceptor) finish(span opentracing.Span, method string, start time.Time, err error) {
+	duration := time.Since(start).Seconds()
+	code := status.Code(err)
+
+	logs := []interface{}{
+		"grpc.method", method,
+		"grpc.code", code.String(),
+		"responseTime", duration,
+		"message", fmt.Sprintf("%s %s %f", method, code, duration),
+	}
+
+	// Logging
+	switch code {
+	case codes.OK:
+		i.logger.Info(logs...)
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		i.logger.Error(logs...)
+	default:
+		i.logger.Warn(logs...)
+	}
+
+	// Metrics
+	i.metrics.ReqCounter.WithLabelValues(method, code.String()).Inc()
+	i.metrics.ReqLatencyHist.WithLabelValues(method, code.String()).Observe(duration)
+
+	// Tracing
+	span.SetTag("grpc.code", code.String())
+	if code != codes.OK {
+		ext.Error.Set(span, true)
+	}
+
+	span.Finish()
+}
+
+// Unary intercepts unary calls
+func (i *Interceptor) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx, span := i.start(ctx, info.FullMethod)
+
+	ctx, err := i.authorize(ctx, info.FullMethod)
+	if err != nil {
+		i.finish(span, info.FullMethod, start, err)
+		return nil, err
+	}
+
+	resp, err := handler(ctx, req)
+	i.finish(span, info.FullMethod, start, err)
+
+	return resp, err
+}
+
+// Stream intercepts streaming calls
+func (i *Interceptor) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, span := i.start(ss.Context(), info.FullMethod)
+
+	ctx, err := i.authorize(ctx, info.FullMethod)
+	if err != nil {
+		i.finish(span, info.FullMethod, start, err)
+		return err
+	}
+
+	err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	i.finish(span, info.FullMethod, start, err)
+
+	return err
+}
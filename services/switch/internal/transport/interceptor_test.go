@@ -0,0 +1,250 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// mockServerStream is a mock implementation of grpc.ServerStream
+type mockServerStream struct {
+	grpc.ServerStream
+	ContextOutContext context.Context
+}
+
+func (m *mockServerStream) Context() context.Context {
+	return m.ContextOutContext
+}
+
+// mockAuthorizer is a mock implementation of Authorizer
+type mockAuthorizer struct {
+	AuthorizeCalled   bool
+	AuthorizeInMethod string
+	AuthorizeOutError error
+}
+
+type authorizedKey struct{}
+
+func (m *mockAuthorizer) Authorize(ctx context.Context, method string) (context.Context, error) {
+	m.AuthorizeCalled = true
+	m.AuthorizeInMethod = method
+	if m.AuthorizeOutError != nil {
+		return ctx, m.AuthorizeOutError
+	}
+	return context.WithValue(ctx, authorizedKey{}, true), nil
+}
+
+// incomingContext creates a context with the metadata of a call carrying a trace context
+func incomingContext(tracer *mocktracer.MockTracer, parent opentracing.Span, legacy bool) context.Context {
+	md := metadata.MD{}
+	if parent != nil {
+		if legacy {
+			carrier := opentracing.TextMapCarrier{}
+			tracer.Inject(parent.Context(), opentracing.TextMap, carrier)
+			data, _ := json.Marshal(carrier)
+			md.Set(legacySpanContextKey, string(data))
+		} else {
+			tracer.Inject(parent.Context(), opentracing.TextMap, metadataCarrier(md))
+		}
+	}
+
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestMetadataCarrier(t *testing.T) {
+	md := metadata.MD{}
+	carrier := metadataCarrier(md)
+	carrier.Set("Trace-ID", "1")
+	carrier.Set("trace-id", "2")
+
+	assert.Equal(t, []string{"1", "2"}, md.Get("trace-id"))
+
+	var vals []string
+	err := carrier.ForeachKey(func(key, val string) error {
+		vals = append(vals, key+"="+val)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"trace-id=1", "trace-id=2"}, vals)
+
+	err = carrier.ForeachKey(func(key, val string) error {
+		return errors.New("handler error")
+	})
+
+	assert.Error(t, err)
+}
+
+func TestInterceptorUnary(t *testing.T) {
+	tests := []struct {
+		name         string
+		parent       bool
+		legacy       bool
+		handlerError error
+		expectedCode codes.Code
+	}{
+		{"NoParent", false, false, nil, codes.OK},
+		{"Parent", true, false, nil, codes.OK},
+		{"LegacyParent", true, true, nil, codes.OK},
+		{"Error", false, false, status.Error(codes.NotFound, "switch not found"), codes.NotFound},
+		{"InternalError", true, false, errors.New("database error"), codes.Unknown},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			metrics := metrics.Mock()
+			interceptor := NewInterceptor(log.NewVoidLogger(), metrics, tracer, nil)
+
+			var parent opentracing.Span
+			if tc.parent {
+				parent = tracer.StartSpan("graphql")
+			}
+
+			ctx := incomingContext(tracer, parent, tc.legacy)
+
+			var handlerCtx context.Context
+			info := &grpc.UnaryServerInfo{FullMethod: "/proto.SwitchService/GetSwitch"}
+			resp, err := interceptor.Unary(ctx, "request", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				handlerCtx = ctx
+				return "response", tc.handlerError
+			})
+
+			assert.Equal(t, "response", resp)
+			assert.Equal(t, tc.handlerError, err)
+
+			// The span of the call is in the context and its trace context goes with outgoing calls
+			span := opentracing.SpanFromContext(handlerCtx)
+			assert.NotNil(t, span)
+			out, ok := metadata.FromOutgoingContext(handlerCtx)
+			assert.True(t, ok)
+			assert.NotEmpty(t, out.Get("mockpfx-ids-spanid"))
+
+			spans := tracer.FinishedSpans()
+			assert.Len(t, spans, 1)
+			assert.Equal(t, info.FullMethod, spans[0].OperationName)
+			assert.Equal(t, tc.expectedCode.String(), spans[0].Tag("grpc.code"))
+			if parent != nil {
+				parentContext := parent.Context().(mocktracer.MockSpanContext)
+				assert.Equal(t, parentContext.TraceID, spans[0].SpanContext.TraceID)
+				assert.Equal(t, parentContext.SpanID, spans[0].ParentID)
+			} else {
+				assert.Zero(t, spans[0].ParentID)
+			}
+			if tc.expectedCode != codes.OK {
+				assert.Equal(t, true, spans[0].Tag("error"))
+			}
+
+			assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ReqCounter.WithLabelValues(info.FullMethod, tc.expectedCode.String())))
+			assert.Equal(t, 1, testutil.CollectAndCount(metrics.ReqLatencyHist))
+		})
+	}
+}
+
+func TestInterceptorStream(t *testing.T) {
+	tests := []struct {
+		name         string
+		parent       bool
+		handlerError error
+		expectedCode codes.Code
+	}{
+		{"Success", true, nil, codes.OK},
+		{"Canceled", false, status.Error(codes.Canceled, "context canceled"), codes.Canceled},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			metrics := metrics.Mock()
+			interceptor := NewInterceptor(log.NewVoidLogger(), metrics, tracer, nil)
+
+			var parent opentracing.Span
+			if tc.parent {
+				parent = tracer.StartSpan("graphql")
+			}
+
+			stream := &mockServerStream{
+				ContextOutContext: incomingContext(tracer, parent, false),
+			}
+
+			var handlerStream grpc.ServerStream
+			info := &grpc.StreamServerInfo{FullMethod: "/proto.SwitchService/WatchSwitches", IsServerStream: true}
+			err := interceptor.Stream(nil, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+				handlerStream = ss
+				return tc.handlerError
+			})
+
+			assert.Equal(t, tc.handlerError, err)
+			assert.NotNil(t, opentracing.SpanFromContext(handlerStream.Context()))
+			_, ok := metadata.FromIncomingContext(handlerStream.Context())
+			assert.True(t, ok)
+
+			spans := tracer.FinishedSpans()
+			assert.Len(t, spans, 1)
+			assert.Equal(t, tc.expectedCode.String(), spans[0].Tag("grpc.code"))
+
+			assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ReqCounter.WithLabelValues(info.FullMethod, tc.expectedCode.String())))
+		})
+	}
+}
+
+func TestInterceptorAuthorize(t *testing.T) {
+	tests := []struct {
+		name         string
+		authorizer   *mockAuthorizer
+		expectedCode codes.Code
+	}{
+		{"Allowed", &mockAuthorizer{}, codes.OK},
+		{"Denied", &mockAuthorizer{AuthorizeOutError: status.Error(codes.PermissionDenied, "permission denied")}, codes.PermissionDenied},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			metrics := metrics.Mock()
+			interceptor := NewInterceptor(log.NewVoidLogger(), metrics, tracer, tc.authorizer)
+
+			unaryInfo := &grpc.UnaryServerInfo{FullMethod: "/proto.SwitchService/SetSwitch"}
+			unaryCalled := false
+			_, err := interceptor.Unary(incomingContext(tracer, nil, false), "request", unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+				unaryCalled = true
+				assert.Equal(t, true, ctx.Value(authorizedKey{}))
+				return "response", nil
+			})
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedCode == codes.OK, unaryCalled)
+			assert.Equal(t, unaryInfo.FullMethod, tc.authorizer.AuthorizeInMethod)
+
+			stream := &mockServerStream{
+				ContextOutContext: incomingContext(tracer, nil, false),
+			}
+			streamInfo := &grpc.StreamServerInfo{FullMethod: "/proto.SwitchService/GetSwitches", IsServerStream: true}
+			streamCalled := false
+			err = interceptor.Stream(nil, stream, streamInfo, func(srv interface{}, ss grpc.ServerStream) error {
+				streamCalled = true
+				assert.Equal(t, true, ss.Context().Value(authorizedKey{}))
+				return nil
+			})
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			assert.Equal(t, tc.expectedCode == codes.OK, streamCalled)
+
+			// Denied calls are still logged, counted and traced
+			assert.Len(t, tracer.FinishedSpans(), 2)
+			assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ReqCounter.WithLabelValues(unaryInfo.FullMethod, tc.expectedCode.String())))
+		})
+	}
+}
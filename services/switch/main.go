@@ -19,7 +19,7 @@ func main() {
 	}
 
 	logger := log.NewLogger(config.ServiceName, "singleton", config.LogLevel)
-	metrics := metrics.New(config.ServiceName)
+	metrics := metrics.New(config.ServiceName, config.MetricsTenants...)
 
 	sampler := trace.NewConstSampler()
 	reporter := trace.NewReporter(config.JaegerLogSpans, config.JaegerAgentAddr)
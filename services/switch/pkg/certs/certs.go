@@ -0,0 +1,323 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultReloadPeriod  = 10 * time.Second
+	defaultExpiryWarning = 24 * time.Hour
+)
+
+type (
+	// Store holds a certificate authority and a key pair and reloads them when their files change.
+	// TLS configurations created by a store always use what it last loaded,
+	// so new connections pick up rotated certificates without a restart.
+	Store struct {
+		caFile   string
+		certFile string
+		keyFile  string
+		logger   *log.Logger
+		expiry   *prometheus.GaugeVec
+
+		mu        sync.RWMutex
+		pool      *x509.CertPool
+		cert      *tls.Certificate
+		expiresAt map[string]time.Time
+		modTimes  []time.Time
+		checkedAt time.Time
+		warned    bool
+	}
+)
+
+// NewStore creates a new store and loads its files.
+// expiry is set to the expiry time of the certificate authority and the certificate and nil disables it.
+func NewStore(caFile, certFile, keyFile string, logger *log.Logger, expiry *prometheus.GaugeVec) (*Store, error) {
+	s := &Store{
+		caFile:   caFile,
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   logger,
+		expiry:   expiry,
+	}
+
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// stat returns the modification times of the files of the store
+func (s *Store) stat() ([]time.Time, error) {
+	modTimes := []time.Time{}
+	for _, path := range []string{s.caFile, s.certFile, s.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	return modTimes, nil
+}
+
+// load reads the certificate authority and the key pair and returns when each of them expires
+func (s *Store) load() (*x509.CertPool, *tls.Certificate, map[string]time.Time, error) {
+	ca, err := ioutil.ReadFile(s.caFile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if ok := pool.AppendCertsFromPEM(ca); !ok {
+		return nil, nil, nil, errors.New("Failed to append certificate authority")
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cert.Leaf = leaf
+
+	// The certificate authority expires with the first certificate of its chain to expire
+	var caExpiresAt time.Time
+	for rest := ca; len(rest) > 0; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if c, err := x509.ParseCertificate(block.Bytes); err == nil {
+			if caExpiresAt.IsZero() || c.NotAfter.Before(caExpiresAt) {
+				caExpiresAt = c.NotAfter
+			}
+		}
+	}
+
+	expiresAt := map[string]time.Time{
+		s.caFile:   caExpiresAt,
+		s.certFile: leaf.NotAfter,
+	}
+
+	return pool, &cert, expiresAt, nil
+}
+
+// Reload loads the files of the store if any of them changed since they were last loaded.
+// The certificate authority and the key pair are replaced together,
+// and invalid files fail and leave the current ones in place.
+func (s *Store) Reload() (bool, error) {
+	modTimes, err := s.stat()
+
+	s.mu.Lock()
+	s.checkedAt = time.Now()
+	unchanged := err == nil && s.cert != nil && equal(modTimes, s.modTimes)
+	s.mu.Unlock()
+
+	if err != nil {
+		return false, err
+	}
+
+	if unchanged {
+		return false, nil
+	}
+
+	pool, cert, expiresAt, err := s.load()
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.pool = pool
+	s.cert = cert
+	s.expiresAt = expiresAt
+	s.modTimes = modTimes
+	s.warned = false
+	s.mu.Unlock()
+
+	if s.expiry != nil {
+		for file, t := range expiresAt {
+			s.expiry.WithLabelValues(file).Set(float64(t.Unix()))
+		}
+	}
+
+	return true, nil
+}
+
+// refresh reloads the files of the store if they were not checked during the last reload period.
+// It keeps the key pair of clients that do not watch the store up-to-date.
+func (s *Store) refresh() {
+	s.mu.RLock()
+	stale := time.Since(s.checkedAt) >= defaultReloadPeriod
+	s.mu.RUnlock()
+
+	if !stale {
+		return
+	}
+
+	if _, err := s.Reload(); err != nil {
+		s.logger.Error("message", fmt.Sprintf("Failed to reload certificates: %s", err))
+	}
+}
+
+// checkExpiry logs a warning, once per reload, for the certificates expiring within the given duration
+func (s *Store) checkExpiry(within time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.warned {
+		return
+	}
+
+	for file, t := range s.expiresAt {
+		if left := time.Until(t); left < within {
+			s.warned = true
+			s.logger.Warn(
+				"file", file,
+				"expiresAt", t.UTC().Format(time.RFC3339),
+				"message", fmt.Sprintf("Certificate %s expires in %s.", file, left.Round(time.Second)),
+			)
+		}
+	}
+}
+
+// Watch reloads the files of the store every period until the context is done
+// and logs a warning when a certificate expires within the given duration.
+func (s *Store) Watch(ctx context.Context, period, expiryWarning time.Duration) {
+	if period <= 0 {
+		period = defaultReloadPeriod
+	}
+
+	if expiryWarning <= 0 {
+		expiryWarning = defaultExpiryWarning
+	}
+
+	s.checkExpiry(expiryWarning)
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := s.Reload()
+		if err != nil {
+			s.logger.Error("message", fmt.Sprintf("Failed to reload certificates: %s", err))
+		} else if reloaded {
+			s.logger.Info("message", "Certificates reloaded.")
+		}
+
+		s.checkExpiry(expiryWarning)
+	}
+}
+
+// Pool returns the certificate authority last loaded
+func (s *Store) Pool() *x509.CertPool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.pool
+}
+
+// Certificate returns the key pair last loaded
+func (s *Store) Certificate() *tls.Certificate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.cert
+}
+
+// verify returns a hook verifying peer certificates against the certificate authority last loaded
+func (s *Store) verify(usage x509.ExtKeyUsage, dnsName string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("no peer certificate")
+		}
+
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = cert
+		}
+
+		opts := x509.VerifyOptions{
+			Roots:         s.Pool(),
+			Intermediates: x509.NewCertPool(),
+			DNSName:       dnsName,
+			KeyUsages:     []x509.ExtKeyUsage{usage},
+		}
+
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+
+		_, err := certs[0].Verify(opts)
+		return err
+	}
+}
+
+// ServerConfig creates a TLS configuration for servers requiring client certificates issued by the certificate authority
+func (s *Store) ServerConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			s.refresh()
+			return s.Certificate(), nil
+		},
+		// Client certificates are verified by VerifyPeerCertificate against the current certificate authority
+		ClientAuth:            tls.RequireAnyClientCert,
+		VerifyPeerCertificate: s.verify(x509.ExtKeyUsageClientAuth, ""),
+	}
+}
+
+// ClientConfig creates a TLS configuration for clients presenting their certificate to a server issued by the certificate authority
+func (s *Store) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			s.refresh()
+			return s.Certificate(), nil
+		},
+		// The server certificate is verified by VerifyPeerCertificate against the current certificate authority
+		InsecureSkipVerify:    true, // nolint: gosec
+		VerifyPeerCertificate: s.verify(x509.ExtKeyUsageServerAuth, serverName),
+	}
+}
+
+func equal(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
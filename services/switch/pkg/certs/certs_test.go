@@ -0,0 +1,367 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/internal/metrics"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	// authority is a certificate authority issuing certificates for testing purposes
+	authority struct {
+		cert *x509.Certificate
+		key  *ecdsa.PrivateKey
+		pem  []byte
+	}
+
+	// files are the files of a store
+	files struct {
+		ca   string
+		cert string
+		key  string
+	}
+
+	// mockLogger records the messages logged
+	mockLogger struct {
+		mu       sync.Mutex
+		messages []string
+	}
+)
+
+func (m *mockLogger) Log(kv ...interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i] == "message" {
+			m.messages = append(m.messages, kv[i+1].(string))
+		}
+	}
+	return nil
+}
+
+func (m *mockLogger) count(prefix string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, message := range m.messages {
+		if strings.HasPrefix(message, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	return key
+}
+
+func encode(t *testing.T, blockType string, bytes []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: bytes})
+}
+
+func newAuthority(t *testing.T, name string) *authority {
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return &authority{
+		cert: cert,
+		key:  key,
+		pem:  encode(t, "CERTIFICATE", der),
+	}
+}
+
+// issue creates a certificate and its key for a name and returns them in PEM
+func (a *authority) issue(t *testing.T, name string, usage x509.ExtKeyUsage, ttl time.Duration) ([]byte, []byte) {
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	assert.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	return encode(t, "CERTIFICATE", der), encode(t, "EC PRIVATE KEY", keyDER)
+}
+
+// write writes the files of a store with a modification time
+func (f files) write(t *testing.T, ca, cert, key []byte, modTime time.Time) {
+	for path, data := range map[string][]byte{f.ca: ca, f.cert: cert, f.key: key} {
+		assert.NoError(t, ioutil.WriteFile(path, data, 0600))
+		assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+}
+
+func newFiles(dir, name string) files {
+	return files{
+		ca:   filepath.Join(dir, name+".ca.cert"),
+		cert: filepath.Join(dir, name+".cert"),
+		key:  filepath.Join(dir, name+".key"),
+	}
+}
+
+// handshake connects a client to a server and returns the common name of the server certificate
+func handshake(serverConfig, clientConfig *tls.Config) (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer conn.Close()
+
+		errs <- tls.Server(conn, serverConfig).Handshake()
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		return "", err
+	}
+
+	client := tls.Client(conn, clientConfig)
+	err = client.Handshake()
+	conn.Close()
+
+	if serverErr := <-errs; err == nil {
+		err = serverErr
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return client.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+}
+
+func TestNewStore(t *testing.T) {
+	tests := []struct {
+		name          string
+		caFile        string
+		certFile      string
+		keyFile       string
+		expectedError bool
+	}{
+		{"NoCACert", "ca.chain", "server.cert", "server.key", true},
+		{"InvalidCACert", "server.key", "server.cert", "server.key", true},
+		{"NoCert", "ca.chain.cert", "server", "server.key", true},
+		{"NoKey", "ca.chain.cert", "server.cert", "server", true},
+		{"KeyMismatch", "ca.chain.cert", "server.cert", "client.key", true},
+		{"Success", "ca.chain.cert", "server.cert", "server.key", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics := metrics.Mock()
+			store, err := NewStore(tc.caFile, tc.certFile, tc.keyFile, log.NewVoidLogger(), metrics.CertExpiry)
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				assert.Nil(t, store)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, store)
+				assert.NotNil(t, store.Pool())
+				assert.Equal(t, "Server", store.Certificate().Leaf.Subject.CommonName)
+				assert.Equal(t, float64(store.Certificate().Leaf.NotAfter.Unix()), testutil.ToFloat64(metrics.CertExpiry.WithLabelValues(tc.certFile)))
+				assert.NotZero(t, testutil.ToFloat64(metrics.CertExpiry.WithLabelValues(tc.caFile)))
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newAuthority(t, "ca")
+	f := newFiles(dir, "server")
+	modTime := time.Now().Add(-time.Hour)
+
+	cert, key := ca.issue(t, "server", x509.ExtKeyUsageServerAuth, time.Hour)
+	f.write(t, ca.pem, cert, key, modTime)
+
+	store, err := NewStore(f.ca, f.cert, f.key, log.NewVoidLogger(), nil)
+	assert.NoError(t, err)
+	first := store.Certificate()
+
+	// Unchanged
+	reloaded, err := store.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// Invalid files are not loaded
+	modTime = modTime.Add(time.Minute)
+	newCert, _ := ca.issue(t, "server", x509.ExtKeyUsageServerAuth, time.Hour)
+	f.write(t, ca.pem, newCert, key, modTime)
+	reloaded, err = store.Reload()
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+	assert.Equal(t, first, store.Certificate())
+
+	// Missing files are not loaded
+	assert.NoError(t, os.Rename(f.key, f.key+".old"))
+	reloaded, err = store.Reload()
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+	assert.Equal(t, first, store.Certificate())
+
+	// Rotated
+	modTime = modTime.Add(time.Minute)
+	newCert, newKey := ca.issue(t, "server", x509.ExtKeyUsageServerAuth, time.Hour)
+	f.write(t, ca.pem, newCert, newKey, modTime)
+	reloaded, err = store.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.NotEqual(t, first, store.Certificate())
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newAuthority(t, "ca")
+	f := newFiles(dir, "server")
+	modTime := time.Now().Add(-time.Hour)
+
+	cert, key := ca.issue(t, "server", x509.ExtKeyUsageServerAuth, time.Hour)
+	f.write(t, ca.pem, cert, key, modTime)
+
+	logger := &mockLogger{}
+	metrics := metrics.Mock()
+	store, err := NewStore(f.ca, f.cert, f.key, &log.Logger{Logger: logger}, metrics.CertExpiry)
+	assert.NoError(t, err)
+
+	// Rotated to a certificate expiring later
+	newCert, newKey := ca.issue(t, "server", x509.ExtKeyUsageServerAuth, 2*time.Hour)
+	f.write(t, ca.pem, newCert, newKey, modTime.Add(time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	store.Watch(ctx, 10*time.Millisecond, 3*time.Hour)
+
+	assert.Equal(t, 1, logger.count("Certificates reloaded."))
+	assert.Equal(t, 2, logger.count("Certificate "+f.cert+" expires in"))
+	assert.Equal(t, float64(store.Certificate().Leaf.NotAfter.Unix()), testutil.ToFloat64(metrics.CertExpiry.WithLabelValues(f.cert)))
+	assert.Equal(t, float64(ca.cert.NotAfter.Unix()), testutil.ToFloat64(metrics.CertExpiry.WithLabelValues(f.ca)))
+}
+
+func TestHandshake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newAuthority(t, "ca")
+	other := newAuthority(t, "other")
+	serverFiles := newFiles(dir, "server")
+	clientFiles := newFiles(dir, "client")
+	modTime := time.Now().Add(-time.Hour)
+
+	cert, key := ca.issue(t, "server", x509.ExtKeyUsageServerAuth, time.Hour)
+	serverFiles.write(t, ca.pem, cert, key, modTime)
+
+	cert, key = ca.issue(t, "client", x509.ExtKeyUsageClientAuth, time.Hour)
+	clientFiles.write(t, ca.pem, cert, key, modTime)
+
+	server, err := NewStore(serverFiles.ca, serverFiles.cert, serverFiles.key, log.NewVoidLogger(), nil)
+	assert.NoError(t, err)
+
+	client, err := NewStore(clientFiles.ca, clientFiles.cert, clientFiles.key, log.NewVoidLogger(), nil)
+	assert.NoError(t, err)
+
+	serverConfig := server.ServerConfig()
+
+	t.Run("Verified", func(t *testing.T) {
+		name, err := handshake(serverConfig, client.ClientConfig("server"))
+		assert.NoError(t, err)
+		assert.Equal(t, "server", name)
+	})
+
+	t.Run("WrongServerName", func(t *testing.T) {
+		_, err := handshake(serverConfig, client.ClientConfig("other"))
+		assert.Error(t, err)
+	})
+
+	t.Run("NoClientCertificate", func(t *testing.T) {
+		_, err := handshake(serverConfig, &tls.Config{ServerName: "server", RootCAs: server.Pool()})
+		assert.Error(t, err)
+	})
+
+	// The server and the client certificates are rotated to another certificate authority
+	modTime = modTime.Add(time.Minute)
+	cert, key = other.issue(t, "server.v2", x509.ExtKeyUsageServerAuth, time.Hour)
+	serverFiles.write(t, other.pem, cert, key, modTime)
+
+	t.Run("UnknownAuthority", func(t *testing.T) {
+		_, err := server.Reload()
+		assert.NoError(t, err)
+
+		_, err = handshake(serverConfig, client.ClientConfig("server.v2"))
+		assert.Error(t, err)
+	})
+
+	cert, key = other.issue(t, "client", x509.ExtKeyUsageClientAuth, time.Hour)
+	clientFiles.write(t, other.pem, cert, key, modTime)
+
+	t.Run("Rotated", func(t *testing.T) {
+		_, err := client.Reload()
+		assert.NoError(t, err)
+
+		name, err := handshake(serverConfig, client.ClientConfig("server.v2"))
+		assert.NoError(t, err)
+		assert.Equal(t, "server.v2", name)
+	})
+}
@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/certs"
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	defaultTimeout          = 10 * time.Second
+	defaultMaxAttempts      = 3
+	defaultKeepaliveTime    = 30 * time.Second
+	defaultKeepaliveTimeout = 10 * time.Second
+
+	// serviceName is the full name of the switch service
+	serviceName = "proto.SwitchService"
+
+	// retryEnv is the environment variable that enables retries in grpc-go before 1.40
+	retryEnv = "GRPC_GO_RETRY"
+)
+
+var (
+	// ErrRetryDisabled is returned when a client is configured to retry calls but retries are not enabled in grpc-go
+	ErrRetryDisabled = errors.New("GRPC_GO_RETRY=on is required to retry calls, otherwise MaxAttempts must be 1")
+
+	// idempotentMethods are the methods that can be retried without changing the outcome of a call
+	idempotentMethods = []string{
+		"GetSwitch",
+		"GetSwitches",
+		"GetAllowedTransitions",
+		"GetSwitchHistory",
+		"GetSchedule",
+		"GetSchedules",
+		"GetGroup",
+		"GetGroups",
+		"GetScene",
+		"GetScenes",
+	}
+)
+
+type (
+	// Config configures a switch client
+	Config struct {
+		// Addr is the address of the switch service.
+		// An address without a scheme is resolved by DNS and calls are balanced across all of its addresses.
+		Addr string
+		// CAChainFile, ClientCertFile and ClientKeyFile enable MTLS and are reloaded when they change
+		CAChainFile    string
+		ClientCertFile string
+		ClientKeyFile  string
+		// CertReload is how often the certificates are checked for changes
+		CertReload time.Duration
+		// CertExpiryWarn is how long before a certificate expires a warning is logged
+		CertExpiryWarn time.Duration
+		// ServerName is the name the server certificate is verified against
+		ServerName string
+		// Timeout is the deadline of unary calls made without one
+		Timeout time.Duration
+		// MaxAttempts is how many times idempotent calls are tried while the switch service is unavailable (at most 5).
+		// More than one attempt requires GRPC_GO_RETRY=on in the environment of the process when it starts.
+		MaxAttempts int
+		// KeepaliveTime is how long a connection stays idle before it is checked with a ping
+		KeepaliveTime time.Duration
+		// KeepaliveTimeout is how long a ping waits for an answer before the connection is closed
+		KeepaliveTimeout time.Duration
+		// Tracer propagates the trace of calls to the switch service and nil disables it
+		Tracer opentracing.Tracer
+		// Registerer registers the metrics of calls and the expiry of the certificates and nil disables them
+		Registerer prometheus.Registerer
+		// Logger logs the certificate reloads, reload failures and expiry warnings and nil disables them
+		Logger *log.Logger
+	}
+)
+
+// WithTenant returns a context that carries the tenant id to the switch service
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "tenant-id", tenantID)
+}
+
+// retryEnabled determines whether grpc-go applies the retry policy of service configs,
+// which before grpc-go 1.40 requires the GRPC_GO_RETRY environment variable to be on
+func retryEnabled() bool {
+	return strings.EqualFold(os.Getenv(retryEnv), "on")
+}
+
+// serviceConfig returns the gRPC service config of the switch service.
+// It balances calls across every address of the service and retries idempotent calls when the service is unavailable.
+func serviceConfig(maxAttempts int) string {
+	methodConfig := []map[string]interface{}{}
+
+	if maxAttempts > 1 {
+		names := []map[string]string{}
+		for _, method := range idempotentMethods {
+			names = append(names, map[string]string{
+				"service": serviceName,
+				"method":  method,
+			})
+		}
+
+		methodConfig = append(methodConfig, map[string]interface{}{
+			"name": names,
+			"retryPolicy": map[string]interface{}{
+				"maxAttempts":          maxAttempts,
+				"initialBackoff":       "0.1s",
+				"maxBackoff":           "1s",
+				"backoffMultiplier":    2,
+				"retryableStatusCodes": []string{"UNAVAILABLE"},
+			},
+		})
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"loadBalancingPolicy": "round_robin",
+		"methodConfig":        methodConfig,
+	})
+
+	return string(data)
+}
+
+// target returns the target an address is dialed with
+func target(addr string) string {
+	if strings.Contains(addr, "://") {
+		return addr
+	}
+	return "dns:///" + addr
+}
+
+// New creates a new client.
+// It returns ErrRetryDisabled if calls are retried but GRPC_GO_RETRY is not on, rather than silently never retrying them.
+func New(config Config) (proto.SwitchServiceClient, *grpc.ClientConn, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+
+	if config.MaxAttempts > 1 && !retryEnabled() {
+		return nil, nil, ErrRetryDisabled
+	}
+
+	if config.KeepaliveTime <= 0 {
+		config.KeepaliveTime = defaultKeepaliveTime
+	}
+
+	if config.KeepaliveTimeout <= 0 {
+		config.KeepaliveTimeout = defaultKeepaliveTimeout
+	}
+
+	interceptor := newInterceptor(config.Timeout, config.Tracer, config.Registerer)
+
+	options := []grpc.DialOption{
+		grpc.WithDefaultServiceConfig(serviceConfig(config.MaxAttempts)),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                config.KeepaliveTime,
+			Timeout:             config.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithChainUnaryInterceptor(interceptor.Unary),
+		grpc.WithChainStreamInterceptor(interceptor.Stream),
+	}
+
+	// Configure MTLS
+	var store *certs.Store
+	if config.CAChainFile != "" && config.ClientCertFile != "" && config.ClientKeyFile != "" {
+		logger := config.Logger
+		if logger == nil {
+			logger = log.NewVoidLogger()
+		}
+
+		var expiry *prometheus.GaugeVec
+		if config.Registerer != nil {
+			// expiry is a gauge tracking when the certificates of the client expire
+			expiry = register(config.Registerer, prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Name: "grpc_client_certificate_expiry_timestamp_seconds",
+					Help: "expiry time of the certificates of the switch client in seconds since epoch",
+				},
+				[]string{"file"},
+			)).(*prometheus.GaugeVec)
+		}
+
+		var err error
+		store, err = certs.NewStore(config.CAChainFile, config.ClientCertFile, config.ClientKeyFile, logger, expiry)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		creds := credentials.NewTLS(store.ClientConfig(config.ServerName))
+		options = append(options, grpc.WithTransportCredentials(creds))
+	} else {
+		options = append(options, grpc.WithInsecure())
+	}
+
+	conn, err := grpc.Dial(target(config.Addr), options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if store != nil {
+		go watch(conn, store, config.CertReload, config.CertExpiryWarn)
+	}
+
+	client := proto.NewSwitchServiceClient(conn)
+
+	return client, conn, nil
+}
+
+// watch reloads the certificates of a store and warns about their expiry until a connection is closed
+func watch(conn *grpc.ClientConn, store *certs.Store, period, expiryWarn time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go store.Watch(ctx, period, expiryWarn)
+
+	for state := conn.GetState(); state != connectivity.Shutdown; state = conn.GetState() {
+		conn.WaitForStateChange(context.Background(), state)
+	}
+}
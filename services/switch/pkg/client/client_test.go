@@ -0,0 +1,269 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// TestMain runs the tests in a new process with retries enabled,
+// since grpc-go only reads GRPC_GO_RETRY when the process starts
+func TestMain(m *testing.M) {
+	if retryEnabled() {
+		os.Exit(m.Run())
+	}
+
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(), retryEnv+"=on")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+		os.Exit(1)
+	}
+}
+
+func TestRetryEnabled(t *testing.T) {
+	assert.True(t, retryEnabled())
+
+	os.Setenv(retryEnv, "off")
+	defer os.Setenv(retryEnv, "on")
+
+	assert.False(t, retryEnabled())
+
+	// Without retries in grpc-go, a client is only created if it does not retry
+	_, _, err := New(Config{Addr: "localhost:9999"})
+	assert.Equal(t, ErrRetryDisabled, err)
+
+	_, conn, err := New(Config{Addr: "localhost:9999", MaxAttempts: 1})
+	assert.NoError(t, err)
+	conn.Close()
+}
+
+func TestServiceConfig(t *testing.T) {
+	tests := []struct {
+		name                string
+		maxAttempts         int
+		expectedRetryPolicy bool
+	}{
+		{"NoRetry", 1, false},
+		{"Retry", 3, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config := struct {
+				LoadBalancingPolicy string `json:"loadBalancingPolicy"`
+				MethodConfig        []struct {
+					Name []struct {
+						Service string `json:"service"`
+						Method  string `json:"method"`
+					} `json:"name"`
+					RetryPolicy struct {
+						MaxAttempts int `json:"maxAttempts"`
+					} `json:"retryPolicy"`
+				} `json:"methodConfig"`
+			}{}
+
+			err := json.Unmarshal([]byte(serviceConfig(tc.maxAttempts)), &config)
+			assert.NoError(t, err)
+			assert.Equal(t, "round_robin", config.LoadBalancingPolicy)
+
+			if !tc.expectedRetryPolicy {
+				assert.Empty(t, config.MethodConfig)
+			} else {
+				assert.Len(t, config.MethodConfig, 1)
+				assert.Len(t, config.MethodConfig[0].Name, len(idempotentMethods))
+				assert.Equal(t, serviceName, config.MethodConfig[0].Name[0].Service)
+				assert.Equal(t, tc.maxAttempts, config.MethodConfig[0].RetryPolicy.MaxAttempts)
+			}
+		})
+	}
+}
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		addr           string
+		expectedTarget string
+	}{
+		{"switch-service:4030", "dns:///switch-service:4030"},
+		{"dns:///switch-service:4030", "dns:///switch-service:4030"},
+		{"passthrough:///localhost:4030", "passthrough:///localhost:4030"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.addr, func(t *testing.T) {
+			assert.Equal(t, tc.expectedTarget, target(tc.addr))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		expectError bool
+	}{
+		{
+			name: "MTLSDisabled",
+			config: Config{
+				Addr: "localhost:9999",
+			},
+			expectError: false,
+		},
+		{
+			name: "MTLSEnabled",
+			config: Config{
+				Addr:           "localhost:9999",
+				ServerName:     "server",
+				CAChainFile:    "../certs/ca.chain.cert",
+				ClientCertFile: "../certs/client.cert",
+				ClientKeyFile:  "../certs/client.key",
+			},
+			expectError: false,
+		},
+		{
+			name: "NoClientCert",
+			config: Config{
+				Addr:           "localhost:9999",
+				ServerName:     "server",
+				CAChainFile:    "../certs/ca.chain.cert",
+				ClientCertFile: "../certs/client",
+				ClientKeyFile:  "../certs/client.key",
+			},
+			expectError: true,
+		},
+		{
+			name: "NoRetry",
+			config: Config{
+				Addr:        "localhost:9999",
+				MaxAttempts: 1,
+			},
+			expectError: false,
+		},
+		{
+			name: "Metrics",
+			config: Config{
+				Addr:       "localhost:9999",
+				Registerer: prometheus.NewRegistry(),
+			},
+			expectError: false,
+		},
+		{
+			name: "MTLSWithMetricsAndLogger",
+			config: Config{
+				Addr:           "localhost:9999",
+				ServerName:     "server",
+				CAChainFile:    "../certs/ca.chain.cert",
+				ClientCertFile: "../certs/client.cert",
+				ClientKeyFile:  "../certs/client.key",
+				Registerer:     prometheus.NewRegistry(),
+				Logger:         log.NewVoidLogger(),
+			},
+			expectError: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, conn, err := New(tc.config)
+
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, client)
+				assert.Nil(t, conn)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, client)
+				assert.NotNil(t, conn)
+				conn.Close()
+			}
+		})
+	}
+}
+
+func TestWithTenant(t *testing.T) {
+	tests := []struct {
+		name     string
+		tenantID string
+	}{
+		{
+			name:     "Default",
+			tenantID: "tttt-tttt",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := WithTenant(context.Background(), tc.tenantID)
+			meta, ok := metadata.FromOutgoingContext(ctx)
+
+			assert.True(t, ok)
+			assert.Equal(t, []string{tc.tenantID}, meta.Get("tenant-id"))
+		})
+	}
+}
+
+func TestNewCertExpiry(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	logs := make(chan string, 10)
+	logger := &log.Logger{Logger: kitlog.LoggerFunc(func(kv ...interface{}) error {
+		select {
+		case logs <- fmt.Sprint(kv...):
+		default:
+		}
+		return nil
+	})}
+
+	_, conn, err := New(Config{
+		Addr:           "localhost:9999",
+		ServerName:     "server",
+		CAChainFile:    "../certs/ca.chain.cert",
+		ClientCertFile: "../certs/client.cert",
+		ClientKeyFile:  "../certs/client.key",
+		// Every certificate expires within the warning, so a warning is logged as soon as the store is watched
+		CertExpiryWarn: 100 * 365 * 24 * time.Hour,
+		Registerer:     registry,
+		Logger:         logger,
+	})
+
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+
+	files := map[string]bool{}
+	for _, family := range families {
+		if family.GetName() == "grpc_client_certificate_expiry_timestamp_seconds" {
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					files[label.GetValue()] = metric.GetGauge().GetValue() > 0
+				}
+			}
+		}
+	}
+	assert.Equal(t, map[string]bool{"../certs/ca.chain.cert": true, "../certs/client.cert": true}, files)
+
+	select {
+	case line := <-logs:
+		assert.Contains(t, line, "expires in")
+	case <-time.After(time.Second):
+		t.Error("no expiry warning logged")
+	}
+}
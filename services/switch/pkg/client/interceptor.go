@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type (
+	// metadataCarrier writes a trace context in gRPC metadata
+	metadataCarrier metadata.MD
+
+	// interceptor sets the default deadline of calls and records metrics and a trace span for them
+	interceptor struct {
+		timeout        time.Duration
+		tracer         opentracing.Tracer
+		reqCounter     *prometheus.CounterVec
+		reqLatencyHist *prometheus.HistogramVec
+	}
+
+	// clientStream finishes a call when its stream ends
+	clientStream struct {
+		grpc.ClientStream
+		desc   *grpc.StreamDesc
+		once   sync.Once
+		finish func(error)
+	}
+)
+
+func (c metadataCarrier) Set(key, val string) {
+	key = strings.ToLower(key)
+	c[key] = append(c[key], val)
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+
+	// Streams from the server end with io.EOF and streams to the server with their only response
+	if err == io.EOF {
+		s.once.Do(func() { s.finish(nil) })
+	} else if err != nil || !s.desc.ServerStreams {
+		s.once.Do(func() { s.finish(err) })
+	}
+
+	return err
+}
+
+// register registers a collector or returns the same collector already registered by another client
+func register(registerer prometheus.Registerer, collector prometheus.Collector) prometheus.Collector {
+	if err := registerer.Register(collector); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+	}
+
+	return collector
+}
+
+// newInterceptor creates a new interceptor
+func newInterceptor(timeout time.Duration, tracer opentracing.Tracer, registerer prometheus.Registerer) *interceptor {
+	i := &interceptor{
+		timeout: timeout,
+		tracer:  tracer,
+	}
+
+	if registerer != nil {
+		// reqCounter is a counter tracking the total number of calls to the switch service
+		i.reqCounter = register(registerer, prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "grpc_client_requests_total",
+				Help: "total number of grpc calls to the switch service",
+			},
+			[]string{"method", "code"},
+		)).(*prometheus.CounterVec)
+
+		// reqLatencyHist is a histogram tracking the response times of calls to the switch service
+		i.reqLatencyHist = register(registerer, prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "grpc_client_request_duration_seconds",
+				Help:    "latency of grpc calls to the switch service",
+				Buckets: []float64{0.01, 0.10, 0.50, 1.00},
+			},
+			[]string{"method", "code"},
+		)).(*prometheus.HistogramVec)
+	}
+
+	return i
+}
+
+// start starts the span of a call as a child of the span in the context and sends its trace context in the metadata of the call
+func (i *interceptor) start(ctx context.Context, method string) (context.Context, opentracing.Span) {
+	if i.tracer == nil {
+		return ctx, nil
+	}
+
+	opts := []opentracing.StartSpanOption{ext.SpanKindRPCClient}
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+
+	span := i.tracer.StartSpan(method, opts...)
+	ext.Component.Set(span, "grpc")
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+
+	_ = i.tracer.Inject(span.Context(), opentracing.TextMap, metadataCarrier(md))
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	return ctx, span
+}
+
+// finish records the metrics of a call and finishes its span
+func (i *interceptor) finish(span opentracing.Span, method string, start time.Time, err error) {
+	code := status.Code(err).String()
+
+	if i.reqCounter != nil {
+		i.reqCounter.WithLabelValues(method, code).Inc()
+		i.reqLatencyHist.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+	}
+
+	if span != nil {
+		span.SetTag("grpc.code", code)
+		if err != nil {
+			ext.Error.Set(span, true)
+		}
+		span.Finish()
+	}
+}
+
+// Unary is the interceptor of unary calls.
+// Calls without a deadline get the default one.
+func (i *interceptor) Unary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if _, ok := ctx.Deadline(); !ok && i.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, i.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	ctx, span := i.start(ctx, method)
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	i.finish(span, method, start, err)
+
+	return err
+}
+
+// Stream is the interceptor of streaming calls.
+// Streams such as WatchSwitches can last as long as their context, so they do not get a default deadline.
+// A call is finished when its stream ends, so streams should be read until they end or their context should be canceled.
+func (i *interceptor) Stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	ctx, span := i.start(ctx, method)
+
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		i.finish(span, method, start, err)
+		return nil, err
+	}
+
+	return &clientStream{
+		ClientStream: stream,
+		desc:         desc,
+		finish: func(err error) {
+			i.finish(span, method, start, err)
+		},
+	}, nil
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// mockServer serves every method of the switch service the same way and records the calls it receives
+type mockServer struct {
+	mu       sync.Mutex
+	methods  []string
+	deadline []bool
+	traced   []bool
+
+	// Switches are sent in response to every call
+	Switches []*proto.Switch
+	// Error fails every call
+	Error error
+}
+
+func (m *mockServer) handle(srv interface{}, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	_, hasDeadline := stream.Context().Deadline()
+	md, _ := metadata.FromIncomingContext(stream.Context())
+
+	traced := false
+	for key := range md {
+		if strings.HasPrefix(key, "mockpfx-") {
+			traced = true
+		}
+	}
+
+	m.mu.Lock()
+	m.methods = append(m.methods, method)
+	m.deadline = append(m.deadline, hasDeadline)
+	m.traced = append(m.traced, traced)
+	m.mu.Unlock()
+
+	if err := stream.RecvMsg(&proto.GetSwitchRequest{}); err != nil {
+		return err
+	}
+
+	if m.Error != nil {
+		return m.Error
+	}
+
+	for _, s := range m.Switches {
+		if err := stream.SendMsg(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *mockServer) calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.methods)
+}
+
+// startServer starts a grpc server for a mock server and returns its address
+func startServer(t *testing.T, m *mockServer) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := grpc.NewServer(grpc.UnknownServiceHandler(m.handle))
+	go server.Serve(listener)
+
+	return listener.Addr().String(), server.Stop
+}
+
+func TestInterceptorUnary(t *testing.T) {
+	server := &mockServer{
+		Switches: []*proto.Switch{{Id: "1111-aaaa", SiteId: "1001", Name: "Light"}},
+	}
+
+	addr, stop := startServer(t, server)
+	defer stop()
+
+	tracer := mocktracer.New()
+	registry := prometheus.NewRegistry()
+	client, conn, err := New(Config{
+		Addr:       addr,
+		Tracer:     tracer,
+		Registerer: registry,
+	})
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// The metrics of clients sharing a registry are shared too
+	_, other, err := New(Config{Addr: addr, Registerer: registry})
+	assert.NoError(t, err)
+	other.Close()
+
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	sw, err := client.GetSwitch(ctx, &proto.GetSwitchRequest{Id: "1111-aaaa"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Light", sw.Name)
+
+	// A deadline of the caller is kept
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, err = client.GetSwitch(ctx, &proto.GetSwitchRequest{Id: "1111-aaaa"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"/proto.SwitchService/GetSwitch", "/proto.SwitchService/GetSwitch"}, server.methods)
+	assert.Equal(t, []bool{true, true}, server.deadline)
+	assert.Equal(t, []bool{true, true}, server.traced)
+
+	spans := tracer.FinishedSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "/proto.SwitchService/GetSwitch", spans[0].OperationName)
+	assert.Equal(t, parent.Context().(mocktracer.MockSpanContext).SpanID, spans[0].ParentID)
+	assert.Equal(t, "OK", spans[0].Tag("grpc.code"))
+
+	expected := `
+		# HELP grpc_client_requests_total total number of grpc calls to the switch service
+		# TYPE grpc_client_requests_total counter
+		grpc_client_requests_total{code="OK",method="/proto.SwitchService/GetSwitch"} 2
+	`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "grpc_client_requests_total"))
+}
+
+func TestInterceptorUnavailable(t *testing.T) {
+	server := &mockServer{
+		Error: status.Error(codes.Unavailable, "shutting down"),
+	}
+
+	addr, stop := startServer(t, server)
+	defer stop()
+
+	registry := prometheus.NewRegistry()
+	client, conn, err := New(Config{
+		Addr:        addr,
+		MaxAttempts: 3,
+		Registerer:  registry,
+	})
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = client.GetSwitch(context.Background(), &proto.GetSwitchRequest{Id: "1111-aaaa"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	_, err = client.SetSwitch(context.Background(), &proto.SetSwitchRequest{Id: "1111-aaaa", State: "ON"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// The idempotent call is tried three times and the other one only once
+	assert.Equal(t, 4, server.calls())
+	assert.Equal(t, []string{
+		"/proto.SwitchService/GetSwitch",
+		"/proto.SwitchService/GetSwitch",
+		"/proto.SwitchService/GetSwitch",
+		"/proto.SwitchService/SetSwitch",
+	}, server.methods)
+}
+
+func TestInterceptorStream(t *testing.T) {
+	server := &mockServer{
+		Switches: []*proto.Switch{
+			{Id: "1111-aaaa", SiteId: "1001", Name: "Light"},
+			{Id: "2222-bbbb", SiteId: "1001", Name: "Fan"},
+		},
+	}
+
+	addr, stop := startServer(t, server)
+	defer stop()
+
+	tracer := mocktracer.New()
+	client, conn, err := New(Config{
+		Addr:   addr,
+		Tracer: tracer,
+	})
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := client.GetSwitches(context.Background(), &proto.GetSwitchesRequest{SiteId: "1001"})
+	assert.NoError(t, err)
+
+	names := []string{}
+	for {
+		sw, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, sw.Name)
+	}
+
+	assert.Equal(t, []string{"Light", "Fan"}, names)
+	assert.Equal(t, []bool{false}, server.deadline)
+	assert.Equal(t, []bool{true}, server.traced)
+
+	spans := tracer.FinishedSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "/proto.SwitchService/GetSwitches", spans[0].OperationName)
+	assert.Equal(t, "OK", spans[0].Tag("grpc.code"))
+}
@@ -0,0 +1,56 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+)
+
+// The NATS subjects the switch service publishes its events to
+const (
+	SubjectInstalled    = "switches.installed"
+	SubjectRemoved      = "switches.removed"
+	SubjectStateChanged = "switches.state_changed"
+)
+
+type (
+	// Message is the JSON body of a switch event published to NATS
+	// Events are delivered at least once, so consumers should ignore the ids they already processed.
+	Message struct {
+		ID   string `json:"id"`
+		Kind string `json:"kind"`
+		// Span is the trace context of the call that made the change as a JSON OpenTracing text map
+		Span     string `json:"span,omitempty"`
+		TenantID string `json:"tenantId"`
+		// Time is the Unix time in nanoseconds of the change
+		Time int64 `json:"time"`
+		// Switch is the switch after the change, or before its removal, in the JSON encoding of proto.Switch
+		Switch json.RawMessage `json:"switch"`
+		// PreviousState is only set for state changes
+		PreviousState string `json:"previousState,omitempty"`
+	}
+)
+
+// EncodeSwitch sets the switch of a message
+func (m *Message) EncodeSwitch(sw *proto.Switch) error {
+	var buf bytes.Buffer
+	if err := new(jsonpb.Marshaler).Marshal(&buf, sw); err != nil {
+		return err
+	}
+
+	m.Switch = buf.Bytes()
+	return nil
+}
+
+// DecodeSwitch returns the switch of a message
+func (m *Message) DecodeSwitch() (*proto.Switch, error) {
+	sw := new(proto.Switch)
+	u := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err := u.Unmarshal(bytes.NewReader(m.Switch), sw); err != nil {
+		return nil, err
+	}
+
+	return sw, nil
+}
@@ -0,0 +1,34 @@
+package event
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage(t *testing.T) {
+	sw := &proto.Switch{Id: "aaaa-aaaa", SiteId: "1111-1111", Name: "light", State: "ON", States: []string{"OFF", "ON"}}
+
+	in := &Message{ID: "1", Kind: SubjectStateChanged, TenantID: "tenant", Time: 1, PreviousState: "OFF"}
+	assert.NoError(t, in.EncodeSwitch(sw))
+
+	data, err := json.Marshal(in)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"siteId":"1111-1111"`)
+
+	out := new(Message)
+	assert.NoError(t, json.Unmarshal(data, out))
+	assert.Equal(t, SubjectStateChanged, out.Kind)
+	assert.Equal(t, "OFF", out.PreviousState)
+
+	decoded, err := out.DecodeSwitch()
+	assert.NoError(t, err)
+	assert.Equal(t, sw.Id, decoded.Id)
+	assert.Equal(t, sw.States, decoded.States)
+
+	out.Switch = json.RawMessage(`{"id": 42}`)
+	_, err = out.DecodeSwitch()
+	assert.Error(t, err)
+}
@@ -0,0 +1,4230 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: switch.proto
+
+package proto
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import field_mask "google.golang.org/genproto/protobuf/field_mask"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// BulkMode decides what happens to the other switches of a bulk request when one of them fails
+type BulkMode int32
+
+const (
+	// Every switch that can be changed is changed
+	BulkMode_BEST_EFFORT BulkMode = 0
+	// Either every switch is changed or none is
+	BulkMode_ALL_OR_NOTHING BulkMode = 1
+)
+
+var BulkMode_name = map[int32]string{
+	0: "BEST_EFFORT",
+	1: "ALL_OR_NOTHING",
+}
+var BulkMode_value = map[string]int32{
+	"BEST_EFFORT":    0,
+	"ALL_OR_NOTHING": 1,
+}
+
+func (x BulkMode) String() string {
+	return proto.EnumName(BulkMode_name, int32(x))
+}
+func (BulkMode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{0}
+}
+
+type Interlock_Operator int32
+
+const (
+	Interlock_GREATER_THAN     Interlock_Operator = 0
+	Interlock_GREATER_OR_EQUAL Interlock_Operator = 1
+	Interlock_LESS_THAN        Interlock_Operator = 2
+	Interlock_LESS_OR_EQUAL    Interlock_Operator = 3
+	Interlock_EQUAL            Interlock_Operator = 4
+	Interlock_NOT_EQUAL        Interlock_Operator = 5
+)
+
+var Interlock_Operator_name = map[int32]string{
+	0: "GREATER_THAN",
+	1: "GREATER_OR_EQUAL",
+	2: "LESS_THAN",
+	3: "LESS_OR_EQUAL",
+	4: "EQUAL",
+	5: "NOT_EQUAL",
+}
+var Interlock_Operator_value = map[string]int32{
+	"GREATER_THAN":     0,
+	"GREATER_OR_EQUAL": 1,
+	"LESS_THAN":        2,
+	"LESS_OR_EQUAL":    3,
+	"EQUAL":            4,
+	"NOT_EQUAL":        5,
+}
+
+func (x Interlock_Operator) String() string {
+	return proto.EnumName(Interlock_Operator_name, int32(x))
+}
+func (Interlock_Operator) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{2, 0}
+}
+
+type Interlock_Limit int32
+
+const (
+	// The value of the interlock
+	Interlock_VALUE Interlock_Limit = 0
+	// The minimum safe value of the sensor
+	Interlock_MIN_SAFE Interlock_Limit = 1
+	// The maximum safe value of the sensor
+	Interlock_MAX_SAFE Interlock_Limit = 2
+)
+
+var Interlock_Limit_name = map[int32]string{
+	0: "VALUE",
+	1: "MIN_SAFE",
+	2: "MAX_SAFE",
+}
+var Interlock_Limit_value = map[string]int32{
+	"VALUE":    0,
+	"MIN_SAFE": 1,
+	"MAX_SAFE": 2,
+}
+
+func (x Interlock_Limit) String() string {
+	return proto.EnumName(Interlock_Limit_name, int32(x))
+}
+func (Interlock_Limit) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{2, 1}
+}
+
+type GetSwitchesRequest_SortBy int32
+
+const (
+	GetSwitchesRequest_NAME    GetSwitchesRequest_SortBy = 0
+	GetSwitchesRequest_STATE   GetSwitchesRequest_SortBy = 1
+	GetSwitchesRequest_SITE_ID GetSwitchesRequest_SortBy = 2
+)
+
+var GetSwitchesRequest_SortBy_name = map[int32]string{
+	0: "NAME",
+	1: "STATE",
+	2: "SITE_ID",
+}
+var GetSwitchesRequest_SortBy_value = map[string]int32{
+	"NAME":    0,
+	"STATE":   1,
+	"SITE_ID": 2,
+}
+
+func (x GetSwitchesRequest_SortBy) String() string {
+	return proto.EnumName(GetSwitchesRequest_SortBy_name, int32(x))
+}
+func (GetSwitchesRequest_SortBy) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{7, 0}
+}
+
+type SwitchEvent_Type int32
+
+const (
+	SwitchEvent_CURRENT       SwitchEvent_Type = 0
+	SwitchEvent_INSTALLED     SwitchEvent_Type = 1
+	SwitchEvent_REMOVED       SwitchEvent_Type = 2
+	SwitchEvent_STATE_CHANGED SwitchEvent_Type = 3
+	SwitchEvent_UPDATED       SwitchEvent_Type = 4
+	// The device agent reported a different state or the switch converged to its desired state
+	SwitchEvent_REPORTED SwitchEvent_Type = 5
+	// The reported state did not converge to the desired state within the sync timeout
+	SwitchEvent_OUT_OF_SYNC SwitchEvent_Type = 6
+)
+
+var SwitchEvent_Type_name = map[int32]string{
+	0: "CURRENT",
+	1: "INSTALLED",
+	2: "REMOVED",
+	3: "STATE_CHANGED",
+	4: "UPDATED",
+	5: "REPORTED",
+	6: "OUT_OF_SYNC",
+}
+var SwitchEvent_Type_value = map[string]int32{
+	"CURRENT":       0,
+	"INSTALLED":     1,
+	"REMOVED":       2,
+	"STATE_CHANGED": 3,
+	"UPDATED":       4,
+	"REPORTED":      5,
+	"OUT_OF_SYNC":   6,
+}
+
+func (x SwitchEvent_Type) String() string {
+	return proto.EnumName(SwitchEvent_Type_name, int32(x))
+}
+func (SwitchEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{20, 0}
+}
+
+type Switch struct {
+	Id     string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	SiteId string `protobuf:"bytes,2,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	Name   string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	// The desired state, changed by SetSwitch
+	State  string   `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	States []string `protobuf:"bytes,5,rep,name=states,proto3" json:"states,omitempty"`
+	// Changes every time the switch changes
+	Revision string `protobuf:"bytes,6,opt,name=revision,proto3" json:"revision,omitempty"`
+	// The allowed state changes, any change between states is allowed if empty
+	Transitions []*Transition `protobuf:"bytes,7,rep,name=transitions,proto3" json:"transitions,omitempty"`
+	// The state last reported by the device agent, empty if it never reported
+	ReportedState string `protobuf:"bytes,8,opt,name=reported_state,json=reportedState,proto3" json:"reported_state,omitempty"`
+	// Unix time in nanoseconds of the last report, zero if the device agent never reported
+	ReportedAt int64 `protobuf:"varint,9,opt,name=reported_at,json=reportedAt,proto3" json:"reported_at,omitempty"`
+	// Set while the reported state differs from the desired state
+	Pending bool `protobuf:"varint,10,opt,name=pending,proto3" json:"pending,omitempty"`
+	// Set when the reported state did not converge to the desired state within the sync timeout
+	OutOfSync bool `protobuf:"varint,11,opt,name=out_of_sync,json=outOfSync,proto3" json:"out_of_sync,omitempty"`
+	// The rules that block changes of state on sensor readings
+	Interlocks           []*Interlock `protobuf:"bytes,12,rep,name=interlocks,proto3" json:"interlocks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Switch) Reset()         { *m = Switch{} }
+func (m *Switch) String() string { return proto.CompactTextString(m) }
+func (*Switch) ProtoMessage()    {}
+func (*Switch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{0}
+}
+func (m *Switch) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Switch.Unmarshal(m, b)
+}
+func (m *Switch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Switch.Marshal(b, m, deterministic)
+}
+func (dst *Switch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Switch.Merge(dst, src)
+}
+func (m *Switch) XXX_Size() int {
+	return xxx_messageInfo_Switch.Size(m)
+}
+func (m *Switch) XXX_DiscardUnknown() {
+	xxx_messageInfo_Switch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Switch proto.InternalMessageInfo
+
+func (m *Switch) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Switch) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+func (m *Switch) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Switch) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *Switch) GetStates() []string {
+	if m != nil {
+		return m.States
+	}
+	return nil
+}
+
+func (m *Switch) GetRevision() string {
+	if m != nil {
+		return m.Revision
+	}
+	return ""
+}
+
+func (m *Switch) GetTransitions() []*Transition {
+	if m != nil {
+		return m.Transitions
+	}
+	return nil
+}
+
+func (m *Switch) GetReportedState() string {
+	if m != nil {
+		return m.ReportedState
+	}
+	return ""
+}
+
+func (m *Switch) GetReportedAt() int64 {
+	if m != nil {
+		return m.ReportedAt
+	}
+	return 0
+}
+
+func (m *Switch) GetPending() bool {
+	if m != nil {
+		return m.Pending
+	}
+	return false
+}
+
+func (m *Switch) GetOutOfSync() bool {
+	if m != nil {
+		return m.OutOfSync
+	}
+	return false
+}
+
+func (m *Switch) GetInterlocks() []*Interlock {
+	if m != nil {
+		return m.Interlocks
+	}
+	return nil
+}
+
+// Transition lists the states a switch can change to from a state
+type Transition struct {
+	From                 string   `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To                   []string `protobuf:"bytes,2,rep,name=to,proto3" json:"to,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Transition) Reset()         { *m = Transition{} }
+func (m *Transition) String() string { return proto.CompactTextString(m) }
+func (*Transition) ProtoMessage()    {}
+func (*Transition) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{1}
+}
+func (m *Transition) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Transition.Unmarshal(m, b)
+}
+func (m *Transition) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Transition.Marshal(b, m, deterministic)
+}
+func (dst *Transition) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Transition.Merge(dst, src)
+}
+func (m *Transition) XXX_Size() int {
+	return xxx_messageInfo_Transition.Size(m)
+}
+func (m *Transition) XXX_DiscardUnknown() {
+	xxx_messageInfo_Transition.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Transition proto.InternalMessageInfo
+
+func (m *Transition) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *Transition) GetTo() []string {
+	if m != nil {
+		return m.To
+	}
+	return nil
+}
+
+// Interlock blocks changing a switch to some states while a reading of a sensor compares to a limit
+type Interlock struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// The states the interlock blocks, every state if empty
+	States   []string `protobuf:"bytes,2,rep,name=states,proto3" json:"states,omitempty"`
+	SensorId string   `protobuf:"bytes,3,opt,name=sensor_id,json=sensorId,proto3" json:"sensor_id,omitempty"`
+	// A change is blocked while the reading of the sensor compared to the limit with the operator is true
+	Operator             Interlock_Operator `protobuf:"varint,4,opt,name=operator,proto3,enum=proto.Interlock_Operator" json:"operator,omitempty"`
+	Limit                Interlock_Limit    `protobuf:"varint,5,opt,name=limit,proto3,enum=proto.Interlock_Limit" json:"limit,omitempty"`
+	Value                float64            `protobuf:"fixed64,6,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *Interlock) Reset()         { *m = Interlock{} }
+func (m *Interlock) String() string { return proto.CompactTextString(m) }
+func (*Interlock) ProtoMessage()    {}
+func (*Interlock) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{2}
+}
+func (m *Interlock) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Interlock.Unmarshal(m, b)
+}
+func (m *Interlock) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Interlock.Marshal(b, m, deterministic)
+}
+func (dst *Interlock) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Interlock.Merge(dst, src)
+}
+func (m *Interlock) XXX_Size() int {
+	return xxx_messageInfo_Interlock.Size(m)
+}
+func (m *Interlock) XXX_DiscardUnknown() {
+	xxx_messageInfo_Interlock.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Interlock proto.InternalMessageInfo
+
+func (m *Interlock) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Interlock) GetStates() []string {
+	if m != nil {
+		return m.States
+	}
+	return nil
+}
+
+func (m *Interlock) GetSensorId() string {
+	if m != nil {
+		return m.SensorId
+	}
+	return ""
+}
+
+func (m *Interlock) GetOperator() Interlock_Operator {
+	if m != nil {
+		return m.Operator
+	}
+	return Interlock_GREATER_THAN
+}
+
+func (m *Interlock) GetLimit() Interlock_Limit {
+	if m != nil {
+		return m.Limit
+	}
+	return Interlock_VALUE
+}
+
+func (m *Interlock) GetValue() float64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+type InstallSwitchRequest struct {
+	SiteId               string        `protobuf:"bytes,1,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	Name                 string        `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	State                string        `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	States               []string      `protobuf:"bytes,4,rep,name=states,proto3" json:"states,omitempty"`
+	Transitions          []*Transition `protobuf:"bytes,5,rep,name=transitions,proto3" json:"transitions,omitempty"`
+	Interlocks           []*Interlock  `protobuf:"bytes,6,rep,name=interlocks,proto3" json:"interlocks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *InstallSwitchRequest) Reset()         { *m = InstallSwitchRequest{} }
+func (m *InstallSwitchRequest) String() string { return proto.CompactTextString(m) }
+func (*InstallSwitchRequest) ProtoMessage()    {}
+func (*InstallSwitchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{3}
+}
+func (m *InstallSwitchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InstallSwitchRequest.Unmarshal(m, b)
+}
+func (m *InstallSwitchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InstallSwitchRequest.Marshal(b, m, deterministic)
+}
+func (dst *InstallSwitchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InstallSwitchRequest.Merge(dst, src)
+}
+func (m *InstallSwitchRequest) XXX_Size() int {
+	return xxx_messageInfo_InstallSwitchRequest.Size(m)
+}
+func (m *InstallSwitchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InstallSwitchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InstallSwitchRequest proto.InternalMessageInfo
+
+func (m *InstallSwitchRequest) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+func (m *InstallSwitchRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *InstallSwitchRequest) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *InstallSwitchRequest) GetStates() []string {
+	if m != nil {
+		return m.States
+	}
+	return nil
+}
+
+func (m *InstallSwitchRequest) GetTransitions() []*Transition {
+	if m != nil {
+		return m.Transitions
+	}
+	return nil
+}
+
+func (m *InstallSwitchRequest) GetInterlocks() []*Interlock {
+	if m != nil {
+		return m.Interlocks
+	}
+	return nil
+}
+
+type RemoveSwitchRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RemoveSwitchRequest) Reset()         { *m = RemoveSwitchRequest{} }
+func (m *RemoveSwitchRequest) String() string { return proto.CompactTextString(m) }
+func (*RemoveSwitchRequest) ProtoMessage()    {}
+func (*RemoveSwitchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{4}
+}
+func (m *RemoveSwitchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RemoveSwitchRequest.Unmarshal(m, b)
+}
+func (m *RemoveSwitchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RemoveSwitchRequest.Marshal(b, m, deterministic)
+}
+func (dst *RemoveSwitchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RemoveSwitchRequest.Merge(dst, src)
+}
+func (m *RemoveSwitchRequest) XXX_Size() int {
+	return xxx_messageInfo_RemoveSwitchRequest.Size(m)
+}
+func (m *RemoveSwitchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RemoveSwitchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RemoveSwitchRequest proto.InternalMessageInfo
+
+func (m *RemoveSwitchRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type RemoveSwitchResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RemoveSwitchResponse) Reset()         { *m = RemoveSwitchResponse{} }
+func (m *RemoveSwitchResponse) String() string { return proto.CompactTextString(m) }
+func (*RemoveSwitchResponse) ProtoMessage()    {}
+func (*RemoveSwitchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{5}
+}
+func (m *RemoveSwitchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RemoveSwitchResponse.Unmarshal(m, b)
+}
+func (m *RemoveSwitchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RemoveSwitchResponse.Marshal(b, m, deterministic)
+}
+func (dst *RemoveSwitchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RemoveSwitchResponse.Merge(dst, src)
+}
+func (m *RemoveSwitchResponse) XXX_Size() int {
+	return xxx_messageInfo_RemoveSwitchResponse.Size(m)
+}
+func (m *RemoveSwitchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RemoveSwitchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RemoveSwitchResponse proto.InternalMessageInfo
+
+type GetSwitchRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetSwitchRequest) Reset()         { *m = GetSwitchRequest{} }
+func (m *GetSwitchRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSwitchRequest) ProtoMessage()    {}
+func (*GetSwitchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{6}
+}
+func (m *GetSwitchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSwitchRequest.Unmarshal(m, b)
+}
+func (m *GetSwitchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSwitchRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetSwitchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSwitchRequest.Merge(dst, src)
+}
+func (m *GetSwitchRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSwitchRequest.Size(m)
+}
+func (m *GetSwitchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSwitchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetSwitchRequest proto.InternalMessageInfo
+
+func (m *GetSwitchRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type GetSwitchesRequest struct {
+	SiteId string `protobuf:"bytes,1,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	// Switches of any of these sites are returned along with the ones of site_id, every site if both are empty
+	SiteIds []string `protobuf:"bytes,2,rep,name=site_ids,json=siteIds,proto3" json:"site_ids,omitempty"`
+	// Case-insensitive filters on the switch name
+	NamePrefix   string                    `protobuf:"bytes,3,opt,name=name_prefix,json=namePrefix,proto3" json:"name_prefix,omitempty"`
+	NameContains string                    `protobuf:"bytes,4,opt,name=name_contains,json=nameContains,proto3" json:"name_contains,omitempty"`
+	State        string                    `protobuf:"bytes,5,opt,name=state,proto3" json:"state,omitempty"`
+	SortBy       GetSwitchesRequest_SortBy `protobuf:"varint,6,opt,name=sort_by,json=sortBy,proto3,enum=proto.GetSwitchesRequest_SortBy" json:"sort_by,omitempty"`
+	Descending   bool                      `protobuf:"varint,7,opt,name=descending,proto3" json:"descending,omitempty"`
+	// Zero streams every switch, otherwise the token of the next page is sent in the next-page-token trailer
+	PageSize             int32    `protobuf:"varint,8,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken            string   `protobuf:"bytes,9,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetSwitchesRequest) Reset()         { *m = GetSwitchesRequest{} }
+func (m *GetSwitchesRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSwitchesRequest) ProtoMessage()    {}
+func (*GetSwitchesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{7}
+}
+func (m *GetSwitchesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSwitchesRequest.Unmarshal(m, b)
+}
+func (m *GetSwitchesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSwitchesRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetSwitchesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSwitchesRequest.Merge(dst, src)
+}
+func (m *GetSwitchesRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSwitchesRequest.Size(m)
+}
+func (m *GetSwitchesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSwitchesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetSwitchesRequest proto.InternalMessageInfo
+
+func (m *GetSwitchesRequest) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+func (m *GetSwitchesRequest) GetSiteIds() []string {
+	if m != nil {
+		return m.SiteIds
+	}
+	return nil
+}
+
+func (m *GetSwitchesRequest) GetNamePrefix() string {
+	if m != nil {
+		return m.NamePrefix
+	}
+	return ""
+}
+
+func (m *GetSwitchesRequest) GetNameContains() string {
+	if m != nil {
+		return m.NameContains
+	}
+	return ""
+}
+
+func (m *GetSwitchesRequest) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *GetSwitchesRequest) GetSortBy() GetSwitchesRequest_SortBy {
+	if m != nil {
+		return m.SortBy
+	}
+	return GetSwitchesRequest_NAME
+}
+
+func (m *GetSwitchesRequest) GetDescending() bool {
+	if m != nil {
+		return m.Descending
+	}
+	return false
+}
+
+func (m *GetSwitchesRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *GetSwitchesRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+type GetAllowedTransitionsRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetAllowedTransitionsRequest) Reset()         { *m = GetAllowedTransitionsRequest{} }
+func (m *GetAllowedTransitionsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetAllowedTransitionsRequest) ProtoMessage()    {}
+func (*GetAllowedTransitionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{8}
+}
+func (m *GetAllowedTransitionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetAllowedTransitionsRequest.Unmarshal(m, b)
+}
+func (m *GetAllowedTransitionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetAllowedTransitionsRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetAllowedTransitionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetAllowedTransitionsRequest.Merge(dst, src)
+}
+func (m *GetAllowedTransitionsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetAllowedTransitionsRequest.Size(m)
+}
+func (m *GetAllowedTransitionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetAllowedTransitionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetAllowedTransitionsRequest proto.InternalMessageInfo
+
+func (m *GetAllowedTransitionsRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type GetAllowedTransitionsResponse struct {
+	CurrentState string `protobuf:"bytes,1,opt,name=current_state,json=currentState,proto3" json:"current_state,omitempty"`
+	// The states the switch can change to from its current state
+	AllowedStates        []string `protobuf:"bytes,2,rep,name=allowed_states,json=allowedStates,proto3" json:"allowed_states,omitempty"`
+	Revision             string   `protobuf:"bytes,3,opt,name=revision,proto3" json:"revision,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetAllowedTransitionsResponse) Reset()         { *m = GetAllowedTransitionsResponse{} }
+func (m *GetAllowedTransitionsResponse) String() string { return proto.CompactTextString(m) }
+func (*GetAllowedTransitionsResponse) ProtoMessage()    {}
+func (*GetAllowedTransitionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{9}
+}
+func (m *GetAllowedTransitionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetAllowedTransitionsResponse.Unmarshal(m, b)
+}
+func (m *GetAllowedTransitionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetAllowedTransitionsResponse.Marshal(b, m, deterministic)
+}
+func (dst *GetAllowedTransitionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetAllowedTransitionsResponse.Merge(dst, src)
+}
+func (m *GetAllowedTransitionsResponse) XXX_Size() int {
+	return xxx_messageInfo_GetAllowedTransitionsResponse.Size(m)
+}
+func (m *GetAllowedTransitionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetAllowedTransitionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetAllowedTransitionsResponse proto.InternalMessageInfo
+
+func (m *GetAllowedTransitionsResponse) GetCurrentState() string {
+	if m != nil {
+		return m.CurrentState
+	}
+	return ""
+}
+
+func (m *GetAllowedTransitionsResponse) GetAllowedStates() []string {
+	if m != nil {
+		return m.AllowedStates
+	}
+	return nil
+}
+
+func (m *GetAllowedTransitionsResponse) GetRevision() string {
+	if m != nil {
+		return m.Revision
+	}
+	return ""
+}
+
+type UpdateSwitchRequest struct {
+	// The id of the switch and the new values of the fields in the update mask
+	Switch *Switch `protobuf:"bytes,1,opt,name=switch,proto3" json:"switch,omitempty"`
+	// The fields to update, any of site_id, name, states, transitions and interlocks
+	UpdateMask           *field_mask.FieldMask `protobuf:"bytes,2,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *UpdateSwitchRequest) Reset()         { *m = UpdateSwitchRequest{} }
+func (m *UpdateSwitchRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateSwitchRequest) ProtoMessage()    {}
+func (*UpdateSwitchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{10}
+}
+func (m *UpdateSwitchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateSwitchRequest.Unmarshal(m, b)
+}
+func (m *UpdateSwitchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateSwitchRequest.Marshal(b, m, deterministic)
+}
+func (dst *UpdateSwitchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateSwitchRequest.Merge(dst, src)
+}
+func (m *UpdateSwitchRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateSwitchRequest.Size(m)
+}
+func (m *UpdateSwitchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateSwitchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateSwitchRequest proto.InternalMessageInfo
+
+func (m *UpdateSwitchRequest) GetSwitch() *Switch {
+	if m != nil {
+		return m.Switch
+	}
+	return nil
+}
+
+func (m *UpdateSwitchRequest) GetUpdateMask() *field_mask.FieldMask {
+	if m != nil {
+		return m.UpdateMask
+	}
+	return nil
+}
+
+type SetSwitchRequest struct {
+	Id    string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State string `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	// Why the state is changed, kept in the switch history
+	Reason string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	// When set, the state is only changed if the switch is still at this revision
+	ExpectedRevision string `protobuf:"bytes,4,opt,name=expected_revision,json=expectedRevision,proto3" json:"expected_revision,omitempty"`
+	// When set, the state is only changed if the switch is still in this state
+	ExpectedState string `protobuf:"bytes,5,opt,name=expected_state,json=expectedState,proto3" json:"expected_state,omitempty"`
+	// Changes the state even if interlocks block it, only allowed to callers with the OverrideInterlocks permission and requires a reason
+	OverrideInterlocks   bool     `protobuf:"varint,6,opt,name=override_interlocks,json=overrideInterlocks,proto3" json:"override_interlocks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetSwitchRequest) Reset()         { *m = SetSwitchRequest{} }
+func (m *SetSwitchRequest) String() string { return proto.CompactTextString(m) }
+func (*SetSwitchRequest) ProtoMessage()    {}
+func (*SetSwitchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{11}
+}
+func (m *SetSwitchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetSwitchRequest.Unmarshal(m, b)
+}
+func (m *SetSwitchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetSwitchRequest.Marshal(b, m, deterministic)
+}
+func (dst *SetSwitchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetSwitchRequest.Merge(dst, src)
+}
+func (m *SetSwitchRequest) XXX_Size() int {
+	return xxx_messageInfo_SetSwitchRequest.Size(m)
+}
+func (m *SetSwitchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetSwitchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetSwitchRequest proto.InternalMessageInfo
+
+func (m *SetSwitchRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *SetSwitchRequest) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *SetSwitchRequest) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *SetSwitchRequest) GetExpectedRevision() string {
+	if m != nil {
+		return m.ExpectedRevision
+	}
+	return ""
+}
+
+func (m *SetSwitchRequest) GetExpectedState() string {
+	if m != nil {
+		return m.ExpectedState
+	}
+	return ""
+}
+
+func (m *SetSwitchRequest) GetOverrideInterlocks() bool {
+	if m != nil {
+		return m.OverrideInterlocks
+	}
+	return false
+}
+
+type SetSwitchResponse struct {
+	Revision             string   `protobuf:"bytes,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	PreviousState        string   `protobuf:"bytes,2,opt,name=previous_state,json=previousState,proto3" json:"previous_state,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetSwitchResponse) Reset()         { *m = SetSwitchResponse{} }
+func (m *SetSwitchResponse) String() string { return proto.CompactTextString(m) }
+func (*SetSwitchResponse) ProtoMessage()    {}
+func (*SetSwitchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{12}
+}
+func (m *SetSwitchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetSwitchResponse.Unmarshal(m, b)
+}
+func (m *SetSwitchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetSwitchResponse.Marshal(b, m, deterministic)
+}
+func (dst *SetSwitchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetSwitchResponse.Merge(dst, src)
+}
+func (m *SetSwitchResponse) XXX_Size() int {
+	return xxx_messageInfo_SetSwitchResponse.Size(m)
+}
+func (m *SetSwitchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetSwitchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetSwitchResponse proto.InternalMessageInfo
+
+func (m *SetSwitchResponse) GetRevision() string {
+	if m != nil {
+		return m.Revision
+	}
+	return ""
+}
+
+func (m *SetSwitchResponse) GetPreviousState() string {
+	if m != nil {
+		return m.PreviousState
+	}
+	return ""
+}
+
+type ReportSwitchStateRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The state the device is actually in
+	State                string   `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReportSwitchStateRequest) Reset()         { *m = ReportSwitchStateRequest{} }
+func (m *ReportSwitchStateRequest) String() string { return proto.CompactTextString(m) }
+func (*ReportSwitchStateRequest) ProtoMessage()    {}
+func (*ReportSwitchStateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{13}
+}
+func (m *ReportSwitchStateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReportSwitchStateRequest.Unmarshal(m, b)
+}
+func (m *ReportSwitchStateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReportSwitchStateRequest.Marshal(b, m, deterministic)
+}
+func (dst *ReportSwitchStateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReportSwitchStateRequest.Merge(dst, src)
+}
+func (m *ReportSwitchStateRequest) XXX_Size() int {
+	return xxx_messageInfo_ReportSwitchStateRequest.Size(m)
+}
+func (m *ReportSwitchStateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReportSwitchStateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReportSwitchStateRequest proto.InternalMessageInfo
+
+func (m *ReportSwitchStateRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *ReportSwitchStateRequest) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+type SetSwitchesRequest struct {
+	// Either a list of switch ids, a group id or a site id selects the switches
+	Ids    []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	SiteId string   `protobuf:"bytes,2,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	// Narrows down the switches of a site to the names matching a pattern (% and _ wildcards, case-insensitive)
+	NameFilter string `protobuf:"bytes,3,opt,name=name_filter,json=nameFilter,proto3" json:"name_filter,omitempty"`
+	// Narrows down the switches of a site to the ones in this state
+	StateFilter string `protobuf:"bytes,4,opt,name=state_filter,json=stateFilter,proto3" json:"state_filter,omitempty"`
+	State       string `protobuf:"bytes,5,opt,name=state,proto3" json:"state,omitempty"`
+	// Why the state is changed, kept in the switch history
+	Reason               string   `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	Mode                 BulkMode `protobuf:"varint,7,opt,name=mode,proto3,enum=proto.BulkMode" json:"mode,omitempty"`
+	GroupId              string   `protobuf:"bytes,8,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetSwitchesRequest) Reset()         { *m = SetSwitchesRequest{} }
+func (m *SetSwitchesRequest) String() string { return proto.CompactTextString(m) }
+func (*SetSwitchesRequest) ProtoMessage()    {}
+func (*SetSwitchesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{14}
+}
+func (m *SetSwitchesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetSwitchesRequest.Unmarshal(m, b)
+}
+func (m *SetSwitchesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetSwitchesRequest.Marshal(b, m, deterministic)
+}
+func (dst *SetSwitchesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetSwitchesRequest.Merge(dst, src)
+}
+func (m *SetSwitchesRequest) XXX_Size() int {
+	return xxx_messageInfo_SetSwitchesRequest.Size(m)
+}
+func (m *SetSwitchesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetSwitchesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetSwitchesRequest proto.InternalMessageInfo
+
+func (m *SetSwitchesRequest) GetIds() []string {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+func (m *SetSwitchesRequest) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+func (m *SetSwitchesRequest) GetNameFilter() string {
+	if m != nil {
+		return m.NameFilter
+	}
+	return ""
+}
+
+func (m *SetSwitchesRequest) GetStateFilter() string {
+	if m != nil {
+		return m.StateFilter
+	}
+	return ""
+}
+
+func (m *SetSwitchesRequest) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *SetSwitchesRequest) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *SetSwitchesRequest) GetMode() BulkMode {
+	if m != nil {
+		return m.Mode
+	}
+	return BulkMode_BEST_EFFORT
+}
+
+func (m *SetSwitchesRequest) GetGroupId() string {
+	if m != nil {
+		return m.GroupId
+	}
+	return ""
+}
+
+type SetSwitchesResponse struct {
+	Results              []*SwitchResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *SetSwitchesResponse) Reset()         { *m = SetSwitchesResponse{} }
+func (m *SetSwitchesResponse) String() string { return proto.CompactTextString(m) }
+func (*SetSwitchesResponse) ProtoMessage()    {}
+func (*SetSwitchesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{15}
+}
+func (m *SetSwitchesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetSwitchesResponse.Unmarshal(m, b)
+}
+func (m *SetSwitchesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetSwitchesResponse.Marshal(b, m, deterministic)
+}
+func (dst *SetSwitchesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetSwitchesResponse.Merge(dst, src)
+}
+func (m *SetSwitchesResponse) XXX_Size() int {
+	return xxx_messageInfo_SetSwitchesResponse.Size(m)
+}
+func (m *SetSwitchesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetSwitchesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetSwitchesResponse proto.InternalMessageInfo
+
+func (m *SetSwitchesResponse) GetResults() []*SwitchResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+type InstallSwitchesRequest struct {
+	// The mode is taken from the first message of the stream
+	Mode                 BulkMode              `protobuf:"varint,1,opt,name=mode,proto3,enum=proto.BulkMode" json:"mode,omitempty"`
+	Switch               *InstallSwitchRequest `protobuf:"bytes,2,opt,name=switch,proto3" json:"switch,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *InstallSwitchesRequest) Reset()         { *m = InstallSwitchesRequest{} }
+func (m *InstallSwitchesRequest) String() string { return proto.CompactTextString(m) }
+func (*InstallSwitchesRequest) ProtoMessage()    {}
+func (*InstallSwitchesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{16}
+}
+func (m *InstallSwitchesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InstallSwitchesRequest.Unmarshal(m, b)
+}
+func (m *InstallSwitchesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InstallSwitchesRequest.Marshal(b, m, deterministic)
+}
+func (dst *InstallSwitchesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InstallSwitchesRequest.Merge(dst, src)
+}
+func (m *InstallSwitchesRequest) XXX_Size() int {
+	return xxx_messageInfo_InstallSwitchesRequest.Size(m)
+}
+func (m *InstallSwitchesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InstallSwitchesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InstallSwitchesRequest proto.InternalMessageInfo
+
+func (m *InstallSwitchesRequest) GetMode() BulkMode {
+	if m != nil {
+		return m.Mode
+	}
+	return BulkMode_BEST_EFFORT
+}
+
+func (m *InstallSwitchesRequest) GetSwitch() *InstallSwitchRequest {
+	if m != nil {
+		return m.Switch
+	}
+	return nil
+}
+
+type InstallSwitchesResponse struct {
+	// One result per switch in the order they were sent
+	Results              []*SwitchResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *InstallSwitchesResponse) Reset()         { *m = InstallSwitchesResponse{} }
+func (m *InstallSwitchesResponse) String() string { return proto.CompactTextString(m) }
+func (*InstallSwitchesResponse) ProtoMessage()    {}
+func (*InstallSwitchesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{17}
+}
+func (m *InstallSwitchesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InstallSwitchesResponse.Unmarshal(m, b)
+}
+func (m *InstallSwitchesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InstallSwitchesResponse.Marshal(b, m, deterministic)
+}
+func (dst *InstallSwitchesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InstallSwitchesResponse.Merge(dst, src)
+}
+func (m *InstallSwitchesResponse) XXX_Size() int {
+	return xxx_messageInfo_InstallSwitchesResponse.Size(m)
+}
+func (m *InstallSwitchesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_InstallSwitchesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InstallSwitchesResponse proto.InternalMessageInfo
+
+func (m *InstallSwitchesResponse) GetResults() []*SwitchResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+type SwitchResult struct {
+	// Empty for a switch that could not be installed
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The gRPC status code and message of the change to the switch
+	Code    int32  `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
+	Message string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	// The switch after a successful change
+	Switch               *Switch  `protobuf:"bytes,4,opt,name=switch,proto3" json:"switch,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SwitchResult) Reset()         { *m = SwitchResult{} }
+func (m *SwitchResult) String() string { return proto.CompactTextString(m) }
+func (*SwitchResult) ProtoMessage()    {}
+func (*SwitchResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{18}
+}
+func (m *SwitchResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SwitchResult.Unmarshal(m, b)
+}
+func (m *SwitchResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SwitchResult.Marshal(b, m, deterministic)
+}
+func (dst *SwitchResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SwitchResult.Merge(dst, src)
+}
+func (m *SwitchResult) XXX_Size() int {
+	return xxx_messageInfo_SwitchResult.Size(m)
+}
+func (m *SwitchResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_SwitchResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SwitchResult proto.InternalMessageInfo
+
+func (m *SwitchResult) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *SwitchResult) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *SwitchResult) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *SwitchResult) GetSwitch() *Switch {
+	if m != nil {
+		return m.Switch
+	}
+	return nil
+}
+
+type WatchSwitchesRequest struct {
+	// Either a site id or a list of switch ids selects the switches to watch
+	SiteId string   `protobuf:"bytes,1,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	Ids    []string `protobuf:"bytes,2,rep,name=ids,proto3" json:"ids,omitempty"`
+	// When set, the current state is not sent and the stream resumes after this revision
+	FromRevision         uint64   `protobuf:"varint,3,opt,name=from_revision,json=fromRevision,proto3" json:"from_revision,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchSwitchesRequest) Reset()         { *m = WatchSwitchesRequest{} }
+func (m *WatchSwitchesRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchSwitchesRequest) ProtoMessage()    {}
+func (*WatchSwitchesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{19}
+}
+func (m *WatchSwitchesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchSwitchesRequest.Unmarshal(m, b)
+}
+func (m *WatchSwitchesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchSwitchesRequest.Marshal(b, m, deterministic)
+}
+func (dst *WatchSwitchesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchSwitchesRequest.Merge(dst, src)
+}
+func (m *WatchSwitchesRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchSwitchesRequest.Size(m)
+}
+func (m *WatchSwitchesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchSwitchesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchSwitchesRequest proto.InternalMessageInfo
+
+func (m *WatchSwitchesRequest) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+func (m *WatchSwitchesRequest) GetIds() []string {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+func (m *WatchSwitchesRequest) GetFromRevision() uint64 {
+	if m != nil {
+		return m.FromRevision
+	}
+	return 0
+}
+
+type SwitchEvent struct {
+	Type                 SwitchEvent_Type `protobuf:"varint,1,opt,name=type,proto3,enum=proto.SwitchEvent_Type" json:"type,omitempty"`
+	Revision             uint64           `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	Switch               *Switch          `protobuf:"bytes,3,opt,name=switch,proto3" json:"switch,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *SwitchEvent) Reset()         { *m = SwitchEvent{} }
+func (m *SwitchEvent) String() string { return proto.CompactTextString(m) }
+func (*SwitchEvent) ProtoMessage()    {}
+func (*SwitchEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{20}
+}
+func (m *SwitchEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SwitchEvent.Unmarshal(m, b)
+}
+func (m *SwitchEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SwitchEvent.Marshal(b, m, deterministic)
+}
+func (dst *SwitchEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SwitchEvent.Merge(dst, src)
+}
+func (m *SwitchEvent) XXX_Size() int {
+	return xxx_messageInfo_SwitchEvent.Size(m)
+}
+func (m *SwitchEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_SwitchEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SwitchEvent proto.InternalMessageInfo
+
+func (m *SwitchEvent) GetType() SwitchEvent_Type {
+	if m != nil {
+		return m.Type
+	}
+	return SwitchEvent_CURRENT
+}
+
+func (m *SwitchEvent) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *SwitchEvent) GetSwitch() *Switch {
+	if m != nil {
+		return m.Switch
+	}
+	return nil
+}
+
+type SwitchStateChange struct {
+	SwitchId      string `protobuf:"bytes,1,opt,name=switch_id,json=switchId,proto3" json:"switch_id,omitempty"`
+	PreviousState string `protobuf:"bytes,2,opt,name=previous_state,json=previousState,proto3" json:"previous_state,omitempty"`
+	State         string `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	// Unix time in nanoseconds
+	Time int64 `protobuf:"varint,4,opt,name=time,proto3" json:"time,omitempty"`
+	// The client certificate common name, or scheduler for the changes of schedules
+	Caller string `protobuf:"bytes,5,opt,name=caller,proto3" json:"caller,omitempty"`
+	Reason string `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	// The interlocks that blocked the change and were overridden
+	OverriddenInterlocks []string `protobuf:"bytes,7,rep,name=overridden_interlocks,json=overriddenInterlocks,proto3" json:"overridden_interlocks,omitempty"`
+	// The caller-id metadata of the request, as claimed by the caller and not authenticated
+	ClaimedCaller        string   `protobuf:"bytes,8,opt,name=claimed_caller,json=claimedCaller,proto3" json:"claimed_caller,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SwitchStateChange) Reset()         { *m = SwitchStateChange{} }
+func (m *SwitchStateChange) String() string { return proto.CompactTextString(m) }
+func (*SwitchStateChange) ProtoMessage()    {}
+func (*SwitchStateChange) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{21}
+}
+func (m *SwitchStateChange) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SwitchStateChange.Unmarshal(m, b)
+}
+func (m *SwitchStateChange) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SwitchStateChange.Marshal(b, m, deterministic)
+}
+func (dst *SwitchStateChange) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SwitchStateChange.Merge(dst, src)
+}
+func (m *SwitchStateChange) XXX_Size() int {
+	return xxx_messageInfo_SwitchStateChange.Size(m)
+}
+func (m *SwitchStateChange) XXX_DiscardUnknown() {
+	xxx_messageInfo_SwitchStateChange.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SwitchStateChange proto.InternalMessageInfo
+
+func (m *SwitchStateChange) GetSwitchId() string {
+	if m != nil {
+		return m.SwitchId
+	}
+	return ""
+}
+
+func (m *SwitchStateChange) GetPreviousState() string {
+	if m != nil {
+		return m.PreviousState
+	}
+	return ""
+}
+
+func (m *SwitchStateChange) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *SwitchStateChange) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *SwitchStateChange) GetCaller() string {
+	if m != nil {
+		return m.Caller
+	}
+	return ""
+}
+
+func (m *SwitchStateChange) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *SwitchStateChange) GetOverriddenInterlocks() []string {
+	if m != nil {
+		return m.OverriddenInterlocks
+	}
+	return nil
+}
+
+func (m *SwitchStateChange) GetClaimedCaller() string {
+	if m != nil {
+		return m.ClaimedCaller
+	}
+	return ""
+}
+
+type GetSwitchHistoryRequest struct {
+	SwitchId string `protobuf:"bytes,1,opt,name=switch_id,json=switchId,proto3" json:"switch_id,omitempty"`
+	// Unix time in nanoseconds, zero means no bound
+	FromTime             int64    `protobuf:"varint,2,opt,name=from_time,json=fromTime,proto3" json:"from_time,omitempty"`
+	ToTime               int64    `protobuf:"varint,3,opt,name=to_time,json=toTime,proto3" json:"to_time,omitempty"`
+	PageSize             int32    `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken            string   `protobuf:"bytes,5,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetSwitchHistoryRequest) Reset()         { *m = GetSwitchHistoryRequest{} }
+func (m *GetSwitchHistoryRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSwitchHistoryRequest) ProtoMessage()    {}
+func (*GetSwitchHistoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{22}
+}
+func (m *GetSwitchHistoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSwitchHistoryRequest.Unmarshal(m, b)
+}
+func (m *GetSwitchHistoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSwitchHistoryRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetSwitchHistoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSwitchHistoryRequest.Merge(dst, src)
+}
+func (m *GetSwitchHistoryRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSwitchHistoryRequest.Size(m)
+}
+func (m *GetSwitchHistoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSwitchHistoryRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetSwitchHistoryRequest proto.InternalMessageInfo
+
+func (m *GetSwitchHistoryRequest) GetSwitchId() string {
+	if m != nil {
+		return m.SwitchId
+	}
+	return ""
+}
+
+func (m *GetSwitchHistoryRequest) GetFromTime() int64 {
+	if m != nil {
+		return m.FromTime
+	}
+	return 0
+}
+
+func (m *GetSwitchHistoryRequest) GetToTime() int64 {
+	if m != nil {
+		return m.ToTime
+	}
+	return 0
+}
+
+func (m *GetSwitchHistoryRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *GetSwitchHistoryRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+type GetSwitchHistoryResponse struct {
+	// The newest changes come first
+	Changes []*SwitchStateChange `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	// Empty when there are no more changes
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetSwitchHistoryResponse) Reset()         { *m = GetSwitchHistoryResponse{} }
+func (m *GetSwitchHistoryResponse) String() string { return proto.CompactTextString(m) }
+func (*GetSwitchHistoryResponse) ProtoMessage()    {}
+func (*GetSwitchHistoryResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{23}
+}
+func (m *GetSwitchHistoryResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSwitchHistoryResponse.Unmarshal(m, b)
+}
+func (m *GetSwitchHistoryResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSwitchHistoryResponse.Marshal(b, m, deterministic)
+}
+func (dst *GetSwitchHistoryResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSwitchHistoryResponse.Merge(dst, src)
+}
+func (m *GetSwitchHistoryResponse) XXX_Size() int {
+	return xxx_messageInfo_GetSwitchHistoryResponse.Size(m)
+}
+func (m *GetSwitchHistoryResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSwitchHistoryResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetSwitchHistoryResponse proto.InternalMessageInfo
+
+func (m *GetSwitchHistoryResponse) GetChanges() []*SwitchStateChange {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+func (m *GetSwitchHistoryResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+type Schedule struct {
+	Id       string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	SwitchId string `protobuf:"bytes,2,opt,name=switch_id,json=switchId,proto3" json:"switch_id,omitempty"`
+	SiteId   string `protobuf:"bytes,3,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	// The state the switch is set to
+	State string `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	// A standard cron expression (minute hour day-of-month month day-of-week) for recurring changes
+	Cron string `protobuf:"bytes,5,opt,name=cron,proto3" json:"cron,omitempty"`
+	// Unix time in nanoseconds of a one-off change, used when there is no cron expression
+	RunAt int64 `protobuf:"varint,6,opt,name=run_at,json=runAt,proto3" json:"run_at,omitempty"`
+	// IANA time zone the cron expression is evaluated in, UTC by default
+	TimeZone string `protobuf:"bytes,7,opt,name=time_zone,json=timeZone,proto3" json:"time_zone,omitempty"`
+	Enabled  bool   `protobuf:"varint,8,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	// Unix time in nanoseconds of the next run, zero when there is none
+	NextRun              int64        `protobuf:"varint,9,opt,name=next_run,json=nextRun,proto3" json:"next_run,omitempty"`
+	LastRun              *ScheduleRun `protobuf:"bytes,10,opt,name=last_run,json=lastRun,proto3" json:"last_run,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Schedule) Reset()         { *m = Schedule{} }
+func (m *Schedule) String() string { return proto.CompactTextString(m) }
+func (*Schedule) ProtoMessage()    {}
+func (*Schedule) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{24}
+}
+func (m *Schedule) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Schedule.Unmarshal(m, b)
+}
+func (m *Schedule) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Schedule.Marshal(b, m, deterministic)
+}
+func (dst *Schedule) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Schedule.Merge(dst, src)
+}
+func (m *Schedule) XXX_Size() int {
+	return xxx_messageInfo_Schedule.Size(m)
+}
+func (m *Schedule) XXX_DiscardUnknown() {
+	xxx_messageInfo_Schedule.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Schedule proto.InternalMessageInfo
+
+func (m *Schedule) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Schedule) GetSwitchId() string {
+	if m != nil {
+		return m.SwitchId
+	}
+	return ""
+}
+
+func (m *Schedule) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+func (m *Schedule) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *Schedule) GetCron() string {
+	if m != nil {
+		return m.Cron
+	}
+	return ""
+}
+
+func (m *Schedule) GetRunAt() int64 {
+	if m != nil {
+		return m.RunAt
+	}
+	return 0
+}
+
+func (m *Schedule) GetTimeZone() string {
+	if m != nil {
+		return m.TimeZone
+	}
+	return ""
+}
+
+func (m *Schedule) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
+	}
+	return false
+}
+
+func (m *Schedule) GetNextRun() int64 {
+	if m != nil {
+		return m.NextRun
+	}
+	return 0
+}
+
+func (m *Schedule) GetLastRun() *ScheduleRun {
+	if m != nil {
+		return m.LastRun
+	}
+	return nil
+}
+
+type ScheduleRun struct {
+	// Unix time in nanoseconds the run was due
+	DueTime int64 `protobuf:"varint,1,opt,name=due_time,json=dueTime,proto3" json:"due_time,omitempty"`
+	// Unix time in nanoseconds the run was executed, zero when it was skipped
+	ExecTime int64  `protobuf:"varint,2,opt,name=exec_time,json=execTime,proto3" json:"exec_time,omitempty"`
+	Success  bool   `protobuf:"varint,3,opt,name=success,proto3" json:"success,omitempty"`
+	Error    string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// Number of runs missed while the scheduler was not running
+	Missed               int32    `protobuf:"varint,5,opt,name=missed,proto3" json:"missed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ScheduleRun) Reset()         { *m = ScheduleRun{} }
+func (m *ScheduleRun) String() string { return proto.CompactTextString(m) }
+func (*ScheduleRun) ProtoMessage()    {}
+func (*ScheduleRun) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{25}
+}
+func (m *ScheduleRun) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScheduleRun.Unmarshal(m, b)
+}
+func (m *ScheduleRun) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScheduleRun.Marshal(b, m, deterministic)
+}
+func (dst *ScheduleRun) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScheduleRun.Merge(dst, src)
+}
+func (m *ScheduleRun) XXX_Size() int {
+	return xxx_messageInfo_ScheduleRun.Size(m)
+}
+func (m *ScheduleRun) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScheduleRun.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ScheduleRun proto.InternalMessageInfo
+
+func (m *ScheduleRun) GetDueTime() int64 {
+	if m != nil {
+		return m.DueTime
+	}
+	return 0
+}
+
+func (m *ScheduleRun) GetExecTime() int64 {
+	if m != nil {
+		return m.ExecTime
+	}
+	return 0
+}
+
+func (m *ScheduleRun) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+func (m *ScheduleRun) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *ScheduleRun) GetMissed() int32 {
+	if m != nil {
+		return m.Missed
+	}
+	return 0
+}
+
+type CreateScheduleRequest struct {
+	SwitchId             string   `protobuf:"bytes,1,opt,name=switch_id,json=switchId,proto3" json:"switch_id,omitempty"`
+	State                string   `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Cron                 string   `protobuf:"bytes,3,opt,name=cron,proto3" json:"cron,omitempty"`
+	RunAt                int64    `protobuf:"varint,4,opt,name=run_at,json=runAt,proto3" json:"run_at,omitempty"`
+	TimeZone             string   `protobuf:"bytes,5,opt,name=time_zone,json=timeZone,proto3" json:"time_zone,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateScheduleRequest) Reset()         { *m = CreateScheduleRequest{} }
+func (m *CreateScheduleRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateScheduleRequest) ProtoMessage()    {}
+func (*CreateScheduleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{26}
+}
+func (m *CreateScheduleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateScheduleRequest.Unmarshal(m, b)
+}
+func (m *CreateScheduleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateScheduleRequest.Marshal(b, m, deterministic)
+}
+func (dst *CreateScheduleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateScheduleRequest.Merge(dst, src)
+}
+func (m *CreateScheduleRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateScheduleRequest.Size(m)
+}
+func (m *CreateScheduleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateScheduleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateScheduleRequest proto.InternalMessageInfo
+
+func (m *CreateScheduleRequest) GetSwitchId() string {
+	if m != nil {
+		return m.SwitchId
+	}
+	return ""
+}
+
+func (m *CreateScheduleRequest) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *CreateScheduleRequest) GetCron() string {
+	if m != nil {
+		return m.Cron
+	}
+	return ""
+}
+
+func (m *CreateScheduleRequest) GetRunAt() int64 {
+	if m != nil {
+		return m.RunAt
+	}
+	return 0
+}
+
+func (m *CreateScheduleRequest) GetTimeZone() string {
+	if m != nil {
+		return m.TimeZone
+	}
+	return ""
+}
+
+type GetScheduleRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetScheduleRequest) Reset()         { *m = GetScheduleRequest{} }
+func (m *GetScheduleRequest) String() string { return proto.CompactTextString(m) }
+func (*GetScheduleRequest) ProtoMessage()    {}
+func (*GetScheduleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{27}
+}
+func (m *GetScheduleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetScheduleRequest.Unmarshal(m, b)
+}
+func (m *GetScheduleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetScheduleRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetScheduleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetScheduleRequest.Merge(dst, src)
+}
+func (m *GetScheduleRequest) XXX_Size() int {
+	return xxx_messageInfo_GetScheduleRequest.Size(m)
+}
+func (m *GetScheduleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetScheduleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetScheduleRequest proto.InternalMessageInfo
+
+func (m *GetScheduleRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type GetSchedulesRequest struct {
+	// Either a switch id or a site id selects the schedules
+	SwitchId             string   `protobuf:"bytes,1,opt,name=switch_id,json=switchId,proto3" json:"switch_id,omitempty"`
+	SiteId               string   `protobuf:"bytes,2,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetSchedulesRequest) Reset()         { *m = GetSchedulesRequest{} }
+func (m *GetSchedulesRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSchedulesRequest) ProtoMessage()    {}
+func (*GetSchedulesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{28}
+}
+func (m *GetSchedulesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSchedulesRequest.Unmarshal(m, b)
+}
+func (m *GetSchedulesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSchedulesRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetSchedulesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSchedulesRequest.Merge(dst, src)
+}
+func (m *GetSchedulesRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSchedulesRequest.Size(m)
+}
+func (m *GetSchedulesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSchedulesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetSchedulesRequest proto.InternalMessageInfo
+
+func (m *GetSchedulesRequest) GetSwitchId() string {
+	if m != nil {
+		return m.SwitchId
+	}
+	return ""
+}
+
+func (m *GetSchedulesRequest) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+type UpdateScheduleRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State                string   `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Cron                 string   `protobuf:"bytes,3,opt,name=cron,proto3" json:"cron,omitempty"`
+	RunAt                int64    `protobuf:"varint,4,opt,name=run_at,json=runAt,proto3" json:"run_at,omitempty"`
+	TimeZone             string   `protobuf:"bytes,5,opt,name=time_zone,json=timeZone,proto3" json:"time_zone,omitempty"`
+	Enabled              bool     `protobuf:"varint,6,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateScheduleRequest) Reset()         { *m = UpdateScheduleRequest{} }
+func (m *UpdateScheduleRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateScheduleRequest) ProtoMessage()    {}
+func (*UpdateScheduleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{29}
+}
+func (m *UpdateScheduleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateScheduleRequest.Unmarshal(m, b)
+}
+func (m *UpdateScheduleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateScheduleRequest.Marshal(b, m, deterministic)
+}
+func (dst *UpdateScheduleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateScheduleRequest.Merge(dst, src)
+}
+func (m *UpdateScheduleRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateScheduleRequest.Size(m)
+}
+func (m *UpdateScheduleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateScheduleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateScheduleRequest proto.InternalMessageInfo
+
+func (m *UpdateScheduleRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *UpdateScheduleRequest) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *UpdateScheduleRequest) GetCron() string {
+	if m != nil {
+		return m.Cron
+	}
+	return ""
+}
+
+func (m *UpdateScheduleRequest) GetRunAt() int64 {
+	if m != nil {
+		return m.RunAt
+	}
+	return 0
+}
+
+func (m *UpdateScheduleRequest) GetTimeZone() string {
+	if m != nil {
+		return m.TimeZone
+	}
+	return ""
+}
+
+func (m *UpdateScheduleRequest) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
+	}
+	return false
+}
+
+type DeleteScheduleRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteScheduleRequest) Reset()         { *m = DeleteScheduleRequest{} }
+func (m *DeleteScheduleRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteScheduleRequest) ProtoMessage()    {}
+func (*DeleteScheduleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{30}
+}
+func (m *DeleteScheduleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteScheduleRequest.Unmarshal(m, b)
+}
+func (m *DeleteScheduleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteScheduleRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteScheduleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteScheduleRequest.Merge(dst, src)
+}
+func (m *DeleteScheduleRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteScheduleRequest.Size(m)
+}
+func (m *DeleteScheduleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteScheduleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteScheduleRequest proto.InternalMessageInfo
+
+func (m *DeleteScheduleRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type DeleteScheduleResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteScheduleResponse) Reset()         { *m = DeleteScheduleResponse{} }
+func (m *DeleteScheduleResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteScheduleResponse) ProtoMessage()    {}
+func (*DeleteScheduleResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{31}
+}
+func (m *DeleteScheduleResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteScheduleResponse.Unmarshal(m, b)
+}
+func (m *DeleteScheduleResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteScheduleResponse.Marshal(b, m, deterministic)
+}
+func (dst *DeleteScheduleResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteScheduleResponse.Merge(dst, src)
+}
+func (m *DeleteScheduleResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteScheduleResponse.Size(m)
+}
+func (m *DeleteScheduleResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteScheduleResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteScheduleResponse proto.InternalMessageInfo
+
+// Group is a named set of switches of a site
+type Group struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	SiteId               string   `protobuf:"bytes,2,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	Name                 string   `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	SwitchIds            []string `protobuf:"bytes,4,rep,name=switch_ids,json=switchIds,proto3" json:"switch_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Group) Reset()         { *m = Group{} }
+func (m *Group) String() string { return proto.CompactTextString(m) }
+func (*Group) ProtoMessage()    {}
+func (*Group) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{32}
+}
+func (m *Group) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Group.Unmarshal(m, b)
+}
+func (m *Group) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Group.Marshal(b, m, deterministic)
+}
+func (dst *Group) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Group.Merge(dst, src)
+}
+func (m *Group) XXX_Size() int {
+	return xxx_messageInfo_Group.Size(m)
+}
+func (m *Group) XXX_DiscardUnknown() {
+	xxx_messageInfo_Group.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Group proto.InternalMessageInfo
+
+func (m *Group) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Group) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+func (m *Group) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Group) GetSwitchIds() []string {
+	if m != nil {
+		return m.SwitchIds
+	}
+	return nil
+}
+
+type CreateGroupRequest struct {
+	SiteId               string   `protobuf:"bytes,1,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	SwitchIds            []string `protobuf:"bytes,3,rep,name=switch_ids,json=switchIds,proto3" json:"switch_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateGroupRequest) Reset()         { *m = CreateGroupRequest{} }
+func (m *CreateGroupRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateGroupRequest) ProtoMessage()    {}
+func (*CreateGroupRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{33}
+}
+func (m *CreateGroupRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateGroupRequest.Unmarshal(m, b)
+}
+func (m *CreateGroupRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateGroupRequest.Marshal(b, m, deterministic)
+}
+func (dst *CreateGroupRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateGroupRequest.Merge(dst, src)
+}
+func (m *CreateGroupRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateGroupRequest.Size(m)
+}
+func (m *CreateGroupRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateGroupRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateGroupRequest proto.InternalMessageInfo
+
+func (m *CreateGroupRequest) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+func (m *CreateGroupRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CreateGroupRequest) GetSwitchIds() []string {
+	if m != nil {
+		return m.SwitchIds
+	}
+	return nil
+}
+
+type GetGroupRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetGroupRequest) Reset()         { *m = GetGroupRequest{} }
+func (m *GetGroupRequest) String() string { return proto.CompactTextString(m) }
+func (*GetGroupRequest) ProtoMessage()    {}
+func (*GetGroupRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{34}
+}
+func (m *GetGroupRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetGroupRequest.Unmarshal(m, b)
+}
+func (m *GetGroupRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetGroupRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetGroupRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetGroupRequest.Merge(dst, src)
+}
+func (m *GetGroupRequest) XXX_Size() int {
+	return xxx_messageInfo_GetGroupRequest.Size(m)
+}
+func (m *GetGroupRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetGroupRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetGroupRequest proto.InternalMessageInfo
+
+func (m *GetGroupRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type GetGroupsRequest struct {
+	SiteId               string   `protobuf:"bytes,1,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetGroupsRequest) Reset()         { *m = GetGroupsRequest{} }
+func (m *GetGroupsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetGroupsRequest) ProtoMessage()    {}
+func (*GetGroupsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{35}
+}
+func (m *GetGroupsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetGroupsRequest.Unmarshal(m, b)
+}
+func (m *GetGroupsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetGroupsRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetGroupsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetGroupsRequest.Merge(dst, src)
+}
+func (m *GetGroupsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetGroupsRequest.Size(m)
+}
+func (m *GetGroupsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetGroupsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetGroupsRequest proto.InternalMessageInfo
+
+func (m *GetGroupsRequest) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+type UpdateGroupRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	SwitchIds            []string `protobuf:"bytes,3,rep,name=switch_ids,json=switchIds,proto3" json:"switch_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateGroupRequest) Reset()         { *m = UpdateGroupRequest{} }
+func (m *UpdateGroupRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateGroupRequest) ProtoMessage()    {}
+func (*UpdateGroupRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{36}
+}
+func (m *UpdateGroupRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateGroupRequest.Unmarshal(m, b)
+}
+func (m *UpdateGroupRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateGroupRequest.Marshal(b, m, deterministic)
+}
+func (dst *UpdateGroupRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateGroupRequest.Merge(dst, src)
+}
+func (m *UpdateGroupRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateGroupRequest.Size(m)
+}
+func (m *UpdateGroupRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateGroupRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateGroupRequest proto.InternalMessageInfo
+
+func (m *UpdateGroupRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *UpdateGroupRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *UpdateGroupRequest) GetSwitchIds() []string {
+	if m != nil {
+		return m.SwitchIds
+	}
+	return nil
+}
+
+type DeleteGroupRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteGroupRequest) Reset()         { *m = DeleteGroupRequest{} }
+func (m *DeleteGroupRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteGroupRequest) ProtoMessage()    {}
+func (*DeleteGroupRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{37}
+}
+func (m *DeleteGroupRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteGroupRequest.Unmarshal(m, b)
+}
+func (m *DeleteGroupRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteGroupRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteGroupRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteGroupRequest.Merge(dst, src)
+}
+func (m *DeleteGroupRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteGroupRequest.Size(m)
+}
+func (m *DeleteGroupRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteGroupRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteGroupRequest proto.InternalMessageInfo
+
+func (m *DeleteGroupRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type DeleteGroupResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteGroupResponse) Reset()         { *m = DeleteGroupResponse{} }
+func (m *DeleteGroupResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteGroupResponse) ProtoMessage()    {}
+func (*DeleteGroupResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{38}
+}
+func (m *DeleteGroupResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteGroupResponse.Unmarshal(m, b)
+}
+func (m *DeleteGroupResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteGroupResponse.Marshal(b, m, deterministic)
+}
+func (dst *DeleteGroupResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteGroupResponse.Merge(dst, src)
+}
+func (m *DeleteGroupResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteGroupResponse.Size(m)
+}
+func (m *DeleteGroupResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteGroupResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteGroupResponse proto.InternalMessageInfo
+
+// Scene is a named preset of the states of switches of a site
+type Scene struct {
+	Id     string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	SiteId string `protobuf:"bytes,2,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	Name   string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	// The target state by switch id
+	States               map[string]string `protobuf:"bytes,4,rep,name=states,proto3" json:"states,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Scene) Reset()         { *m = Scene{} }
+func (m *Scene) String() string { return proto.CompactTextString(m) }
+func (*Scene) ProtoMessage()    {}
+func (*Scene) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{39}
+}
+func (m *Scene) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Scene.Unmarshal(m, b)
+}
+func (m *Scene) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Scene.Marshal(b, m, deterministic)
+}
+func (dst *Scene) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Scene.Merge(dst, src)
+}
+func (m *Scene) XXX_Size() int {
+	return xxx_messageInfo_Scene.Size(m)
+}
+func (m *Scene) XXX_DiscardUnknown() {
+	xxx_messageInfo_Scene.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Scene proto.InternalMessageInfo
+
+func (m *Scene) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Scene) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+func (m *Scene) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Scene) GetStates() map[string]string {
+	if m != nil {
+		return m.States
+	}
+	return nil
+}
+
+type CreateSceneRequest struct {
+	SiteId               string            `protobuf:"bytes,1,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	Name                 string            `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	States               map[string]string `protobuf:"bytes,3,rep,name=states,proto3" json:"states,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *CreateSceneRequest) Reset()         { *m = CreateSceneRequest{} }
+func (m *CreateSceneRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateSceneRequest) ProtoMessage()    {}
+func (*CreateSceneRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{40}
+}
+func (m *CreateSceneRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateSceneRequest.Unmarshal(m, b)
+}
+func (m *CreateSceneRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateSceneRequest.Marshal(b, m, deterministic)
+}
+func (dst *CreateSceneRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateSceneRequest.Merge(dst, src)
+}
+func (m *CreateSceneRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateSceneRequest.Size(m)
+}
+func (m *CreateSceneRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateSceneRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateSceneRequest proto.InternalMessageInfo
+
+func (m *CreateSceneRequest) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+func (m *CreateSceneRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CreateSceneRequest) GetStates() map[string]string {
+	if m != nil {
+		return m.States
+	}
+	return nil
+}
+
+type GetSceneRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetSceneRequest) Reset()         { *m = GetSceneRequest{} }
+func (m *GetSceneRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSceneRequest) ProtoMessage()    {}
+func (*GetSceneRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{41}
+}
+func (m *GetSceneRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSceneRequest.Unmarshal(m, b)
+}
+func (m *GetSceneRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSceneRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetSceneRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSceneRequest.Merge(dst, src)
+}
+func (m *GetSceneRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSceneRequest.Size(m)
+}
+func (m *GetSceneRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSceneRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetSceneRequest proto.InternalMessageInfo
+
+func (m *GetSceneRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type GetScenesRequest struct {
+	SiteId               string   `protobuf:"bytes,1,opt,name=site_id,json=siteId,proto3" json:"site_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetScenesRequest) Reset()         { *m = GetScenesRequest{} }
+func (m *GetScenesRequest) String() string { return proto.CompactTextString(m) }
+func (*GetScenesRequest) ProtoMessage()    {}
+func (*GetScenesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{42}
+}
+func (m *GetScenesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetScenesRequest.Unmarshal(m, b)
+}
+func (m *GetScenesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetScenesRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetScenesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetScenesRequest.Merge(dst, src)
+}
+func (m *GetScenesRequest) XXX_Size() int {
+	return xxx_messageInfo_GetScenesRequest.Size(m)
+}
+func (m *GetScenesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetScenesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetScenesRequest proto.InternalMessageInfo
+
+func (m *GetScenesRequest) GetSiteId() string {
+	if m != nil {
+		return m.SiteId
+	}
+	return ""
+}
+
+type UpdateSceneRequest struct {
+	Id                   string            `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string            `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	States               map[string]string `protobuf:"bytes,3,rep,name=states,proto3" json:"states,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *UpdateSceneRequest) Reset()         { *m = UpdateSceneRequest{} }
+func (m *UpdateSceneRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateSceneRequest) ProtoMessage()    {}
+func (*UpdateSceneRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{43}
+}
+func (m *UpdateSceneRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateSceneRequest.Unmarshal(m, b)
+}
+func (m *UpdateSceneRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateSceneRequest.Marshal(b, m, deterministic)
+}
+func (dst *UpdateSceneRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateSceneRequest.Merge(dst, src)
+}
+func (m *UpdateSceneRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateSceneRequest.Size(m)
+}
+func (m *UpdateSceneRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateSceneRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateSceneRequest proto.InternalMessageInfo
+
+func (m *UpdateSceneRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *UpdateSceneRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *UpdateSceneRequest) GetStates() map[string]string {
+	if m != nil {
+		return m.States
+	}
+	return nil
+}
+
+type DeleteSceneRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteSceneRequest) Reset()         { *m = DeleteSceneRequest{} }
+func (m *DeleteSceneRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteSceneRequest) ProtoMessage()    {}
+func (*DeleteSceneRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{44}
+}
+func (m *DeleteSceneRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteSceneRequest.Unmarshal(m, b)
+}
+func (m *DeleteSceneRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteSceneRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteSceneRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteSceneRequest.Merge(dst, src)
+}
+func (m *DeleteSceneRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteSceneRequest.Size(m)
+}
+func (m *DeleteSceneRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteSceneRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteSceneRequest proto.InternalMessageInfo
+
+func (m *DeleteSceneRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type DeleteSceneResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteSceneResponse) Reset()         { *m = DeleteSceneResponse{} }
+func (m *DeleteSceneResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteSceneResponse) ProtoMessage()    {}
+func (*DeleteSceneResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{45}
+}
+func (m *DeleteSceneResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteSceneResponse.Unmarshal(m, b)
+}
+func (m *DeleteSceneResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteSceneResponse.Marshal(b, m, deterministic)
+}
+func (dst *DeleteSceneResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteSceneResponse.Merge(dst, src)
+}
+func (m *DeleteSceneResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteSceneResponse.Size(m)
+}
+func (m *DeleteSceneResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteSceneResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteSceneResponse proto.InternalMessageInfo
+
+type ApplySceneRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Why the states are changed, kept in the switch history
+	Reason               string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ApplySceneRequest) Reset()         { *m = ApplySceneRequest{} }
+func (m *ApplySceneRequest) String() string { return proto.CompactTextString(m) }
+func (*ApplySceneRequest) ProtoMessage()    {}
+func (*ApplySceneRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{46}
+}
+func (m *ApplySceneRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ApplySceneRequest.Unmarshal(m, b)
+}
+func (m *ApplySceneRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ApplySceneRequest.Marshal(b, m, deterministic)
+}
+func (dst *ApplySceneRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApplySceneRequest.Merge(dst, src)
+}
+func (m *ApplySceneRequest) XXX_Size() int {
+	return xxx_messageInfo_ApplySceneRequest.Size(m)
+}
+func (m *ApplySceneRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApplySceneRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApplySceneRequest proto.InternalMessageInfo
+
+func (m *ApplySceneRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *ApplySceneRequest) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type ApplySceneResponse struct {
+	// One result per switch of the scene, ordered by switch id
+	Results []*SwitchResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	// The switches already in their target state, which are left unchanged
+	UnchangedIds         []string `protobuf:"bytes,2,rep,name=unchanged_ids,json=unchangedIds,proto3" json:"unchanged_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ApplySceneResponse) Reset()         { *m = ApplySceneResponse{} }
+func (m *ApplySceneResponse) String() string { return proto.CompactTextString(m) }
+func (*ApplySceneResponse) ProtoMessage()    {}
+func (*ApplySceneResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_switch_d70532c4b3ba90b7, []int{47}
+}
+func (m *ApplySceneResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ApplySceneResponse.Unmarshal(m, b)
+}
+func (m *ApplySceneResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ApplySceneResponse.Marshal(b, m, deterministic)
+}
+func (dst *ApplySceneResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApplySceneResponse.Merge(dst, src)
+}
+func (m *ApplySceneResponse) XXX_Size() int {
+	return xxx_messageInfo_ApplySceneResponse.Size(m)
+}
+func (m *ApplySceneResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApplySceneResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApplySceneResponse proto.InternalMessageInfo
+
+func (m *ApplySceneResponse) GetResults() []*SwitchResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+func (m *ApplySceneResponse) GetUnchangedIds() []string {
+	if m != nil {
+		return m.UnchangedIds
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Switch)(nil), "proto.Switch")
+	proto.RegisterType((*Transition)(nil), "proto.Transition")
+	proto.RegisterType((*Interlock)(nil), "proto.Interlock")
+	proto.RegisterType((*InstallSwitchRequest)(nil), "proto.InstallSwitchRequest")
+	proto.RegisterType((*RemoveSwitchRequest)(nil), "proto.RemoveSwitchRequest")
+	proto.RegisterType((*RemoveSwitchResponse)(nil), "proto.RemoveSwitchResponse")
+	proto.RegisterType((*GetSwitchRequest)(nil), "proto.GetSwitchRequest")
+	proto.RegisterType((*GetSwitchesRequest)(nil), "proto.GetSwitchesRequest")
+	proto.RegisterType((*GetAllowedTransitionsRequest)(nil), "proto.GetAllowedTransitionsRequest")
+	proto.RegisterType((*GetAllowedTransitionsResponse)(nil), "proto.GetAllowedTransitionsResponse")
+	proto.RegisterType((*UpdateSwitchRequest)(nil), "proto.UpdateSwitchRequest")
+	proto.RegisterType((*SetSwitchRequest)(nil), "proto.SetSwitchRequest")
+	proto.RegisterType((*SetSwitchResponse)(nil), "proto.SetSwitchResponse")
+	proto.RegisterType((*ReportSwitchStateRequest)(nil), "proto.ReportSwitchStateRequest")
+	proto.RegisterType((*SetSwitchesRequest)(nil), "proto.SetSwitchesRequest")
+	proto.RegisterType((*SetSwitchesResponse)(nil), "proto.SetSwitchesResponse")
+	proto.RegisterType((*InstallSwitchesRequest)(nil), "proto.InstallSwitchesRequest")
+	proto.RegisterType((*InstallSwitchesResponse)(nil), "proto.InstallSwitchesResponse")
+	proto.RegisterType((*SwitchResult)(nil), "proto.SwitchResult")
+	proto.RegisterType((*WatchSwitchesRequest)(nil), "proto.WatchSwitchesRequest")
+	proto.RegisterType((*SwitchEvent)(nil), "proto.SwitchEvent")
+	proto.RegisterType((*SwitchStateChange)(nil), "proto.SwitchStateChange")
+	proto.RegisterType((*GetSwitchHistoryRequest)(nil), "proto.GetSwitchHistoryRequest")
+	proto.RegisterType((*GetSwitchHistoryResponse)(nil), "proto.GetSwitchHistoryResponse")
+	proto.RegisterType((*Schedule)(nil), "proto.Schedule")
+	proto.RegisterType((*ScheduleRun)(nil), "proto.ScheduleRun")
+	proto.RegisterType((*CreateScheduleRequest)(nil), "proto.CreateScheduleRequest")
+	proto.RegisterType((*GetScheduleRequest)(nil), "proto.GetScheduleRequest")
+	proto.RegisterType((*GetSchedulesRequest)(nil), "proto.GetSchedulesRequest")
+	proto.RegisterType((*UpdateScheduleRequest)(nil), "proto.UpdateScheduleRequest")
+	proto.RegisterType((*DeleteScheduleRequest)(nil), "proto.DeleteScheduleRequest")
+	proto.RegisterType((*DeleteScheduleResponse)(nil), "proto.DeleteScheduleResponse")
+	proto.RegisterType((*Group)(nil), "proto.Group")
+	proto.RegisterType((*CreateGroupRequest)(nil), "proto.CreateGroupRequest")
+	proto.RegisterType((*GetGroupRequest)(nil), "proto.GetGroupRequest")
+	proto.RegisterType((*GetGroupsRequest)(nil), "proto.GetGroupsRequest")
+	proto.RegisterType((*UpdateGroupRequest)(nil), "proto.UpdateGroupRequest")
+	proto.RegisterType((*DeleteGroupRequest)(nil), "proto.DeleteGroupRequest")
+	proto.RegisterType((*DeleteGroupResponse)(nil), "proto.DeleteGroupResponse")
+	proto.RegisterType((*Scene)(nil), "proto.Scene")
+	proto.RegisterMapType((map[string]string)(nil), "proto.Scene.StatesEntry")
+	proto.RegisterType((*CreateSceneRequest)(nil), "proto.CreateSceneRequest")
+	proto.RegisterMapType((map[string]string)(nil), "proto.CreateSceneRequest.StatesEntry")
+	proto.RegisterType((*GetSceneRequest)(nil), "proto.GetSceneRequest")
+	proto.RegisterType((*GetScenesRequest)(nil), "proto.GetScenesRequest")
+	proto.RegisterType((*UpdateSceneRequest)(nil), "proto.UpdateSceneRequest")
+	proto.RegisterMapType((map[string]string)(nil), "proto.UpdateSceneRequest.StatesEntry")
+	proto.RegisterType((*DeleteSceneRequest)(nil), "proto.DeleteSceneRequest")
+	proto.RegisterType((*DeleteSceneResponse)(nil), "proto.DeleteSceneResponse")
+	proto.RegisterType((*ApplySceneRequest)(nil), "proto.ApplySceneRequest")
+	proto.RegisterType((*ApplySceneResponse)(nil), "proto.ApplySceneResponse")
+	proto.RegisterEnum("proto.BulkMode", BulkMode_name, BulkMode_value)
+	proto.RegisterEnum("proto.Interlock_Operator", Interlock_Operator_name, Interlock_Operator_value)
+	proto.RegisterEnum("proto.Interlock_Limit", Interlock_Limit_name, Interlock_Limit_value)
+	proto.RegisterEnum("proto.GetSwitchesRequest_SortBy", GetSwitchesRequest_SortBy_name, GetSwitchesRequest_SortBy_value)
+	proto.RegisterEnum("proto.SwitchEvent_Type", SwitchEvent_Type_name, SwitchEvent_Type_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// SwitchServiceClient is the client API for SwitchService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type SwitchServiceClient interface {
+	InstallSwitch(ctx context.Context, in *InstallSwitchRequest, opts ...grpc.CallOption) (*Switch, error)
+	RemoveSwitch(ctx context.Context, in *RemoveSwitchRequest, opts ...grpc.CallOption) (*RemoveSwitchResponse, error)
+	GetSwitch(ctx context.Context, in *GetSwitchRequest, opts ...grpc.CallOption) (*Switch, error)
+	GetSwitches(ctx context.Context, in *GetSwitchesRequest, opts ...grpc.CallOption) (SwitchService_GetSwitchesClient, error)
+	SetSwitch(ctx context.Context, in *SetSwitchRequest, opts ...grpc.CallOption) (*SetSwitchResponse, error)
+	// Device agents report the state the device is actually in
+	ReportSwitchState(ctx context.Context, in *ReportSwitchStateRequest, opts ...grpc.CallOption) (*Switch, error)
+	UpdateSwitch(ctx context.Context, in *UpdateSwitchRequest, opts ...grpc.CallOption) (*Switch, error)
+	GetAllowedTransitions(ctx context.Context, in *GetAllowedTransitionsRequest, opts ...grpc.CallOption) (*GetAllowedTransitionsResponse, error)
+	SetSwitches(ctx context.Context, in *SetSwitchesRequest, opts ...grpc.CallOption) (*SetSwitchesResponse, error)
+	InstallSwitches(ctx context.Context, opts ...grpc.CallOption) (SwitchService_InstallSwitchesClient, error)
+	WatchSwitches(ctx context.Context, in *WatchSwitchesRequest, opts ...grpc.CallOption) (SwitchService_WatchSwitchesClient, error)
+	GetSwitchHistory(ctx context.Context, in *GetSwitchHistoryRequest, opts ...grpc.CallOption) (*GetSwitchHistoryResponse, error)
+	CreateSchedule(ctx context.Context, in *CreateScheduleRequest, opts ...grpc.CallOption) (*Schedule, error)
+	GetSchedule(ctx context.Context, in *GetScheduleRequest, opts ...grpc.CallOption) (*Schedule, error)
+	GetSchedules(ctx context.Context, in *GetSchedulesRequest, opts ...grpc.CallOption) (SwitchService_GetSchedulesClient, error)
+	UpdateSchedule(ctx context.Context, in *UpdateScheduleRequest, opts ...grpc.CallOption) (*Schedule, error)
+	DeleteSchedule(ctx context.Context, in *DeleteScheduleRequest, opts ...grpc.CallOption) (*DeleteScheduleResponse, error)
+	CreateGroup(ctx context.Context, in *CreateGroupRequest, opts ...grpc.CallOption) (*Group, error)
+	GetGroup(ctx context.Context, in *GetGroupRequest, opts ...grpc.CallOption) (*Group, error)
+	GetGroups(ctx context.Context, in *GetGroupsRequest, opts ...grpc.CallOption) (SwitchService_GetGroupsClient, error)
+	UpdateGroup(ctx context.Context, in *UpdateGroupRequest, opts ...grpc.CallOption) (*Group, error)
+	DeleteGroup(ctx context.Context, in *DeleteGroupRequest, opts ...grpc.CallOption) (*DeleteGroupResponse, error)
+	CreateScene(ctx context.Context, in *CreateSceneRequest, opts ...grpc.CallOption) (*Scene, error)
+	GetScene(ctx context.Context, in *GetSceneRequest, opts ...grpc.CallOption) (*Scene, error)
+	GetScenes(ctx context.Context, in *GetScenesRequest, opts ...grpc.CallOption) (SwitchService_GetScenesClient, error)
+	UpdateScene(ctx context.Context, in *UpdateSceneRequest, opts ...grpc.CallOption) (*Scene, error)
+	DeleteScene(ctx context.Context, in *DeleteSceneRequest, opts ...grpc.CallOption) (*DeleteSceneResponse, error)
+	ApplyScene(ctx context.Context, in *ApplySceneRequest, opts ...grpc.CallOption) (*ApplySceneResponse, error)
+}
+
+type switchServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewSwitchServiceClient(cc *grpc.ClientConn) SwitchServiceClient {
+	return &switchServiceClient{cc}
+}
+
+func (c *switchServiceClient) InstallSwitch(ctx context.Context, in *InstallSwitchRequest, opts ...grpc.CallOption) (*Switch, error) {
+	out := new(Switch)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/InstallSwitch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) RemoveSwitch(ctx context.Context, in *RemoveSwitchRequest, opts ...grpc.CallOption) (*RemoveSwitchResponse, error) {
+	out := new(RemoveSwitchResponse)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/RemoveSwitch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) GetSwitch(ctx context.Context, in *GetSwitchRequest, opts ...grpc.CallOption) (*Switch, error) {
+	out := new(Switch)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/GetSwitch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) GetSwitches(ctx context.Context, in *GetSwitchesRequest, opts ...grpc.CallOption) (SwitchService_GetSwitchesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_SwitchService_serviceDesc.Streams[0], "/proto.SwitchService/GetSwitches", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &switchServiceGetSwitchesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SwitchService_GetSwitchesClient interface {
+	Recv() (*Switch, error)
+	grpc.ClientStream
+}
+
+type switchServiceGetSwitchesClient struct {
+	grpc.ClientStream
+}
+
+func (x *switchServiceGetSwitchesClient) Recv() (*Switch, error) {
+	m := new(Switch)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *switchServiceClient) SetSwitch(ctx context.Context, in *SetSwitchRequest, opts ...grpc.CallOption) (*SetSwitchResponse, error) {
+	out := new(SetSwitchResponse)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/SetSwitch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) ReportSwitchState(ctx context.Context, in *ReportSwitchStateRequest, opts ...grpc.CallOption) (*Switch, error) {
+	out := new(Switch)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/ReportSwitchState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) UpdateSwitch(ctx context.Context, in *UpdateSwitchRequest, opts ...grpc.CallOption) (*Switch, error) {
+	out := new(Switch)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/UpdateSwitch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) GetAllowedTransitions(ctx context.Context, in *GetAllowedTransitionsRequest, opts ...grpc.CallOption) (*GetAllowedTransitionsResponse, error) {
+	out := new(GetAllowedTransitionsResponse)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/GetAllowedTransitions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) SetSwitches(ctx context.Context, in *SetSwitchesRequest, opts ...grpc.CallOption) (*SetSwitchesResponse, error) {
+	out := new(SetSwitchesResponse)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/SetSwitches", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) InstallSwitches(ctx context.Context, opts ...grpc.CallOption) (SwitchService_InstallSwitchesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_SwitchService_serviceDesc.Streams[1], "/proto.SwitchService/InstallSwitches", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &switchServiceInstallSwitchesClient{stream}
+	return x, nil
+}
+
+type SwitchService_InstallSwitchesClient interface {
+	Send(*InstallSwitchesRequest) error
+	CloseAndRecv() (*InstallSwitchesResponse, error)
+	grpc.ClientStream
+}
+
+type switchServiceInstallSwitchesClient struct {
+	grpc.ClientStream
+}
+
+func (x *switchServiceInstallSwitchesClient) Send(m *InstallSwitchesRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *switchServiceInstallSwitchesClient) CloseAndRecv() (*InstallSwitchesResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(InstallSwitchesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *switchServiceClient) WatchSwitches(ctx context.Context, in *WatchSwitchesRequest, opts ...grpc.CallOption) (SwitchService_WatchSwitchesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_SwitchService_serviceDesc.Streams[2], "/proto.SwitchService/WatchSwitches", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &switchServiceWatchSwitchesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SwitchService_WatchSwitchesClient interface {
+	Recv() (*SwitchEvent, error)
+	grpc.ClientStream
+}
+
+type switchServiceWatchSwitchesClient struct {
+	grpc.ClientStream
+}
+
+func (x *switchServiceWatchSwitchesClient) Recv() (*SwitchEvent, error) {
+	m := new(SwitchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *switchServiceClient) GetSwitchHistory(ctx context.Context, in *GetSwitchHistoryRequest, opts ...grpc.CallOption) (*GetSwitchHistoryResponse, error) {
+	out := new(GetSwitchHistoryResponse)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/GetSwitchHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) CreateSchedule(ctx context.Context, in *CreateScheduleRequest, opts ...grpc.CallOption) (*Schedule, error) {
+	out := new(Schedule)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/CreateSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) GetSchedule(ctx context.Context, in *GetScheduleRequest, opts ...grpc.CallOption) (*Schedule, error) {
+	out := new(Schedule)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/GetSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) GetSchedules(ctx context.Context, in *GetSchedulesRequest, opts ...grpc.CallOption) (SwitchService_GetSchedulesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_SwitchService_serviceDesc.Streams[3], "/proto.SwitchService/GetSchedules", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &switchServiceGetSchedulesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SwitchService_GetSchedulesClient interface {
+	Recv() (*Schedule, error)
+	grpc.ClientStream
+}
+
+type switchServiceGetSchedulesClient struct {
+	grpc.ClientStream
+}
+
+func (x *switchServiceGetSchedulesClient) Recv() (*Schedule, error) {
+	m := new(Schedule)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *switchServiceClient) UpdateSchedule(ctx context.Context, in *UpdateScheduleRequest, opts ...grpc.CallOption) (*Schedule, error) {
+	out := new(Schedule)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/UpdateSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) DeleteSchedule(ctx context.Context, in *DeleteScheduleRequest, opts ...grpc.CallOption) (*DeleteScheduleResponse, error) {
+	out := new(DeleteScheduleResponse)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/DeleteSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) CreateGroup(ctx context.Context, in *CreateGroupRequest, opts ...grpc.CallOption) (*Group, error) {
+	out := new(Group)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/CreateGroup", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) GetGroup(ctx context.Context, in *GetGroupRequest, opts ...grpc.CallOption) (*Group, error) {
+	out := new(Group)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/GetGroup", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) GetGroups(ctx context.Context, in *GetGroupsRequest, opts ...grpc.CallOption) (SwitchService_GetGroupsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_SwitchService_serviceDesc.Streams[4], "/proto.SwitchService/GetGroups", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &switchServiceGetGroupsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SwitchService_GetGroupsClient interface {
+	Recv() (*Group, error)
+	grpc.ClientStream
+}
+
+type switchServiceGetGroupsClient struct {
+	grpc.ClientStream
+}
+
+func (x *switchServiceGetGroupsClient) Recv() (*Group, error) {
+	m := new(Group)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *switchServiceClient) UpdateGroup(ctx context.Context, in *UpdateGroupRequest, opts ...grpc.CallOption) (*Group, error) {
+	out := new(Group)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/UpdateGroup", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) DeleteGroup(ctx context.Context, in *DeleteGroupRequest, opts ...grpc.CallOption) (*DeleteGroupResponse, error) {
+	out := new(DeleteGroupResponse)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/DeleteGroup", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) CreateScene(ctx context.Context, in *CreateSceneRequest, opts ...grpc.CallOption) (*Scene, error) {
+	out := new(Scene)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/CreateScene", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) GetScene(ctx context.Context, in *GetSceneRequest, opts ...grpc.CallOption) (*Scene, error) {
+	out := new(Scene)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/GetScene", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) GetScenes(ctx context.Context, in *GetScenesRequest, opts ...grpc.CallOption) (SwitchService_GetScenesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_SwitchService_serviceDesc.Streams[5], "/proto.SwitchService/GetScenes", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &switchServiceGetScenesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SwitchService_GetScenesClient interface {
+	Recv() (*Scene, error)
+	grpc.ClientStream
+}
+
+type switchServiceGetScenesClient struct {
+	grpc.ClientStream
+}
+
+func (x *switchServiceGetScenesClient) Recv() (*Scene, error) {
+	m := new(Scene)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *switchServiceClient) UpdateScene(ctx context.Context, in *UpdateSceneRequest, opts ...grpc.CallOption) (*Scene, error) {
+	out := new(Scene)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/UpdateScene", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) DeleteScene(ctx context.Context, in *DeleteSceneRequest, opts ...grpc.CallOption) (*DeleteSceneResponse, error) {
+	out := new(DeleteSceneResponse)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/DeleteScene", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchServiceClient) ApplyScene(ctx context.Context, in *ApplySceneRequest, opts ...grpc.CallOption) (*ApplySceneResponse, error) {
+	out := new(ApplySceneResponse)
+	err := c.cc.Invoke(ctx, "/proto.SwitchService/ApplyScene", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SwitchServiceServer is the server API for SwitchService service.
+type SwitchServiceServer interface {
+	InstallSwitch(context.Context, *InstallSwitchRequest) (*Switch, error)
+	RemoveSwitch(context.Context, *RemoveSwitchRequest) (*RemoveSwitchResponse, error)
+	GetSwitch(context.Context, *GetSwitchRequest) (*Switch, error)
+	GetSwitches(*GetSwitchesRequest, SwitchService_GetSwitchesServer) error
+	SetSwitch(context.Context, *SetSwitchRequest) (*SetSwitchResponse, error)
+	// Device agents report the state the device is actually in
+	ReportSwitchState(context.Context, *ReportSwitchStateRequest) (*Switch, error)
+	UpdateSwitch(context.Context, *UpdateSwitchRequest) (*Switch, error)
+	GetAllowedTransitions(context.Context, *GetAllowedTransitionsRequest) (*GetAllowedTransitionsResponse, error)
+	SetSwitches(context.Context, *SetSwitchesRequest) (*SetSwitchesResponse, error)
+	InstallSwitches(SwitchService_InstallSwitchesServer) error
+	WatchSwitches(*WatchSwitchesRequest, SwitchService_WatchSwitchesServer) error
+	GetSwitchHistory(context.Context, *GetSwitchHistoryRequest) (*GetSwitchHistoryResponse, error)
+	CreateSchedule(context.Context, *CreateScheduleRequest) (*Schedule, error)
+	GetSchedule(context.Context, *GetScheduleRequest) (*Schedule, error)
+	GetSchedules(*GetSchedulesRequest, SwitchService_GetSchedulesServer) error
+	UpdateSchedule(context.Context, *UpdateScheduleRequest) (*Schedule, error)
+	DeleteSchedule(context.Context, *DeleteScheduleRequest) (*DeleteScheduleResponse, error)
+	CreateGroup(context.Context, *CreateGroupRequest) (*Group, error)
+	GetGroup(context.Context, *GetGroupRequest) (*Group, error)
+	GetGroups(*GetGroupsRequest, SwitchService_GetGroupsServer) error
+	UpdateGroup(context.Context, *UpdateGroupRequest) (*Group, error)
+	DeleteGroup(context.Context, *DeleteGroupRequest) (*DeleteGroupResponse, error)
+	CreateScene(context.Context, *CreateSceneRequest) (*Scene, error)
+	GetScene(context.Context, *GetSceneRequest) (*Scene, error)
+	GetScenes(*GetScenesRequest, SwitchService_GetScenesServer) error
+	UpdateScene(context.Context, *UpdateSceneRequest) (*Scene, error)
+	DeleteScene(context.Context, *DeleteSceneRequest) (*DeleteSceneResponse, error)
+	ApplyScene(context.Context, *ApplySceneRequest) (*ApplySceneResponse, error)
+}
+
+func RegisterSwitchServiceServer(s *grpc.Server, srv SwitchServiceServer) {
+	s.RegisterService(&_SwitchService_serviceDesc, srv)
+}
+
+func _SwitchService_InstallSwitch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstallSwitchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).InstallSwitch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/InstallSwitch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).InstallSwitch(ctx, req.(*InstallSwitchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_RemoveSwitch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveSwitchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).RemoveSwitch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/RemoveSwitch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).RemoveSwitch(ctx, req.(*RemoveSwitchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_GetSwitch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSwitchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).GetSwitch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/GetSwitch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).GetSwitch(ctx, req.(*GetSwitchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_GetSwitches_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetSwitchesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SwitchServiceServer).GetSwitches(m, &switchServiceGetSwitchesServer{stream})
+}
+
+type SwitchService_GetSwitchesServer interface {
+	Send(*Switch) error
+	grpc.ServerStream
+}
+
+type switchServiceGetSwitchesServer struct {
+	grpc.ServerStream
+}
+
+func (x *switchServiceGetSwitchesServer) Send(m *Switch) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SwitchService_SetSwitch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetSwitchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).SetSwitch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/SetSwitch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).SetSwitch(ctx, req.(*SetSwitchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_ReportSwitchState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportSwitchStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).ReportSwitchState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/ReportSwitchState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).ReportSwitchState(ctx, req.(*ReportSwitchStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_UpdateSwitch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateSwitchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).UpdateSwitch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/UpdateSwitch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).UpdateSwitch(ctx, req.(*UpdateSwitchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_GetAllowedTransitions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllowedTransitionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).GetAllowedTransitions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/GetAllowedTransitions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).GetAllowedTransitions(ctx, req.(*GetAllowedTransitionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_SetSwitches_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetSwitchesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).SetSwitches(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/SetSwitches",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).SetSwitches(ctx, req.(*SetSwitchesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_InstallSwitches_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SwitchServiceServer).InstallSwitches(&switchServiceInstallSwitchesServer{stream})
+}
+
+type SwitchService_InstallSwitchesServer interface {
+	SendAndClose(*InstallSwitchesResponse) error
+	Recv() (*InstallSwitchesRequest, error)
+	grpc.ServerStream
+}
+
+type switchServiceInstallSwitchesServer struct {
+	grpc.ServerStream
+}
+
+func (x *switchServiceInstallSwitchesServer) SendAndClose(m *InstallSwitchesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *switchServiceInstallSwitchesServer) Recv() (*InstallSwitchesRequest, error) {
+	m := new(InstallSwitchesRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _SwitchService_WatchSwitches_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchSwitchesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SwitchServiceServer).WatchSwitches(m, &switchServiceWatchSwitchesServer{stream})
+}
+
+type SwitchService_WatchSwitchesServer interface {
+	Send(*SwitchEvent) error
+	grpc.ServerStream
+}
+
+type switchServiceWatchSwitchesServer struct {
+	grpc.ServerStream
+}
+
+func (x *switchServiceWatchSwitchesServer) Send(m *SwitchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SwitchService_GetSwitchHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSwitchHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).GetSwitchHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/GetSwitchHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).GetSwitchHistory(ctx, req.(*GetSwitchHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_CreateSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).CreateSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/CreateSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).CreateSchedule(ctx, req.(*CreateScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_GetSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).GetSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/GetSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).GetSchedule(ctx, req.(*GetScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_GetSchedules_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetSchedulesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SwitchServiceServer).GetSchedules(m, &switchServiceGetSchedulesServer{stream})
+}
+
+type SwitchService_GetSchedulesServer interface {
+	Send(*Schedule) error
+	grpc.ServerStream
+}
+
+type switchServiceGetSchedulesServer struct {
+	grpc.ServerStream
+}
+
+func (x *switchServiceGetSchedulesServer) Send(m *Schedule) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SwitchService_UpdateSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).UpdateSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/UpdateSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).UpdateSchedule(ctx, req.(*UpdateScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_DeleteSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).DeleteSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/DeleteSchedule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).DeleteSchedule(ctx, req.(*DeleteScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_CreateGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateGroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).CreateGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/CreateGroup",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).CreateGroup(ctx, req.(*CreateGroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_GetGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).GetGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/GetGroup",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).GetGroup(ctx, req.(*GetGroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_GetGroups_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetGroupsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SwitchServiceServer).GetGroups(m, &switchServiceGetGroupsServer{stream})
+}
+
+type SwitchService_GetGroupsServer interface {
+	Send(*Group) error
+	grpc.ServerStream
+}
+
+type switchServiceGetGroupsServer struct {
+	grpc.ServerStream
+}
+
+func (x *switchServiceGetGroupsServer) Send(m *Group) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SwitchService_UpdateGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateGroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).UpdateGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/UpdateGroup",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).UpdateGroup(ctx, req.(*UpdateGroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_DeleteGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteGroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).DeleteGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/DeleteGroup",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).DeleteGroup(ctx, req.(*DeleteGroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_CreateScene_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSceneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).CreateScene(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/CreateScene",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).CreateScene(ctx, req.(*CreateSceneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_GetScene_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSceneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).GetScene(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/GetScene",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).GetScene(ctx, req.(*GetSceneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_GetScenes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetScenesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SwitchServiceServer).GetScenes(m, &switchServiceGetScenesServer{stream})
+}
+
+type SwitchService_GetScenesServer interface {
+	Send(*Scene) error
+	grpc.ServerStream
+}
+
+type switchServiceGetScenesServer struct {
+	grpc.ServerStream
+}
+
+func (x *switchServiceGetScenesServer) Send(m *Scene) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SwitchService_UpdateScene_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateSceneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).UpdateScene(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/UpdateScene",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).UpdateScene(ctx, req.(*UpdateSceneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_DeleteScene_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSceneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).DeleteScene(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/DeleteScene",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).DeleteScene(ctx, req.(*DeleteSceneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchService_ApplyScene_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplySceneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServiceServer).ApplyScene(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.SwitchService/ApplyScene",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServiceServer).ApplyScene(ctx, req.(*ApplySceneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _SwitchService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.SwitchService",
+	HandlerType: (*SwitchServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "InstallSwitch",
+			Handler:    _SwitchService_InstallSwitch_Handler,
+		},
+		{
+			MethodName: "RemoveSwitch",
+			Handler:    _SwitchService_RemoveSwitch_Handler,
+		},
+		{
+			MethodName: "GetSwitch",
+			Handler:    _SwitchService_GetSwitch_Handler,
+		},
+		{
+			MethodName: "SetSwitch",
+			Handler:    _SwitchService_SetSwitch_Handler,
+		},
+		{
+			MethodName: "ReportSwitchState",
+			Handler:    _SwitchService_ReportSwitchState_Handler,
+		},
+		{
+			MethodName: "UpdateSwitch",
+			Handler:    _SwitchService_UpdateSwitch_Handler,
+		},
+		{
+			MethodName: "GetAllowedTransitions",
+			Handler:    _SwitchService_GetAllowedTransitions_Handler,
+		},
+		{
+			MethodName: "SetSwitches",
+			Handler:    _SwitchService_SetSwitches_Handler,
+		},
+		{
+			MethodName: "GetSwitchHistory",
+			Handler:    _SwitchService_GetSwitchHistory_Handler,
+		},
+		{
+			MethodName: "CreateSchedule",
+			Handler:    _SwitchService_CreateSchedule_Handler,
+		},
+		{
+			MethodName: "GetSchedule",
+			Handler:    _SwitchService_GetSchedule_Handler,
+		},
+		{
+			MethodName: "UpdateSchedule",
+			Handler:    _SwitchService_UpdateSchedule_Handler,
+		},
+		{
+			MethodName: "DeleteSchedule",
+			Handler:    _SwitchService_DeleteSchedule_Handler,
+		},
+		{
+			MethodName: "CreateGroup",
+			Handler:    _SwitchService_CreateGroup_Handler,
+		},
+		{
+			MethodName: "GetGroup",
+			Handler:    _SwitchService_GetGroup_Handler,
+		},
+		{
+			MethodName: "UpdateGroup",
+			Handler:    _SwitchService_UpdateGroup_Handler,
+		},
+		{
+			MethodName: "DeleteGroup",
+			Handler:    _SwitchService_DeleteGroup_Handler,
+		},
+		{
+			MethodName: "CreateScene",
+			Handler:    _SwitchService_CreateScene_Handler,
+		},
+		{
+			MethodName: "GetScene",
+			Handler:    _SwitchService_GetScene_Handler,
+		},
+		{
+			MethodName: "UpdateScene",
+			Handler:    _SwitchService_UpdateScene_Handler,
+		},
+		{
+			MethodName: "DeleteScene",
+			Handler:    _SwitchService_DeleteScene_Handler,
+		},
+		{
+			MethodName: "ApplyScene",
+			Handler:    _SwitchService_ApplyScene_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetSwitches",
+			Handler:       _SwitchService_GetSwitches_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "InstallSwitches",
+			Handler:       _SwitchService_InstallSwitches_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchSwitches",
+			Handler:       _SwitchService_WatchSwitches_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetSchedules",
+			Handler:       _SwitchService_GetSchedules_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetGroups",
+			Handler:       _SwitchService_GetGroups_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetScenes",
+			Handler:       _SwitchService_GetScenes_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "switch.proto",
+}
+
+func init() { proto.RegisterFile("switch.proto", fileDescriptor_switch_d70532c4b3ba90b7) }
+
+var fileDescriptor_switch_d70532c4b3ba90b7 = []byte{
+	// 2439 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0xcb, 0x73, 0x1b, 0x49,
+	0x19, 0xdf, 0x19, 0xbd, 0x3f, 0x3d, 0x2c, 0xb7, 0x5f, 0x8a, 0xb2, 0x49, 0xbc, 0x93, 0x18, 0x52,
+	0x1b, 0x56, 0x71, 0x39, 0x40, 0x11, 0x52, 0x0b, 0x28, 0xb6, 0xec, 0xa8, 0xf0, 0x23, 0x8c, 0xe4,
+	0x2c, 0x70, 0x19, 0x26, 0x9a, 0xb6, 0x33, 0x65, 0x79, 0x46, 0x4c, 0xcf, 0x78, 0xa3, 0xfc, 0x03,
+	0x9c, 0xf6, 0xb2, 0xff, 0x00, 0x37, 0x8a, 0x2b, 0x5c, 0xf9, 0x3b, 0xa8, 0xe2, 0x3f, 0xe0, 0x44,
+	0x51, 0xdc, 0x38, 0x52, 0xfd, 0x1a, 0xcd, 0x4b, 0xb2, 0xd7, 0xbb, 0x9c, 0x66, 0xfa, 0xfb, 0xbe,
+	0xee, 0xfe, 0xde, 0xfd, 0xeb, 0x86, 0x1a, 0xf9, 0xd2, 0xf6, 0x47, 0xef, 0x3a, 0x13, 0xcf, 0xf5,
+	0x5d, 0x54, 0x60, 0x9f, 0xf6, 0xe6, 0xb9, 0xeb, 0x9e, 0x8f, 0xf1, 0x53, 0x36, 0x7a, 0x1b, 0x9c,
+	0x3d, 0x3d, 0xb3, 0xf1, 0xd8, 0x32, 0x2e, 0x4d, 0x72, 0xc1, 0x05, 0xb5, 0xff, 0xaa, 0x50, 0x1c,
+	0xb0, 0x99, 0xa8, 0x01, 0xaa, 0x6d, 0xb5, 0x94, 0x4d, 0xe5, 0x71, 0x45, 0x57, 0x6d, 0x0b, 0x6d,
+	0x40, 0x89, 0xd8, 0x3e, 0x36, 0x6c, 0xab, 0xa5, 0x32, 0x62, 0x91, 0x0e, 0xfb, 0x16, 0x42, 0x90,
+	0x77, 0xcc, 0x4b, 0xdc, 0xca, 0x31, 0x2a, 0xfb, 0x47, 0xab, 0x50, 0x20, 0xbe, 0xe9, 0xe3, 0x56,
+	0x9e, 0x11, 0xf9, 0x00, 0xad, 0x43, 0x91, 0xfd, 0x90, 0x56, 0x61, 0x33, 0xc7, 0x56, 0x60, 0x23,
+	0xd4, 0x86, 0xb2, 0x87, 0xaf, 0x6c, 0x62, 0xbb, 0x4e, 0xab, 0xc8, 0x26, 0x84, 0x63, 0xf4, 0x0c,
+	0xaa, 0xbe, 0x67, 0x3a, 0xc4, 0xf6, 0x6d, 0xd7, 0x21, 0xad, 0xd2, 0x66, 0xee, 0x71, 0x75, 0x67,
+	0x99, 0xab, 0xdb, 0x19, 0x86, 0x1c, 0x3d, 0x2a, 0x85, 0xb6, 0xa0, 0xe1, 0xe1, 0x89, 0xeb, 0xf9,
+	0xd8, 0x32, 0xb8, 0x1e, 0x65, 0xb6, 0x6c, 0x5d, 0x52, 0x07, 0x4c, 0x9f, 0x07, 0x50, 0x0d, 0xc5,
+	0x4c, 0xbf, 0x55, 0xd9, 0x54, 0x1e, 0xe7, 0x74, 0x90, 0xa4, 0xae, 0x8f, 0x5a, 0x50, 0x9a, 0x60,
+	0xc7, 0xb2, 0x9d, 0xf3, 0x16, 0x6c, 0x2a, 0x8f, 0xcb, 0xba, 0x1c, 0xa2, 0xfb, 0x50, 0x75, 0x03,
+	0xdf, 0x70, 0xcf, 0x0c, 0x32, 0x75, 0x46, 0xad, 0x2a, 0xe3, 0x56, 0xdc, 0xc0, 0x3f, 0x39, 0x1b,
+	0x4c, 0x9d, 0x11, 0xda, 0x06, 0xb0, 0x1d, 0x1f, 0x7b, 0x63, 0x77, 0x74, 0x41, 0x5a, 0x35, 0xa6,
+	0x75, 0x53, 0x68, 0xdd, 0x97, 0x0c, 0x3d, 0x22, 0xa3, 0x6d, 0x03, 0xcc, 0xcc, 0xa1, 0x4e, 0x3d,
+	0xf3, 0xdc, 0x4b, 0xe1, 0x7f, 0xf6, 0x4f, 0x23, 0xe2, 0xbb, 0x2d, 0x95, 0xb9, 0x4e, 0xf5, 0x5d,
+	0xed, 0xdf, 0x2a, 0x54, 0xc2, 0xb5, 0xc2, 0x30, 0x28, 0x91, 0x30, 0xcc, 0x1c, 0xae, 0xc6, 0x1c,
+	0x7e, 0x17, 0x2a, 0x04, 0x3b, 0xc4, 0xf5, 0x68, 0x34, 0x79, 0xdc, 0xca, 0x9c, 0xd0, 0xb7, 0xd0,
+	0x8f, 0xa0, 0xec, 0x4e, 0xb0, 0x67, 0xfa, 0xae, 0xc7, 0xc2, 0xd7, 0xd8, 0xb9, 0x93, 0x54, 0xbc,
+	0x73, 0x22, 0x04, 0xf4, 0x50, 0x14, 0xfd, 0x00, 0x0a, 0x63, 0xfb, 0xd2, 0xf6, 0x5b, 0x05, 0x36,
+	0x67, 0x3d, 0x35, 0xe7, 0x90, 0x72, 0x75, 0x2e, 0x44, 0x13, 0xe4, 0xca, 0x1c, 0x07, 0x98, 0xc5,
+	0x5b, 0xd1, 0xf9, 0x40, 0x73, 0xa0, 0x2c, 0x57, 0x46, 0x4d, 0xa8, 0x1d, 0xe8, 0xbd, 0xee, 0xb0,
+	0xa7, 0x1b, 0xc3, 0x57, 0xdd, 0xe3, 0xe6, 0x47, 0x68, 0x15, 0x9a, 0x92, 0x72, 0xa2, 0x1b, 0xbd,
+	0x5f, 0x9d, 0x76, 0x0f, 0x9b, 0x0a, 0xaa, 0x43, 0xe5, 0xb0, 0x37, 0x18, 0x70, 0x21, 0x15, 0x2d,
+	0x43, 0x9d, 0x0d, 0x43, 0x89, 0x1c, 0xaa, 0x40, 0x81, 0xff, 0xe6, 0xa9, 0xf0, 0xf1, 0xc9, 0x50,
+	0x70, 0x0a, 0x5a, 0x07, 0x0a, 0x4c, 0x2b, 0x2a, 0xf2, 0xa6, 0x7b, 0x78, 0xda, 0x6b, 0x7e, 0x84,
+	0x6a, 0x50, 0x3e, 0xea, 0x1f, 0x1b, 0x83, 0xee, 0x7e, 0xaf, 0xa9, 0xb0, 0x51, 0xf7, 0xd7, 0x7c,
+	0xa4, 0x6a, 0xff, 0x50, 0x60, 0xb5, 0xef, 0x10, 0xdf, 0x1c, 0x8f, 0x79, 0x95, 0xe8, 0xf8, 0xf7,
+	0x01, 0x26, 0x7e, 0xb4, 0x38, 0x94, 0xcc, 0xe2, 0x50, 0xb3, 0x8a, 0x23, 0x97, 0x5d, 0x1c, 0xf9,
+	0x58, 0xac, 0x12, 0x05, 0x50, 0xb8, 0x51, 0x01, 0xc4, 0xd3, 0xaf, 0x78, 0x83, 0xf4, 0xdb, 0x82,
+	0x15, 0x1d, 0x5f, 0xba, 0x57, 0x38, 0x6e, 0x58, 0xa2, 0x0b, 0x68, 0xeb, 0xb0, 0x1a, 0x17, 0x23,
+	0x13, 0xd7, 0x21, 0x58, 0xd3, 0xa0, 0x79, 0x80, 0xfd, 0xc5, 0x73, 0xff, 0xa9, 0x02, 0x0a, 0x85,
+	0x30, 0xb9, 0xd6, 0x77, 0x77, 0xa0, 0x2c, 0x18, 0x32, 0x7f, 0x4b, 0x9c, 0x43, 0x68, 0xe5, 0x52,
+	0x57, 0x1a, 0x13, 0x0f, 0x9f, 0xd9, 0xef, 0x85, 0x23, 0x81, 0x92, 0x5e, 0x33, 0x0a, 0x7a, 0x08,
+	0x75, 0x26, 0x30, 0x72, 0x1d, 0xdf, 0xb4, 0x1d, 0x22, 0x1a, 0x51, 0x8d, 0x12, 0x77, 0x05, 0x6d,
+	0x16, 0x88, 0x42, 0x34, 0x10, 0xcf, 0xa1, 0x44, 0x5c, 0xcf, 0x37, 0xde, 0x4e, 0x59, 0x72, 0x36,
+	0x76, 0x36, 0x85, 0xe3, 0xd2, 0xba, 0x77, 0x06, 0xae, 0xe7, 0xbf, 0x9c, 0xea, 0x45, 0xc2, 0xbe,
+	0xe8, 0x3e, 0x80, 0x85, 0xc9, 0x48, 0xb4, 0x8c, 0x12, 0x6b, 0x0a, 0x11, 0x0a, 0xad, 0xbb, 0x89,
+	0x79, 0x8e, 0x0d, 0x62, 0x7f, 0xe0, 0x2d, 0xa9, 0xa0, 0x97, 0x29, 0x61, 0x60, 0x7f, 0xc0, 0xe8,
+	0x1e, 0x00, 0x63, 0xfa, 0xee, 0x05, 0x76, 0x58, 0x33, 0xaa, 0xe8, 0x4c, 0x7c, 0x48, 0x09, 0xda,
+	0xa7, 0x50, 0xe4, 0xbb, 0xa1, 0x32, 0xe4, 0x8f, 0xbb, 0x47, 0x34, 0x57, 0x2b, 0x50, 0x18, 0x0c,
+	0xbb, 0x43, 0x9a, 0xa8, 0x55, 0x28, 0x0d, 0xfa, 0xc3, 0x9e, 0xd1, 0xdf, 0x6b, 0xaa, 0x5a, 0x07,
+	0x3e, 0x3e, 0xc0, 0x7e, 0x77, 0x3c, 0x76, 0xbf, 0xc4, 0xd6, 0x2c, 0x47, 0xc8, 0xbc, 0xc8, 0xfc,
+	0x41, 0x81, 0x7b, 0x73, 0x26, 0xf0, 0xf8, 0x52, 0x7f, 0x8e, 0x02, 0xcf, 0xc3, 0x8e, 0x2f, 0x1a,
+	0x2a, 0x9f, 0x5c, 0x13, 0x44, 0xde, 0x4f, 0xb7, 0xa0, 0x61, 0xf2, 0x25, 0x8c, 0x58, 0xdb, 0xa9,
+	0x0b, 0xea, 0x20, 0xdd, 0xee, 0x73, 0xf1, 0x76, 0xaf, 0x4d, 0x61, 0xe5, 0x74, 0x62, 0x99, 0x7e,
+	0x22, 0x0d, 0xb7, 0xa0, 0xc8, 0x0f, 0x34, 0xb6, 0x6f, 0x75, 0xa7, 0x2e, 0x42, 0x22, 0xa4, 0x04,
+	0x13, 0xbd, 0x80, 0x6a, 0xc0, 0x66, 0xb3, 0x33, 0x8d, 0x15, 0x5d, 0x75, 0xa7, 0xdd, 0xe1, 0xc7,
+	0x5e, 0x47, 0x1e, 0x7b, 0x9d, 0x7d, 0x7a, 0xec, 0x1d, 0x99, 0xe4, 0x42, 0x07, 0x2e, 0x4e, 0xff,
+	0xb5, 0xbf, 0x2b, 0xd0, 0x1c, 0x5c, 0x93, 0xc3, 0xb3, 0x94, 0x51, 0x13, 0xb5, 0xeb, 0x61, 0x93,
+	0x84, 0xf6, 0x88, 0x11, 0x7a, 0x02, 0xcb, 0xf8, 0xfd, 0x04, 0x8f, 0xe8, 0x01, 0x13, 0x9a, 0xcc,
+	0x33, 0xb1, 0x29, 0x19, 0xba, 0xa0, 0x53, 0xef, 0x85, 0xc2, 0xd1, 0xb4, 0xac, 0x4b, 0x2a, 0x77,
+	0xf2, 0x53, 0x58, 0x71, 0xaf, 0xb0, 0xe7, 0xd9, 0x16, 0x36, 0x62, 0x35, 0x4e, 0x93, 0x0d, 0x49,
+	0x56, 0x7f, 0x56, 0xd9, 0x6f, 0x60, 0x39, 0x62, 0x96, 0x88, 0x67, 0x34, 0x06, 0x4a, 0xe2, 0xc8,
+	0xdd, 0x82, 0xc6, 0x84, 0x0e, 0xdc, 0x80, 0x18, 0x51, 0x63, 0xeb, 0x92, 0xca, 0x14, 0xd1, 0x7e,
+	0x01, 0x2d, 0x9d, 0x1d, 0x95, 0x7c, 0x69, 0x46, 0xfc, 0x46, 0x6e, 0xd3, 0xfe, 0xa3, 0x00, 0x1a,
+	0xa4, 0x1b, 0x42, 0x13, 0x72, 0xb4, 0xe4, 0x15, 0x96, 0x3b, 0xf4, 0x77, 0x3e, 0xf6, 0x90, 0x7d,
+	0xe0, 0xcc, 0x1e, 0xfb, 0xd8, 0x8b, 0xf6, 0x81, 0x7d, 0x46, 0x41, 0x9f, 0x40, 0x8d, 0xed, 0x25,
+	0x25, 0xb8, 0xf3, 0xab, 0x8c, 0x26, 0x44, 0xb2, 0xbb, 0xc0, 0x2c, 0xa4, 0xc5, 0x58, 0x48, 0x1f,
+	0x42, 0xfe, 0xd2, 0xb5, 0x30, 0x2b, 0xee, 0xc6, 0xce, 0x92, 0xc8, 0xc3, 0x97, 0xc1, 0xf8, 0xe2,
+	0xc8, 0xb5, 0xb0, 0xce, 0x98, 0xb4, 0x73, 0x9d, 0x7b, 0x6e, 0x30, 0xa1, 0x0a, 0x73, 0xe4, 0x51,
+	0x62, 0xe3, 0xbe, 0xa5, 0xed, 0xc1, 0x4a, 0xcc, 0x64, 0x11, 0x8f, 0xcf, 0xa0, 0xe4, 0x61, 0x12,
+	0x8c, 0x7d, 0x6e, 0x77, 0x75, 0x67, 0x25, 0x9e, 0xe1, 0x8c, 0xa7, 0x4b, 0x19, 0xcd, 0x83, 0xf5,
+	0xd8, 0x39, 0x34, 0x73, 0x9e, 0xd4, 0x4f, 0x59, 0xa4, 0xdf, 0xb3, 0xb0, 0x9c, 0x78, 0x89, 0xdc,
+	0x0d, 0x8f, 0x86, 0xf4, 0xd9, 0x26, 0x8b, 0x4b, 0x7b, 0x05, 0x1b, 0xa9, 0x3d, 0x6f, 0xa7, 0x3d,
+	0x81, 0x5a, 0x94, 0x91, 0xca, 0x16, 0x04, 0xf9, 0x11, 0xb5, 0x41, 0x65, 0x1d, 0x92, 0xfd, 0x53,
+	0x28, 0x76, 0x89, 0x09, 0x31, 0xcf, 0xe5, 0xb1, 0x29, 0x87, 0x91, 0xde, 0x90, 0x5f, 0xd0, 0x1b,
+	0xb4, 0x33, 0x58, 0xfd, 0xc2, 0xa4, 0x79, 0x7a, 0xd3, 0xe3, 0x47, 0xa4, 0xa1, 0x3a, 0x4b, 0xc3,
+	0x87, 0x50, 0xa7, 0x40, 0xcc, 0x88, 0x75, 0xaf, 0xbc, 0x5e, 0xa3, 0x44, 0x59, 0xc6, 0xda, 0xbf,
+	0x14, 0xa8, 0xf2, 0x3d, 0x7a, 0x57, 0xd8, 0xf1, 0xd1, 0x13, 0xc8, 0xfb, 0xd3, 0x89, 0x0c, 0xc8,
+	0x46, 0x4c, 0x39, 0x26, 0xd1, 0x19, 0x4e, 0x27, 0x58, 0x67, 0x42, 0xb1, 0xb2, 0x54, 0xd9, 0xe2,
+	0xd1, 0xb2, 0x94, 0x76, 0xe6, 0x16, 0xd9, 0xe9, 0x40, 0x9e, 0x2e, 0x48, 0x0f, 0x84, 0xdd, 0x53,
+	0x5d, 0xef, 0x1d, 0x0f, 0x9b, 0x1f, 0x51, 0xdc, 0xd3, 0x3f, 0x1e, 0x0c, 0xbb, 0x87, 0x87, 0xbd,
+	0x3d, 0x7e, 0x58, 0xe8, 0xbd, 0xa3, 0x93, 0x37, 0xbd, 0x3d, 0x8e, 0x98, 0xd8, 0x21, 0x62, 0xec,
+	0xbe, 0xea, 0x1e, 0x1f, 0xf4, 0xf6, 0x9a, 0x39, 0xca, 0x3f, 0x7d, 0xbd, 0xd7, 0x1d, 0xf6, 0xf6,
+	0x9a, 0x79, 0x0a, 0x81, 0xf4, 0xde, 0xeb, 0x13, 0x9d, 0x8e, 0x0a, 0x68, 0x09, 0xaa, 0x27, 0xa7,
+	0x43, 0xe3, 0x64, 0xdf, 0x18, 0xfc, 0xe6, 0x78, 0xb7, 0x59, 0xd4, 0xbe, 0x52, 0x61, 0x39, 0xd2,
+	0x01, 0x76, 0xdf, 0x99, 0xce, 0x39, 0x66, 0x08, 0x93, 0x11, 0x67, 0x7e, 0x2d, 0x73, 0x42, 0xdf,
+	0xba, 0x61, 0x83, 0x99, 0x83, 0x93, 0x10, 0xe4, 0x7d, 0xfb, 0x92, 0xdf, 0x2c, 0x72, 0x3a, 0xfb,
+	0xa7, 0xc5, 0x3a, 0x32, 0xc7, 0x63, 0xec, 0x89, 0x1a, 0x16, 0xa3, 0xb9, 0x45, 0xfc, 0x0c, 0xd6,
+	0x44, 0xa3, 0xb4, 0xb0, 0x13, 0xed, 0xa2, 0x25, 0x16, 0xec, 0xd5, 0x19, 0x73, 0xd6, 0x47, 0xa9,
+	0xd6, 0xa3, 0xb1, 0x69, 0x5f, 0x62, 0xcb, 0x10, 0x9b, 0x89, 0x4b, 0x85, 0xa0, 0xee, 0x32, 0xa2,
+	0xf6, 0x67, 0x05, 0x36, 0x42, 0xa4, 0xf0, 0xca, 0x26, 0xbe, 0xeb, 0x4d, 0x65, 0xae, 0x2d, 0xf4,
+	0xca, 0x5d, 0xa8, 0xb0, 0xec, 0x62, 0xd6, 0xa9, 0xcc, 0xba, 0x32, 0x25, 0x0c, 0xa9, 0x85, 0x1b,
+	0x50, 0xf2, 0x5d, 0xce, 0xca, 0x31, 0x56, 0xd1, 0x77, 0x19, 0x23, 0x06, 0x29, 0xf2, 0x0b, 0x21,
+	0x45, 0x21, 0x09, 0x29, 0xae, 0xa0, 0x95, 0xd6, 0x54, 0x94, 0xf4, 0x0e, 0x94, 0x46, 0x2c, 0x94,
+	0xb2, 0xa4, 0x5b, 0xb1, 0x74, 0x8b, 0xc4, 0x5a, 0x97, 0x82, 0xe8, 0x7b, 0xb0, 0xe4, 0xe0, 0xf7,
+	0xbe, 0x11, 0xd9, 0x53, 0x04, 0x96, 0x92, 0x5f, 0x87, 0xfb, 0x7e, 0xad, 0x42, 0x79, 0x30, 0x7a,
+	0x87, 0xad, 0x60, 0x8c, 0x53, 0xc5, 0x1f, 0xf3, 0x91, 0x9a, 0xf0, 0x51, 0xa4, 0x58, 0x73, 0xb1,
+	0x62, 0xcd, 0xbe, 0x70, 0xd2, 0x46, 0xe2, 0xb9, 0xd2, 0x72, 0xf6, 0x8f, 0xd6, 0xa0, 0xe8, 0x05,
+	0x0e, 0xbd, 0xef, 0x15, 0x99, 0x23, 0x0b, 0x5e, 0xe0, 0x74, 0x59, 0x68, 0xa8, 0x77, 0x8d, 0x0f,
+	0xae, 0xc3, 0x9b, 0x7b, 0x45, 0x2f, 0x53, 0xc2, 0x6f, 0x5d, 0x87, 0x35, 0x1f, 0xec, 0x98, 0x6f,
+	0xc7, 0x98, 0xb7, 0xf3, 0xb2, 0x2e, 0x87, 0xb4, 0xd3, 0x33, 0x93, 0xbd, 0xc0, 0x11, 0xf7, 0xc7,
+	0x12, 0x1d, 0xeb, 0x81, 0x83, 0x3e, 0x83, 0xf2, 0xd8, 0x24, 0x9c, 0x05, 0xac, 0x62, 0x91, 0x74,
+	0xa1, 0xb0, 0x5d, 0x0f, 0x1c, 0xbd, 0x44, 0x65, 0xf4, 0xc0, 0xd1, 0xbe, 0xa2, 0x7d, 0x63, 0xc6,
+	0xa0, 0x2b, 0x5b, 0x01, 0xe6, 0x21, 0x57, 0xf8, 0xca, 0x56, 0x80, 0x65, 0xcc, 0xf1, 0x7b, 0x3c,
+	0x8a, 0x65, 0x0a, 0x25, 0x30, 0x66, 0x0b, 0x4a, 0x24, 0x18, 0x8d, 0x30, 0x21, 0xcc, 0x45, 0x65,
+	0x5d, 0x0e, 0xa9, 0x8f, 0xb0, 0xe7, 0xb9, 0xf2, 0x10, 0xe4, 0x03, 0x5a, 0x23, 0x97, 0x36, 0x21,
+	0xd8, 0x62, 0x5e, 0x2a, 0xe8, 0x62, 0xa4, 0x7d, 0xad, 0xc0, 0xda, 0xae, 0x87, 0x29, 0x14, 0x93,
+	0x5a, 0xdd, 0x24, 0x8b, 0xb3, 0x01, 0x92, 0x0c, 0x44, 0x2e, 0x33, 0x10, 0xf9, 0xb9, 0x81, 0x28,
+	0xc4, 0x03, 0xa1, 0x3d, 0xe2, 0x37, 0x88, 0x84, 0x42, 0x49, 0x38, 0xfb, 0x4b, 0x58, 0x89, 0x48,
+	0x91, 0x1b, 0xe9, 0x3d, 0x0f, 0x62, 0x68, 0x7f, 0x54, 0x60, 0x4d, 0x40, 0xd2, 0xc5, 0xdb, 0xfe,
+	0x7f, 0x4d, 0x8f, 0xe6, 0x60, 0x31, 0x96, 0x83, 0xda, 0xf7, 0x61, 0x6d, 0x0f, 0x8f, 0xf1, 0xb5,
+	0x0a, 0x6a, 0x2d, 0x58, 0x4f, 0x0a, 0x8a, 0xeb, 0xdb, 0x08, 0x0a, 0x07, 0x14, 0xa0, 0x7c, 0xbb,
+	0x57, 0x9f, 0x7b, 0x00, 0xa1, 0x83, 0xe5, 0x35, 0xb6, 0x22, 0x3d, 0x4c, 0xb4, 0xdf, 0x01, 0xe2,
+	0x09, 0xc5, 0xb6, 0xba, 0xd5, 0xd5, 0x39, 0xbe, 0x43, 0x2e, 0xb9, 0xc3, 0x27, 0xb0, 0x74, 0x80,
+	0xfd, 0xd8, 0xf2, 0x49, 0x1f, 0x3c, 0x81, 0xa6, 0x14, 0xb9, 0x16, 0x02, 0x68, 0x5f, 0x00, 0xe2,
+	0xa1, 0x5f, 0xb4, 0xe4, 0x6d, 0x14, 0x7d, 0x04, 0x88, 0x47, 0x62, 0xa1, 0xae, 0x6b, 0xb0, 0x12,
+	0x93, 0x12, 0xc1, 0xfa, 0x8b, 0x02, 0x85, 0xc1, 0x08, 0x3b, 0xf8, 0xdb, 0x45, 0x6b, 0x3b, 0xf6,
+	0xe0, 0x10, 0x69, 0xf0, 0x74, 0xe9, 0x0e, 0xbf, 0xab, 0xf5, 0x1c, 0xdf, 0x9b, 0xca, 0xa7, 0x88,
+	0xf6, 0x73, 0xa8, 0x46, 0xc8, 0x14, 0x20, 0x5d, 0xe0, 0xa9, 0xd8, 0x9e, 0xfe, 0xce, 0x5e, 0x75,
+	0x44, 0x05, 0xb0, 0xc1, 0x4f, 0xd5, 0x9f, 0x28, 0xda, 0xdf, 0x14, 0x19, 0x7c, 0xb6, 0xfc, 0xad,
+	0x82, 0xff, 0x79, 0xa8, 0x70, 0x8e, 0x29, 0xbc, 0x25, 0x14, 0x4e, 0xaf, 0xfb, 0x5d, 0x6b, 0xcf,
+	0xf3, 0x2a, 0xa6, 0x79, 0x76, 0x5e, 0x31, 0x91, 0xeb, 0xf3, 0xea, 0xaf, 0x8a, 0x4c, 0xac, 0x45,
+	0x6b, 0x7e, 0x23, 0x27, 0xa4, 0x97, 0xfb, 0xae, 0x9d, 0x10, 0xe6, 0xec, 0x42, 0x3f, 0x84, 0x39,
+	0x2b, 0xa4, 0x44, 0xce, 0xbe, 0x80, 0xe5, 0xee, 0x64, 0x32, 0x9e, 0x2e, 0xb4, 0x77, 0x06, 0xd7,
+	0xd4, 0x28, 0x5c, 0xd3, 0xde, 0x01, 0x8a, 0x4e, 0xbe, 0xd5, 0xa5, 0x83, 0x82, 0xf7, 0xc0, 0xe1,
+	0x48, 0xc5, 0x8a, 0x3c, 0x29, 0xd5, 0x42, 0x62, 0xdf, 0x22, 0x9f, 0x3e, 0x85, 0xb2, 0xbc, 0x2a,
+	0x51, 0xa4, 0xfb, 0xb2, 0x37, 0x18, 0x1a, 0xbd, 0xfd, 0xfd, 0x13, 0x9d, 0x82, 0x68, 0x04, 0x8d,
+	0xee, 0xe1, 0x21, 0x7d, 0x59, 0x3c, 0x3e, 0x19, 0xbe, 0xea, 0x1f, 0x1f, 0x34, 0x95, 0x9d, 0x3f,
+	0x35, 0xa0, 0x2e, 0x10, 0x11, 0xf6, 0xae, 0xec, 0x11, 0x46, 0x2f, 0xa0, 0x1e, 0xbb, 0x26, 0xa1,
+	0x45, 0x97, 0xab, 0x76, 0x1c, 0xc4, 0xa3, 0x03, 0xa8, 0x45, 0x9f, 0xd7, 0x50, 0x5b, 0xb0, 0x33,
+	0x9e, 0xe6, 0xda, 0x77, 0x33, 0x79, 0xc2, 0x39, 0xcf, 0xa0, 0x12, 0x42, 0x3b, 0xb4, 0x91, 0x7c,
+	0xc0, 0x9a, 0xb3, 0xfb, 0x73, 0xa8, 0x1e, 0xcc, 0xee, 0xa6, 0xe8, 0xce, 0xdc, 0x77, 0xaf, 0xc4,
+	0xc4, 0x6d, 0x05, 0xfd, 0x0c, 0x2a, 0x83, 0xd4, 0x7e, 0xc9, 0xd7, 0x94, 0x76, 0x2b, 0xcd, 0x10,
+	0xfa, 0xee, 0xc2, 0x72, 0xea, 0x31, 0x01, 0x3d, 0x08, 0x2d, 0xcc, 0x7e, 0x66, 0x48, 0xeb, 0x5f,
+	0x8b, 0x3e, 0x1e, 0x85, 0xde, 0xcb, 0x78, 0x51, 0x4a, 0x4e, 0x7d, 0x0b, 0x6b, 0x99, 0x0f, 0x60,
+	0xe8, 0xe1, 0xcc, 0x09, 0x73, 0xdf, 0xd3, 0xda, 0x8f, 0x16, 0x0b, 0x09, 0x1b, 0xf7, 0xa0, 0x3a,
+	0xc8, 0x70, 0x6f, 0xfa, 0x05, 0xa4, 0xdd, 0xce, 0x62, 0x89, 0x55, 0x74, 0x58, 0x4a, 0x5c, 0xc3,
+	0xd1, 0xbd, 0xac, 0x0c, 0x9b, 0xad, 0x76, 0x7f, 0x1e, 0x9b, 0xaf, 0xf8, 0x58, 0x41, 0x2f, 0xa1,
+	0x1e, 0xbb, 0x1b, 0x87, 0x39, 0x9b, 0x75, 0x63, 0x6e, 0xa3, 0xf4, 0x1d, 0x76, 0x5b, 0x41, 0x03,
+	0x68, 0x26, 0x2f, 0x13, 0xe8, 0x7e, 0x32, 0x83, 0xe2, 0xf7, 0xa1, 0xf6, 0x83, 0xb9, 0x7c, 0x61,
+	0xec, 0xcf, 0xa1, 0x11, 0xc7, 0xa0, 0xe8, 0xe3, 0x44, 0xd3, 0x8f, 0x21, 0x9e, 0xf6, 0x52, 0x02,
+	0x61, 0xcb, 0x94, 0x96, 0xc3, 0x68, 0x4a, 0x5f, 0x37, 0xf5, 0x73, 0xa8, 0x45, 0xc4, 0x48, 0x98,
+	0x4d, 0x19, 0xd0, 0x32, 0x35, 0x79, 0x5b, 0xa1, 0xaa, 0xc7, 0x61, 0x63, 0xa8, 0x7a, 0x26, 0x9a,
+	0x4c, 0xef, 0x7f, 0x04, 0x8d, 0x38, 0x5a, 0x0b, 0x17, 0xc8, 0x44, 0x7b, 0xed, 0x7b, 0x73, 0xb8,
+	0xc2, 0x95, 0x3f, 0x86, 0x6a, 0x04, 0x7d, 0x85, 0x9e, 0x48, 0x23, 0xb2, 0x76, 0x4d, 0x1a, 0xca,
+	0x04, 0xb7, 0xa1, 0x2c, 0x01, 0x13, 0x5a, 0x9f, 0xb9, 0x60, 0xc1, 0x8c, 0x1f, 0xb2, 0xde, 0xc3,
+	0xfe, 0x49, 0xb4, 0xf7, 0xc4, 0x40, 0x57, 0x7c, 0xce, 0xb6, 0x42, 0xf5, 0x8b, 0x60, 0xad, 0x50,
+	0xbf, 0x34, 0xfe, 0x4a, 0xec, 0xb6, 0x07, 0xd5, 0x08, 0x48, 0x0a, 0xe7, 0xa5, 0xe1, 0x55, 0xbb,
+	0x9d, 0xc5, 0x4a, 0x7a, 0x87, 0x03, 0xab, 0x3b, 0x73, 0xa1, 0x45, 0xb8, 0x3b, 0x17, 0xe4, 0xde,
+	0xe1, 0xff, 0xeb, 0xd1, 0x04, 0x99, 0x3b, 0x83, 0x7b, 0x87, 0xfd, 0xc7, 0xbc, 0x13, 0x83, 0x0e,
+	0xf1, 0x39, 0x51, 0xef, 0xc4, 0xf5, 0x4b, 0x9f, 0xfa, 0x89, 0xdd, 0x42, 0xef, 0xc4, 0xe7, 0xa5,
+	0x0f, 0xf2, 0x76, 0x3b, 0x8b, 0x25, 0xbc, 0xd3, 0x05, 0x98, 0x1d, 0xc0, 0x48, 0x76, 0xf1, 0xd4,
+	0x81, 0xde, 0xbe, 0x93, 0xc1, 0xe1, 0x4b, 0xbc, 0x2d, 0x32, 0xce, 0xb3, 0xff, 0x0d, 0x00, 0xb6,
+	0x5b, 0x87, 0x0b, 0x99, 0x1e, 0x00, 0x00,
+}
@@ -0,0 +1,206 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/moorara/microservices-demo/pkg/breaker"
+	"github.com/moorara/microservices-demo/pkg/cache"
+)
+
+const (
+	tenantHeader = "X-Tenant-ID"
+
+	defaultTimeout          = 2 * time.Second
+	defaultCacheTTL         = 30 * time.Second
+	defaultMaxAge           = time.Minute
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 10 * time.Second
+	maxCacheEntries         = 10000
+)
+
+var (
+	// ErrUnknownSensor is returned when the sensor service does not know a sensor id
+	ErrUnknownSensor = errors.New("unknown sensor id")
+
+	// ErrNoReading is returned when a sensor has no reading yet
+	ErrNoReading = errors.New("sensor has no reading")
+
+	// ErrStaleReading is returned when the latest reading of a sensor is older than the maximum age
+	ErrStaleReading = errors.New("sensor reading is stale")
+
+	// ErrUnavailable is returned when the sensor service cannot be reached
+	ErrUnavailable = errors.New("sensor service unavailable")
+)
+
+type (
+	// Reading is the latest reading of a sensor with the safe range of the sensor
+	Reading struct {
+		SensorID string
+		Value    float64
+		Time     time.Time
+		MinSafe  float64
+		MaxSafe  float64
+	}
+
+	// Client reads sensors from the sensor service
+	Client interface {
+		Read(ctx context.Context, tenantID, sensorID string) (*Reading, error)
+	}
+
+	// Config configures a sensor client
+	Config struct {
+		// Addr is the base address of the sensor service (e.g. http://sensor-service:4020)
+		Addr string
+		// Timeout bounds every call to the sensor service
+		Timeout time.Duration
+		// CacheTTL is how long the safe range of a sensor is remembered, readings are never cached
+		CacheTTL time.Duration
+		// MaxAge is how old the latest reading of a sensor can be before it is stale
+		MaxAge time.Duration
+		// BreakerThreshold is the number of consecutive failures that opens the circuit
+		BreakerThreshold int
+		// BreakerCooldown is how long the circuit stays open before a call is retried
+		BreakerCooldown time.Duration
+	}
+
+	sensor struct {
+		MinSafe float64 `json:"minSafe"`
+		MaxSafe float64 `json:"maxSafe"`
+	}
+
+	reading struct {
+		Value float64   `json:"value"`
+		Time  time.Time `json:"time"`
+	}
+
+	client struct {
+		addr    string
+		maxAge  time.Duration
+		http    *http.Client
+		breaker *breaker.Breaker
+		cache   *cache.Cache
+		now     func() time.Time
+	}
+)
+
+// NewClient creates a new sensor client
+func NewClient(config Config) Client {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = defaultCacheTTL
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = defaultMaxAge
+	}
+	if config.BreakerThreshold <= 0 {
+		config.BreakerThreshold = defaultBreakerThreshold
+	}
+	if config.BreakerCooldown <= 0 {
+		config.BreakerCooldown = defaultBreakerCooldown
+	}
+
+	return &client{
+		addr:   strings.TrimSuffix(config.Addr, "/"),
+		maxAge: config.MaxAge,
+		http: &http.Client{
+			Timeout: config.Timeout,
+		},
+		breaker: breaker.New(config.BreakerThreshold, config.BreakerCooldown),
+		cache:   cache.New(config.CacheTTL, maxCacheEntries),
+		now:     time.Now,
+	}
+}
+
+// fetch gets a resource of the sensor service into v and returns notFound if the sensor service responds with 404
+func (c *client) fetch(ctx context.Context, tenantID, path string, v interface{}, notFound error) error {
+	now := c.now()
+	if !c.breaker.Allow(now) {
+		return ErrUnavailable
+	}
+
+	err := c.get(ctx, tenantID, path, v, notFound)
+	// A call given up by the caller says nothing about the health of the sensor service
+	if err != nil && err != notFound && (ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+		return ctx.Err()
+	}
+
+	// A sensor or reading that does not exist does not count as a failure of the sensor service
+	c.breaker.Record(c.now(), err == nil || err == notFound)
+
+	if err != nil && err != notFound {
+		return ErrUnavailable
+	}
+
+	return err
+}
+
+func (c *client) get(ctx context.Context, tenantID, path string, v interface{}, notFound error) error {
+	req, err := http.NewRequest("GET", c.addr+path, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(tenantHeader, tenantID)
+
+	res, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(res.Body).Decode(v)
+	case http.StatusNotFound:
+		return notFound
+	default:
+		return errors.New(res.Status)
+	}
+}
+
+// Read returns the latest reading of a sensor of a tenant.
+// It returns ErrUnknownSensor if the sensor does not exist, ErrNoReading if it has no reading yet,
+// ErrStaleReading if its latest reading is older than the maximum age, and ErrUnavailable if the sensor service cannot be reached.
+func (c *client) Read(ctx context.Context, tenantID, sensorID string) (*Reading, error) {
+	if sensorID == "" {
+		return nil, ErrUnknownSensor
+	}
+
+	path := "/v1/sensors/" + url.PathEscape(sensorID)
+	key := tenantID + "/" + sensorID
+
+	var s sensor
+	if v, ok := c.cache.Get(key, c.now()); ok {
+		s = v.(sensor)
+	} else {
+		if err := c.fetch(ctx, tenantID, path, &s, ErrUnknownSensor); err != nil {
+			return nil, err
+		}
+		c.cache.Set(key, s, c.now())
+	}
+
+	r := reading{}
+	if err := c.fetch(ctx, tenantID, path+"/reading", &r, ErrNoReading); err != nil {
+		return nil, err
+	}
+
+	if c.now().Sub(r.Time) > c.maxAge {
+		return nil, ErrStaleReading
+	}
+
+	return &Reading{
+		SensorID: sensorID,
+		Value:    r.Value,
+		Time:     r.Time,
+		MinSafe:  s.MinSafe,
+		MaxSafe:  s.MaxSafe,
+	}, nil
+}
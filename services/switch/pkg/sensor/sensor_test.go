@@ -0,0 +1,201 @@
+package sensor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          Config
+		expectedTimeout time.Duration
+		expectedMaxAge  time.Duration
+	}{
+		{
+			"Defaults",
+			Config{Addr: "http://localhost:4020/"},
+			defaultTimeout,
+			defaultMaxAge,
+		},
+		{
+			"Custom",
+			Config{Addr: "http://localhost:4020", Timeout: time.Second, CacheTTL: time.Minute, MaxAge: 5 * time.Minute},
+			time.Second,
+			5 * time.Minute,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewClient(tc.config).(*client)
+
+			assert.Equal(t, "http://localhost:4020", c.addr)
+			assert.Equal(t, tc.expectedTimeout, c.http.Timeout)
+			assert.Equal(t, tc.expectedMaxAge, c.maxAge)
+			assert.NotNil(t, c.breaker)
+			assert.NotNil(t, c.cache)
+		})
+	}
+}
+
+// readingTime is the time of the reading served by sensorServer
+var readingTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// sensorServer serves a sensor and its reading with the given status codes
+func sensorServer(sensorCode, readingCode int, calls *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, r.Header.Get(tenantHeader)+" "+r.URL.Path)
+
+		if strings.HasSuffix(r.URL.Path, "/reading") {
+			w.WriteHeader(readingCode)
+			if readingCode == 200 {
+				w.Write([]byte(`{"sensorId":"aaaa","value":42.5,"time":"2020-01-01T00:00:00Z"}`))
+			}
+			return
+		}
+
+		w.WriteHeader(sensorCode)
+		if sensorCode == 200 {
+			w.Write([]byte(`{"id":"aaaa","tenantId":"tenant","minSafe":-10,"maxSafe":40}`))
+		}
+	}))
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name            string
+		sensorCode      int
+		readingCode     int
+		sensorID        string
+		expectedError   error
+		expectedReading *Reading
+		expectedCalls   []string
+	}{
+		{"EmptyID", 200, 200, "", ErrUnknownSensor, nil, nil},
+		{
+			"Success", 200, 200, "aaaa", nil,
+			&Reading{SensorID: "aaaa", Value: 42.5, Time: readingTime, MinSafe: -10, MaxSafe: 40},
+			[]string{"tenant /v1/sensors/aaaa", "tenant /v1/sensors/aaaa/reading"},
+		},
+		{"UnknownSensor", 404, 200, "aaaa", ErrUnknownSensor, nil, []string{"tenant /v1/sensors/aaaa"}},
+		{"NoReading", 200, 404, "aaaa", ErrNoReading, nil, []string{"tenant /v1/sensors/aaaa", "tenant /v1/sensors/aaaa/reading"}},
+		{"SensorUnavailable", 503, 200, "aaaa", ErrUnavailable, nil, []string{"tenant /v1/sensors/aaaa"}},
+		{"ReadingUnavailable", 200, 500, "aaaa", ErrUnavailable, nil, []string{"tenant /v1/sensors/aaaa", "tenant /v1/sensors/aaaa/reading"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			ts := sensorServer(tc.sensorCode, tc.readingCode, &calls)
+			defer ts.Close()
+
+			c := NewClient(Config{Addr: ts.URL}).(*client)
+			c.now = func() time.Time { return readingTime.Add(30 * time.Second) }
+			reading, err := c.Read(context.Background(), "tenant", tc.sensorID)
+
+			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedReading, reading)
+			assert.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestReadCache(t *testing.T) {
+	var calls []string
+	ts := sensorServer(200, 200, &calls)
+	defer ts.Close()
+
+	now := readingTime
+	c := NewClient(Config{Addr: ts.URL, CacheTTL: time.Minute, MaxAge: time.Hour}).(*client)
+	c.now = func() time.Time { return now }
+
+	_, err := c.Read(context.Background(), "tenant", "aaaa")
+	assert.NoError(t, err)
+	_, err = c.Read(context.Background(), "tenant", "aaaa")
+	assert.NoError(t, err)
+
+	// The safe range is cached but the reading is not
+	assert.Equal(t, []string{"tenant /v1/sensors/aaaa", "tenant /v1/sensors/aaaa/reading", "tenant /v1/sensors/aaaa/reading"}, calls)
+
+	// The cache is kept per tenant
+	_, err = c.Read(context.Background(), "other", "aaaa")
+	assert.NoError(t, err)
+	assert.Len(t, calls, 5)
+
+	now = now.Add(2 * time.Minute)
+	_, err = c.Read(context.Background(), "tenant", "aaaa")
+	assert.NoError(t, err)
+	assert.Len(t, calls, 7)
+}
+
+func TestReadBreaker(t *testing.T) {
+	var calls []string
+	ts := sensorServer(500, 200, &calls)
+	defer ts.Close()
+
+	now := time.Now()
+	c := NewClient(Config{Addr: ts.URL, BreakerThreshold: 2, BreakerCooldown: time.Minute}).(*client)
+	c.now = func() time.Time { return now }
+
+	_, err := c.Read(context.Background(), "tenant", "aaaa")
+	assert.Equal(t, ErrUnavailable, err)
+	_, err = c.Read(context.Background(), "tenant", "bbbb")
+	assert.Equal(t, ErrUnavailable, err)
+	assert.Len(t, calls, 2)
+
+	// The circuit is open, so the sensor service is not called
+	_, err = c.Read(context.Background(), "tenant", "cccc")
+	assert.Equal(t, ErrUnavailable, err)
+	assert.Len(t, calls, 2)
+
+	// After the cooldown a call is let through again
+	now = now.Add(2 * time.Minute)
+	_, err = c.Read(context.Background(), "tenant", "dddd")
+	assert.Equal(t, ErrUnavailable, err)
+	assert.Len(t, calls, 3)
+}
+
+func TestReadStale(t *testing.T) {
+	var calls []string
+	ts := sensorServer(200, 200, &calls)
+	defer ts.Close()
+
+	now := readingTime.Add(time.Minute)
+	c := NewClient(Config{Addr: ts.URL, MaxAge: time.Minute}).(*client)
+	c.now = func() time.Time { return now }
+
+	_, err := c.Read(context.Background(), "tenant", "aaaa")
+	assert.NoError(t, err)
+
+	// A reading older than the maximum age is stale
+	now = now.Add(time.Second)
+	reading, err := c.Read(context.Background(), "tenant", "aaaa")
+	assert.Equal(t, ErrStaleReading, err)
+	assert.Nil(t, reading)
+}
+
+func TestReadCanceled(t *testing.T) {
+	var calls []string
+	ts := sensorServer(200, 200, &calls)
+	defer ts.Close()
+
+	c := NewClient(Config{Addr: ts.URL, BreakerThreshold: 1, BreakerCooldown: time.Minute}).(*client)
+	c.now = func() time.Time { return readingTime }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.Read(ctx, "tenant", "aaaa")
+	assert.Equal(t, context.Canceled, err)
+
+	// The caller giving up did not open the circuit
+	_, err = c.Read(context.Background(), "tenant", "aaaa")
+	assert.NoError(t, err)
+}
@@ -24,6 +24,7 @@ function process_args {
 
 function test_component {
   export COMPONENT_TEST=true
+  export GRPC_GO_RETRY=on
   go test -v ./test/component
 }
 
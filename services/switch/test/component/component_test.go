@@ -8,9 +8,11 @@ import (
 	"testing"
 	"time"
 
-	"github.com/moorara/microservices-demo/services/switch/internal/client"
-	"github.com/moorara/microservices-demo/services/switch/internal/proto"
+	"github.com/moorara/microservices-demo/services/switch/pkg/client"
+	"github.com/moorara/microservices-demo/services/switch/pkg/proto"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func componentTest() bool {
@@ -80,7 +82,8 @@ func TestContract(t *testing.T) {
 	}
 
 	grpcAddr, _ := serviceConfig()
-	client, conn, err := client.New(grpcAddr)
+	tenantCtx := client.WithTenant(context.Background(), "demo-tenant")
+	client, conn, err := client.New(client.Config{Addr: grpcAddr})
 	assert.NoError(t, err)
 	defer conn.Close()
 
@@ -169,7 +172,7 @@ func TestContract(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			ctx := context.Background()
+			ctx := tenantCtx
 
 			// CREATE SWITCHES
 			t.Run("InstallSwitch", func(t *testing.T) {
@@ -180,6 +183,7 @@ func TestContract(t *testing.T) {
 					assert.NotEmpty(t, sw.GetId())
 					tc.switchID[i] = sw.GetId()
 					tc.installSwitchResponses[i].Id = sw.GetId()
+					tc.installSwitchResponses[i].Revision = sw.GetRevision()
 
 					assert.Equal(t, tc.installSwitchResponses[i].Id, sw.GetId())
 					assert.Equal(t, tc.installSwitchResponses[i].SiteId, sw.GetSiteId())
@@ -213,10 +217,30 @@ func TestContract(t *testing.T) {
 			t.Run("SetSwitch", func(t *testing.T) {
 				for i, id := range tc.switchID {
 					tc.setSwitchRequests[i].Id = id
+					tc.setSwitchRequests[i].ExpectedRevision = tc.installSwitchResponses[i].Revision
 					resp, err := client.SetSwitch(ctx, &tc.setSwitchRequests[i])
 
 					assert.NoError(t, err)
 					assert.NotNil(t, resp)
+					assert.NotEqual(t, tc.installSwitchResponses[i].Revision, resp.GetRevision())
+					assert.Equal(t, tc.installSwitchResponses[i].State, resp.GetPreviousState())
+
+					// The same request is now out of date
+					_, err = client.SetSwitch(ctx, &tc.setSwitchRequests[i])
+					assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+				}
+			})
+
+			// GET SWITCH HISTORY
+			t.Run("GetSwitchHistory", func(t *testing.T) {
+				for i, id := range tc.switchID {
+					resp, err := client.GetSwitchHistory(ctx, &proto.GetSwitchHistoryRequest{SwitchId: id})
+
+					assert.NoError(t, err)
+					assert.Len(t, resp.GetChanges(), 1)
+					assert.Equal(t, tc.installSwitchResponses[i].State, resp.GetChanges()[0].GetPreviousState())
+					assert.Equal(t, tc.setSwitchRequests[i].State, resp.GetChanges()[0].GetState())
+					assert.Empty(t, resp.GetNextPageToken())
 				}
 			})
 
@@ -236,6 +260,102 @@ func TestContract(t *testing.T) {
 				}
 			})
 
+			// GROUPS AND SCENES
+			t.Run("GroupsAndScenes", func(t *testing.T) {
+				group, err := client.CreateGroup(ctx, &proto.CreateGroupRequest{
+					SiteId:    "2222",
+					Name:      "Gauges",
+					SwitchIds: tc.switchID[1:],
+				})
+				assert.NoError(t, err)
+
+				set, err := client.SetSwitches(ctx, &proto.SetSwitchesRequest{GroupId: group.GetId(), State: "High"})
+				assert.NoError(t, err)
+				assert.Len(t, set.GetResults(), 2)
+
+				scene, err := client.CreateScene(ctx, &proto.CreateSceneRequest{
+					SiteId: "2222",
+					Name:   "Night",
+					States: map[string]string{tc.switchID[1]: "High", tc.switchID[2]: "Low"},
+				})
+				assert.NoError(t, err)
+
+				applied, err := client.ApplyScene(ctx, &proto.ApplySceneRequest{Id: scene.GetId()})
+				assert.NoError(t, err)
+				assert.Len(t, applied.GetResults(), 2)
+				assert.Equal(t, []string{tc.switchID[1]}, applied.GetUnchangedIds())
+
+				_, err = client.DeleteScene(ctx, &proto.DeleteSceneRequest{Id: scene.GetId()})
+				assert.NoError(t, err)
+
+				_, err = client.DeleteGroup(ctx, &proto.DeleteGroupRequest{Id: group.GetId()})
+				assert.NoError(t, err)
+			})
+
+			// SCHEDULES
+			t.Run("Schedules", func(t *testing.T) {
+				for i, id := range tc.switchID {
+					sc, err := client.CreateSchedule(ctx, &proto.CreateScheduleRequest{
+						SwitchId: id,
+						State:    tc.installSwitchResponses[i].State,
+						Cron:     "0 18 * * *",
+						TimeZone: "America/Toronto",
+					})
+					assert.NoError(t, err)
+					assert.True(t, sc.GetEnabled())
+					assert.NotZero(t, sc.GetNextRun())
+
+					sc, err = client.UpdateSchedule(ctx, &proto.UpdateScheduleRequest{
+						Id:      sc.GetId(),
+						State:   sc.GetState(),
+						Cron:    "0 6 * * *",
+						Enabled: false,
+					})
+					assert.NoError(t, err)
+					assert.False(t, sc.GetEnabled())
+
+					sc, err = client.GetSchedule(ctx, &proto.GetScheduleRequest{Id: sc.GetId()})
+					assert.NoError(t, err)
+					assert.Equal(t, "0 6 * * *", sc.GetCron())
+
+					_, err = client.DeleteSchedule(ctx, &proto.DeleteScheduleRequest{Id: sc.GetId()})
+					assert.NoError(t, err)
+				}
+			})
+
+			// BULK
+			t.Run("Bulk", func(t *testing.T) {
+				stream, err := client.InstallSwitches(ctx)
+				assert.NoError(t, err)
+
+				for _, name := range []string{"Light 1", "Light 2"} {
+					err = stream.Send(&proto.InstallSwitchesRequest{
+						Mode:   proto.BulkMode_ALL_OR_NOTHING,
+						Switch: &proto.InstallSwitchRequest{SiteId: "3333", Name: name, State: "ON", States: []string{"OFF", "ON"}},
+					})
+					assert.NoError(t, err)
+				}
+
+				installed, err := stream.CloseAndRecv()
+				assert.NoError(t, err)
+				assert.Len(t, installed.GetResults(), 2)
+
+				set, err := client.SetSwitches(ctx, &proto.SetSwitchesRequest{
+					SiteId:     "3333",
+					NameFilter: "light%",
+					State:      "OFF",
+					Mode:       proto.BulkMode_ALL_OR_NOTHING,
+				})
+				assert.NoError(t, err)
+				assert.Len(t, set.GetResults(), 2)
+
+				for _, r := range installed.GetResults() {
+					assert.Equal(t, int32(codes.OK), r.GetCode())
+					_, err = client.RemoveSwitch(ctx, &proto.RemoveSwitchRequest{Id: r.GetId()})
+					assert.NoError(t, err)
+				}
+			})
+
 			// DELETE SWITCHES
 			t.Run("RemoveSwitch", func(t *testing.T) {
 				for i, id := range tc.switchID {
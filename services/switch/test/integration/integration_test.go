@@ -187,18 +187,20 @@ func TestArangoService(t *testing.T) {
 	address, user, password := arangoConfig()
 
 	tests := []struct {
-		name           string
-		databaseName   string
-		collectionName string
-		document       map[string]interface{}
-		update         map[string]interface{}
-		query          string
-		vars           map[string]interface{}
+		name              string
+		databaseName      string
+		collectionName    string
+		historyCollection string
+		document          map[string]interface{}
+		update            map[string]interface{}
+		query             string
+		vars              map[string]interface{}
 	}{
 		{
-			name:           "MammalsCollection",
-			databaseName:   "animals",
-			collectionName: "mammals",
+			name:              "MammalsCollection",
+			databaseName:      "animals",
+			collectionName:    "mammals",
+			historyCollection: "mammals_history",
 			document: map[string]interface{}{
 				"name":  "moose",
 				"class": "mammalia",
@@ -213,9 +215,10 @@ func TestArangoService(t *testing.T) {
 			},
 		},
 		{
-			name:           "BirdsCollection",
-			databaseName:   "animals",
-			collectionName: "birds",
+			name:              "BirdsCollection",
+			databaseName:      "animals",
+			collectionName:    "birds",
+			historyCollection: "birds_history",
 			document: map[string]interface{}{
 				"name":  "parrot",
 				"class": "aves",
@@ -230,9 +233,10 @@ func TestArangoService(t *testing.T) {
 			},
 		},
 		{
-			name:           "ReptilesCollection",
-			databaseName:   "animals",
-			collectionName: "reptiles",
+			name:              "ReptilesCollection",
+			databaseName:      "animals",
+			collectionName:    "reptiles",
+			historyCollection: "reptiles_history",
 			document: map[string]interface{}{
 				"name":  "chameleon",
 				"class": "reptilia",
@@ -265,7 +269,7 @@ func TestArangoService(t *testing.T) {
 			})
 
 			t.Run("Connect", func(t *testing.T) {
-				err = arangoService.Connect(ctx, tc.databaseName, tc.collectionName)
+				err = arangoService.Connect(ctx, tc.databaseName, tc.collectionName, tc.historyCollection)
 				assert.NoError(t, err)
 			})
 
@@ -292,6 +296,12 @@ func TestArangoService(t *testing.T) {
 				assert.NotEmpty(t, docMeta)
 			})
 
+			t.Run("CreateHistoryDocument", func(t *testing.T) {
+				meta, err := arangoService.CreateHistoryDocument(ctx, tc.update)
+				assert.NoError(t, err)
+				assert.NotEmpty(t, meta)
+			})
+
 			t.Run("Query", func(t *testing.T) {
 				cursor, err := arangoService.Query(ctx, tc.query, tc.vars)
 				assert.NoError(t, err)